@@ -0,0 +1,52 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DomainZoneEntry routes the validation records of names under Suffix to a hosted zone
+type DomainZoneEntry struct {
+	// Suffix is the domain the entry covers, itself and every name below it
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^\.?([a-zA-Z0-9_]([-a-zA-Z0-9_]*[a-zA-Z0-9])?\.)*[a-zA-Z0-9_]([-a-zA-Z0-9_]*[a-zA-Z0-9])?\.?$`
+	Suffix string `json:"suffix"`
+	// HostedZoneID is the Route 53 hosted zone receiving the records, with or without the
+	// /hostedzone/ prefix
+	// +kubebuilder:validation:MinLength=1
+	HostedZoneID string `json:"hostedZoneID"`
+	// RoleARN, when set, is the IAM role the records are written with, for a zone in another
+	// account
+	// +kubebuilder:validation:Pattern=`^arn:[a-z-]+:iam::[0-9]{12}:role/.+$`
+	// +optional
+	RoleARN string `json:"roleARN,omitempty"`
+}
+
+// DomainZoneMappingSpec is the desired routing of validation records
+type DomainZoneMappingSpec struct {
+	// Entries map domain suffixes to hosted zones; the longest matching suffix wins
+	// +kubebuilder:validation:MinItems=1
+	Entries []DomainZoneEntry `json:"entries"`
+}
+
+// DomainZoneMapping routes the DNS validation records of every managed Ingress to hosted
+// zones by domain suffix, ahead of hosted zone discovery
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=dzm
+type DomainZoneMapping struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DomainZoneMappingSpec `json:"spec"`
+}
+
+// DomainZoneMappingList is a list of DomainZoneMapping
+// +kubebuilder:object:root=true
+type DomainZoneMappingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DomainZoneMapping `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DomainZoneMapping{}, &DomainZoneMappingList{})
+}
@@ -0,0 +1,20 @@
+// Package v1alpha1 contains the acm.tedens.dev v1alpha1 API types
+// +kubebuilder:object:generate=true
+// +groupName=acm.tedens.dev
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of these types
+	GroupVersion = schema.GroupVersion{Group: "acm.tedens.dev", Version: "v1alpha1"}
+
+	// SchemeBuilder registers the types of GroupVersion
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types of GroupVersion to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// adminServer serves the admin endpoints on --admin-bind-address, apart from the metrics
+// server, which controller-runtime serves without authentication by default
+type adminServer struct {
+	addr string
+	mux  *http.ServeMux
+}
+
+func newAdminServer(addr string) *adminServer {
+	return &adminServer{addr: addr, mux: http.NewServeMux()}
+}
+
+// Handle serves handler at path
+func (s *adminServer) Handle(path string, handler http.Handler) {
+	s.mux.Handle(path, handler)
+}
+
+// NeedLeaderElection serves the endpoints on every replica, since each has its own log level
+// and preflight results
+func (s *adminServer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start listens on addr until ctx is done
+func (s *adminServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s.mux, ReadHeaderTimeout: 10 * time.Second}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-done
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tedens/acm-manager/controllers"
+	"github.com/tedens/acm-manager/pkg/certs"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// runAdopt implements `acm-manager adopt`, which brings Ingresses that already reference ACM
+// certificates under the controller's management
+func runAdopt(args []string) error {
+	fs := flag.NewFlagSet("adopt", flag.ExitOnError)
+	namespace := fs.String("namespace", "", "Namespace to scan for Ingresses. Empty scans all namespaces.")
+	managedByValue := fs.String("managed-by-value", controllers.DefaultManagedByValue,
+		"ManagedBy tag value to stamp on adopted certificates; must match the controller's --managed-by-value.")
+	enableAuditLog := fs.Bool("enable-audit-log", true, "Write a JSON line to --audit-log-path for every mutating AWS call.")
+	auditLogPath := fs.String("audit-log-path", "-",
+		"File the JSON audit log of mutating AWS calls is appended to. \"-\" writes to stdout, an empty value disables it.")
+	useFIPS := fs.Bool("use-fips-endpoints", false, "Call ACM through its FIPS endpoint where the region has one.")
+	finalizerName := fs.String("finalizer-name", controllers.DefaultFinalizerName,
+		"Finalizer put on adopted Ingresses; must match the controller's --finalizer-name.")
+	requiredTags := fs.String("required-tags", "",
+		"Comma-separated key=value tags stamped on adopted certificates; must match the controller's --required-tags.")
+	dryRun := fs.Bool("dry-run", false, "Report what would be adopted without tagging certificates or patching Ingresses.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateFinalizerName(*finalizerName); err != nil {
+		return err
+	}
+	certificateTags, err := parseKeyValues("required-tags", *requiredTags)
+	if err != nil {
+		return err
+	}
+	if _, ok := certificateTags[certs.ManagedByTagKey]; ok {
+		return fmt.Errorf("--required-tags must not set %s, use --managed-by-value", certs.ManagedByTagKey)
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	ctx := context.Background()
+
+	k8sClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("unable to create Kubernetes client: %w", err)
+	}
+	awsCfg, err := controllers.LoadAWSConfig(ctx, 0, 0, *useFIPS)
+	if err != nil {
+		return fmt.Errorf("unable to load AWS config: %w", err)
+	}
+
+	if !*enableAuditLog {
+		*auditLogPath = ""
+	}
+	audit, err := openAuditLog(*auditLogPath)
+	if err != nil {
+		return fmt.Errorf("unable to open audit log: %w", err)
+	}
+
+	var acmClient controllers.ACMAPI = controllers.NewACMClient(awsCfg)
+	if audit != nil {
+		acmClient = audit.ACM(acmClient)
+	}
+	r := &controllers.IngressReconciler{
+		Client:         k8sClient,
+		ACMClient:      acmClient,
+		ManagedByValue: *managedByValue,
+		RequiredTags:   certificateTags,
+		IngressDryRun:  *dryRun,
+		FinalizerName:  *finalizerName,
+	}
+	results, err := r.Adopt(ctx, *namespace)
+	for _, result := range results {
+		if result.Skipped != "" {
+			fmt.Fprintf(os.Stdout, "skipped %s: %s\n", result.Ingress, result.Skipped)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "adopted %s (%s)\n", result.Ingress, strings.Join(result.CertificateArns, ","))
+	}
+	return err
+}
@@ -1,21 +1,35 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 
+	acmv1alpha1 "github.com/tedens/acm-manager/api/v1alpha1"
 	"github.com/tedens/acm-manager/controllers"
+	"github.com/tedens/acm-manager/internal/loglevel"
+	"github.com/tedens/acm-manager/internal/version"
+	"github.com/tedens/acm-manager/pkg/certs"
+	"github.com/tedens/acm-manager/pkg/config"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"fmt"
+	"net"
+	"net/url"
+	"slices"
 	"strings"
+	"time"
 
 	"k8s.io/client-go/discovery"
 )
@@ -23,28 +37,358 @@ import (
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
-	version  = "0.1.0"
 )
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(networkingv1.AddToScheme(scheme))
-	flag.StringVar(&version, "version", version, "Version of the binary")
+	utilruntime.Must(acmv1alpha1.AddToScheme(scheme))
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		fmt.Println(version.String())
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "adopt" {
+		if err := runAdopt(os.Args[2:]); err != nil {
+			setupLog.Error(err, "adopt failed")
+			os.Exit(1)
+		}
+		return
+	}
+
+	var printVersion bool
 	var metricsAddr string
+	var probeAddr string
 	var enableLeaderElection bool
+	var managedByValue string
+	var ingressDryRun bool
+	var manageByDefault bool
+	var noRoute53 bool
+	var pendingCertificateMaxAge time.Duration
+	var gcInterval time.Duration
+	var notificationWebhookURL string
+	var postIssuanceHook string
+	var policyConfigMap string
+	var logLevel string
+	var logLevelRevertAfter time.Duration
+	var namespaceRoleMap string
+	var cloudflareTokenSecret string
+	var dnsProvider string
+	var dnsWebhookURL string
+	var shardIndex int
+	var certificateArnConflict string
+	var maintenanceWindow string
+	var maintenanceWindowTimezone string
+	var shardCount int
+	var reuseCertificateStatuses string
+	var awsMaxAttempts int
+	var awsMaxBackoff time.Duration
+	var useFIPSEndpoints bool
+	var allowProfileAnnotation bool
+	var zoneFilterTags string
+	var requiredTags string
+	var namespaceTagAnnotations string
+	var albIngressControllers string
+	var certificateInfoMetric bool
+	var auditLogPath string
+	var enableAuditLog bool
+	var requeuePendingValidation bool
+	var validationLagThreshold time.Duration
+	var certificateIndexInterval time.Duration
+	var negativeZoneCacheTTL time.Duration
+	var prioritizeExpiring bool
+	var inventoryConfigMap string
+	var observeOnly bool
+	var domainZoneMappings bool
+	var podIdentity string
+	var finalizerName string
+	var maxCertificateRequestsPerHour int
+	var inventoryInterval time.Duration
+	var noHostRequeueAttempts int
+	var autoSplitCertificates bool
+	var maxNamesPerCertificate int
+	var wildcardSANPolicy string
+	var noHostRequeueInterval time.Duration
+	var quarantineAfterFailures int
+	var quarantineProbeInterval time.Duration
+	var useACMWaiter bool
+	var acmWaiter certs.WaiterOptions
+	var renewBefore time.Duration
+	var eventDedupWindow time.Duration
+	var includeWWW bool
+	var domainSuffix string
+	var enableAdminEndpoint bool
+	var preflight bool
+	var adminTokenFile string
+	var adminAddr string
+	var configFile string
+	var configReloadInterval time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&certificateArnConflict, "certificate-arn-conflict", controllers.CertificateArnConflictYield,
+		"What to do when a certificate ARN annotation was changed outside the controller: yield keeps the other value, warn also records a Warning event. Neither overwrites it.")
+	flag.StringVar(&maintenanceWindow, "maintenance-window", "",
+		"Comma-separated weekly time ranges, such as \"Mon-Fri 22:00-02:00,Sat 00:00-24:00\", outside which certificate requests, imports and deletions and validation record changes are deferred. Empty allows them at any time.")
+	flag.StringVar(&maintenanceWindowTimezone, "maintenance-window-timezone", "UTC",
+		"IANA time zone of --maintenance-window, for example Europe/Berlin.")
+	flag.IntVar(&shardCount, "shard-count", 0,
+		"Split managed Ingresses over this many replicas, each with its own --shard-index and leader lease. 0 or 1 disables sharding.")
+	flag.IntVar(&shardIndex, "shard-index", 0,
+		"Shard of --shard-count this replica reconciles, from 0.")
+	flag.StringVar(&managedByValue, "managed-by-value", controllers.DefaultManagedByValue,
+		"Value of the ManagedBy tag stamped on requested certificates and required on certificates this instance reuses or deletes.")
+	flag.BoolVar(&manageByDefault, "manage-by-default", false,
+		"Treat every Ingress as managed unless it is annotated acm.tedens.dev/managed: \"false\".")
+	flag.BoolVar(&noRoute53, "no-route53", false,
+		"Never call Route 53. Validation records are published on each Ingress as an event and the acm.tedens.dev/validation-records annotation instead.")
+	flag.BoolVar(&ingressDryRun, "ingress-dry-run", false,
+		"Log the annotation patches and finalizer updates the controller would apply to Ingresses instead of applying them.")
+	flag.DurationVar(&pendingCertificateMaxAge, "pending-certificate-max-age", 0,
+		"Delete owned certificates stuck in PENDING_VALIDATION for longer than this. Zero disables the sweep.")
+	flag.DurationVar(&gcInterval, "gc-interval", controllers.DefaultGCInterval,
+		"How often the pending certificate sweep runs.")
+	flag.StringVar(&notificationWebhookURL, "notification-webhook-url", "",
+		"URL that receives a JSON POST for certificate issued, failed and expiring events. Delivery is best-effort.")
+	flag.StringVar(&postIssuanceHook, "post-issuance-hook", "",
+		"URL that receives a JSON POST once per certificate newly issued and attached to an Ingress, for example to invalidate caches. Delivery is best-effort.")
+	flag.StringVar(&policyConfigMap, "policy-configmap", "",
+		"namespace/name of a ConfigMap providing cluster-wide defaults for acm.tedens.dev annotations.")
+	flag.StringVar(&namespaceRoleMap, "namespace-role-map", "",
+		"namespace/name of a ConfigMap mapping namespaces to the IAM role ARN, and optional region, used for their Ingresses.")
+	flag.StringVar(&logLevel, "log-level", "info",
+		"Log level to start with and revert to: debug, info or warn.")
+	flag.DurationVar(&logLevelRevertAfter, "log-level-revert-after", 15*time.Minute,
+		"Revert a log level changed at runtime to --log-level after this long; 0 keeps it.")
+	flag.BoolVar(&printVersion, "version", false, "Print build information and exit.")
+	flag.StringVar(&configFile, "config", "",
+		"YAML file of kind "+config.Kind+" setting the options of the other flags; flags given on the command line override it.")
+	flag.DurationVar(&configReloadInterval, "config-reload-interval", config.DefaultReloadInterval,
+		"How often the --config file is checked for changes, which are applied without a restart where possible; 0 disables reloading.")
+	flag.StringVar(&cloudflareTokenSecret, "cloudflare-api-token-secret", "",
+		"namespace/name of a Secret whose api-token key holds the Cloudflare API token for the cloudflare DNS provider.")
+	flag.StringVar(&dnsProvider, "dns-provider", controllers.DNSProviderRoute53,
+		"DNS provider writing the validation records of Ingresses without acm.tedens.dev/dns-provider: route53, cloudflare, webhook, manual or none.")
+	flag.StringVar(&dnsWebhookURL, "dns-webhook-url", "",
+		"URL the webhook DNS provider POSTs validation records to for an external service to create or delete.")
+	flag.StringVar(&reuseCertificateStatuses, "reuse-certificate-statuses", "ISSUED,PENDING_VALIDATION",
+		"Comma-separated ACM certificate statuses considered when reusing an existing certificate.")
+	flag.IntVar(&awsMaxAttempts, "aws-max-attempts", 0,
+		"Maximum attempts, including the first, the AWS SDK makes per API call. Zero keeps the SDK default (3).")
+	flag.DurationVar(&awsMaxBackoff, "aws-max-backoff", 0,
+		"Upper bound on the AWS SDK's backoff between retries. Zero keeps the SDK default (20s).")
+	flag.BoolVar(&useFIPSEndpoints, "use-fips-endpoints", false,
+		"Call ACM, Route 53 and STS through FIPS endpoints. A service without a FIPS endpoint in the region uses its standard endpoint.")
+	flag.BoolVar(&allowProfileAnnotation, "allow-profile-annotation", false,
+		"Honor acm.tedens.dev/aws-profile, using that shared config profile for the Ingress' AWS calls. Meant for running the controller locally; in-cluster credentials have no profiles.")
+	flag.StringVar(&requiredTags, "required-tags", "",
+		"Comma-separated key=value tags stamped on every certificate the controller creates. A certificate must carry all of them, besides ManagedBy, to be reused or deleted.")
+	flag.StringVar(&namespaceTagAnnotations, "namespace-tag-annotations", "",
+		"Comma-separated Namespace annotation keys copied, under the same key, as tags onto the certificates of the Namespace's Ingresses.")
+	flag.StringVar(&albIngressControllers, "alb-ingress-controllers", controllers.DefaultALBIngressController,
+		"Comma-separated IngressClass controllers that read the ALB annotations. Managed Ingresses of other classes get no certificate unless they are provision-only or set acm.tedens.dev/force-non-alb.")
+	flag.StringVar(&zoneFilterTags, "zone-filter-tags", "",
+		"Comma-separated key=value tags a Route 53 hosted zone must carry to be auto-discovered.")
+	flag.BoolVar(&certificateInfoMetric, "ingress-certificate-info-metric", true,
+		"Export acm_manager_ingress_certificate_info with one series per managed Ingress and attached certificate.")
+	flag.BoolVar(&enableAuditLog, "enable-audit-log", true,
+		"Write a JSON line to --audit-log-path for every mutating AWS call and Ingress write.")
+	flag.StringVar(&auditLogPath, "audit-log-path", "-",
+		"File the JSON audit log is appended to. \"-\" writes to stdout, an empty value disables it.")
+	flag.BoolVar(&requeuePendingValidation, "requeue-pending-validation", false,
+		"Requeue Ingresses whose certificate is pending validation with an exponential backoff instead of waiting in the reconcile.")
+	flag.DurationVar(&validationLagThreshold, "validation-lag-threshold", 5*time.Minute,
+		"How long a name may stay pending validation after the certificate's other names validated before a ValidationLagging event names it; 0 disables it.")
+	flag.DurationVar(&certificateIndexInterval, "certificate-index-interval", controllers.DefaultCertificateIndexInterval,
+		"How often the in-memory index of managed ACM certificates, consulted before listing the account, is rebuilt; 0 disables the index.")
+	flag.DurationVar(&negativeZoneCacheTTL, "negative-zone-cache-ttl", certs.DefaultNegativeZoneCacheTTL,
+		"How long a domain without an eligible Route 53 hosted zone is remembered before the zones are listed again.")
+	flag.BoolVar(&prioritizeExpiring, "prioritize-expiring-certificates", true,
+		"Reconcile Ingresses whose certificate is inside its renewal margin before the others, closest to expiry first, using controller-runtime's priority queue.")
+	flag.StringVar(&finalizerName, "finalizer-name", controllers.DefaultFinalizerName,
+		"Finalizer put on managed Ingresses. Ingresses carrying the default acm.tedens.dev/finalizer are migrated to it, so two instances can run side by side.")
+	flag.BoolVar(&domainZoneMappings, "enable-domain-zone-mappings", false,
+		"Route validation records by the cluster-scoped DomainZoneMapping objects, which must have their CRD installed, and requeue pending Ingresses when they change.")
+	hostname, _ := os.Hostname()
+	flag.StringVar(&podIdentity, "pod-identity", hostname,
+		"Identity stamped in acm.tedens.dev/managed-by-pod on reconciled Ingresses to detect two controllers leading at once. Defaults to the hostname, which is the pod name; empty disables it.")
+	flag.BoolVar(&observeOnly, "observe-only", false,
+		"Make no AWS changes and add no finalizers; report the changes reconciles would make in the acm.tedens.dev/observed-plan annotation, ObservedPlan events and metrics.")
+	flag.IntVar(&maxCertificateRequestsPerHour, "max-certificate-requests-per-hour", 0,
+		"Certificate requests allowed per hour across all reconciles, to protect the account's yearly ACM quota; requests over it are requeued. Up to an hour's worth may be made at once. 0 disables the limit.")
+	flag.StringVar(&inventoryConfigMap, "inventory-configmap", "",
+		"ConfigMap, as namespace/name, the leader writes an inventory of the managed Ingresses to. Empty disables the report.")
+	flag.DurationVar(&inventoryInterval, "inventory-interval", controllers.DefaultInventoryInterval,
+		"How often the inventory ConfigMap is brought up to date.")
+	flag.BoolVar(&autoSplitCertificates, "auto-split-certificates", false,
+		"Split Ingresses with more names than fit on one certificate over several certificates, all attached to the ALB.")
+	flag.IntVar(&maxNamesPerCertificate, "max-names-per-certificate", 10,
+		"Names per certificate, including the primary domain; raise it only with a raised ACM quota.")
+	flag.StringVar(&wildcardSANPolicy, "wildcard-san-policy", controllers.WildcardSANPolicyKeep,
+		"What to do with SANs a wildcard name of the same certificate already covers: keep, prune or error.")
+	flag.IntVar(&noHostRequeueAttempts, "no-host-requeue-attempts", controllers.DefaultNoHostRequeueAttempts,
+		"How many times a managed Ingress without a host is checked again before it is skipped with a NoHosts Warning; 0 skips it right away.")
+	flag.DurationVar(&noHostRequeueInterval, "no-host-requeue-interval", controllers.DefaultNoHostRequeueInterval,
+		"Delay between the checks of a managed Ingress without a host.")
+	flag.IntVar(&quarantineAfterFailures, "quarantine-after-failures", 0,
+		"Quarantine an Ingress after this many consecutive failures that retrying cannot fix, such as a missing hosted zone or failed validation; 0 never quarantines.")
+	flag.DurationVar(&quarantineProbeInterval, "quarantine-probe-interval", controllers.DefaultQuarantineProbeInterval,
+		"How often a quarantined Ingress is still retried.")
+	flag.BoolVar(&useACMWaiter, "use-acm-waiter", false,
+		"Wait for certificate validation with the AWS SDK's ACM waiter instead of polling every 15s. Ignored with --requeue-pending-validation.")
+	flag.DurationVar(&acmWaiter.MinDelay, "acm-waiter-min-delay", 0,
+		"Minimum delay between the ACM waiter's describes. Zero keeps the SDK default (60s).")
+	flag.DurationVar(&acmWaiter.MaxDelay, "acm-waiter-max-delay", 0,
+		"Maximum delay between the ACM waiter's describes. Zero keeps the SDK default (120s).")
+	flag.DurationVar(&acmWaiter.MaxWait, "acm-waiter-max-wait", 0,
+		"Longest a reconcile waits with the ACM waiter. Zero waits until the validation deadline (10m).")
+	flag.DurationVar(&renewBefore, "renew-before", controllers.DefaultRenewBefore,
+		"How close to expiry an attached certificate may get before its renewal is escalated, unless acm.tedens.dev/renew-before is set.")
+	flag.DurationVar(&eventDedupWindow, "event-dedup-window", controllers.DefaultEventDedupWindow,
+		"Aggregate identical events on an Ingress within this window instead of recording each one; 0 disables deduplication.")
+	flag.BoolVar(&includeWWW, "include-www", false,
+		"Add www.<domain> to the certificates of apex domains, and the apex to those of their www host, unless an Ingress sets acm.tedens.dev/include-www.")
+	flag.StringVar(&domainSuffix, "domain-suffix", "",
+		"Append .<suffix> to Ingress hosts without a dot unless an Ingress sets acm.tedens.dev/domain-suffix.")
+	flag.BoolVar(&enableAdminEndpoint, "enable-admin-endpoint", false,
+		"Serve POST "+controllers.ReconcileAllPath+" on --admin-bind-address to reconcile every managed Ingress.")
+	flag.StringVar(&adminTokenFile, "admin-token-file", "",
+		"File holding a bearer token required by the admin endpoints; empty leaves them unauthenticated.")
+	flag.StringVar(&adminAddr, "admin-bind-address", "0",
+		"The address the admin endpoints, such as "+loglevel.Path+", bind to, apart from the metrics endpoint; 0 disables them.")
+	flag.BoolVar(&preflight, "preflight", false,
+		"Probe the read-only AWS permissions at startup, log a summary, and stay unready until they pass. Results are served at "+controllers.PreflightPath+" on --admin-bind-address.")
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	if printVersion {
+		fmt.Println(version.String())
+		return
+	}
+
+	var fileConfig *config.Configuration
+	if configFile != "" {
+		var err error
+		fileConfig, err = config.Load(configFile)
+		if err == nil {
+			err = fileConfig.Apply(flag.CommandLine)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid --config:", err)
+			os.Exit(1)
+		}
+	}
+
+	baseLevel, err := loglevel.Parse(logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid --log-level:", err)
+		os.Exit(1)
+	}
+	logLevels := loglevel.New(baseLevel, logLevelRevertAfter)
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true), zap.Level(logLevels.Level())))
+	setupLog.Info("starting acm-manager", "version", version.Version, "commit", version.Commit, "date", version.Date)
+	if effective, err := config.Effective(flag.CommandLine); err != nil {
+		setupLog.Error(err, "failed to describe the effective configuration")
+	} else {
+		setupLog.Info("effective configuration", "config", effective)
+	}
+
+	if addressesCollide(metricsAddr, probeAddr) {
+		setupLog.Error(fmt.Errorf("metrics and health probe addresses both bind %q / %q", metricsAddr, probeAddr),
+			"--metrics-bind-address and --health-probe-bind-address must use different ports")
+		os.Exit(1)
+	}
+	if addressesCollide(adminAddr, metricsAddr) || addressesCollide(adminAddr, probeAddr) {
+		setupLog.Error(fmt.Errorf("admin address %q collides with %q / %q", adminAddr, metricsAddr, probeAddr),
+			"--admin-bind-address must use a port of its own")
+		os.Exit(1)
+	}
+
+	if enableAdminEndpoint && (adminAddr == "0" || adminAddr == "") {
+		setupLog.Error(fmt.Errorf("--admin-bind-address=%q", adminAddr),
+			"--enable-admin-endpoint is served on the admin address, which must be set")
+		os.Exit(1)
+	}
+
+	// liveSettings validates the flags a configuration reload can change and returns the
+	// reconciler settings they amount to, without the notifiers, which need the manager
+	liveSettings := func() (controllers.LiveSettings, error) {
+		if suffix := strings.Trim(strings.ToLower(domainSuffix), "."); suffix != "" {
+			if errs := validation.IsDNS1123Subdomain(suffix); len(errs) > 0 {
+				return controllers.LiveSettings{}, fmt.Errorf("--domain-suffix=%q: %s", domainSuffix, strings.Join(errs, "; "))
+			}
+		}
+		if maxNamesPerCertificate < 1 {
+			return controllers.LiveSettings{}, fmt.Errorf("--max-names-per-certificate=%d: at least one name must fit on a certificate", maxNamesPerCertificate)
+		}
+		if noHostRequeueAttempts < 0 || noHostRequeueInterval < 0 {
+			return controllers.LiveSettings{}, fmt.Errorf("--no-host-requeue-attempts=%d --no-host-requeue-interval=%s: no-host requeue settings must not be negative",
+				noHostRequeueAttempts, noHostRequeueInterval)
+		}
+		if quarantineAfterFailures < 0 || quarantineProbeInterval <= 0 {
+			return controllers.LiveSettings{}, fmt.Errorf("--quarantine-after-failures=%d --quarantine-probe-interval=%s: quarantine failures must not be negative and the probe interval must be positive",
+				quarantineAfterFailures, quarantineProbeInterval)
+		}
+		policy := strings.ToLower(strings.TrimSpace(wildcardSANPolicy))
+		if !controllers.IsWildcardSANPolicy(policy) {
+			return controllers.LiveSettings{}, fmt.Errorf("--wildcard-san-policy=%q: the wildcard SAN policy must be keep, prune or error", wildcardSANPolicy)
+		}
+		if certificateArnConflict != controllers.CertificateArnConflictYield && certificateArnConflict != controllers.CertificateArnConflictWarn {
+			return controllers.LiveSettings{}, fmt.Errorf("--certificate-arn-conflict=%q: must be yield or warn", certificateArnConflict)
+		}
+		reuseStatuses, err := certs.ParseCertificateStatuses(reuseCertificateStatuses)
+		if err != nil {
+			return controllers.LiveSettings{}, fmt.Errorf("--reuse-certificate-statuses: %w", err)
+		}
+		nsTagAnnotations := parseList(namespaceTagAnnotations)
+		if slices.Contains(nsTagAnnotations, certs.ManagedByTagKey) {
+			return controllers.LiveSettings{}, fmt.Errorf("--namespace-tag-annotations must not include %s", certs.ManagedByTagKey)
+		}
+		if postIssuanceHook != "" && !isHTTPURL(postIssuanceHook) {
+			return controllers.LiveSettings{}, fmt.Errorf("--post-issuance-hook=%q: the post-issuance hook must be an http or https URL", postIssuanceHook)
+		}
+		return controllers.LiveSettings{
+			ManageByDefault:          manageByDefault,
+			IncludeWWW:               includeWWW,
+			DomainSuffix:             domainSuffix,
+			NamespaceTagAnnotations:  nsTagAnnotations,
+			ReuseCertificateStatuses: reuseStatuses,
+			ALBIngressControllers:    parseList(albIngressControllers),
+			ValidationLagThreshold:   validationLagThreshold,
+			MaxNamesPerCertificate:   maxNamesPerCertificate,
+			AutoSplitCertificates:    autoSplitCertificates,
+			WildcardSANPolicy:        policy,
+			NoHostRequeueAttempts:    noHostRequeueAttempts,
+			NoHostRequeueInterval:    noHostRequeueInterval,
+			QuarantineAfterFailures:  quarantineAfterFailures,
+			QuarantineProbeInterval:  quarantineProbeInterval,
+			RenewBefore:              renewBefore,
+			CertificateArnConflict:   certificateArnConflict,
+		}, nil
+	}
+	live, err := liveSettings()
+	if err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+
+	if acmWaiter.MinDelay < 0 || acmWaiter.MaxDelay < 0 || acmWaiter.MaxWait < 0 {
+		setupLog.Error(fmt.Errorf("--acm-waiter-min-delay=%s --acm-waiter-max-delay=%s --acm-waiter-max-wait=%s",
+			acmWaiter.MinDelay, acmWaiter.MaxDelay, acmWaiter.MaxWait), "ACM waiter settings must not be negative")
+		os.Exit(1)
+	}
+
+	if awsMaxAttempts < 0 || awsMaxBackoff < 0 {
+		setupLog.Error(fmt.Errorf("--aws-max-attempts=%d --aws-max-backoff=%s", awsMaxAttempts, awsMaxBackoff),
+			"AWS retry settings must not be negative")
+		os.Exit(1)
+	}
 
 	// Kubernetes version check: require >= v1.32
-	config := ctrl.GetConfigOrDie()
-	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	restConfig := ctrl.GetConfigOrDie()
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
 	if err != nil {
 		setupLog.Error(err, "unable to create discovery client")
 		os.Exit(1)
@@ -70,31 +414,271 @@ func main() {
 		os.Exit(1)
 	}
 
-	mgr, err := ctrl.NewManager(config, ctrl.Options{
+	policyRef, err := parseNamespacedName("policy-configmap", policyConfigMap)
+	if err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+	roleMapRef, err := parseNamespacedName("namespace-role-map", namespaceRoleMap)
+	if err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+	cloudflareRef, err := parseNamespacedName("cloudflare-api-token-secret", cloudflareTokenSecret)
+	if err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+	inventoryRef, err := parseNamespacedName("inventory-configmap", inventoryConfigMap)
+	if err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+
+	dnsProvider = strings.ToLower(strings.TrimSpace(dnsProvider))
+	if !controllers.IsDNSProvider(dnsProvider) {
+		setupLog.Error(fmt.Errorf("--dns-provider=%q", dnsProvider), "unknown DNS provider")
+		os.Exit(1)
+	}
+	if dnsWebhookURL != "" && !isHTTPURL(dnsWebhookURL) {
+		setupLog.Error(fmt.Errorf("--dns-webhook-url=%q", dnsWebhookURL), "the DNS webhook must be an http or https URL")
+		os.Exit(1)
+	}
+	if dnsProvider == controllers.DNSProviderWebhook && dnsWebhookURL == "" {
+		setupLog.Error(fmt.Errorf("--dns-provider=webhook"), "the webhook DNS provider needs --dns-webhook-url")
+		os.Exit(1)
+	}
+
+	var window *controllers.MaintenanceWindow
+	if maintenanceWindow != "" {
+		location, err := time.LoadLocation(maintenanceWindowTimezone)
+		if err != nil {
+			setupLog.Error(err, "invalid flag", "flag", "maintenance-window-timezone")
+			os.Exit(1)
+		}
+		if window, err = controllers.ParseMaintenanceWindow(maintenanceWindow, location); err != nil {
+			setupLog.Error(err, "invalid flag", "flag", "maintenance-window")
+			os.Exit(1)
+		}
+	}
+
+	zoneTags, err := parseKeyValues("zone-filter-tags", zoneFilterTags)
+	if err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+	certificateTags, err := parseKeyValues("required-tags", requiredTags)
+	if err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+	if _, ok := certificateTags[certs.ManagedByTagKey]; ok {
+		setupLog.Error(fmt.Errorf("--required-tags must not set %s, use --managed-by-value", certs.ManagedByTagKey), "invalid flag")
+		os.Exit(1)
+	}
+	if err := validateFinalizerName(finalizerName); err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+
+	leaderElectionID, err := shardLeaderElectionID("acm-ingress-controller.tedens.dev", shardIndex, shardCount)
+	if err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme: scheme,
 		Metrics: server.Options{
-			BindAddress: "0", // disables metrics temporarily
+			BindAddress: metricsAddr,
 		},
-		HealthProbeBindAddress: ":8080",
+		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "acm-ingress-controller.tedens.dev",
+		LeaderElectionID:       leaderElectionID,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if err = (&controllers.IngressReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	if err := version.RegisterMetrics(metrics.Registry); err != nil {
+		setupLog.Error(err, "unable to register build info metric")
+		os.Exit(1)
+	}
+
+	if err := controllers.RegisterMetrics(metrics.Registry); err != nil {
+		setupLog.Error(err, "unable to register controller metrics")
+		os.Exit(1)
+	}
+
+	if err := logLevels.RegisterMetrics(metrics.Registry); err != nil {
+		setupLog.Error(err, "unable to register log level metric")
+		os.Exit(1)
+	}
+
+	if !enableAuditLog {
+		auditLogPath = ""
+	}
+	audit, err := openAuditLog(auditLogPath)
+	if err != nil {
+		setupLog.Error(err, "unable to open audit log")
+		os.Exit(1)
+	}
+
+	var certificateInfo *controllers.CertificateInfoMetric
+	if certificateInfoMetric {
+		certificateInfo = controllers.NewCertificateInfoMetric()
+		if err := metrics.Registry.Register(certificateInfo); err != nil {
+			setupLog.Error(err, "unable to register certificate info metric")
+			os.Exit(1)
+		}
+	}
+
+	notifications := &webhookTarget{add: mgr.Add}
+	if live.Notifier, err = notifications.set(notificationWebhookURL); err != nil {
+		setupLog.Error(err, "unable to add notification webhook")
+		os.Exit(1)
+	}
+	postIssuanceHooks := &webhookTarget{add: mgr.Add}
+	if live.PostIssuanceHook, err = postIssuanceHooks.set(postIssuanceHook); err != nil {
+		setupLog.Error(err, "unable to add post-issuance hook")
+		os.Exit(1)
+	}
+
+	reconciler := &controllers.IngressReconciler{
+		Client:                        mgr.GetClient(),
+		Scheme:                        mgr.GetScheme(),
+		ManagedByValue:                managedByValue,
+		LiveSettings:                  live,
+		NoRoute53:                     noRoute53,
+		IngressDryRun:                 ingressDryRun,
+		PendingCertificateMaxAge:      pendingCertificateMaxAge,
+		GCInterval:                    gcInterval,
+		PolicyConfigMap:               policyRef,
+		NamespaceRoleMap:              roleMapRef,
+		CloudflareTokenSecret:         cloudflareRef,
+		DefaultDNSProvider:            dnsProvider,
+		DNSWebhookURL:                 dnsWebhookURL,
+		MaintenanceWindow:             window,
+		ShardCount:                    shardCount,
+		ShardIndex:                    shardIndex,
+		AWSMaxAttempts:                awsMaxAttempts,
+		AWSMaxBackoff:                 awsMaxBackoff,
+		UseFIPSEndpoints:              useFIPSEndpoints,
+		AllowProfileAnnotation:        allowProfileAnnotation,
+		RequiredTags:                  certificateTags,
+		ZoneFilterTags:                zoneTags,
+		CertificateInfo:               certificateInfo,
+		Audit:                         audit,
+		RequeuePendingValidation:      requeuePendingValidation,
+		UseACMWaiter:                  useACMWaiter,
+		CertificateIndexInterval:      certificateIndexInterval,
+		NegativeZoneCacheTTL:          negativeZoneCacheTTL,
+		PrioritizeExpiring:            prioritizeExpiring,
+		InventoryConfigMap:            inventoryRef,
+		ObserveOnly:                   observeOnly,
+		PodIdentity:                   podIdentity,
+		FinalizerName:                 finalizerName,
+		DomainZoneMappings:            domainZoneMappings,
+		InventoryInterval:             inventoryInterval,
+		ACMWaiter:                     acmWaiter,
+		EventDedupWindow:              eventDedupWindow,
+		MaxCertificateRequestsPerHour: maxCertificateRequestsPerHour,
+	}
+	if err = reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Ingress")
 		os.Exit(1)
 	}
 
+	if fileConfig != nil && configReloadInterval > 0 {
+		watcher := config.NewWatcher(fileConfig, flag.CommandLine, configReloadInterval, func(context.Context) error {
+			settings, err := liveSettings()
+			if err != nil {
+				return err
+			}
+			// Nothing changes until every part of the reload succeeded, so a failure keeps the
+			// previous notifiers and settings in use
+			notification, err := notifications.prepare(notificationWebhookURL)
+			if err != nil {
+				return err
+			}
+			hook, err := postIssuanceHooks.prepare(postIssuanceHook)
+			if err != nil {
+				notification.discard()
+				return err
+			}
+			settings.Notifier, settings.PostIssuanceHook = notification.notifier(), hook.notifier()
+			reconciler.ApplySettings(settings)
+			// The replaced notifiers are stopped only once the reconciler no longer uses them
+			notification.apply()
+			hook.apply()
+			return nil
+		})
+		if err := watcher.RegisterMetrics(metrics.Registry); err != nil {
+			setupLog.Error(err, "unable to register configuration reload metrics")
+			os.Exit(1)
+		}
+		if err := mgr.Add(watcher); err != nil {
+			setupLog.Error(err, "unable to add configuration watcher")
+			os.Exit(1)
+		}
+	}
+
+	adminToken, err := readAdminToken(adminTokenFile)
+	if err != nil {
+		setupLog.Error(err, "unable to read admin token")
+		os.Exit(1)
+	}
+
+	// The admin endpoints get a listener of their own: the metrics server is unauthenticated
+	// and usually reachable by everything that scrapes it
+	var admin *adminServer
+	if adminAddr != "0" && adminAddr != "" {
+		if adminToken == "" {
+			setupLog.Info("admin endpoints are unauthenticated; set --admin-token-file to require a token")
+		}
+		admin = newAdminServer(adminAddr)
+		admin.Handle(loglevel.Path, logLevels.Handler(ctrl.LoggerInto(context.Background(), setupLog), adminToken))
+		if enableAdminEndpoint {
+			admin.Handle(controllers.ReconcileAllPath, reconciler.ReconcileAllHandler(adminToken))
+		}
+	}
+
+	if err := mgr.Add(&controllers.LeaderStatus{Elected: mgr.Elected()}); err != nil {
+		setupLog.Error(err, "unable to add leader status")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(logLevels); err != nil {
+		setupLog.Error(err, "unable to add log level signal handler")
+		os.Exit(1)
+	}
+
 	setupLog.Info("adding health and readiness checks")
 	mgr.AddHealthzCheck("healthz", healthz.Ping)
 	mgr.AddReadyzCheck("readyz", healthz.Ping)
+	// Not ready while the AWS credentials cannot be loaded, as before the IRSA webhook injected
+	// them; the controller keeps retrying instead of exiting
+	mgr.AddReadyzCheck("aws-credentials", reconciler.AWSCredentialsCheck)
+	// Without --preflight readiness makes no AWS API calls: replicas waiting for the lease stay
+	// ready to serve metrics without permissions they may not have
+	if preflight {
+		checks := reconciler.NewPreflight()
+		if err := mgr.Add(checks); err != nil {
+			setupLog.Error(err, "unable to add preflight")
+			os.Exit(1)
+		}
+		if admin != nil {
+			admin.Handle(controllers.PreflightPath, checks.PreflightHandler())
+		}
+		mgr.AddReadyzCheck("preflight", checks.ReadyCheck)
+	}
+	if admin != nil {
+		if err := mgr.Add(admin); err != nil {
+			setupLog.Error(err, "unable to add admin server")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -102,3 +686,199 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// readAdminToken returns the trimmed token in path; an empty path yields no token
+func readAdminToken(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("admin token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// addressesCollide reports whether two listen addresses would bind the same port.
+// "0" and "" disable a listener and never collide.
+func addressesCollide(a, b string) bool {
+	if a == "0" || a == "" || b == "0" || b == "" {
+		return false
+	}
+	hostA, portA, errA := net.SplitHostPort(a)
+	hostB, portB, errB := net.SplitHostPort(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	if portA != portB {
+		return false
+	}
+	return hostA == hostB || isWildcardHost(hostA) || isWildcardHost(hostB)
+}
+
+func isWildcardHost(host string) bool {
+	return host == "" || host == "0.0.0.0" || host == "::"
+}
+
+// shardLeaderElectionID validates the shard flags and returns the lease name of the shard, so
+// each shard elects its own leader
+func shardLeaderElectionID(base string, index, count int) (string, error) {
+	if count < 0 {
+		return "", fmt.Errorf("--shard-count must not be negative, got %d", count)
+	}
+	if count <= 1 {
+		if index != 0 {
+			return "", fmt.Errorf("--shard-index=%d needs --shard-count greater than 1", index)
+		}
+		return base, nil
+	}
+	if index < 0 || index >= count {
+		return "", fmt.Errorf("--shard-index must be between 0 and %d, got %d", count-1, index)
+	}
+	return fmt.Sprintf("%s-shard-%d", base, index), nil
+}
+
+// validateFinalizerName checks that value is a domain-qualified name, as Kubernetes expects of
+// finalizers
+func validateFinalizerName(value string) error {
+	if !strings.Contains(value, "/") {
+		return fmt.Errorf("--finalizer-name must be qualified with a domain, like %s, got %q", controllers.DefaultFinalizerName, value)
+	}
+	if errs := validation.IsQualifiedName(value); len(errs) > 0 {
+		return fmt.Errorf("--finalizer-name %q is invalid: %s", value, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// webhookTarget is a webhook notifier whose URL a configuration reload can change
+type webhookTarget struct {
+	add      func(manager.Runnable) error
+	url      string
+	notifier *controllers.WebhookNotifier
+}
+
+// set returns the notifier for url, nil when it is empty. A changed URL gets a new notifier,
+// added to the manager, and the one it replaces is stopped once its queue is delivered.
+func (t *webhookTarget) set(url string) (controllers.Notifier, error) {
+	change, err := t.prepare(url)
+	if err != nil {
+		return nil, err
+	}
+	change.apply()
+	return change.notifier(), nil
+}
+
+// webhookChange is a notifier prepared for a new URL that is not in use yet, so a reload that
+// fails later can discard it and keep the current one
+type webhookChange struct {
+	target *webhookTarget
+	url    string
+	next   *controllers.WebhookNotifier
+}
+
+// prepare returns the change to url, adding a new notifier to the manager when the URL changed
+func (t *webhookTarget) prepare(url string) (*webhookChange, error) {
+	change := &webhookChange{target: t, url: url, next: t.notifier}
+	if url != t.url {
+		change.next = nil
+		if url != "" {
+			next := controllers.NewWebhookNotifier(url)
+			if err := t.add(next); err != nil {
+				return nil, err
+			}
+			change.next = next
+		}
+	}
+	return change, nil
+}
+
+// notifier returns the notifier of the change, nil when its URL is empty
+func (c *webhookChange) notifier() controllers.Notifier {
+	if c.next == nil {
+		return nil
+	}
+	return c.next
+}
+
+// apply makes the change's notifier current and stops the one it replaces once its queue is
+// delivered
+func (c *webhookChange) apply() {
+	t := c.target
+	if t.notifier != nil && t.notifier != c.next {
+		t.notifier.Stop()
+	}
+	t.url, t.notifier = c.url, c.next
+}
+
+// discard stops the notifier the change added, leaving the current one in use
+func (c *webhookChange) discard() {
+	if c.next != nil && c.next != c.target.notifier {
+		c.next.Stop()
+	}
+}
+
+// isHTTPURL reports whether value is an absolute http or https URL
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// parseNamespacedName parses a namespace/name flag value; an empty value yields an empty name
+func parseNamespacedName(flagName, value string) (types.NamespacedName, error) {
+	if value == "" {
+		return types.NamespacedName{}, nil
+	}
+	namespace, name, ok := strings.Cut(value, "/")
+	if !ok || namespace == "" || name == "" {
+		return types.NamespacedName{}, fmt.Errorf("--%s must be namespace/name, got %q", flagName, value)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// parseKeyValues parses a key=value,key=value flag value; an empty value yields nil
+func parseKeyValues(flagName, value string) (map[string]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	pairs := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("--%s must be key=value pairs, got %q", flagName, pair)
+		}
+		pairs[key] = strings.TrimSpace(val)
+	}
+	return pairs, nil
+}
+
+// parseList parses a comma-separated flag value, dropping empty entries
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// openAuditLog returns the audit logger for path: "-" is stdout, "" disables auditing and any
+// other value is a file opened for appending
+func openAuditLog(path string) (*controllers.AuditLogger, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case "-":
+		return controllers.NewAuditLogger(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return controllers.NewAuditLogger(f), nil
+}
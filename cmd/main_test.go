@@ -0,0 +1,132 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/tedens/acm-manager/controllers"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+func TestAddressesCollide(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{":8080", ":8081", false},
+		{":8080", ":8080", true},
+		{"0.0.0.0:8080", ":8080", true},
+		{"127.0.0.1:8080", "10.0.0.1:8080", false},
+		{"127.0.0.1:8080", ":8080", true},
+		{"0", ":8080", false},
+		{":8080", "", false},
+	}
+	for _, c := range cases {
+		if got := addressesCollide(c.a, c.b); got != c.want {
+			t.Errorf("addressesCollide(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	got, err := parseKeyValues("zone-filter-tags", "team=platform, env = prod")
+	if err != nil {
+		t.Fatalf("parseKeyValues: %v", err)
+	}
+	if want := map[string]string{"team": "platform", "env": "prod"}; !maps.Equal(got, want) {
+		t.Fatalf("parseKeyValues = %v, want %v", got, want)
+	}
+	if got, err := parseKeyValues("zone-filter-tags", ""); err != nil || got != nil {
+		t.Fatalf("empty value = %v, %v; want nil", got, err)
+	}
+	if _, err := parseKeyValues("zone-filter-tags", "team"); err == nil {
+		t.Fatal("expected an error for a pair without =")
+	}
+}
+
+func TestShardLeaderElectionID(t *testing.T) {
+	const base = "acm-ingress-controller.tedens.dev"
+	if id, err := shardLeaderElectionID(base, 0, 0); err != nil || id != base {
+		t.Fatalf("without sharding = %q, %v; want the base lease", id, err)
+	}
+	if id, err := shardLeaderElectionID(base, 2, 3); err != nil || id != base+"-shard-2" {
+		t.Fatalf("shard 2 of 3 = %q, %v", id, err)
+	}
+	for _, c := range [][2]int{{3, 3}, {-1, 3}, {1, 0}, {0, -2}} {
+		if _, err := shardLeaderElectionID(base, c[0], c[1]); err == nil {
+			t.Errorf("expected --shard-index=%d --shard-count=%d to be rejected", c[0], c[1])
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	if got := parseList(" ourco.io/cost-center, ,team "); !slices.Equal(got, []string{"ourco.io/cost-center", "team"}) {
+		t.Fatalf("parseList = %v", got)
+	}
+	if got := parseList(""); got != nil {
+		t.Fatalf("empty value = %v, want nil", got)
+	}
+}
+
+func TestValidateFinalizerName(t *testing.T) {
+	for _, name := range []string{"acm.tedens.dev/finalizer", "acm-v2.tedens.dev/finalizer"} {
+		if err := validateFinalizerName(name); err != nil {
+			t.Errorf("validateFinalizerName(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "finalizer", "acm.tedens.dev/", "Acm.tedens.dev/fin alizer"} {
+		if err := validateFinalizerName(name); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestWebhookTargetReplacesNotifierOnURLChange(t *testing.T) {
+	var added []manager.Runnable
+	target := &webhookTarget{add: func(r manager.Runnable) error {
+		added = append(added, r)
+		return nil
+	}}
+
+	if n, err := target.set(""); err != nil || n != nil || len(added) != 0 {
+		t.Fatalf("empty URL gave %v, %v with %d notifiers, want none", n, err, len(added))
+	}
+	first, err := target.set("https://hooks.example.com/a")
+	if err != nil || first == nil {
+		t.Fatalf("set: %v, %v", first, err)
+	}
+	if same, _ := target.set("https://hooks.example.com/a"); same != first || len(added) != 1 {
+		t.Fatalf("an unchanged URL started another notifier")
+	}
+	second, _ := target.set("https://hooks.example.com/b")
+	if second == first || len(added) != 2 || second.(*controllers.WebhookNotifier).URL != "https://hooks.example.com/b" {
+		t.Fatalf("a changed URL should start a notifier for it, got %v", second)
+	}
+	if n, _ := target.set(""); n != nil {
+		t.Fatalf("clearing the URL gave %v, want no notifier", n)
+	}
+}
+
+func TestWebhookChangeDiscardKeepsCurrentNotifier(t *testing.T) {
+	target := &webhookTarget{add: func(manager.Runnable) error { return nil }}
+	current, err := target.set("https://hooks.example.com/a")
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	change, err := target.prepare("https://hooks.example.com/b")
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if target.notifier != current || target.url != "https://hooks.example.com/a" {
+		t.Fatal("prepare must not replace the notifier in use")
+	}
+	change.discard()
+	if target.notifier != current {
+		t.Fatal("discard must keep the notifier in use")
+	}
+	if same, _ := target.set("https://hooks.example.com/a"); same != current {
+		t.Fatal("after a discarded change the current URL must keep its notifier")
+	}
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ReconcileAllPath is where ReconcileAllHandler is served on the metrics server
+const ReconcileAllPath = "/admin/reconcile-all"
+
+// ReconcileAll enqueues every managed Ingress of this replica's shard for an immediate
+// reconcile, clearing retry backoffs and forgetting cached missing hosted zones, and returns
+// how many were enqueued
+func (r *IngressReconciler) ReconcileAll(ctx context.Context) (int, error) {
+	if provider, ok := r.DNSProvider.(*certs.Route53Provider); ok {
+		provider.ForgetMissingZones()
+	}
+	for _, provider := range r.awsClients().route53Providers() {
+		provider.ForgetMissingZones()
+	}
+
+	policy, err := r.loadPolicy(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var list networkingv1.IngressList
+	if err := r.List(ctx, &list); err != nil {
+		return 0, err
+	}
+
+	enqueued := 0
+	for i := range list.Items {
+		cfg, err := r.ingressConfig(ctx, &list.Items[i], policy)
+		if err != nil {
+			return enqueued, err
+		}
+		// Paused Ingresses must not even have their backoff cleared
+		if !cfg.Managed || isPaused(&list.Items[i]) || !r.inShard(&list.Items[i]) {
+			continue
+		}
+		if err := r.clearRetryBackoff(ctx, &list.Items[i]); err != nil {
+			return enqueued, err
+		}
+		select {
+		case r.resync <- event.GenericEvent{Object: &list.Items[i]}:
+			enqueued++
+		case <-ctx.Done():
+			return enqueued, ctx.Err()
+		}
+	}
+	log.FromContext(ctx).Info("Enqueued all managed Ingresses", "count", enqueued)
+	return enqueued, nil
+}
+
+// ReconcileAllHandler serves POST requests running ReconcileAll on the leader. When token is
+// set, requests must carry it as a bearer token.
+func (r *IngressReconciler) ReconcileAllHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		if token != "" && subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		select {
+		case <-r.elected:
+		default:
+			http.Error(w, "not the leader", http.StatusServiceUnavailable)
+			return
+		}
+
+		enqueued, err := r.ReconcileAll(req.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"enqueued": enqueued})
+	})
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func newAdminTestReconciler(t *testing.T) *IngressReconciler {
+	t.Helper()
+	unmanaged := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}}
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"),
+		newManagedIngress("web", "web.example.com", map[string]string{retryBackoffAnnotation: `{"failures":7}`}),
+		newManagedIngress("api", "api.example.com", nil),
+		unmanaged)
+	r.resync = make(chan event.GenericEvent, 10)
+	elected := make(chan struct{})
+	close(elected)
+	r.elected = elected
+	return r
+}
+
+func TestReconcileAllEnqueuesManagedIngresses(t *testing.T) {
+	r := newAdminTestReconciler(t)
+
+	enqueued, err := r.ReconcileAll(context.Background())
+	if err != nil {
+		t.Fatalf("ReconcileAll: %v", err)
+	}
+	if enqueued != 2 {
+		t.Fatalf("enqueued = %d, want 2", enqueued)
+	}
+	close(r.resync)
+	var names []string
+	for e := range r.resync {
+		names = append(names, e.Object.GetName())
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"api", "web"}) {
+		t.Fatalf("enqueued %v", names)
+	}
+	var web networkingv1.Ingress
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, &web); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if _, ok := web.Annotations[retryBackoffAnnotation]; ok {
+		t.Fatal("ReconcileAll must clear the retry backoff")
+	}
+}
+
+func TestReconcileAllHandler(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		auth   string
+		want   int
+	}{
+		{name: "authorized", method: http.MethodPost, auth: "Bearer secret", want: http.StatusOK},
+		{name: "wrong token", method: http.MethodPost, auth: "Bearer nope", want: http.StatusUnauthorized},
+		{name: "missing token", method: http.MethodPost, want: http.StatusUnauthorized},
+		{name: "get", method: http.MethodGet, auth: "Bearer secret", want: http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newAdminTestReconciler(t)
+			req := httptest.NewRequest(tc.method, ReconcileAllPath, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			rec := httptest.NewRecorder()
+			r.ReconcileAllHandler("secret").ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+			if enqueued := len(r.resync); tc.want != http.StatusOK && enqueued != 0 {
+				t.Fatalf("rejected request enqueued %d Ingresses", enqueued)
+			}
+		})
+	}
+}
+
+func TestReconcileAllHandlerRequiresLeader(t *testing.T) {
+	r := newAdminTestReconciler(t)
+	r.elected = make(chan struct{})
+
+	rec := httptest.NewRecorder()
+	r.ReconcileAllHandler("").ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ReconcileAllPath, nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
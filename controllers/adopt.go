@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// AdoptResult describes what Adopt did with one Ingress
+type AdoptResult struct {
+	Ingress         string
+	CertificateArns []string
+	// Skipped explains why the Ingress was left alone; empty when it was adopted
+	Skipped string
+}
+
+// Adopt brings Ingresses in namespace (all namespaces when empty) that already carry an ALB
+// certificate-arn under management: their certificates are tagged with this instance's
+// ManagedBy value, and the Ingresses get the managed annotation and the finalizer, so the
+// controller takes them over without requesting new certificates. Certificates owned by
+// another instance are never re-tagged.
+func (r *IngressReconciler) Adopt(ctx context.Context, namespace string) ([]AdoptResult, error) {
+	logger := log.FromContext(ctx)
+
+	var list networkingv1.IngressList
+	if err := r.List(ctx, &list, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ingresses: %w", err)
+	}
+
+	var results []AdoptResult
+	for i := range list.Items {
+		ingress := &list.Items[i]
+		arns := attachedCertificateArns(ingress, albCertificateArnAnnotation)
+		if len(arns) == 0 {
+			continue
+		}
+		result := AdoptResult{Ingress: ingressKey(ingress), CertificateArns: arns}
+		ctx := withAuditSubject(ctx, "adopt "+result.Ingress)
+
+		cfg := ParseIngressAnnotations(ingress.Annotations)
+		switch {
+		case cfg.Managed && r.hasFinalizer(ingress):
+			result.Skipped = "already managed"
+		case !ingress.DeletionTimestamp.IsZero():
+			result.Skipped = "being deleted"
+		default:
+			skipped, err := r.adoptCertificates(ctx, arns)
+			if err != nil {
+				return results, fmt.Errorf("failed to adopt certificates of %s: %w", result.Ingress, err)
+			}
+			result.Skipped = skipped
+		}
+		if result.Skipped != "" {
+			logger.Info("Not adopting Ingress", "ingress", result.Ingress, "reason", result.Skipped)
+			results = append(results, result)
+			continue
+		}
+
+		patch := client.MergeFrom(ingress.DeepCopy())
+		if ingress.Annotations == nil {
+			ingress.Annotations = map[string]string{}
+		}
+		ingress.Annotations["acm.tedens.dev/managed"] = "true"
+		controllerutil.AddFinalizer(ingress, r.finalizer())
+		if err := r.patchIngress(ctx, ingress, patch); err != nil {
+			return results, fmt.Errorf("failed to annotate %s: %w", result.Ingress, err)
+		}
+		logger.Info("Adopted Ingress", "ingress", result.Ingress, "arns", arns)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// adoptCertificates tags every certificate in arns with this instance's ManagedBy value and
+// RequiredTags, as a requested certificate is. It returns a reason instead when any of them is
+// missing or owned by another instance, before tagging any.
+func (r *IngressReconciler) adoptCertificates(ctx context.Context, arns []string) (string, error) {
+	var untagged []string
+	for _, arn := range arns {
+		tags, err := r.acm(ctx).ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{
+			CertificateArn: aws.String(arn),
+		})
+		if err != nil {
+			var notFound *acmtypes.ResourceNotFoundException
+			if errors.As(err, &notFound) {
+				return fmt.Sprintf("certificate %s not found", arn), nil
+			}
+			return "", err
+		}
+		values := map[string]string{}
+		for _, tag := range tags.Tags {
+			values[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		owner, tagged := values[certs.ManagedByTagKey]
+		if tagged && owner != r.managedByValue() {
+			return fmt.Sprintf("certificate %s is managed by %q", arn, owner), nil
+		}
+		if !tagged || !hasTags(values, r.RequiredTags) {
+			untagged = append(untagged, arn)
+		}
+	}
+
+	if r.IngressDryRun {
+		log.FromContext(ctx).Info("Dry-run: certificates not tagged", "arns", untagged)
+		return "", nil
+	}
+	adoptTags := []acmtypes.Tag{{Key: aws.String(certs.ManagedByTagKey), Value: aws.String(r.managedByValue())}}
+	for key, value := range r.RequiredTags {
+		if key != certs.ManagedByTagKey {
+			adoptTags = append(adoptTags, acmtypes.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+	}
+	for _, arn := range untagged {
+		_, err := r.acm(ctx).AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+			CertificateArn: aws.String(arn),
+			Tags:           adoptTags,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to tag certificate %s: %w", arn, err)
+		}
+	}
+	return "", nil
+}
+
+// hasTags reports whether values carries every tag in required with the same value
+func hasTags(values, required map[string]string) bool {
+	for key, value := range required {
+		if got, ok := values[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
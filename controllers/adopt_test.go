@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestAdopt(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	untagged := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, nil)
+	foreign := fakeACM.addCert("other.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "someone-else"})
+
+	legacy := newUnannotatedIngress("legacy", "app.example.com", map[string]string{
+		"alb.ingress.kubernetes.io/certificate-arn": untagged,
+	})
+	owned := newUnannotatedIngress("owned", "other.example.com", map[string]string{
+		"alb.ingress.kubernetes.io/certificate-arn": foreign,
+	})
+	plain := newUnannotatedIngress("plain", "plain.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), legacy, owned, plain)
+
+	results, err := r.Adopt(ctx, "default")
+	if err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected results for the two Ingresses with certificates, got %+v", results)
+	}
+
+	if got := fakeACM.tagValue(untagged, "ManagedBy"); got != DefaultManagedByValue {
+		t.Fatalf("adopted certificate ManagedBy = %q, want %q", got, DefaultManagedByValue)
+	}
+	if got := fakeACM.tagValue(foreign, "ManagedBy"); got != "someone-else" {
+		t.Fatalf("certificate owned by another instance must not be re-tagged, got %q", got)
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(legacy).NamespacedName, &got); err != nil {
+		t.Fatalf("get legacy: %v", err)
+	}
+	if got.Annotations["acm.tedens.dev/managed"] != "true" || !controllerutil.ContainsFinalizer(&got, DefaultFinalizerName) {
+		t.Fatalf("adopted Ingress should be annotated and finalized, got %v %v", got.Annotations, got.Finalizers)
+	}
+	if err := r.Get(ctx, requestFor(owned).NamespacedName, &got); err != nil {
+		t.Fatalf("get owned: %v", err)
+	}
+	if _, ok := got.Annotations["acm.tedens.dev/managed"]; ok {
+		t.Fatal("Ingress whose certificate belongs to another instance must be skipped")
+	}
+
+	// The adopted Ingress keeps its certificate on the next reconcile
+	if _, err := r.Reconcile(ctx, requestFor(legacy)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("adoption must not re-issue, got %d requests", len(fakeACM.requests))
+	}
+}
+
+func TestAdoptStampsRequiredTags(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	untagged := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, nil)
+	ours := fakeACM.addCert("api.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": DefaultManagedByValue})
+	ingress := newUnannotatedIngress("legacy", "app.example.com", map[string]string{
+		"alb.ingress.kubernetes.io/certificate-arn": untagged + "," + ours,
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.RequiredTags = map[string]string{"CostCenter": "42"}
+
+	if _, err := r.Adopt(ctx, "default"); err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+	for _, arn := range []string{untagged, ours} {
+		if owned, err := r.ownsCertificate(ctx, arn); err != nil || !owned {
+			t.Errorf("ownsCertificate(%s) = %v, %v after adoption, want the required tags stamped", arn, owned, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultALBIngressController is the spec.controller of IngressClasses served by the AWS
+	// Load Balancer Controller
+	DefaultALBIngressController = "ingress.k8s.aws/alb"
+	// forceNonALBAnnotation issues certificates for Ingresses of other controllers anyway
+	forceNonALBAnnotation = "acm.tedens.dev/force-non-alb"
+)
+
+// classControllers caches the spec.controller of the IngressClass an Ingress names, keyed by
+// the name, with "" for Ingresses naming none. A class that does not exist is cached as "".
+type classControllers struct {
+	mu          sync.Mutex
+	controllers map[string]string
+}
+
+func (c *classControllers) get(name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	controller, ok := c.controllers[name]
+	return controller, ok
+}
+
+func (c *classControllers) set(name, controller string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.controllers == nil {
+		c.controllers = map[string]string{}
+	}
+	c.controllers[name] = controller
+}
+
+// reset drops every class, since a change to any of them can move the default mark
+func (c *classControllers) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.controllers = nil
+}
+
+// ingressClassController returns the spec.controller of the Ingress' IngressClass, or "" when
+// it has none
+func (r *IngressReconciler) ingressClassController(ctx context.Context, ingress *networkingv1.Ingress) (string, error) {
+	name := ingressClassName(ingress)
+	if controller, ok := r.classControllers.get(name); ok {
+		return controller, nil
+	}
+	class, err := r.ingressClassFor(ctx, ingress)
+	if err != nil {
+		return "", err
+	}
+	var controller string
+	if class != nil {
+		controller = class.Spec.Controller
+	}
+	r.classControllers.set(name, controller)
+	return controller, nil
+}
+
+// albIngressControllers returns the IngressClass controllers that read the ALB annotations
+func (r *IngressReconciler) albIngressControllers() []string {
+	if controllers := r.settings().ALBIngressControllers; len(controllers) > 0 {
+		return controllers
+	}
+	return []string{DefaultALBIngressController}
+}
+
+// skipNonALBIngress reports whether the Ingress belongs to an IngressClass of a controller
+// that ignores the ALB certificate annotation, recording a Warning event when it does.
+// Ingresses without a known class get the benefit of the doubt, and provision-only or forced
+// Ingresses are never skipped.
+func (r *IngressReconciler) skipNonALBIngress(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig) (bool, error) {
+	if cfg.ProvisionOnly || cfg.ForceNonALB || !ingress.DeletionTimestamp.IsZero() {
+		return false, nil
+	}
+	controller, err := r.ingressClassController(ctx, ingress)
+	if err != nil || controller == "" || slices.Contains(r.albIngressControllers(), controller) {
+		return false, err
+	}
+	log.FromContext(ctx).Info("Ingress is not served by the AWS Load Balancer Controller, skipping", "controller", controller)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonNotALBIngress,
+			"IngressClass controller %s does not read %s, so no certificate is requested; set %s or %s to issue one anyway",
+			controller, albCertificateArnAnnotation, provisionOnlyAnnotation, forceNonALBAnnotation)
+	}
+	return true, nil
+}
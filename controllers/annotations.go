@@ -4,12 +4,16 @@ import (
 	"strings"
 	"time"
 
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 // IngressConfig defines parsed annotation values for ACM management
 type IngressConfig struct {
-	Managed             bool
+	Managed bool
+	// ManagedAnnotated is true when acm.tedens.dev/managed is set at all, so an explicit
+	// "false" can be told apart from an absent annotation
+	ManagedAnnotated    bool
 	DomainOverride      string
 	ZoneID              string
 	Wildcard            bool
@@ -18,15 +22,100 @@ type IngressConfig struct {
 	ReuseExisting       bool
 	DeleteCertOnIngress bool
 	FallbackWildcard    bool
+	KeyAlgorithm        acmtypes.KeyAlgorithm
+	// DisableCTLogging opts the certificate out of certificate transparency logging
+	DisableCTLogging bool
+	Tags             map[string]string
+	// DNSProvider selects where validation records are written (route53, cloudflare, manual/none)
+	DNSProvider string
+	// RoleARN is an IAM role assumed for the Ingress' AWS calls
+	RoleARN string
+	// AWSProfile is a shared config profile for the Ingress' AWS calls
+	AWSProfile string
+	// ReplicaRegions are extra regions that get a certificate with the same names
+	ReplicaRegions []string
+	// NamesFromConfigMap names a ConfigMap in the Ingress' namespace supplying domain and SANs
+	NamesFromConfigMap string
+	// DomainTemplate and SANTemplate are Go templates over the Ingress' metadata rendered into
+	// the primary domain and extra SANs
+	DomainTemplate string
+	SANTemplate    string
+	// PrimaryRuleIndex is the raw acm.tedens.dev/primary-rule-index selecting the rule whose
+	// host is the primary domain
+	PrimaryRuleIndex string
+	// ImportFromSecret names a TLS Secret in the Ingress' namespace whose certificate is
+	// imported into ACM instead of requesting one
+	ImportFromSecret string
+	// PinPrivateKey refuses to re-import the Secret once its private key changed; certificates
+	// issued by ACM get a new key on every issuance and renewal, so it only applies to imports
+	PinPrivateKey bool
+	// OCSPStapling is acm.tedens.dev/ocsp-stapling, which nothing can honour on an ALB
+	OCSPStapling bool
+	// IncludeWWW adds www.<domain> to the SANs of apex domains; IncludeWWWAnnotated is true
+	// when acm.tedens.dev/include-www is set, so --include-www only applies where it is not
+	IncludeWWW          bool
+	IncludeWWWAnnotated bool
+	// IncludeWildcardSAN adds *.<domain> for apex domains, or *.<parent> otherwise, to the SANs
+	IncludeWildcardSAN bool
+	// ProvisionOnly records certificates in acm.tedens.dev/certificate-arn instead of the ALB
+	// certificate-arn annotation
+	ProvisionOnly bool
+	// DomainSuffix is appended to hosts, the domain and SANs without a dot; ingressConfig
+	// defaults it to --domain-suffix and qualifies the SANs
+	DomainSuffix string
+	// DomainFrom is acm.tedens.dev/domain-from; "status-lb" takes the domain of an Ingress
+	// without rule hosts from its load balancer hostname
+	DomainFrom string
+	// ConfigureHTTPS adds an HTTPS listener to alb.ingress.kubernetes.io/listen-ports with the
+	// certificate ARN, and SSLRedirect the redirect to it
+	ConfigureHTTPS bool
+	SSLRedirect    bool
+	// ForceNonALB requests certificates for Ingresses of IngressClasses that are not served by
+	// the AWS Load Balancer Controller
+	ForceNonALB bool
+	// DeletionProtection keeps the certificate when the Ingress is deleted, whatever
+	// DeleteCertOnIngress says
+	DeletionProtection bool
+	// RenewBefore is the renewal margin from acm.tedens.dev/renew-before; zero uses the default
+	RenewBefore time.Duration
 }
 
+// AnnotationPrefix is the prefix shared by every annotation the controller reads
+const AnnotationPrefix = "acm.tedens.dev/"
+
+// deletionProtectionAnnotation keeps the certificate of a deleted Ingress, overriding
+// acm.tedens.dev/delete-cert-on-ingress-delete
+const deletionProtectionAnnotation = "acm.tedens.dev/deletion-protection"
+
 // DefaultCertTTL is used when no TTL is specified (1 year)
 var DefaultCertTTL = 365 * 24 * time.Hour
 
 // ParseIngressAnnotations parses acm.tedens.dev/* annotations into a config struct
 func ParseIngressAnnotations(annotations map[string]string) IngressConfig {
+	return ParseIngressAnnotationsWithDefaults(annotations, nil)
+}
+
+// ParseIngressAnnotationsWithDefaults parses annotations on top of cluster-wide defaults.
+// Defaults are keyed by annotation name without the acm.tedens.dev/ prefix (e.g. "cert-ttl")
+// and only apply where the Ingress does not set the annotation itself. The managed flag
+// cannot be defaulted.
+func ParseIngressAnnotationsWithDefaults(annotations map[string]string, defaults map[string]string) IngressConfig {
 	logger := logf.Log.WithName("annotations")
 
+	if len(defaults) > 0 {
+		merged := make(map[string]string, len(annotations)+len(defaults))
+		for key, value := range defaults {
+			if key == "managed" {
+				continue
+			}
+			merged[AnnotationPrefix+key] = value
+		}
+		for key, value := range annotations {
+			merged[key] = value
+		}
+		annotations = merged
+	}
+
 	rawWildcard := strings.ToLower(annotations["acm.tedens.dev/wildcard"])
 	if rawWildcard == "true" {
 		logger.Info("Annotation overrides default: wildcard enabled")
@@ -37,14 +126,39 @@ func ParseIngressAnnotations(annotations map[string]string) IngressConfig {
 		logger.Info("Annotation overrides default: delete cert on ingress delete enabled")
 	}
 
+	_, managedAnnotated := annotations["acm.tedens.dev/managed"]
+	rawIncludeWWW, includeWWWAnnotated := annotations[includeWWWAnnotation]
+
 	cfg := IngressConfig{
 		Managed:             annotations["acm.tedens.dev/managed"] == "true",
+		ManagedAnnotated:    managedAnnotated,
 		DomainOverride:      annotations["acm.tedens.dev/domain"],
 		ZoneID:              annotations["acm.tedens.dev/zone-id"],
 		Wildcard:            rawWildcard == "true",
 		ReuseExisting:       annotations["acm.tedens.dev/reuse-existing"] != "false",
 		DeleteCertOnIngress: rawDelete == "true",
 		FallbackWildcard:    annotations["acm.tedens.dev/fallback-wildcard"] == "true",
+		DNSProvider:         strings.ToLower(strings.TrimSpace(annotations["acm.tedens.dev/dns-provider"])),
+		RoleARN:             strings.TrimSpace(annotations[roleArnAnnotation]),
+		AWSProfile:          strings.TrimSpace(annotations[awsProfileAnnotation]),
+		ReplicaRegions:      parseReplicaRegions(annotations[replicateRegionsAnnotation], strings.ToLower(strings.TrimSpace(annotations[replicateToUSEast1Annotation])) == "true"),
+		NamesFromConfigMap:  strings.TrimSpace(annotations[namesFromConfigMapAnnotation]),
+		DomainTemplate:      strings.TrimSpace(annotations[domainTemplateAnnotation]),
+		SANTemplate:         strings.TrimSpace(annotations[sanTemplateAnnotation]),
+		PrimaryRuleIndex:    strings.TrimSpace(annotations[primaryRuleIndexAnnotation]),
+		ImportFromSecret:    strings.TrimSpace(annotations[importFromSecretAnnotation]),
+		PinPrivateKey:       strings.ToLower(strings.TrimSpace(annotations[pinPrivateKeyAnnotation])) == "true",
+		OCSPStapling:        strings.ToLower(strings.TrimSpace(annotations[ocspStaplingAnnotation])) == "true",
+		IncludeWWW:          strings.ToLower(strings.TrimSpace(rawIncludeWWW)) == "true",
+		IncludeWWWAnnotated: includeWWWAnnotated,
+		IncludeWildcardSAN:  strings.ToLower(strings.TrimSpace(annotations[includeWildcardSANAnnotation])) == "true",
+		ProvisionOnly:       strings.ToLower(strings.TrimSpace(annotations[provisionOnlyAnnotation])) == "true",
+		DomainSuffix:        parseDomainSuffix(annotations[domainSuffixAnnotation]),
+		DomainFrom:          strings.ToLower(strings.TrimSpace(annotations[domainFromAnnotation])),
+		ConfigureHTTPS:      strings.ToLower(strings.TrimSpace(annotations[configureHTTPSAnnotation])) == "true",
+		SSLRedirect:         strings.ToLower(strings.TrimSpace(annotations[sslRedirectAnnotation])) == "true",
+		ForceNonALB:         strings.ToLower(strings.TrimSpace(annotations[forceNonALBAnnotation])) == "true",
+		DeletionProtection:  strings.ToLower(strings.TrimSpace(annotations[deletionProtectionAnnotation])) == "true",
 	}
 
 	// Parse SANs
@@ -66,5 +180,43 @@ func ParseIngressAnnotations(annotations map[string]string) IngressConfig {
 		cfg.CertTTL = DefaultCertTTL
 	}
 
+	// An invalid value is reported by warnRenewBefore
+	if dur, ok := parseRenewBefore(annotations[renewBeforeAnnotation]); ok {
+		cfg.RenewBefore = dur
+	}
+
+	if alg, ok := annotations["acm.tedens.dev/key-algorithm"]; ok {
+		if isValidKeyAlgorithm(alg) {
+			cfg.KeyAlgorithm = acmtypes.KeyAlgorithm(alg)
+		} else {
+			logger.Info("Ignoring unsupported key algorithm", "keyAlgorithm", alg)
+		}
+	}
+
+	cfg.DisableCTLogging = strings.ToLower(annotations["acm.tedens.dev/certificate-transparency-logging"]) == "disabled"
+
+	// Parse extra certificate tags (key=value,key=value)
+	if tagStr, ok := annotations["acm.tedens.dev/tags"]; ok {
+		cfg.Tags = map[string]string{}
+		for _, pair := range strings.Split(tagStr, ",") {
+			key, value, found := strings.Cut(pair, "=")
+			key = strings.TrimSpace(key)
+			if !found || key == "" {
+				logger.Info("Ignoring malformed tag", "tag", pair)
+				continue
+			}
+			cfg.Tags[key] = strings.TrimSpace(value)
+		}
+	}
+
 	return cfg
 }
+
+func isValidKeyAlgorithm(alg string) bool {
+	for _, valid := range acmtypes.KeyAlgorithm("").Values() {
+		if string(valid) == alg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+func TestParseIngressAnnotationsWithDefaults(t *testing.T) {
+	defaults := map[string]string{
+		"managed":                          "true",
+		"cert-ttl":                         "720h",
+		"key-algorithm":                    "EC_prime256v1",
+		"certificate-transparency-logging": "disabled",
+		"tags":                             "team=platform,env=prod",
+	}
+	annotations := map[string]string{
+		"acm.tedens.dev/key-algorithm": "RSA_2048",
+	}
+
+	cfg := ParseIngressAnnotationsWithDefaults(annotations, defaults)
+	if cfg.Managed {
+		t.Fatal("managed must not be defaulted by policy")
+	}
+	if cfg.CertTTL != 720*time.Hour {
+		t.Fatalf("CertTTL = %s, want policy default 720h", cfg.CertTTL)
+	}
+	if cfg.KeyAlgorithm != acmtypes.KeyAlgorithmRsa2048 {
+		t.Fatalf("KeyAlgorithm = %q, annotation should override policy", cfg.KeyAlgorithm)
+	}
+	if !cfg.DisableCTLogging {
+		t.Fatal("expected CT logging to be disabled by policy")
+	}
+	if cfg.Tags["team"] != "platform" || cfg.Tags["env"] != "prod" {
+		t.Fatalf("unexpected tags %v", cfg.Tags)
+	}
+}
+
+func TestParseIngressAnnotationsRejectsUnknownKeyAlgorithm(t *testing.T) {
+	cfg := ParseIngressAnnotations(map[string]string{"acm.tedens.dev/key-algorithm": "DSA_1024"})
+	if cfg.KeyAlgorithm != "" {
+		t.Fatalf("KeyAlgorithm = %q, want unset for unsupported value", cfg.KeyAlgorithm)
+	}
+}
+
+func TestParseManagedAnnotated(t *testing.T) {
+	if cfg := ParseIngressAnnotations(nil); cfg.Managed || cfg.ManagedAnnotated {
+		t.Fatalf("absent annotation: %+v", cfg)
+	}
+	if cfg := ParseIngressAnnotations(map[string]string{"acm.tedens.dev/managed": "false"}); cfg.Managed || !cfg.ManagedAnnotated {
+		t.Fatalf("explicit false should be annotated but unmanaged: %+v", cfg)
+	}
+	if cfg := ParseIngressAnnotations(map[string]string{"acm.tedens.dev/managed": "true"}); !cfg.Managed || !cfg.ManagedAnnotated {
+		t.Fatalf("explicit true: %+v", cfg)
+	}
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// AuditEntry is one line of the audit log, written for every mutating AWS call and Ingress write
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Subject   string    `json:"subject,omitempty"`
+	// Target is what the operation changed: a certificate ARN, the validation record names or
+	// the namespace/name of an Ingress
+	Target  string         `json:"target,omitempty"`
+	Inputs  map[string]any `json:"inputs"`
+	Result  map[string]any `json:"result,omitempty"`
+	Outcome string         `json:"outcome"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// AuditLogger appends AuditEntry values as JSON lines to a writer
+type AuditLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// NewAuditLogger returns an AuditLogger writing to w
+func NewAuditLogger(w io.Writer) *AuditLogger {
+	return &AuditLogger{w: w, now: time.Now}
+}
+
+func (a *AuditLogger) record(ctx context.Context, operation, target string, inputs, result map[string]any, err error) {
+	entry := AuditEntry{
+		Time:      a.now().UTC(),
+		Operation: operation,
+		Subject:   auditSubject(ctx),
+		Target:    target,
+		Inputs:    inputs,
+		Result:    result,
+		Outcome:   "success",
+	}
+	if err != nil {
+		entry.Outcome = "error"
+		entry.Error = err.Error()
+		entry.Result = nil
+	}
+
+	data, marshalErr := json.Marshal(entry)
+	if marshalErr == nil {
+		a.mu.Lock()
+		_, marshalErr = a.w.Write(append(data, '\n'))
+		a.mu.Unlock()
+	}
+	if marshalErr != nil {
+		log.FromContext(ctx).Error(marshalErr, "failed to write audit entry", "operation", operation)
+	}
+}
+
+type auditSubjectKey struct{}
+
+// withAuditSubject names what triggered the AWS calls made with ctx, usually namespace/name of an Ingress
+func withAuditSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, auditSubjectKey{}, subject)
+}
+
+func auditSubject(ctx context.Context) string {
+	subject, _ := ctx.Value(auditSubjectKey{}).(string)
+	return subject
+}
+
+// ACM wraps client so every mutating call is audited. Every ACMAPI method is implemented
+// explicitly, so adding one to the interface fails to compile until it is classified here.
+func (a *AuditLogger) ACM(client ACMAPI) ACMAPI {
+	return &auditedACM{client: client, audit: a}
+}
+
+// Route53 wraps client so every mutating call is audited
+func (a *AuditLogger) Route53(client Route53API) Route53API {
+	return &auditedRoute53{client: client, audit: a}
+}
+
+type auditedACM struct {
+	client ACMAPI
+	audit  *AuditLogger
+}
+
+func (c *auditedACM) RequestCertificate(ctx context.Context, in *acm.RequestCertificateInput, optFns ...func(*acm.Options)) (*acm.RequestCertificateOutput, error) {
+	out, err := c.client.RequestCertificate(ctx, in, optFns...)
+	inputs := map[string]any{
+		"domain":                  aws.ToString(in.DomainName),
+		"subjectAlternativeNames": in.SubjectAlternativeNames,
+		"keyAlgorithm":            string(in.KeyAlgorithm),
+	}
+	var result map[string]any
+	target := aws.ToString(in.DomainName)
+	if out != nil {
+		target = aws.ToString(out.CertificateArn)
+		result = map[string]any{"certificateArn": target}
+	}
+	c.audit.record(ctx, "acm:RequestCertificate", target, inputs, result, err)
+	return out, err
+}
+
+func (c *auditedACM) DeleteCertificate(ctx context.Context, in *acm.DeleteCertificateInput, optFns ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error) {
+	out, err := c.client.DeleteCertificate(ctx, in, optFns...)
+	c.audit.record(ctx, "acm:DeleteCertificate", aws.ToString(in.CertificateArn), map[string]any{"certificateArn": aws.ToString(in.CertificateArn)}, nil, err)
+	return out, err
+}
+
+func (c *auditedACM) AddTagsToCertificate(ctx context.Context, in *acm.AddTagsToCertificateInput, optFns ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	out, err := c.client.AddTagsToCertificate(ctx, in, optFns...)
+	tags := make([]string, 0, len(in.Tags))
+	for _, tag := range in.Tags {
+		tags = append(tags, aws.ToString(tag.Key)+"="+aws.ToString(tag.Value))
+	}
+	c.audit.record(ctx, "acm:AddTagsToCertificate", aws.ToString(in.CertificateArn), map[string]any{"certificateArn": aws.ToString(in.CertificateArn), "tags": tags}, nil, err)
+	return out, err
+}
+
+func (c *auditedACM) ImportCertificate(ctx context.Context, in *acm.ImportCertificateInput, optFns ...func(*acm.Options)) (*acm.ImportCertificateOutput, error) {
+	out, err := c.client.ImportCertificate(ctx, in, optFns...)
+	var result map[string]any
+	target := aws.ToString(in.CertificateArn)
+	if out != nil {
+		target = aws.ToString(out.CertificateArn)
+		result = map[string]any{"certificateArn": target}
+	}
+	c.audit.record(ctx, "acm:ImportCertificate", target, map[string]any{"certificateArn": aws.ToString(in.CertificateArn)}, result, err)
+	return out, err
+}
+
+// Read-only ACM calls are not audited
+
+func (c *auditedACM) DescribeCertificate(ctx context.Context, in *acm.DescribeCertificateInput, optFns ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error) {
+	return c.client.DescribeCertificate(ctx, in, optFns...)
+}
+
+func (c *auditedACM) ListCertificates(ctx context.Context, in *acm.ListCertificatesInput, optFns ...func(*acm.Options)) (*acm.ListCertificatesOutput, error) {
+	return c.client.ListCertificates(ctx, in, optFns...)
+}
+
+func (c *auditedACM) ListTagsForCertificate(ctx context.Context, in *acm.ListTagsForCertificateInput, optFns ...func(*acm.Options)) (*acm.ListTagsForCertificateOutput, error) {
+	return c.client.ListTagsForCertificate(ctx, in, optFns...)
+}
+
+type auditedRoute53 struct {
+	client Route53API
+	audit  *AuditLogger
+}
+
+func (c *auditedRoute53) ChangeResourceRecordSets(ctx context.Context, in *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	out, err := c.client.ChangeResourceRecordSets(ctx, in, optFns...)
+	var changes, names []string
+	if in.ChangeBatch != nil {
+		for _, change := range in.ChangeBatch.Changes {
+			if set := change.ResourceRecordSet; set != nil {
+				values := make([]string, 0, len(set.ResourceRecords))
+				for _, record := range set.ResourceRecords {
+					values = append(values, aws.ToString(record.Value))
+				}
+				changes = append(changes, fmt.Sprintf("%s %s %s %s", change.Action, aws.ToString(set.Name), set.Type, strings.Join(values, ",")))
+				names = append(names, aws.ToString(set.Name))
+			}
+		}
+	}
+	c.audit.record(ctx, "route53:ChangeResourceRecordSets", strings.Join(names, ","), map[string]any{"hostedZoneId": aws.ToString(in.HostedZoneId), "changes": changes}, nil, err)
+	return out, err
+}
+
+// Read-only Route 53 calls are not audited
+
+func (c *auditedRoute53) ListHostedZones(ctx context.Context, in *route53.ListHostedZonesInput, optFns ...func(*route53.Options)) (*route53.ListHostedZonesOutput, error) {
+	return c.client.ListHostedZones(ctx, in, optFns...)
+}
+
+func (c *auditedRoute53) ListTagsForResource(ctx context.Context, in *route53.ListTagsForResourceInput, optFns ...func(*route53.Options)) (*route53.ListTagsForResourceOutput, error) {
+	return c.client.ListTagsForResource(ctx, in, optFns...)
+}
+
+// IngressPatched audits a patch of ingress that changes annotations, with each changed
+// annotation's new value or nil when it was removed. Patches without annotations are not
+// audited.
+func (a *AuditLogger) IngressPatched(ctx context.Context, ingress *networkingv1.Ingress, patch client.Patch, err error) {
+	data, dataErr := patch.Data(ingress)
+	if dataErr != nil {
+		log.FromContext(ctx).Error(dataErr, "failed to compute the audited Ingress patch")
+		return
+	}
+	var merge struct {
+		Metadata struct {
+			Annotations map[string]*string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if json.Unmarshal(data, &merge) != nil || len(merge.Metadata.Annotations) == 0 {
+		return
+	}
+	annotations := make(map[string]any, len(merge.Metadata.Annotations))
+	for key, value := range merge.Metadata.Annotations {
+		if value == nil {
+			annotations[key] = nil
+		} else {
+			annotations[key] = *value
+		}
+	}
+	a.record(ctx, "kubernetes:PatchIngress", ingressKey(ingress), map[string]any{"annotations": annotations}, nil, err)
+}
+
+// IngressUpdated audits an update of ingress, which the controller only makes to change its
+// finalizers
+func (a *AuditLogger) IngressUpdated(ctx context.Context, ingress *networkingv1.Ingress, err error) {
+	a.record(ctx, "kubernetes:UpdateIngress", ingressKey(ingress), map[string]any{"finalizers": ingress.Finalizers}, nil, err)
+}
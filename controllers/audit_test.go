@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tedens/acm-manager/pkg/certs"
+)
+
+func TestAuditLogRecordsMutatingCalls(t *testing.T) {
+	var buf bytes.Buffer
+	audit := NewAuditLogger(&buf)
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, audit.ACM(newFakeACM()), nil, ingress)
+	r.DNSProvider = certs.NewRoute53Provider(audit.Route53(newFakeRoute53("example.com")))
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	var operations []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("audit line %q is not JSON: %v", line, err)
+		}
+		if entry.Subject != "default/web" || entry.Outcome != "success" {
+			t.Fatalf("unexpected audit entry %+v", entry)
+		}
+		operations = append(operations, entry.Operation)
+		if entry.Operation == "acm:RequestCertificate" && (entry.Inputs["domain"] != "app.example.com" || entry.Result["certificateArn"] != certificateArnOf(t, r, ingress)) {
+			t.Fatalf("unexpected RequestCertificate entry %+v", entry)
+		}
+	}
+	want := []string{"acm:RequestCertificate", "route53:ChangeResourceRecordSets", "acm:AddTagsToCertificate"}
+	if strings.Join(operations, ",") != strings.Join(want, ",") {
+		t.Fatalf("audited operations = %v, want %v", operations, want)
+	}
+}
+
+func TestAuditLogRecordsIngressWrites(t *testing.T) {
+	var buf bytes.Buffer
+	audit := NewAuditLogger(&buf)
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, audit.ACM(newFakeACM()), nil, ingress)
+	r.DNSProvider = certs.NewRoute53Provider(audit.Route53(newFakeRoute53("example.com")))
+	r.Audit = audit
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	certArn := certificateArnOf(t, r, ingress)
+
+	targets := map[string]string{}
+	var attached bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("audit line %q is not JSON: %v", line, err)
+		}
+		targets[entry.Operation] = entry.Target
+		if entry.Operation != "kubernetes:PatchIngress" {
+			continue
+		}
+		annotations, _ := entry.Inputs["annotations"].(map[string]any)
+		if len(annotations) == 0 {
+			t.Fatalf("an audited patch must name the annotations it changes, got %+v", entry)
+		}
+		if annotations["alb.ingress.kubernetes.io/certificate-arn"] == certArn {
+			attached = true
+		}
+	}
+	if !attached {
+		t.Fatalf("expected the certificate ARN annotation write to be audited, got %s", buf.String())
+	}
+	if targets["kubernetes:UpdateIngress"] != "default/web" || targets["kubernetes:PatchIngress"] != "default/web" {
+		t.Fatalf("Ingress writes must target the Ingress, got %v", targets)
+	}
+	if targets["acm:RequestCertificate"] != certArn || targets["route53:ChangeResourceRecordSets"] != "_acme.app.example.com." {
+		t.Fatalf("AWS calls must target the certificate and record, got %v", targets)
+	}
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/tedens/acm-manager/internal/version"
+	"github.com/tedens/acm-manager/pkg/certs"
+)
+
+// ACMAPI is the subset of the ACM client used by the controller
+type ACMAPI = certs.ACMAPI
+
+// Route53API is the subset of the Route 53 client used by the controller
+type Route53API = certs.Route53API
+
+// newRetryer returns a standard SDK retryer with the given limits; zero keeps the SDK default
+func newRetryer(maxAttempts int, maxBackoff time.Duration) func() aws.Retryer {
+	return func() aws.Retryer {
+		return retry.NewStandard(func(o *retry.StandardOptions) {
+			if maxAttempts > 0 {
+				o.MaxAttempts = maxAttempts
+			}
+			if maxBackoff > 0 {
+				o.MaxBackoff = maxBackoff
+			}
+		})
+	}
+}
+
+// LoadAWSConfig loads the default AWS configuration with the controller's User-Agent and
+// retryer, asking for FIPS endpoints when useFIPS is set; extra options are applied last
+func LoadAWSConfig(ctx context.Context, maxAttempts int, maxBackoff time.Duration, useFIPS bool, extra ...func(*config.LoadOptions) error) (aws.Config, error) {
+	opts := []func(*config.LoadOptions) error{
+		// Identify the controller build in the User-Agent so CloudTrail shows which version made each call
+		config.WithAPIOptions([]func(*middleware.Stack) error{
+			awsmiddleware.AddUserAgentKeyValue("acm-manager", version.Version),
+		}),
+		config.WithRetryer(newRetryer(maxAttempts, maxBackoff)),
+	}
+	if useFIPS {
+		opts = append(opts, config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled))
+	}
+	return config.LoadDefaultConfig(ctx, append(opts, extra...)...)
+}
+
+// awsTarget selects the role and region AWS clients act with; the zero value is the
+// controller's own credentials in the default region. Profile, when set, replaces the
+// controller's configuration with that shared config profile.
+type awsTarget struct {
+	RoleARN string
+	Region  string
+	Profile string
+}
+
+// awsClients are the AWS clients used for one target. DNS is the Route 53 provider over
+// Route53, kept with the clients so its hosted zone cache is shared.
+type awsClients struct {
+	ACM     ACMAPI
+	Route53 Route53API
+	DNS     *certs.Route53Provider
+}
+
+// awsClientCache builds awsClients per target from a base configuration loaded on first use.
+// A failed load is retried with exponential backoff, so the controller keeps running while
+// its credentials are not available yet. It is safe for concurrent use by reconcile workers.
+type awsClientCache struct {
+	// loadConfig loads the base configuration; build creates the clients of a target from it
+	loadConfig func(ctx context.Context) (aws.Config, error)
+	build      func(cfg aws.Config) awsClients
+	// loadProfile loads the configuration of a shared config profile
+	loadProfile func(ctx context.Context, profile string) (aws.Config, error)
+	// assumeRole returns the credentials of roleARN assumed with cfg; nil uses STS
+	assumeRole func(cfg aws.Config, roleARN string) aws.CredentialsProvider
+	// zoneTags is the hosted zone tag filter of the Route 53 providers
+	zoneTags map[string]string
+	// negativeZoneTTL is how long the Route 53 providers cache domains without a hosted zone
+	negativeZoneTTL time.Duration
+	// now returns the current time; nil uses time.Now
+	now func() time.Time
+	// changes follows the record changes of every target's Route 53 client; nil times none
+	changes *route53ChangeFollower
+
+	baseMu   sync.Mutex
+	loaded   bool
+	base     aws.Config
+	baseErr  error
+	failures int
+	retryAt  time.Time
+
+	mu      sync.Mutex
+	clients map[awsTarget]*awsClients
+}
+
+// assumeRoleCredentials returns cached credentials of roleARN, assumed through STS with cfg
+func assumeRoleCredentials(cfg aws.Config, roleARN string) aws.CredentialsProvider {
+	return aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(newSTSClient(cfg), roleARN))
+}
+
+// newAWSClientCache returns a cache of audited SDK clients using the given retry limits, FIPS
+// endpoint setting and hosted zone tag filter
+func newAWSClientCache(maxAttempts int, maxBackoff time.Duration, useFIPS bool, audit *AuditLogger, zoneTags map[string]string) *awsClientCache {
+	changes := newRoute53ChangeFollower()
+	return &awsClientCache{
+		zoneTags: zoneTags,
+		changes:  changes,
+		loadConfig: func(ctx context.Context) (aws.Config, error) {
+			cfg, err := LoadAWSConfig(ctx, maxAttempts, maxBackoff, useFIPS)
+			if err != nil {
+				return cfg, err
+			}
+			// Credentials are resolved lazily, so retrieve them once to learn whether they exist
+			if cfg.Credentials != nil {
+				if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+					return cfg, fmt.Errorf("retrieve credentials: %w", err)
+				}
+			}
+			return cfg, nil
+		},
+		loadProfile: func(ctx context.Context, profile string) (aws.Config, error) {
+			return LoadAWSConfig(ctx, maxAttempts, maxBackoff, useFIPS, config.WithSharedConfigProfile(profile))
+		},
+		build: func(cfg aws.Config) awsClients {
+			sdkRoute53 := newRoute53Client(cfg, countRoute53Throttles)
+			var route53Client Route53API = newMeteredRoute53(sdkRoute53, sdkRoute53, changes)
+			if audit != nil {
+				route53Client = audit.Route53(route53Client)
+			}
+			return awsClients{ACM: NewACMClient(cfg), Route53: route53Client}
+		},
+	}
+}
+
+// loadBase returns the base configuration, loading it unless it was loaded already or the
+// last failed load is still backing off
+func (c *awsClientCache) loadBase(ctx context.Context) (aws.Config, error) {
+	c.baseMu.Lock()
+	defer c.baseMu.Unlock()
+	if c.loaded {
+		return c.base, nil
+	}
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	if c.failures == 0 || !now().Before(c.retryAt) {
+		cfg, err := c.loadConfig(ctx)
+		if err == nil {
+			c.base, c.loaded, c.baseErr, c.failures = cfg, true, nil, 0
+			return cfg, nil
+		}
+		c.baseErr = err
+		c.failures++
+		c.retryAt = now().Add(awsCredentialsBackoff(c.failures))
+	}
+	return aws.Config{}, &awsCredentialsError{err: c.baseErr, retryAt: c.retryAt}
+}
+
+// get returns the clients for target, building them on first use
+func (c *awsClientCache) get(ctx context.Context, target awsTarget) (*awsClients, error) {
+	var base aws.Config
+	var err error
+	if target.Profile == "" {
+		base, err = c.loadBase(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if clients := c.cached(target); clients != nil {
+		return clients, nil
+	}
+	if target.Profile != "" {
+		// Loaded without c.mu, which a slow profile would hold for every reconcile. Only used
+		// with --allow-profile-annotation, so a failed load is simply retried.
+		base, err = c.loadProfile(ctx, target.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("load AWS profile %q: %w", target.Profile, err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Another reconcile may have built the clients while the profile loaded
+	if clients, ok := c.clients[target]; ok {
+		return clients, nil
+	}
+	cfg := base.Copy()
+	if target.Region != "" {
+		cfg.Region = target.Region
+	}
+	if target.RoleARN != "" {
+		assumeRole := c.assumeRole
+		if assumeRole == nil {
+			assumeRole = assumeRoleCredentials
+		}
+		cfg.Credentials = assumeRole(cfg, target.RoleARN)
+	}
+	clients := c.build(cfg)
+	if clients.DNS == nil && clients.Route53 != nil {
+		clients.DNS = certs.NewRoute53Provider(clients.Route53)
+		clients.DNS.ZoneTags = c.zoneTags
+		clients.DNS.NegativeCacheTTL = c.negativeZoneTTL
+		observeZoneCache(clients.DNS)
+	}
+	if c.clients == nil {
+		c.clients = map[awsTarget]*awsClients{}
+	}
+	c.clients[target] = &clients
+	return &clients, nil
+}
+
+// cached returns the clients built for target, or nil
+func (c *awsClientCache) cached(target awsTarget) *awsClients {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clients[target]
+}
+
+// route53Providers returns the Route 53 providers of every target built so far
+func (c *awsClientCache) route53Providers() []*certs.Route53Provider {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	providers := make([]*certs.Route53Provider, 0, len(c.clients))
+	for _, clients := range c.clients {
+		if clients.DNS != nil {
+			providers = append(providers, clients.DNS)
+		}
+	}
+	return providers
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+func TestNewRetryer(t *testing.T) {
+	if got := newRetryer(7, 0)().MaxAttempts(); got != 7 {
+		t.Fatalf("MaxAttempts() = %d, want 7", got)
+	}
+	if got := newRetryer(0, time.Second)().MaxAttempts(); got != retry.DefaultMaxAttempts {
+		t.Fatalf("zero MaxAttempts should keep the SDK default %d, got %d", retry.DefaultMaxAttempts, got)
+	}
+}
+
+// Run with -race: lookups from many reconcile workers must not race
+func TestAWSClientCacheConcurrentLookups(t *testing.T) {
+	var loads, builds atomic.Int32
+	cache := &awsClientCache{
+		loadConfig: func(context.Context) (aws.Config, error) {
+			loads.Add(1)
+			return aws.Config{Region: "us-west-2"}, nil
+		},
+		build: func(cfg aws.Config) awsClients {
+			builds.Add(1)
+			return awsClients{ACM: newFakeACM(), Route53: newFakeRoute53(cfg.Region)}
+		},
+	}
+
+	regions := []string{"", "us-east-1", "eu-west-1"}
+	got := make([][]*awsClients, len(regions))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for j, region := range regions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				clients, err := cache.get(context.Background(), awsTarget{Region: region})
+				if err != nil {
+					t.Errorf("get(%q): %v", region, err)
+					return
+				}
+				mu.Lock()
+				got[j] = append(got[j], clients)
+				mu.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Fatalf("base config should be loaded once, got %d", loads.Load())
+	}
+	if builds.Load() != int32(len(regions)) {
+		t.Fatalf("expected one client set per region, got %d", builds.Load())
+	}
+	for j, region := range regions {
+		for _, clients := range got[j] {
+			if clients != got[j][0] {
+				t.Fatalf("region %q returned different clients", region)
+			}
+		}
+	}
+	if zone := got[0][0].Route53.(*fakeRoute53).zones[0]; aws.ToString(zone.Name) != "us-west-2." {
+		t.Fatalf("default region should keep the base config region, got %s", aws.ToString(zone.Name))
+	}
+	if zone := got[1][0].Route53.(*fakeRoute53).zones[0]; aws.ToString(zone.Name) != "us-east-1." {
+		t.Fatalf("region override not applied, got %s", aws.ToString(zone.Name))
+	}
+}
+
+func TestAWSClientCacheLoadsProfilesWithoutBlocking(t *testing.T) {
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	cache := &awsClientCache{
+		loadConfig: func(context.Context) (aws.Config, error) { return aws.Config{Region: "us-east-1"}, nil },
+		loadProfile: func(context.Context, string) (aws.Config, error) {
+			close(loading)
+			<-release
+			return aws.Config{Region: "us-west-2"}, nil
+		},
+		build: func(cfg aws.Config) awsClients {
+			return awsClients{ACM: newFakeACM(), Route53: newFakeRoute53(cfg.Region)}
+		},
+	}
+
+	done := make(chan *awsClients)
+	go func() {
+		clients, err := cache.get(context.Background(), awsTarget{Profile: "slow"})
+		if err != nil {
+			t.Errorf("get(slow): %v", err)
+		}
+		done <- clients
+	}()
+	<-loading
+	// Other targets are served while the profile loads
+	if _, err := cache.get(context.Background(), awsTarget{}); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	close(release)
+	profile := <-done
+	if again, err := cache.get(context.Background(), awsTarget{Profile: "slow"}); err != nil || again != profile {
+		t.Fatalf("the loaded profile's clients should be cached, got %v, %v", again, err)
+	}
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Requeue delays while a certificate is pending validation with RequeuePendingValidation
+const (
+	pendingRequeueMin = 15 * time.Second
+	pendingRequeueMax = 5 * time.Minute
+)
+
+// pendingBackoff tracks a per-Ingress exponential requeue delay for pending certificates. The
+// delay doubles while the observed validation state stays the same and resets when it changes.
+type pendingBackoff struct {
+	mu      sync.Mutex
+	entries map[types.UID]pendingBackoffEntry
+}
+
+type pendingBackoffEntry struct {
+	observed string
+	delay    time.Duration
+}
+
+// next returns the delay before the Ingress is checked again, given its observed state
+func (b *pendingBackoff) next(uid types.UID, observed string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.entries == nil {
+		b.entries = map[types.UID]pendingBackoffEntry{}
+	}
+	entry, ok := b.entries[uid]
+	if !ok || entry.observed != observed {
+		entry = pendingBackoffEntry{observed: observed, delay: pendingRequeueMin}
+	} else {
+		entry.delay = min(entry.delay*2, pendingRequeueMax)
+	}
+	b.entries[uid] = entry
+	return entry.delay
+}
+
+// forget drops the Ingress' delay once its certificate is no longer pending
+func (b *pendingBackoff) forget(uid types.UID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.entries, uid)
+}
+
+// validationPending reports whether an Ensure of a split or group certificate left it pending
+// validation with RequeuePendingValidation. Those certificates are reused rather than resumed,
+// so a later reconcile finds the pending certificate instead of checking it again.
+func (r *IngressReconciler) validationPending(result certs.EnsureResult, err error) bool {
+	if errors.Is(err, certs.ErrValidationPending) {
+		return true
+	}
+	return err == nil && r.RequeuePendingValidation && result.Status == acmtypes.CertificateStatusPendingValidation
+}
+
+// Defaults for waiting on managed Ingresses that have no host yet
+const (
+	DefaultNoHostRequeueInterval = 10 * time.Second
+	DefaultNoHostRequeueAttempts = 6
+)
+
+// hostWaits counts how often each managed Ingress without a host has been requeued
+type hostWaits struct {
+	mu     sync.Mutex
+	counts map[types.UID]int
+}
+
+// next records another check of the Ingress without a host and returns how many there were
+func (w *hostWaits) next(uid types.UID) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.counts == nil {
+		w.counts = map[types.UID]int{}
+	}
+	w.counts[uid]++
+	return w.counts[uid]
+}
+
+// forget drops the Ingress' count once it has a host
+func (w *hostWaits) forget(uid types.UID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.counts, uid)
+}
+
+// waitForHost returns how long to wait before checking a managed Ingress without a host
+// again, in case another controller fills its rules in. Once NoHostRequeueAttempts checks
+// have passed it records a NoHosts Warning and returns zero.
+func (r *IngressReconciler) waitForHost(ingress *networkingv1.Ingress) time.Duration {
+	settings := r.settings()
+	if settings.NoHostRequeueAttempts <= 0 || !ingress.DeletionTimestamp.IsZero() {
+		return 0
+	}
+	checks := r.hostWaits.next(ingress.UID)
+	if checks <= settings.NoHostRequeueAttempts {
+		if settings.NoHostRequeueInterval > 0 {
+			return settings.NoHostRequeueInterval
+		}
+		return DefaultNoHostRequeueInterval
+	}
+	if checks == settings.NoHostRequeueAttempts+1 {
+		r.warnOnce(ingress, ReasonNoHosts, "",
+			"Ingress still has no host after %d checks; no certificate is requested until one is set", settings.NoHostRequeueAttempts)
+	}
+	return 0
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestPendingBackoffSchedule(t *testing.T) {
+	var b pendingBackoff
+	want := []time.Duration{15 * time.Second, 30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, delay := range want {
+		if got := b.next("uid", "a PENDING_VALIDATION"); got != delay {
+			t.Fatalf("attempt %d: got %s, want %s", i+1, got, delay)
+		}
+	}
+
+	// A change in the observed state starts over
+	if got := b.next("uid", "a SUCCESS"); got != pendingRequeueMin {
+		t.Fatalf("changed state should reset the backoff, got %s", got)
+	}
+	if got := b.next("other", "a PENDING_VALIDATION"); got != pendingRequeueMin {
+		t.Fatalf("backoff must be per object, got %s", got)
+	}
+	b.forget("uid")
+	if got := b.next("uid", "a SUCCESS"); got != pendingRequeueMin {
+		t.Fatalf("forgotten object should start over, got %s", got)
+	}
+}
+
+func TestReconcileRequeuesPendingValidation(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.holdPending = true
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/reuse-existing": "false"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.RequeuePendingValidation = true
+
+	for i, want := range []time.Duration{15 * time.Second, 30 * time.Second} {
+		result, err := r.Reconcile(ctx, requestFor(ingress))
+		if err != nil {
+			t.Fatalf("Reconcile %d: %v", i+1, err)
+		}
+		if result.RequeueAfter != want {
+			t.Fatalf("Reconcile %d: expected requeue after %s, got %s", i+1, want, result.RequeueAfter)
+		}
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("requeued reconciles must resume the pending certificate, got %d requests", len(fakeACM.requests))
+	}
+
+	// Per-name progress resets the backoff
+	fakeACM.mu.Lock()
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{"app.example.com": acmtypes.DomainStatusSuccess}
+	fakeACM.mu.Unlock()
+	result, err := r.Reconcile(ctx, requestFor(ingress))
+	if err != nil || result.RequeueAfter != pendingRequeueMin {
+		t.Fatalf("expected the backoff to reset after progress, got %s, %v", result.RequeueAfter, err)
+	}
+
+	fakeACM.mu.Lock()
+	fakeACM.holdPending = false
+	fakeACM.mu.Unlock()
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after issue: %v", err)
+	}
+	if got := certificateArnOf(t, r, ingress); got == "" {
+		t.Fatal("expected the issued certificate to be attached")
+	}
+}
+
+func TestIngressWaitsForDelayedHost(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "", nil)
+	ingress.UID = "uid-web"
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.NoHostRequeueAttempts = 3
+	r.NoHostRequeueInterval = 2 * time.Second
+
+	result, err := r.Reconcile(ctx, requestFor(ingress))
+	if err != nil || result.RequeueAfter != 2*time.Second {
+		t.Fatalf("an Ingress without a host should be checked again, got %+v, %v", result, err)
+	}
+
+	// Another controller fills the host in before the next check
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	got.Spec.Rules[0].Host = "app.example.com"
+	if err := r.Update(ctx, &got); err != nil {
+		t.Fatalf("update ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile with a host: %v", err)
+	}
+	if len(fakeACM.requests) != 1 || aws.ToString(fakeACM.requests[0].DomainName) != "app.example.com" {
+		t.Fatalf("expected a certificate for the delayed host, got %d requests", len(fakeACM.requests))
+	}
+	if certificateArnOf(t, r, ingress) == "" {
+		t.Fatal("expected the certificate to be attached")
+	}
+}
+
+func TestIngressWithoutHostGivesUp(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "", nil)
+	ingress.UID = "uid-web"
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.NoHostRequeueAttempts = 2
+
+	for i := 1; i <= 4; i++ {
+		result, err := r.Reconcile(ctx, requestFor(ingress))
+		if err != nil {
+			t.Fatalf("Reconcile %d: %v", i, err)
+		}
+		if requeued := result.RequeueAfter == DefaultNoHostRequeueInterval; requeued != (i <= 2) {
+			t.Fatalf("check %d: unexpected result %+v", i, result)
+		}
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+ReasonNoHosts) {
+		t.Fatalf("expected one %s warning, got %v", ReasonNoHosts, events)
+	}
+}
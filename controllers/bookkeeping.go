@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// bookkeepingAnnotation holds the controller's own records about an Ingress as one compact JSON
+// object, so they change together in a single write
+const bookkeepingAnnotation = "acm.tedens.dev/state"
+
+// bookkeepingVersion is the "v" written into bookkeepingAnnotation
+const bookkeepingVersion = 1
+
+// legacyBookkeepingAnnotations were written one by one before bookkeepingAnnotation existed;
+// they are folded into it on the next reconcile
+var legacyBookkeepingAnnotations = []string{writtenCertificateArnAnnotation, pendingCertificatesAnnotation, postIssuanceHookAnnotation}
+
+// bookkeeping is the value of bookkeepingAnnotation. Fields written by a newer version are
+// kept in unknown and written back, so a downgrade neither fails nor loses them.
+type bookkeeping struct {
+	// WrittenCertificateArn is the certificate ARN value the controller last wrote, unset when
+	// nothing is tracked
+	WrittenCertificateArn *string
+	// PendingCertificateArns are certificates requested for the Ingress that never issued
+	PendingCertificateArns []string
+	// HookFired is the certificate ARN value the post-issuance hook last fired for
+	HookFired string
+	// SupersededCertificateArns are certificates a successor replaced that are deleted once
+	// nothing uses them
+	SupersededCertificateArns []string
+	// SupersededReplicaArns are replicas a successor replaced, by region, deleted once nothing
+	// uses them
+	SupersededReplicaArns map[string][]string
+	// Generation is bumped whenever the attached certificate is forgotten, so the next
+	// request gets a new idempotency token instead of ACM's deleted certificate
+	Generation int
+
+	unknown map[string]json.RawMessage
+}
+
+const (
+	bookkeepingVersionKey    = "v"
+	bookkeepingWrittenKey    = "written"
+	bookkeepingPendingKey    = "pending"
+	bookkeepingHookKey       = "hookFired"
+	bookkeepingSupersededKey = "superseded"
+	bookkeepingReplicasKey   = "supersededReplicas"
+	bookkeepingGenerationKey = "generation"
+)
+
+func (b *bookkeeping) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields == nil {
+		return fmt.Errorf("not a JSON object")
+	}
+	decode := func(key string, into any) error {
+		raw, ok := fields[key]
+		if !ok {
+			return nil
+		}
+		delete(fields, key)
+		if err := json.Unmarshal(raw, into); err != nil {
+			return fmt.Errorf("field %q: %w", key, err)
+		}
+		return nil
+	}
+	var version int
+	if err := decode(bookkeepingVersionKey, &version); err != nil {
+		return err
+	}
+	if err := decode(bookkeepingWrittenKey, &b.WrittenCertificateArn); err != nil {
+		return err
+	}
+	if err := decode(bookkeepingPendingKey, &b.PendingCertificateArns); err != nil {
+		return err
+	}
+	if err := decode(bookkeepingHookKey, &b.HookFired); err != nil {
+		return err
+	}
+	if err := decode(bookkeepingSupersededKey, &b.SupersededCertificateArns); err != nil {
+		return err
+	}
+	if err := decode(bookkeepingReplicasKey, &b.SupersededReplicaArns); err != nil {
+		return err
+	}
+	if err := decode(bookkeepingGenerationKey, &b.Generation); err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		b.unknown = fields
+	}
+	return nil
+}
+
+func (b bookkeeping) MarshalJSON() ([]byte, error) {
+	fields := map[string]any{}
+	for key, raw := range b.unknown {
+		fields[key] = raw
+	}
+	fields[bookkeepingVersionKey] = bookkeepingVersion
+	if b.WrittenCertificateArn != nil {
+		fields[bookkeepingWrittenKey] = *b.WrittenCertificateArn
+	}
+	if len(b.PendingCertificateArns) > 0 {
+		fields[bookkeepingPendingKey] = b.PendingCertificateArns
+	}
+	if b.HookFired != "" {
+		fields[bookkeepingHookKey] = b.HookFired
+	}
+	if len(b.SupersededCertificateArns) > 0 {
+		fields[bookkeepingSupersededKey] = b.SupersededCertificateArns
+	}
+	if len(b.SupersededReplicaArns) > 0 {
+		fields[bookkeepingReplicasKey] = b.SupersededReplicaArns
+	}
+	if b.Generation != 0 {
+		fields[bookkeepingGenerationKey] = b.Generation
+	}
+	return json.Marshal(fields)
+}
+
+func (b bookkeeping) empty() bool {
+	return b.WrittenCertificateArn == nil && len(b.PendingCertificateArns) == 0 && b.HookFired == "" && len(b.SupersededCertificateArns) == 0 && len(b.SupersededReplicaArns) == 0 && b.Generation == 0 && len(b.unknown) == 0
+}
+
+// parseBookkeeping reads bookkeepingAnnotation, filling fields it lacks from the legacy
+// annotations not migrated yet. A corrupt value yields the legacy fields and the error.
+func parseBookkeeping(annotations map[string]string) (bookkeeping, error) {
+	var b bookkeeping
+	var err error
+	if raw, ok := annotations[bookkeepingAnnotation]; ok {
+		if err = json.Unmarshal([]byte(raw), &b); err != nil {
+			b = bookkeeping{}
+			err = fmt.Errorf("%s is corrupt: %w", bookkeepingAnnotation, err)
+		}
+	}
+	if written, ok := annotations[writtenCertificateArnAnnotation]; ok && b.WrittenCertificateArn == nil {
+		b.WrittenCertificateArn = &written
+	}
+	if len(b.PendingCertificateArns) == 0 {
+		b.PendingCertificateArns = splitArns(annotations[pendingCertificatesAnnotation])
+	}
+	if b.HookFired == "" {
+		b.HookFired = annotations[postIssuanceHookAnnotation]
+	}
+	return b, err
+}
+
+// readBookkeeping returns the Ingress' bookkeeping, empty where it is corrupt
+func readBookkeeping(ingress *networkingv1.Ingress) bookkeeping {
+	b, _ := parseBookkeeping(ingress.GetAnnotations())
+	return b
+}
+
+// writeBookkeeping sets bookkeepingAnnotation of the Ingress to b, or removes it when b is
+// empty, and drops the legacy annotations it replaces
+func writeBookkeeping(ingress *networkingv1.Ingress, b bookkeeping) {
+	for _, key := range legacyBookkeepingAnnotations {
+		delete(ingress.Annotations, key)
+	}
+	if b.empty() {
+		delete(ingress.Annotations, bookkeepingAnnotation)
+		return
+	}
+	// Every field marshals, including the unknown ones, which were parsed as valid JSON
+	data, _ := json.Marshal(b)
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+	ingress.Annotations[bookkeepingAnnotation] = string(data)
+}
+
+// updateBookkeeping applies update to the Ingress' bookkeeping in memory
+func updateBookkeeping(ingress *networkingv1.Ingress, update func(*bookkeeping)) {
+	b := readBookkeeping(ingress)
+	update(&b)
+	writeBookkeeping(ingress, b)
+}
+
+// migrateBookkeeping folds the legacy bookkeeping annotations of the Ingress into
+// bookkeepingAnnotation and removes them, in one patch. A corrupt value is discarded with a
+// BookkeepingDiscarded Warning event instead of failing the reconcile.
+func (r *IngressReconciler) migrateBookkeeping(ctx context.Context, ingress *networkingv1.Ingress) error {
+	b, err := parseBookkeeping(ingress.Annotations)
+	if err != nil {
+		log.FromContext(ctx).Info("Discarding corrupt bookkeeping", "error", err.Error())
+		if r.Recorder != nil {
+			r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonBookkeepingDiscarded,
+				"Discarded %s: %v", bookkeepingAnnotation, err)
+		}
+	}
+	original := ingress.DeepCopy()
+	writeBookkeeping(ingress, b)
+	if maps.Equal(original.Annotations, ingress.Annotations) {
+		return nil
+	}
+	if err == nil {
+		log.FromContext(ctx).Info("Migrated bookkeeping annotations", "annotation", bookkeepingAnnotation)
+	}
+	return r.patchIngress(ctx, ingress, client.MergeFrom(original))
+}
+
+// splitArns splits a comma-separated list of ARNs, dropping empty entries
+func splitArns(raw string) []string {
+	var arns []string
+	for _, arn := range strings.Split(raw, ",") {
+		if arn = strings.TrimSpace(arn); arn != "" {
+			arns = append(arns, arn)
+		}
+	}
+	return arns
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestBookkeepingMigratesLegacyAnnotations(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	arn := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "acm-manager"})
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		"alb.ingress.kubernetes.io/certificate-arn": arn,
+		writtenCertificateArnAnnotation:             arn,
+		postIssuanceHookAnnotation:                  arn,
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	for _, key := range legacyBookkeepingAnnotations {
+		if _, ok := got.Annotations[key]; ok {
+			t.Errorf("legacy annotation %s was not removed", key)
+		}
+	}
+	b, err := parseBookkeeping(got.Annotations)
+	if err != nil {
+		t.Fatalf("parseBookkeeping: %v", err)
+	}
+	if b.WrittenCertificateArn == nil || *b.WrittenCertificateArn != arn || b.HookFired != arn {
+		t.Fatalf("expected the legacy values in %s, got %q", bookkeepingAnnotation, got.Annotations[bookkeepingAnnotation])
+	}
+}
+
+func TestBookkeepingKeepsUnknownFields(t *testing.T) {
+	written := "arn:aws:acm:us-east-1:123456789012:certificate/a"
+	b, err := parseBookkeeping(map[string]string{bookkeepingAnnotation: `{"v":2,"written":"` + written + `","future":{"x":true}}`})
+	if err != nil {
+		t.Fatalf("parseBookkeeping: %v", err)
+	}
+	b.HookFired = written
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if string(fields["future"]) != `{"x":true}` || string(fields["v"]) != "1" || string(fields["hookFired"]) != `"`+written+`"` {
+		t.Fatalf("unexpected bookkeeping %s", data)
+	}
+}
+
+func TestCorruptBookkeepingIsDiscarded(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{bookkeepingAnnotation: "{not json"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if !slices.ContainsFunc(events, func(e string) bool { return strings.Contains(e, "Warning "+ReasonBookkeepingDiscarded) }) {
+		t.Errorf("expected a %s warning, got %v", ReasonBookkeepingDiscarded, events)
+	}
+	got := getIngress(t, r, ingress)
+	if _, err := parseBookkeeping(got.Annotations); err != nil {
+		t.Fatalf("bookkeeping still corrupt: %v", err)
+	}
+	if written, _ := writtenCertificateArn(got); written != certificateArnOf(t, r, ingress) {
+		t.Fatalf("expected the attached ARN to be tracked, got %q", written)
+	}
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tedens/acm-manager/pkg/certs"
+)
+
+// Lookups answered by the Route 53 providers' cache of domains without a hosted zone, and
+// its size, for tuning --negative-zone-cache-ttl
+var (
+	hostedZoneCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "acm_manager_hosted_zone_cache_hits_total",
+		Help: "Hosted zone lookups answered from the cache of domains without a hosted zone.",
+	})
+	hostedZoneCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "acm_manager_hosted_zone_cache_misses_total",
+		Help: "Hosted zone lookups that listed the hosted zones.",
+	})
+	hostedZoneCacheMissingDomains = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "acm_manager_hosted_zone_cache_missing_domains",
+		Help: "Number of domains cached as having no hosted zone. Hosted zones that were found are not cached.",
+	})
+)
+
+// Lookups answered by the certificate index, and its size, for tuning
+// --certificate-index-interval
+var (
+	certificateCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "acm_manager_certificate_cache_hits_total",
+		Help: "Certificate lookups by name the certificate index had candidates for.",
+	})
+	certificateCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "acm_manager_certificate_cache_misses_total",
+		Help: "Certificate lookups by name the certificate index could not answer, falling back to listing ACM.",
+	})
+	certificateCacheCertificates = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "acm_manager_certificate_cache_certificates",
+		Help: "Number of certificates in the certificate index.",
+	})
+)
+
+// observeZoneCache reports the provider's hosted zone cache use to the cache metrics; every
+// provider adds to the same series
+func observeZoneCache(provider *certs.Route53Provider) {
+	provider.OnCacheLookup = func(hit bool) {
+		if hit {
+			hostedZoneCacheHits.Inc()
+		} else {
+			hostedZoneCacheMisses.Inc()
+		}
+	}
+	provider.OnCacheResize = func(delta int) { hostedZoneCacheMissingDomains.Add(float64(delta)) }
+}
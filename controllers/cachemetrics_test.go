@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+)
+
+func TestHostedZoneCacheMetrics(t *testing.T) {
+	ctx := context.Background()
+	provider := certs.NewRoute53Provider(newFakeRoute53("example.com"))
+	observeZoneCache(provider)
+	hits, misses, zones := metricValue(t, hostedZoneCacheHits), metricValue(t, hostedZoneCacheMisses), metricValue(t, hostedZoneCacheMissingDomains)
+
+	for range 3 {
+		if _, err := provider.FindZone(ctx, "app.example.org"); !errors.Is(err, certs.ErrNoHostedZone) {
+			t.Fatalf("expected ErrNoHostedZone, got %v", err)
+		}
+	}
+	if _, err := provider.FindZone(ctx, "app.example.com"); err != nil {
+		t.Fatalf("FindZone: %v", err)
+	}
+	if got := metricValue(t, hostedZoneCacheHits) - hits; got != 2 {
+		t.Errorf("expected 2 hits, got %v", got)
+	}
+	if got := metricValue(t, hostedZoneCacheMisses) - misses; got != 2 {
+		t.Errorf("expected 2 misses, got %v", got)
+	}
+	if got := metricValue(t, hostedZoneCacheMissingDomains) - zones; got != 1 {
+		t.Errorf("expected 1 cached domain, got %v", got)
+	}
+
+	provider.ForgetMissingZones()
+	if got := metricValue(t, hostedZoneCacheMissingDomains) - zones; got != 0 {
+		t.Errorf("expected the forgotten domains to leave the gauge, got %v", got)
+	}
+}
+
+func TestCertificateCacheMetrics(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": DefaultManagedByValue})
+	index := &CertificateIndex{ACMClient: fakeACM, ManagedByValue: DefaultManagedByValue}
+	hits, misses := metricValue(t, certificateCacheHits), metricValue(t, certificateCacheMisses)
+
+	index.Lookup("app.example.com")
+	if err := index.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	index.Lookup("app.example.com")
+	index.Lookup("shop.example.com")
+
+	if got := metricValue(t, certificateCacheHits) - hits; got != 1 {
+		t.Errorf("expected 1 hit, got %v", got)
+	}
+	if got := metricValue(t, certificateCacheMisses) - misses; got != 2 {
+		t.Errorf("expected the cold and the unknown lookups to miss, got %v", got)
+	}
+	if got := metricValue(t, certificateCacheCertificates); got != 1 {
+		t.Errorf("expected 1 indexed certificate, got %v", got)
+	}
+}
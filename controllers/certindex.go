@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultCertificateIndexInterval is how often the certificate index is rebuilt from ACM
+const DefaultCertificateIndexInterval = 10 * time.Minute
+
+// CertificateIndex keeps the ACM certificates carrying this instance's ManagedBy tag and
+// RequiredTags in memory, by domain and SAN, so reconciles need not list the account. It is
+// warmed when the leader starts, rebuilt every Interval and updated after every certificate
+// the controller requests, imports, tags or deletes. Entries can go stale between refreshes,
+// so callers confirm them with a describe and fall back to listing on a miss.
+type CertificateIndex struct {
+	ACMClient      ACMAPI
+	ManagedByValue string
+	RequiredTags   map[string]string
+	Interval       time.Duration
+
+	mu     sync.RWMutex
+	warm   bool
+	names  map[string][]string // certificate ARN to its lower-cased names
+	byName map[string]map[string]struct{}
+	// unowned remembers certificates whose tags showed they are not ours, so refreshes skip
+	// fetching their tags again
+	unowned map[string]bool
+}
+
+var _ certs.CertificateIndex = (*CertificateIndex)(nil)
+
+// NeedLeaderElection makes the index run only on the elected leader, which is the only
+// replica reconciling
+func (x *CertificateIndex) NeedLeaderElection() bool {
+	return true
+}
+
+// Start warms the index and rebuilds it every Interval until ctx is cancelled
+func (x *CertificateIndex) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("certificate-index")
+	ctx = withAuditSubject(ctx, "certificate-index")
+
+	interval := x.Interval
+	if interval <= 0 {
+		interval = DefaultCertificateIndexInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := x.Refresh(ctx); err != nil {
+			logger.Error(err, "certificate index refresh failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh rebuilds the index from a listing of every certificate in the account. Tags are
+// only fetched for certificates the index has not seen before.
+func (x *CertificateIndex) Refresh(ctx context.Context) error {
+	x.mu.RLock()
+	known, unowned := x.names, x.unowned
+	x.mu.RUnlock()
+
+	names := map[string][]string{}
+	nextUnowned := map[string]bool{}
+	paginator := acm.NewListCertificatesPaginator(x.ACMClient, &acm.ListCertificatesInput{
+		CertificateStatuses: acmtypes.CertificateStatus("").Values(),
+		Includes:            certs.AllKeyTypes(),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, summary := range page.CertificateSummaryList {
+			certArn := aws.ToString(summary.CertificateArn)
+			if unowned[certArn] {
+				nextUnowned[certArn] = true
+				continue
+			}
+			if _, ok := known[certArn]; !ok {
+				owned, err := certs.ManagedByWithTags(ctx, x.ACMClient, certArn, x.ManagedByValue, x.RequiredTags)
+				if err != nil {
+					return err
+				}
+				if !owned {
+					nextUnowned[certArn] = true
+					continue
+				}
+			}
+			certNames := []string{aws.ToString(summary.DomainName)}
+			certNames = append(certNames, summary.SubjectAlternativeNameSummaries...)
+			names[certArn] = certNames
+		}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.names, x.byName, x.unowned = map[string][]string{}, map[string]map[string]struct{}{}, nextUnowned
+	certificateCacheCertificates.Set(0)
+	for certArn, certNames := range names {
+		x.putLocked(certArn, certNames)
+	}
+	x.warm = true
+	return nil
+}
+
+// Lookup returns the ARNs of indexed certificates carrying name, or nil before the first
+// refresh
+func (x *CertificateIndex) Lookup(name string) []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var arns []string
+	if x.warm {
+		for certArn := range x.byName[strings.ToLower(name)] {
+			arns = append(arns, certArn)
+		}
+	}
+	if len(arns) > 0 {
+		certificateCacheHits.Inc()
+	} else {
+		certificateCacheMisses.Inc()
+	}
+	return arns
+}
+
+// Forget drops a certificate from the index
+func (x *CertificateIndex) Forget(certArn string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(certArn)
+}
+
+// track describes and indexes a certificate the controller just changed, or drops it when it
+// is gone or not ours. Failures only leave the entry to the next refresh.
+func (x *CertificateIndex) track(ctx context.Context, certArn string) {
+	describe, err := x.ACMClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+		CertificateArn: aws.String(certArn),
+	})
+	var notFound *acmtypes.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		x.Forget(certArn)
+		return
+	}
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to index certificate", "arn", certArn)
+		return
+	}
+	owned, err := certs.ManagedByWithTags(ctx, x.ACMClient, certArn, x.ManagedByValue, x.RequiredTags)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to index certificate", "arn", certArn)
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(certArn)
+	delete(x.unowned, certArn)
+	if !owned {
+		if x.unowned != nil {
+			x.unowned[certArn] = true
+		}
+		return
+	}
+	cert := describe.Certificate
+	x.putLocked(certArn, append([]string{aws.ToString(cert.DomainName)}, cert.SubjectAlternativeNames...))
+}
+
+func (x *CertificateIndex) putLocked(certArn string, names []string) {
+	if x.names == nil {
+		x.names, x.byName = map[string][]string{}, map[string]map[string]struct{}{}
+	}
+	lowered := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if name == "" {
+			continue
+		}
+		lowered = append(lowered, name)
+		if x.byName[name] == nil {
+			x.byName[name] = map[string]struct{}{}
+		}
+		x.byName[name][certArn] = struct{}{}
+	}
+	x.names[certArn] = lowered
+	certificateCacheCertificates.Set(float64(len(x.names)))
+}
+
+func (x *CertificateIndex) removeLocked(certArn string) {
+	for _, name := range x.names[certArn] {
+		delete(x.byName[name], certArn)
+		if len(x.byName[name]) == 0 {
+			delete(x.byName, name)
+		}
+	}
+	delete(x.names, certArn)
+	certificateCacheCertificates.Set(float64(len(x.names)))
+}
+
+// indexedACM keeps a CertificateIndex current with the certificates requested, imported,
+// tagged and deleted through it
+type indexedACM struct {
+	ACMAPI
+	index *CertificateIndex
+}
+
+func (c *indexedACM) RequestCertificate(ctx context.Context, in *acm.RequestCertificateInput, optFns ...func(*acm.Options)) (*acm.RequestCertificateOutput, error) {
+	out, err := c.ACMAPI.RequestCertificate(ctx, in, optFns...)
+	if err == nil {
+		c.index.track(ctx, aws.ToString(out.CertificateArn))
+	}
+	return out, err
+}
+
+func (c *indexedACM) ImportCertificate(ctx context.Context, in *acm.ImportCertificateInput, optFns ...func(*acm.Options)) (*acm.ImportCertificateOutput, error) {
+	out, err := c.ACMAPI.ImportCertificate(ctx, in, optFns...)
+	if err == nil {
+		c.index.track(ctx, aws.ToString(out.CertificateArn))
+	}
+	return out, err
+}
+
+func (c *indexedACM) AddTagsToCertificate(ctx context.Context, in *acm.AddTagsToCertificateInput, optFns ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	out, err := c.ACMAPI.AddTagsToCertificate(ctx, in, optFns...)
+	if err == nil {
+		c.index.track(ctx, aws.ToString(in.CertificateArn))
+	}
+	return out, err
+}
+
+func (c *indexedACM) DeleteCertificate(ctx context.Context, in *acm.DeleteCertificateInput, optFns ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error) {
+	out, err := c.ACMAPI.DeleteCertificate(ctx, in, optFns...)
+	var notFound *acmtypes.ResourceNotFoundException
+	if err == nil || errors.As(err, &notFound) {
+		c.index.Forget(aws.ToString(in.CertificateArn))
+	}
+	return out, err
+}
+
+// certificateIndex returns the index for lookups in ctx, or nil when there is none or ctx
+// targets a role or region of its own, which the index does not cover
+func (r *IngressReconciler) certificateIndex(ctx context.Context) *CertificateIndex {
+	if _, scoped := ctx.Value(awsScopeKey{}).(*awsClients); scoped {
+		return nil
+	}
+	return r.certIndex
+}
+
+// indexedCertificatesFor returns the ARNs the index holds for domain in ctx
+func (r *IngressReconciler) indexedCertificatesFor(ctx context.Context, domain string) []string {
+	if index := r.certificateIndex(ctx); index != nil {
+		return index.Lookup(domain)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+// useCertificateIndex gives r a warm certificate index over fakeACM, as SetupWithManager does
+func useCertificateIndex(t *testing.T, r *IngressReconciler, fakeACM *fakeACM) *CertificateIndex {
+	t.Helper()
+	r.certIndex = &CertificateIndex{ACMClient: fakeACM, ManagedByValue: r.managedByValue()}
+	r.ACMClient = &indexedACM{ACMAPI: fakeACM, index: r.certIndex}
+	if err := r.certIndex.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	return r.certIndex
+}
+
+func TestCertificateIndexHoldsOwnedCertificates(t *testing.T) {
+	fakeACM := newFakeACM()
+	owned := fakeACM.addCert("App.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": DefaultManagedByValue})
+	fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "someone-else"})
+	index := &CertificateIndex{ACMClient: fakeACM, ManagedByValue: DefaultManagedByValue}
+
+	if arns := index.Lookup("app.example.com"); arns != nil {
+		t.Fatalf("a cold index must not answer, got %v", arns)
+	}
+	if err := index.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if arns := index.Lookup("app.EXAMPLE.com"); !slices.Equal(arns, []string{owned}) {
+		t.Fatalf("expected only the owned certificate, got %v", arns)
+	}
+
+	index.Forget(owned)
+	if arns := index.Lookup("app.example.com"); arns != nil {
+		t.Fatalf("expected the forgotten certificate to be dropped, got %v", arns)
+	}
+}
+
+func TestReconcileReusesIndexedCertificate(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	existing := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": DefaultManagedByValue})
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	useCertificateIndex(t, r, fakeACM)
+	fakeACM.lists = 0
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if arn := certificateArnOf(t, r, ingress); arn != existing || len(fakeACM.requests) != 0 {
+		t.Fatalf("expected the indexed certificate to be reused, got %q after %d requests", arn, len(fakeACM.requests))
+	}
+	if fakeACM.lists != 0 {
+		t.Fatalf("an index hit must not list certificates, listed %d times", fakeACM.lists)
+	}
+}
+
+func TestCertificateIndexDropsVanishedCertificate(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	gone := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": DefaultManagedByValue})
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	index := useCertificateIndex(t, r, fakeACM)
+	delete(fakeACM.certs, gone)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+	if arn == "" || arn == gone || len(fakeACM.requests) != 1 {
+		t.Fatalf("expected a certificate to be requested in place of the vanished one, got %q", arn)
+	}
+	if arns := index.Lookup("app.example.com"); !slices.Equal(arns, []string{arn}) {
+		t.Fatalf("expected the index to drop the vanished certificate and hold the requested one, got %v", arns)
+	}
+
+	if err := r.deleteCertificateForDomain(ctx, "app.example.com", ingressKey(ingress)); err != nil {
+		t.Fatalf("deleteCertificateForDomain: %v", err)
+	}
+	if !slices.Contains(fakeACM.deleted, arn) || index.Lookup("app.example.com") != nil {
+		t.Fatalf("expected the deletion to reach ACM and the index, deleted %v", fakeACM.deleted)
+	}
+}
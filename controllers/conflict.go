@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// writtenCertificateArnAnnotation held the certificate ARNs the controller last wrote
+	// before they moved to bookkeepingAnnotation
+	writtenCertificateArnAnnotation = "acm.tedens.dev/written-certificate-arn"
+	// certificateArnConflictAnnotation holds a certificate ARN value written by someone else
+	// that the controller is leaving alone
+	certificateArnConflictAnnotation = "acm.tedens.dev/certificate-arn-conflict"
+)
+
+// Policies of --certificate-arn-conflict for certificate ARNs changed outside the controller
+const (
+	// CertificateArnConflictYield treats the other value as authoritative
+	CertificateArnConflictYield = "yield"
+	// CertificateArnConflictWarn also stops overwriting, but records a Warning event
+	CertificateArnConflictWarn = "warn"
+)
+
+// certificateArnConflict returns the certificate ARN annotation's value when it is not what
+// the controller last wrote. A missing annotation is no conflict: it is written again.
+func certificateArnConflict(ingress *networkingv1.Ingress, cfg IngressConfig) (string, bool) {
+	written, tracked := writtenCertificateArn(ingress)
+	current, exists := ingress.Annotations[certificateArnKey(cfg)]
+	if !tracked || !exists || current == written {
+		return "", false
+	}
+	return current, true
+}
+
+// yieldCertificateArn reports whether the Ingress' certificate ARNs were changed outside the
+// controller, which must then leave them alone. An event is recorded once per foreign value,
+// and the conflict annotation is cleared once the conflict is resolved.
+func (r *IngressReconciler) yieldCertificateArn(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig) (bool, error) {
+	current, conflict := certificateArnConflict(ingress, cfg)
+	seen, marked := ingress.Annotations[certificateArnConflictAnnotation]
+	if !conflict {
+		if !marked {
+			return false, nil
+		}
+		patch := client.MergeFrom(ingress.DeepCopy())
+		delete(ingress.Annotations, certificateArnConflictAnnotation)
+		if err := r.patchIngress(ctx, ingress, patch); err != nil {
+			return false, fmt.Errorf("failed to clear certificate ARN conflict: %w", err)
+		}
+		return false, nil
+	}
+	if marked && seen == current {
+		return true, nil
+	}
+
+	key := certificateArnKey(cfg)
+	written, _ := writtenCertificateArn(ingress)
+	log.FromContext(ctx).Info("Certificate ARN annotation was changed outside the controller, not overwriting it",
+		"annotation", key, "value", current, "written", written)
+	if r.Recorder != nil {
+		if r.settings().CertificateArnConflict == CertificateArnConflictWarn {
+			r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonCertificateArnConflict,
+				"%s was changed to %s outside the controller; not overwriting it. Remove the annotation, or restore %s, to let the controller manage it again",
+				key, current, written)
+		} else {
+			r.Recorder.Eventf(ingress, corev1.EventTypeNormal, ReasonCertificateArnOverridden,
+				"Keeping %s=%s set outside the controller; remove the annotation to let the controller manage it again", key, current)
+		}
+	}
+	patch := client.MergeFrom(ingress.DeepCopy())
+	ingress.Annotations[certificateArnConflictAnnotation] = current
+	if err := r.patchIngress(ctx, ingress, patch); err != nil {
+		return true, fmt.Errorf("failed to record certificate ARN conflict: %w", err)
+	}
+	return true, nil
+}
+
+// trackWrittenCertificateArn records the attached certificate ARNs as the controller's own
+// when nothing is tracked yet, as on Ingresses attached before the tracking existed
+func (r *IngressReconciler) trackWrittenCertificateArn(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig) error {
+	current, exists := ingress.Annotations[certificateArnKey(cfg)]
+	if _, tracked := writtenCertificateArn(ingress); tracked || !exists {
+		return nil
+	}
+	patch := client.MergeFrom(ingress.DeepCopy())
+	setWrittenCertificateArn(ingress, current)
+	return r.patchIngress(ctx, ingress, patch)
+}
+
+// writtenCertificateArn returns the certificate ARN value the controller last wrote, to tell
+// its own writes from those of users or other controllers, and whether one is tracked
+func writtenCertificateArn(ingress *networkingv1.Ingress) (string, bool) {
+	written := readBookkeeping(ingress).WrittenCertificateArn
+	if written == nil {
+		return "", false
+	}
+	return *written, true
+}
+
+func setWrittenCertificateArn(ingress *networkingv1.Ingress, value string) {
+	updateBookkeeping(ingress, func(b *bookkeeping) { b.WrittenCertificateArn = &value })
+}
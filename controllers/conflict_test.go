@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// overwriteCertificateArn sets the ALB certificate-arn annotation as another controller would
+func overwriteCertificateArn(t *testing.T, r *IngressReconciler, name, value string) {
+	t.Helper()
+	got := getIngress(t, r, newManagedIngress(name, "", nil))
+	patch := client.MergeFrom(got.DeepCopy())
+	got.Annotations[albCertificateArnAnnotation] = value
+	if err := r.Patch(context.Background(), got, patch); err != nil {
+		t.Fatalf("patch ingress: %v", err)
+	}
+}
+
+func TestCertificateArnOverwrittenExternally(t *testing.T) {
+	for _, policy := range []string{CertificateArnConflictYield, CertificateArnConflictWarn} {
+		t.Run(policy, func(t *testing.T) {
+			ctx := context.Background()
+			fakeACM := newFakeACM()
+			ingress := newManagedIngress("web", "app.example.com", nil)
+			r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+			r.CertificateArnConflict = policy
+
+			if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+			ours := certificateArnOf(t, r, ingress)
+			if got, _ := writtenCertificateArn(getIngress(t, r, ingress)); got != ours {
+				t.Fatalf("expected the written ARN %q to be tracked, got %q", ours, got)
+			}
+			drainEvents(r.Recorder.(*record.FakeRecorder))
+
+			// Another controller writes its own certificate, which must survive reconciles
+			const theirs = "arn:aws:acm:us-east-1:123456789012:certificate/external"
+			overwriteCertificateArn(t, r, "web", theirs)
+			for i := 0; i < 2; i++ {
+				if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+					t.Fatalf("Reconcile: %v", err)
+				}
+			}
+			if got := certificateArnOf(t, r, ingress); got != theirs {
+				t.Fatalf("the external value must not be overwritten, got %q", got)
+			}
+			if len(fakeACM.requests) != 1 {
+				t.Fatalf("no new certificate may be requested, got %d requests", len(fakeACM.requests))
+			}
+			events := drainEvents(r.Recorder.(*record.FakeRecorder))
+			wantType, wantReason := "Normal", ReasonCertificateArnOverridden
+			if policy == CertificateArnConflictWarn {
+				wantType, wantReason = "Warning", ReasonCertificateArnConflict
+			}
+			if len(events) != 1 || !strings.HasPrefix(events[0], wantType+" "+wantReason) || !strings.Contains(events[0], theirs) {
+				t.Fatalf("expected one %s %s event, got %v", wantType, wantReason, events)
+			}
+
+			// Restoring the controller's value ends the conflict
+			overwriteCertificateArn(t, r, "web", ours)
+			if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+			if _, ok := getIngress(t, r, ingress).Annotations[certificateArnConflictAnnotation]; ok {
+				t.Fatal("expected the conflict to be cleared")
+			}
+		})
+	}
+}
+
+func TestCertificateArnRemovedIsWrittenAgain(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	patch := client.MergeFrom(got.DeepCopy())
+	delete(got.Annotations, albCertificateArnAnnotation)
+	if err := r.Patch(ctx, got, patch); err != nil {
+		t.Fatalf("patch ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if certificateArnOf(t, r, ingress) == "" {
+		t.Fatal("a removed annotation must be written again")
+	}
+}
+
+func TestCertificateArnTrackedForExistingIngresses(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	// An Ingress attached before the tracking existed is adopted on its next reconcile
+	got := getIngress(t, r, ingress)
+	patch := client.MergeFrom(got.DeepCopy())
+	delete(got.Annotations, bookkeepingAnnotation)
+	delete(got.Annotations, writtenCertificateArnAnnotation)
+	if err := r.Patch(ctx, got, patch); err != nil {
+		t.Fatalf("patch ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if written, _ := writtenCertificateArn(getIngress(t, r, ingress)); written != certificateArnOf(t, r, ingress) {
+		t.Fatalf("expected the attached ARN to be tracked, got %q", written)
+	}
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// coveredNamesAnnotation lists the names covered by the certificates attached to the Ingress
+const coveredNamesAnnotation = "acm.tedens.dev/covered-names"
+
+// coveredNames returns the comma-separated domain and SANs of the certificates, deduplicated
+func coveredNames(certificates ...*acmtypes.CertificateDetail) string {
+	seen := map[string]bool{}
+	var names []string
+	for _, cert := range certificates {
+		for _, name := range append([]string{aws.ToString(cert.DomainName)}, cert.SubjectAlternativeNames...) {
+			name = strings.ToLower(name)
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// describeCertificates returns the details of the certificates in arns
+func (r *IngressReconciler) describeCertificates(ctx context.Context, arns []string) ([]*acmtypes.CertificateDetail, error) {
+	var certificates []*acmtypes.CertificateDetail
+	for _, arn := range arns {
+		describe, err := r.acm(ctx).DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+			CertificateArn: aws.String(arn),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe certificate %s: %w", arn, err)
+		}
+		certificates = append(certificates, describe.Certificate)
+	}
+	return certificates, nil
+}
+
+// uncoveredNames returns the names that none of the certificates cover, either listing them
+// or through a wildcard one label up
+func uncoveredNames(certificates []*acmtypes.CertificateDetail, names []string) []string {
+	covered := map[string]bool{}
+	for _, name := range strings.Split(coveredNames(certificates...), ",") {
+		covered[name] = true
+	}
+	var missing []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || covered[name] {
+			continue
+		}
+		if _, parent, ok := strings.Cut(name, "."); ok && !strings.HasPrefix(name, "*.") && covered["*."+parent] {
+			continue
+		}
+		missing = append(missing, name)
+	}
+	return missing
+}
+
+// certificateMismatchError reports certificates that do not cover every name of the Ingress
+type certificateMismatchError struct {
+	arns    []string
+	missing []string
+}
+
+func (e *certificateMismatchError) Error() string {
+	return fmt.Sprintf("certificate %s does not cover %s", strings.Join(e.arns, ","), strings.Join(e.missing, ","))
+}
+
+// setCoveredNames patches the covered-names annotation when it differs from names
+func (r *IngressReconciler) setCoveredNames(ctx context.Context, ingress *networkingv1.Ingress, names string) error {
+	if ingress.Annotations[coveredNamesAnnotation] == names {
+		return nil
+	}
+	patch := client.MergeFrom(ingress.DeepCopy())
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+	ingress.Annotations[coveredNamesAnnotation] = names
+	return r.patchIngress(ctx, ingress, patch)
+}
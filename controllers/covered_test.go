@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestCoveredNamesAnnotation(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/san": "www.example.com"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if names := got.Annotations[coveredNamesAnnotation]; names != "app.example.com,www.example.com" {
+		t.Fatalf("unexpected covered names %q", names)
+	}
+
+	// A stale value is corrected on the next reconcile of the issued certificate
+	patch := client.MergeFrom(got.DeepCopy())
+	got.Annotations[coveredNamesAnnotation] = "app.example.com"
+	if err := r.Patch(ctx, &got, patch); err != nil {
+		t.Fatalf("patch ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if names := got.Annotations[coveredNamesAnnotation]; names != "app.example.com,www.example.com" {
+		t.Fatalf("expected covered names to be refreshed, got %q", names)
+	}
+
+	// Writing the annotation does not trigger another reconcile
+	updated := got.DeepCopy()
+	updated.Annotations[coveredNamesAnnotation] = "other.example.com"
+	if ignoreStatusAnnotationUpdates().Update(event.UpdateEvent{ObjectOld: &got, ObjectNew: updated}) {
+		t.Fatal("an update that only changes covered-names must not trigger a reconcile")
+	}
+}
+
+func TestUncoveredNames(t *testing.T) {
+	certificates := []*acmtypes.CertificateDetail{
+		{DomainName: aws.String("app.example.com"), SubjectAlternativeNames: []string{"app.example.com", "*.dev.example.com"}},
+	}
+	got := uncoveredNames(certificates, []string{"APP.example.com", "api.dev.example.com", "*.dev.example.com", "x.api.dev.example.com", "api.example.com"})
+	if !slices.Equal(got, []string{"x.api.dev.example.com", "api.example.com"}) {
+		t.Fatalf("uncoveredNames = %v", got)
+	}
+}
+
+func TestSharedCertificateMissingSANIsNotAttached(t *testing.T) {
+	ctx := context.Background()
+	ingresses := sharedIngresses(map[string]map[string]string{"b": {"acm.tedens.dev/san": "api.example.com"}})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingresses[0], ingresses[1])
+
+	if _, err := r.Reconcile(ctx, requestFor(ingresses[0])); err != nil {
+		t.Fatalf("Reconcile a: %v", err)
+	}
+	drainEvents(r.Recorder.(*record.FakeRecorder))
+
+	// b reuses a's certificate, which lacks b's SAN
+	var mismatch *certificateMismatchError
+	if _, err := r.Reconcile(ctx, requestFor(ingresses[1])); !errors.As(err, &mismatch) {
+		t.Fatalf("expected a certificate mismatch, got %v", err)
+	}
+	if arn := certificateArnOf(t, r, ingresses[1]); arn != "" {
+		t.Fatalf("a certificate not covering api.example.com must not be attached, got %s", arn)
+	}
+	if events := strings.Join(drainEvents(r.Recorder.(*record.FakeRecorder)), "\n"); !strings.Contains(events, "Warning "+ReasonCertificateMismatch) || !strings.Contains(events, "api.example.com") {
+		t.Fatalf("expected a mismatch warning naming the SAN, got %q", events)
+	}
+}
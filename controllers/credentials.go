@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+)
+
+const (
+	// awsCredentialsMinBackoff is the wait after the first failed load of the AWS configuration
+	awsCredentialsMinBackoff = time.Second
+	// awsCredentialsMaxBackoff caps the wait between loads
+	awsCredentialsMaxBackoff = 5 * time.Minute
+)
+
+// awsCredentialsBackoff returns the wait after failures consecutive failed loads
+func awsCredentialsBackoff(failures int) time.Duration {
+	wait := awsCredentialsMinBackoff
+	for i := 1; i < failures && wait < awsCredentialsMaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, awsCredentialsMaxBackoff)
+}
+
+// awsCredentialsError is returned by AWS calls while the AWS configuration or credentials
+// cannot be loaded, as before the IRSA webhook injected them
+type awsCredentialsError struct {
+	err     error
+	retryAt time.Time
+}
+
+func (e *awsCredentialsError) Error() string {
+	return fmt.Sprintf("waiting for AWS credentials: %v", e.err)
+}
+
+func (e *awsCredentialsError) Unwrap() error {
+	return e.err
+}
+
+// isDeferred reports whether err only postpones the reconcile, until the maintenance window
+// opens, AWS credentials are available, the certificate request rate allows or observe-only
+// mode is turned off, so it is no failure to notify about
+func isDeferred(err error) bool {
+	var credentials *awsCredentialsError
+	var limited *requestRateLimitedError
+	return isMaintenanceDeferred(err) || errors.As(err, &credentials) || errors.As(err, &limited) || errors.Is(err, ErrObserveOnly)
+}
+
+// lazyACM is the ACM client of the default target, built on first use
+type lazyACM struct {
+	cache *awsClientCache
+}
+
+func (c *lazyACM) client(ctx context.Context) (ACMAPI, error) {
+	clients, err := c.cache.get(ctx, awsTarget{})
+	if err != nil {
+		return nil, err
+	}
+	return clients.ACM, nil
+}
+
+func (c *lazyACM) RequestCertificate(ctx context.Context, in *acm.RequestCertificateInput, optFns ...func(*acm.Options)) (*acm.RequestCertificateOutput, error) {
+	client, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.RequestCertificate(ctx, in, optFns...)
+}
+
+func (c *lazyACM) DescribeCertificate(ctx context.Context, in *acm.DescribeCertificateInput, optFns ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error) {
+	client, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.DescribeCertificate(ctx, in, optFns...)
+}
+
+func (c *lazyACM) ListCertificates(ctx context.Context, in *acm.ListCertificatesInput, optFns ...func(*acm.Options)) (*acm.ListCertificatesOutput, error) {
+	client, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListCertificates(ctx, in, optFns...)
+}
+
+func (c *lazyACM) ListTagsForCertificate(ctx context.Context, in *acm.ListTagsForCertificateInput, optFns ...func(*acm.Options)) (*acm.ListTagsForCertificateOutput, error) {
+	client, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListTagsForCertificate(ctx, in, optFns...)
+}
+
+func (c *lazyACM) DeleteCertificate(ctx context.Context, in *acm.DeleteCertificateInput, optFns ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error) {
+	client, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.DeleteCertificate(ctx, in, optFns...)
+}
+
+func (c *lazyACM) ImportCertificate(ctx context.Context, in *acm.ImportCertificateInput, optFns ...func(*acm.Options)) (*acm.ImportCertificateOutput, error) {
+	client, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.ImportCertificate(ctx, in, optFns...)
+}
+
+func (c *lazyACM) AddTagsToCertificate(ctx context.Context, in *acm.AddTagsToCertificateInput, optFns ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	client, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.AddTagsToCertificate(ctx, in, optFns...)
+}
+
+// lazyRoute53 is the Route 53 client of the default target, built on first use
+type lazyRoute53 struct {
+	cache *awsClientCache
+}
+
+func (c *lazyRoute53) client(ctx context.Context) (Route53API, error) {
+	clients, err := c.cache.get(ctx, awsTarget{})
+	if err != nil {
+		return nil, err
+	}
+	return clients.Route53, nil
+}
+
+func (c *lazyRoute53) ChangeResourceRecordSets(ctx context.Context, in *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	client, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.ChangeResourceRecordSets(ctx, in, optFns...)
+}
+
+func (c *lazyRoute53) ListHostedZones(ctx context.Context, in *route53.ListHostedZonesInput, optFns ...func(*route53.Options)) (*route53.ListHostedZonesOutput, error) {
+	client, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListHostedZones(ctx, in, optFns...)
+}
+
+func (c *lazyRoute53) ListTagsForResource(ctx context.Context, in *route53.ListTagsForResourceInput, optFns ...func(*route53.Options)) (*route53.ListTagsForResourceOutput, error) {
+	client, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListTagsForResource(ctx, in, optFns...)
+}
+
+// AWSCredentialsCheck is a readiness check failing while the controller's own AWS
+// configuration or credentials cannot be loaded. Each check retries the load once its
+// backoff expired.
+func (r *IngressReconciler) AWSCredentialsCheck(req *http.Request) error {
+	if !r.lazyClients {
+		return nil
+	}
+	_, err := r.awsClients().get(req.Context(), awsTarget{})
+	return err
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/tedens/acm-manager/pkg/certs"
+)
+
+// failingCache returns a cache whose base configuration fails to load until *available is
+// set, counting the loads, with its clock at *now
+func failingCache(available *bool, loads *int, now *time.Time, fakeACM *fakeACM) *awsClientCache {
+	return &awsClientCache{
+		now: func() time.Time { return *now },
+		loadConfig: func(context.Context) (aws.Config, error) {
+			*loads++
+			if !*available {
+				return aws.Config{}, errors.New("no EC2 IMDS role found")
+			}
+			return aws.Config{Region: "us-east-1"}, nil
+		},
+		build: func(aws.Config) awsClients {
+			return awsClients{ACM: fakeACM, Route53: newFakeRoute53("example.com")}
+		},
+	}
+}
+
+func TestAWSCredentialsBackoff(t *testing.T) {
+	cases := map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 20: awsCredentialsMaxBackoff}
+	for failures, want := range cases {
+		if got := awsCredentialsBackoff(failures); got != want {
+			t.Errorf("awsCredentialsBackoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestAWSClientCacheRetriesFailedLoad(t *testing.T) {
+	ctx := context.Background()
+	available, loads, now := false, 0, time.Now()
+	cache := failingCache(&available, &loads, &now, newFakeACM())
+
+	_, err := cache.get(ctx, awsTarget{})
+	var credentials *awsCredentialsError
+	if !errors.As(err, &credentials) || !credentials.retryAt.Equal(now.Add(time.Second)) {
+		t.Fatalf("expected a credentials error retrying in 1s, got %v", err)
+	}
+	if _, err := cache.get(ctx, awsTarget{Region: "eu-west-1"}); err == nil || loads != 1 {
+		t.Fatalf("the load must back off for every target, got %v after %d loads", err, loads)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := cache.get(ctx, awsTarget{}); !errors.As(err, &credentials) || !credentials.retryAt.Equal(now.Add(2*time.Second)) {
+		t.Fatalf("expected the backoff to double, got %v", err)
+	}
+
+	available = true
+	now = now.Add(2 * time.Second)
+	clients, err := cache.get(ctx, awsTarget{})
+	if err != nil {
+		t.Fatalf("get after the credentials appeared: %v", err)
+	}
+	if again, _ := cache.get(ctx, awsTarget{}); again != clients || loads != 3 {
+		t.Fatalf("the loaded configuration must be cached, got %d loads", loads)
+	}
+}
+
+func TestReconcileWaitsForAWSCredentials(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	available, loads, now := false, 0, time.Now()
+	r.now = func() time.Time { return now }
+	r.clientsOnce.Do(func() { r.clients = failingCache(&available, &loads, &now, fakeACM) })
+	r.ACMClient = &lazyACM{cache: r.awsClients()}
+	r.DNSProvider = certs.NewRoute53Provider(&lazyRoute53{cache: r.awsClients()})
+	r.lazyClients = true
+
+	res, err := r.Reconcile(ctx, requestFor(ingress))
+	if err != nil {
+		t.Fatalf("missing credentials must requeue without an error, got %v", err)
+	}
+	if res.RequeueAfter != time.Second {
+		t.Fatalf("expected a requeue when the load is retried, got %s", res.RequeueAfter)
+	}
+	got := getIngress(t, r, ingress)
+	for _, key := range []string{lastErrorAnnotation, retryBackoffAnnotation} {
+		if _, ok := got.Annotations[key]; ok {
+			t.Errorf("missing credentials must not set %s", key)
+		}
+	}
+	if err := r.AWSCredentialsCheck(httptest.NewRequest("GET", "/readyz", nil)); err == nil {
+		t.Fatal("expected the readiness check to fail while waiting for credentials")
+	}
+
+	available = true
+	now = now.Add(time.Second)
+	if err := r.AWSCredentialsCheck(httptest.NewRequest("GET", "/readyz", nil)); err != nil {
+		t.Fatalf("readiness check after the credentials appeared: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile with credentials: %v", err)
+	}
+	if len(fakeACM.requests) != 1 || certificateArnOf(t, r, ingress) == "" {
+		t.Fatal("expected the certificate to be issued once credentials are available")
+	}
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DNS provider names accepted by the acm.tedens.dev/dns-provider annotation and --dns-provider
+const (
+	DNSProviderRoute53    = "route53"
+	DNSProviderCloudflare = "cloudflare"
+	DNSProviderWebhook    = "webhook"
+	DNSProviderManual     = "manual"
+	DNSProviderNone       = "none"
+)
+
+// IsDNSProvider reports whether name is a known DNS provider
+func IsDNSProvider(name string) bool {
+	switch name {
+	case DNSProviderRoute53, DNSProviderCloudflare, DNSProviderWebhook, DNSProviderManual, DNSProviderNone:
+		return true
+	}
+	return false
+}
+
+// ValidationRecord is a DNS record ACM requires to validate one domain of a certificate
+type ValidationRecord = certs.ValidationRecord
+
+// DNSProvider manages ACM validation records in a DNS service
+type DNSProvider = certs.DNSProvider
+
+// dnsProviderFor returns the DNS provider selected for the Ingress, DefaultDNSProvider when it
+// selects none, deferring its changes to the maintenance window and skipping them with
+// ObserveOnly
+func (r *IngressReconciler) dnsProviderFor(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig) (DNSProvider, error) {
+	provider, err := r.selectDNSProvider(ctx, ingress, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return r.observeDNSProvider(r.maintenanceDNSProvider(provider)), nil
+}
+
+func (r *IngressReconciler) selectDNSProvider(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig) (DNSProvider, error) {
+	name := cfg.DNSProvider
+	if name == "" {
+		name = r.DefaultDNSProvider
+	}
+	switch name {
+	case "", DNSProviderRoute53:
+		if r.NoRoute53 {
+			if cfg.DNSProvider == DNSProviderRoute53 {
+				return nil, fmt.Errorf("route53 DNS provider is disabled by --no-route53")
+			}
+			return r.manualDNSProvider(ingress), nil
+		}
+		provider := r.route53Provider(ctx)
+		if provider == nil {
+			return nil, fmt.Errorf("route53 DNS provider is not configured")
+		}
+		return r.zoneMappedProvider(ctx, provider)
+	case DNSProviderCloudflare:
+		token, err := r.cloudflareToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return NewCloudflareProvider(token), nil
+	case DNSProviderWebhook:
+		if r.DNSWebhookURL == "" {
+			return nil, fmt.Errorf("webhook DNS provider requested but no --dns-webhook-url is configured")
+		}
+		return NewWebhookDNSProvider(r.DNSWebhookURL), nil
+	case DNSProviderManual, DNSProviderNone:
+		return r.manualDNSProvider(ingress), nil
+	default:
+		return nil, fmt.Errorf("unknown DNS provider %q", name)
+	}
+}
+
+// manualDNSProvider publishes validation records on the Ingress as an event and as the
+// acm.tedens.dev/validation-records annotation
+func (r *IngressReconciler) manualDNSProvider(ingress *networkingv1.Ingress) *ManualDNSProvider {
+	return &ManualDNSProvider{
+		Recorder: r.Recorder,
+		Object:   ingress,
+		Annotate: func(ctx context.Context, records []ValidationRecord) error {
+			return r.annotateValidationRecords(ctx, ingress, records)
+		},
+	}
+}
+
+// validationRecordsAnnotation holds the JSON list of records the Ingress' certificate needs
+const validationRecordsAnnotation = "acm.tedens.dev/validation-records"
+
+type annotatedRecord struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// annotateValidationRecords writes records to the validation-records annotation
+func (r *IngressReconciler) annotateValidationRecords(ctx context.Context, ingress *networkingv1.Ingress, records []ValidationRecord) error {
+	out := make([]annotatedRecord, 0, len(records))
+	for _, rec := range records {
+		out = append(out, annotatedRecord{Name: rec.Name, Type: rec.Type, Value: rec.Value})
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	if ingress.Annotations[validationRecordsAnnotation] == string(data) {
+		return nil
+	}
+
+	patch := client.MergeFrom(ingress.DeepCopy())
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+	ingress.Annotations[validationRecordsAnnotation] = string(data)
+	if err := r.patchIngress(ctx, ingress, patch); err != nil {
+		return fmt.Errorf("failed to annotate validation records: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	cloudflareAPIURL = "https://api.cloudflare.com/client/v4"
+	// cloudflareTokenKey is the Secret data key holding the Cloudflare API token
+	cloudflareTokenKey = "api-token"
+)
+
+// CloudflareProvider manages validation records through the Cloudflare v4 API
+type CloudflareProvider struct {
+	APIToken   string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewCloudflareProvider returns a provider authenticating with an API token
+// that has Zone:Read and DNS:Edit permissions
+func NewCloudflareProvider(token string) *CloudflareProvider {
+	return &CloudflareProvider{
+		APIToken:   token,
+		BaseURL:    cloudflareAPIURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type cloudflareResponse struct {
+	Success bool              `json:"success"`
+	Errors  []cloudflareError `json:"errors"`
+	Result  json.RawMessage   `json:"result"`
+}
+
+type cloudflareError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type cloudflareZone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+	Proxied bool   `json:"proxied"`
+}
+
+func (p *CloudflareProvider) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.APIToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var parsed cloudflareResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return fmt.Errorf("cloudflare %s %s: %s: %w", method, path, resp.Status, err)
+	}
+	if !parsed.Success {
+		return fmt.Errorf("cloudflare %s %s failed: %+v", method, path, parsed.Errors)
+	}
+	if result != nil {
+		return json.Unmarshal(parsed.Result, result)
+	}
+	return nil
+}
+
+// FindZone returns the ID of the most specific active Cloudflare zone containing domain
+func (p *CloudflareProvider) FindZone(ctx context.Context, domain string) (string, error) {
+	labels := strings.Split(strings.TrimSuffix(strings.TrimPrefix(domain, "*."), "."), ".")
+	for i := 0; i < len(labels)-1; i++ {
+		candidate := strings.Join(labels[i:], ".")
+		var zones []cloudflareZone
+		if err := p.do(ctx, http.MethodGet, "/zones?status=active&name="+url.QueryEscape(candidate), nil, &zones); err != nil {
+			return "", err
+		}
+		if len(zones) > 0 {
+			return zones[0].ID, nil
+		}
+	}
+	return "", fmt.Errorf("no matching Cloudflare zone found for domain: %s", domain)
+}
+
+func (p *CloudflareProvider) zoneFor(ctx context.Context, zoneID string, record ValidationRecord) (string, error) {
+	if zoneID != "" {
+		return zoneID, nil
+	}
+	return p.FindZone(ctx, record.Domain)
+}
+
+func (p *CloudflareProvider) existing(ctx context.Context, zoneID string, record ValidationRecord) ([]cloudflareRecord, error) {
+	query := url.Values{}
+	query.Set("type", record.Type)
+	query.Set("name", strings.TrimSuffix(record.Name, "."))
+	var records []cloudflareRecord
+	err := p.do(ctx, http.MethodGet, "/zones/"+zoneID+"/dns_records?"+query.Encode(), nil, &records)
+	return records, err
+}
+
+func (p *CloudflareProvider) EnsureRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	logger := log.FromContext(ctx)
+
+	for _, record := range records {
+		zone, err := p.zoneFor(ctx, zoneID, record)
+		if err != nil {
+			return err
+		}
+
+		desired := cloudflareRecord{
+			Type:    record.Type,
+			Name:    strings.TrimSuffix(record.Name, "."),
+			Content: strings.TrimSuffix(record.Value, "."),
+			TTL:     300,
+		}
+
+		current, err := p.existing(ctx, zone, record)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case len(current) == 0:
+			logger.Info("Creating Cloudflare validation record", "zone", zone, "name", desired.Name, "type", desired.Type)
+			err = p.do(ctx, http.MethodPost, "/zones/"+zone+"/dns_records", desired, nil)
+		case strings.TrimSuffix(current[0].Content, ".") != desired.Content:
+			logger.Info("Updating Cloudflare validation record", "zone", zone, "name", desired.Name, "type", desired.Type)
+			err = p.do(ctx, http.MethodPut, "/zones/"+zone+"/dns_records/"+current[0].ID, desired, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write Cloudflare validation record: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *CloudflareProvider) DeleteRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	for _, record := range records {
+		zone, err := p.zoneFor(ctx, zoneID, record)
+		if err != nil {
+			return err
+		}
+		current, err := p.existing(ctx, zone, record)
+		if err != nil {
+			return err
+		}
+		for _, rec := range current {
+			if err := p.do(ctx, http.MethodDelete, "/zones/"+zone+"/dns_records/"+rec.ID, nil, nil); err != nil {
+				return fmt.Errorf("failed to delete Cloudflare validation record: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// cloudflareToken reads the API token from the configured Secret
+func (r *IngressReconciler) cloudflareToken(ctx context.Context) (string, error) {
+	if r.CloudflareTokenSecret.Name == "" {
+		return "", fmt.Errorf("cloudflare DNS provider requested but no API token Secret is configured")
+	}
+
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	var secret corev1.Secret
+	if err := reader.Get(ctx, r.CloudflareTokenSecret, &secret); err != nil {
+		return "", fmt.Errorf("failed to read Cloudflare API token Secret %s: %w", r.CloudflareTokenSecret, err)
+	}
+	token := strings.TrimSpace(string(secret.Data[cloudflareTokenKey]))
+	if token == "" {
+		return "", fmt.Errorf("secret %s has no %q key", r.CloudflareTokenSecret, cloudflareTokenKey)
+	}
+	return token, nil
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ReasonValidationRecordsRequired is recorded when validation records must be created by hand
+const ReasonValidationRecordsRequired = "ValidationRecordsRequired"
+
+// ManualDNSProvider writes nothing: it publishes the required validation records as an
+// event on Object, and through Annotate when set, so a human or external tooling can create them
+type ManualDNSProvider struct {
+	Recorder record.EventRecorder
+	Object   runtime.Object
+	Annotate func(ctx context.Context, records []ValidationRecord) error
+}
+
+func (p *ManualDNSProvider) FindZone(_ context.Context, _ string) (string, error) {
+	return "", nil
+}
+
+func (p *ManualDNSProvider) EnsureRecords(ctx context.Context, _ string, records []ValidationRecord) error {
+	lines := make([]string, 0, len(records))
+	for _, rec := range records {
+		lines = append(lines, fmt.Sprintf("%s %s %s", rec.Name, rec.Type, rec.Value))
+	}
+	message := "Create these DNS records to validate the certificate: " + strings.Join(lines, "; ")
+
+	log.FromContext(ctx).Info("Manual DNS validation required", "records", lines)
+	if p.Recorder != nil && p.Object != nil {
+		p.Recorder.Event(p.Object, corev1.EventTypeNormal, ReasonValidationRecordsRequired, message)
+	}
+	if p.Annotate != nil {
+		return p.Annotate(ctx, records)
+	}
+	return nil
+}
+
+func (p *ManualDNSProvider) DeleteRecords(_ context.Context, _ string, _ []ValidationRecord) error {
+	return nil
+}
@@ -0,0 +1,386 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/tedens/acm-manager/pkg/certs"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// fakeDNSProvider records every EnsureRecords call
+type fakeDNSProvider struct {
+	mu      sync.Mutex
+	zone    string
+	ensured []ValidationRecord
+}
+
+func (f *fakeDNSProvider) FindZone(_ context.Context, _ string) (string, error) {
+	return f.zone, nil
+}
+
+func (f *fakeDNSProvider) EnsureRecords(_ context.Context, _ string, records []ValidationRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ensured = append(f.ensured, records...)
+	return nil
+}
+
+func (f *fakeDNSProvider) DeleteRecords(_ context.Context, _ string, _ []ValidationRecord) error {
+	return nil
+}
+
+func TestReconcileUsesInjectedDNSProvider(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), nil, ingress)
+	provider := &fakeDNSProvider{zone: "Z1"}
+	r.DNSProvider = provider
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(provider.ensured) != 1 || provider.ensured[0].Name != "_acme.app.example.com." {
+		t.Fatalf("expected the validation record to go through the provider, got %+v", provider.ensured)
+	}
+}
+
+func TestManualDNSProviderRecordsEvent(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/dns-provider": "manual"})
+	r := newTestReconciler(t, newFakeACM(), nil, ingress)
+	r.DNSProvider = nil
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	recorder := r.Recorder.(*record.FakeRecorder)
+	found := false
+	for len(recorder.Events) > 0 {
+		event := <-recorder.Events
+		if strings.Contains(event, ReasonValidationRecordsRequired) && strings.Contains(event, "_acme.app.example.com.") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected a ValidationRecordsRequired event listing the record")
+	}
+}
+
+func TestUnknownDNSProviderFails(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/dns-provider": "bind9"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err == nil || !strings.Contains(err.Error(), "bind9") {
+		t.Fatalf("expected unknown provider error, got %v", err)
+	}
+}
+
+// fakeCloudflare serves the subset of the Cloudflare v4 API used by CloudflareProvider
+type fakeCloudflare struct {
+	mu      sync.Mutex
+	token   string
+	records map[string]cloudflareRecord
+	nextID  int
+}
+
+func (f *fakeCloudflare) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(result interface{}) {
+		data, _ := json.Marshal(result)
+		_ = json.NewEncoder(w).Encode(cloudflareResponse{Success: true, Result: data})
+	}
+	if req.Header.Get("Authorization") != "Bearer "+f.token {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(cloudflareResponse{Errors: []cloudflareError{{Code: 9109, Message: "invalid token"}}})
+		return
+	}
+
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/zones":
+		if req.URL.Query().Get("name") == "example.com" {
+			reply([]cloudflareZone{{ID: "cfzone", Name: "example.com"}})
+			return
+		}
+		reply([]cloudflareZone{})
+	case req.Method == http.MethodGet && req.URL.Path == "/zones/cfzone/dns_records":
+		var matches []cloudflareRecord
+		for _, rec := range f.records {
+			if rec.Name == req.URL.Query().Get("name") && rec.Type == req.URL.Query().Get("type") {
+				matches = append(matches, rec)
+			}
+		}
+		reply(matches)
+	case req.Method == http.MethodPost && req.URL.Path == "/zones/cfzone/dns_records":
+		var rec cloudflareRecord
+		_ = json.NewDecoder(req.Body).Decode(&rec)
+		f.nextID++
+		rec.ID = fmt.Sprintf("rec%d", f.nextID)
+		f.records[rec.ID] = rec
+		reply(rec)
+	case req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, "/zones/cfzone/dns_records/"):
+		delete(f.records, strings.TrimPrefix(req.URL.Path, "/zones/cfzone/dns_records/"))
+		reply(map[string]string{})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(cloudflareResponse{Errors: []cloudflareError{{Message: "not found"}}})
+	}
+}
+
+func TestCloudflareProviderLifecycle(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeCloudflare{token: "secret-token", records: map[string]cloudflareRecord{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	provider := NewCloudflareProvider("secret-token")
+	provider.BaseURL = server.URL
+
+	zone, err := provider.FindZone(ctx, "*.app.example.com")
+	if err != nil || zone != "cfzone" {
+		t.Fatalf("FindZone = %q, %v", zone, err)
+	}
+
+	records := []ValidationRecord{{Domain: "app.example.com", Name: "_x.app.example.com.", Type: "CNAME", Value: "_y.acm-validations.aws."}}
+	if err := provider.EnsureRecords(ctx, "", records); err != nil {
+		t.Fatalf("EnsureRecords: %v", err)
+	}
+	// A second ensure must not duplicate the record
+	if err := provider.EnsureRecords(ctx, "", records); err != nil {
+		t.Fatalf("second EnsureRecords: %v", err)
+	}
+	if len(fake.records) != 1 {
+		t.Fatalf("expected exactly one record, got %+v", fake.records)
+	}
+	for _, rec := range fake.records {
+		if rec.Name != "_x.app.example.com" || rec.Content != "_y.acm-validations.aws" || rec.Proxied {
+			t.Fatalf("unexpected record %+v", rec)
+		}
+	}
+
+	if err := provider.DeleteRecords(ctx, "cfzone", records); err != nil {
+		t.Fatalf("DeleteRecords: %v", err)
+	}
+	if len(fake.records) != 0 {
+		t.Fatalf("expected record to be deleted, got %+v", fake.records)
+	}
+}
+
+func TestCloudflareTokenFromSecret(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "acm-manager", Name: "cloudflare"},
+		Data:       map[string][]byte{"api-token": []byte("secret-token\n")},
+	}
+	r := newTestReconciler(t, newFakeACM(), nil, secret)
+	r.CloudflareTokenSecret = types.NamespacedName{Namespace: "acm-manager", Name: "cloudflare"}
+
+	token, err := r.cloudflareToken(context.Background())
+	if err != nil || token != "secret-token" {
+		t.Fatalf("cloudflareToken = %q, %v", token, err)
+	}
+}
+
+func TestNoRoute53PublishesRecordsOnIngress(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), nil, ingress)
+	r.DNSProvider = nil
+	r.NoRoute53 = true
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	var records []annotatedRecord
+	if err := json.Unmarshal([]byte(got.Annotations[validationRecordsAnnotation]), &records); err != nil {
+		t.Fatalf("validation-records annotation: %v", err)
+	}
+	if len(records) != 1 || records[0].Name != "_acme.app.example.com." || records[0].Type != "CNAME" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+	if got.Annotations["alb.ingress.kubernetes.io/certificate-arn"] == "" {
+		t.Fatal("certificate should still be attached once ACM issues it")
+	}
+
+	explicit := newManagedIngress("explicit", "api.example.com", map[string]string{"acm.tedens.dev/dns-provider": "route53"})
+	if err := r.Create(ctx, explicit); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(explicit)); err == nil || !strings.Contains(err.Error(), "--no-route53") {
+		t.Fatalf("explicit route53 should be rejected, got %v", err)
+	}
+}
+
+func TestMissingHostedZoneRequeuesLater(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.unknown.org", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	result, err := r.Reconcile(ctx, requestFor(ingress))
+	if err != nil {
+		t.Fatalf("a missing hosted zone should not be retried as an error, got %v", err)
+	}
+	if result.RequeueAfter != noHostedZoneRequeue {
+		t.Fatalf("expected a requeue after %s, got %s", noHostedZoneRequeue, result.RequeueAfter)
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("no certificate should be requested without a zone, got %d", len(fakeACM.requests))
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if msg := got.Annotations[lastErrorAnnotation]; !strings.Contains(msg, "no matching public hosted zone") {
+		t.Fatalf("expected the missing zone in last-error, got %q", msg)
+	}
+	found := false
+	for events := r.Recorder.(*record.FakeRecorder).Events; len(events) > 0; {
+		if e := <-events; strings.HasPrefix(e, "Warning CertificateFailed") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected a CertificateFailed warning event")
+	}
+}
+
+func TestWebhookDNSProviderAsDefault(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var received []dnsWebhookRequest
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body dnsWebhookRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Errorf("decode webhook body: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	fakeR53 := newFakeRoute53("example.com")
+	r := newTestReconciler(t, newFakeACM(), fakeR53, ingress)
+	r.DefaultDNSProvider = DNSProviderWebhook
+	r.DNSWebhookURL = server.URL
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	mu.Lock()
+	if len(received) != 1 || received[0].Action != dnsWebhookActionUpsert ||
+		len(received[0].Records) != 1 || received[0].Records[0].Name != "_acme.app.example.com." {
+		t.Fatalf("expected one UPSERT of the validation record, got %+v", received)
+	}
+	mu.Unlock()
+	if len(fakeR53.changes) != 0 {
+		t.Fatalf("the webhook default must not write to Route 53, got %d changes", len(fakeR53.changes))
+	}
+
+	provider := NewWebhookDNSProvider(server.URL)
+	records := []ValidationRecord{{Domain: "app.example.com", Name: "_acme.app.example.com.", Type: "CNAME", Value: "_v.acm-validations.aws."}}
+	if err := provider.DeleteRecords(ctx, "", records); err != nil {
+		t.Fatalf("DeleteRecords: %v", err)
+	}
+	mu.Lock()
+	if last := received[len(received)-1]; last.Action != dnsWebhookActionDelete {
+		t.Fatalf("expected a DELETE, got %+v", last)
+	}
+	status = http.StatusBadGateway
+	mu.Unlock()
+	if err := provider.EnsureRecords(ctx, "", records); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("a failing webhook must fail the write, got %v", err)
+	}
+}
+
+func TestDNSProviderAnnotationOverridesDefault(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/dns-provider": "route53"})
+	fakeR53 := newFakeRoute53("example.com")
+	r := newTestReconciler(t, newFakeACM(), fakeR53, ingress)
+	r.DefaultDNSProvider = DNSProviderWebhook
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeR53.changes) != 1 {
+		t.Fatalf("the annotation must select Route 53, got %d changes", len(fakeR53.changes))
+	}
+
+	other := newManagedIngress("api", "api.example.com", nil)
+	if _, err := r.dnsProviderFor(ctx, other, ParseIngressAnnotations(other.Annotations)); err == nil || !strings.Contains(err.Error(), "--dns-webhook-url") {
+		t.Fatalf("expected the webhook default to require a URL, got %v", err)
+	}
+}
+
+// unsupportedRecordACM returns validation records of a type Route 53 has no support for
+type unsupportedRecordACM struct {
+	*fakeACM
+}
+
+func (f unsupportedRecordACM) DescribeCertificate(ctx context.Context, in *acm.DescribeCertificateInput, optFns ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error) {
+	out, err := f.fakeACM.DescribeCertificate(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	detail := *out.Certificate
+	detail.DomainValidationOptions = slices.Clone(detail.DomainValidationOptions)
+	for i, validation := range detail.DomainValidationOptions {
+		if validation.ResourceRecord != nil {
+			record := *validation.ResourceRecord
+			record.Type = "BOGUS"
+			detail.DomainValidationOptions[i].ResourceRecord = &record
+		}
+	}
+	return &acm.DescribeCertificateOutput{Certificate: &detail}, nil
+}
+
+func TestUnsupportedValidationRecordWarns(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.holdPending = true
+	fakeR53 := newFakeRoute53("example.com")
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, fakeR53, ingress)
+	r.ACMClient = unsupportedRecordACM{fakeACM}
+
+	var unsupported *certs.UnsupportedRecordTypeError
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); !errors.As(err, &unsupported) {
+		t.Fatalf("Reconcile = %v, want an UnsupportedRecordTypeError", err)
+	}
+	if len(fakeR53.changes) != 0 {
+		t.Fatalf("no validation record may be written, got %d changes", len(fakeR53.changes))
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if !slices.ContainsFunc(events, func(e string) bool {
+		return strings.HasPrefix(e, "Warning "+ReasonUnsupportedValidationRecord) && strings.Contains(e, "BOGUS")
+	}) {
+		t.Fatalf("events = %v, want an %s warning naming the type", events, ReasonUnsupportedValidationRecord)
+	}
+}
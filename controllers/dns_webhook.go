@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Actions of a DNS webhook request
+const (
+	dnsWebhookActionUpsert = "UPSERT"
+	dnsWebhookActionDelete = "DELETE"
+)
+
+// dnsWebhookRequest is the JSON body POSTed to the DNS webhook
+type dnsWebhookRequest struct {
+	Action  string             `json:"action"`
+	ZoneID  string             `json:"zoneId,omitempty"`
+	Records []ValidationRecord `json:"records"`
+}
+
+// WebhookDNSProvider hands validation records to an external service, which creates or
+// deletes them in whatever DNS it manages. Any 2xx response is success; anything else fails
+// the reconcile so the records are sent again.
+type WebhookDNSProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookDNSProvider returns a provider POSTing records to url
+func NewWebhookDNSProvider(url string) *WebhookDNSProvider {
+	return &WebhookDNSProvider{URL: url, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// FindZone leaves zone selection to the external service
+func (p *WebhookDNSProvider) FindZone(_ context.Context, _ string) (string, error) {
+	return "", nil
+}
+
+func (p *WebhookDNSProvider) EnsureRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	return p.post(ctx, dnsWebhookRequest{Action: dnsWebhookActionUpsert, ZoneID: zoneID, Records: records})
+}
+
+func (p *WebhookDNSProvider) DeleteRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	return p.post(ctx, dnsWebhookRequest{Action: dnsWebhookActionDelete, ZoneID: zoneID, Records: records})
+}
+
+func (p *WebhookDNSProvider) post(ctx context.Context, request dnsWebhookRequest) error {
+	if len(request.Records) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	log.FromContext(ctx).Info("Sending validation records to the DNS webhook", "action", request.Action, "records", len(request.Records))
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call DNS webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("DNS webhook returned %s for %s", resp.Status, request.Action)
+	}
+	return nil
+}
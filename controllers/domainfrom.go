@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"slices"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// domainFromAnnotation names where the domain of an Ingress without rule hosts comes from
+	domainFromAnnotation = "acm.tedens.dev/domain-from"
+	// DomainFromStatusLB takes the domain from the load balancer hostname in the Ingress status
+	DomainFromStatusLB = "status-lb"
+)
+
+// statusLBHostname returns the first load balancer hostname in the Ingress status
+func statusLBHostname(ingress *networkingv1.Ingress) string {
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if host := strings.TrimSpace(lb.Hostname); host != "" {
+			return strings.ToLower(host)
+		}
+	}
+	return ""
+}
+
+// hasRuleHosts reports whether any rule of the Ingress sets a host
+func hasRuleHosts(ingress *networkingv1.Ingress) bool {
+	return slices.ContainsFunc(ingress.Spec.Rules, func(rule networkingv1.IngressRule) bool { return rule.Host != "" })
+}
+
+// statusLBHostnameChanged reports whether an Ingress taking its domain from the load balancer
+// got a different hostname. Status updates do not bump the generation, so they are let
+// through explicitly.
+func statusLBHostnameChanged(oldObj, newObj client.Object) bool {
+	oldIngress, ok := oldObj.(*networkingv1.Ingress)
+	if !ok {
+		return false
+	}
+	newIngress, ok := newObj.(*networkingv1.Ingress)
+	if !ok {
+		return false
+	}
+	if strings.ToLower(strings.TrimSpace(newIngress.Annotations[domainFromAnnotation])) != DomainFromStatusLB {
+		return false
+	}
+	return statusLBHostname(oldIngress) != statusLBHostname(newIngress)
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func TestDomainFromStatusLoadBalancer(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "", map[string]string{domainFromAnnotation: DomainFromStatusLB})
+	ingress.UID = "uid-web"
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.NoHostRequeueAttempts = 1
+
+	// Waiting for the load balancer outlasts the no-host checks
+	for i := 1; i <= 3; i++ {
+		result, err := r.Reconcile(ctx, requestFor(ingress))
+		if err != nil || result.RequeueAfter != DefaultNoHostRequeueInterval {
+			t.Fatalf("check %d: expected a requeue until the hostname is set, got %+v, %v", i, result, err)
+		}
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("nothing should be requested without a hostname, got %d requests", len(fakeACM.requests))
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	old := got.DeepCopy()
+	got.Status.LoadBalancer.Ingress = []networkingv1.IngressLoadBalancerIngress{{IP: "10.0.0.1"}, {Hostname: "LB.example.com"}}
+	if !statusLBHostnameChanged(old, &got) {
+		t.Error("a new load balancer hostname should pass the update predicate")
+	}
+	if err := r.Status().Update(ctx, &got); err != nil {
+		t.Fatalf("update ingress status: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile with a hostname: %v", err)
+	}
+	if len(fakeACM.requests) != 1 || aws.ToString(fakeACM.requests[0].DomainName) != "lb.example.com" {
+		t.Fatalf("expected a certificate for the load balancer hostname, got %d requests", len(fakeACM.requests))
+	}
+	if certificateArnOf(t, r, ingress) == "" {
+		t.Fatal("expected the certificate to be attached")
+	}
+}
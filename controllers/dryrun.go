@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// updateIngress writes finalizer changes, or only logs them when IngressDryRun or ObserveOnly
+// is set
+func (r *IngressReconciler) updateIngress(ctx context.Context, ingress *networkingv1.Ingress) error {
+	if r.ObserveOnly {
+		log.FromContext(ctx).Info("Observe-only: finalizers not changed", "finalizers", ingress.Finalizers)
+		return nil
+	}
+	if r.IngressDryRun {
+		log.FromContext(ctx).Info("Dry-run: Ingress update not applied", "finalizers", ingress.Finalizers)
+		return nil
+	}
+	err := r.Update(ctx, ingress)
+	if r.Audit != nil {
+		r.Audit.IngressUpdated(ctx, ingress, err)
+	}
+	return err
+}
+
+// patchIngress applies the patch, or logs the JSON merge patch it would send when IngressDryRun is set
+func (r *IngressReconciler) patchIngress(ctx context.Context, ingress *networkingv1.Ingress, patch client.Patch) error {
+	if r.IngressDryRun {
+		data, err := patch.Data(ingress)
+		if err != nil {
+			return fmt.Errorf("failed to compute ingress patch: %w", err)
+		}
+		log.FromContext(ctx).Info("Dry-run: Ingress patch not applied", "patch", string(data))
+		return nil
+	}
+	if r.Audit == nil {
+		return r.Patch(ctx, ingress, patch)
+	}
+	// The patch is computed against the Ingress before the call replaces it with the response
+	audited := ingress.DeepCopy()
+	err := r.Patch(ctx, ingress, patch)
+	r.Audit.IngressPatched(ctx, audited, patch, err)
+	return err
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func TestReconcileIngressDryRunLeavesIngressUntouched(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.IngressDryRun = true
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if len(got.Finalizers) != 0 {
+		t.Fatalf("dry-run must not add finalizers, got %v", got.Finalizers)
+	}
+	if _, ok := got.Annotations["alb.ingress.kubernetes.io/certificate-arn"]; ok {
+		t.Fatal("dry-run must not patch the certificate-arn annotation")
+	}
+}
+
+func TestReconcileAppliesPatchWithoutDryRun(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if got.Annotations["alb.ingress.kubernetes.io/certificate-arn"] == "" {
+		t.Fatal("expected the certificate-arn annotation to be patched")
+	}
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// DefaultEventDedupWindow is how long identical events are aggregated by default
+const DefaultEventDedupWindow = 10 * time.Minute
+
+// maxEventsPerObject caps the events recorded on one object per dedup window
+const maxEventsPerObject = 20
+
+// DedupRecorder is an EventRecorder that drops repeats of an event already recorded on the same
+// object within the window, keyed by type, reason and message template (the format string of
+// Eventf). The next occurrence after the window carries the number of events suppressed in
+// between. Each object gets at most maxEventsPerObject events per window.
+type DedupRecorder struct {
+	inner  record.EventRecorder
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	events    map[dedupKey]*dedupEntry
+	objects   map[string]*objectEvents
+	lastPrune time.Time
+}
+
+type dedupKey struct {
+	object, eventtype, reason, template string
+}
+
+type dedupEntry struct {
+	recorded   time.Time
+	suppressed int
+}
+
+type objectEvents struct {
+	start time.Time
+	count int
+}
+
+// NewDedupRecorder wraps inner, aggregating identical events within window
+func NewDedupRecorder(inner record.EventRecorder, window time.Duration) *DedupRecorder {
+	return &DedupRecorder{
+		inner:   inner,
+		window:  window,
+		now:     time.Now,
+		events:  map[dedupKey]*dedupEntry{},
+		objects: map[string]*objectEvents{},
+	}
+}
+
+func (d *DedupRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if message, ok := d.admit(object, eventtype, reason, message, message); ok {
+		d.inner.Event(object, eventtype, reason, message)
+	}
+}
+
+func (d *DedupRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if message, ok := d.admit(object, eventtype, reason, messageFmt, fmt.Sprintf(messageFmt, args...)); ok {
+		d.inner.Event(object, eventtype, reason, message)
+	}
+}
+
+func (d *DedupRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	if message, ok := d.admit(object, eventtype, reason, messageFmt, fmt.Sprintf(messageFmt, args...)); ok {
+		d.inner.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+	}
+}
+
+// admit reports whether the event should be recorded, returning its message with the count
+// of suppressed repeats appended
+func (d *DedupRecorder) admit(object runtime.Object, eventtype, reason, template, message string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	d.prune(now)
+
+	id := objectID(object)
+	key := dedupKey{object: id, eventtype: eventtype, reason: reason, template: template}
+	entry := d.events[key]
+	if entry != nil && now.Sub(entry.recorded) < d.window {
+		entry.suppressed++
+		return "", false
+	}
+
+	counter := d.objects[id]
+	if counter == nil || now.Sub(counter.start) >= d.window {
+		counter = &objectEvents{start: now}
+		d.objects[id] = counter
+	}
+	if counter.count >= maxEventsPerObject {
+		// Over the cap: count it against the key so the next recorded one reports it
+		if entry == nil {
+			entry = &dedupEntry{}
+			d.events[key] = entry
+		}
+		entry.recorded = now
+		entry.suppressed++
+		return "", false
+	}
+	counter.count++
+
+	if entry != nil && entry.suppressed > 0 {
+		message = fmt.Sprintf("%s (%d similar events suppressed in the last %s)", message, entry.suppressed, d.window)
+	}
+	d.events[key] = &dedupEntry{recorded: now}
+	return message, true
+}
+
+// prune forgets events and objects whose window ended, at most once per window
+func (d *DedupRecorder) prune(now time.Time) {
+	if now.Sub(d.lastPrune) < d.window {
+		return
+	}
+	d.lastPrune = now
+	for key, entry := range d.events {
+		// Entries with suppressed repeats are kept one more window so the count can be reported
+		expiry := d.window
+		if entry.suppressed > 0 {
+			expiry *= 2
+		}
+		if now.Sub(entry.recorded) >= expiry {
+			delete(d.events, key)
+		}
+	}
+	for id, counter := range d.objects {
+		if now.Sub(counter.start) >= d.window {
+			delete(d.objects, id)
+		}
+	}
+}
+
+// objectID identifies the object an event is recorded on
+func objectID(object runtime.Object) string {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return fmt.Sprintf("%T", object)
+	}
+	if uid := accessor.GetUID(); uid != "" {
+		return string(uid)
+	}
+	return fmt.Sprintf("%T/%s/%s", object, accessor.GetNamespace(), accessor.GetName())
+}
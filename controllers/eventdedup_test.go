@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func newTestDedupRecorder(window time.Duration) (*DedupRecorder, *record.FakeRecorder, *time.Time) {
+	fake := record.NewFakeRecorder(100)
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDedupRecorder(fake, window)
+	d.now = func() time.Time { return clock }
+	return d, fake, &clock
+}
+
+func drainEvents(fake *record.FakeRecorder) []string {
+	var events []string
+	for len(fake.Events) > 0 {
+		events = append(events, <-fake.Events)
+	}
+	return events
+}
+
+func TestDedupRecorderAggregatesWithinWindow(t *testing.T) {
+	d, fake, clock := newTestDedupRecorder(10 * time.Minute)
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	ingress.UID = types.UID("uid-1")
+
+	// A stuck certificate failing every 15 seconds, with a different request ID each time
+	for i := 0; i < 20; i++ {
+		d.Eventf(ingress, corev1.EventTypeWarning, ReasonCertificateFailed, "Certificate for %s failed: %s", "app.example.com", fmt.Sprintf("RequestID: %d", i))
+		*clock = clock.Add(15 * time.Second)
+	}
+	if events := drainEvents(fake); len(events) != 1 || events[0] != "Warning CertificateFailed Certificate for app.example.com failed: RequestID: 0" {
+		t.Fatalf("expected only the first event inside the window, got %q", events)
+	}
+
+	// A different reason is its own event
+	d.Event(ingress, corev1.EventTypeNormal, ReasonValidationProgress, "Validation of arn: app.example.com=PENDING_VALIDATION")
+	if events := drainEvents(fake); len(events) != 1 {
+		t.Fatalf("expected an event with another reason to be recorded, got %q", events)
+	}
+
+	// After the window the next occurrence is recorded with the suppressed count
+	*clock = clock.Add(10 * time.Minute)
+	d.Eventf(ingress, corev1.EventTypeWarning, ReasonCertificateFailed, "Certificate for %s failed: %s", "app.example.com", "RequestID: 99")
+	want := "Warning CertificateFailed Certificate for app.example.com failed: RequestID: 99 (19 similar events suppressed in the last 10m0s)"
+	if events := drainEvents(fake); len(events) != 1 || events[0] != want {
+		t.Fatalf("expected %q, got %q", want, events)
+	}
+
+	// Other objects are deduplicated separately
+	other := newManagedIngress("api", "api.example.com", nil)
+	other.UID = types.UID("uid-2")
+	d.Eventf(other, corev1.EventTypeWarning, ReasonCertificateFailed, "Certificate for %s failed: %s", "api.example.com", "boom")
+	if events := drainEvents(fake); len(events) != 1 {
+		t.Fatalf("expected the other Ingress' event to be recorded, got %q", events)
+	}
+}
+
+func TestDedupRecorderCapsEventsPerObject(t *testing.T) {
+	d, fake, clock := newTestDedupRecorder(time.Minute)
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	ingress.UID = types.UID("uid-1")
+
+	for i := 0; i < maxEventsPerObject+5; i++ {
+		d.Event(ingress, corev1.EventTypeNormal, ReasonValidationProgress, fmt.Sprintf("message %d", i))
+	}
+	if events := drainEvents(fake); len(events) != maxEventsPerObject {
+		t.Fatalf("expected %d events before the cap, got %d", maxEventsPerObject, len(events))
+	}
+
+	*clock = clock.Add(time.Minute)
+	d.Event(ingress, corev1.EventTypeNormal, ReasonValidationProgress, "message 0")
+	if events := drainEvents(fake); len(events) != 1 {
+		t.Fatalf("expected the cap to reset with the window, got %q", events)
+	}
+}
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Event reasons recorded on Ingresses
+const (
+	ReasonCertificateIssued   = "CertificateIssued"
+	ReasonCertificateFailed   = "CertificateFailed"
+	ReasonCertificateExpiring = "CertificateExpiring"
+	ReasonValidationProgress  = "ValidationProgress"
+	ReasonCertificateImported = "CertificateImported"
+	ReasonCertificateMissing  = "CertificateMissing"
+	ReasonCertificateMismatch = "CertificateMismatch"
+	ReasonNameTemplateFailed  = "NameTemplateFailed"
+	ReasonNoHosts             = "NoHosts"
+	ReasonQuarantined         = "Quarantined"
+	ReasonPaused              = "Paused"
+	// ReasonCertificateArnOverridden and ReasonCertificateArnConflict are recorded when the
+	// certificate ARN annotation was changed outside the controller
+	ReasonCertificateArnOverridden = "CertificateArnOverridden"
+	ReasonCertificateArnConflict   = "CertificateArnConflict"
+	// ReasonOperationDeferred is recorded when a change waits for the maintenance window
+	ReasonOperationDeferred = "OperationDeferred"
+	// ReasonDomainTooLong is recorded when no name is short enough to be the primary domain
+	ReasonDomainTooLong = "DomainTooLong"
+	// ReasonUnsupportedValidationRecord is recorded when ACM asks for a validation record the
+	// DNS provider cannot write
+	ReasonUnsupportedValidationRecord = "UnsupportedValidationRecord"
+	// ReasonRenewalIneligible is recorded when ACM cannot renew a certificate in use by itself
+	ReasonRenewalIneligible = "RenewalIneligible"
+	// ReasonValidationLagging is recorded when some names stay pending after others validated
+	ReasonValidationLagging = "ValidationLagging"
+	// ReasonPrivateKeyChanged is recorded when an import Secret's private key changed
+	ReasonPrivateKeyChanged = "PrivateKeyChanged"
+	// ReasonKeyPinningUnsupported is recorded when a certificate issued by ACM is asked to keep
+	// its private key
+	ReasonKeyPinningUnsupported = "KeyPinningUnsupported"
+	// ReasonOCSPStaplingUnsupported is recorded when an Ingress asks for OCSP stapling, which
+	// ALB listeners cannot be configured for
+	ReasonOCSPStaplingUnsupported = "OCSPStaplingUnsupported"
+	// ReasonInvalidAnnotation is recorded when an annotation's value cannot be parsed and is
+	// ignored
+	ReasonInvalidAnnotation = "InvalidAnnotation"
+	// ReasonRenewBeforeTooLong is recorded when acm.tedens.dev/renew-before is not shorter
+	// than the certificate's lifetime and the default margin is used
+	ReasonRenewBeforeTooLong = "RenewBeforeTooLong"
+	// ReasonValidationExpired is recorded when a certificate past ACM's validation window is
+	// replaced
+	ReasonValidationExpired = "ValidationExpired"
+	// ReasonCertificateTagsRestored is recorded when ownership tags removed from a certificate
+	// outside the controller are put back
+	ReasonCertificateTagsRestored = "CertificateTagsRestored"
+	// ReasonObservedPlan is recorded in observe-only mode when the changes a reconcile would
+	// make differ from the last ones reported
+	ReasonObservedPlan = "ObservedPlan"
+	// ReasonListenPortsInvalid is recorded when acm.tedens.dev/configure-https cannot merge
+	// the HTTPS listener into listen-ports, which are left alone
+	ReasonListenPortsInvalid = "ListenPortsInvalid"
+	// ReasonNotALBIngress is recorded when a managed Ingress belongs to an IngressClass of a
+	// controller that ignores the ALB certificate annotation
+	ReasonNotALBIngress = "NotALBIngress"
+	// ReasonSplitBrainSuspected is recorded when another pod recently reconciled an Ingress
+	// this pod is reconciling too
+	ReasonSplitBrainSuspected = "SplitBrainSuspected"
+	// ReasonDeletionProtected is recorded when acm.tedens.dev/deletion-protection keeps the
+	// certificate of a deleted Ingress
+	ReasonDeletionProtected = "DeletionProtected"
+	// ReasonBookkeepingDiscarded is recorded when the bookkeeping annotation could not be parsed
+	// and was dropped
+	ReasonBookkeepingDiscarded = "BookkeepingDiscarded"
+	// ReasonImportSecretNotRotated is recorded when an imported certificate is inside its
+	// renewal margin and its import Secret still holds it
+	ReasonImportSecretNotRotated = "ImportSecretNotRotated"
+	// ReasonCertificateReplicated is recorded when a replica in another region is published
+	ReasonCertificateReplicated = "CertificateReplicated"
+	// ReasonReplicationUnsupported is recorded when an Ingress whose certificate is imported,
+	// shared, split or a group's asks for replicas
+	ReasonReplicationUnsupported = "ReplicationUnsupported"
+)
+
+// notifyCertificateEvent records a Kubernetes event on the Ingress and forwards the same
+// event to the configured notifier
+func (r *IngressReconciler) notifyCertificateEvent(ingress *networkingv1.Ingress, event, domain, arn, status, reason string) {
+	// Recorded with Eventf so the deduplicating recorder groups them by message template
+	eventType, eventReason := corev1.EventTypeNormal, ReasonCertificateIssued
+	format, args := "Certificate %s issued for %s", []interface{}{arn, domain}
+	switch event {
+	case NotificationFailed:
+		eventType, eventReason = corev1.EventTypeWarning, ReasonCertificateFailed
+		format, args = "Certificate for %s failed: %s", []interface{}{domain, reason}
+	case NotificationExpiring:
+		eventType, eventReason = corev1.EventTypeWarning, ReasonCertificateExpiring
+		format, args = "Certificate %s for %s is expiring: %s", []interface{}{arn, domain, reason}
+	}
+
+	if r.Recorder != nil {
+		r.Recorder.Eventf(ingress, eventType, eventReason, format, args...)
+	}
+	if notifier := r.settings().Notifier; notifier != nil {
+		notifier.Notify(Notification{
+			Event:     event,
+			Ingress:   ingress.Namespace + "/" + ingress.Name,
+			Domain:    domain,
+			ARN:       arn,
+			Status:    status,
+			Reason:    reason,
+			Timestamp: time.Now().UTC(),
+		})
+	}
+}
+
+// validationProgressRecorder returns a callback recording each change in per-name validation
+// status on the Ingress, as a warning once any name has failed
+func (r *IngressReconciler) validationProgressRecorder(ingress *networkingv1.Ingress) func(string, []certs.DomainStatus) {
+	return func(certArn string, domains []certs.DomainStatus) {
+		if r.Recorder == nil {
+			return
+		}
+		eventType := corev1.EventTypeNormal
+		for _, domain := range domains {
+			if domain.Status == acmtypes.DomainStatusFailed {
+				eventType = corev1.EventTypeWarning
+			}
+		}
+		r.Recorder.Event(ingress, eventType, ReasonValidationProgress,
+			fmt.Sprintf("Validation of %s: %s", certArn, certs.FormatDomainStatuses(domains)))
+	}
+}
+
+// validationLagRecorder returns a callback recording a Warning event naming the names that
+// block issuance, and the record each one needs, while the certificate's other names validated
+func (r *IngressReconciler) validationLagRecorder(ingress *networkingv1.Ingress) func(string, []certs.DomainStatus) {
+	return func(certArn string, lagging []certs.DomainStatus) {
+		if r.Recorder == nil || len(lagging) == 0 {
+			return
+		}
+		parts := make([]string, 0, len(lagging))
+		for _, domain := range lagging {
+			if domain.Record == nil {
+				parts = append(parts, domain.Domain)
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("%s (needs %s %s -> %s)", domain.Domain, domain.Record.Type, domain.Record.Name, domain.Record.Value))
+		}
+		r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonValidationLagging,
+			"Validation of %s is blocked by %s while its other names validated; check that the records resolve in the zones serving those names",
+			certArn, strings.Join(parts, ", "))
+	}
+}
+
+// recordedEvents remembers, by Ingress and reason, the value each once-per-change event was
+// last recorded for
+type recordedEvents struct {
+	mu     sync.Mutex
+	values map[types.NamespacedName]map[string]string
+}
+
+// eventOnce records an event on the Ingress unless one with the same reason was recorded for
+// value already, so a setting that stays wrong is reported once, and again when it changes.
+// It does nothing without a Recorder.
+func (r *IngressReconciler) eventOnce(ingress *networkingv1.Ingress, eventType, reason, value, messageFmt string, args ...interface{}) {
+	r.recordOnce(ingress, reason, eventType, reason, value, messageFmt, args...)
+}
+
+// recordOnce is eventOnce remembering value under slot, for reasons recorded for several
+// causes at once
+func (r *IngressReconciler) recordOnce(ingress *networkingv1.Ingress, slot, eventType, reason, value, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	key := client.ObjectKeyFromObject(ingress)
+	r.recorded.mu.Lock()
+	if last, ok := r.recorded.values[key][slot]; ok && last == value {
+		r.recorded.mu.Unlock()
+		return
+	}
+	if r.recorded.values == nil {
+		r.recorded.values = map[types.NamespacedName]map[string]string{}
+	}
+	if r.recorded.values[key] == nil {
+		r.recorded.values[key] = map[string]string{}
+	}
+	r.recorded.values[key][slot] = value
+	r.recorded.mu.Unlock()
+	r.Recorder.Eventf(ingress, eventType, reason, messageFmt, args...)
+}
+
+// warnOnce is eventOnce for a Warning event
+func (r *IngressReconciler) warnOnce(ingress *networkingv1.Ingress, reason, value, messageFmt string, args ...interface{}) {
+	r.eventOnce(ingress, corev1.EventTypeWarning, reason, value, messageFmt, args...)
+}
+
+// forgetEvent lets the event of reason, or slot, be recorded again once its cause comes back;
+// an empty one forgets every event of the Ingress, as when it is deleted
+func (r *IngressReconciler) forgetEvent(key types.NamespacedName, slot string) {
+	r.recorded.mu.Lock()
+	defer r.recorded.mu.Unlock()
+	if slot == "" {
+		delete(r.recorded.values, key)
+		return
+	}
+	delete(r.recorded.values[key], slot)
+}
+
+// invalidAnnotationSlot is where the InvalidAnnotation Warning of annotation is remembered
+func invalidAnnotationSlot(annotation string) string {
+	return ReasonInvalidAnnotation + " " + annotation
+}
+
+// warnInvalidAnnotation records an InvalidAnnotation Warning for the value of annotation once
+// per value
+func (r *IngressReconciler) warnInvalidAnnotation(ingress *networkingv1.Ingress, annotation, messageFmt string, args ...interface{}) {
+	r.recordOnce(ingress, invalidAnnotationSlot(annotation), corev1.EventTypeWarning, ReasonInvalidAnnotation,
+		ingress.Annotations[annotation], messageFmt, args...)
+}
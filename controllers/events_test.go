@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestValidationEventsNameFailedSAN(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{
+		"app.example.com": acmtypes.DomainStatusSuccess,
+		"api.example.com": acmtypes.DomainStatusFailed,
+	}
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/san": "api.example.com"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err == nil {
+		t.Fatal("expected validation failure")
+	}
+
+	var progress, failed string
+	recorder := r.Recorder.(*record.FakeRecorder)
+	for len(recorder.Events) > 0 {
+		event := <-recorder.Events
+		switch {
+		case strings.Contains(event, ReasonValidationProgress):
+			progress = event
+		case strings.Contains(event, ReasonCertificateFailed):
+			failed = event
+		}
+	}
+	if !strings.HasPrefix(progress, "Warning") || !strings.Contains(progress, "app.example.com=SUCCESS") || !strings.Contains(progress, "api.example.com=FAILED") {
+		t.Fatalf("expected a per-name progress warning, got %q", progress)
+	}
+	if !strings.Contains(failed, "failed domains: api.example.com") || strings.Contains(failed, "failed domains: app.example.com") {
+		t.Fatalf("failure event should name exactly the failed SAN, got %q", failed)
+	}
+}
+
+func TestValidationEventsNameLaggingSAN(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.holdPending = true
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{
+		"app.example.com":  acmtypes.DomainStatusSuccess,
+		"shop.example.com": acmtypes.DomainStatusPendingValidation,
+	}
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/san": "shop.example.com"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.RequeuePendingValidation = true
+	r.ValidationLagThreshold = time.Nanosecond
+
+	for range 2 {
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+	}
+
+	var lagging []string
+	for _, e := range drainEvents(r.Recorder.(*record.FakeRecorder)) {
+		if strings.Contains(e, ReasonValidationLagging) {
+			lagging = append(lagging, e)
+		}
+	}
+	if len(lagging) != 1 {
+		t.Fatalf("expected one ValidationLagging event across reconciles, got %v", lagging)
+	}
+	if !strings.HasPrefix(lagging[0], "Warning") || !strings.Contains(lagging[0], "blocked by shop.example.com (needs CNAME _acme.shop.example.com.") || strings.Contains(lagging[0], "app.example.com (") {
+		t.Fatalf("the event should name exactly the lagging SAN and its record, got %q", lagging[0])
+	}
+
+	state := savedValidationState(ctx, getIngress(t, r, ingress))
+	if state == nil || state.Domains["app.example.com"] != acmtypes.DomainStatusSuccess || state.Domains["shop.example.com"] != acmtypes.DomainStatusPendingValidation {
+		t.Fatalf("expected the per-name statuses in the validation state, got %+v", state)
+	}
+	if len(state.Lagging) != 1 || state.Lagging[0] != "shop.example.com" {
+		t.Fatalf("expected the lagging name to be saved, got %v", state.Lagging)
+	}
+}
+
+func TestDeletedCertificateIsReprovisioned(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	gone := "arn:aws:acm:us-east-1:123456789012:certificate/deleted"
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		"alb.ingress.kubernetes.io/certificate-arn": gone,
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile with a deleted certificate should recover, got %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected a replacement certificate request, got %d", len(fakeACM.requests))
+	}
+	if arn := certificateArnOf(t, r, ingress); arn == gone || arn == "" {
+		t.Fatalf("expected the replacement certificate to be attached, got %q", arn)
+	}
+
+	found := false
+	for events := r.Recorder.(*record.FakeRecorder).Events; len(events) > 0; {
+		if e := <-events; strings.HasPrefix(e, "Warning "+ReasonCertificateMissing+" Certificate "+gone) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected a CertificateMissing event")
+	}
+}
+
+func TestLongDomainIsDemotedToSAN(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	long := "feature-" + strings.Repeat("x", 40) + ".pr-1234.preview.example.com"
+	ingress := newManagedIngress("web", long, map[string]string{"acm.tedens.dev/san": "preview.example.com"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 || aws.ToString(fakeACM.requests[0].DomainName) != "preview.example.com" {
+		t.Fatalf("expected a request with the short name as primary, got %d requests", len(fakeACM.requests))
+	}
+	certArn := certificateArnOf(t, r, ingress)
+	if certArn == "" {
+		t.Fatal("expected the certificate to be attached")
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil || len(fakeACM.requests) != 1 || certificateArnOf(t, r, ingress) != certArn {
+		t.Fatalf("the next reconcile must keep the certificate, got %v after %d requests", err, len(fakeACM.requests))
+	}
+}
+
+func TestLongDomainWithoutShortNameFails(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "feature-"+strings.Repeat("x", 40)+".pr-1234.preview.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err == nil {
+		t.Fatal("expected the reconcile to fail")
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("nothing ACM rejects may be requested, got %d requests", len(fakeACM.requests))
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if len(events) != 1 || !strings.Contains(events[0], "Warning "+ReasonDomainTooLong) || !strings.Contains(events[0], "64 characters") {
+		t.Fatalf("expected one DomainTooLong Warning explaining the limit, got %v", events)
+	}
+}
+
+func TestWarnOnceRecordsEachChangeOnce(t *testing.T) {
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	recorder := r.Recorder.(*record.FakeRecorder)
+
+	r.warnOnce(ingress, ReasonKeyPinningUnsupported, "a", "value %s", "a")
+	r.warnOnce(ingress, ReasonKeyPinningUnsupported, "a", "value %s", "a")
+	if events := drainEvents(recorder); len(events) != 1 {
+		t.Fatalf("events = %v, want one for an unchanged value", events)
+	}
+	r.warnOnce(ingress, ReasonKeyPinningUnsupported, "b", "value %s", "b")
+	if events := drainEvents(recorder); len(events) != 1 || !strings.Contains(events[0], "value b") {
+		t.Fatalf("events = %v, want one for the changed value", events)
+	}
+	r.forgetEvent(requestFor(ingress).NamespacedName, ReasonKeyPinningUnsupported)
+	r.warnOnce(ingress, ReasonKeyPinningUnsupported, "b", "value %s", "b")
+	if events := drainEvents(recorder); len(events) != 1 {
+		t.Fatalf("events = %v, want the warning again once forgotten", events)
+	}
+
+	r.Recorder = nil
+	r.warnOnce(ingress, ReasonKeyPinningUnsupported, "c", "value %s", "c")
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// validationExpiredReplacements counts certificates replaced because ACM's validation window
+// passed before they validated
+var validationExpiredReplacements = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "acm_manager_certificate_validation_expired_total",
+	Help: "Certificate requests replaced because they did not validate within ACM's 72-hour validation window, by ACM status.",
+}, []string{"status"})
+
+// validationExpiredRecorder returns a callback counting each certificate replaced past the
+// validation window and recording a Warning event explaining the replacement on the Ingress
+func (r *IngressReconciler) validationExpiredRecorder(ingress *networkingv1.Ingress) func(string, acmtypes.CertificateStatus, bool) {
+	return func(certArn string, status acmtypes.CertificateStatus, deleted bool) {
+		validationExpiredReplacements.WithLabelValues(string(status)).Inc()
+		if r.Recorder == nil {
+			return
+		}
+		action := "deleted it"
+		if !deleted {
+			action = "left it alone since the controller does not own it"
+		}
+		r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonValidationExpired,
+			"Certificate %s did not validate within ACM's 72-hour validation window (status %s); the controller %s and requested a new certificate. Check that the DNS validation records resolve",
+			certArn, status, action)
+	}
+}
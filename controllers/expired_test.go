@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/tools/record"
+)
+
+// validationExpiredCount returns the acm_manager_certificate_validation_expired_total value for status
+func validationExpiredCount(t *testing.T, status acmtypes.CertificateStatus) float64 {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(validationExpiredReplacements)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, l := range metric.GetLabel() {
+				if l.GetName() == "status" && l.GetValue() == string(status) {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestExpiredValidationIsReplaced(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	stale := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusPendingValidation, map[string]string{"ManagedBy": DefaultManagedByValue})
+	fakeACM.certs[stale].CreatedAt = aws.Time(time.Now().Add(-73 * time.Hour))
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	before := validationExpiredCount(t, acmtypes.CertificateStatusPendingValidation)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if !slices.Contains(fakeACM.deleted, stale) {
+		t.Fatalf("expected the request past the validation window to be deleted, deleted %v", fakeACM.deleted)
+	}
+	if arn := certificateArnOf(t, r, ingress); arn == "" || arn == stale || len(fakeACM.requests) != 1 {
+		t.Fatalf("expected a fresh certificate to be requested and attached, got %q after %d requests", arn, len(fakeACM.requests))
+	}
+	if got := validationExpiredCount(t, acmtypes.CertificateStatusPendingValidation); got != before+1 {
+		t.Fatalf("expected the replacement to be counted, got %v", got-before)
+	}
+	found := false
+	for _, e := range drainEvents(r.Recorder.(*record.FakeRecorder)) {
+		found = found || (strings.HasPrefix(e, "Warning "+ReasonValidationExpired) && strings.Contains(e, stale))
+	}
+	if !found {
+		t.Fatal("expected a ValidationExpired event naming the replaced certificate")
+	}
+}
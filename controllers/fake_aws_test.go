@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// fakeACM is an in-memory ACMAPI. Requested certificates get a DNS validation
+// record immediately and are issued on the first describe unless holdPending is set; a
+// repeated idempotency token returns the certificate first requested with it;
+// seeded certificates keep the status they were added with. domainStatus overrides the
+// validation status of names on requested certificates; any FAILED name fails the certificate.
+type fakeACM struct {
+	mu           sync.Mutex
+	certs        map[string]*acmtypes.CertificateDetail
+	tags         map[string][]acmtypes.Tag
+	requests     []*acm.RequestCertificateInput
+	deleted      []string
+	requested    map[string]bool
+	holdPending  bool
+	domainStatus map[string]acmtypes.DomainStatus
+	tokens       map[string]string
+	imports      []*acm.ImportCertificateInput
+	lists        int
+	next         int
+}
+
+func newFakeACM() *fakeACM {
+	return &fakeACM{
+		certs:     map[string]*acmtypes.CertificateDetail{},
+		tags:      map[string][]acmtypes.Tag{},
+		requested: map[string]bool{},
+		tokens:    map[string]string{},
+	}
+}
+
+// addCert seeds a certificate with the given domain, status and tags
+func (f *fakeACM) addCert(domain string, status acmtypes.CertificateStatus, tags map[string]string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	arn := f.newArnLocked()
+	f.certs[arn] = &acmtypes.CertificateDetail{
+		CertificateArn:          aws.String(arn),
+		DomainName:              aws.String(domain),
+		SubjectAlternativeNames: []string{domain},
+		Status:                  status,
+		DomainValidationOptions: []acmtypes.DomainValidation{
+			validationFor(domain),
+		},
+	}
+	for k, v := range tags {
+		f.tags[arn] = append(f.tags[arn], acmtypes.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return arn
+}
+
+func (f *fakeACM) newArnLocked() string {
+	f.next++
+	return fmt.Sprintf("arn:aws:acm:us-east-1:123456789012:certificate/%04d", f.next)
+}
+
+func validationFor(domain string) acmtypes.DomainValidation {
+	name := "_acme." + strings.TrimPrefix(domain, "*.") + "."
+	return acmtypes.DomainValidation{
+		DomainName: aws.String(domain),
+		ResourceRecord: &acmtypes.ResourceRecord{
+			Name:  aws.String(name),
+			Type:  acmtypes.RecordTypeCname,
+			Value: aws.String("_validate.acm-validations.aws."),
+		},
+		ValidationStatus: acmtypes.DomainStatusPendingValidation,
+	}
+}
+
+func (f *fakeACM) RequestCertificate(_ context.Context, in *acm.RequestCertificateInput, _ ...func(*acm.Options)) (*acm.RequestCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, in)
+	if arn, ok := f.tokens[aws.ToString(in.IdempotencyToken)]; ok {
+		return &acm.RequestCertificateOutput{CertificateArn: aws.String(arn)}, nil
+	}
+	arn := f.newArnLocked()
+	if in.IdempotencyToken != nil {
+		f.tokens[*in.IdempotencyToken] = arn
+	}
+	detail := &acmtypes.CertificateDetail{
+		CertificateArn:          aws.String(arn),
+		DomainName:              in.DomainName,
+		SubjectAlternativeNames: append([]string{aws.ToString(in.DomainName)}, in.SubjectAlternativeNames...),
+		Status:                  acmtypes.CertificateStatusPendingValidation,
+		KeyAlgorithm:            in.KeyAlgorithm,
+		DomainValidationOptions: []acmtypes.DomainValidation{validationFor(aws.ToString(in.DomainName))},
+	}
+	for _, san := range in.SubjectAlternativeNames {
+		detail.DomainValidationOptions = append(detail.DomainValidationOptions, validationFor(san))
+	}
+	f.certs[arn] = detail
+	f.requested[arn] = true
+	f.tags[arn] = append([]acmtypes.Tag(nil), in.Tags...)
+	return &acm.RequestCertificateOutput{CertificateArn: aws.String(arn)}, nil
+}
+
+func (f *fakeACM) DescribeCertificate(_ context.Context, in *acm.DescribeCertificateInput, _ ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	detail, ok := f.certs[aws.ToString(in.CertificateArn)]
+	if !ok {
+		return nil, &acmtypes.ResourceNotFoundException{Message: aws.String("certificate not found")}
+	}
+	if f.requested[aws.ToString(in.CertificateArn)] && detail.Status == acmtypes.CertificateStatusPendingValidation {
+		failed := false
+		for i, option := range detail.DomainValidationOptions {
+			if status, ok := f.domainStatus[aws.ToString(option.DomainName)]; ok {
+				detail.DomainValidationOptions[i].ValidationStatus = status
+				failed = failed || status == acmtypes.DomainStatusFailed
+			}
+		}
+		switch {
+		case failed:
+			detail.Status = acmtypes.CertificateStatusFailed
+			detail.FailureReason = acmtypes.FailureReasonCaaError
+		case !f.holdPending:
+			detail.Status = acmtypes.CertificateStatusIssued
+		}
+	}
+	copied := *detail
+	copied.DomainValidationOptions = append([]acmtypes.DomainValidation(nil), detail.DomainValidationOptions...)
+	return &acm.DescribeCertificateOutput{Certificate: &copied}, nil
+}
+
+func (f *fakeACM) ListCertificates(_ context.Context, in *acm.ListCertificatesInput, _ ...func(*acm.Options)) (*acm.ListCertificatesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lists++
+	out := &acm.ListCertificatesOutput{}
+	for arn, detail := range f.certs {
+		if len(in.CertificateStatuses) > 0 && !containsStatus(in.CertificateStatuses, detail.Status) {
+			continue
+		}
+		if !listsKeyType(in.Includes, detail.KeyAlgorithm) {
+			continue
+		}
+		out.CertificateSummaryList = append(out.CertificateSummaryList, acmtypes.CertificateSummary{
+			CertificateArn: aws.String(arn),
+			DomainName:     detail.DomainName,
+			Status:         detail.Status,
+		})
+	}
+	return out, nil
+}
+
+// listsKeyType reports whether ListCertificates returns a certificate with the key algorithm,
+// which like ACM is only RSA_2048 without a key type filter
+func listsKeyType(includes *acmtypes.Filters, alg acmtypes.KeyAlgorithm) bool {
+	if alg == "" {
+		alg = acmtypes.KeyAlgorithmRsa2048
+	}
+	if includes == nil || len(includes.KeyTypes) == 0 {
+		return alg == acmtypes.KeyAlgorithmRsa2048
+	}
+	return slices.Contains(includes.KeyTypes, alg)
+}
+
+func containsStatus(statuses []acmtypes.CertificateStatus, status acmtypes.CertificateStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeACM) ListTagsForCertificate(_ context.Context, in *acm.ListTagsForCertificateInput, _ ...func(*acm.Options)) (*acm.ListTagsForCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.certs[aws.ToString(in.CertificateArn)]; !ok {
+		return nil, &acmtypes.ResourceNotFoundException{Message: aws.String("certificate not found")}
+	}
+	return &acm.ListTagsForCertificateOutput{Tags: f.tags[aws.ToString(in.CertificateArn)]}, nil
+}
+
+func (f *fakeACM) DeleteCertificate(_ context.Context, in *acm.DeleteCertificateInput, _ ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	arn := aws.ToString(in.CertificateArn)
+	if _, ok := f.certs[arn]; !ok {
+		return nil, &acmtypes.ResourceNotFoundException{Message: aws.String("certificate not found")}
+	}
+	delete(f.certs, arn)
+	delete(f.tags, arn)
+	f.deleted = append(f.deleted, arn)
+	return &acm.DeleteCertificateOutput{}, nil
+}
+
+func (f *fakeACM) AddTagsToCertificate(_ context.Context, in *acm.AddTagsToCertificateInput, _ ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	arn := aws.ToString(in.CertificateArn)
+	if _, ok := f.certs[arn]; !ok {
+		return nil, &acmtypes.ResourceNotFoundException{Message: aws.String("certificate not found")}
+	}
+	for _, tag := range in.Tags {
+		replaced := false
+		for i, existing := range f.tags[arn] {
+			if aws.ToString(existing.Key) == aws.ToString(tag.Key) {
+				f.tags[arn][i] = tag
+				replaced = true
+			}
+		}
+		if !replaced {
+			f.tags[arn] = append(f.tags[arn], tag)
+		}
+	}
+	return &acm.AddTagsToCertificateOutput{}, nil
+}
+
+// ImportCertificate stores the leaf of in.Certificate as an issued certificate, in place when
+// in.CertificateArn is set
+func (f *fakeACM) ImportCertificate(_ context.Context, in *acm.ImportCertificateInput, _ ...func(*acm.Options)) (*acm.ImportCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	block, _ := pem.Decode(in.Certificate)
+	if block == nil {
+		return nil, &acmtypes.ValidationException{Message: aws.String("certificate is not PEM")}
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, &acmtypes.ValidationException{Message: aws.String(err.Error())}
+	}
+	arn := aws.ToString(in.CertificateArn)
+	if arn == "" {
+		arn = f.newArnLocked()
+		f.tags[arn] = append([]acmtypes.Tag(nil), in.Tags...)
+	} else if _, ok := f.certs[arn]; !ok {
+		return nil, &acmtypes.ResourceNotFoundException{Message: aws.String("certificate not found")}
+	}
+	f.imports = append(f.imports, in)
+	f.certs[arn] = &acmtypes.CertificateDetail{
+		CertificateArn:          aws.String(arn),
+		DomainName:              aws.String(leaf.Subject.CommonName),
+		SubjectAlternativeNames: leaf.DNSNames,
+		Status:                  acmtypes.CertificateStatusIssued,
+		Type:                    acmtypes.CertificateTypeImported,
+		Serial:                  aws.String(leaf.SerialNumber.String()),
+		NotAfter:                aws.Time(leaf.NotAfter),
+	}
+	return &acm.ImportCertificateOutput{CertificateArn: aws.String(arn)}, nil
+}
+
+// tagValue returns the value of key on arn, or "" when unset
+func (f *fakeACM) tagValue(arn, key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, tag := range f.tags[arn] {
+		if aws.ToString(tag.Key) == key {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return ""
+}
+
+// fakeRoute53 is an in-memory Route53API recording every change batch; a set err fails changes
+type fakeRoute53 struct {
+	mu       sync.Mutex
+	err      error
+	zones    []route53types.HostedZone
+	zoneTags map[string][]route53types.Tag
+	changes  []*route53.ChangeResourceRecordSetsInput
+}
+
+func newFakeRoute53(zoneNames ...string) *fakeRoute53 {
+	f := &fakeRoute53{}
+	for i, name := range zoneNames {
+		f.zones = append(f.zones, route53types.HostedZone{
+			Id:   aws.String(fmt.Sprintf("/hostedzone/Z%d", i+1)),
+			Name: aws.String(name + "."),
+		})
+	}
+	return f
+}
+
+func (f *fakeRoute53) ChangeResourceRecordSets(_ context.Context, in *route53.ChangeResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.changes = append(f.changes, in)
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+func (f *fakeRoute53) ListHostedZones(_ context.Context, _ *route53.ListHostedZonesInput, _ ...func(*route53.Options)) (*route53.ListHostedZonesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &route53.ListHostedZonesOutput{HostedZones: f.zones}, nil
+}
+
+func (f *fakeRoute53) ListTagsForResource(_ context.Context, in *route53.ListTagsForResourceInput, _ ...func(*route53.Options)) (*route53.ListTagsForResourceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &route53.ListTagsForResourceOutput{ResourceTagSet: &route53types.ResourceTagSet{
+		ResourceId: in.ResourceId,
+		Tags:       f.zoneTags[aws.ToString(in.ResourceId)],
+	}}, nil
+}
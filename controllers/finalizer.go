@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"context"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// finalizer returns the finalizer this instance puts on managed Ingresses
+func (r *IngressReconciler) finalizer() string {
+	if r.FinalizerName != "" {
+		return r.FinalizerName
+	}
+	return DefaultFinalizerName
+}
+
+// hasFinalizer reports whether the Ingress carries this instance's finalizer, or the legacy
+// DefaultFinalizerName not migrated yet
+func (r *IngressReconciler) hasFinalizer(ingress *networkingv1.Ingress) bool {
+	return controllerutil.ContainsFinalizer(ingress, r.finalizer()) || controllerutil.ContainsFinalizer(ingress, DefaultFinalizerName)
+}
+
+// removeFinalizers removes this instance's finalizer and the legacy one
+func (r *IngressReconciler) removeFinalizers(ingress *networkingv1.Ingress) {
+	controllerutil.RemoveFinalizer(ingress, r.finalizer())
+	controllerutil.RemoveFinalizer(ingress, DefaultFinalizerName)
+}
+
+// ensureFinalizer adds this instance's finalizer to the Ingress, replacing the legacy one, and
+// reports whether the finalizers changed
+func (r *IngressReconciler) ensureFinalizer(ctx context.Context, ingress *networkingv1.Ingress) bool {
+	name := r.finalizer()
+	changed := controllerutil.AddFinalizer(ingress, name)
+	if name != DefaultFinalizerName && controllerutil.RemoveFinalizer(ingress, DefaultFinalizerName) {
+		log.FromContext(ctx).Info("Migrating the legacy finalizer", "from", DefaultFinalizerName, "to", name)
+		changed = true
+	}
+	return changed
+}
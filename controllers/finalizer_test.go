@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+)
+
+const migratedFinalizer = "acm-v2.tedens.dev/finalizer"
+
+func TestFinalizerMigratesFromLegacyName(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	ingress.Finalizers = []string{"other.example.com/keep", DefaultFinalizerName}
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.FinalizerName = migratedFinalizer
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if got := getIngress(t, r, ingress).Finalizers; !slices.Equal(got, []string{"other.example.com/keep", migratedFinalizer}) {
+		t.Fatalf("finalizers = %v, want the legacy one swapped and others kept", got)
+	}
+}
+
+func TestDeletionAcceptsEitherFinalizer(t *testing.T) {
+	ctx := context.Background()
+	for _, finalizer := range []string{DefaultFinalizerName, migratedFinalizer} {
+		ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/delete-cert-on-ingress-delete": "true"})
+		ingress.Finalizers = []string{finalizer}
+		fakeACM := newFakeACM()
+		r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+		r.FinalizerName = migratedFinalizer
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+
+		if err := r.Delete(ctx, getIngress(t, r, ingress)); err != nil {
+			t.Fatalf("delete ingress: %v", err)
+		}
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+			t.Fatalf("Reconcile after delete with %s: %v", finalizer, err)
+		}
+		var got networkingv1.Ingress
+		if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); !apierrors.IsNotFound(err) {
+			t.Fatalf("Ingress with %s should be gone, got finalizers %v, %v", finalizer, got.Finalizers, err)
+		}
+		if len(fakeACM.deleted) != 1 {
+			t.Errorf("expected the certificate deleted with %s, got %v", finalizer, fakeACM.deleted)
+		}
+	}
+}
+
+func TestDeletionRemovesBothFinalizers(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	ingress.Finalizers = []string{DefaultFinalizerName, migratedFinalizer}
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.FinalizerName = migratedFinalizer
+	if err := r.Delete(ctx, getIngress(t, r, ingress)); err != nil {
+		t.Fatalf("delete ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); !apierrors.IsNotFound(err) {
+		t.Fatalf("both names should be removed, got finalizers %v, %v", got.Finalizers, err)
+	}
+}
+
+func TestDeletionProtectionWinsOverDeleteCertOnIngressDelete(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		"acm.tedens.dev/delete-cert-on-ingress-delete": "true",
+		deletionProtectionAnnotation:                   "true",
+	})
+	fakeACM := newFakeACM()
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	drainEvents(r.Recorder.(*record.FakeRecorder))
+
+	if err := r.Delete(ctx, getIngress(t, r, ingress)); err != nil {
+		t.Fatalf("delete ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after delete: %v", err)
+	}
+	if len(fakeACM.deleted) != 0 {
+		t.Fatalf("a protected certificate must not be deleted, deleted %v", fakeACM.deleted)
+	}
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); !apierrors.IsNotFound(err) {
+		t.Fatalf("the Ingress should still finish deleting, got finalizers %v, %v", got.Finalizers, err)
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if !slices.ContainsFunc(events, func(e string) bool { return strings.HasPrefix(e, "Normal "+ReasonDeletionProtected) }) {
+		t.Errorf("expected a %s event, got %v", ReasonDeletionProtected, events)
+	}
+}
+
+func TestDeletionFindsECCertificates(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		"acm.tedens.dev/delete-cert-on-ingress-delete": "true",
+		"acm.tedens.dev/key-algorithm":                 "EC_prime256v1",
+	})
+	fakeACM := newFakeACM()
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+
+	if err := r.Delete(ctx, getIngress(t, r, ingress)); err != nil {
+		t.Fatalf("delete ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after delete: %v", err)
+	}
+	if !slices.Equal(fakeACM.deleted, []string{arn}) {
+		t.Fatalf("deleted %v, want the EC certificate %s", fakeACM.deleted, arn)
+	}
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// fipsLookupTimeout bounds the lookup of a FIPS endpoint's host name
+const fipsLookupTimeout = 5 * time.Second
+
+// lookupHost resolves host names to check FIPS endpoints; tests replace it
+var lookupHost = net.DefaultResolver.LookupHost
+
+// fipsEndpointAvailable resolves the FIPS endpoint of service in region and reports whether
+// its host exists, logging the endpoint used. The SDK builds a FIPS host name for every
+// region, so only a host that does not exist falls back to the standard endpoint; other
+// lookup errors keep FIPS, and calls fail rather than silently leave FIPS.
+func fipsEndpointAvailable(service, region string, resolve func(ctx context.Context) (smithyendpoints.Endpoint, error)) bool {
+	logger := logf.Log.WithName("aws").WithValues("service", service, "region", region)
+	ctx, cancel := context.WithTimeout(context.Background(), fipsLookupTimeout)
+	defer cancel()
+
+	endpoint, err := resolve(ctx)
+	if err != nil {
+		logger.Info("No FIPS endpoint, using the standard endpoint", "error", err.Error())
+		return false
+	}
+	_, err = lookupHost(ctx, endpoint.URI.Hostname())
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		logger.Info("No FIPS endpoint, using the standard endpoint", "fipsEndpoint", endpoint.URI.String())
+		return false
+	}
+	if err != nil {
+		logger.Error(err, "failed to look up the FIPS endpoint, using it anyway", "endpoint", endpoint.URI.String())
+	} else {
+		logger.Info("Using FIPS endpoint", "endpoint", endpoint.URI.String())
+	}
+	return true
+}
+
+// NewACMClient returns an ACM client for cfg. When cfg enables FIPS endpoints, through
+// --use-fips-endpoints or AWS_USE_FIPS_ENDPOINT, regions without one use the standard endpoint.
+func NewACMClient(cfg aws.Config) *acm.Client {
+	return acm.NewFromConfig(cfg, func(o *acm.Options) {
+		if o.EndpointOptions.UseFIPSEndpoint == aws.FIPSEndpointStateEnabled &&
+			!fipsEndpointAvailable("acm", o.Region, func(ctx context.Context) (smithyendpoints.Endpoint, error) {
+				return acm.NewDefaultEndpointResolverV2().ResolveEndpoint(ctx, acm.EndpointParameters{Region: aws.String(o.Region), UseFIPS: aws.Bool(true)})
+			}) {
+			o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateDisabled
+		}
+	})
+}
+
+// newRoute53Client is NewACMClient for Route 53, which has no FIPS endpoint in some partitions
+func newRoute53Client(cfg aws.Config, optFns ...func(*route53.Options)) *route53.Client {
+	return route53.NewFromConfig(cfg, append([]func(*route53.Options){func(o *route53.Options) {
+		if o.EndpointOptions.UseFIPSEndpoint == aws.FIPSEndpointStateEnabled &&
+			!fipsEndpointAvailable("route53", o.Region, func(ctx context.Context) (smithyendpoints.Endpoint, error) {
+				return route53.NewDefaultEndpointResolverV2().ResolveEndpoint(ctx, route53.EndpointParameters{Region: aws.String(o.Region), UseFIPS: aws.Bool(true)})
+			}) {
+			o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateDisabled
+		}
+	}}, optFns...)...)
+}
+
+// newSTSClient is NewACMClient for STS, used to assume namespace roles
+func newSTSClient(cfg aws.Config) *sts.Client {
+	return sts.NewFromConfig(cfg, func(o *sts.Options) {
+		if o.EndpointOptions.UseFIPSEndpoint == aws.FIPSEndpointStateEnabled &&
+			!fipsEndpointAvailable("sts", o.Region, func(ctx context.Context) (smithyendpoints.Endpoint, error) {
+				return sts.NewDefaultEndpointResolverV2().ResolveEndpoint(ctx, sts.EndpointParameters{Region: aws.String(o.Region), UseFIPS: aws.Bool(true)})
+			}) {
+			o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateDisabled
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+)
+
+// fakeLookups makes lookupHost find only the given hosts and fail others with err, or with
+// "no such host" when err is nil
+func fakeLookups(t *testing.T, err error, hosts ...string) *[]string {
+	t.Helper()
+	var looked []string
+	original := lookupHost
+	t.Cleanup(func() { lookupHost = original })
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		looked = append(looked, host)
+		for _, h := range hosts {
+			if h == host {
+				return []string{"192.0.2.1"}, nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return &looked
+}
+
+// fipsConfig returns a configuration for region asking for FIPS endpoints, as LoadAWSConfig does
+func fipsConfig(region string, state aws.FIPSEndpointState) aws.Config {
+	return aws.Config{
+		Region:        region,
+		ConfigSources: []interface{}{config.LoadOptions{UseFIPSEndpoint: state}},
+	}
+}
+
+func TestFIPSEndpointsDegradePerService(t *testing.T) {
+	ctx := context.Background()
+	fakeLookups(t, nil, "acm-fips.cn-north-1.amazonaws.com.cn")
+	cfg := fipsConfig("cn-north-1", aws.FIPSEndpointStateEnabled)
+
+	acmClient := NewACMClient(cfg)
+	if got := acmClient.Options().EndpointOptions.UseFIPSEndpoint; got != aws.FIPSEndpointStateEnabled {
+		t.Fatalf("ACM should keep its FIPS endpoint, got state %v", got)
+	}
+	route53Client := newRoute53Client(cfg)
+	options := route53Client.Options()
+	if got := options.EndpointOptions.UseFIPSEndpoint; got != aws.FIPSEndpointStateDisabled {
+		t.Fatalf("Route 53 has no FIPS endpoint here and should fall back, got state %v", got)
+	}
+	endpoint, err := options.EndpointResolverV2.ResolveEndpoint(ctx, route53.EndpointParameters{
+		Region:  aws.String(options.Region),
+		UseFIPS: aws.Bool(options.EndpointOptions.UseFIPSEndpoint == aws.FIPSEndpointStateEnabled),
+	})
+	if err != nil || endpoint.URI.Hostname() != "route53.amazonaws.com.cn" {
+		t.Fatalf("expected the standard Route 53 endpoint, got %v, %v", endpoint.URI.String(), err)
+	}
+}
+
+func TestFIPSEndpointKeptOnLookupFailure(t *testing.T) {
+	fakeLookups(t, &net.DNSError{Err: "i/o timeout", Name: "acm-fips.us-east-1.amazonaws.com", IsTimeout: true})
+	client := NewACMClient(fipsConfig("us-east-1", aws.FIPSEndpointStateEnabled))
+	if got := client.Options().EndpointOptions.UseFIPSEndpoint; got != aws.FIPSEndpointStateEnabled {
+		t.Fatalf("a failed lookup must not leave FIPS, got state %v", got)
+	}
+	options := client.Options()
+	endpoint, err := options.EndpointResolverV2.ResolveEndpoint(context.Background(), acm.EndpointParameters{
+		Region:  aws.String(options.Region),
+		UseFIPS: aws.Bool(true),
+	})
+	if err != nil || endpoint.URI.Hostname() != "acm-fips.us-east-1.amazonaws.com" {
+		t.Fatalf("expected the ACM FIPS endpoint, got %v, %v", endpoint.URI.String(), err)
+	}
+}
+
+func TestFIPSEndpointsNotCheckedWhenDisabled(t *testing.T) {
+	looked := fakeLookups(t, nil)
+	NewACMClient(fipsConfig("us-east-1", aws.FIPSEndpointStateUnset))
+	newSTSClient(aws.Config{Region: "us-east-1"})
+	if len(*looked) != 0 {
+		t.Fatalf("endpoints must only be checked with FIPS enabled, looked up %v", *looked)
+	}
+}
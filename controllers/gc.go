@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultGCInterval is how often the pending certificate sweep runs when no interval is set
+var DefaultGCInterval = time.Hour
+
+// PendingCertificateGC periodically deletes PENDING_VALIDATION certificates carrying this
+// instance's ManagedBy tag and RequiredTags that are older than MaxAge and not in use
+type PendingCertificateGC struct {
+	ACMClient      ACMAPI
+	ManagedByValue string
+	RequiredTags   map[string]string
+	MaxAge         time.Duration
+	Interval       time.Duration
+}
+
+// NeedLeaderElection makes the sweep run only on the elected leader
+func (g *PendingCertificateGC) NeedLeaderElection() bool {
+	return true
+}
+
+// Start runs the sweep every Interval until ctx is cancelled
+func (g *PendingCertificateGC) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("pending-certificate-gc")
+	ctx = withAuditSubject(ctx, "pending-certificate-gc")
+
+	interval := g.Interval
+	if interval <= 0 {
+		interval = DefaultGCInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := g.Sweep(ctx); isDeferred(err) {
+			logger.Info("Deferring the sweep", "reason", err.Error())
+		} else if err != nil {
+			logger.Error(err, "pending certificate sweep failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep deletes every eligible stale pending certificate once
+func (g *PendingCertificateGC) Sweep(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("pending-certificate-gc")
+
+	paginator := acm.NewListCertificatesPaginator(g.ACMClient, &acm.ListCertificatesInput{
+		CertificateStatuses: []acmtypes.CertificateStatus{
+			acmtypes.CertificateStatusPendingValidation,
+		},
+		Includes: certs.AllKeyTypes(),
+	})
+
+	cutoff := time.Now().Add(-g.MaxAge)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, summary := range page.CertificateSummaryList {
+			certArn := aws.ToString(summary.CertificateArn)
+
+			describe, err := g.ACMClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+				CertificateArn: aws.String(certArn),
+			})
+			if err != nil {
+				return err
+			}
+			cert := describe.Certificate
+			if cert.Status != acmtypes.CertificateStatusPendingValidation || len(cert.InUseBy) > 0 {
+				continue
+			}
+			if cert.CreatedAt == nil || cert.CreatedAt.After(cutoff) {
+				continue
+			}
+
+			owned, err := certs.ManagedByWithTags(ctx, g.ACMClient, certArn, g.ManagedByValue, g.RequiredTags)
+			if err != nil {
+				return err
+			}
+			if !owned {
+				continue
+			}
+
+			logger.Info("Deleting stale pending certificate", "arn", certArn, "createdAt", cert.CreatedAt)
+			_, err = g.ACMClient.DeleteCertificate(ctx, &acm.DeleteCertificateInput{
+				CertificateArn: aws.String(certArn),
+			})
+			// Observe-only mode logged the deletion; report the rest of the sweep too
+			if err != nil && !errors.Is(err, ErrObserveOnly) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const albGroupNameAnnotation = "alb.ingress.kubernetes.io/group.name"
+
+// maxNamesPerCertificate is ACM's default limit on domain names per certificate, used unless
+// MaxNamesPerCertificate is set
+const maxNamesPerCertificate = 10
+
+// certGroupAnnotation names a certificate group: its managed Ingresses share certificates
+// covering all their hosts, like an ALB IngressGroup, without sharing an ALB
+const certGroupAnnotation = "acm.tedens.dev/cert-group"
+
+// albGroupName returns the ALB IngressGroup the Ingress belongs to, or ""
+func albGroupName(ingress *networkingv1.Ingress) string {
+	return strings.TrimSpace(ingress.Annotations[albGroupNameAnnotation])
+}
+
+// certificateGroup returns the group whose certificates the Ingress shares, or "". A
+// certificate group takes precedence over the Ingress' ALB IngressGroup; the prefixes keep
+// groups of the same name apart.
+func certificateGroup(ingress *networkingv1.Ingress) string {
+	if name := strings.TrimSpace(ingress.Annotations[certGroupAnnotation]); name != "" {
+		return "cert-group/" + name
+	}
+	if name := albGroupName(ingress); name != "" {
+		return "alb/" + name
+	}
+	return ""
+}
+
+// groupMembers returns the managed Ingresses of a certificate group, sorted by namespace/name. Members
+// being deleted are left out, except ingress itself.
+func (r *IngressReconciler) groupMembers(ctx context.Context, ingress *networkingv1.Ingress, group string, policy map[string]string) ([]networkingv1.Ingress, error) {
+	var list networkingv1.IngressList
+	if err := r.List(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	members := []networkingv1.Ingress{*ingress}
+	for _, other := range list.Items {
+		if ingressKey(&other) == ingressKey(ingress) || !other.DeletionTimestamp.IsZero() || certificateGroup(&other) != group {
+			continue
+		}
+		otherCfg, err := r.ingressConfig(ctx, &other, policy)
+		if err != nil {
+			return nil, err
+		}
+		if otherCfg.Managed {
+			members = append(members, other)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return ingressKey(&members[i]) < ingressKey(&members[j])
+	})
+	return members, nil
+}
+
+// groupHosts returns the sorted union of the hosts served by the group members, qualified with
+// their domain suffix
+func (r *IngressReconciler) groupHosts(members []networkingv1.Ingress, policy map[string]string) []string {
+	seen := map[string]bool{}
+	var hosts []string
+	add := func(host string) {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	for i := range members {
+		cfg := ParseIngressAnnotationsWithDefaults(members[i].Annotations, policy)
+		suffix := cfg.DomainSuffix
+		if suffix == "" {
+			suffix = parseDomainSuffix(r.settings().DomainSuffix)
+		}
+		add(qualifyHost(cfg.DomainOverride, suffix))
+		for _, rule := range members[i].Spec.Rules {
+			add(qualifyHost(rule.Host, suffix))
+		}
+		for _, san := range cfg.SANs {
+			add(qualifyHost(san, suffix))
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// groupCertificateNames returns the names whose certificates the last member of a group may
+// delete: the group's hosts, plus the names its attached certificates covered before members
+// left. Wildcards only count as group hosts, so an attached fallback wildcard is kept.
+func (r *IngressReconciler) groupCertificateNames(ingress *networkingv1.Ingress, members []networkingv1.Ingress, policy map[string]string) []string {
+	names := r.groupHosts(members, policy)
+	for _, name := range strings.Split(ingress.Annotations[coveredNamesAnnotation], ",") {
+		if name != "" && !strings.HasPrefix(name, "*.") && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// reconcileGroup ensures certificates covering every host of the group and patches the same
+// ARN list onto each member, so the group shares one set of certificates
+func (r *IngressReconciler) reconcileGroup(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, members []networkingv1.Ingress, policy map[string]string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	group := certificateGroup(ingress)
+	domain := resolveDomain(ingress, cfg)
+
+	hosts := r.groupHosts(members, policy)
+	if len(hosts) == 0 {
+		return ctrl.Result{}, nil
+	}
+	logger.Info("Reconciling certificate group", "group", group, "members", len(members), "hosts", hosts)
+
+	dnsProvider, err := r.dnsProviderFor(ctx, ingress, cfg)
+	if err != nil {
+		r.notifyCertificateEvent(ingress, NotificationFailed, domain, "", "", err.Error())
+		return ctrl.Result{}, err
+	}
+
+	// Ownership tags removed outside the controller are put back first, or reuse would not
+	// find the group's certificates. Members may live in different Namespaces, so no
+	// Namespace tags are applied.
+	if err := r.syncCertificateTags(ctx, ingress, certificateArnKey(cfg), false); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Certificates left pending by an earlier reconcile are tracked on this member only
+	var certArns, pending []string
+	requested := pendingCertificateArns(ingress)
+	for names := range slices.Chunk(hosts, r.maxNames()) {
+		// Groups always reuse, otherwise every member reconcile would request new certificates
+		result, err := r.certManager(ctx).Ensure(ctx, certs.EnsureRequest{
+			Domain:                  names[0],
+			SubjectAlternativeNames: names[1:],
+			ReuseExisting:           true,
+			DNS:                     dnsProvider,
+			ZoneID:                  cfg.ZoneID,
+			KeyAlgorithm:            cfg.KeyAlgorithm,
+			DisableCTLogging:        cfg.DisableCTLogging,
+			Tags:                    cfg.Tags,
+			OnProgress:              r.validationProgressRecorder(ingress),
+			OnLagging:               r.validationLagRecorder(ingress),
+			LagThreshold:            r.settings().ValidationLagThreshold,
+			OnValidationExpired:     r.validationExpiredRecorder(ingress),
+			NoWait:                  r.RequeuePendingValidation,
+		})
+		if r.validationPending(result, err) {
+			// Like split certificates, every chunk is requested before the member is requeued
+			pending = append(pending, result.CertificateArn+" "+string(result.Status))
+			if err != nil {
+				if trackErr := r.trackPendingCertificate(ctx, ingress, result.CertificateArn); trackErr != nil {
+					logger.Error(trackErr, "failed to track pending certificate", "arn", result.CertificateArn)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			logger.Error(err, "failed to ensure group certificate", "group", group, "names", names)
+			if !isDeferred(err) {
+				r.notifyCertificateEvent(ingress, NotificationFailed, names[0], result.CertificateArn, string(result.Status), err.Error())
+			}
+			if result.CertificateArn != "" {
+				if trackErr := r.trackPendingCertificate(ctx, ingress, result.CertificateArn); trackErr != nil {
+					logger.Error(trackErr, "failed to track pending certificate", "arn", result.CertificateArn)
+				}
+			}
+			return ctrl.Result{}, err
+		}
+		// Each member records itself when it is reconciled, which also lets a ManagedBy tag
+		// removed outside the controller be put back
+		if err := r.addCertificateOwner(ctx, result.CertificateArn, ingress); err != nil {
+			logOwnerError(ctx, err, result.CertificateArn)
+		}
+		certArns = append(certArns, result.CertificateArn)
+		if !result.Reused {
+			requested = append(requested, result.CertificateArn)
+		}
+	}
+	if len(pending) > 0 {
+		delay := r.pendingRequeues.next(ingress.UID, strings.Join(pending, ", "))
+		logger.Info("Group certificates are pending validation, checking again later", "group", group, "pending", len(pending), "after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+	r.pendingRequeues.forget(ingress.UID)
+
+	var errs []error
+	// The certificates each attached member carried before, retired once no member uses them
+	// unless the member has deletion protection
+	replaced := map[int][]string{}
+	keys := map[int]string{}
+	for i := range members {
+		member := &members[i]
+		if isPaused(member) {
+			continue
+		}
+		memberCfg, err := r.ingressConfig(ctx, member, policy)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		key := certificateArnKey(memberCfg)
+		if !memberCfg.FallbackWildcard && member.Annotations[key] == strings.Join(certArns, ",") {
+			keys[i] = key
+			continue
+		}
+		if yield, err := r.yieldCertificateArn(ctx, member, memberCfg); yield || err != nil {
+			if err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		previous := attachedCertificateArns(member, key)
+		if err := r.attachCertificate(ctx, member, memberCfg, resolveDomain(member, memberCfg), certArns, requested); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		keys[i] = key
+		if !memberCfg.DeletionProtection {
+			replaced[i] = previous
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// A member that was skipped, such as a paused one, may still use a replaced certificate
+	var referenced []string
+	for i := range members {
+		for _, key := range []string{albCertificateArnAnnotation, certificateArnAnnotation} {
+			referenced = append(referenced, attachedCertificateArns(&members[i], key)...)
+		}
+	}
+	for i, key := range keys {
+		var retired []string
+		for _, arn := range replaced[i] {
+			if !slices.Contains(referenced, arn) {
+				retired = append(retired, arn)
+			}
+		}
+		if err := r.retireCertificates(ctx, &members[i], key, retired); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return ctrl.Result{}, err
+	}
+	return recheckAfter(ctx, recheckInterval), nil
+}
+
+// deleteOwnedCertificates deletes the certificates in arns that this instance owns, whose
+// primary domain is one of hosts and that no Ingress besides owner holds, leaving attached
+// fallback wildcards alone
+func (r *IngressReconciler) deleteOwnedCertificates(ctx context.Context, arns, hosts []string, owner string) error {
+	var notFound *acmtypes.ResourceNotFoundException
+	for _, arn := range arns {
+		describe, err := r.acm(ctx).DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+			CertificateArn: aws.String(arn),
+		})
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(hosts, strings.ToLower(aws.ToString(describe.Certificate.DomainName))) {
+			continue
+		}
+		if _, err := r.deleteOwnedCertificate(ctx, arn, owner); err != nil && !errors.As(err, &notFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachedCertificateArns returns the ARNs in the Ingress' certificate ARN annotation key
+func attachedCertificateArns(ingress *networkingv1.Ingress, key string) []string {
+	var arns []string
+	for _, arn := range strings.Split(ingress.Annotations[key], ",") {
+		if arn = strings.TrimSpace(arn); arn != "" {
+			arns = append(arns, arn)
+		}
+	}
+	return arns
+}
+
+// groupMembershipHandler enqueues the remaining members of a group that an Ingress left or was
+// deleted from, so they swap to certificates without its hosts. Joining needs nothing extra:
+// reconciling the new member patches the whole group.
+func (r *IngressReconciler) groupMembershipHandler() handler.EventHandler {
+	return handler.Funcs{
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			oldIngress, ok := e.ObjectOld.(*networkingv1.Ingress)
+			newIngress, ok2 := e.ObjectNew.(*networkingv1.Ingress)
+			if !ok || !ok2 {
+				return
+			}
+			if group := certificateGroup(oldIngress); group != "" && group != certificateGroup(newIngress) {
+				r.enqueueGroupMembers(ctx, group, newIngress, q)
+			}
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if ingress, ok := e.Object.(*networkingv1.Ingress); ok {
+				if group := certificateGroup(ingress); group != "" {
+					r.enqueueGroupMembers(ctx, group, ingress, q)
+				}
+			}
+		},
+	}
+}
+
+// enqueueGroupMembers adds the Ingresses of group other than left to q
+func (r *IngressReconciler) enqueueGroupMembers(ctx context.Context, group string, left *networkingv1.Ingress, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	var list networkingv1.IngressList
+	if err := r.List(ctx, &list); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ingresses to reconcile their group", "group", group)
+		return
+	}
+	for i := range list.Items {
+		if ingressKey(&list.Items[i]) != ingressKey(left) && certificateGroup(&list.Items[i]) == group {
+			q.Add(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+		}
+	}
+}
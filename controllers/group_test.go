@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newGroupIngress(namespace, name, host string) *networkingv1.Ingress {
+	ingress := newManagedIngress(name, host, map[string]string{
+		albGroupNameAnnotation:                         "shared",
+		"acm.tedens.dev/delete-cert-on-ingress-delete": "true",
+	})
+	ingress.Namespace = namespace
+	return ingress
+}
+
+func TestGroupMembersShareOneCertificate(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	a := newGroupIngress("team-a", "a.example.com", "a.example.com")
+	b := newGroupIngress("team-b", "b.example.com", "b.example.com")
+	c := newGroupIngress("team-b", "c.example.com", "c.example.com")
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), a, b, c)
+
+	if _, err := r.Reconcile(ctx, requestFor(b)); err != nil {
+		t.Fatalf("Reconcile b: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one certificate for the group, got %d", len(fakeACM.requests))
+	}
+	req := fakeACM.requests[0]
+	if aws.ToString(req.DomainName) != "a.example.com" || len(req.SubjectAlternativeNames) != 2 {
+		t.Fatalf("certificate should cover every group host, got %s %v", aws.ToString(req.DomainName), req.SubjectAlternativeNames)
+	}
+
+	arn := certificateArnOf(t, r, a)
+	for _, member := range []*networkingv1.Ingress{b, c} {
+		if got := certificateArnOf(t, r, member); got != arn {
+			t.Fatalf("%s should carry the group certificate %s, got %q", member.Name, arn, got)
+		}
+	}
+
+	// Reconciling other members reuses the group certificate
+	for _, member := range []*networkingv1.Ingress{a, c} {
+		if _, err := r.Reconcile(ctx, requestFor(member)); err != nil {
+			t.Fatalf("Reconcile %s: %v", member.Name, err)
+		}
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("members must not request their own certificates, got %d requests", len(fakeACM.requests))
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(c).NamespacedName, &got); err != nil {
+		t.Fatalf("get c: %v", err)
+	}
+	if err := r.Delete(ctx, &got); err != nil {
+		t.Fatalf("delete c: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(c)); err != nil {
+		t.Fatalf("Reconcile c after delete: %v", err)
+	}
+	if len(fakeACM.deleted) != 0 {
+		t.Fatalf("certificate still needed by the group must not be deleted, deleted %v", fakeACM.deleted)
+	}
+}
+
+func TestGroupRequeuesPendingValidation(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.holdPending = true
+	a := newGroupIngress("default", "a", "a.example.com")
+	b := newGroupIngress("default", "b", "b.example.com")
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), a, b)
+	r.RequeuePendingValidation = true
+
+	for i, want := range []time.Duration{15 * time.Second, 30 * time.Second} {
+		result, err := r.Reconcile(ctx, requestFor(a))
+		if err != nil {
+			t.Fatalf("Reconcile %d: %v", i+1, err)
+		}
+		if result.RequeueAfter != want {
+			t.Fatalf("Reconcile %d: expected requeue after %s, got %s", i+1, want, result.RequeueAfter)
+		}
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("requeued reconciles must reuse the pending certificate, got %d requests", len(fakeACM.requests))
+	}
+	for _, member := range []*networkingv1.Ingress{a, b} {
+		if got := certificateArnOf(t, r, member); got != "" {
+			t.Fatalf("%s must not carry the pending certificate, got %q", member.Name, got)
+		}
+	}
+	if pending := pendingCertificateArns(getIngress(t, r, a)); len(pending) != 1 {
+		t.Fatalf("expected the pending certificate tracked, got %v", pending)
+	}
+
+	fakeACM.mu.Lock()
+	fakeACM.holdPending = false
+	fakeACM.mu.Unlock()
+	if _, err := r.Reconcile(ctx, requestFor(a)); err != nil {
+		t.Fatalf("Reconcile after issue: %v", err)
+	}
+	arn := certificateArnOf(t, r, a)
+	if arn == "" || certificateArnOf(t, r, b) != arn {
+		t.Fatalf("expected both members to carry the issued certificate, got %q and %q", arn, certificateArnOf(t, r, b))
+	}
+}
+
+func TestGroupCertificateFollowsNewMembers(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	a := newGroupIngress("default", "a", "a.example.com")
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), a)
+
+	if _, err := r.Reconcile(ctx, requestFor(a)); err != nil {
+		t.Fatalf("Reconcile a: %v", err)
+	}
+	first := certificateArnOf(t, r, a)
+
+	b := newGroupIngress("default", "b", "b.example.com")
+	if err := r.Create(ctx, b); err != nil {
+		t.Fatalf("create b: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(b)); err != nil {
+		t.Fatalf("Reconcile b: %v", err)
+	}
+	if len(fakeACM.requests) != 2 {
+		t.Fatalf("a new host should produce a new group certificate, got %d requests", len(fakeACM.requests))
+	}
+	second := certificateArnOf(t, r, b)
+	if second == first || certificateArnOf(t, r, a) != second {
+		t.Fatalf("both members should move to the new certificate %s, a has %s", second, certificateArnOf(t, r, a))
+	}
+	if !slices.Equal(fakeACM.deleted, []string{first}) {
+		t.Fatalf("deleted %v, want the replaced group certificate %s", fakeACM.deleted, first)
+	}
+}
+
+func TestReplacedGroupCertificateRetriedWhileInUse(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	a := newGroupIngress("default", "a", "a.example.com")
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), a)
+	if _, err := r.Reconcile(ctx, requestFor(a)); err != nil {
+		t.Fatalf("Reconcile a: %v", err)
+	}
+	first := certificateArnOf(t, r, a)
+	fakeACM.certs[first].InUseBy = []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/shared/1"}
+
+	b := newGroupIngress("default", "b", "b.example.com")
+	if err := r.Create(ctx, b); err != nil {
+		t.Fatalf("create b: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(b)); err != nil {
+		t.Fatalf("Reconcile b: %v", err)
+	}
+	if len(fakeACM.deleted) != 0 {
+		t.Fatalf("a certificate still in use must not be deleted, deleted %v", fakeACM.deleted)
+	}
+	if got := supersededCertificateArns(getIngress(t, r, a)); !slices.Equal(got, []string{first}) {
+		t.Fatalf("superseded = %v, want %s tracked for a retry", got, first)
+	}
+
+	fakeACM.certs[first].InUseBy = nil
+	if _, err := r.Reconcile(ctx, requestFor(a)); err != nil {
+		t.Fatalf("Reconcile a: %v", err)
+	}
+	if !slices.Equal(fakeACM.deleted, []string{first}) {
+		t.Fatalf("deleted %v, want %s once the load balancer let go", fakeACM.deleted, first)
+	}
+	if got := supersededCertificateArns(getIngress(t, r, a)); len(got) != 0 {
+		t.Fatalf("superseded = %v after the deletion, want none", got)
+	}
+}
+
+func newCertGroupIngress(namespace, name, host string) *networkingv1.Ingress {
+	ingress := newManagedIngress(name, host, map[string]string{
+		certGroupAnnotation:                            "api",
+		"acm.tedens.dev/delete-cert-on-ingress-delete": "true",
+	})
+	ingress.Namespace = namespace
+	return ingress
+}
+
+func TestCertGroupConsolidatesHosts(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	a := newCertGroupIngress("team-a", "a", "a.api.example.com")
+	b := newCertGroupIngress("team-b", "b", "b.api.example.com")
+	c := newCertGroupIngress("team-b", "c", "c.api.example.com")
+	// An ALB group of the same name is a different group
+	other := newGroupIngress("team-c", "other", "other.example.com")
+	other.Annotations[albGroupNameAnnotation] = "api"
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), a, b, c, other)
+
+	if _, err := r.Reconcile(ctx, requestFor(a)); err != nil {
+		t.Fatalf("Reconcile a: %v", err)
+	}
+	if len(fakeACM.requests) != 1 || len(fakeACM.requests[0].SubjectAlternativeNames) != 2 {
+		t.Fatalf("expected one certificate for the three hosts, got %d requests", len(fakeACM.requests))
+	}
+	first := certificateArnOf(t, r, a)
+	for _, member := range []*networkingv1.Ingress{b, c} {
+		if got := certificateArnOf(t, r, member); got != first {
+			t.Fatalf("%s should carry the group certificate %s, got %q", member.Name, first, got)
+		}
+	}
+	if certificateArnOf(t, r, other) != "" {
+		t.Fatal("an ALB group of the same name must not be patched")
+	}
+
+	// Deleting a member enqueues the rest, which swap to a certificate without its host
+	got := getIngress(t, r, c)
+	if err := r.Delete(ctx, got); err != nil {
+		t.Fatalf("delete c: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(c)); err != nil {
+		t.Fatalf("Reconcile c after delete: %v", err)
+	}
+	if len(fakeACM.deleted) != 0 {
+		t.Fatalf("the group certificate must survive while members remain, deleted %v", fakeACM.deleted)
+	}
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer queue.ShutDown()
+	r.groupMembershipHandler().Delete(ctx, event.DeleteEvent{Object: got}, queue)
+	if queue.Len() != 2 {
+		t.Fatalf("expected the two remaining members to be enqueued, got %d", queue.Len())
+	}
+	if _, err := r.Reconcile(ctx, requestFor(b)); err != nil {
+		t.Fatalf("Reconcile b: %v", err)
+	}
+	second := certificateArnOf(t, r, a)
+	if second == first || certificateArnOf(t, r, b) != second {
+		t.Fatalf("remaining members should move to a new certificate, a has %s, b has %s", second, certificateArnOf(t, r, b))
+	}
+	if sans := fakeACM.requests[1].SubjectAlternativeNames; len(sans) != 1 || sans[0] != "b.api.example.com" {
+		t.Fatalf("the new certificate should drop the deleted host, got SANs %v", sans)
+	}
+
+	// The last member deletes the group certificate
+	for _, member := range []*networkingv1.Ingress{a, b} {
+		if err := r.Delete(ctx, getIngress(t, r, member)); err != nil {
+			t.Fatalf("delete %s: %v", member.Name, err)
+		}
+		if _, err := r.Reconcile(ctx, requestFor(member)); err != nil {
+			t.Fatalf("Reconcile %s after delete: %v", member.Name, err)
+		}
+	}
+	if !slices.Contains(fakeACM.deleted, second) {
+		t.Fatalf("expected the last member to delete %s, deleted %v", second, fakeACM.deleted)
+	}
+}
+
+func TestLeavingCertGroupEnqueuesMembers(t *testing.T) {
+	a := newCertGroupIngress("default", "a", "a.api.example.com")
+	b := newCertGroupIngress("default", "b", "b.api.example.com")
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), a, b)
+
+	left := a.DeepCopy()
+	delete(left.Annotations, certGroupAnnotation)
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer queue.ShutDown()
+	handler := r.groupMembershipHandler()
+	handler.Update(context.Background(), event.UpdateEvent{ObjectOld: a, ObjectNew: a.DeepCopy()}, queue)
+	if queue.Len() != 0 {
+		t.Fatal("an update within the group must not enqueue other members")
+	}
+	handler.Update(context.Background(), event.UpdateEvent{ObjectOld: a, ObjectNew: left}, queue)
+	if queue.Len() != 1 {
+		t.Fatalf("leaving the group should enqueue its remaining member, got %d", queue.Len())
+	}
+	if req, _ := queue.Get(); req.Name != "b" {
+		t.Fatalf("expected b to be enqueued, got %v", req)
+	}
+}
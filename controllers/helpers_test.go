@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"testing"
+
+	acmv1alpha1 "github.com/tedens/acm-manager/api/v1alpha1"
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("building scheme: %v", err)
+	}
+	if err := acmv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("building scheme: %v", err)
+	}
+	return scheme
+}
+
+// newTestReconciler wires a reconciler to a fake Kubernetes client seeded with objs
+func newTestReconciler(t *testing.T, acmClient ACMAPI, route53Client Route53API, objs ...client.Object) *IngressReconciler {
+	t.Helper()
+	scheme := testScheme(t)
+	return &IngressReconciler{
+		Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Scheme:      scheme,
+		ACMClient:   acmClient,
+		DNSProvider: certs.NewRoute53Provider(route53Client),
+		Recorder:    record.NewFakeRecorder(100),
+	}
+}
+
+func newManagedIngress(name, host string, annotations map[string]string) *networkingv1.Ingress {
+	all := map[string]string{"acm.tedens.dev/managed": "true"}
+	for k, v := range annotations {
+		all[k] = v
+	}
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: all,
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{Host: host}},
+		},
+	}
+}
+
+func requestFor(obj client.Object) ctrl.Request {
+	return ctrl.Request{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}}
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// postIssuanceHookAnnotation recorded the certificate ARNs the post-issuance hook last fired
+// for before they moved to bookkeepingAnnotation
+const postIssuanceHookAnnotation = "acm.tedens.dev/post-issuance-hook-fired"
+
+// NotificationPostIssuance is the event of notifications delivered to the post-issuance hook
+const NotificationPostIssuance = "post-issuance"
+
+// preparePostIssuanceHook returns the notification for the post-issuance hook when arns were
+// not attached already and include a certificate in requested, recording them on the Ingress;
+// it returns nil when the hook is disabled, already fired for arns or all of them were reused
+func (r *IngressReconciler) preparePostIssuanceHook(ingress *networkingv1.Ingress, domain string, arns, requested []string) *Notification {
+	joined := strings.Join(arns, ",")
+	// Recorded so the hook fires once per newly attached certificate, not on every reconcile
+	if r.settings().PostIssuanceHook == nil || readBookkeeping(ingress).HookFired == joined {
+		return nil
+	}
+	if !slices.ContainsFunc(arns, func(arn string) bool { return slices.Contains(requested, arn) }) {
+		return nil
+	}
+	updateBookkeeping(ingress, func(b *bookkeeping) { b.HookFired = joined })
+	return &Notification{
+		Event:     NotificationPostIssuance,
+		Ingress:   ingress.Namespace + "/" + ingress.Name,
+		Domain:    domain,
+		ARN:       joined,
+		Status:    string(acmtypes.CertificateStatusIssued),
+		Timestamp: time.Now().UTC(),
+	}
+}
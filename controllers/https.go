@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// configureHTTPSAnnotation adds an HTTPS listener next to the attached certificate
+	configureHTTPSAnnotation = "acm.tedens.dev/configure-https"
+	// sslRedirectAnnotation also redirects HTTP to that listener
+	sslRedirectAnnotation = "acm.tedens.dev/ssl-redirect"
+	// albListenPortsAnnotation is the JSON list of listeners of the AWS Load Balancer Controller
+	albListenPortsAnnotation = "alb.ingress.kubernetes.io/listen-ports"
+	// albSSLRedirectAnnotation is the port the AWS Load Balancer Controller redirects HTTP to
+	albSSLRedirectAnnotation = "alb.ingress.kubernetes.io/ssl-redirect"
+	// defaultHTTPSPort is the port of the HTTPS listener added to listen-ports
+	defaultHTTPSPort = 443
+	// ocspStaplingAnnotation asks for OCSP stapling on the load balancer, which ALB listeners
+	// have no setting for, so it only records a Warning event
+	ocspStaplingAnnotation = "acm.tedens.dev/ocsp-stapling"
+)
+
+// mergeHTTPSListenPort returns listenPorts with an HTTPS listener on port 443, and the port of
+// its HTTPS listener. An existing HTTPS listener is kept, whatever its port, and the other
+// entries are kept in order. Without listen-ports the ALB listens on HTTP port 80, which is
+// kept next to the new listener. Anything but a JSON list of objects is an error, and left
+// for the user to fix.
+func mergeHTTPSListenPort(listenPorts string) (string, int, error) {
+	if strings.TrimSpace(listenPorts) == "" {
+		listenPorts = `[{"HTTP":80}]`
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal([]byte(listenPorts), &entries); err != nil {
+		return "", 0, fmt.Errorf("%s is not a JSON list: %w", albListenPortsAnnotation, err)
+	}
+	for i, entry := range entries {
+		var listener map[string]json.RawMessage
+		if err := json.Unmarshal(entry, &listener); err != nil || listener == nil {
+			return "", 0, fmt.Errorf("%s entry %d is not an object: %s", albListenPortsAnnotation, i, entry)
+		}
+		for protocol, rawPort := range listener {
+			if protocol != "HTTPS" {
+				continue
+			}
+			var port int
+			if err := json.Unmarshal(rawPort, &port); err != nil {
+				return "", 0, fmt.Errorf("%s has an HTTPS port that is not a number: %s", albListenPortsAnnotation, rawPort)
+			}
+			return listenPorts, port, nil
+		}
+	}
+	entries = append(entries, json.RawMessage(`{"HTTPS":`+strconv.Itoa(defaultHTTPSPort)+`}`))
+	merged, err := json.Marshal(entries)
+	if err != nil {
+		return "", 0, err
+	}
+	return string(merged), defaultHTTPSPort, nil
+}
+
+// configureHTTPS adds the HTTPS listener, and with acm.tedens.dev/ssl-redirect the redirect
+// to it, to the annotations of an Ingress with acm.tedens.dev/configure-https. A redirect the
+// user set is kept. It reports whether it changed anything.
+func configureHTTPS(ingress *networkingv1.Ingress, cfg IngressConfig) (bool, error) {
+	if !cfg.ConfigureHTTPS || cfg.ProvisionOnly {
+		return false, nil
+	}
+	current, exists := ingress.Annotations[albListenPortsAnnotation]
+	merged, port, err := mergeHTTPSListenPort(current)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	if merged != current || !exists {
+		ingress.Annotations[albListenPortsAnnotation] = merged
+		changed = true
+	}
+	if _, set := ingress.Annotations[albSSLRedirectAnnotation]; cfg.SSLRedirect && !set {
+		ingress.Annotations[albSSLRedirectAnnotation] = strconv.Itoa(port)
+		changed = true
+	}
+	return changed, nil
+}
+
+// warnOCSPStapling records a Warning event when the Ingress asks for OCSP stapling, or sets
+// acm.tedens.dev/ocsp-stapling to something other than a boolean, once per value
+func (r *IngressReconciler) warnOCSPStapling(ingress *networkingv1.Ingress) {
+	key := client.ObjectKeyFromObject(ingress)
+	switch raw := strings.ToLower(strings.TrimSpace(ingress.Annotations[ocspStaplingAnnotation])); raw {
+	case "true":
+		r.forgetEvent(key, invalidAnnotationSlot(ocspStaplingAnnotation))
+		r.warnOnce(ingress, ReasonOCSPStaplingUnsupported, raw,
+			"ALB listeners have no OCSP stapling setting and the AWS Load Balancer Controller no annotation for it, so %s is ignored",
+			ocspStaplingAnnotation)
+	case "", "false":
+		r.forgetEvent(key, invalidAnnotationSlot(ocspStaplingAnnotation))
+		r.forgetEvent(key, ReasonOCSPStaplingUnsupported)
+	default:
+		r.forgetEvent(key, ReasonOCSPStaplingUnsupported)
+		r.warnInvalidAnnotation(ingress, ocspStaplingAnnotation, "%s must be true or false, not %q; it is ignored",
+			ocspStaplingAnnotation, ingress.Annotations[ocspStaplingAnnotation])
+	}
+}
+
+// warnListenPorts records that the listen-ports of the Ingress could not be merged and were
+// left as they are
+func (r *IngressReconciler) warnListenPorts(ctx context.Context, ingress *networkingv1.Ingress, err error) {
+	log.FromContext(ctx).Info("Not configuring HTTPS, listen-ports left unchanged", "error", err.Error())
+	if r.Recorder != nil {
+		r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonListenPortsInvalid,
+			"Not configuring HTTPS: %v", err)
+	}
+}
+
+// ensureHTTPSListener configures HTTPS on an Ingress whose certificate was attached before
+// acm.tedens.dev/configure-https was set
+func (r *IngressReconciler) ensureHTTPSListener(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig) error {
+	original := ingress.DeepCopy()
+	changed, err := configureHTTPS(ingress, cfg)
+	if err != nil {
+		r.warnListenPorts(ctx, ingress, err)
+		return nil
+	}
+	if !changed {
+		return nil
+	}
+	if r.ObserveOnly {
+		ingress.Annotations = original.Annotations
+		return observeGate(ctx, "configure-https", ingress.Annotations[albListenPortsAnnotation])
+	}
+	return r.patchIngress(ctx, ingress, client.MergeFrom(original))
+}
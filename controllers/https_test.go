@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestMergeHTTPSListenPort(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+		port           int
+	}{
+		{"absent keeps the default HTTP listener", "", `[{"HTTP":80},{"HTTPS":443}]`, 443},
+		{"HTTP only", `[{"HTTP": 80}]`, `[{"HTTP":80},{"HTTPS":443}]`, 443},
+		{"other listeners kept in order", `[{"HTTP": 8080}, {"HTTP": 80}]`, `[{"HTTP":8080},{"HTTP":80},{"HTTPS":443}]`, 443},
+		{"existing HTTPS untouched", `[{"HTTP": 80}, {"HTTPS": 443}]`, `[{"HTTP": 80}, {"HTTPS": 443}]`, 443},
+		{"HTTPS on another port wins", `[{"HTTPS": 8443}]`, `[{"HTTPS": 8443}]`, 8443},
+		{"empty list", `[]`, `[{"HTTPS":443}]`, 443},
+	}
+	for _, c := range cases {
+		got, port, err := mergeHTTPSListenPort(c.in)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if got != c.want || port != c.port {
+			t.Errorf("%s: mergeHTTPSListenPort(%q) = %q, %d; want %q, %d", c.name, c.in, got, port, c.want, c.port)
+		}
+	}
+
+	for _, in := range []string{`{"HTTP": 80}`, `[{"HTTP": 80}`, `["HTTP"]`, `[null]`, `[{"HTTPS": "443"}]`, `HTTP:80`} {
+		if got, _, err := mergeHTTPSListenPort(in); err == nil {
+			t.Errorf("mergeHTTPSListenPort(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestConfigureHTTPSWithCertificate(t *testing.T) {
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		configureHTTPSAnnotation: "true",
+		sslRedirectAnnotation:    "true",
+		albListenPortsAnnotation: `[{"HTTP": 80}]`,
+	})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	if got.Annotations[albCertificateArnAnnotation] == "" {
+		t.Fatal("expected the certificate to be attached")
+	}
+	if ports := got.Annotations[albListenPortsAnnotation]; ports != `[{"HTTP":80},{"HTTPS":443}]` {
+		t.Errorf("listen-ports = %q", ports)
+	}
+	if redirect := got.Annotations[albSSLRedirectAnnotation]; redirect != "443" {
+		t.Errorf("ssl-redirect = %q, want 443", redirect)
+	}
+}
+
+func TestConfigureHTTPSKeepsUserValues(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "acm-manager"})
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		configureHTTPSAnnotation: "true",
+		sslRedirectAnnotation:    "true",
+		albSSLRedirectAnnotation: "8443",
+		albListenPortsAnnotation: `[{"HTTP": 80}`,
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	if got.Annotations[albCertificateArnAnnotation] == "" {
+		t.Fatal("invalid listen-ports must not keep the certificate from being attached")
+	}
+	if ports := got.Annotations[albListenPortsAnnotation]; ports != `[{"HTTP": 80}` {
+		t.Errorf("invalid listen-ports were rewritten to %q", ports)
+	}
+	if redirect := got.Annotations[albSSLRedirectAnnotation]; redirect != "8443" {
+		t.Errorf("ssl-redirect = %q, want the user's 8443", redirect)
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if !slices.ContainsFunc(events, func(e string) bool { return strings.HasPrefix(e, "Warning "+ReasonListenPortsInvalid) }) {
+		t.Errorf("expected a %s warning, got %v", ReasonListenPortsInvalid, events)
+	}
+}
+
+func TestConfigureHTTPSOnAttachedCertificate(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	if _, set := got.Annotations[albListenPortsAnnotation]; set {
+		t.Fatal("listen-ports changed without configure-https")
+	}
+
+	// Opting in later configures the Ingress whose certificate is already attached
+	got.Annotations[configureHTTPSAnnotation] = "true"
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatalf("update ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got = getIngress(t, r, ingress)
+	if ports := got.Annotations[albListenPortsAnnotation]; ports != `[{"HTTP":80},{"HTTPS":443}]` {
+		t.Errorf("listen-ports = %q", ports)
+	}
+	if _, set := got.Annotations[albSSLRedirectAnnotation]; set {
+		t.Error("ssl-redirect set without acm.tedens.dev/ssl-redirect")
+	}
+}
+
+func TestOCSPStaplingOnlyWarns(t *testing.T) {
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{ocspStaplingAnnotation: "true"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	if got.Annotations[albCertificateArnAnnotation] == "" {
+		t.Fatal("expected the certificate to be attached")
+	}
+	for key := range got.Annotations {
+		if strings.HasPrefix(key, "alb.ingress.kubernetes.io/") && key != albCertificateArnAnnotation {
+			t.Errorf("unexpected ALB annotation %s", key)
+		}
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if !slices.ContainsFunc(events, func(e string) bool { return strings.HasPrefix(e, "Warning "+ReasonOCSPStaplingUnsupported) }) {
+		t.Fatalf("expected an OCSPStaplingUnsupported warning, got %v", events)
+	}
+
+	// The warning is not repeated while the annotation stays the same
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if events := drainEvents(r.Recorder.(*record.FakeRecorder)); slices.ContainsFunc(events, func(e string) bool { return strings.Contains(e, ReasonOCSPStaplingUnsupported) }) {
+		t.Fatalf("events = %v, want no repeated OCSPStaplingUnsupported warning", events)
+	}
+}
+
+func TestOCSPStaplingRejectsNonBooleans(t *testing.T) {
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{ocspStaplingAnnotation: "yes please"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.Recorder = nil
+	r.warnOCSPStapling(ingress)
+	r.Recorder = record.NewFakeRecorder(10)
+
+	r.warnOCSPStapling(ingress)
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+ReasonInvalidAnnotation) || !strings.Contains(events[0], "yes please") {
+		t.Fatalf("events = %v, want one InvalidAnnotation warning naming the value", events)
+	}
+}
@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	importFromSecretAnnotation = "acm.tedens.dev/import-from-secret"
+	// importedCertificateAnnotation records the ARN, Secret hash, serial and expiry of the
+	// certificate last imported for the Ingress
+	importedCertificateAnnotation = "acm.tedens.dev/imported-certificate"
+	// importSecretIndex indexes Ingresses by the namespace/name of their import Secret
+	importSecretIndex = "acm.tedens.dev/import-secret"
+	// pinPrivateKeyAnnotation refuses to re-import a Secret whose private key changed
+	pinPrivateKeyAnnotation = "acm.tedens.dev/pin-private-key"
+	// importRotationRecheck is how often the import Secret of a certificate inside its renewal
+	// margin is read again, in case its rotation was missed
+	importRotationRecheck = time.Hour
+)
+
+// importedCertificate is the JSON value of importedCertificateAnnotation
+type importedCertificate struct {
+	CertificateArn string    `json:"certificateArn"`
+	SHA256         string    `json:"sha256"`
+	Serial         string    `json:"serial"`
+	NotAfter       time.Time `json:"notAfter"`
+	// KeySHA256 is the SHA-256 of the certificate's public key, identifying its private key
+	KeySHA256 string `json:"keySha256,omitempty"`
+}
+
+// privateKeyChangedError reports an import Secret whose private key changed while the Ingress
+// pins the key of its imported certificate
+type privateKeyChangedError struct {
+	certificateArn string
+	secret         string
+}
+
+func (e *privateKeyChangedError) Error() string {
+	return fmt.Sprintf("the private key in Secret %s differs from the one imported as %s; %s is set, so it is not re-imported", e.secret, e.certificateArn, pinPrivateKeyAnnotation)
+}
+
+// tlsMaterial is the certificate read from an import Secret
+type tlsMaterial struct {
+	certificate []byte
+	chain       []byte
+	privateKey  []byte
+	leaf        *x509.Certificate
+	hash        string
+	// keyHash is the SHA-256 of the leaf's public key
+	keyHash string
+}
+
+// readTLSSecret reads tls.crt, tls.key and the optional ca.crt of the Secret. The first
+// certificate in tls.crt is the leaf; the rest of tls.crt and ca.crt form the chain.
+func (r *IngressReconciler) readTLSSecret(ctx context.Context, key types.NamespacedName) (tlsMaterial, error) {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	var secret corev1.Secret
+	if err := reader.Get(ctx, key, &secret); err != nil {
+		return tlsMaterial{}, fmt.Errorf("failed to read import Secret %s: %w", key, err)
+	}
+
+	crt, tlsKey := secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	if len(crt) == 0 || len(tlsKey) == 0 {
+		return tlsMaterial{}, fmt.Errorf("import Secret %s needs %s and %s", key, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+
+	block, rest := pem.Decode(crt)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return tlsMaterial{}, fmt.Errorf("import Secret %s: %s does not start with a PEM certificate", key, corev1.TLSCertKey)
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return tlsMaterial{}, fmt.Errorf("import Secret %s: %w", key, err)
+	}
+	if _, err := tls.X509KeyPair(pem.EncodeToMemory(block), tlsKey); err != nil {
+		return tlsMaterial{}, fmt.Errorf("import Secret %s: %s does not match the certificate: %w", key, corev1.TLSPrivateKeyKey, err)
+	}
+	keyHash := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+
+	h := sha256.New()
+	for _, part := range [][]byte{crt, tlsKey, secret.Data["ca.crt"]} {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+
+	chain := slices.Concat(bytes.TrimSpace(rest), []byte("\n"), secret.Data["ca.crt"])
+	return tlsMaterial{
+		certificate: pem.EncodeToMemory(block),
+		chain:       bytes.TrimSpace(chain),
+		privateKey:  tlsKey,
+		leaf:        leaf,
+		hash:        hex.EncodeToString(h.Sum(nil)),
+		keyHash:     hex.EncodeToString(keyHash[:]),
+	}, nil
+}
+
+// importedState returns the Ingress' imported certificate record, or nil
+func importedState(ingress *networkingv1.Ingress) *importedCertificate {
+	raw := ingress.Annotations[importedCertificateAnnotation]
+	if raw == "" {
+		return nil
+	}
+	var state importedCertificate
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		return nil
+	}
+	return &state
+}
+
+// reconcileImportedCertificate imports the certificate of the Ingress' import Secret into ACM
+// and attaches it. When the Secret's contents change, the certificate is re-imported in place
+// under the same ARN, so the ALB picks up the rotated certificate.
+func (r *IngressReconciler) reconcileImportedCertificate(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, domain string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	key := types.NamespacedName{Namespace: ingress.Namespace, Name: cfg.ImportFromSecret}
+
+	material, err := r.readTLSSecret(ctx, key)
+	if err != nil {
+		logger.Error(err, "failed to read import Secret")
+		r.notifyCertificateEvent(ingress, NotificationFailed, domain, "", "", err.Error())
+		return ctrl.Result{}, err
+	}
+
+	current := importedState(ingress)
+	if current != nil && current.SHA256 == material.hash && slices.Contains(attachedCertificateArns(ingress, certificateArnKey(cfg)), current.CertificateArn) {
+		return r.checkImportRotation(ctx, ingress, cfg, current.CertificateArn, material.leaf), nil
+	}
+
+	previousArn := ""
+	if current != nil {
+		previousArn = current.CertificateArn
+	}
+	// Only a certificate on the same key keeps clients pinning that key working; ACM cannot
+	// tell, since re-importing over the ARN accepts any key
+	keyChanged := current != nil && current.KeySHA256 != "" && current.KeySHA256 != material.keyHash
+	if keyChanged && cfg.PinPrivateKey {
+		err := &privateKeyChangedError{certificateArn: previousArn, secret: cfg.ImportFromSecret}
+		logger.Error(err, "refusing to re-import certificate", "secret", key)
+		if r.Recorder != nil {
+			r.Recorder.Event(ingress, corev1.EventTypeWarning, ReasonPrivateKeyChanged, err.Error())
+		}
+		return ctrl.Result{}, err
+	}
+
+	certArn, err := r.importCertificate(ctx, material, previousArn, cfg.Tags)
+	if err != nil {
+		logger.Error(err, "failed to import certificate", "secret", key)
+		if !isDeferred(err) {
+			r.notifyCertificateEvent(ingress, NotificationFailed, domain, previousArn, "", err.Error())
+		}
+		return ctrl.Result{}, err
+	}
+
+	state := importedCertificate{
+		CertificateArn: certArn,
+		SHA256:         material.hash,
+		Serial:         material.leaf.SerialNumber.Text(16),
+		NotAfter:       material.leaf.NotAfter.UTC(),
+		KeySHA256:      material.keyHash,
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	patch := client.MergeFrom(ingress.DeepCopy())
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+	ingress.Annotations[importedCertificateAnnotation] = string(data)
+	if err := r.patchIngress(ctx, ingress, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	verb := "Imported"
+	if certArn == previousArn {
+		verb = "Re-imported"
+	}
+	logger.Info(verb+" certificate from Secret", "secret", key, "arn", certArn, "serial", state.Serial, "notAfter", state.NotAfter, "keyChanged", keyChanged)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(ingress, corev1.EventTypeNormal, ReasonCertificateImported, "%s certificate %s from Secret %s (serial %s, expires %s)",
+			verb, certArn, cfg.ImportFromSecret, state.Serial, state.NotAfter.Format(time.RFC3339))
+		if keyChanged {
+			r.Recorder.Eventf(ingress, corev1.EventTypeNormal, ReasonPrivateKeyChanged,
+				"Certificate %s was re-imported with a new private key from Secret %s; clients pinning the previous key will fail, set %s to refuse such re-imports",
+				certArn, cfg.ImportFromSecret, pinPrivateKeyAnnotation)
+		}
+	}
+
+	if err := r.attachCertificate(ctx, ingress, cfg, domain, []string{certArn}, []string{certArn}); err != nil {
+		return ctrl.Result{}, err
+	}
+	return recheckAfter(ctx, recheckInterval), nil
+}
+
+// checkImportRotation returns when to read the import Secret of the unchanged certificate leaf
+// again. ACM never renews imported certificates, so inside the renewal margin a Secret that has
+// not been rotated gets a Warning event and is read again every importRotationRecheck.
+func (r *IngressReconciler) checkImportRotation(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, certArn string, leaf *x509.Certificate) ctrl.Result {
+	margin := r.renewBefore(ctx, ingress, cfg, &acmtypes.CertificateDetail{NotBefore: aws.Time(leaf.NotBefore), NotAfter: aws.Time(leaf.NotAfter)})
+	if untilWindow := leaf.NotAfter.Add(-margin).Sub(r.clock()); untilWindow > 0 {
+		return recheckAfter(ctx, min(untilWindow, recheckInterval))
+	}
+	log.FromContext(ctx).Info("Imported certificate is inside its renewal margin but its Secret has not been rotated",
+		"arn", certArn, "secret", cfg.ImportFromSecret, "notAfter", leaf.NotAfter)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonImportSecretNotRotated,
+			"Certificate %s imported from Secret %s expires at %s and ACM does not renew imported certificates; rotate the Secret to re-import it",
+			certArn, cfg.ImportFromSecret, leaf.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return recheckAfter(ctx, importRotationRecheck)
+}
+
+// importCertificate imports material into ACM, over previousArn when it still exists
+func (r *IngressReconciler) importCertificate(ctx context.Context, material tlsMaterial, previousArn string, tags map[string]string) (string, error) {
+	input := &acm.ImportCertificateInput{
+		Certificate: material.certificate,
+		PrivateKey:  material.privateKey,
+	}
+	if len(material.chain) > 0 {
+		input.CertificateChain = material.chain
+	}
+
+	if previousArn != "" {
+		input.CertificateArn = aws.String(previousArn)
+		out, err := r.acm(ctx).ImportCertificate(ctx, input)
+		var notFound *acmtypes.ResourceNotFoundException
+		if !errors.As(err, &notFound) {
+			if err != nil {
+				return "", err
+			}
+			return aws.ToString(out.CertificateArn), nil
+		}
+		log.FromContext(ctx).Info("Previously imported certificate is gone, importing a new one", "arn", previousArn)
+		input.CertificateArn = nil
+	}
+
+	// Tags can only be set when a certificate is first imported
+	input.Tags = []acmtypes.Tag{{Key: aws.String(certs.ManagedByTagKey), Value: aws.String(r.managedByValue())}}
+	for k, v := range tags {
+		if _, required := r.RequiredTags[k]; k != certs.ManagedByTagKey && !required {
+			input.Tags = append(input.Tags, acmtypes.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+	}
+	for k, v := range r.RequiredTags {
+		if k != certs.ManagedByTagKey {
+			input.Tags = append(input.Tags, acmtypes.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+	}
+	out, err := r.acm(ctx).ImportCertificate(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.CertificateArn), nil
+}
+
+// indexImportSecret indexes an Ingress under the namespace/name of its import Secret
+func indexImportSecret(obj client.Object) []string {
+	name := obj.GetAnnotations()[importFromSecretAnnotation]
+	if name == "" {
+		return nil
+	}
+	return []string{obj.GetNamespace() + "/" + name}
+}
+
+// enqueueIngressesForSecret requeues the Ingresses importing their certificate from obj
+func (r *IngressReconciler) enqueueIngressesForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	var ingresses networkingv1.IngressList
+	if err := r.List(ctx, &ingresses, client.MatchingFields{importSecretIndex: obj.GetNamespace() + "/" + obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ingresses for Secret change")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(ingresses.Items))
+	for _, ing := range ingresses.Items {
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}})
+	}
+	return requests
+}
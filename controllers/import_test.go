@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newTLSSecret returns a kubernetes.io/tls Secret holding a self-signed certificate for host
+func newTLSSecret(t *testing.T, name, host string, serial int64) *corev1.Secret {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return newTLSSecretWithKey(t, name, host, serial, key)
+}
+
+// newTLSSecretWithKey returns a kubernetes.io/tls Secret holding a certificate for host
+// self-signed with key
+func newTLSSecretWithKey(t *testing.T, name, host string, serial int64, key *ecdsa.PrivateKey) *corev1.Secret {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		},
+	}
+}
+
+func TestImportedCertificateIsReimportedOnRotation(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	secret := newTLSSecret(t, "web-tls", "app.example.com", 1)
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{importFromSecretAnnotation: "web-tls"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), secret, ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 0 || len(fakeACM.imports) != 1 {
+		t.Fatalf("expected one import and no request, got %d imports and %d requests", len(fakeACM.imports), len(fakeACM.requests))
+	}
+	arn := certificateArnOf(t, r, ingress)
+	if arn == "" || fakeACM.tagValue(arn, "ManagedBy") != DefaultManagedByValue {
+		t.Fatalf("imported certificate %q should be attached and owned", arn)
+	}
+
+	// Unchanged Secret: nothing to do
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	if len(fakeACM.imports) != 1 {
+		t.Fatalf("unchanged Secret must not be re-imported, got %d imports", len(fakeACM.imports))
+	}
+
+	// The external CA rotates the Secret
+	rotated := newTLSSecret(t, "web-tls", "app.example.com", 2)
+	secret.Data = rotated.Data
+	if err := r.Update(ctx, secret); err != nil {
+		t.Fatalf("update secret: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after rotation: %v", err)
+	}
+	if len(fakeACM.imports) != 2 || aws.ToString(fakeACM.imports[1].CertificateArn) != arn {
+		t.Fatalf("rotation should re-import over %s, got %d imports", arn, len(fakeACM.imports))
+	}
+	if got := certificateArnOf(t, r, ingress); got != arn {
+		t.Fatalf("re-import must keep the ARN, got %s", got)
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if state := importedState(&got); state == nil || state.Serial != "2" || state.CertificateArn != arn {
+		t.Fatalf("imported-certificate should record serial 2 of %s, got %+v", arn, state)
+	}
+
+	events := r.Recorder.(*record.FakeRecorder).Events
+	found := false
+	for len(events) > 0 {
+		if e := <-events; e == "Normal CertificateImported Re-imported certificate "+arn+" from Secret web-tls (serial 2, expires "+importedState(&got).NotAfter.Format(time.RFC3339)+")" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected a CertificateImported event for the re-import")
+	}
+}
+
+func TestImportedCertificateNearExpiryRereadsSecret(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	secret := newTLSSecret(t, "web-tls", "app.example.com", 1)
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{importFromSecretAnnotation: "web-tls"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), secret, ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+	drainEvents(r.Recorder.(*record.FakeRecorder))
+
+	// 75 days into its 90, the certificate is inside the default 30-day margin
+	now := time.Now().Add(75 * 24 * time.Hour)
+	r.now = func() time.Time { return now }
+	result, err := r.Reconcile(ctx, requestFor(ingress))
+	if err != nil {
+		t.Fatalf("Reconcile near expiry: %v", err)
+	}
+	if result.RequeueAfter != importRotationRecheck {
+		t.Errorf("RequeueAfter = %s, want the Secret read again after %s", result.RequeueAfter, importRotationRecheck)
+	}
+	if len(fakeACM.imports) != 1 {
+		t.Fatalf("an unrotated Secret must not be re-imported, got %d imports", len(fakeACM.imports))
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if !slices.ContainsFunc(events, func(e string) bool {
+		return strings.HasPrefix(e, "Warning "+ReasonImportSecretNotRotated+" Certificate "+arn)
+	}) {
+		t.Fatalf("expected a %s Warning, got %v", ReasonImportSecretNotRotated, events)
+	}
+
+	// Once rotated, the re-read Secret is re-imported over the same ARN
+	secret.Data = newTLSSecret(t, "web-tls", "app.example.com", 2).Data
+	if err := r.Update(ctx, secret); err != nil {
+		t.Fatalf("update secret: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after rotation: %v", err)
+	}
+	if len(fakeACM.imports) != 2 || aws.ToString(fakeACM.imports[1].CertificateArn) != arn {
+		t.Fatalf("rotation should re-import over %s, got %d imports", arn, len(fakeACM.imports))
+	}
+}
+
+func TestEnqueueIngressesForSecret(t *testing.T) {
+	secret := newTLSSecret(t, "web-tls", "app.example.com", 1)
+	importing := newManagedIngress("web", "app.example.com", map[string]string{importFromSecretAnnotation: "web-tls"})
+	other := newManagedIngress("other", "other.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"))
+	r.Client = fake.NewClientBuilder().WithScheme(r.Scheme).
+		WithObjects(secret, importing, other).
+		WithIndex(&networkingv1.Ingress{}, importSecretIndex, indexImportSecret).
+		Build()
+
+	requests := r.enqueueIngressesForSecret(context.Background(), secret)
+	if len(requests) != 1 || requests[0] != requestFor(importing) {
+		t.Fatalf("expected only the importing Ingress, got %v", requests)
+	}
+}
+
+func TestReimportDetectsPrivateKeyChange(t *testing.T) {
+	ctx := context.Background()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	fakeACM := newFakeACM()
+	secret := newTLSSecretWithKey(t, "web-tls", "app.example.com", 1, key)
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{importFromSecretAnnotation: "web-tls", pinPrivateKeyAnnotation: "true"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), secret, ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+	first := importedState(getIngress(t, r, ingress))
+	drainEvents(r.Recorder.(*record.FakeRecorder))
+
+	// Renewed on the same key: re-imported over the ARN
+	secret.Data = newTLSSecretWithKey(t, "web-tls", "app.example.com", 2, key).Data
+	if err := r.Update(ctx, secret); err != nil {
+		t.Fatalf("update secret: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after renewal on the same key: %v", err)
+	}
+	if len(fakeACM.imports) != 2 || aws.ToString(fakeACM.imports[1].CertificateArn) != arn {
+		t.Fatalf("a renewal on the same key should re-import over %s, got %d imports", arn, len(fakeACM.imports))
+	}
+	renewed := importedState(getIngress(t, r, ingress))
+	if renewed.Serial != "2" || renewed.KeySHA256 == "" || renewed.KeySHA256 != first.KeySHA256 {
+		t.Fatalf("expected serial 2 on the same key, got %+v after %+v", renewed, first)
+	}
+	for _, e := range drainEvents(r.Recorder.(*record.FakeRecorder)) {
+		if strings.Contains(e, ReasonPrivateKeyChanged) {
+			t.Fatalf("an unchanged key must not be reported, got %q", e)
+		}
+	}
+
+	// A new key is refused while the key is pinned
+	secret.Data = newTLSSecret(t, "web-tls", "app.example.com", 3).Data
+	if err := r.Update(ctx, secret); err != nil {
+		t.Fatalf("update secret: %v", err)
+	}
+	_, err = r.Reconcile(ctx, requestFor(ingress))
+	var changed *privateKeyChangedError
+	if !errors.As(err, &changed) || quarantineReason(err) != "PrivateKeyChanged" {
+		t.Fatalf("expected the changed key to be refused, got %v", err)
+	}
+	if len(fakeACM.imports) != 2 {
+		t.Fatalf("a changed pinned key must not be re-imported, got %d imports", len(fakeACM.imports))
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if len(events) == 0 || !strings.HasPrefix(events[0], "Warning "+ReasonPrivateKeyChanged) {
+		t.Fatalf("expected a PrivateKeyChanged warning, got %v", events)
+	}
+}
+
+func TestImportRejectsMismatchedPrivateKey(t *testing.T) {
+	secret := newTLSSecret(t, "web-tls", "app.example.com", 1)
+	secret.Data[corev1.TLSPrivateKeyKey] = newTLSSecret(t, "other", "app.example.com", 1).Data[corev1.TLSPrivateKeyKey]
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), secret)
+
+	_, err := r.readTLSSecret(context.Background(), types.NamespacedName{Namespace: "default", Name: "web-tls"})
+	if err == nil || !strings.Contains(err.Error(), "does not match the certificate") {
+		t.Fatalf("expected a key that does not match the certificate to be rejected, got %v", err)
+	}
+}
+
+func TestKeyPinningUnsupportedForIssuedCertificates(t *testing.T) {
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{pinPrivateKeyAnnotation: "true"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	found := false
+	for _, e := range drainEvents(r.Recorder.(*record.FakeRecorder)) {
+		found = found || strings.HasPrefix(e, "Warning "+ReasonKeyPinningUnsupported)
+	}
+	if !found {
+		t.Fatal("expected a KeyPinningUnsupported warning for a certificate issued by ACM")
+	}
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	for _, e := range drainEvents(r.Recorder.(*record.FakeRecorder)) {
+		if strings.HasPrefix(e, "Warning "+ReasonKeyPinningUnsupported) {
+			t.Fatalf("KeyPinningUnsupported recorded again for an unchanged annotation: %v", e)
+		}
+	}
+}
@@ -2,112 +2,687 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/acm"
 	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
-	"github.com/aws/aws-sdk-go-v2/service/route53"
-	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	acmv1alpha1 "github.com/tedens/acm-manager/api/v1alpha1"
+	"github.com/tedens/acm-manager/pkg/certs"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-const ingressFinalizer = "acm.tedens.dev/finalizer"
+// DefaultFinalizerName is the finalizer of managed Ingresses unless FinalizerName sets another.
+// It is also the legacy name Ingresses are migrated from.
+const DefaultFinalizerName = "acm.tedens.dev/finalizer"
+
+// noHostedZoneRequeue is when an Ingress whose domain has no hosted zone is reconciled again
+const noHostedZoneRequeue = time.Hour
+
+// How long and how often ensureCertificate polls a requested certificate until it is issued
+var (
+	validationTimeout      = 10 * time.Minute
+	validationPollInterval = 15 * time.Second
+)
 
 type IngressReconciler struct {
 	client.Client
-	Scheme        *runtime.Scheme
-	ACMClient     *acm.Client
-	Route53Client *route53.Client
+	Scheme    *runtime.Scheme
+	ACMClient ACMAPI
+	// DNSProvider is the default provider for validation records, Route 53 unless overridden
+	DNSProvider DNSProvider
+	// NoRoute53 never builds or calls a Route 53 client: records are published on the Ingress
+	// (event and acm.tedens.dev/validation-records annotation) for someone else to create
+	NoRoute53 bool
+	// APIReader reads objects that are not cached by the manager, such as Secrets
+	APIReader client.Reader
+
+	// ManagedByValue is the ManagedBy tag stamped on requested certificates and
+	// required on existing ones before they are reused or deleted
+	ManagedByValue string
+
+	// RequiredTags are stamped on requested and imported certificates like ManagedByValue, and
+	// an existing certificate must carry all of them to be reused or deleted
+	RequiredTags map[string]string
+
+	// LiveSettings are the options the configuration file can change while the controller
+	// runs; ApplySettings replaces them
+	LiveSettings
+	live atomic.Pointer[LiveSettings]
+
+	// IngressDryRun logs the Ingress updates and patches a reconcile would
+	// make instead of applying them
+	IngressDryRun bool
+
+	// PendingCertificateMaxAge enables a periodic sweep deleting owned certificates
+	// left in PENDING_VALIDATION for longer than this; zero disables the sweep
+	PendingCertificateMaxAge time.Duration
+	GCInterval               time.Duration
+
+	// PolicyConfigMap names a ConfigMap whose data provides cluster-wide annotation defaults
+	PolicyConfigMap types.NamespacedName
+
+	// AWSMaxAttempts and AWSMaxBackoff tune the SDK retryer of the ACM and Route 53 clients
+	// built in SetupWithManager; zero keeps the SDK default
+	AWSMaxAttempts int
+	AWSMaxBackoff  time.Duration
+	// UseFIPSEndpoints makes the ACM, Route 53 and STS clients use FIPS endpoints where their
+	// region has one
+	UseFIPSEndpoints bool
+	// AllowProfileAnnotation honors acm.tedens.dev/aws-profile, for running the controller
+	// locally against shared credentials files
+	AllowProfileAnnotation bool
+	clientsOnce            sync.Once
+	clients                *awsClientCache
+	// lazyClients is set when the default AWS clients are built on first use
+	lazyClients bool
+
+	// UseACMWaiter waits for validation with the SDK's ACM waiter, tuned by ACMWaiter, instead
+	// of polling; it has no effect with RequeuePendingValidation
+	UseACMWaiter bool
+	ACMWaiter    certs.WaiterOptions
+
+	// CertificateIndexInterval enables an in-memory index of owned certificates, rebuilt this
+	// often, that reuse and deletion consult before listing ACM; zero disables it
+	CertificateIndexInterval time.Duration
+	certIndex                *CertificateIndex
+
+	// PrioritizeExpiring queues Ingresses whose certificate is inside its renewal margin ahead
+	// of the others, closest to expiry first
+	PrioritizeExpiring bool
+
+	// ObserveOnly makes no AWS changes and adds or removes no finalizers. Every change a
+	// reconcile would make fails with ErrObserveOnly and is reported in the observed-plan
+	// annotation, an ObservedPlan event and acm_manager_observe_only_skipped_total.
+	ObserveOnly bool
+
+	// MaxCertificateRequestsPerHour limits certificate requests across all reconciles, to
+	// protect the account's yearly ACM quota; requests over it are requeued. Zero disables it.
+	MaxCertificateRequestsPerHour int
+	requestLimiterOnce            sync.Once
+	requestLimiter                *rate.Limiter
+
+	// InventoryConfigMap, when set, names the ConfigMap the leader writes a summary of every
+	// managed Ingress to every InventoryInterval
+	InventoryConfigMap types.NamespacedName
+	InventoryInterval  time.Duration
+	lastReconciled     reconcileTimes
+
+	// Audit, when set, records every mutating AWS call made by the clients built or set here
+	Audit *AuditLogger
+
+	// CertificateInfo, when set, is kept in sync with the certificates attached to each Ingress
+	CertificateInfo *CertificateInfoMetric
+
+	// NamespaceRoleMap names a ConfigMap mapping namespaces to the IAM role, and optionally
+	// the region, used for their Ingresses
+	NamespaceRoleMap types.NamespacedName
+
+	// ZoneFilterTags limits Route 53 zone discovery to hosted zones carrying these tags
+	ZoneFilterTags map[string]string
+	// NegativeZoneCacheTTL is how long a domain without a hosted zone is remembered; zero uses
+	// certs.DefaultNegativeZoneCacheTTL
+	NegativeZoneCacheTTL time.Duration
+
+	// RequeuePendingValidation returns from Reconcile while a requested certificate is pending
+	// validation and checks it again with an exponential backoff, instead of waiting for it
+	RequeuePendingValidation bool
+	pendingRequeues          pendingBackoff
+	ownerLocks               certificateLocks
+
+	// hostWaits counts the checks of Ingresses without a host; classControllers caches the
+	// controllers of IngressClasses
+	hostWaits        hostWaits
+	classControllers classControllers
+
+	// PodIdentity, when set, is stamped on the managed Ingresses this pod reconciles, so a
+	// second pod reconciling them at the same time is reported as a suspected split brain
+	PodIdentity string
+	podStamps   reconcileTimes
+
+	// FinalizerName is the finalizer put on managed Ingresses, so two instances can run side by
+	// side; empty means DefaultFinalizerName. Ingresses carrying DefaultFinalizerName are
+	// migrated to it.
+	FinalizerName string
+
+	// DomainZoneMappings routes validation records to hosted zones by the cluster's
+	// DomainZoneMapping objects before discovering zones, and requires their CRD
+	DomainZoneMappings bool
+	zoneMappingChanges zoneMappingChanges
+
+	// quarantined tracks the Ingresses quarantined for repeated failures
+	quarantined ingressSet
+	// paused tracks the Ingresses skipped for acm.tedens.dev/paused
+	paused ingressSet
+	// recorded tracks the events recorded once per change
+	recorded recordedEvents
+
+	// CloudflareTokenSecret holds the API token (key "api-token") for the cloudflare DNS provider
+	CloudflareTokenSecret types.NamespacedName
+	// DefaultDNSProvider is the DNS provider of Ingresses without acm.tedens.dev/dns-provider;
+	// empty is route53
+	DefaultDNSProvider string
+	// DNSWebhookURL receives the validation records of the webhook DNS provider
+	DNSWebhookURL string
+	// MaintenanceWindow, when set, defers certificate requests, imports and deletions and
+	// validation record changes to its time ranges; read-only reconciles still run
+	MaintenanceWindow *MaintenanceWindow
+	// ShardCount splits managed Ingresses over this many replicas, each reconciling only the
+	// Ingresses of shard ShardIndex; 0 or 1 reconciles all of them
+	ShardCount int
+	ShardIndex int
+
+	Recorder record.EventRecorder
+	// resync feeds ReconcileAll into the controller; elected is closed once this instance leads
+	resync  chan event.GenericEvent
+	elected <-chan struct{}
+	// now replaces time.Now for the retry backoff in tests
+	now func() time.Time
+	// EventDedupWindow aggregates identical events on an object within this window when
+	// SetupWithManager creates the Recorder; zero records every event
+	EventDedupWindow time.Duration
 }
 
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=acm.tedens.dev,resources=domainzonemappings,verbs=get;list;watch
 
 func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	start := time.Now()
+	ctx, finished := withReconcileFinished(ctx)
+	result, err := r.reconcile(ctx, req)
+	observeReconcile(time.Since(start), finished.Load(), result, err)
+	return result, err
+}
+
+func (r *IngressReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx = withAuditSubject(ctx, req.NamespacedName.String())
+	var plan *observedPlan
+	if r.ObserveOnly {
+		ctx, plan = withObservedPlan(ctx)
+	}
+	var ingress networkingv1.Ingress
+	if err := r.Get(ctx, req.NamespacedName, &ingress); err == nil {
+		// Watches map to Ingresses of every shard; another replica owns this one
+		if !r.inShard(&ingress) {
+			return ctrl.Result{}, nil
+		}
+		if r.skipPaused(ctx, &ingress) {
+			return ctrl.Result{}, nil
+		}
+		// A lifted quarantine starts the failure count over
+		if backoff := savedRetryBackoff(ctx, &ingress); backoff != nil && quarantineLifted(backoff, &ingress) {
+			log.FromContext(ctx).Info("Quarantine lifted, retrying")
+			if err := r.clearRetryBackoff(ctx, &ingress); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		// A changed zone mapping may fix what the Ingress failed on
+		if r.zoneMappingChanges.takeRetry(req.NamespacedName) && savedRetryBackoff(ctx, &ingress) != nil {
+			log.FromContext(ctx).Info("DomainZoneMapping changed, retrying")
+			if err := r.clearRetryBackoff(ctx, &ingress); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		if wait := r.retryWait(ctx, &ingress); wait > 0 {
+			if reason, ok := ingress.Annotations[quarantinedAnnotation]; ok {
+				r.quarantined.set(req.NamespacedName, true, quarantinedIngresses)
+				log.FromContext(ctx).Info("Quarantined, probing again later", "reason", reason, "after", wait)
+			} else {
+				log.FromContext(ctx).Info("Backing off after failed reconciles", "after", wait)
+			}
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	} else if apierrors.IsNotFound(err) {
+		r.paused.set(req.NamespacedName, false, pausedIngresses)
+		r.lastReconciled.forget(req.NamespacedName)
+		r.podStamps.forget(req.NamespacedName)
+	}
+
+	result, err := r.reconcileIngress(ctx, req)
+	if ingress.Name != "" {
+		r.lastReconciled.set(req.NamespacedName, r.clock())
+	}
+	if _, planned := ingress.Annotations[observedPlanAnnotation]; ingress.Name != "" && (r.ObserveOnly || planned) {
+		r.recordObservedPlan(ctx, req.NamespacedName, plan.String())
+	}
+	if errors.Is(err, ErrObserveOnly) {
+		// Not a failure: the change is reported in the plan and looked at again later
+		return ctrl.Result{RequeueAfter: observeOnlyRequeue}, nil
+	}
+	var deferred *maintenanceDeferredError
+	if errors.As(err, &deferred) {
+		// Not a failure: the change waits for the window without backoff or a last error
+		wait := deferred.opens.Sub(r.clock())
+		log.FromContext(ctx).Info("Outside the maintenance window, deferring", "operation", deferred.operation, "opens", deferred.opens, "after", wait)
+		if ingress.Name != "" {
+			r.resetTerminalFailures(ctx, req.NamespacedName)
+		}
+		if ingress.Name != "" && r.Recorder != nil {
+			r.Recorder.Eventf(&ingress, corev1.EventTypeNormal, ReasonOperationDeferred,
+				"%s is outside the maintenance window; retrying when it opens at %s", deferred.operation, deferred.opens.UTC().Format(time.RFC3339))
+		}
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+	var limited *requestRateLimitedError
+	if errors.As(err, &limited) {
+		// Not a failure: the request waits for the rate limit without backoff or a last error
+		wait := max(limited.retryAt.Sub(r.clock()), time.Second)
+		log.FromContext(ctx).Info("Certificate request rate limit reached, deferring", "after", wait)
+		if ingress.Name != "" {
+			r.resetTerminalFailures(ctx, req.NamespacedName)
+		}
+		if ingress.Name != "" && r.Recorder != nil {
+			r.Recorder.Eventf(&ingress, corev1.EventTypeNormal, ReasonOperationDeferred,
+				"The limit of %d certificate requests per hour was reached; retrying at %s", limited.perHour, limited.retryAt.UTC().Format(time.RFC3339))
+		}
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+	var credentials *awsCredentialsError
+	if errors.As(err, &credentials) {
+		// Not the Ingress' failure: every reconcile waits for the same credentials
+		wait := max(credentials.retryAt.Sub(r.clock()), awsCredentialsMinBackoff)
+		log.FromContext(ctx).Info("Waiting for AWS credentials", "after", wait, "error", credentials.err.Error())
+		if ingress.Name != "" {
+			r.resetTerminalFailures(ctx, req.NamespacedName)
+		}
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+	var retryAfter time.Duration
+	if errors.Is(err, certs.ErrNoHostedZone) {
+		retryAfter = noHostedZoneRequeue
+	}
+	r.recordLastError(ctx, req.NamespacedName, err, retryAfter)
+	if errors.Is(err, certs.ErrNoHostedZone) {
+		// Retrying right away cannot help until the zone is created
+		log.FromContext(ctx).Info("No hosted zone for the Ingress' domain, checking again later", "after", noHostedZoneRequeue, "error", err.Error())
+		return ctrl.Result{RequeueAfter: noHostedZoneRequeue}, nil
+	}
+	return result, err
+}
+
+func (r *IngressReconciler) reconcileIngress(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
 	var ingress networkingv1.Ingress
 	if err := r.Get(ctx, req.NamespacedName, &ingress); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.forgetCertificateMetrics(req.Namespace, req.Name)
+			r.forgetEvent(req.NamespacedName, "")
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	cfg := ParseIngressAnnotations(ingress.GetAnnotations())
+	policy, err := r.loadPolicy(ctx)
+	if err != nil {
+		logger.Error(err, "failed to load policy ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	cfg, err := r.ingressConfig(ctx, &ingress, policy)
+	if err != nil {
+		logger.Error(err, "failed to load namespace defaults")
+		return ctrl.Result{}, err
+	}
 	if !cfg.Managed {
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.releaseIngress(ctx, &ingress)
 	}
+	if skip, err := r.skipNonALBIngress(ctx, &ingress, cfg); skip || err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.migrateBookkeeping(ctx, &ingress); err != nil {
+		logger.Error(err, "failed to migrate bookkeeping annotations")
+		return ctrl.Result{}, err
+	}
+	r.stampPodIdentity(ctx, &ingress)
 
-	if certArn, exists := ingress.Annotations["alb.ingress.kubernetes.io/certificate-arn"]; exists && cfg.Managed {
-		logger := log.FromContext(ctx)
-		describe, err := r.ACMClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
-			CertificateArn: aws.String(certArn),
-		})
-		if err != nil {
-			logger.Error(err, "Failed to describe existing ACM certificate")
+	target, err := r.awsTargetFor(ctx, &ingress, cfg)
+	if err == nil {
+		ctx, err = r.withAWSTarget(ctx, target)
+	}
+	if err != nil {
+		logger.Error(err, "failed to select the AWS role")
+		r.notifyCertificateEvent(&ingress, NotificationFailed, cfg.DomainOverride, "", "", err.Error())
+		return ctrl.Result{}, err
+	}
+	if target != (awsTarget{}) {
+		logger = logger.WithValues("roleArn", target.RoleARN, "region", target.Region)
+		if target.Profile != "" {
+			logger = logger.WithValues("awsProfile", target.Profile)
+		}
+		ctx = log.IntoContext(ctx, logger)
+	}
+
+	if err := applyNameTemplates(&ingress, &cfg); err != nil {
+		if ingress.DeletionTimestamp.IsZero() {
+			logger.Error(err, "failed to render name templates")
+			r.warnOnce(&ingress, ReasonNameTemplateFailed, err.Error(), "%s", err.Error())
 			return ctrl.Result{}, err
 		}
+		logger.Info("Ignoring name templates while the Ingress is being deleted", "error", err.Error())
+	} else {
+		r.forgetEvent(req.NamespacedName, ReasonNameTemplateFailed)
+	}
 
-		status := describe.Certificate.Status
-		if status != acmtypes.CertificateStatusIssued {
-			logger.Info("Existing cert is not issued, proceeding with reconciliation", "status", status)
-		} else {
-			logger.Info("ACM certificate already issued and valid, skipping reconciliation")
-			return ctrl.Result{RequeueAfter: 12 * time.Hour}, nil
+	if err := r.applyNamesFromConfigMap(ctx, &ingress, &cfg); err != nil {
+		if ingress.DeletionTimestamp.IsZero() {
+			logger.Error(err, "failed to load names from ConfigMap")
+			r.notifyCertificateEvent(&ingress, NotificationFailed, cfg.DomainOverride, "", "", err.Error())
+			return ctrl.Result{}, err
+		}
+		logger.Info("Ignoring names ConfigMap while the Ingress is being deleted", "error", err.Error())
+	}
+
+	if err := applyPrimaryRule(&ingress, &cfg); err != nil {
+		if ingress.DeletionTimestamp.IsZero() {
+			logger.Error(err, "failed to select the primary rule")
+			r.notifyCertificateEvent(&ingress, NotificationFailed, "", "", "", err.Error())
+			return ctrl.Result{}, err
 		}
+		logger.Info("Ignoring the primary rule index while the Ingress is being deleted", "error", err.Error())
 	}
 
-	domain := cfg.DomainOverride
-	if domain == "" && len(ingress.Spec.Rules) > 0 {
-		domain = ingress.Spec.Rules[0].Host
+	domain := resolveDomain(&ingress, cfg)
+
+	// Members of a certificate or ALB group share the group's certificates; the last member
+	// owns them
+	var members []networkingv1.Ingress
+	group := certificateGroup(&ingress)
+	if domain == "" && group == "" {
+		if cfg.DomainFrom == DomainFromStatusLB && ingress.DeletionTimestamp.IsZero() {
+			after := r.settings().NoHostRequeueInterval
+			if after <= 0 {
+				after = DefaultNoHostRequeueInterval
+			}
+			logger.Info("Ingress has no load balancer hostname yet, checking again", "after", after)
+			return ctrl.Result{RequeueAfter: after}, nil
+		}
+		if after := r.waitForHost(&ingress); after > 0 {
+			logger.Info("Ingress has no host yet, checking again", "after", after)
+			return ctrl.Result{RequeueAfter: after}, nil
+		}
+		logger.Info("Ingress has no host to request a certificate for, skipping")
+		return ctrl.Result{}, r.releaseIngress(ctx, &ingress)
+	}
+	r.hostWaits.forget(ingress.UID)
+	r.forgetEvent(req.NamespacedName, ReasonNoHosts)
+	primary := true
+	if group != "" {
+		members, err = r.groupMembers(ctx, &ingress, group, policy)
+		primary = len(members) == 1
+	} else {
+		primary, err = r.isDomainPrimary(ctx, &ingress, domain, policy)
+	}
+	if err != nil {
+		logger.Error(err, "failed to determine certificate owner")
+		return ctrl.Result{}, err
 	}
 
 	if ingress.ObjectMeta.DeletionTimestamp.IsZero() {
-		if !controllerutil.ContainsFinalizer(&ingress, ingressFinalizer) {
-			controllerutil.AddFinalizer(&ingress, ingressFinalizer)
-			if err := r.Update(ctx, &ingress); err != nil {
+		if r.ensureFinalizer(ctx, &ingress) {
+			if err := r.updateIngress(ctx, &ingress); err != nil {
 				return ctrl.Result{}, err
 			}
 		}
 	} else {
-		if controllerutil.ContainsFinalizer(&ingress, ingressFinalizer) {
-			if cfg.DeleteCertOnIngress {
+		if r.hasFinalizer(&ingress) {
+			// A pending certificate was never attached, so nothing else can depend on it
+			if remaining := r.cleanupPendingCertificates(ctx, pendingCertificateArns(&ingress), ""); len(remaining) > 0 {
+				return ctrl.Result{}, fmt.Errorf("failed to delete pending certificates: %s", strings.Join(remaining, ","))
+			}
+			// Whether or not its certificates are deleted, the Ingress no longer holds them
+			if err := r.releaseCertificateOwners(ctx, &ingress, attachedCertificateArns(&ingress, certificateArnKey(cfg))); err != nil {
+				logger.Error(err, "failed to release certificate ownership")
+				return ctrl.Result{}, err
+			}
+			deleteReplicas := cfg.DeleteCertOnIngress && !cfg.DeletionProtection && primary
+			if err := r.releaseReplicas(ctx, &ingress, cfg, deleteReplicas); err != nil {
+				logger.Error(err, "Failed to release certificate replicas")
+				return ctrl.Result{}, err
+			}
+			if cfg.DeleteCertOnIngress && cfg.DeletionProtection {
+				logger.Info("Deletion protection is on, leaving the certificate", "domain", domain)
+				r.eventOnce(&ingress, corev1.EventTypeNormal, ReasonDeletionProtected, domain,
+					"Not deleting the certificate for %s: %s is set", domain, deletionProtectionAnnotation)
+			} else if cfg.DeleteCertOnIngress && !primary {
+				logger.Info("Certificate is still shared with other Ingresses, leaving it", "domain", domain)
+			} else if cfg.DeleteCertOnIngress && group != "" {
+				logger.Info("Last member of the group is being deleted. Deleting group certificates...", "group", group)
+				if err := r.deleteOwnedCertificates(ctx, attachedCertificateArns(&ingress, certificateArnKey(cfg)), r.groupCertificateNames(&ingress, members, policy), ingressKey(&ingress)); err != nil {
+					logger.Error(err, "Failed to delete ACM certificate")
+					return ctrl.Result{}, err
+				}
+			} else if attached := attachedCertificateArns(&ingress, certificateArnKey(cfg)); cfg.DeleteCertOnIngress && r.settings().AutoSplitCertificates && len(attached) > 1 {
+				logger.Info("Ingress is being deleted. Deleting its split certificates...", "domain", domain)
+				names := []string{strings.ToLower(certificateDomain(domain, cfg))}
+				for _, san := range cfg.SANs {
+					names = append(names, strings.ToLower(san))
+				}
+				if err := r.deleteOwnedCertificates(ctx, attached, names, ingressKey(&ingress)); err != nil {
+					logger.Error(err, "Failed to delete ACM certificate")
+					return ctrl.Result{}, err
+				}
+			} else if cfg.DeleteCertOnIngress {
 				logger.Info("Ingress is being deleted. Deleting associated ACM certificate...", "domain", domain)
-				if err := r.deleteCertificateForDomain(ctx, domain); err != nil {
+				if err := r.deleteCertificateForDomain(ctx, acmPrimaryDomain(domain, cfg.SANs), ingressKey(&ingress)); err != nil {
 					logger.Error(err, "Failed to delete ACM certificate")
 					return ctrl.Result{}, err
 				}
 			}
-			controllerutil.RemoveFinalizer(&ingress, ingressFinalizer)
-			if err := r.Update(ctx, &ingress); err != nil {
+			r.removeFinalizers(&ingress)
+			if err := r.updateIngress(ctx, &ingress); err != nil {
 				return ctrl.Result{}, err
 			}
+			r.forgetCertificateMetrics(ingress.Namespace, ingress.Name)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, nil
 	}
 
+	if yield, err := r.yieldCertificateArn(ctx, &ingress, cfg); yield || err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.warnOCSPStapling(&ingress)
+	r.warnRenewBefore(&ingress)
+	if cfg.ImportFromSecret != "" {
+		r.rejectReplicas(ctx, &ingress, cfg, "Imported")
+		return r.reconcileImportedCertificate(ctx, &ingress, cfg, domain)
+	}
+	if cfg.PinPrivateKey {
+		r.warnOnce(&ingress, ReasonKeyPinningUnsupported, "true",
+			"ACM gives the certificates it issues a new private key on every issuance and renewal, so %s only applies with %s and is ignored",
+			pinPrivateKeyAnnotation, importFromSecretAnnotation)
+	} else {
+		r.forgetEvent(req.NamespacedName, ReasonKeyPinningUnsupported)
+	}
+
+	if group != "" {
+		r.rejectReplicas(ctx, &ingress, cfg, "Group")
+		return r.reconcileGroup(ctx, &ingress, cfg, members, policy)
+	}
+
+	if cfg.IncludeWildcardSAN || cfg.IncludeWWW {
+		dnsProvider, err := r.dnsProviderFor(ctx, &ingress, cfg)
+		if err == nil {
+			err = r.includeWildcardSAN(ctx, dnsProvider, domain, &cfg)
+		}
+		if err == nil {
+			err = r.includeWWW(ctx, dnsProvider, domain, &cfg)
+		}
+		if err != nil {
+			logger.Error(err, "failed to check for an apex domain")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.applyWildcardSANPolicy(ctx, domain, &cfg); err != nil {
+		r.notifyCertificateEvent(&ingress, NotificationFailed, domain, "", "", err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if names := 1 + len(cfg.SANs); names > r.maxNames() {
+		if r.settings().AutoSplitCertificates {
+			r.rejectReplicas(ctx, &ingress, cfg, "Split")
+			return r.reconcileSplit(ctx, &ingress, cfg, domain)
+		}
+		err := &errTooManyNames{names: names, limit: r.maxNames()}
+		logger.Error(err, "cannot request a certificate")
+		r.notifyCertificateEvent(&ingress, NotificationFailed, domain, "", "", err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if certArn, exists := ingress.Annotations[certificateArnKey(cfg)]; exists && cfg.Managed {
+		logger := log.FromContext(ctx)
+		describe, err := r.acm(ctx).DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+			CertificateArn: aws.String(certArn),
+		})
+		var notFound *acmtypes.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			if err := r.forgetMissingCertificate(ctx, &ingress, cfg, certArn); err != nil {
+				return ctrl.Result{}, err
+			}
+		} else if err != nil {
+			logger.Error(err, "Failed to describe existing ACM certificate")
+			return ctrl.Result{}, err
+		} else if status := describe.Certificate.Status; status != acmtypes.CertificateStatusIssued {
+			logger.Info("Existing cert is not issued, proceeding with reconciliation", "status", status)
+		} else {
+			renewBefore := r.renewBefore(ctx, &ingress, cfg, describe.Certificate)
+			if notAfter := describe.Certificate.NotAfter; notAfter != nil && time.Until(*notAfter) < renewBefore {
+				if err := r.escalateRenewal(ctx, &ingress, cfg, domain, describe.Certificate); err != nil {
+					logger.Error(err, "failed to escalate certificate renewal", "arn", certArn)
+					return ctrl.Result{}, err
+				}
+			}
+			if err := r.setCoveredNames(ctx, &ingress, coveredNames(describe.Certificate)); err != nil {
+				return ctrl.Result{}, err
+			}
+			if err := r.setNotAfter(ctx, &ingress, earliestNotAfter(describe.Certificate)); err != nil {
+				return ctrl.Result{}, err
+			}
+			if err := r.trackWrittenCertificateArn(ctx, &ingress, cfg); err != nil {
+				return ctrl.Result{}, err
+			}
+			if err := r.ensureHTTPSListener(ctx, &ingress, cfg); err != nil {
+				return ctrl.Result{}, err
+			}
+			// A shared certificate gets the tags of the primary Ingress' Namespace only
+			if err := r.syncCertificateTags(ctx, &ingress, certificateArnKey(cfg), primary); err != nil {
+				logger.Error(err, "failed to update certificate tags", "arn", certArn)
+				return ctrl.Result{}, err
+			}
+			if err := r.addCertificateOwner(ctx, certArn, &ingress); err != nil {
+				logOwnerError(ctx, err, certArn)
+			}
+			logger.Info("ACM certificate already issued and valid, skipping reconciliation")
+			r.CertificateInfo.set(&ingress, domain, []string{certArn}, string(describe.Certificate.Status))
+			r.observeRenewal(&ingress, describe.Certificate)
+			result := recheckAfter(ctx, renewalRequeue(describe.Certificate.NotAfter, renewBefore))
+			if !primary {
+				r.rejectReplicas(ctx, &ingress, cfg, "Shared")
+				return result, nil
+			}
+			return r.withReplicas(ctx, &ingress, cfg, domain, certArn, result)
+		}
+	}
+
+	if !primary {
+		r.rejectReplicas(ctx, &ingress, cfg, "Shared")
+		return r.attachSharedCertificate(ctx, &ingress, cfg, domain)
+	}
+
 	logger.Info("Reconciling managed Ingress", "name", req.NamespacedName, "domain", domain)
 
-	certArn, err := r.ensureCertificate(ctx, domain, cfg)
+	dnsProvider, err := r.dnsProviderFor(ctx, &ingress, cfg)
+	if err != nil {
+		logger.Error(err, "failed to select DNS provider")
+		r.notifyCertificateEvent(&ingress, NotificationFailed, domain, "", "", err.Error())
+		return ctrl.Result{}, err
+	}
+
+	result, err := r.ensureCertificate(ctx, &ingress, domain, cfg, dnsProvider)
+	certArn := result.CertificateArn
+	if errors.Is(err, certs.ErrValidationPending) {
+		delay := r.pendingRequeues.next(ingress.UID, certArn+" "+certs.FormatDomainStatuses(result.Domains))
+		logger.Info("Certificate is pending validation, checking again later", "arn", certArn, "after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+	r.pendingRequeues.forget(ingress.UID)
+	if errors.Is(err, certs.ErrAbandoned) {
+		logger.Info("Ingress went away while its certificate was validating, deleting the certificate", "arn", certArn)
+		r.abandonCertificate(ctx, certArn)
+		return ctrl.Result{}, nil
+	}
 	if err != nil {
 		logger.Error(err, "failed to ensure certificate")
+		var unsupported *certs.UnsupportedRecordTypeError
+		if errors.As(err, &unsupported) {
+			r.warnOnce(&ingress, ReasonUnsupportedValidationRecord, err.Error(), "%s", err.Error())
+		}
+		var tooLong *certs.DomainTooLongError
+		if errors.As(err, &tooLong) {
+			r.warnOnce(&ingress, ReasonDomainTooLong, err.Error(), "%s", err.Error())
+		} else if !isDeferred(err) {
+			r.notifyCertificateEvent(&ingress, NotificationFailed, domain, certArn, "", err.Error())
+		}
+		if certArn != "" {
+			if trackErr := r.trackPendingCertificate(ctx, &ingress, certArn); trackErr != nil {
+				logger.Error(trackErr, "failed to record pending certificate", "arn", certArn)
+			}
+		}
 		return ctrl.Result{}, err
 	}
 
-	patch := client.MergeFrom(ingress.DeepCopy())
-	if ingress.Annotations == nil {
-		ingress.Annotations = map[string]string{}
+	if err := r.tagCertificateOwner(ctx, certArn, &ingress); err != nil {
+		logOwnerError(ctx, err, certArn)
+	}
+
+	var requested []string
+	if !result.Reused {
+		requested = []string{certArn}
+	}
+	if err := r.attachCertificate(ctx, &ingress, cfg, domain, []string{certArn}, requested); err != nil {
+		return ctrl.Result{}, err
 	}
+	return r.withReplicas(ctx, &ingress, cfg, domain, certArn, recheckAfter(ctx, recheckInterval))
+}
+
+// attachCertificate patches the ALB certificate-arn annotation, or certificate-arn for
+// provision-only Ingresses, with certArns (plus the fallback wildcard when requested) and clears
+// pending certificates they replace. Certificates that do not cover the Ingress' domain and
+// SANs are not attached. requested are those of certArns this controller requested or imported
+// in this reconcile; with the Ingress' pending certificates, they fire the post-issuance hook.
+func (r *IngressReconciler) attachCertificate(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, domain string, certArns, requested []string) error {
+	logger := log.FromContext(ctx)
 
-	certARNs := []string{certArn}
+	certARNs := append([]string(nil), certArns...)
 	if cfg.FallbackWildcard {
 		wildcardArn, err := r.findFallbackWildcardCert(ctx, domain)
 		if err == nil && wildcardArn != "" {
@@ -115,22 +690,104 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 	}
 
-	ingress.Annotations["alb.ingress.kubernetes.io/certificate-arn"] = strings.Join(certARNs, ",")
+	certificates, err := r.describeCertificates(ctx, certARNs)
+	if err != nil {
+		logger.Error(err, "failed to verify certificates before attaching them")
+		return err
+	}
+	if missing := uncoveredNames(certificates, append([]string{certificateDomain(domain, cfg)}, cfg.SANs...)); len(missing) > 0 {
+		mismatch := &certificateMismatchError{arns: certARNs, missing: missing}
+		logger.Info("Not attaching a certificate that does not cover the Ingress", "arn", certARNs, "missing", missing)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonCertificateMismatch,
+				"Not attaching certificate %s: it does not cover %s", strings.Join(certARNs, ","), strings.Join(missing, ","))
+		}
+		return mismatch
+	}
+
+	if r.ObserveOnly {
+		return observeGate(ctx, "attach", strings.Join(certARNs, ","))
+	}
+
+	patch := client.MergeFrom(ingress.DeepCopy())
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+
+	// Pending certificates were requested by an earlier reconcile, and are dropped below
+	requested = append(slices.Clone(requested), pendingCertificateArns(ingress)...)
+	var replaced []string
+	for _, arn := range pendingCertificateArns(ingress) {
+		if !slices.Contains(certArns, arn) {
+			replaced = append(replaced, arn)
+		}
+	}
+	setPendingCertificateArns(ingress, r.cleanupPendingCertificates(ctx, replaced, ""))
+	delete(ingress.Annotations, validationStateAnnotation)
+
+	ingress.Annotations[certificateArnKey(cfg)] = strings.Join(certARNs, ",")
+	setWrittenCertificateArn(ingress, strings.Join(certARNs, ","))
+	delete(ingress.Annotations, certificateArnConflictAnnotation)
+	if _, err := configureHTTPS(ingress, cfg); err != nil {
+		r.warnListenPorts(ctx, ingress, err)
+	}
+	if names := coveredNames(certificates...); names != "" {
+		ingress.Annotations[coveredNamesAnnotation] = names
+	}
+	if notAfter := earliestNotAfter(certificates...); notAfter != "" {
+		ingress.Annotations[notAfterAnnotation] = notAfter
+	}
+	hook := r.preparePostIssuanceHook(ingress, domain, certArns, requested)
 
-	if err := r.Patch(ctx, &ingress, patch); err != nil {
+	if err := r.patchIngress(ctx, ingress, patch); err != nil {
 		logger.Error(err, "failed to patch ingress with cert ARN")
-		return ctrl.Result{}, err
+		return err
+	}
+	// A reload may have removed the hook since it was prepared
+	if notifier := r.settings().PostIssuanceHook; hook != nil && notifier != nil {
+		logger.Info("Firing post-issuance hook", "arn", hook.ARN)
+		notifier.Notify(*hook)
+	}
+
+	logger.Info("Patched ingress with ACM cert ARN", "arn", certARNs, "annotation", certificateArnKey(cfg))
+	r.CertificateInfo.set(ingress, domain, certArns, string(acmtypes.CertificateStatusIssued))
+	// The renewal series of the certificates replaced are stale; the next check of the
+	// attached certificate exports them again
+	deleteRenewalMetrics(ingress.Namespace, ingress.Name)
+	r.notifyCertificateEvent(ingress, NotificationIssued, domain, strings.Join(certArns, ","), string(acmtypes.CertificateStatusIssued), "")
+	return nil
+}
+
+// forgetMissingCertificate removes the certificate ARN annotation of an Ingress whose attached
+// certificate was deleted outside the controller, so it is provisioned again. In observe-only
+// mode it only records the event.
+func (r *IngressReconciler) forgetMissingCertificate(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, certArn string) error {
+	log.FromContext(ctx).Info("Attached certificate no longer exists, provisioning a new one", "arn", certArn)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonCertificateMissing,
+			"Certificate %s no longer exists in ACM, provisioning a new one", certArn)
+	}
+	if r.ObserveOnly {
+		return observeGate(ctx, "forget", certArn)
 	}
 
-	logger.Info("Patched ingress with ACM cert ARN", "arn", certARNs)
-	return ctrl.Result{RequeueAfter: 12 * time.Hour}, nil
+	patch := client.MergeFrom(ingress.DeepCopy())
+	delete(ingress.Annotations, certificateArnKey(cfg))
+	delete(ingress.Annotations, notAfterAnnotation)
+	updateBookkeeping(ingress, func(b *bookkeeping) { b.Generation++ })
+	if err := r.patchIngress(ctx, ingress, patch); err != nil {
+		return fmt.Errorf("failed to clear stale certificate ARN: %w", err)
+	}
+	r.forgetCertificateMetrics(ingress.Namespace, ingress.Name)
+	return nil
 }
 
 func (r *IngressReconciler) findFallbackWildcardCert(ctx context.Context, domain string) (string, error) {
-	paginator := acm.NewListCertificatesPaginator(r.ACMClient, &acm.ListCertificatesInput{
+	paginator := acm.NewListCertificatesPaginator(r.acm(ctx), &acm.ListCertificatesInput{
 		CertificateStatuses: []acmtypes.CertificateStatus{
 			acmtypes.CertificateStatusIssued,
 		},
+		Includes: certs.AllKeyTypes(),
 	})
 
 	for paginator.HasMorePages() {
@@ -151,12 +808,39 @@ func (r *IngressReconciler) findFallbackWildcardCert(ctx context.Context, domain
 	return "", nil
 }
 
-func (r *IngressReconciler) deleteCertificateForDomain(ctx context.Context, domain string) error {
-	out, err := r.ACMClient.ListCertificates(ctx, &acm.ListCertificatesInput{
-		CertificateStatuses: []acmtypes.CertificateStatus{
-			acmtypes.CertificateStatusIssued,
-			acmtypes.CertificateStatusPendingValidation,
-		},
+// deleteCertificateForDomain deletes the owned certificate of domain that no Ingress besides
+// owner still holds
+func (r *IngressReconciler) deleteCertificateForDomain(ctx context.Context, domain, owner string) error {
+	statuses := []acmtypes.CertificateStatus{
+		acmtypes.CertificateStatusIssued,
+		acmtypes.CertificateStatusPendingValidation,
+	}
+
+	// Indexed certificates are confirmed with a describe; a miss falls back to the listing
+	for _, certArn := range r.indexedCertificatesFor(ctx, domain) {
+		describe, err := r.acm(ctx).DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+			CertificateArn: aws.String(certArn),
+		})
+		var notFound *acmtypes.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			r.certificateIndex(ctx).Forget(certArn)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		cert := describe.Certificate
+		if !strings.EqualFold(aws.ToString(cert.DomainName), domain) || !slices.Contains(statuses, cert.Status) {
+			continue
+		}
+		if deleted, err := r.deleteOwnedCertificate(ctx, certArn, owner); err != nil || deleted {
+			return err
+		}
+	}
+
+	out, err := r.acm(ctx).ListCertificates(ctx, &acm.ListCertificatesInput{
+		CertificateStatuses: statuses,
+		Includes:            certs.AllKeyTypes(),
 	})
 	if err != nil {
 		return err
@@ -164,249 +848,203 @@ func (r *IngressReconciler) deleteCertificateForDomain(ctx context.Context, doma
 
 	for _, cert := range out.CertificateSummaryList {
 		if strings.EqualFold(aws.ToString(cert.DomainName), domain) {
-			_, err := r.ACMClient.DeleteCertificate(ctx, &acm.DeleteCertificateInput{
-				CertificateArn: cert.CertificateArn,
-			})
-			return err
+			if deleted, err := r.deleteOwnedCertificate(ctx, aws.ToString(cert.CertificateArn), owner); err != nil || deleted {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
-func (r *IngressReconciler) ensureCertificate(ctx context.Context, domain string, cfg IngressConfig) (string, error) {
-	if cfg.ReuseExisting {
-		out, err := r.ACMClient.ListCertificates(ctx, &acm.ListCertificatesInput{
-			CertificateStatuses: []acmtypes.CertificateStatus{
-				acmtypes.CertificateStatusIssued,
-				acmtypes.CertificateStatusPendingValidation,
-			},
-		})
-		if err != nil {
-			return "", err
-		}
-		for _, cert := range out.CertificateSummaryList {
-			if strings.EqualFold(aws.ToString(cert.DomainName), domain) {
-				certArn := aws.ToString(cert.CertificateArn)
-				logger := log.FromContext(ctx)
-				logger.Info("Reusing existing ACM certificate", "domain", domain, "arn", certArn)
-
-				// Confirm ResourceRecord exists before proceeding
-				describe, err := r.ACMClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
-					CertificateArn: aws.String(certArn),
-				})
-				if err != nil {
-					return certArn, err
-				}
-				options := describe.Certificate.DomainValidationOptions
-				if len(options) > 0 && options[0].ResourceRecord != nil {
-					// ResourceRecord already exists; skip DNS setup and validation wait
-					return certArn, nil
-				}
-				break // exit loop and proceed to DNS record creation if needed
-			}
-		}
+// deleteOwnedCertificate deletes the certificate when this instance owns it and no Ingress
+// besides owner holds it, reporting whether it was handled
+func (r *IngressReconciler) deleteOwnedCertificate(ctx context.Context, certArn, owner string) (bool, error) {
+	owned, err := r.ownsCertificate(ctx, certArn)
+	if err != nil || !owned {
+		return false, err
 	}
-
-	req := &acm.RequestCertificateInput{
-		DomainName:       aws.String(domain),
-		ValidationMethod: acmtypes.ValidationMethodDns,
-		Tags: []acmtypes.Tag{
-			{Key: aws.String("ManagedBy"), Value: aws.String("acm-manager")},
-		},
+	if held, err := r.certificateStillHeld(ctx, certArn, owner); err != nil || held {
+		return true, err
 	}
+	_, err = r.acm(ctx).DeleteCertificate(ctx, &acm.DeleteCertificateInput{
+		CertificateArn: aws.String(certArn),
+	})
+	return true, err
+}
 
-	if cfg.ZoneID == "" {
-		_, err := r.findMatchingHostedZone(ctx, domain)
-		if err != nil {
-			return "", fmt.Errorf("failed to find matching Route53 zone for domain %s: %w", domain, err)
+func (r *IngressReconciler) ensureCertificate(ctx context.Context, ingress *networkingv1.Ingress, domain string, cfg IngressConfig, dnsProvider DNSProvider) (certs.EnsureResult, error) {
+	certDomain := certificateDomain(domain, cfg)
+
+	result, err := r.certManager(ctx).Ensure(ctx, certs.EnsureRequest{
+		Domain:                  certDomain,
+		SubjectAlternativeNames: cfg.SANs,
+		ReuseExisting:           cfg.ReuseExisting,
+		DNS:                     dnsProvider,
+		ZoneID:                  cfg.ZoneID,
+		KeyAlgorithm:            cfg.KeyAlgorithm,
+		DisableCTLogging:        cfg.DisableCTLogging,
+		Tags:                    cfg.Tags,
+		OnProgress:              r.validationProgressRecorder(ingress),
+		OnLagging:               r.validationLagRecorder(ingress),
+		LagThreshold:            r.settings().ValidationLagThreshold,
+		OnValidationExpired:     r.validationExpiredRecorder(ingress),
+		IdempotencyToken:        requestToken(ingress, certDomain, cfg.SANs),
+		Resume:                  r.resumeAfterZoneMappingChange(ctx, savedValidationState(ctx, ingress)),
+		OnState:                 r.validationStateSaver(ctx, ingress),
+		NoWait:                  r.RequeuePendingValidation,
+		Wanted:                  r.ingressWanted(ingress),
+	})
+	if err != nil && validationFinished(err) {
+		if clearErr := r.clearValidationState(ctx, ingress); clearErr != nil {
+			log.FromContext(ctx).Error(clearErr, "failed to clear validation state", "arn", result.CertificateArn)
 		}
 	}
+	return result, err
+}
 
+// certificateDomain returns the primary name of the certificate requested for domain
+func certificateDomain(domain string, cfg IngressConfig) string {
 	if cfg.Wildcard {
-		req.DomainName = aws.String("*." + domain)
-	}
-
-	if len(cfg.SANs) > 0 {
-		req.SubjectAlternativeNames = cfg.SANs
+		return "*." + domain
 	}
+	return domain
+}
 
-	resp, err := r.ACMClient.RequestCertificate(ctx, req)
-	if err != nil {
-		return "", err
+// certManager returns the certificate manager backing ensureCertificate, using the ACM client
+// of the role and region in ctx
+func (r *IngressReconciler) certManager(ctx context.Context) *certs.Manager {
+	m := certs.NewManager(r.acm(ctx), r.managedByValue())
+	m.ReuseStatuses = r.settings().ReuseCertificateStatuses
+	m.RequiredTags = r.RequiredTags
+	m.ValidationTimeout = validationTimeout
+	m.PollInterval = validationPollInterval
+	m.UseWaiter = r.UseACMWaiter
+	m.Waiter = r.ACMWaiter
+	if index := r.certificateIndex(ctx); index != nil {
+		m.Index = index
 	}
+	return m
+}
 
-	certArn := aws.ToString(resp.CertificateArn)
+// awsClients returns the reconciler's AWS client cache, creating it exactly once
+func (r *IngressReconciler) awsClients() *awsClientCache {
+	r.clientsOnce.Do(func() {
+		r.clients = newAWSClientCache(r.AWSMaxAttempts, r.AWSMaxBackoff, r.UseFIPSEndpoints, r.Audit, r.ZoneFilterTags)
+		r.clients.negativeZoneTTL = r.NegativeZoneCacheTTL
+	})
+	return r.clients
+}
 
-	// Wait for ResourceRecord to be ready
-	resourceReady := false
-	for i := 0; i < 10; i++ {
-		describe, err := r.ACMClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
-			CertificateArn: aws.String(certArn),
-		})
-		if err != nil {
-			return certArn, err
-		}
-		options := describe.Certificate.DomainValidationOptions
-		if len(options) > 0 && options[0].ResourceRecord != nil {
-			resourceReady = true
-			break
-		}
-		log.FromContext(ctx).Info("Waiting for ResourceRecord to be available", "attempt", i+1)
-		time.Sleep(5 * time.Second)
+func (r *IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The clients are built on first use, so missing credentials delay reconciles instead of
+	// failing the manager
+	if r.ACMClient == nil {
+		r.ACMClient = &lazyACM{cache: r.awsClients()}
+		r.lazyClients = true
 	}
-
-	if !resourceReady {
-		return certArn, fmt.Errorf("resource record not available yet for domain: %s", domain)
+	if r.DNSProvider == nil && !r.NoRoute53 {
+		provider := certs.NewRoute53Provider(&lazyRoute53{cache: r.awsClients()})
+		provider.ZoneTags = r.ZoneFilterTags
+		provider.NegativeCacheTTL = r.NegativeZoneCacheTTL
+		observeZoneCache(provider)
+		r.DNSProvider = provider
+		r.lazyClients = true
 	}
 
-	if err := r.createRoute53ValidationRecords(ctx, certArn, cfg.ZoneID); err != nil {
-		logger := log.FromContext(ctx)
-		logger.Error(err, "failed to create DNS validation records")
-		return certArn, err
+	if r.Audit != nil {
+		r.ACMClient = r.Audit.ACM(r.ACMClient)
 	}
-
-	timeout := 10 * time.Minute
-	interval := 15 * time.Second
-	deadline := time.Now().Add(timeout)
-
-	attempts := 0
-
-	for {
-		if time.Now().After(deadline) {
-			return certArn, fmt.Errorf("certificate validation timed out: %s", certArn)
-		}
-
-		describe, err := r.ACMClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
-			CertificateArn: aws.String(certArn),
-		})
-		if err != nil {
-			return certArn, err
-		}
-
-		status := describe.Certificate.Status
-
-		attempts++
-		if attempts%4 == 0 {
-			logger := log.FromContext(ctx)
-			logger.Info("Waiting for ACM certificate validation", "attempt", attempts, "certArn", certArn)
-		}
-
-		switch status {
-		case acmtypes.CertificateStatusIssued:
-			return certArn, nil
-		case acmtypes.CertificateStatusFailed:
-			return certArn, fmt.Errorf("certificate validation failed: %s", describe.Certificate.FailureReason)
-		default:
-			time.Sleep(interval)
+	if changes := r.awsClients().changes; changes != nil {
+		if err := mgr.Add(changes); err != nil {
+			return err
 		}
 	}
-}
-
-func (r *IngressReconciler) createRoute53ValidationRecords(ctx context.Context, certArn string, zoneID string) error {
-	describe, err := r.ACMClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
-		CertificateArn: aws.String(certArn),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to describe certificate: %w", err)
-	}
-
-	seen := make(map[string]bool)
-	for _, option := range describe.Certificate.DomainValidationOptions {
-		logger := log.FromContext(ctx)
-		logger.Info("Processing domain validation option", "domain", aws.ToString(option.DomainName))
 
-		record := option.ResourceRecord
-		logger.Info("ACM ResourceRecord", "record", record)
-		if record == nil {
-			logger.Info("ResourceRecord is nil, skipping and requeuing")
-			return fmt.Errorf("resource record not available yet for domain: %s", aws.ToString(option.DomainName))
+	// The index covers the default role and region; it reads through the client it wraps
+	if r.CertificateIndexInterval > 0 {
+		r.certIndex = &CertificateIndex{
+			ACMClient:      r.ACMClient,
+			ManagedByValue: r.managedByValue(),
+			RequiredTags:   r.RequiredTags,
+			Interval:       r.CertificateIndexInterval,
 		}
-
-		key := fmt.Sprintf("%s|%s|%s", aws.ToString(record.Name), record.Type, aws.ToString(record.Value))
-		if seen[key] {
-			continue
+		r.ACMClient = &indexedACM{ACMAPI: r.ACMClient, index: r.certIndex}
+		if err := mgr.Add(r.certIndex); err != nil {
+			return err
 		}
-		seen[key] = true
+	}
 
-		hostedZoneID := zoneID
-		if hostedZoneID == "" {
-			guessedZoneID, err := r.findMatchingHostedZone(ctx, aws.ToString(option.DomainName))
-			if err != nil {
-				return fmt.Errorf("failed to infer zone: %w", err)
-			}
-			hostedZoneID = guessedZoneID
-		}
-
-		logger.Info("Creating Route 53 validation record", "zone", hostedZoneID, "name", aws.ToString(record.Name), "type", record.Type, "value", aws.ToString(record.Value))
-
-		change := &route53.ChangeResourceRecordSetsInput{
-			HostedZoneId: aws.String(hostedZoneID),
-			ChangeBatch: &route53types.ChangeBatch{
-				Changes: []route53types.Change{
-					{
-						Action: route53types.ChangeActionUpsert,
-						ResourceRecordSet: &route53types.ResourceRecordSet{
-							Name: record.Name,
-							Type: route53types.RRType(record.Type),
-							TTL:  aws.Int64(300),
-							ResourceRecords: []route53types.ResourceRecord{
-								{Value: record.Value},
-							},
-						},
-					},
-				},
-			},
-		}
-
-		_, err := r.Route53Client.ChangeResourceRecordSets(ctx, change)
-		if err != nil {
-			return fmt.Errorf("failed to create DNS validation record: %w", err)
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("acm-manager")
+		if r.EventDedupWindow > 0 {
+			r.Recorder = NewDedupRecorder(r.Recorder, r.EventDedupWindow)
 		}
 	}
-
-	return nil
-}
-
-func (r *IngressReconciler) findMatchingHostedZone(ctx context.Context, domain string) (string, error) {
-	list, err := r.Route53Client.ListHostedZones(ctx, &route53.ListHostedZonesInput{})
-	if err != nil {
-		return "", err
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
 	}
+	r.resync = make(chan event.GenericEvent)
+	r.elected = mgr.Elected()
 
-	var matchedZoneID string
-	var longestMatchLen int
-
-	for _, zone := range list.HostedZones {
-		// Skip private zones
-		if zone.Config != nil && zone.Config.PrivateZone {
-			continue
-		}
-
-		zoneName := strings.TrimSuffix(aws.ToString(zone.Name), ".")
-		if strings.HasSuffix(domain, zoneName) && len(zoneName) > longestMatchLen {
-			matchedZoneID = aws.ToString(zone.Id)
-			longestMatchLen = len(zoneName)
+	// The sweep covers the whole account, so only the first shard runs it
+	if r.PendingCertificateMaxAge > 0 && r.ShardIndex == 0 {
+		if err := mgr.Add(&PendingCertificateGC{
+			ACMClient:      r.observeACMClient(r.maintenanceACMClient(r.ACMClient)),
+			ManagedByValue: r.managedByValue(),
+			RequiredTags:   r.RequiredTags,
+			MaxAge:         r.PendingCertificateMaxAge,
+			Interval:       r.GCInterval,
+		}); err != nil {
+			return err
 		}
 	}
 
-	if matchedZoneID == "" {
-		return "", fmt.Errorf("no matching public hosted zone found for domain: %s", domain)
+	if r.InventoryConfigMap.Name != "" {
+		if err := mgr.Add(&inventoryReporter{
+			r:         r,
+			configMap: r.inventoryConfigMap(),
+			interval:  r.InventoryInterval,
+		}); err != nil {
+			return err
+		}
 	}
 
-	return strings.TrimPrefix(matchedZoneID, "/hostedzone/"), nil
-}
-
-func (r *IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
-	if err != nil {
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &networkingv1.Ingress{}, importSecretIndex, indexImportSecret); err != nil {
 		return err
 	}
 
-	r.ACMClient = acm.NewFromConfig(cfg)
-	r.Route53Client = route53.NewFromConfig(cfg)
-
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&networkingv1.Ingress{}).
-		Complete(r)
+	// With the priority queue, Ingresses whose certificate is inside its renewal margin are
+	// reconciled before the rest of the fleet
+	usePriorityQueue := r.PrioritizeExpiring
+	// The expiring-first watch enqueues the same Ingresses as the primary one, so status
+	// annotation writes must not requeue through either
+	ingressPredicates := builder.WithPredicates(ignoreStatusAnnotationUpdates(), predicate.NewPredicateFuncs(r.inShard))
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			UsePriorityQueue: &usePriorityQueue,
+			// The in-memory retries back off like the persisted ones, see retryBackoffDelay
+			RateLimiter: workqueue.NewTypedItemExponentialFailureRateLimiter[ctrl.Request](retryBackoffBase, retryBackoffMax),
+		}).
+		For(&networkingv1.Ingress{}, ingressPredicates).
+		Watches(&networkingv1.Ingress{}, expiringFirstHandler{r: r}, ingressPredicates).
+		Watches(&networkingv1.Ingress{}, r.groupMembershipHandler()).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.enqueueManagedIngressesForPolicy),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.isPolicyConfigMap))).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.enqueueManagedIngressesForRoleMap),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.isNamespaceRoleMap))).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.enqueueIngressesForNamesConfigMap),
+			builder.WithPredicates(configMapDataChanged())).
+		Watches(&networkingv1.IngressClass{}, handler.EnqueueRequestsFromMapFunc(r.enqueueIngressesForIngressClass)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.enqueueIngressesForIngressClassParameters)).
+		// Only Secret metadata is cached; their data is read uncached when an import is due
+		WatchesMetadata(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.enqueueIngressesForSecret)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.enqueueIngressesForNamespace),
+			builder.WithPredicates(predicate.AnnotationChangedPredicate{})).
+		WatchesRawSource(source.Channel(r.resync, expiringFirstHandler{r: r, next: &handler.EnqueueRequestForObject{}}))
+	if r.DomainZoneMappings {
+		bldr = bldr.Watches(&acmv1alpha1.DomainZoneMapping{}, handler.EnqueueRequestsFromMapFunc(r.enqueueIngressesForZoneMapping),
+			builder.WithPredicates(zoneMappingChanged()))
+	}
+	return bldr.Complete(r)
 }
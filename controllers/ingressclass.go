@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// regionAnnotation on an IngressClass names the AWS region of its Ingresses' certificates
+	regionAnnotation = "acm.tedens.dev/region"
+	// legacyIngressClassAnnotation selects the class of Ingresses without spec.ingressClassName
+	legacyIngressClassAnnotation = "kubernetes.io/ingress.class"
+	// defaultIngressClassAnnotation marks the class of Ingresses naming none
+	defaultIngressClassAnnotation = "ingressclass.kubernetes.io/is-default-class"
+)
+
+// ingressClassName returns the IngressClass the Ingress names, or "" when it names none
+func ingressClassName(ingress *networkingv1.Ingress) string {
+	if ingress.Spec.IngressClassName != nil {
+		return *ingress.Spec.IngressClassName
+	}
+	return ingress.Annotations[legacyIngressClassAnnotation]
+}
+
+// ingressClassParametersConfigMap returns the ConfigMap the class' spec.parameters reference,
+// or ok false when they reference anything else
+func ingressClassParametersConfigMap(class *networkingv1.IngressClass) (key types.NamespacedName, ok bool) {
+	params := class.Spec.Parameters
+	if params == nil || (params.APIGroup != nil && *params.APIGroup != "") || params.Kind != "ConfigMap" ||
+		params.Scope == nil || *params.Scope != networkingv1.IngressClassParametersReferenceScopeNamespace || params.Namespace == nil {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: *params.Namespace, Name: params.Name}, true
+}
+
+// ingressClassFor returns the IngressClass of the Ingress, the one marked default when the
+// Ingress names none, or nil when there is no such class
+func (r *IngressReconciler) ingressClassFor(ctx context.Context, ingress *networkingv1.Ingress) (*networkingv1.IngressClass, error) {
+	if name := ingressClassName(ingress); name != "" {
+		var class networkingv1.IngressClass
+		if err := r.Get(ctx, types.NamespacedName{Name: name}, &class); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return &class, nil
+	}
+
+	var classes networkingv1.IngressClassList
+	if err := r.List(ctx, &classes); err != nil {
+		return nil, err
+	}
+	for i := range classes.Items {
+		if classes.Items[i].Annotations[defaultIngressClassAnnotation] == "true" {
+			return &classes.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ingressClassTarget returns the role and region the Ingress' IngressClass assigns: the
+// role-arn and region keys of a ConfigMap its spec.parameters reference, overridden by the
+// class' own acm.tedens.dev/role-arn and acm.tedens.dev/region annotations
+func (r *IngressReconciler) ingressClassTarget(ctx context.Context, ingress *networkingv1.Ingress) (awsTarget, error) {
+	class, err := r.ingressClassFor(ctx, ingress)
+	if err != nil || class == nil {
+		return awsTarget{}, err
+	}
+
+	var target awsTarget
+	if key, ok := ingressClassParametersConfigMap(class); ok {
+		var cm corev1.ConfigMap
+		if err := r.Get(ctx, key, &cm); err != nil && !apierrors.IsNotFound(err) {
+			return awsTarget{}, err
+		}
+		target = awsTarget{RoleARN: strings.TrimSpace(cm.Data["role-arn"]), Region: strings.TrimSpace(cm.Data["region"])}
+	}
+	if value := strings.TrimSpace(class.Annotations[roleArnAnnotation]); value != "" {
+		target.RoleARN = value
+	}
+	if value := strings.TrimSpace(class.Annotations[regionAnnotation]); value != "" {
+		target.Region = value
+	}
+	if err := validateRoleARN(target.RoleARN); err != nil {
+		return awsTarget{}, fmt.Errorf("IngressClass %s: %w", class.Name, err)
+	}
+	return target, nil
+}
+
+// usesIngressClass reports whether the Ingress belongs to class
+func usesIngressClass(ingress *networkingv1.Ingress, class client.Object) bool {
+	name := ingressClassName(ingress)
+	if name == "" {
+		return class.GetAnnotations()[defaultIngressClassAnnotation] == "true"
+	}
+	return name == class.GetName()
+}
+
+// ingressClassRequests returns a request for every Ingress of one of classes
+func (r *IngressReconciler) ingressClassRequests(ctx context.Context, classes ...client.Object) []reconcile.Request {
+	var ingresses networkingv1.IngressList
+	if err := r.List(ctx, &ingresses); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ingresses for IngressClass change")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ing := range ingresses.Items {
+		for _, class := range classes {
+			if usesIngressClass(&ing, class) {
+				requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// enqueueIngressesForIngressClass requeues the Ingresses of an IngressClass that changed, so
+// the role and region it assigns, and whether the ALB controller serves it, stay current.
+// Updates map both the old and new class, so Ingresses of a class losing its default mark are
+// requeued too.
+func (r *IngressReconciler) enqueueIngressesForIngressClass(ctx context.Context, obj client.Object) []reconcile.Request {
+	r.classControllers.reset()
+	return r.ingressClassRequests(ctx, obj)
+}
+
+// enqueueIngressesForIngressClassParameters requeues the Ingresses of every IngressClass whose
+// spec.parameters reference the ConfigMap that changed
+func (r *IngressReconciler) enqueueIngressesForIngressClassParameters(ctx context.Context, obj client.Object) []reconcile.Request {
+	var classes networkingv1.IngressClassList
+	if err := r.List(ctx, &classes); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list IngressClasses for ConfigMap change")
+		return nil
+	}
+
+	var referencing []client.Object
+	for i := range classes.Items {
+		key, ok := ingressClassParametersConfigMap(&classes.Items[i])
+		if ok && key.Namespace == obj.GetNamespace() && key.Name == obj.GetName() {
+			referencing = append(referencing, &classes.Items[i])
+		}
+	}
+	if len(referencing) == 0 {
+		return nil
+	}
+	return r.ingressClassRequests(ctx, referencing...)
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// newIngressClass returns an IngressClass whose parameters reference the ConfigMap
+// acm-manager/<params> when params is set
+func newIngressClass(name, params string, annotations map[string]string) *networkingv1.IngressClass {
+	class := &networkingv1.IngressClass{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+	if params != "" {
+		class.Spec.Parameters = &networkingv1.IngressClassParametersReference{
+			Kind:      "ConfigMap",
+			Name:      params,
+			Scope:     aws.String(networkingv1.IngressClassParametersReferenceScopeNamespace),
+			Namespace: aws.String("acm-manager"),
+		}
+	}
+	return class
+}
+
+func TestIngressClassSelectsClients(t *testing.T) {
+	ctx := context.Background()
+	const classRole = "arn:aws:iam::111122223333:role/alb-prod"
+	params := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "acm-manager", Name: "alb-prod"},
+		Data:       map[string]string{"role-arn": classRole, "region": "us-west-2"},
+	}
+	prod := newIngressClass("alb-prod", "alb-prod", nil)
+	eu := newIngressClass("alb-eu", "alb-prod", map[string]string{regionAnnotation: "eu-central-1"})
+	web := newManagedIngress("web", "app.example.com", nil)
+	web.Spec.IngressClassName = aws.String("alb-prod")
+	legacy := newManagedIngress("legacy", "legacy.example.com", map[string]string{legacyIngressClassAnnotation: "alb-eu"})
+
+	defaultACM := newFakeACM()
+	r := newTestReconciler(t, defaultACM, newFakeRoute53("example.com"), params, prod, eu, web, legacy)
+	built := useTargetClients(r, "example.com")
+
+	for _, ingress := range []*networkingv1.Ingress{web, legacy} {
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+			t.Fatalf("Reconcile %s: %v", ingress.Name, err)
+		}
+	}
+	prodACM := built[awsTarget{RoleARN: classRole, Region: "us-west-2"}]
+	if prodACM == nil || len(prodACM.requests) != 1 || aws.ToString(prodACM.requests[0].DomainName) != "app.example.com" {
+		t.Fatalf("the class parameters should select the role and region, built %v", built)
+	}
+	euACM := built[awsTarget{RoleARN: classRole, Region: "eu-central-1"}]
+	if euACM == nil || len(euACM.requests) != 1 || aws.ToString(euACM.requests[0].DomainName) != "legacy.example.com" {
+		t.Fatalf("the class annotation should replace the parameters' region, built %v", built)
+	}
+	if len(defaultACM.requests) != 0 {
+		t.Fatalf("no certificate should be requested with the default clients, got %d", len(defaultACM.requests))
+	}
+}
+
+func TestIngressClassTargetDefaultClass(t *testing.T) {
+	ctx := context.Background()
+	class := newIngressClass("alb", "", map[string]string{defaultIngressClassAnnotation: "true", regionAnnotation: "eu-west-1"})
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53(), class, ingress)
+
+	if got, err := r.ingressClassTarget(ctx, ingress); err != nil || got != (awsTarget{Region: "eu-west-1"}) {
+		t.Fatalf("an Ingress naming no class should use the default class, got %+v, %v", got, err)
+	}
+	ingress.Spec.IngressClassName = aws.String("missing")
+	if got, err := r.ingressClassTarget(ctx, ingress); err != nil || got != (awsTarget{}) {
+		t.Fatalf("a missing class should assign nothing, got %+v, %v", got, err)
+	}
+
+	invalid := newIngressClass("invalid", "", map[string]string{roleArnAnnotation: "acm-manager"})
+	ingress.Spec.IngressClassName = aws.String("invalid")
+	r = newTestReconciler(t, newFakeACM(), newFakeRoute53(), invalid, ingress)
+	if _, err := r.ingressClassTarget(ctx, ingress); err == nil {
+		t.Fatal("expected a role that is not an IAM role ARN to be rejected")
+	}
+}
+
+func TestIngressClassChangeEnqueuesIngresses(t *testing.T) {
+	ctx := context.Background()
+	params := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "acm-manager", Name: "alb-params"}}
+	class := newIngressClass("alb", "alb-params", nil)
+	other := newIngressClass("nginx", "", nil)
+	web := newManagedIngress("web", "app.example.com", nil)
+	web.Spec.IngressClassName = aws.String("alb")
+	legacy := newManagedIngress("legacy", "legacy.example.com", map[string]string{legacyIngressClassAnnotation: "alb"})
+	unclassed := newManagedIngress("unclassed", "unclassed.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53(), params, class, other, web, legacy, unclassed)
+
+	if requests := r.enqueueIngressesForIngressClass(ctx, class); len(requests) != 2 {
+		t.Fatalf("expected the class' two Ingresses to be enqueued, got %v", requests)
+	}
+	if requests := r.enqueueIngressesForIngressClassParameters(ctx, params); len(requests) != 2 {
+		t.Fatalf("expected a parameters change to enqueue the class' Ingresses, got %v", requests)
+	}
+	if requests := r.enqueueIngressesForIngressClass(ctx, other); len(requests) != 0 {
+		t.Fatalf("other classes should not enqueue, got %v", requests)
+	}
+	unrelated := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "acm-manager", Name: "other"}}
+	if requests := r.enqueueIngressesForIngressClassParameters(ctx, unrelated); len(requests) != 0 {
+		t.Fatalf("unreferenced ConfigMaps should not enqueue, got %v", requests)
+	}
+
+	other.Annotations = map[string]string{defaultIngressClassAnnotation: "true"}
+	if requests := r.enqueueIngressesForIngressClass(ctx, other); len(requests) != 1 || requests[0].Name != "unclassed" {
+		t.Fatalf("the default class should enqueue Ingresses naming none, got %v", requests)
+	}
+}
+
+func TestNonALBIngressClassIsSkipped(t *testing.T) {
+	ctx := context.Background()
+	nginx := newIngressClass("nginx", "", nil)
+	nginx.Spec.Controller = "k8s.io/ingress-nginx"
+	alb := newIngressClass("alb", "", nil)
+	alb.Spec.Controller = DefaultALBIngressController
+	web := newManagedIngress("web", "web.example.com", nil)
+	web.Spec.IngressClassName = aws.String("nginx")
+	forced := newManagedIngress("forced", "forced.example.com", map[string]string{forceNonALBAnnotation: "true"})
+	forced.Spec.IngressClassName = aws.String("nginx")
+	api := newManagedIngress("api", "api.example.com", map[string]string{legacyIngressClassAnnotation: "alb"})
+	fakeACM := newFakeACM()
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), nginx, alb, web, forced, api)
+
+	if _, err := r.Reconcile(ctx, requestFor(web)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 0 || getIngress(t, r, web).Annotations[albCertificateArnAnnotation] != "" {
+		t.Fatalf("an nginx Ingress should get no certificate, got %d requests", len(fakeACM.requests))
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+ReasonNotALBIngress) {
+		t.Fatalf("expected one %s warning, got %v", ReasonNotALBIngress, events)
+	}
+
+	for _, ingress := range []*networkingv1.Ingress{forced, api} {
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+			t.Fatalf("Reconcile %s: %v", ingress.Name, err)
+		}
+		if certificateArnOf(t, r, ingress) == "" {
+			t.Errorf("expected a certificate for %s", ingress.Name)
+		}
+	}
+
+	// The class' controller is cached until an IngressClass changes
+	if controller, cached := r.classControllers.get("nginx"); !cached || controller != "k8s.io/ingress-nginx" {
+		t.Fatalf("expected the nginx controller cached, got %q, %v", controller, cached)
+	}
+	r.enqueueIngressesForIngressClass(ctx, nginx)
+	if _, cached := r.classControllers.get("nginx"); cached {
+		t.Fatal("an IngressClass change should drop the cache")
+	}
+
+	// Other controllers can be listed as reading the ALB annotations
+	r.ALBIngressControllers = []string{DefaultALBIngressController, "k8s.io/ingress-nginx"}
+	if _, err := r.Reconcile(ctx, requestFor(web)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if certificateArnOf(t, r, web) == "" {
+		t.Error("expected a certificate once the controller is listed")
+	}
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultInventoryInterval is how often the inventory ConfigMap is brought up to date
+const DefaultInventoryInterval = 15 * time.Minute
+
+// inventoryHeartbeat is how old the inventory's generatedAt may get before it is rewritten
+// even though nothing changed, so consumers can tell a stale report from a quiet fleet
+const inventoryHeartbeat = time.Hour
+
+// ConfigMap keys of the inventory report
+const (
+	inventoryKey            = "inventory.json"
+	inventoryGeneratedAtKey = "generatedAt"
+)
+
+// inventoryEntry is one managed Ingress in the inventory report
+type inventoryEntry struct {
+	Namespace      string   `json:"namespace"`
+	Name           string   `json:"name"`
+	Domains        []string `json:"domains"`
+	CertificateArn string   `json:"certificateArn,omitempty"`
+	Status         string   `json:"status"`
+	NotAfter       string   `json:"notAfter,omitempty"`
+	LastError      string   `json:"lastError,omitempty"`
+	LastReconciled string   `json:"lastReconciled,omitempty"`
+}
+
+// reconcileTimes remembers when this replica last reconciled each Ingress
+type reconcileTimes struct {
+	mu    sync.Mutex
+	times map[types.NamespacedName]time.Time
+}
+
+func (t *reconcileTimes) set(key types.NamespacedName, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.times == nil {
+		t.times = map[types.NamespacedName]time.Time{}
+	}
+	t.times[key] = at
+}
+
+func (t *reconcileTimes) forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.times, key)
+}
+
+func (t *reconcileTimes) get(key types.NamespacedName) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	at, ok := t.times[key]
+	return at, ok
+}
+
+// inventoryStatus summarizes the state of a managed Ingress from its annotations
+func inventoryStatus(ingress *networkingv1.Ingress, certArn string) string {
+	_, quarantined := ingress.Annotations[quarantinedAnnotation]
+	_, validating := ingress.Annotations[validationStateAnnotation]
+	switch {
+	case isPaused(ingress):
+		return "Paused"
+	case quarantined:
+		return "Quarantined"
+	case ingress.Annotations[lastErrorAnnotation] != "":
+		return "Failing"
+	case validating:
+		return "PendingValidation"
+	case certArn != "":
+		return "Attached"
+	}
+	return "Pending"
+}
+
+// inventoryDomains returns the names covered by the Ingress' certificates, or its hosts
+// until a certificate is attached
+func inventoryDomains(ingress *networkingv1.Ingress) []string {
+	if covered := ingress.Annotations[coveredNamesAnnotation]; covered != "" {
+		return strings.Split(covered, ",")
+	}
+	var hosts []string
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" && !slices.Contains(hosts, rule.Host) {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	return hosts
+}
+
+// inventoryReporter periodically writes a summary of every managed Ingress of this replica's
+// shard to a ConfigMap, for tooling that can read the cluster but not scrape metrics
+type inventoryReporter struct {
+	r         *IngressReconciler
+	configMap types.NamespacedName
+	interval  time.Duration
+}
+
+// NeedLeaderElection makes the report run only on the elected leader
+func (p *inventoryReporter) NeedLeaderElection() bool {
+	return true
+}
+
+// Start writes the report every interval until ctx is cancelled
+func (p *inventoryReporter) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("inventory")
+
+	interval := p.interval
+	if interval <= 0 {
+		interval = DefaultInventoryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Report(ctx); err != nil {
+			logger.Error(err, "failed to write the inventory report", "configMap", p.configMap)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// entries returns the inventory of the managed Ingresses of this replica's shard, sorted by
+// namespace and name
+func (p *inventoryReporter) entries(ctx context.Context) ([]inventoryEntry, error) {
+	policy, err := p.r.loadPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var list networkingv1.IngressList
+	if err := p.r.List(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	entries := []inventoryEntry{}
+	for i := range list.Items {
+		ingress := &list.Items[i]
+		if !p.r.inShard(ingress) {
+			continue
+		}
+		cfg, err := p.r.ingressConfig(ctx, ingress, policy)
+		if err != nil {
+			return nil, err
+		}
+		if !cfg.Managed {
+			continue
+		}
+		certArn := ingress.Annotations[certificateArnKey(cfg)]
+		entry := inventoryEntry{
+			Namespace:      ingress.Namespace,
+			Name:           ingress.Name,
+			Domains:        inventoryDomains(ingress),
+			CertificateArn: certArn,
+			Status:         inventoryStatus(ingress, certArn),
+			NotAfter:       ingress.Annotations[notAfterAnnotation],
+			LastError:      ingress.Annotations[lastErrorAnnotation],
+		}
+		if at, ok := p.r.lastReconciled.get(client.ObjectKeyFromObject(ingress)); ok {
+			entry.LastReconciled = at.UTC().Format(time.RFC3339)
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// Report writes the inventory to the ConfigMap, creating it when missing. The write is skipped
+// when the inventory is unchanged and generatedAt is younger than inventoryHeartbeat.
+func (p *inventoryReporter) Report(ctx context.Context) error {
+	entries, err := p.entries(ctx)
+	if err != nil {
+		return err
+	}
+	report, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	now := p.r.clock().UTC()
+
+	var cm corev1.ConfigMap
+	err = p.r.Get(ctx, p.configMap, &cm)
+	if apierrors.IsNotFound(err) {
+		cm = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: p.configMap.Namespace, Name: p.configMap.Name},
+			Data:       map[string]string{inventoryKey: string(report), inventoryGeneratedAtKey: now.Format(time.RFC3339)},
+		}
+		if err := p.r.Create(ctx, &cm); err != nil {
+			return fmt.Errorf("failed to create inventory ConfigMap: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	generatedAt, parseErr := time.Parse(time.RFC3339, cm.Data[inventoryGeneratedAtKey])
+	if sameInventory(cm.Data[inventoryKey], entries) && parseErr == nil && now.Sub(generatedAt) < inventoryHeartbeat {
+		return nil
+	}
+	patch := client.MergeFrom(cm.DeepCopy())
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[inventoryKey] = string(report)
+	cm.Data[inventoryGeneratedAtKey] = now.Format(time.RFC3339)
+	if err := p.r.Patch(ctx, &cm, patch); err != nil {
+		return fmt.Errorf("failed to update inventory ConfigMap: %w", err)
+	}
+	return nil
+}
+
+// inventoryConfigMap returns InventoryConfigMap, suffixed with the shard index when
+// Ingresses are sharded so every shard's leader reports its own Ingresses
+func (r *IngressReconciler) inventoryConfigMap() types.NamespacedName {
+	key := r.InventoryConfigMap
+	if r.ShardCount > 1 {
+		key.Name = fmt.Sprintf("%s-shard-%d", key.Name, r.ShardIndex)
+	}
+	return key
+}
+
+// sameInventory reports whether the written report lists the same entries, leaving out
+// lastReconciled, which changes on every reconcile and alone does not warrant a write
+func sameInventory(written string, entries []inventoryEntry) bool {
+	var previous []inventoryEntry
+	if err := json.Unmarshal([]byte(written), &previous); err != nil || len(previous) != len(entries) {
+		return false
+	}
+	for i := range entries {
+		a, b := previous[i], entries[i]
+		a.LastReconciled, b.LastReconciled = "", ""
+		aJSON, errA := json.Marshal(a)
+		bJSON, errB := json.Marshal(b)
+		if errA != nil || errB != nil || string(aJSON) != string(bJSON) {
+			return false
+		}
+	}
+	return true
+}
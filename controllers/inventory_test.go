@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func readInventory(t *testing.T, r *IngressReconciler, key types.NamespacedName) (*corev1.ConfigMap, []inventoryEntry) {
+	t.Helper()
+	var cm corev1.ConfigMap
+	if err := r.Get(context.Background(), key, &cm); err != nil {
+		t.Fatalf("get inventory ConfigMap: %v", err)
+	}
+	var entries []inventoryEntry
+	if err := json.Unmarshal([]byte(cm.Data[inventoryKey]), &entries); err != nil {
+		t.Fatalf("decode inventory: %v", err)
+	}
+	return &cm, entries
+}
+
+func TestInventoryReportsManagedIngresses(t *testing.T) {
+	attached := newManagedIngress("attached", "app.example.com", map[string]string{
+		"alb.ingress.kubernetes.io/certificate-arn": "arn:aws:acm:us-east-1:123:certificate/app",
+		coveredNamesAnnotation:                      "app.example.com,www.example.com",
+		notAfterAnnotation:                          "2026-12-01T00:00:00Z",
+	})
+	failing := newManagedIngress("failing", "broken.example.com", map[string]string{
+		lastErrorAnnotation: "no hosted zone",
+	})
+	unmanaged := newManagedIngress("unmanaged", "other.example.com", nil)
+	delete(unmanaged.Annotations, "acm.tedens.dev/managed")
+
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53(), attached, failing, unmanaged)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	r.lastReconciled.set(requestFor(attached).NamespacedName, now.Add(-time.Minute))
+
+	key := types.NamespacedName{Namespace: "kube-system", Name: "acm-inventory"}
+	reporter := &inventoryReporter{r: r, configMap: key}
+	if err := reporter.Report(context.Background()); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	cm, entries := readInventory(t, r, key)
+	if cm.Data[inventoryGeneratedAtKey] != "2026-10-01T12:00:00Z" {
+		t.Errorf("generatedAt = %q", cm.Data[inventoryGeneratedAtKey])
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	got := entries[0]
+	if got.Name != "attached" || got.Status != "Attached" || got.CertificateArn != "arn:aws:acm:us-east-1:123:certificate/app" ||
+		len(got.Domains) != 2 || got.NotAfter != "2026-12-01T00:00:00Z" || got.LastReconciled != "2026-10-01T11:59:00Z" {
+		t.Errorf("unexpected entry %+v", got)
+	}
+	if got := entries[1]; got.Name != "failing" || got.Status != "Failing" || got.LastError != "no hosted zone" ||
+		len(got.Domains) != 1 || got.Domains[0] != "broken.example.com" {
+		t.Errorf("unexpected entry %+v", got)
+	}
+}
+
+func TestInventorySkipsUnchangedWrites(t *testing.T) {
+	ingress := newManagedIngress("app", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53(), ingress)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	key := types.NamespacedName{Namespace: "kube-system", Name: "acm-inventory"}
+	reporter := &inventoryReporter{r: r, configMap: key}
+	if err := reporter.Report(context.Background()); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	first, _ := readInventory(t, r, key)
+
+	now = now.Add(15 * time.Minute)
+	if err := reporter.Report(context.Background()); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if second, _ := readInventory(t, r, key); second.ResourceVersion != first.ResourceVersion {
+		t.Errorf("unchanged inventory was rewritten")
+	}
+
+	// A reconcile alone does not rewrite it
+	r.lastReconciled.set(types.NamespacedName{Namespace: ingress.Namespace, Name: ingress.Name}, now)
+	if err := reporter.Report(context.Background()); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if second, _ := readInventory(t, r, key); second.ResourceVersion != first.ResourceVersion {
+		t.Errorf("inventory was rewritten for a new lastReconciled only")
+	}
+
+	current := getIngress(t, r, ingress)
+	current.Annotations[lastErrorAnnotation] = "throttled"
+	if err := r.Update(context.Background(), current); err != nil {
+		t.Fatalf("update ingress: %v", err)
+	}
+	if err := reporter.Report(context.Background()); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	third, entries := readInventory(t, r, key)
+	if third.ResourceVersion == first.ResourceVersion || entries[0].Status != "Failing" {
+		t.Errorf("changed inventory was not written: %+v", entries)
+	}
+	if third.Data[inventoryGeneratedAtKey] != "2026-10-01T12:15:00Z" {
+		t.Errorf("generatedAt = %q", third.Data[inventoryGeneratedAtKey])
+	}
+}
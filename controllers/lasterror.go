@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/aws/smithy-go"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// lastErrorAnnotation holds the error of the Ingress' most recent failed reconcile, prefixed
+// with the AWS error code when there is one. It is removed after a successful reconcile.
+const lastErrorAnnotation = "acm.tedens.dev/last-error"
+
+// maxLastErrorLength bounds the length of the last-error annotation value
+const maxLastErrorLength = 1024
+
+// statusAnnotations are written by the controller to report progress; changing only them
+// must not trigger another reconcile
+var statusAnnotations = []string{lastErrorAnnotation, validationStateAnnotation, importedCertificateAnnotation, coveredNamesAnnotation, certificateArnAnnotation, retryBackoffAnnotation, quarantinedAnnotation, postIssuanceHookAnnotation, writtenCertificateArnAnnotation, certificateArnConflictAnnotation, notAfterAnnotation, observedPlanAnnotation, managedByPodAnnotation, bookkeepingAnnotation, pendingCertificatesAnnotation}
+
+// formatLastError renders err for the last-error annotation
+func formatLastError(err error) string {
+	msg := err.Error()
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		msg = fmt.Sprintf("%s: %s", apiErr.ErrorCode(), msg)
+	}
+	if len(msg) > maxLastErrorLength {
+		msg = msg[:maxLastErrorLength-3] + "..."
+	}
+	return msg
+}
+
+// recordLastError sets the last-error and retry backoff annotations of the Ingress from err,
+// retried in retryAfter or the default backoff when zero, or removes them when err is nil
+func (r *IngressReconciler) recordLastError(ctx context.Context, key types.NamespacedName, err error, retryAfter time.Duration) {
+	var ingress networkingv1.Ingress
+	if getErr := r.Get(ctx, key, &ingress); getErr != nil {
+		if apierrors.IsNotFound(getErr) {
+			r.quarantined.set(key, false, quarantinedIngresses)
+		} else {
+			log.FromContext(ctx).Error(getErr, "failed to read ingress to record the last error")
+		}
+		return
+	}
+
+	_, exists := ingress.Annotations[lastErrorAnnotation]
+	_, backingOff := ingress.Annotations[retryBackoffAnnotation]
+	if err == nil && !exists && !backingOff {
+		r.quarantined.set(key, false, quarantinedIngresses)
+		return
+	}
+
+	patch := client.MergeFrom(ingress.DeepCopy())
+	if err == nil {
+		delete(ingress.Annotations, lastErrorAnnotation)
+		delete(ingress.Annotations, retryBackoffAnnotation)
+		delete(ingress.Annotations, quarantinedAnnotation)
+	} else {
+		if ingress.Annotations == nil {
+			ingress.Annotations = map[string]string{}
+		}
+		ingress.Annotations[lastErrorAnnotation] = formatLastError(err)
+		// A reconcile cut short by shutdown or a lost lease did not fail on its own
+		if ctx.Err() == nil {
+			r.nextRetryBackoff(ctx, &ingress, retryAfter, err)
+		}
+	}
+	if patchErr := r.patchIngress(ctx, &ingress, patch); patchErr != nil {
+		if !apierrors.IsNotFound(patchErr) {
+			log.FromContext(ctx).Error(patchErr, "failed to record the last error on the ingress")
+		}
+		return
+	}
+	_, quarantined := ingress.Annotations[quarantinedAnnotation]
+	r.quarantined.set(key, quarantined, quarantinedIngresses)
+}
+
+// ignoreStatusAnnotationUpdates drops Ingress updates that only change statusAnnotations
+func ignoreStatusAnnotationUpdates() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldObj, newObj := e.ObjectOld, e.ObjectNew
+			if oldObj == nil || newObj == nil {
+				return true
+			}
+			// Removing the quarantine is how users ask for a retry
+			_, wasQuarantined := oldObj.GetAnnotations()[quarantinedAnnotation]
+			_, quarantined := newObj.GetAnnotations()[quarantinedAnnotation]
+			return wasQuarantined && !quarantined || oldObj.GetGeneration() != newObj.GetGeneration() ||
+				!oldObj.GetDeletionTimestamp().Equal(newObj.GetDeletionTimestamp()) ||
+				!slices.Equal(oldObj.GetFinalizers(), newObj.GetFinalizers()) ||
+				!maps.Equal(oldObj.GetLabels(), newObj.GetLabels()) || statusLBHostnameChanged(oldObj, newObj) ||
+				!maps.Equal(withoutStatusAnnotations(oldObj.GetAnnotations()), withoutStatusAnnotations(newObj.GetAnnotations()))
+		},
+	}
+}
+
+func withoutStatusAnnotations(annotations map[string]string) map[string]string {
+	filtered := maps.Clone(annotations)
+	for _, key := range statusAnnotations {
+		delete(filtered, key)
+	}
+	maps.DeleteFunc(filtered, func(key, _ string) bool { return isReplicaArnAnnotation(key) })
+	return filtered
+}
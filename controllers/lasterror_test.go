@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestLastErrorAnnotation(t *testing.T) {
+	ctx := context.Background()
+	fakeR53 := newFakeRoute53("example.com")
+	fakeR53.err = &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"}
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), fakeR53, ingress)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err == nil {
+		t.Fatal("expected the Route 53 error")
+	}
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if msg := got.Annotations[lastErrorAnnotation]; !strings.HasPrefix(msg, "Throttling: ") || !strings.Contains(msg, "Rate exceeded") {
+		t.Fatalf("last-error = %q, want the AWS code and message", msg)
+	}
+
+	fakeR53.mu.Lock()
+	fakeR53.err = nil
+	fakeR53.mu.Unlock()
+	now = now.Add(retryBackoffDelay(1))
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if _, ok := got.Annotations[lastErrorAnnotation]; ok {
+		t.Fatal("last-error should be cleared after a successful reconcile")
+	}
+	if _, ok := got.Annotations[retryBackoffAnnotation]; ok {
+		t.Fatal("the retry backoff should be cleared after a successful reconcile")
+	}
+}
+
+func TestFormatLastErrorTruncates(t *testing.T) {
+	msg := formatLastError(&smithy.GenericAPIError{Code: "LimitExceededException", Message: strings.Repeat("x", 2*maxLastErrorLength)})
+	if len(msg) != maxLastErrorLength || !strings.HasSuffix(msg, "...") {
+		t.Fatalf("expected a truncated message of %d bytes, got %d", maxLastErrorLength, len(msg))
+	}
+}
+
+func TestIgnoreStatusAnnotationUpdates(t *testing.T) {
+	p := ignoreStatusAnnotationUpdates()
+	old := newManagedIngress("web", "app.example.com", nil)
+
+	withError := old.DeepCopy()
+	withError.Annotations[lastErrorAnnotation] = "Throttling: Rate exceeded"
+	if p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: withError}) {
+		t.Fatal("an update that only sets last-error must not trigger a reconcile")
+	}
+
+	withDomain := withError.DeepCopy()
+	withDomain.Annotations["acm.tedens.dev/domain"] = "other.example.com"
+	if !p.Update(event.UpdateEvent{ObjectOld: withError, ObjectNew: withDomain}) {
+		t.Fatal("a change to a user annotation must trigger a reconcile")
+	}
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// isLeader is 1 while this replica holds the leader lease, and so makes the AWS calls
+var isLeader = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "acm_manager_is_leader",
+	Help: "1 while this replica is the elected leader, 0 otherwise.",
+})
+
+// leaderTransitions counts this replica acquiring and losing leadership
+var leaderTransitions = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "acm_manager_leader_transitions_total",
+	Help: "Times this replica acquired or lost leadership.",
+})
+
+// LeaderStatus reports leadership of this replica through acm_manager_is_leader and logs
+// each handover. It runs on every replica.
+type LeaderStatus struct {
+	// Elected is closed once this replica is the leader, as returned by the manager's Elected
+	Elected <-chan struct{}
+}
+
+// NeedLeaderElection lets the status run on replicas that are not the leader
+func (s *LeaderStatus) NeedLeaderElection() bool {
+	return false
+}
+
+// Start records leadership until ctx is cancelled, which is when the manager stops after
+// losing or releasing the lease
+func (s *LeaderStatus) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("leader-status")
+	isLeader.Set(0)
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-s.Elected:
+	}
+	isLeader.Set(1)
+	leaderTransitions.Inc()
+	logger.Info("Acquired leadership", "at", time.Now().UTC())
+
+	<-ctx.Done()
+	isLeader.Set(0)
+	leaderTransitions.Inc()
+	logger.Info("Lost or released leadership", "at", time.Now().UTC())
+	return nil
+}
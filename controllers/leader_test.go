@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricValue returns the value of the unlabelled gauge or counter collector, or the sample
+// count of a histogram
+func metricValue(t *testing.T, collector prometheus.Collector) float64 {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil || len(families) != 1 || len(families[0].GetMetric()) != 1 {
+		t.Fatalf("Gather: %v, %v", families, err)
+	}
+	metric := families[0].GetMetric()[0]
+	if metric.GetGauge() != nil {
+		return metric.GetGauge().GetValue()
+	}
+	if metric.GetHistogram() != nil {
+		return float64(metric.GetHistogram().GetSampleCount())
+	}
+	return metric.GetCounter().GetValue()
+}
+
+// waitForMetric waits for the collector to reach want
+func waitForMetric(t *testing.T, collector prometheus.Collector, want float64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for metricValue(t, collector) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("metric stayed at %v, want %v", metricValue(t, collector), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLeaderStatusTracksLeadership(t *testing.T) {
+	elected := make(chan struct{})
+	status := &LeaderStatus{Elected: elected}
+	if status.NeedLeaderElection() {
+		t.Fatal("leader status must run on every replica")
+	}
+	transitions := metricValue(t, leaderTransitions)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- status.Start(ctx) }()
+
+	waitForMetric(t, isLeader, 0)
+	close(elected)
+	waitForMetric(t, isLeader, 1)
+	if got := metricValue(t, leaderTransitions); got != transitions+1 {
+		t.Fatalf("acquiring leadership should count one transition, got %v", got-transitions)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if metricValue(t, isLeader) != 0 || metricValue(t, leaderTransitions) != transitions+2 {
+		t.Fatalf("losing leadership should reset the gauge and count a transition")
+	}
+}
@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+)
+
+// MaintenanceWindow is the set of weekly time ranges in which mutating AWS operations may run
+type MaintenanceWindow struct {
+	ranges   []maintenanceRange
+	location *time.Location
+}
+
+// maintenanceRange opens at start and closes at end, in minutes after midnight, on days. A
+// range ending before it starts closes on the next day.
+type maintenanceRange struct {
+	days       [7]bool
+	start, end int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseMaintenanceWindow parses comma-separated ranges such as "Mon-Fri 22:00-02:00" or
+// "Sat 00:00-24:00" in location; a range without days applies to every day
+func ParseMaintenanceWindow(spec string, location *time.Location) (*MaintenanceWindow, error) {
+	w := &MaintenanceWindow{location: location}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		r, err := parseMaintenanceRange(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window %q: %w", entry, err)
+		}
+		w.ranges = append(w.ranges, r)
+	}
+	if len(w.ranges) == 0 {
+		return nil, errors.New("maintenance window has no time ranges")
+	}
+	return w, nil
+}
+
+func parseMaintenanceRange(entry string) (maintenanceRange, error) {
+	var r maintenanceRange
+	fields := strings.Fields(entry)
+	switch len(fields) {
+	case 1:
+		for i := range r.days {
+			r.days[i] = true
+		}
+	case 2:
+		first, last, _ := strings.Cut(strings.ToLower(fields[0]), "-")
+		if last == "" {
+			last = first
+		}
+		from, ok1 := weekdays[first]
+		to, ok2 := weekdays[last]
+		if !ok1 || !ok2 {
+			return r, fmt.Errorf("unknown days %q, want for example Mon or Mon-Fri", fields[0])
+		}
+		for day := from; ; day = (day + 1) % 7 {
+			r.days[day] = true
+			if day == to {
+				break
+			}
+		}
+	default:
+		return r, errors.New("want [days] HH:MM-HH:MM")
+	}
+
+	start, end, ok := strings.Cut(fields[len(fields)-1], "-")
+	if !ok {
+		return r, errors.New("want a time range HH:MM-HH:MM")
+	}
+	var err error
+	if r.start, err = parseClock(start); err != nil {
+		return r, err
+	}
+	if r.end, err = parseClock(end); err != nil {
+		return r, err
+	}
+	if r.start == r.end || r.start == 24*60 {
+		return r, errors.New("the range must not be empty")
+	}
+	return r, nil
+}
+
+// parseClock returns the minutes after midnight of HH:MM, allowing 24:00
+func parseClock(value string) (int, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes); err != nil || len(value) != 5 {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", value)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours*60+minutes > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return hours*60 + minutes, nil
+}
+
+// Open reports whether t falls in the window
+func (w *MaintenanceWindow) Open(t time.Time) bool {
+	local := t.In(w.location)
+	minute := local.Hour()*60 + local.Minute()
+	today, yesterday := local.Weekday(), (local.Weekday()+6)%7
+	for _, r := range w.ranges {
+		if r.start < r.end && r.days[today] && minute >= r.start && minute < r.end {
+			return true
+		}
+		if r.start > r.end && ((r.days[today] && minute >= r.start) || (r.days[yesterday] && minute < r.end)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns t when the window is open, else when it next opens
+func (w *MaintenanceWindow) Next(t time.Time) time.Time {
+	if w.Open(t) {
+		return t
+	}
+	local := t.In(w.location)
+	var next time.Time
+	for day := 0; day <= 7; day++ {
+		date := local.AddDate(0, 0, day)
+		for _, r := range w.ranges {
+			if !r.days[date.Weekday()] {
+				continue
+			}
+			opens := time.Date(date.Year(), date.Month(), date.Day(), r.start/60, r.start%60, 0, 0, w.location)
+			if opens.After(t) && (next.IsZero() || opens.Before(next)) {
+				next = opens
+			}
+		}
+	}
+	return next
+}
+
+// maintenanceDeferredError is returned by mutating AWS operations outside the maintenance window
+type maintenanceDeferredError struct {
+	operation string
+	opens     time.Time
+}
+
+func (e *maintenanceDeferredError) Error() string {
+	return fmt.Sprintf("%s deferred until the maintenance window opens at %s", e.operation, e.opens.UTC().Format(time.RFC3339))
+}
+
+// isMaintenanceDeferred reports whether err is an operation deferred to the maintenance window
+func isMaintenanceDeferred(err error) bool {
+	var deferred *maintenanceDeferredError
+	return errors.As(err, &deferred)
+}
+
+// maintenanceGate fails operation with a maintenanceDeferredError outside window
+func maintenanceGate(window *MaintenanceWindow, now func() time.Time, operation string) error {
+	t := now()
+	if window.Open(t) {
+		return nil
+	}
+	return &maintenanceDeferredError{operation: operation, opens: window.Next(t)}
+}
+
+// maintenanceACM defers certificate requests, imports and deletions to the maintenance window
+type maintenanceACM struct {
+	ACMAPI
+	window *MaintenanceWindow
+	now    func() time.Time
+}
+
+func (c *maintenanceACM) RequestCertificate(ctx context.Context, in *acm.RequestCertificateInput, optFns ...func(*acm.Options)) (*acm.RequestCertificateOutput, error) {
+	if err := maintenanceGate(c.window, c.now, "acm:RequestCertificate"); err != nil {
+		return nil, err
+	}
+	return c.ACMAPI.RequestCertificate(ctx, in, optFns...)
+}
+
+func (c *maintenanceACM) DeleteCertificate(ctx context.Context, in *acm.DeleteCertificateInput, optFns ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error) {
+	if err := maintenanceGate(c.window, c.now, "acm:DeleteCertificate"); err != nil {
+		return nil, err
+	}
+	return c.ACMAPI.DeleteCertificate(ctx, in, optFns...)
+}
+
+func (c *maintenanceACM) ImportCertificate(ctx context.Context, in *acm.ImportCertificateInput, optFns ...func(*acm.Options)) (*acm.ImportCertificateOutput, error) {
+	if err := maintenanceGate(c.window, c.now, "acm:ImportCertificate"); err != nil {
+		return nil, err
+	}
+	return c.ACMAPI.ImportCertificate(ctx, in, optFns...)
+}
+
+// maintenanceDNS defers validation record changes to the maintenance window
+type maintenanceDNS struct {
+	DNSProvider
+	window *MaintenanceWindow
+	now    func() time.Time
+}
+
+func (p *maintenanceDNS) EnsureRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	if err := maintenanceGate(p.window, p.now, "writing validation records"); err != nil {
+		return err
+	}
+	return p.DNSProvider.EnsureRecords(ctx, zoneID, records)
+}
+
+func (p *maintenanceDNS) DeleteRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	if err := maintenanceGate(p.window, p.now, "deleting validation records"); err != nil {
+		return err
+	}
+	return p.DNSProvider.DeleteRecords(ctx, zoneID, records)
+}
+
+// maintenanceACMClient returns client, deferring its mutating calls when a window is configured
+func (r *IngressReconciler) maintenanceACMClient(client ACMAPI) ACMAPI {
+	if r.MaintenanceWindow == nil || client == nil {
+		return client
+	}
+	return &maintenanceACM{ACMAPI: client, window: r.MaintenanceWindow, now: r.clock}
+}
+
+// maintenanceDNSProvider returns provider, deferring its record changes when a window is
+// configured. The manual provider writes nothing and is never deferred.
+func (r *IngressReconciler) maintenanceDNSProvider(provider DNSProvider) DNSProvider {
+	if _, manual := provider.(*ManualDNSProvider); r.MaintenanceWindow == nil || manual {
+		return provider
+	}
+	return &maintenanceDNS{DNSProvider: provider, window: r.MaintenanceWindow, now: r.clock}
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// at returns hour:minute UTC on day in the week starting Sunday 2026-01-04
+func at(day time.Weekday, hour, minute int) time.Time {
+	return time.Date(2026, 1, 4+int(day), hour, minute, 0, 0, time.UTC)
+}
+
+func TestMaintenanceWindowOpen(t *testing.T) {
+	w, err := ParseMaintenanceWindow("Mon-Fri 22:00-02:00, Sat 00:00-24:00", time.UTC)
+	if err != nil {
+		t.Fatalf("ParseMaintenanceWindow: %v", err)
+	}
+	cases := []struct {
+		t    time.Time
+		open bool
+	}{
+		{at(time.Monday, 21, 59), false},
+		{at(time.Monday, 22, 0), true},
+		{at(time.Tuesday, 1, 59), true},
+		{at(time.Tuesday, 2, 0), false},
+		{at(time.Monday, 1, 0), false},  // Sunday night has no range
+		{at(time.Saturday, 1, 0), true}, // Friday night and all of Saturday
+		{at(time.Saturday, 23, 59), true},
+		{at(time.Sunday, 0, 30), false},
+	}
+	for _, c := range cases {
+		if got := w.Open(c.t); got != c.open {
+			t.Errorf("Open(%s) = %v, want %v", c.t.Format("Mon 15:04"), got, c.open)
+		}
+	}
+}
+
+func TestMaintenanceWindowNext(t *testing.T) {
+	w, err := ParseMaintenanceWindow("Mon-Fri 22:00-02:00", time.UTC)
+	if err != nil {
+		t.Fatalf("ParseMaintenanceWindow: %v", err)
+	}
+	if got, want := w.Next(at(time.Monday, 12, 0)), at(time.Monday, 22, 0); !got.Equal(want) {
+		t.Errorf("Next(Mon 12:00) = %s, want %s", got, want)
+	}
+	if got, want := w.Next(at(time.Saturday, 12, 0)), at(time.Monday, 22, 0).AddDate(0, 0, 7); !got.Equal(want) {
+		t.Errorf("Next(Sat 12:00) = %s, want the next Monday %s", got, want)
+	}
+	if now := at(time.Tuesday, 1, 0); !w.Next(now).Equal(now) {
+		t.Error("Next must return the time itself inside the window")
+	}
+}
+
+func TestMaintenanceWindowTimezone(t *testing.T) {
+	berlin := time.FixedZone("CET", 3600)
+	w, err := ParseMaintenanceWindow("03:00-04:00", berlin)
+	if err != nil {
+		t.Fatalf("ParseMaintenanceWindow: %v", err)
+	}
+	if !w.Open(at(time.Monday, 2, 30)) || w.Open(at(time.Monday, 3, 30)) {
+		t.Fatal("the ranges must be read in the window's time zone")
+	}
+}
+
+func TestParseMaintenanceWindowInvalid(t *testing.T) {
+	for _, spec := range []string{"", "22:00", "Mon", "Funday 01:00-02:00", "25:00-26:00", "01:00-01:00", "1:00-2:00", "Mon Tue 01:00-02:00"} {
+		if _, err := ParseMaintenanceWindow(spec, time.UTC); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+// newMaintenanceReconciler returns a reconciler whose window is open Mondays 22:00-23:00,
+// with its clock at *now
+func newMaintenanceReconciler(t *testing.T, fakeACM *fakeACM, now *time.Time, objs ...client.Object) *IngressReconciler {
+	t.Helper()
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), objs...)
+	window, err := ParseMaintenanceWindow("Mon 22:00-23:00", time.UTC)
+	if err != nil {
+		t.Fatalf("ParseMaintenanceWindow: %v", err)
+	}
+	r.MaintenanceWindow = window
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func TestMaintenanceWindowDefersRequests(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	now := at(time.Monday, 20, 0)
+	r := newMaintenanceReconciler(t, fakeACM, &now, ingress)
+
+	res, err := r.Reconcile(ctx, requestFor(ingress))
+	if err != nil {
+		t.Fatalf("a deferred operation is not a failure, got %v", err)
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("no certificate may be requested outside the window, got %d", len(fakeACM.requests))
+	}
+	if res.RequeueAfter != 2*time.Hour {
+		t.Fatalf("expected a requeue when the window opens in 2h, got %s", res.RequeueAfter)
+	}
+	got := getIngress(t, r, ingress)
+	for _, key := range []string{lastErrorAnnotation, retryBackoffAnnotation} {
+		if _, ok := got.Annotations[key]; ok {
+			t.Errorf("a deferred operation must not set %s", key)
+		}
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if len(events) != 1 || !strings.Contains(events[0], ReasonOperationDeferred) || !strings.Contains(events[0], "acm:RequestCertificate") {
+		t.Fatalf("expected one OperationDeferred event, got %v", events)
+	}
+
+	now = at(time.Monday, 22, 0)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile inside the window: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected the certificate to be requested inside the window, got %d requests", len(fakeACM.requests))
+	}
+	if certificateArnOf(t, r, ingress) == "" {
+		t.Fatal("expected the certificate to be attached inside the window")
+	}
+}
+
+func TestMaintenanceWindowAllowsReadOnlyReconciles(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	now := at(time.Monday, 22, 30)
+	r := newMaintenanceReconciler(t, fakeACM, &now, ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile inside the window: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+	drainEvents(r.Recorder.(*record.FakeRecorder))
+
+	now = at(time.Tuesday, 12, 0)
+	res, err := r.Reconcile(ctx, requestFor(ingress))
+	if err != nil {
+		t.Fatalf("Reconcile outside the window: %v", err)
+	}
+	if got := certificateArnOf(t, r, ingress); got != arn || len(fakeACM.requests) != 1 {
+		t.Fatalf("an issued certificate must stay attached without new requests, got %q after %d requests", got, len(fakeACM.requests))
+	}
+	for _, e := range drainEvents(r.Recorder.(*record.FakeRecorder)) {
+		if strings.Contains(e, ReasonOperationDeferred) {
+			t.Fatalf("a read-only reconcile must not be deferred, got %q (result %+v)", e, res)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ingressConfig parses the Ingress' annotations on top of its Namespace's acm.tedens.dev
+// annotations, which in turn override the policy defaults. An Ingress without its own managed
+// annotation follows its Namespace's managed annotation, then ManageByDefault. The
+// Namespace's NamespaceTagAnnotations are added to the certificate tags, over the Ingress' own.
+func (r *IngressReconciler) ingressConfig(ctx context.Context, ingress *networkingv1.Ingress, policy map[string]string) (IngressConfig, error) {
+	nsDefaults, err := r.namespaceDefaults(ctx, ingress.Namespace)
+	if err != nil {
+		return IngressConfig{}, err
+	}
+
+	defaults := policy
+	if len(nsDefaults) > 0 {
+		defaults = make(map[string]string, len(policy)+len(nsDefaults))
+		for key, value := range policy {
+			defaults[key] = value
+		}
+		for key, value := range nsDefaults {
+			defaults[key] = value
+		}
+	}
+
+	settings := r.settings()
+	cfg := ParseIngressAnnotationsWithDefaults(ingress.GetAnnotations(), defaults)
+	if !cfg.ManagedAnnotated {
+		switch nsDefaults["managed"] {
+		case "true":
+			cfg.Managed = true
+		case "false":
+			cfg.Managed = false
+		default:
+			cfg.Managed = settings.ManageByDefault
+		}
+	}
+	if !cfg.IncludeWWWAnnotated {
+		cfg.IncludeWWW = settings.IncludeWWW
+	}
+	if cfg.DomainSuffix == "" {
+		cfg.DomainSuffix = parseDomainSuffix(settings.DomainSuffix)
+	}
+	cfg.SANs = qualifyHosts(cfg.SANs, cfg.DomainSuffix)
+
+	nsTags, err := r.namespaceTags(ctx, ingress.Namespace)
+	if err != nil {
+		return IngressConfig{}, err
+	}
+	for key, value := range nsTags {
+		if cfg.Tags == nil {
+			cfg.Tags = map[string]string{}
+		}
+		cfg.Tags[key] = value
+	}
+	return cfg, nil
+}
+
+// namespaceDefaults returns the acm.tedens.dev annotations of a Namespace keyed without the
+// prefix, or nil when the Namespace has none or does not exist
+func (r *IngressReconciler) namespaceDefaults(ctx context.Context, namespace string) (map[string]string, error) {
+	var ns corev1.Namespace
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var defaults map[string]string
+	for key, value := range ns.Annotations {
+		if name, ok := strings.CutPrefix(key, AnnotationPrefix); ok {
+			if defaults == nil {
+				defaults = map[string]string{}
+			}
+			defaults[name] = value
+		}
+	}
+	return defaults, nil
+}
+
+// enqueueIngressesForNamespace requeues every Ingress in a Namespace whose annotations changed,
+// including unmanaged ones so opted-out Ingresses release their finalizer
+func (r *IngressReconciler) enqueueIngressesForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	var ingresses networkingv1.IngressList
+	if err := r.List(ctx, &ingresses, client.InNamespace(obj.GetName())); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ingresses for namespace change", "namespace", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(ingresses.Items))
+	for _, ing := range ingresses.Items {
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}})
+	}
+	return requests
+}
+
+// releaseIngress removes the finalizer from an Ingress that is no longer managed, either
+// because it opted out or because --manage-by-default was turned off. Its certificates are
+// left alone since nobody asked for them to be deleted.
+func (r *IngressReconciler) releaseIngress(ctx context.Context, ingress *networkingv1.Ingress) error {
+	if !r.hasFinalizer(ingress) {
+		r.forgetCertificateMetrics(ingress.Namespace, ingress.Name)
+		return nil
+	}
+	log.FromContext(ctx).Info("Ingress is no longer managed, removing finalizer")
+	r.removeFinalizers(ingress)
+	if err := r.updateIngress(ctx, ingress); err != nil {
+		return err
+	}
+	r.forgetCertificateMetrics(ingress.Namespace, ingress.Name)
+	return nil
+}
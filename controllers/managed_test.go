@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func newUnannotatedIngress(name, host string, annotations map[string]string) *networkingv1.Ingress {
+	ingress := newManagedIngress(name, host, annotations)
+	if _, ok := annotations["acm.tedens.dev/managed"]; !ok {
+		delete(ingress.Annotations, "acm.tedens.dev/managed")
+	}
+	return ingress
+}
+
+func TestManageByDefault(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	implicit := newUnannotatedIngress("implicit", "app.example.com", nil)
+	optOut := newUnannotatedIngress("opt-out", "other.example.com", map[string]string{"acm.tedens.dev/managed": "false"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), implicit, optOut)
+	r.ManageByDefault = true
+
+	if _, err := r.Reconcile(ctx, requestFor(implicit)); err != nil {
+		t.Fatalf("Reconcile implicit: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(optOut)); err != nil {
+		t.Fatalf("Reconcile opt-out: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("only the unannotated Ingress should be managed, got %d requests", len(fakeACM.requests))
+	}
+	if certificateArnOf(t, r, implicit) == "" {
+		t.Fatal("unannotated Ingress should get a certificate")
+	}
+	if certificateArnOf(t, r, optOut) != "" {
+		t.Fatal("Ingress annotated managed=false must be left alone")
+	}
+}
+
+func TestTurningOffManageByDefaultReleasesFinalizer(t *testing.T) {
+	ctx := context.Background()
+	ingress := newUnannotatedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.ManageByDefault = true
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	r.ManageByDefault = false
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile with flag off: %v", err)
+	}
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if controllerutil.ContainsFinalizer(&got, DefaultFinalizerName) {
+		t.Fatal("finalizer must be removed once the Ingress is no longer managed")
+	}
+}
+
+func TestNamespaceOptIn(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "default",
+		Annotations: map[string]string{
+			"acm.tedens.dev/managed":       "true",
+			"acm.tedens.dev/key-algorithm": "EC_prime256v1",
+		},
+	}}
+	implicit := newUnannotatedIngress("implicit", "app.example.com", nil)
+	optOut := newUnannotatedIngress("opt-out", "other.example.com", map[string]string{"acm.tedens.dev/managed": "false"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ns, implicit, optOut)
+
+	if _, err := r.Reconcile(ctx, requestFor(implicit)); err != nil {
+		t.Fatalf("Reconcile implicit: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(optOut)); err != nil {
+		t.Fatalf("Reconcile opt-out: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("only the Ingress without managed=false should be managed, got %d requests", len(fakeACM.requests))
+	}
+	if alg := fakeACM.requests[0].KeyAlgorithm; alg != acmtypes.KeyAlgorithmEcPrime256v1 {
+		t.Fatalf("namespace key-algorithm default should apply, got %q", alg)
+	}
+
+	requests := r.enqueueIngressesForNamespace(ctx, ns)
+	if len(requests) != 2 {
+		t.Fatalf("a namespace change should requeue both Ingresses, got %v", requests)
+	}
+}
+
+func TestNamespaceOptOutOverridesManageByDefault(t *testing.T) {
+	ctx := context.Background()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "default",
+		Annotations: map[string]string{"acm.tedens.dev/managed": "false"},
+	}}
+	ingress := newUnannotatedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ns, ingress)
+	r.ManageByDefault = true
+
+	cfg, err := r.ingressConfig(ctx, ingress, nil)
+	if err != nil {
+		t.Fatalf("ingressConfig: %v", err)
+	}
+	if cfg.Managed {
+		t.Fatal("a Namespace annotated managed=false should opt its Ingresses out")
+	}
+}
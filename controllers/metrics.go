@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ingressSet tracks Ingresses in some state, such as quarantined or paused, for a gauge of
+// how many there are
+type ingressSet struct {
+	mu   sync.Mutex
+	keys map[types.NamespacedName]bool
+}
+
+// set adds or removes the Ingress, sets gauge to the new size and reports whether the
+// Ingress was added or removed
+func (s *ingressSet) set(key types.NamespacedName, member bool, gauge prometheus.Gauge) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		s.keys = map[types.NamespacedName]bool{}
+	}
+	changed := s.keys[key] != member
+	if member {
+		s.keys[key] = true
+	} else {
+		delete(s.keys, key)
+	}
+	gauge.Set(float64(len(s.keys)))
+	return changed
+}
+
+// CertificateInfoMetric maintains acm_manager_ingress_certificate_info, an info-style gauge
+// with one series per managed Ingress and attached certificate. A nil metric is disabled.
+type CertificateInfoMetric struct {
+	vec *prometheus.GaugeVec
+}
+
+// NewCertificateInfoMetric returns the metric; register it before use
+func NewCertificateInfoMetric() *CertificateInfoMetric {
+	return &CertificateInfoMetric{vec: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "acm_manager_ingress_certificate_info",
+		Help: "Certificates attached to each managed Ingress, always 1.",
+	}, []string{"namespace", "ingress", "domain", "arn", "status"})}
+}
+
+func (m *CertificateInfoMetric) Describe(ch chan<- *prometheus.Desc) { m.vec.Describe(ch) }
+
+func (m *CertificateInfoMetric) Collect(ch chan<- prometheus.Metric) { m.vec.Collect(ch) }
+
+// set replaces the Ingress' series with one per certificate in arns
+func (m *CertificateInfoMetric) set(ingress *networkingv1.Ingress, domain string, arns []string, status string) {
+	if m == nil {
+		return
+	}
+	m.delete(ingress.Namespace, ingress.Name)
+	for _, arn := range arns {
+		m.vec.WithLabelValues(ingress.Namespace, ingress.Name, domain, arn, status).Set(1)
+	}
+}
+
+// delete drops every series of the Ingress
+func (m *CertificateInfoMetric) delete(namespace, name string) {
+	if m == nil {
+		return
+	}
+	m.vec.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "ingress": name})
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// certificateInfoSeries returns the label sets of every acm_manager_ingress_certificate_info series
+func certificateInfoSeries(t *testing.T, registry *prometheus.Registry) []map[string]string {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	var series []map[string]string
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range metric.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			series = append(series, labels)
+		}
+	}
+	return series
+}
+
+func TestCertificateInfoMetric(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.CertificateInfo = NewCertificateInfoMetric()
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(r.CertificateInfo); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	series := certificateInfoSeries(t, registry)
+	want := map[string]string{
+		"namespace": "default",
+		"ingress":   "web",
+		"domain":    "app.example.com",
+		"arn":       certificateArnOf(t, r, ingress),
+		"status":    "ISSUED",
+	}
+	if len(series) != 1 || len(series[0]) != len(want) {
+		t.Fatalf("expected one series %v, got %v", want, series)
+	}
+	for k, v := range want {
+		if series[0][k] != v {
+			t.Fatalf("label %s = %q, want %q", k, series[0][k], v)
+		}
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if err := r.Delete(ctx, &got); err != nil {
+		t.Fatalf("delete ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after delete: %v", err)
+	}
+	if series := certificateInfoSeries(t, registry); len(series) != 0 {
+		t.Fatalf("series should be removed with the Ingress, got %v", series)
+	}
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const namesFromConfigMapAnnotation = "acm.tedens.dev/names-from-configmap"
+
+// Keys read from a names-from-configmap ConfigMap
+const (
+	namesConfigMapDomainKey = "domain"
+	namesConfigMapSANsKey   = "sans"
+)
+
+// applyNamesFromConfigMap reads the primary domain and SANs from the ConfigMap named by
+// acm.tedens.dev/names-from-configmap in the Ingress' namespace. The ConfigMap's domain is
+// used unless acm.tedens.dev/domain is set; its SANs are added to acm.tedens.dev/san.
+func (r *IngressReconciler) applyNamesFromConfigMap(ctx context.Context, ingress *networkingv1.Ingress, cfg *IngressConfig) error {
+	if cfg.NamesFromConfigMap == "" {
+		return nil
+	}
+
+	var cm corev1.ConfigMap
+	key := types.NamespacedName{Namespace: ingress.Namespace, Name: cfg.NamesFromConfigMap}
+	if err := r.Get(ctx, key, &cm); err != nil {
+		return fmt.Errorf("failed to read names ConfigMap %s: %w", key, err)
+	}
+
+	domain := qualifyHost(strings.ToLower(strings.TrimSpace(cm.Data[namesConfigMapDomainKey])), cfg.DomainSuffix)
+	sans := qualifyHosts(parseNameList(cm.Data[namesConfigMapSANsKey]), cfg.DomainSuffix)
+
+	names := sans
+	if domain != "" {
+		names = append([]string{domain}, sans...)
+	}
+	if invalid := invalidHostnames(names); len(invalid) > 0 {
+		return fmt.Errorf("names ConfigMap %s has invalid hostnames: %s", key, strings.Join(invalid, ", "))
+	}
+
+	if cfg.DomainOverride == "" {
+		cfg.DomainOverride = domain
+	}
+	for _, san := range sans {
+		if san != cfg.DomainOverride && !containsFold(cfg.SANs, san) {
+			cfg.SANs = append(cfg.SANs, san)
+		}
+	}
+	return nil
+}
+
+// parseNameList splits a list of names separated by commas, spaces or newlines
+func parseNameList(value string) []string {
+	fields := strings.FieldsFunc(value, func(c rune) bool {
+		return c == ',' || c == ' ' || c == '\n' || c == '\t' || c == '\r'
+	})
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, strings.ToLower(field))
+	}
+	return names
+}
+
+// invalidHostnames returns the names that are not DNS hostnames, allowing a leading "*."
+func invalidHostnames(names []string) []string {
+	var invalid []string
+	for _, name := range names {
+		if errs := validation.IsDNS1123Subdomain(strings.TrimPrefix(name, "*.")); len(errs) > 0 {
+			invalid = append(invalid, name)
+		}
+	}
+	return invalid
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueIngressesForNamesConfigMap requeues the Ingresses that read their names from obj
+func (r *IngressReconciler) enqueueIngressesForNamesConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	var ingresses networkingv1.IngressList
+	if err := r.List(ctx, &ingresses, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ingresses for names ConfigMap change")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ing := range ingresses.Items {
+		if ing.Annotations[namesFromConfigMapAnnotation] == obj.GetName() {
+			requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}})
+		}
+	}
+	return requests
+}
+
+// configMapDataChanged passes ConfigMap updates that change its data, skipping those that only
+// touch metadata, such as another controller's annotations
+func configMapDataChanged() predicate.Predicate {
+	return predicate.Funcs{UpdateFunc: func(e event.UpdateEvent) bool {
+		previous, okOld := e.ObjectOld.(*corev1.ConfigMap)
+		current, okNew := e.ObjectNew.(*corev1.ConfigMap)
+		if !okOld || !okNew {
+			return true
+		}
+		return !maps.Equal(previous.Data, current.Data) || !maps.EqualFunc(previous.BinaryData, current.BinaryData, bytes.Equal)
+	}}
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func newNamesConfigMap(name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Data:       data,
+	}
+}
+
+func TestNamesFromConfigMap(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	cm := newNamesConfigMap("web-names", map[string]string{
+		"domain": "App.Example.com",
+		"sans":   "api.example.com, www.example.com\n*.cdn.example.com",
+	})
+	ingress := newManagedIngress("web", "ignored.example.com", map[string]string{
+		namesFromConfigMapAnnotation: "web-names",
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), cm, ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected 1 certificate request, got %d", len(fakeACM.requests))
+	}
+	req := fakeACM.requests[0]
+	if got := aws.ToString(req.DomainName); got != "app.example.com" {
+		t.Fatalf("domain = %q, want app.example.com", got)
+	}
+	want := []string{"api.example.com", "www.example.com", "*.cdn.example.com"}
+	if !slices.Equal(req.SubjectAlternativeNames, want) {
+		t.Fatalf("SANs = %v, want %v", req.SubjectAlternativeNames, want)
+	}
+
+	// A second reconcile keeps the issued certificate since it covers the same names
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("unchanged names must not request a new certificate, got %d requests", len(fakeACM.requests))
+	}
+}
+
+func TestNamesFromConfigMapInvalidHostname(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	cm := newNamesConfigMap("web-names", map[string]string{"sans": "ok.example.com, bad_name.example.com"})
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		namesFromConfigMapAnnotation: "web-names",
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), cm, ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err == nil {
+		t.Fatal("expected an error for an invalid hostname")
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("no certificate should be requested, got %d requests", len(fakeACM.requests))
+	}
+}
+
+func TestEnqueueIngressesForNamesConfigMap(t *testing.T) {
+	cm := newNamesConfigMap("web-names", nil)
+	referencing := newManagedIngress("web", "app.example.com", map[string]string{namesFromConfigMapAnnotation: "web-names"})
+	other := newManagedIngress("other", "other.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), cm, referencing, other)
+
+	requests := r.enqueueIngressesForNamesConfigMap(context.Background(), cm)
+	if len(requests) != 1 || requests[0] != requestFor(referencing) {
+		t.Fatalf("expected only the referencing Ingress, got %v", requests)
+	}
+}
+
+func TestConfigMapDataChanged(t *testing.T) {
+	previous := newNamesConfigMap("web-names", map[string]string{"domain": "app.example.com"})
+	relabeled := previous.DeepCopy()
+	relabeled.Labels = map[string]string{"team": "web"}
+	edited := previous.DeepCopy()
+	edited.Data["sans"] = "api.example.com"
+
+	changed := configMapDataChanged()
+	if changed.Update(event.UpdateEvent{ObjectOld: previous, ObjectNew: relabeled}) {
+		t.Error("a metadata-only update should not requeue the Ingresses")
+	}
+	if !changed.Update(event.UpdateEvent{ObjectOld: previous, ObjectNew: edited}) {
+		t.Error("a data change should requeue the Ingresses")
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Notification event names, mirroring the Kubernetes events the controller records
+const (
+	NotificationIssued   = "issued"
+	NotificationFailed   = "failed"
+	NotificationExpiring = "expiring"
+)
+
+// Notification is the JSON payload delivered to the notification webhook
+type Notification struct {
+	Event     string    `json:"event"`
+	Ingress   string    `json:"ingress"`
+	Domain    string    `json:"domain"`
+	ARN       string    `json:"arn,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Notifier receives certificate lifecycle notifications. Notify must not block.
+type Notifier interface {
+	Notify(n Notification)
+}
+
+// WebhookNotifier POSTs notifications as JSON to a URL from a background worker.
+// Delivery is best-effort: notifications are dropped when the queue is full or the POST fails.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+	queue  chan Notification
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWebhookNotifier returns a notifier for url; it must be added to the manager to deliver
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan Notification, 100),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Stop makes Start return once the notifications queued so far are delivered, for a notifier
+// replaced while the controller runs. Notifications queued after Stop may be dropped.
+func (w *WebhookNotifier) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+// Notify queues n for delivery without blocking
+func (w *WebhookNotifier) Notify(n Notification) {
+	select {
+	case w.queue <- n:
+	default:
+		log.Log.WithName("notifier").Info("Notification queue full, dropping notification", "event", n.Event, "ingress", n.Ingress)
+	}
+}
+
+// NeedLeaderElection lets the worker run on every replica so queued notifications are never stranded
+func (w *WebhookNotifier) NeedLeaderElection() bool {
+	return false
+}
+
+// Start delivers queued notifications until ctx is cancelled
+func (w *WebhookNotifier) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("notifier")
+	for {
+		var n Notification
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.stop:
+			// Drained one at a time, returning once the queue is empty
+			select {
+			case n = <-w.queue:
+			default:
+				return nil
+			}
+		case n = <-w.queue:
+		}
+		if err := w.deliver(ctx, n); err != nil {
+			logger.Error(err, "failed to deliver notification", "event", n.Event, "ingress", n.Ingress)
+		}
+	}
+}
+
+func (w *WebhookNotifier) deliver(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []Notification
+}
+
+func (n *recordingNotifier) Notify(notification Notification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, notification)
+}
+
+func TestWebhookNotifierDeliversJSON(t *testing.T) {
+	received := make(chan Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var n Notification
+		if err := json.NewDecoder(req.Body).Decode(&n); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- n
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notifier := NewWebhookNotifier(server.URL)
+	go func() { _ = notifier.Start(ctx) }()
+
+	notifier.Notify(Notification{Event: NotificationIssued, Ingress: "default/web", Domain: "app.example.com", ARN: "arn:1"})
+
+	select {
+	case n := <-received:
+		if n.Event != NotificationIssued || n.Ingress != "default/web" || n.ARN != "arn:1" {
+			t.Fatalf("unexpected payload %+v", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestWebhookNotifierStopDeliversQueued(t *testing.T) {
+	var delivered atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { delivered.Add(1) }))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL)
+	notifier.Notify(Notification{Event: NotificationIssued})
+	notifier.Notify(Notification{Event: NotificationFailed})
+	notifier.Stop()
+	notifier.Stop()
+
+	done := make(chan error, 1)
+	go func() { done <- notifier.Start(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a stopped notifier kept running")
+	}
+	if got := delivered.Load(); got != 2 {
+		t.Fatalf("delivered %d notifications, want the 2 queued before Stop", got)
+	}
+}
+
+func TestWebhookNotifierNeverBlocks(t *testing.T) {
+	notifier := NewWebhookNotifier("http://127.0.0.1:0")
+	done := make(chan struct{})
+	go func() {
+		// Not started, so the queue fills up and further notifications are dropped
+		for i := 0; i < 500; i++ {
+			notifier.Notify(Notification{Event: NotificationFailed})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify blocked")
+	}
+}
+
+func TestReconcileNotifiesIssuedAndExpiring(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	notifier := &recordingNotifier{}
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.Notifier = notifier
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].Event != NotificationIssued || notifier.sent[0].Domain != "app.example.com" {
+		t.Fatalf("expected one issued notification, got %+v", notifier.sent)
+	}
+
+	arn := notifier.sent[0].ARN
+	soon := time.Now().Add(24 * time.Hour)
+	fakeACM.certs[arn].NotAfter = aws.Time(soon)
+	fakeACM.certs[arn].Status = acmtypes.CertificateStatusIssued
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	if len(notifier.sent) != 2 || notifier.sent[1].Event != NotificationExpiring || notifier.sent[1].ARN != arn {
+		t.Fatalf("expected an expiring notification, got %+v", notifier.sent)
+	}
+}
+
+func TestPostIssuanceHookFiresOncePerCertificate(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	hook := &recordingNotifier{}
+	r.PostIssuanceHook = hook
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+	if len(hook.sent) != 1 || hook.sent[0].Event != NotificationPostIssuance || hook.sent[0].ARN != arn {
+		t.Fatalf("expected one post-issuance notification for %s, got %+v", arn, hook.sent)
+	}
+
+	// Neither another reconcile nor attaching the same certificate again fires the hook
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	patch := client.MergeFrom(got.DeepCopy())
+	delete(got.Annotations, albCertificateArnAnnotation)
+	if err := r.Patch(ctx, got, patch); err != nil {
+		t.Fatalf("patch ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after detaching: %v", err)
+	}
+	if certificateArnOf(t, r, ingress) != arn || len(hook.sent) != 1 {
+		t.Fatalf("re-attaching %s must not fire the hook again, got %+v", arn, hook.sent)
+	}
+
+	// A replacement certificate fires it once more
+	if _, err := fakeACM.DeleteCertificate(ctx, &acm.DeleteCertificateInput{CertificateArn: aws.String(arn)}); err != nil {
+		t.Fatalf("delete certificate: %v", err)
+	}
+	// ACM forgets idempotency tokens after an hour, so the replacement is a new certificate
+	fakeACM.tokens = map[string]string{}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after deletion: %v", err)
+	}
+	replacement := certificateArnOf(t, r, ingress)
+	if replacement == arn || len(hook.sent) != 2 || hook.sent[1].ARN != replacement {
+		t.Fatalf("expected one more notification for the replacement %s, got %+v", replacement, hook.sent)
+	}
+}
+
+func TestPostIssuanceHookSkipsReusedCertificates(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	existing := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": DefaultManagedByValue})
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	hook := &recordingNotifier{}
+	r.PostIssuanceHook = hook
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if certificateArnOf(t, r, ingress) != existing || len(fakeACM.requests) != 0 {
+		t.Fatalf("expected %s to be reused, got %s after %d requests", existing, certificateArnOf(t, r, ingress), len(fakeACM.requests))
+	}
+	if len(hook.sent) != 0 {
+		t.Fatalf("a reused certificate must not fire the hook, got %+v", hook.sent)
+	}
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// namespaceTags returns the NamespaceTagAnnotations set on a Namespace as certificate tags,
+// keyed by the annotation, or nil when none is set or the Namespace does not exist
+func (r *IngressReconciler) namespaceTags(ctx context.Context, namespace string) (map[string]string, error) {
+	keys := r.settings().NamespaceTagAnnotations
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	var ns corev1.Namespace
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var tags map[string]string
+	for _, key := range keys {
+		if value, ok := ns.Annotations[key]; ok {
+			if tags == nil {
+				tags = map[string]string{}
+			}
+			tags[key] = value
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ErrObserveOnly is returned, wrapped with the operation, by every change ObserveOnly keeps
+// the controller from making
+var ErrObserveOnly = errors.New("not changed in observe-only mode")
+
+// observedPlanAnnotation lists the changes the last reconcile would have made without
+// ObserveOnly. It is removed once a reconcile needs none.
+const observedPlanAnnotation = "acm.tedens.dev/observed-plan"
+
+// observeOnlyRequeue is how soon an Ingress with a plan is looked at again
+const observeOnlyRequeue = time.Hour
+
+// observeOnlySkipped counts the changes observe-only mode did not make
+var observeOnlySkipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "acm_manager_observe_only_skipped_total",
+	Help: "Changes to AWS or Ingresses not made because of --observe-only, by operation.",
+}, []string{"operation"})
+
+// observedPlan collects the changes a reconcile would have made
+type observedPlan struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+type observedPlanKey struct{}
+
+// withObservedPlan returns ctx collecting the changes skipped while it is in use
+func withObservedPlan(ctx context.Context) (context.Context, *observedPlan) {
+	plan := &observedPlan{}
+	return context.WithValue(ctx, observedPlanKey{}, plan), plan
+}
+
+func (p *observedPlan) String() string {
+	if p == nil {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return strings.Join(p.steps, "; ")
+}
+
+// observeGate logs and counts operation as skipped, adds it with detail to the plan in ctx and
+// returns ErrObserveOnly
+func observeGate(ctx context.Context, operation, detail string) error {
+	step := operation
+	if detail != "" {
+		step += " " + detail
+	}
+	log.FromContext(ctx).Info("Observe-only: not changing anything", "operation", operation, "detail", detail)
+	observeOnlySkipped.WithLabelValues(operation).Inc()
+	if plan, ok := ctx.Value(observedPlanKey{}).(*observedPlan); ok {
+		plan.mu.Lock()
+		plan.steps = append(plan.steps, step)
+		plan.mu.Unlock()
+	}
+	return fmt.Errorf("%s: %w", step, ErrObserveOnly)
+}
+
+// observeACM makes no certificate requests, imports, deletions or tag changes
+type observeACM struct {
+	ACMAPI
+}
+
+func (c *observeACM) RequestCertificate(ctx context.Context, in *acm.RequestCertificateInput, _ ...func(*acm.Options)) (*acm.RequestCertificateOutput, error) {
+	names := append([]string{aws.ToString(in.DomainName)}, in.SubjectAlternativeNames...)
+	return nil, observeGate(ctx, "acm:RequestCertificate", strings.Join(names, ","))
+}
+
+func (c *observeACM) DeleteCertificate(ctx context.Context, in *acm.DeleteCertificateInput, _ ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error) {
+	return nil, observeGate(ctx, "acm:DeleteCertificate", aws.ToString(in.CertificateArn))
+}
+
+func (c *observeACM) ImportCertificate(ctx context.Context, in *acm.ImportCertificateInput, _ ...func(*acm.Options)) (*acm.ImportCertificateOutput, error) {
+	return nil, observeGate(ctx, "acm:ImportCertificate", aws.ToString(in.CertificateArn))
+}
+
+func (c *observeACM) AddTagsToCertificate(ctx context.Context, in *acm.AddTagsToCertificateInput, _ ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	keys := make([]string, 0, len(in.Tags))
+	for _, tag := range in.Tags {
+		keys = append(keys, aws.ToString(tag.Key))
+	}
+	return nil, observeGate(ctx, "acm:AddTagsToCertificate", fmt.Sprintf("%s %s", aws.ToString(in.CertificateArn), strings.Join(keys, ",")))
+}
+
+// observeDNS writes and deletes no validation records
+type observeDNS struct {
+	DNSProvider
+}
+
+func (p *observeDNS) EnsureRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	return observeGate(ctx, "dns:EnsureRecords", fmt.Sprintf("%s %s", zoneID, recordNames(records)))
+}
+
+func (p *observeDNS) DeleteRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	return observeGate(ctx, "dns:DeleteRecords", fmt.Sprintf("%s %s", zoneID, recordNames(records)))
+}
+
+func recordNames(records []ValidationRecord) string {
+	names := make([]string, 0, len(records))
+	for _, record := range records {
+		names = append(names, record.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// observeACMClient returns client, making none of its mutating calls with ObserveOnly
+func (r *IngressReconciler) observeACMClient(client ACMAPI) ACMAPI {
+	if !r.ObserveOnly || client == nil {
+		return client
+	}
+	return &observeACM{ACMAPI: client}
+}
+
+// observeDNSProvider returns provider, changing no records with ObserveOnly
+func (r *IngressReconciler) observeDNSProvider(provider DNSProvider) DNSProvider {
+	if !r.ObserveOnly || provider == nil {
+		return provider
+	}
+	return &observeDNS{DNSProvider: provider}
+}
+
+// recordObservedPlan sets the observed-plan annotation of the Ingress to plan, recording an
+// ObservedPlan event when it changed, or removes it when plan is empty
+func (r *IngressReconciler) recordObservedPlan(ctx context.Context, key types.NamespacedName, plan string) {
+	var ingress networkingv1.Ingress
+	if err := r.Get(ctx, key, &ingress); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.FromContext(ctx).Error(err, "failed to read ingress to record the observed plan")
+		}
+		return
+	}
+	current, exists := ingress.Annotations[observedPlanAnnotation]
+	if current == plan && (exists || plan == "") {
+		return
+	}
+
+	patch := client.MergeFrom(ingress.DeepCopy())
+	if plan == "" {
+		delete(ingress.Annotations, observedPlanAnnotation)
+	} else {
+		if ingress.Annotations == nil {
+			ingress.Annotations = map[string]string{}
+		}
+		ingress.Annotations[observedPlanAnnotation] = plan
+	}
+	if err := r.patchIngress(ctx, &ingress, patch); err != nil {
+		log.FromContext(ctx).Error(err, "failed to record the observed plan")
+		return
+	}
+	if plan != "" && r.Recorder != nil {
+		r.Recorder.Eventf(&ingress, corev1.EventTypeNormal, ReasonObservedPlan, "Observe-only: would %s", plan)
+	}
+}
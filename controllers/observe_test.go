@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestObserveOnlyMakesNoChanges(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeR53 := newFakeRoute53("example.com")
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/san": "www.example.com"})
+	r := newTestReconciler(t, fakeACM, fakeR53, ingress)
+	r.ObserveOnly = true
+
+	result, err := r.Reconcile(ctx, requestFor(ingress))
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if result.RequeueAfter != observeOnlyRequeue {
+		t.Errorf("RequeueAfter = %s, want %s", result.RequeueAfter, observeOnlyRequeue)
+	}
+	if len(fakeACM.requests) != 0 || len(fakeR53.changes) != 0 {
+		t.Fatalf("observe-only made %d requests and %d record changes", len(fakeACM.requests), len(fakeR53.changes))
+	}
+	got := getIngress(t, r, ingress)
+	if controllerutil.ContainsFinalizer(got, DefaultFinalizerName) {
+		t.Error("observe-only added the finalizer")
+	}
+	if plan := got.Annotations[observedPlanAnnotation]; plan != "acm:RequestCertificate app.example.com,www.example.com" {
+		t.Errorf("observed plan = %q", plan)
+	}
+	if _, failed := got.Annotations[lastErrorAnnotation]; failed {
+		t.Errorf("a skipped change is no failure, got last error %q", got.Annotations[lastErrorAnnotation])
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if !slices.ContainsFunc(events, func(e string) bool { return strings.Contains(e, ReasonObservedPlan) }) {
+		t.Errorf("expected an %s event, got %v", ReasonObservedPlan, events)
+	}
+
+	// The same plan is not reported again
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if events := drainEvents(r.Recorder.(*record.FakeRecorder)); slices.ContainsFunc(events, func(e string) bool { return strings.Contains(e, ReasonObservedPlan) }) {
+		t.Errorf("unchanged plan reported again: %v", events)
+	}
+
+	// Turned off, the next reconcile carries the plan out and clears it
+	r.ObserveOnly = false
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one request once observe-only is off, got %d", len(fakeACM.requests))
+	}
+	got = getIngress(t, r, ingress)
+	if _, planned := got.Annotations[observedPlanAnnotation]; planned || got.Annotations["alb.ingress.kubernetes.io/certificate-arn"] == "" {
+		t.Errorf("expected the certificate attached and the plan cleared, got %v", got.Annotations)
+	}
+}
+
+func TestObserveOnlyReportsAttachingAReusableCertificate(t *testing.T) {
+	fakeACM := newFakeACM()
+	arn := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "acm-manager"})
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.ObserveOnly = true
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	if got.Annotations["alb.ingress.kubernetes.io/certificate-arn"] != "" {
+		t.Fatal("observe-only attached the certificate")
+	}
+	if plan := got.Annotations[observedPlanAnnotation]; !strings.Contains(plan, "attach "+arn) {
+		t.Errorf("observed plan = %q, want attaching %s", plan, arn)
+	}
+}
+
+func TestObserveOnlyKeepsMissingCertificateArn(t *testing.T) {
+	gone := "arn:aws:acm:us-east-1:123456789012:certificate/deleted"
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		"alb.ingress.kubernetes.io/certificate-arn": gone,
+		notAfterAnnotation:                          "2026-01-01T00:00:00Z",
+	})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.ObserveOnly = true
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	if arn := got.Annotations["alb.ingress.kubernetes.io/certificate-arn"]; arn != gone {
+		t.Errorf("certificate-arn = %q, want the missing certificate left in place", arn)
+	}
+	if _, ok := got.Annotations[notAfterAnnotation]; !ok {
+		t.Error("observe-only removed the not-after annotation")
+	}
+	if plan := got.Annotations[observedPlanAnnotation]; !strings.Contains(plan, "forget "+gone) {
+		t.Errorf("observed plan = %q, want it to forget %s", plan, gone)
+	}
+}
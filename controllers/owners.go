@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ownersTagKey is the certificate tag listing the namespace/name of every Ingress the
+	// certificate is attached to, separated by spaces
+	ownersTagKey = "acm.tedens.dev/owners"
+	// ownersOverflowTagKey marks a certificate shared by more Ingresses than its owners tag
+	// can list. The controller never deletes a marked certificate.
+	ownersOverflowTagKey = "acm.tedens.dev/owners-overflow"
+	// maxTagValueLength is the longest tag value ACM accepts
+	maxTagValueLength = 256
+)
+
+// certificateLocks serializes the owners tag read-modify-write of each certificate, which
+// Ingresses sharing it reconcile concurrently
+type certificateLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the certificate and returns the function unlocking it
+func (l *certificateLocks) lock(certArn string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*sync.Mutex{}
+	}
+	lock, ok := l.locks[certArn]
+	if !ok {
+		lock = &sync.Mutex{}
+		l.locks[certArn] = lock
+	}
+	l.mu.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
+// certificateTags returns the tags of the certificate by key
+func (r *IngressReconciler) certificateTags(ctx context.Context, certArn string) (map[string]string, error) {
+	out, err := r.acm(ctx).ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{
+		CertificateArn: aws.String(certArn),
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(out.Tags))
+	for _, tag := range out.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags, nil
+}
+
+// certificateOwners returns the Ingresses in the owners tag. A certificate tagged before the
+// owners tag existed is owned by the Ingress in its owner tag.
+func certificateOwners(tags map[string]string) []string {
+	if value, ok := tags[ownersTagKey]; ok {
+		return strings.Fields(value)
+	}
+	if owner := tags[ownerTagKey]; owner != "" {
+		return []string{owner}
+	}
+	return nil
+}
+
+// setCertificateOwners overwrites the owners tag; an empty list leaves the tag empty
+func (r *IngressReconciler) setCertificateOwners(ctx context.Context, certArn string, owners []string) error {
+	_, err := r.acm(ctx).AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+		CertificateArn: aws.String(certArn),
+		Tags:           []acmtypes.Tag{{Key: aws.String(ownersTagKey), Value: aws.String(strings.Join(owners, " "))}},
+	})
+	return err
+}
+
+// addCertificateOwner adds the Ingress to the owners tag of a certificate this instance
+// manages. When the owner no longer fits in the tag value the certificate is marked as
+// overflowed instead, so it is never deleted from under an owner the tag cannot list.
+func (r *IngressReconciler) addCertificateOwner(ctx context.Context, certArn string, ingress *networkingv1.Ingress) error {
+	defer r.ownerLocks.lock(certArn)()
+	tags, err := r.certificateTags(ctx, certArn)
+	if err != nil {
+		return fmt.Errorf("failed to list tags of certificate %s: %w", certArn, err)
+	}
+	if tags[certs.ManagedByTagKey] != r.managedByValue() {
+		return nil
+	}
+	owners := certificateOwners(tags)
+	key := ingressKey(ingress)
+	if slices.Contains(owners, key) {
+		return nil
+	}
+	owners = append(owners, key)
+	slices.Sort(owners)
+	if len(strings.Join(owners, " ")) > maxTagValueLength {
+		if tags[ownersOverflowTagKey] == "true" {
+			return nil
+		}
+		log.FromContext(ctx).Info("Too many Ingresses share the certificate to list them all in its owners tag, it will not be deleted", "arn", certArn, "owners", len(owners))
+		_, err := r.acm(ctx).AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+			CertificateArn: aws.String(certArn),
+			Tags:           []acmtypes.Tag{{Key: aws.String(ownersOverflowTagKey), Value: aws.String("true")}},
+		})
+		return err
+	}
+	return r.setCertificateOwners(ctx, certArn, owners)
+}
+
+// logOwnerError logs a failure to record the owner of a certificate. Deferred writes, such as
+// in observe-only mode, happen on every reconcile and are only logged at debug level.
+func logOwnerError(ctx context.Context, err error, certArn string) {
+	if isDeferred(err) {
+		log.FromContext(ctx).V(1).Info("Certificate owner not recorded", "arn", certArn, "reason", err.Error())
+		return
+	}
+	log.FromContext(ctx).Error(err, "failed to record certificate owner", "arn", certArn)
+}
+
+// releaseCertificateOwners removes the Ingress from the owners tag of each certificate, which
+// it no longer holds once it is deleted. Certificates that are gone are skipped.
+func (r *IngressReconciler) releaseCertificateOwners(ctx context.Context, ingress *networkingv1.Ingress, certArns []string) error {
+	key := ingressKey(ingress)
+	var notFound *acmtypes.ResourceNotFoundException
+	for _, certArn := range certArns {
+		if err := r.releaseCertificateOwner(ctx, certArn, key); err != nil && !errors.As(err, &notFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseCertificateOwner removes owner from the owners tag of the certificate
+func (r *IngressReconciler) releaseCertificateOwner(ctx context.Context, certArn, owner string) error {
+	defer r.ownerLocks.lock(certArn)()
+	tags, err := r.certificateTags(ctx, certArn)
+	if err != nil {
+		return fmt.Errorf("failed to list tags of certificate %s: %w", certArn, err)
+	}
+	owners := certificateOwners(tags)
+	if !slices.Contains(owners, owner) {
+		return nil
+	}
+	remaining := slices.DeleteFunc(owners, func(o string) bool { return o == owner })
+	if err := r.setCertificateOwners(ctx, certArn, remaining); err != nil {
+		return fmt.Errorf("failed to update the owners of certificate %s: %w", certArn, err)
+	}
+	return nil
+}
+
+// certificateStillHeld reports whether an Ingress other than owner is in the certificate's
+// owners tag, or a load balancer still uses it, in which case it must not be deleted yet
+func (r *IngressReconciler) certificateStillHeld(ctx context.Context, certArn, owner string) (bool, error) {
+	tags, err := r.certificateTags(ctx, certArn)
+	if err != nil {
+		return false, err
+	}
+	if tags[ownersOverflowTagKey] == "true" {
+		log.FromContext(ctx).Info("Certificate has more owners than its owners tag lists, leaving it", "arn", certArn)
+		return true, nil
+	}
+	others := slices.DeleteFunc(certificateOwners(tags), func(o string) bool { return o == owner })
+	if len(others) > 0 {
+		log.FromContext(ctx).Info("Certificate is still attached to other Ingresses, leaving it", "arn", certArn, "owners", others)
+		return true, nil
+	}
+	describe, err := r.acm(ctx).DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+		CertificateArn: aws.String(certArn),
+	})
+	if err != nil {
+		return false, err
+	}
+	if inUse := describe.Certificate.InUseBy; len(inUse) > 0 {
+		return true, fmt.Errorf("certificate %s is still in use by %s", certArn, strings.Join(inUse, ", "))
+	}
+	return false, nil
+}
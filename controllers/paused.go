@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// pausedAnnotation suspends reconciling an Ingress, without AWS calls or annotation updates,
+// until it is removed or set to anything but "true"
+const pausedAnnotation = "acm.tedens.dev/paused"
+
+// pausedIngresses is the number of Ingresses currently paused
+var pausedIngresses = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "acm_manager_paused_ingresses",
+	Help: "Number of Ingresses whose reconciliation is paused by acm.tedens.dev/paused.",
+})
+
+// isPaused reports whether the Ingress is annotated acm.tedens.dev/paused: "true"
+func isPaused(ingress *networkingv1.Ingress) bool {
+	return strings.EqualFold(strings.TrimSpace(ingress.Annotations[pausedAnnotation]), "true")
+}
+
+// skipPaused reports whether the Ingress is paused and must not be reconciled. Ingresses being
+// deleted are never skipped, so their finalizer still runs. A Paused event is recorded when a
+// pause is first seen.
+func (r *IngressReconciler) skipPaused(ctx context.Context, ingress *networkingv1.Ingress) bool {
+	logger := log.FromContext(ctx)
+	paused := isPaused(ingress) && ingress.DeletionTimestamp.IsZero()
+	if !r.paused.set(client.ObjectKeyFromObject(ingress), paused, pausedIngresses) {
+		return paused
+	}
+	if paused {
+		logger.Info("Ingress is paused, not reconciling")
+		r.eventOnce(ingress, corev1.EventTypeNormal, ReasonPaused, "",
+			"Reconciliation paused by %s; no AWS calls or annotation updates until it is removed", pausedAnnotation)
+	} else {
+		logger.Info("Ingress is no longer paused, reconciling")
+		r.forgetEvent(client.ObjectKeyFromObject(ingress), ReasonPaused)
+	}
+	return paused
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestPausedIngressIsNotReconciled(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{pausedAnnotation: "true"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	for i := 0; i < 2; i++ {
+		if result, err := r.Reconcile(ctx, requestFor(ingress)); err != nil || result.RequeueAfter != 0 {
+			t.Fatalf("Reconcile: %+v, %v", result, err)
+		}
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("a paused Ingress must not call AWS, got %d requests", len(fakeACM.requests))
+	}
+	got := getIngress(t, r, ingress)
+	if len(got.Finalizers) != 0 || len(got.Annotations) != len(ingress.Annotations) {
+		t.Fatalf("a paused Ingress must not be patched, got %v %v", got.Finalizers, got.Annotations)
+	}
+	var paused []string
+	for _, e := range drainEvents(r.Recorder.(*record.FakeRecorder)) {
+		if strings.Contains(e, ReasonPaused) {
+			paused = append(paused, e)
+		}
+	}
+	if len(paused) != 1 {
+		t.Fatalf("expected one Paused event, got %v", paused)
+	}
+	if v := metricValue(t, pausedIngresses); v != 1 {
+		t.Fatalf("acm_manager_paused_ingresses = %v, want 1", v)
+	}
+
+	// Unpausing reconciles in full
+	patch := client.MergeFrom(got.DeepCopy())
+	delete(got.Annotations, pausedAnnotation)
+	if err := r.Patch(ctx, got, patch); err != nil {
+		t.Fatalf("patch ingress: %v", err)
+	}
+	if !ignoreStatusAnnotationUpdates().Update(event.UpdateEvent{ObjectOld: ingress, ObjectNew: got}) {
+		t.Fatal("unpausing must trigger a reconcile")
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after unpausing: %v", err)
+	}
+	if len(fakeACM.requests) != 1 || certificateArnOf(t, r, ingress) == "" {
+		t.Fatalf("expected the unpaused Ingress to get a certificate, got %d requests", len(fakeACM.requests))
+	}
+	if v := metricValue(t, pausedIngresses); v != 0 {
+		t.Fatalf("acm_manager_paused_ingresses = %v, want 0", v)
+	}
+}
+
+func TestPausedIngressStillFinalizesOnDelete(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/delete-cert-on-ingress-delete": "true"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+
+	got := getIngress(t, r, ingress)
+	patch := client.MergeFrom(got.DeepCopy())
+	got.Annotations[pausedAnnotation] = "true"
+	if err := r.Patch(ctx, got, patch); err != nil {
+		t.Fatalf("patch ingress: %v", err)
+	}
+	if err := r.Delete(ctx, got); err != nil {
+		t.Fatalf("delete ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after delete: %v", err)
+	}
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, got); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the finalizer to be removed, got %v", err)
+	}
+	if !slices.Contains(fakeACM.deleted, arn) {
+		t.Fatalf("expected %s to be deleted, got %v", arn, fakeACM.deleted)
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// pendingCertificatesAnnotation recorded certificates requested for an Ingress that never
+// reached ISSUED before they moved to bookkeepingAnnotation
+const pendingCertificatesAnnotation = "acm.tedens.dev/pending-certificate-arns"
+
+// pendingCertificateArns returns the certificates requested for the Ingress that never reached
+// ISSUED, so they can be removed once a replacement issues or the Ingress goes away
+func pendingCertificateArns(ingress *networkingv1.Ingress) []string {
+	return readBookkeeping(ingress).PendingCertificateArns
+}
+
+func setPendingCertificateArns(ingress *networkingv1.Ingress, arns []string) {
+	updateBookkeeping(ingress, func(b *bookkeeping) { b.PendingCertificateArns = arns })
+}
+
+// trackPendingCertificate adds certArn to the Ingress' pending certificates
+func (r *IngressReconciler) trackPendingCertificate(ctx context.Context, ingress *networkingv1.Ingress, certArn string) error {
+	arns := pendingCertificateArns(ingress)
+	for _, arn := range arns {
+		if arn == certArn {
+			return nil
+		}
+	}
+
+	patch := client.MergeFrom(ingress.DeepCopy())
+	setPendingCertificateArns(ingress, append(arns, certArn))
+	return r.patchIngress(ctx, ingress, patch)
+}
+
+// cleanupPendingCertificates deletes the tracked pending certificates other than keep and
+// returns the ARNs that could not be deleted and must stay tracked
+func (r *IngressReconciler) cleanupPendingCertificates(ctx context.Context, arns []string, keep string) []string {
+	logger := log.FromContext(ctx)
+
+	var remaining []string
+	for _, arn := range arns {
+		if arn == keep {
+			continue
+		}
+		if err := r.deletePendingCertificate(ctx, arn); err != nil {
+			logger.Error(err, "failed to delete pending certificate", "arn", arn)
+			remaining = append(remaining, arn)
+		}
+	}
+	return remaining
+}
+
+// deletePendingCertificate deletes certArn if this instance owns it and it is still
+// PENDING_VALIDATION and unused. Certificates that are gone or have since issued are left alone.
+func (r *IngressReconciler) deletePendingCertificate(ctx context.Context, certArn string) error {
+	logger := log.FromContext(ctx)
+
+	describe, err := r.acm(ctx).DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+		CertificateArn: aws.String(certArn),
+	})
+	if err != nil {
+		var notFound *acmtypes.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return err
+	}
+
+	if describe.Certificate.Status != acmtypes.CertificateStatusPendingValidation || len(describe.Certificate.InUseBy) > 0 {
+		logger.Info("Pending certificate is no longer pending or is in use, leaving it", "arn", certArn, "status", describe.Certificate.Status)
+		return nil
+	}
+
+	owned, err := r.ownsCertificate(ctx, certArn)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return nil
+	}
+
+	logger.Info("Deleting never-issued pending certificate", "arn", certArn)
+	_, err = r.acm(ctx).DeleteCertificate(ctx, &acm.DeleteCertificateInput{
+		CertificateArn: aws.String(certArn),
+	})
+	return err
+}
+
+// ingressWanted reports whether the Ingress still exists and is not being deleted or replaced,
+// read from the cache so the validation wait can check it at every poll. Errors other than
+// NotFound keep the wait going.
+func (r *IngressReconciler) ingressWanted(ingress *networkingv1.Ingress) func(context.Context) bool {
+	key := client.ObjectKeyFromObject(ingress)
+	uid := ingress.UID
+	return func(ctx context.Context) bool {
+		var current networkingv1.Ingress
+		if err := r.Get(ctx, key, &current); err != nil {
+			return !apierrors.IsNotFound(err)
+		}
+		return current.UID == uid && current.DeletionTimestamp.IsZero()
+	}
+}
+
+// abandonCertificate deletes a certificate whose validation wait stopped because its Ingress
+// went away. One that cannot be deleted is left to the pending certificate sweep.
+func (r *IngressReconciler) abandonCertificate(ctx context.Context, certArn string) {
+	if certArn == "" {
+		return
+	}
+	if err := r.deletePendingCertificate(ctx, certArn); err != nil {
+		log.FromContext(ctx).Error(err, "failed to delete the certificate of a gone Ingress", "arn", certArn)
+	}
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// shortValidationWait shrinks the validation polling window for the duration of a test
+func shortValidationWait(t *testing.T) {
+	t.Helper()
+	timeout, interval := validationTimeout, validationPollInterval
+	validationTimeout, validationPollInterval = 20*time.Millisecond, time.Millisecond
+	t.Cleanup(func() {
+		validationTimeout, validationPollInterval = timeout, interval
+	})
+}
+
+func TestTimedOutCertificateIsDeletedWithIngress(t *testing.T) {
+	shortValidationWait(t)
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.holdPending = true
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err == nil {
+		t.Fatal("expected validation timeout error")
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one certificate request, got %d", len(fakeACM.requests))
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	pending := pendingCertificateArns(&got)
+	if len(pending) != 1 {
+		t.Fatalf("expected the timed-out certificate to be tracked, got %v", pending)
+	}
+
+	// delete-cert-on-ingress-delete is off, the pending certificate must still go
+	if err := r.Delete(ctx, &got); err != nil {
+		t.Fatalf("delete ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after delete: %v", err)
+	}
+	if len(fakeACM.deleted) != 1 || fakeACM.deleted[0] != pending[0] {
+		t.Fatalf("expected %s to be deleted, deleted %v", pending[0], fakeACM.deleted)
+	}
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected ingress to be gone after finalizer removal, got %v", err)
+	}
+}
+
+func TestValidationWaitStopsWhenIngressIsDeleted(t *testing.T) {
+	timeout, interval := validationTimeout, validationPollInterval
+	validationTimeout, validationPollInterval = time.Minute, time.Millisecond
+	t.Cleanup(func() {
+		validationTimeout, validationPollInterval = timeout, interval
+	})
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.holdPending = true
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	// Delete the Ingress once the certificate is waited for
+	go func() {
+		for {
+			fakeACM.mu.Lock()
+			requested := len(fakeACM.requests)
+			fakeACM.mu.Unlock()
+			if requested > 0 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		var got networkingv1.Ingress
+		if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err == nil {
+			_ = r.Delete(ctx, &got)
+		}
+	}()
+
+	start := time.Now()
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Fatalf("Reconcile waited %v, want it to stop once the Ingress is deleted", elapsed)
+	}
+	if len(fakeACM.requests) != 1 || len(fakeACM.deleted) != 1 {
+		t.Fatalf("requested %d and deleted %v, want the pending certificate deleted", len(fakeACM.requests), fakeACM.deleted)
+	}
+}
+
+func TestPendingCertificateDeletedWhenReplacementIssues(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	stale := fakeACM.addCert("old.example.com", acmtypes.CertificateStatusPendingValidation, map[string]string{"ManagedBy": "acm-manager"})
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{pendingCertificatesAnnotation: stale})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.deleted) != 1 || fakeACM.deleted[0] != stale {
+		t.Fatalf("expected stale pending certificate %s to be deleted, deleted %v", stale, fakeACM.deleted)
+	}
+
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if pending := pendingCertificateArns(&got); len(pending) != 0 {
+		t.Fatalf("pending certificates should be cleared, got %v", pending)
+	}
+}
+
+func TestPendingCertificateGCSweep(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	old := time.Now().Add(-96 * time.Hour)
+	young := time.Now().Add(-time.Hour)
+	owned := map[string]string{"ManagedBy": "acm-manager"}
+
+	stale := fakeACM.addCert("a.example.com", acmtypes.CertificateStatusPendingValidation, owned)
+	fakeACM.certs[stale].CreatedAt = &old
+	recent := fakeACM.addCert("b.example.com", acmtypes.CertificateStatusPendingValidation, owned)
+	fakeACM.certs[recent].CreatedAt = &young
+	foreign := fakeACM.addCert("c.example.com", acmtypes.CertificateStatusPendingValidation, map[string]string{"ManagedBy": "someone-else"})
+	fakeACM.certs[foreign].CreatedAt = &old
+	inUse := fakeACM.addCert("d.example.com", acmtypes.CertificateStatusPendingValidation, owned)
+	fakeACM.certs[inUse].CreatedAt = &old
+	fakeACM.certs[inUse].InUseBy = []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/x"}
+
+	gc := &PendingCertificateGC{ACMClient: fakeACM, ManagedByValue: "acm-manager", MaxAge: 72 * time.Hour}
+	if err := gc.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if len(fakeACM.deleted) != 1 || fakeACM.deleted[0] != stale {
+		t.Fatalf("expected only %s to be swept, deleted %v", stale, fakeACM.deleted)
+	}
+}
+
+func TestPendingCertificateGCRequiredTags(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	old := time.Now().Add(-96 * time.Hour)
+	untagged := fakeACM.addCert("a.example.com", acmtypes.CertificateStatusPendingValidation, map[string]string{"ManagedBy": "acm-manager"})
+	fakeACM.certs[untagged].CreatedAt = &old
+	tagged := fakeACM.addCert("b.example.com", acmtypes.CertificateStatusPendingValidation, map[string]string{"ManagedBy": "acm-manager", "CostCenter": "42"})
+	fakeACM.certs[tagged].CreatedAt = &old
+
+	gc := &PendingCertificateGC{ACMClient: fakeACM, ManagedByValue: "acm-manager", RequiredTags: map[string]string{"CostCenter": "42"}, MaxAge: 72 * time.Hour}
+	if err := gc.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if len(fakeACM.deleted) != 1 || fakeACM.deleted[0] != tagged {
+		t.Fatalf("expected only %s, which carries the required tags, to be swept, deleted %v", tagged, fakeACM.deleted)
+	}
+}
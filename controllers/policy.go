@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// loadPolicy returns the cluster-wide annotation defaults from the policy ConfigMap, or nil
+// when no policy is configured or the ConfigMap does not exist
+func (r *IngressReconciler) loadPolicy(ctx context.Context) (map[string]string, error) {
+	if r.PolicyConfigMap.Name == "" {
+		return nil, nil
+	}
+
+	var cm corev1.ConfigMap
+	if err := r.Get(ctx, r.PolicyConfigMap, &cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.FromContext(ctx).Info("Policy ConfigMap not found, using built-in defaults", "configmap", r.PolicyConfigMap)
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cm.Data, nil
+}
+
+// isPolicyConfigMap reports whether obj is the configured policy ConfigMap
+func (r *IngressReconciler) isPolicyConfigMap(obj client.Object) bool {
+	return r.PolicyConfigMap.Name != "" &&
+		obj.GetNamespace() == r.PolicyConfigMap.Namespace && obj.GetName() == r.PolicyConfigMap.Name
+}
+
+// enqueueManagedIngressesForPolicy requeues every managed Ingress when the policy ConfigMap changes
+func (r *IngressReconciler) enqueueManagedIngressesForPolicy(ctx context.Context, obj client.Object) []reconcile.Request {
+	if !r.isPolicyConfigMap(obj) {
+		return nil
+	}
+	return r.managedIngressRequests(ctx)
+}
+
+// managedIngressRequests returns a request for every managed Ingress
+func (r *IngressReconciler) managedIngressRequests(ctx context.Context) []reconcile.Request {
+	var ingresses networkingv1.IngressList
+	if err := r.List(ctx, &ingresses); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ingresses for policy change")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ing := range ingresses.Items {
+		cfg, err := r.ingressConfig(ctx, &ing, nil)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to load namespace defaults", "ingress", ing.Namespace+"/"+ing.Name)
+			continue
+		}
+		if !cfg.Managed {
+			continue
+		}
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}})
+	}
+	return requests
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestReconcileAppliesPolicyConfigMap(t *testing.T) {
+	ctx := context.Background()
+	policy := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "acm-manager", Name: "policy"},
+		Data: map[string]string{
+			"key-algorithm": "EC_prime256v1",
+			"tags":          "team=platform",
+		},
+	}
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	fakeACM := newFakeACM()
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress, policy)
+	r.PolicyConfigMap = types.NamespacedName{Namespace: "acm-manager", Name: "policy"}
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(fakeACM.requests))
+	}
+	req := fakeACM.requests[0]
+	if req.KeyAlgorithm != acmtypes.KeyAlgorithmEcPrime256v1 {
+		t.Fatalf("KeyAlgorithm = %q, want policy default", req.KeyAlgorithm)
+	}
+	var team string
+	for _, tag := range req.Tags {
+		if aws.ToString(tag.Key) == "team" {
+			team = aws.ToString(tag.Value)
+		}
+	}
+	if team != "platform" {
+		t.Fatalf("expected team=platform tag, got %+v", req.Tags)
+	}
+}
+
+func TestPolicyChangeEnqueuesManagedIngresses(t *testing.T) {
+	ctx := context.Background()
+	policy := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "acm-manager", Name: "policy"}}
+	managed := newManagedIngress("web", "app.example.com", nil)
+	unmanaged := newManagedIngress("other", "other.example.com", map[string]string{"acm.tedens.dev/managed": "false"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53(), managed, unmanaged)
+	r.PolicyConfigMap = types.NamespacedName{Namespace: "acm-manager", Name: "policy"}
+
+	requests := r.enqueueManagedIngressesForPolicy(ctx, policy)
+	if len(requests) != 1 || requests[0].Name != "web" {
+		t.Fatalf("expected only the managed ingress to be enqueued, got %v", requests)
+	}
+
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "acm-manager", Name: "unrelated"}}
+	if got := r.enqueueManagedIngressesForPolicy(ctx, other); len(got) != 0 {
+		t.Fatalf("unrelated ConfigMap should enqueue nothing, got %v", got)
+	}
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/smithy-go"
+	"github.com/tedens/acm-manager/pkg/certs"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// PreflightPath is where PreflightHandler serves the preflight results on the metrics server
+const PreflightPath = "/preflight"
+
+// DefaultPreflightInterval is how often failing preflight probes are run again
+const DefaultPreflightInterval = 30 * time.Second
+
+// Preflight result statuses
+const (
+	PreflightOK        = "ok"
+	PreflightMissing   = "missing"
+	PreflightError     = "error"
+	PreflightNotProbed = "not-probed"
+)
+
+// PreflightResult is the outcome of probing one IAM permission
+type PreflightResult struct {
+	Permission string `json:"permission"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// preflightNotProbed are needed permissions without a call that can safely test them: they
+// mutate and have no dry run. Check them with IAM policy simulation instead.
+var preflightNotProbed = []string{
+	"acm:RequestCertificate",
+	"acm:DeleteCertificate",
+	"acm:AddTagsToCertificate",
+	"route53:ChangeResourceRecordSets",
+}
+
+// Preflight probes the read-only AWS permissions of the controller's own credentials with
+// cheap calls, logs one summary, and fails readiness until every probe passes. It runs on
+// every replica and stops probing once the probes pass.
+type Preflight struct {
+	ACM ACMAPI
+	// Route53 is probed unless nil, when Route 53 is disabled or not the default provider
+	Route53 Route53API
+	// Interval between runs while probes fail; zero uses DefaultPreflightInterval
+	Interval time.Duration
+
+	mu      sync.Mutex
+	ran     bool
+	results []PreflightResult
+}
+
+// NewPreflight returns the preflight of the reconciler's AWS clients; it must run after
+// SetupWithManager built them
+func (r *IngressReconciler) NewPreflight() *Preflight {
+	p := &Preflight{ACM: r.ACMClient}
+	provider, ok := r.DNSProvider.(*certs.Route53Provider)
+	usesRoute53 := r.DefaultDNSProvider == "" || r.DefaultDNSProvider == DNSProviderRoute53
+	if ok && !r.NoRoute53 && usesRoute53 {
+		p.Route53 = provider.Client
+	}
+	return p
+}
+
+// NeedLeaderElection runs the probes on every replica, since each one reports readiness
+func (p *Preflight) NeedLeaderElection() bool {
+	return false
+}
+
+// Start runs the probes until they pass or ctx is cancelled
+func (p *Preflight) Start(ctx context.Context) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultPreflightInterval
+	}
+	for {
+		if preflightPassed(p.Run(ctx)) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Run probes every permission once, stores the results and logs their summary when it
+// changed since the previous run
+func (p *Preflight) Run(ctx context.Context) []PreflightResult {
+	results := []PreflightResult{probePermission("acm:ListCertificates", func() error {
+		_, err := p.ACM.ListCertificates(ctx, &acm.ListCertificatesInput{MaxItems: aws.Int32(1)})
+		return err
+	})}
+	if p.Route53 != nil {
+		results = append(results, probePermission("route53:ListHostedZones", func() error {
+			_, err := p.Route53.ListHostedZones(ctx, &route53.ListHostedZonesInput{MaxItems: aws.Int32(1)})
+			return err
+		}))
+	}
+	for _, permission := range preflightNotProbed {
+		if strings.HasPrefix(permission, "route53:") && p.Route53 == nil {
+			continue
+		}
+		results = append(results, PreflightResult{Permission: permission, Status: PreflightNotProbed})
+	}
+
+	p.mu.Lock()
+	changed := !p.ran || !slices.Equal(p.results, results)
+	p.ran = true
+	p.results = results
+	p.mu.Unlock()
+	if changed {
+		logPreflight(ctx, results)
+	}
+	return results
+}
+
+// probePermission runs call and classifies its error
+func probePermission(permission string, call func() error) PreflightResult {
+	err := call()
+	if err == nil {
+		return PreflightResult{Permission: permission, Status: PreflightOK}
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "AccessDenied", "AccessDeniedException", "UnauthorizedOperation":
+			return PreflightResult{Permission: permission, Status: PreflightMissing, Detail: apiErr.ErrorMessage()}
+		}
+	}
+	return PreflightResult{Permission: permission, Status: PreflightError, Detail: err.Error()}
+}
+
+// preflightPassed reports whether no probe failed
+func preflightPassed(results []PreflightResult) bool {
+	for _, result := range results {
+		if result.Status == PreflightMissing || result.Status == PreflightError {
+			return false
+		}
+	}
+	return true
+}
+
+// permissionsWith returns the permissions of results with status
+func permissionsWith(results []PreflightResult, status string) []string {
+	var permissions []string
+	for _, result := range results {
+		if result.Status == status {
+			permissions = append(permissions, result.Permission)
+		}
+	}
+	return permissions
+}
+
+func logPreflight(ctx context.Context, results []PreflightResult) {
+	logger := log.FromContext(ctx).WithName("preflight")
+	notProbed := permissionsWith(results, PreflightNotProbed)
+	if preflightPassed(results) {
+		logger.Info("Preflight passed", "ok", permissionsWith(results, PreflightOK), "notProbed", notProbed)
+		return
+	}
+	var failures []string
+	for _, result := range results {
+		if result.Status == PreflightMissing || result.Status == PreflightError {
+			failures = append(failures, fmt.Sprintf("%s (%s: %s)", result.Permission, result.Status, result.Detail))
+		}
+	}
+	logger.Error(errors.New(strings.Join(failures, "; ")), "Preflight failed, not ready until the controller's IAM role allows these calls",
+		"missing", permissionsWith(results, PreflightMissing), "errors", permissionsWith(results, PreflightError), "notProbed", notProbed)
+}
+
+// ReadyCheck is a readiness check failing until the probes passed
+func (p *Preflight) ReadyCheck(_ *http.Request) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.ran {
+		return errors.New("preflight has not run yet")
+	}
+	if !preflightPassed(p.results) {
+		return fmt.Errorf("preflight failed: missing %v, errors %v; see %s",
+			permissionsWith(p.results, PreflightMissing), permissionsWith(p.results, PreflightError), PreflightPath)
+	}
+	return nil
+}
+
+// PreflightHandler serves the latest results as JSON, with 503 while the probes fail
+func (p *Preflight) PreflightHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		p.mu.Lock()
+		body := struct {
+			Passed  bool              `json:"passed"`
+			Results []PreflightResult `json:"results"`
+		}{Passed: p.ran && preflightPassed(p.results), Results: p.results}
+		p.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !body.Passed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/smithy-go"
+)
+
+// deniedACM fails ListCertificates with err
+type deniedACM struct {
+	*fakeACM
+	err error
+}
+
+func (d *deniedACM) ListCertificates(ctx context.Context, in *acm.ListCertificatesInput, optFns ...func(*acm.Options)) (*acm.ListCertificatesOutput, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d.fakeACM.ListCertificates(ctx, in, optFns...)
+}
+
+func TestPreflightGatesReadiness(t *testing.T) {
+	ctx := context.Background()
+	acmClient := &deniedACM{fakeACM: newFakeACM(), err: &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized to perform acm:ListCertificates"}}
+	r := newTestReconciler(t, acmClient, newFakeRoute53("example.com"))
+	p := r.NewPreflight()
+
+	if err := p.ReadyCheck(nil); err == nil {
+		t.Fatal("readiness must wait for the first run")
+	}
+	results := p.Run(ctx)
+	if got := permissionsWith(results, PreflightMissing); !slices.Equal(got, []string{"acm:ListCertificates"}) {
+		t.Fatalf("missing = %v, want acm:ListCertificates", got)
+	}
+	if got := permissionsWith(results, PreflightOK); !slices.Equal(got, []string{"route53:ListHostedZones"}) {
+		t.Fatalf("ok = %v, want route53:ListHostedZones", got)
+	}
+	if !slices.Contains(permissionsWith(results, PreflightNotProbed), "route53:ChangeResourceRecordSets") {
+		t.Fatalf("mutating permissions must be listed as not probed, got %+v", results)
+	}
+	if err := p.ReadyCheck(nil); err == nil {
+		t.Fatal("a missing permission must fail readiness")
+	}
+
+	rec := httptest.NewRecorder()
+	p.PreflightHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, PreflightPath, nil))
+	var body struct {
+		Passed  bool              `json:"passed"`
+		Results []PreflightResult `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || rec.Code != http.StatusServiceUnavailable || body.Passed {
+		t.Fatalf("expected a failing preflight report, got %d %+v, %v", rec.Code, body, err)
+	}
+
+	// Fixing the role lets the next run pass
+	acmClient.err = nil
+	if !preflightPassed(p.Run(ctx)) {
+		t.Fatal("expected the preflight to pass")
+	}
+	if err := p.ReadyCheck(nil); err != nil {
+		t.Fatalf("ReadyCheck after passing: %v", err)
+	}
+}
+
+func TestPreflightSkipsRoute53WhenUnused(t *testing.T) {
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"))
+	r.NoRoute53 = true
+	for _, result := range r.NewPreflight().Run(context.Background()) {
+		if result.Permission == "route53:ListHostedZones" || result.Permission == "route53:ChangeResourceRecordSets" {
+			t.Fatalf("Route 53 must not be probed with --no-route53, got %+v", result)
+		}
+	}
+}
+
+func TestPreflightReportsOtherErrors(t *testing.T) {
+	result := probePermission("acm:ListCertificates", func() error {
+		return errors.New("failed to refresh cached credentials")
+	})
+	if result.Status != PreflightError || preflightPassed([]PreflightResult{result}) {
+		t.Fatalf("a credential failure must fail the preflight, got %+v", result)
+	}
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// primaryRuleIndexAnnotation selects the rule whose host is the certificate's primary domain
+const primaryRuleIndexAnnotation = "acm.tedens.dev/primary-rule-index"
+
+// primaryRuleIndex returns the index of the rule providing the primary domain, 0 unless
+// acm.tedens.dev/primary-rule-index selects a rule with a host
+func primaryRuleIndex(ingress *networkingv1.Ingress, cfg IngressConfig) (int, error) {
+	if cfg.PrimaryRuleIndex == "" {
+		return 0, nil
+	}
+	index, err := strconv.Atoi(cfg.PrimaryRuleIndex)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: must be a rule index", primaryRuleIndexAnnotation, cfg.PrimaryRuleIndex)
+	}
+	if index < 0 || index >= len(ingress.Spec.Rules) {
+		return 0, fmt.Errorf("%s %d is out of range: the Ingress has %d rules", primaryRuleIndexAnnotation, index, len(ingress.Spec.Rules))
+	}
+	if strings.TrimSpace(ingress.Spec.Rules[index].Host) == "" {
+		return 0, fmt.Errorf("%s %d selects a rule without a host", primaryRuleIndexAnnotation, index)
+	}
+	return index, nil
+}
+
+// applyPrimaryRule adds the hosts of the other rules to cfg's SANs when
+// acm.tedens.dev/primary-rule-index selects the primary domain, so resolveDomain takes the
+// selected host. An explicit or derived domain wins over the index.
+func applyPrimaryRule(ingress *networkingv1.Ingress, cfg *IngressConfig) error {
+	if cfg.PrimaryRuleIndex == "" || cfg.DomainOverride != "" {
+		return nil
+	}
+	index, err := primaryRuleIndex(ingress, *cfg)
+	if err != nil {
+		return err
+	}
+	domain := strings.ToLower(resolveDomain(ingress, *cfg))
+	for i, rule := range ingress.Spec.Rules {
+		host := strings.ToLower(qualifyHost(strings.TrimSpace(rule.Host), cfg.DomainSuffix))
+		if i == index || host == "" || host == domain || slices.Contains(cfg.SANs, host) {
+			continue
+		}
+		cfg.SANs = append(cfg.SANs, host)
+	}
+	return nil
+}
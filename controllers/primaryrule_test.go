@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// newMultiRuleIngress returns a managed Ingress with one rule per host
+func newMultiRuleIngress(name string, annotations map[string]string, hosts ...string) *networkingv1.Ingress {
+	ingress := newManagedIngress(name, hosts[0], annotations)
+	for _, host := range hosts[1:] {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{Host: host})
+	}
+	return ingress
+}
+
+func TestPrimaryRuleIndexSelectsDomain(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newMultiRuleIngress("web", map[string]string{primaryRuleIndexAnnotation: "2"},
+		"api.example.com", "www.example.com", "app.example.com", "api.example.com")
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected 1 certificate request, got %d", len(fakeACM.requests))
+	}
+	req := fakeACM.requests[0]
+	if got := aws.ToString(req.DomainName); got != "app.example.com" {
+		t.Fatalf("domain = %q, want the host of rule 2", got)
+	}
+	if want := []string{"api.example.com", "www.example.com"}; !slices.Equal(req.SubjectAlternativeNames, want) {
+		t.Fatalf("SANs = %v, want the other rule hosts %v", req.SubjectAlternativeNames, want)
+	}
+}
+
+func TestPrimaryRuleIndexOutOfRange(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	for name, index := range map[string]string{"web": "2", "api": "-1", "www": "first"} {
+		ingress := newMultiRuleIngress(name, map[string]string{primaryRuleIndexAnnotation: index},
+			"api.example.com", "www.example.com")
+		r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err == nil {
+			t.Errorf("expected index %q to be rejected", index)
+		}
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("an invalid index must not request certificates, got %d", len(fakeACM.requests))
+	}
+}
+
+func TestPrimaryRuleIndexDomainWins(t *testing.T) {
+	ingress := newMultiRuleIngress("web", map[string]string{
+		primaryRuleIndexAnnotation: "1",
+		"acm.tedens.dev/domain":    "example.com",
+	}, "api.example.com", "www.example.com")
+	cfg := ParseIngressAnnotations(ingress.Annotations)
+	if err := applyPrimaryRule(ingress, &cfg); err != nil {
+		t.Fatalf("applyPrimaryRule: %v", err)
+	}
+	if got := resolveDomain(ingress, cfg); got != "example.com" || len(cfg.SANs) != 0 {
+		t.Fatalf("an explicit domain must win over the index, got %q with SANs %v", got, cfg.SANs)
+	}
+}
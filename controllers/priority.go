@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/priorityqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// notAfterAnnotation holds the earliest expiry of the certificates attached to the Ingress, so
+// Ingresses close to expiry can be queued first without asking ACM
+const notAfterAnnotation = "acm.tedens.dev/certificate-not-after"
+
+// expiringPriority is the queue priority of an Ingress whose certificate entered its renewal
+// margin; every hour further inside the margin adds one, so the closest to expiry go first
+const expiringPriority = 1000
+
+// maxExpiringHours bounds how much being inside the renewal margin adds to expiringPriority
+const maxExpiringHours = 1 << 20
+
+// earliestNotAfter returns the earliest NotAfter of certificates in RFC 3339, or "" when none
+// has one
+func earliestNotAfter(certificates ...*acmtypes.CertificateDetail) string {
+	var earliest time.Time
+	for _, cert := range certificates {
+		if notAfter := aws.ToTime(cert.NotAfter); !notAfter.IsZero() && (earliest.IsZero() || notAfter.Before(earliest)) {
+			earliest = notAfter
+		}
+	}
+	if earliest.IsZero() {
+		return ""
+	}
+	return earliest.UTC().Format(time.RFC3339)
+}
+
+// setNotAfter patches the certificate-not-after annotation when it differs from notAfter
+func (r *IngressReconciler) setNotAfter(ctx context.Context, ingress *networkingv1.Ingress, notAfter string) error {
+	if notAfter == "" || ingress.Annotations[notAfterAnnotation] == notAfter {
+		return nil
+	}
+	patch := client.MergeFrom(ingress.DeepCopy())
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+	ingress.Annotations[notAfterAnnotation] = notAfter
+	return r.patchIngress(ctx, ingress, patch)
+}
+
+// expiryPriority returns the queue priority of the Ingress from its certificate-not-after
+// annotation and the renewal margin of its acm.tedens.dev/renew-before annotation or
+// RenewBefore, reporting false when its certificate is not inside the margin
+func (r *IngressReconciler) expiryPriority(obj client.Object, now time.Time) (int, bool) {
+	annotations := obj.GetAnnotations()
+	notAfter, err := time.Parse(time.RFC3339, annotations[notAfterAnnotation])
+	if err != nil {
+		return 0, false
+	}
+	renewBefore := r.settings().RenewBefore
+	if renewBefore <= 0 {
+		renewBefore = DefaultRenewBefore
+	}
+	if dur, ok := parseRenewBefore(annotations[renewBeforeAnnotation]); ok {
+		renewBefore = dur
+	}
+
+	inside := renewBefore - notAfter.Sub(now)
+	if inside < 0 {
+		return 0, false
+	}
+	return expiringPriority + int(min(inside/time.Hour, maxExpiringHours)), true
+}
+
+// expiringFirstHandler queues Ingresses whose certificate is inside its renewal margin ahead of
+// everything else when the controller uses a priority queue, so after a restart or resync
+// they do not wait behind the whole fleet. Other Ingresses are passed to next, when set.
+type expiringFirstHandler struct {
+	r    *IngressReconciler
+	next handler.EventHandler
+}
+
+var _ handler.EventHandler = expiringFirstHandler{}
+
+func (h expiringFirstHandler) Create(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	if !h.addExpiring(e.Object, q) && h.next != nil {
+		h.next.Create(ctx, e, q)
+	}
+}
+
+func (h expiringFirstHandler) Update(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	if !h.addExpiring(e.ObjectNew, q) && h.next != nil {
+		h.next.Update(ctx, e, q)
+	}
+}
+
+func (h expiringFirstHandler) Delete(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	if h.next != nil {
+		h.next.Delete(ctx, e, q)
+	}
+}
+
+func (h expiringFirstHandler) Generic(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	if !h.addExpiring(e.Object, q) && h.next != nil {
+		h.next.Generic(ctx, e, q)
+	}
+}
+
+// addExpiring queues obj with its expiry priority and reports whether it did
+func (h expiringFirstHandler) addExpiring(obj client.Object, q workqueue.TypedRateLimitingInterface[reconcile.Request]) bool {
+	pq, ok := q.(priorityqueue.PriorityQueue[reconcile.Request])
+	if !ok || obj == nil {
+		return false
+	}
+	priority, expiring := h.r.expiryPriority(obj, h.r.clock())
+	if !expiring {
+		return false
+	}
+	pq.AddWithOpts(priorityqueue.AddOpts{Priority: priority}, reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()},
+	})
+	return true
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"sigs.k8s.io/controller-runtime/pkg/controller/priorityqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestExpiryPriority(t *testing.T) {
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	r := &IngressReconciler{LiveSettings: LiveSettings{RenewBefore: 30 * 24 * time.Hour}}
+	cases := []struct {
+		annotations map[string]string
+		want        int
+		expiring    bool
+	}{
+		{annotations: nil},
+		{annotations: map[string]string{notAfterAnnotation: "not a time"}},
+		{annotations: map[string]string{notAfterAnnotation: now.Add(60 * 24 * time.Hour).Format(time.RFC3339)}},
+		{annotations: map[string]string{notAfterAnnotation: now.Add(29 * 24 * time.Hour).Format(time.RFC3339)}, want: expiringPriority + 24, expiring: true},
+		{annotations: map[string]string{notAfterAnnotation: now.Add(5 * 24 * time.Hour).Format(time.RFC3339)}, want: expiringPriority + 25*24, expiring: true},
+		{annotations: map[string]string{notAfterAnnotation: now.Add(60 * 24 * time.Hour).Format(time.RFC3339), renewBeforeAnnotation: "1440h"}, want: expiringPriority, expiring: true},
+	}
+	for i, c := range cases {
+		got, expiring := r.expiryPriority(newManagedIngress("web", "app.example.com", c.annotations), now)
+		if got != c.want || expiring != c.expiring {
+			t.Errorf("case %d: got %d, %t, want %d, %t", i, got, expiring, c.want, c.expiring)
+		}
+	}
+}
+
+func TestExpiringIngressesAreQueuedFirst(t *testing.T) {
+	ctx := context.Background()
+	r := &IngressReconciler{}
+	q := priorityqueue.New[reconcile.Request]("test")
+	defer q.ShutDown()
+	// The Ingress watch's own handler queues the initial list with a low priority
+	watch := handler.WithLowPriorityWhenUnchanged(&handler.EnqueueRequestForObject{})
+	expiringFirst := expiringFirstHandler{r: r}
+
+	for i := range 200 {
+		var annotations map[string]string
+		switch i {
+		case 150:
+			annotations = map[string]string{notAfterAnnotation: time.Now().Add(10 * 24 * time.Hour).Format(time.RFC3339)}
+		case 199:
+			annotations = map[string]string{notAfterAnnotation: time.Now().Add(2 * 24 * time.Hour).Format(time.RFC3339)}
+		default:
+			annotations = map[string]string{notAfterAnnotation: time.Now().Add(80 * 24 * time.Hour).Format(time.RFC3339)}
+		}
+		e := event.CreateEvent{Object: newManagedIngress(fmt.Sprintf("web-%d", i), "app.example.com", annotations), IsInInitialList: true}
+		watch.Create(ctx, e, q)
+		expiringFirst.Create(ctx, e, q)
+	}
+
+	for _, want := range []string{"web-199", "web-150"} {
+		item, _, _ := q.GetWithPriority()
+		if item.Name != want {
+			t.Fatalf("expected %s to be reconciled next, got %s", want, item.Name)
+		}
+		q.Done(item)
+	}
+	if q.Len() != 198 {
+		t.Fatalf("expected every Ingress to be queued once, %d left", q.Len())
+	}
+}
+
+func TestResyncQueuesExpiringIngressesFirst(t *testing.T) {
+	ctx := context.Background()
+	r := &IngressReconciler{}
+	q := priorityqueue.New[reconcile.Request]("test")
+	defer q.ShutDown()
+	resync := expiringFirstHandler{r: r, next: &handler.EnqueueRequestForObject{}}
+
+	resync.Generic(ctx, event.GenericEvent{Object: newManagedIngress("healthy", "app.example.com", nil)}, q)
+	resync.Generic(ctx, event.GenericEvent{Object: newManagedIngress("expiring", "shop.example.com", map[string]string{
+		notAfterAnnotation: time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+	})}, q)
+
+	if item, _, _ := q.GetWithPriority(); item.Name != "expiring" {
+		t.Fatalf("expected the expiring Ingress first, got %s", item.Name)
+	}
+	if item, _, _ := q.GetWithPriority(); item.Name != "healthy" {
+		t.Fatalf("expected the healthy Ingress to be queued too, got %s", item.Name)
+	}
+}
+
+func TestReconcileRecordsCertificateExpiry(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	notAfter := time.Now().Add(5 * 24 * time.Hour).UTC().Truncate(time.Second)
+	fakeACM.certs[certificateArnOf(t, r, ingress)].NotAfter = aws.Time(notAfter)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	if got.Annotations[notAfterAnnotation] != notAfter.Format(time.RFC3339) {
+		t.Fatalf("expected the expiry to be recorded, got %q", got.Annotations[notAfterAnnotation])
+	}
+	if _, expiring := r.expiryPriority(got, time.Now()); !expiring {
+		t.Fatal("expected the Ingress to be queued as expiring")
+	}
+}
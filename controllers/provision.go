@@ -0,0 +1,20 @@
+package controllers
+
+const (
+	// albCertificateArnAnnotation is read by the AWS Load Balancer Controller to attach
+	// certificates to the ALB listener
+	albCertificateArnAnnotation = "alb.ingress.kubernetes.io/certificate-arn"
+	// provisionOnlyAnnotation keeps certificates off albCertificateArnAnnotation, for ALBs
+	// configured by other tooling
+	provisionOnlyAnnotation = "acm.tedens.dev/provision-only"
+	// certificateArnAnnotation holds the certificates of provision-only Ingresses
+	certificateArnAnnotation = "acm.tedens.dev/certificate-arn"
+)
+
+// certificateArnKey returns the annotation the Ingress' certificate ARNs are written to
+func certificateArnKey(cfg IngressConfig) string {
+	if cfg.ProvisionOnly {
+		return certificateArnAnnotation
+	}
+	return albCertificateArnAnnotation
+}
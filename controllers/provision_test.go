@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestProvisionOnlyLeavesALBAnnotation(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		provisionOnlyAnnotation:     "true",
+		albCertificateArnAnnotation: "arn:aws:acm:us-east-1:123456789012:certificate/external",
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	arn := got.Annotations[certificateArnAnnotation]
+	if arn == "" || arn == ingress.Annotations[albCertificateArnAnnotation] {
+		t.Fatalf("expected the provisioned ARN in %s, got %q", certificateArnAnnotation, arn)
+	}
+	if alb := got.Annotations[albCertificateArnAnnotation]; alb != ingress.Annotations[albCertificateArnAnnotation] {
+		t.Fatalf("provision-only must not touch the ALB annotation, got %q", alb)
+	}
+	if events := strings.Join(drainEvents(r.Recorder.(*record.FakeRecorder)), "\n"); !strings.Contains(events, ReasonCertificateIssued+" Certificate "+arn) {
+		t.Fatalf("expected an issued event naming the certificate, got %q", events)
+	}
+
+	// The next reconcile finds the provisioned certificate issued instead of requesting another
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one certificate request, got %d", len(fakeACM.requests))
+	}
+}
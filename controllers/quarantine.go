@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tedens/acm-manager/pkg/certs"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// quarantinedAnnotation holds the failure class of an Ingress the controller stopped retrying
+// after too many consecutive failures; removing it or editing the Ingress lifts the quarantine
+const quarantinedAnnotation = "acm.tedens.dev/quarantined"
+
+// DefaultQuarantineProbeInterval is how often a quarantined Ingress is still tried
+const DefaultQuarantineProbeInterval = 6 * time.Hour
+
+// quarantinedIngresses is the number of Ingresses currently quarantined
+var quarantinedIngresses = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "acm_manager_quarantined_ingresses",
+	Help: "Number of Ingresses quarantined after repeated failures that retrying cannot fix.",
+})
+
+// quarantineReason returns the failure class of err when retrying is unlikely to fix it
+// without a change to the Ingress or AWS, or "" for failures that may be transient
+func quarantineReason(err error) string {
+	var validationFailed *certs.ValidationFailedError
+	var tooMany *errTooManyNames
+	var mismatch *certificateMismatchError
+	var tooLong *certs.DomainTooLongError
+	var keyChanged *privateKeyChangedError
+	var redundant *redundantSANError
+	var unsupported *certs.UnsupportedRecordTypeError
+	var apiErr smithy.APIError
+	switch {
+	case errors.Is(err, certs.ErrNoHostedZone):
+		return "NoHostedZone"
+	case errors.As(err, &validationFailed):
+		return "ValidationFailed"
+	case errors.As(err, &tooMany):
+		return "TooManyNames"
+	case errors.As(err, &mismatch):
+		return "CertificateMismatch"
+	case errors.As(err, &tooLong):
+		return "DomainTooLong"
+	case errors.As(err, &keyChanged):
+		return "PrivateKeyChanged"
+	case errors.As(err, &redundant):
+		return "RedundantSAN"
+	case errors.As(err, &unsupported):
+		return "UnsupportedValidationRecord"
+	case errors.As(err, &apiErr):
+		switch apiErr.ErrorCode() {
+		case "AccessDenied", "AccessDeniedException", "UnauthorizedOperation":
+			return "AccessDenied"
+		case "InvalidDomainValidationOptionsException", "InvalidParameterException", "ValidationException":
+			return "InvalidRequest"
+		}
+	}
+	return ""
+}
+
+// quarantineProbeInterval returns QuarantineProbeInterval, or its default when unset
+func (r *IngressReconciler) quarantineProbeInterval() time.Duration {
+	if interval := r.settings().QuarantineProbeInterval; interval > 0 {
+		return interval
+	}
+	return DefaultQuarantineProbeInterval
+}
+
+// quarantine marks the Ingress quarantined when it failed with a terminal err, such as err,
+// QuarantineAfterFailures times in a row, and returns whether it is. The Warning event is recorded only when the
+// quarantine starts.
+func (r *IngressReconciler) quarantine(ingress *networkingv1.Ingress, failures int, err error) bool {
+	reason := quarantineReason(err)
+	if after := r.settings().QuarantineAfterFailures; after <= 0 || failures < after || reason == "" {
+		return false
+	}
+	if _, ok := ingress.Annotations[quarantinedAnnotation]; !ok && r.Recorder != nil {
+		r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonQuarantined,
+			"Quarantined after %d consecutive failures (%s): %s. Retrying only every %s; fix the cause and remove the %s annotation, or edit the Ingress, to retry now",
+			failures, reason, formatLastError(err), r.quarantineProbeInterval(), quarantinedAnnotation)
+	}
+	ingress.Annotations[quarantinedAnnotation] = reason
+	return true
+}
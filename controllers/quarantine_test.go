@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/aws/smithy-go"
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestQuarantineReason(t *testing.T) {
+	cases := map[string]error{
+		"NoHostedZone":     fmt.Errorf("find zone: %w", certs.ErrNoHostedZone),
+		"ValidationFailed": &certs.ValidationFailedError{Reason: acmtypes.FailureReasonCaaError},
+		"TooManyNames":     &errTooManyNames{names: 12, limit: 10},
+		"AccessDenied":     &smithy.GenericAPIError{Code: "AccessDeniedException"},
+		"":                 errors.New("connection reset by peer"),
+	}
+	for want, err := range cases {
+		if got := quarantineReason(err); got != want {
+			t.Errorf("quarantineReason(%v) = %q, want %q", err, got, want)
+		}
+	}
+	if got := quarantineReason(&smithy.GenericAPIError{Code: "ThrottlingException"}); got != "" {
+		t.Errorf("throttling must not quarantine, got %q", got)
+	}
+}
+
+// failUntilQuarantined reconciles the failing Ingress, waiting out each backoff, until it has
+// failed times in a row
+func failUntilQuarantined(t *testing.T, r *IngressReconciler, ingress *networkingv1.Ingress, now *time.Time, times int) {
+	t.Helper()
+	for i := 1; i <= times; i++ {
+		if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err == nil {
+			t.Fatal("expected the validation failure")
+		}
+		if i < times {
+			*now = now.Add(retryBackoffDelay(i))
+		}
+	}
+}
+
+func TestQuarantineAfterRepeatedFailures(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{"app.example.com": acmtypes.DomainStatusFailed}
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.QuarantineAfterFailures = 3
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	failUntilQuarantined(t, r, ingress, &now, 2)
+	got := getIngress(t, r, ingress)
+	if _, ok := got.Annotations[quarantinedAnnotation]; ok {
+		t.Fatal("quarantined before reaching the failure budget")
+	}
+	drainEvents(r.Recorder.(*record.FakeRecorder))
+
+	now = now.Add(retryBackoffDelay(2))
+	failUntilQuarantined(t, r, ingress, &now, 1)
+	got = getIngress(t, r, ingress)
+	if reason := got.Annotations[quarantinedAnnotation]; reason != "ValidationFailed" {
+		t.Fatalf("expected a ValidationFailed quarantine, got %q", reason)
+	}
+	if wait := r.retryWait(ctx, got); wait != DefaultQuarantineProbeInterval {
+		t.Fatalf("a quarantined Ingress must wait for the probe interval, got %s", wait)
+	}
+	var quarantined []string
+	for _, e := range drainEvents(r.Recorder.(*record.FakeRecorder)) {
+		if strings.Contains(e, ReasonQuarantined) {
+			quarantined = append(quarantined, e)
+		}
+	}
+	if len(quarantined) != 1 || !strings.Contains(quarantined[0], quarantinedAnnotation) {
+		t.Fatalf("expected one Quarantined event explaining how to lift it, got %v", quarantined)
+	}
+	if v := metricValue(t, quarantinedIngresses); v != 1 {
+		t.Fatalf("acm_manager_quarantined_ingresses = %v, want 1", v)
+	}
+
+	// The probe still fails and stays quarantined without another event
+	requests := len(fakeACM.requests)
+	now = now.Add(DefaultQuarantineProbeInterval)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err == nil {
+		t.Fatal("expected the probe to fail")
+	}
+	if len(fakeACM.requests) != requests+1 {
+		t.Fatal("expected the probe to retry the Ingress")
+	}
+	if events := drainEvents(r.Recorder.(*record.FakeRecorder)); strings.Contains(strings.Join(events, "\n"), ReasonQuarantined) {
+		t.Fatalf("the quarantine must be announced once, got %v", events)
+	}
+	if r.retryWait(ctx, getIngress(t, r, ingress)) != DefaultQuarantineProbeInterval {
+		t.Fatalf("expected the next probe after the probe interval, got %v", getIngress(t, r, ingress).Annotations)
+	}
+
+	// Removing the annotation retries at once and starts the count over
+	got = getIngress(t, r, ingress)
+	patch := client.MergeFrom(got.DeepCopy())
+	delete(got.Annotations, quarantinedAnnotation)
+	if err := r.Patch(ctx, got, patch); err != nil {
+		t.Fatalf("patch ingress: %v", err)
+	}
+	if wait := r.retryWait(ctx, got); wait != 0 {
+		t.Fatalf("removing the quarantine must retry at once, got a wait of %s", wait)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err == nil {
+		t.Fatal("expected the validation failure")
+	}
+	got = getIngress(t, r, ingress)
+	if backoff := savedRetryBackoff(ctx, got); backoff == nil || backoff.Failures != 1 || backoff.Quarantined {
+		t.Fatalf("expected the failure count to start over, got %+v", backoff)
+	}
+	if _, ok := got.Annotations[quarantinedAnnotation]; ok {
+		t.Fatal("the quarantine must stay lifted until the budget is used up again")
+	}
+	if v := metricValue(t, quarantinedIngresses); v != 0 {
+		t.Fatalf("acm_manager_quarantined_ingresses = %v, want 0", v)
+	}
+}
+
+func TestQuarantineLiftedByEdit(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{"app.example.com": acmtypes.DomainStatusFailed}
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.QuarantineAfterFailures = 2
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	failUntilQuarantined(t, r, ingress, &now, 2)
+	got := getIngress(t, r, ingress)
+	if _, ok := got.Annotations[quarantinedAnnotation]; !ok {
+		t.Fatal("expected the Ingress to be quarantined")
+	}
+
+	// The fix arrives with the edit, so the retry succeeds and clears everything
+	delete(fakeACM.domainStatus, "app.example.com")
+	patch := client.MergeFrom(got.DeepCopy())
+	got.Annotations["acm.tedens.dev/zone-id"] = "Z1"
+	if err := r.Patch(ctx, got, patch); err != nil {
+		t.Fatalf("patch ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("reconcile after the edit: %v", err)
+	}
+	got = getIngress(t, r, ingress)
+	for _, key := range []string{quarantinedAnnotation, retryBackoffAnnotation} {
+		if _, ok := got.Annotations[key]; ok {
+			t.Errorf("expected %s to be removed after a successful reconcile", key)
+		}
+	}
+}
+
+func TestQuarantineSkipsTransientFailures(t *testing.T) {
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.QuarantineAfterFailures = 1
+	if r.quarantine(ingress, 5, &smithy.GenericAPIError{Code: "ThrottlingException"}) {
+		t.Fatal("throttling must never quarantine")
+	}
+	if _, ok := ingress.Annotations[quarantinedAnnotation]; ok {
+		t.Fatal("unexpected quarantine annotation")
+	}
+}
+
+func TestTransientFailureRestartsQuarantineCount(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.QuarantineAfterFailures = 3
+	terminal := &certs.ValidationFailedError{}
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException"}
+
+	for _, err := range []error{terminal, terminal, throttled, terminal, terminal} {
+		r.nextRetryBackoff(ctx, ingress, 0, err)
+	}
+	if _, ok := ingress.Annotations[quarantinedAnnotation]; ok {
+		t.Fatal("throttling in between must restart the count of terminal failures")
+	}
+	backoff := savedRetryBackoff(ctx, ingress)
+	if backoff.Failures != 5 || backoff.Terminal != 2 {
+		t.Fatalf("backoff = %+v, want 5 failures of which the last 2 terminal", backoff)
+	}
+
+	// A deferral restarts the count too
+	if err := r.Update(ctx, ingress); err != nil {
+		t.Fatalf("update ingress: %v", err)
+	}
+	r.resetTerminalFailures(ctx, requestFor(ingress).NamespacedName)
+	got := getIngress(t, r, ingress)
+	r.nextRetryBackoff(ctx, got, 0, terminal)
+	if _, ok := got.Annotations[quarantinedAnnotation]; ok {
+		t.Fatal("a deferral in between must restart the count of terminal failures")
+	}
+	r.nextRetryBackoff(ctx, got, 0, terminal)
+	r.nextRetryBackoff(ctx, got, 0, terminal)
+	if reason := got.Annotations[quarantinedAnnotation]; reason != "ValidationFailed" {
+		t.Fatalf("expected a quarantine after 3 terminal failures in a row, got %q", reason)
+	}
+}
+
+func TestRemovingQuarantineTriggersReconcile(t *testing.T) {
+	old := newManagedIngress("web", "app.example.com", map[string]string{quarantinedAnnotation: "NoHostedZone"})
+	updated := old.DeepCopy()
+	delete(updated.Annotations, quarantinedAnnotation)
+	p := ignoreStatusAnnotationUpdates()
+	if !p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}) {
+		t.Fatal("removing the quarantine must reconcile the Ingress")
+	}
+	if p.Update(event.UpdateEvent{ObjectOld: updated, ObjectNew: old}) {
+		t.Fatal("setting the quarantine must not reconcile the Ingress")
+	}
+}
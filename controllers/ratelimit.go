@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// certificateRequestsDeferred counts certificate requests deferred by the request rate limit
+var certificateRequestsDeferred = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "acm_manager_certificate_requests_deferred_total",
+	Help: "Certificate requests deferred because --max-certificate-requests-per-hour was reached.",
+})
+
+// requestRateLimitedError is returned by certificate requests over the request rate limit
+type requestRateLimitedError struct {
+	perHour int
+	retryAt time.Time
+}
+
+func (e *requestRateLimitedError) Error() string {
+	return fmt.Sprintf("acm:RequestCertificate deferred: the limit of %d certificate requests per hour was reached, retrying at %s",
+		e.perHour, e.retryAt.UTC().Format(time.RFC3339))
+}
+
+// rateLimitedACM defers certificate requests over a token bucket shared by every client
+// wrapped with the same limiter
+type rateLimitedACM struct {
+	ACMAPI
+	limiter *rate.Limiter
+	perHour int
+	now     func() time.Time
+}
+
+func (c *rateLimitedACM) RequestCertificate(ctx context.Context, in *acm.RequestCertificateInput, optFns ...func(*acm.Options)) (*acm.RequestCertificateOutput, error) {
+	now := c.now()
+	if !c.limiter.AllowN(now, 1) {
+		// Reserve only to learn when a token is due, then give it back
+		reservation := c.limiter.ReserveN(now, 1)
+		retryAt := now.Add(reservation.DelayFrom(now))
+		reservation.CancelAt(now)
+		certificateRequestsDeferred.Inc()
+		return nil, &requestRateLimitedError{perHour: c.perHour, retryAt: retryAt}
+	}
+	return c.ACMAPI.RequestCertificate(ctx, in, optFns...)
+}
+
+// requestRateLimitedACMClient returns client, deferring certificate requests over
+// MaxCertificateRequestsPerHour. All clients share one bucket, which holds up to an hour's
+// worth of requests.
+func (r *IngressReconciler) requestRateLimitedACMClient(client ACMAPI) ACMAPI {
+	if r.MaxCertificateRequestsPerHour <= 0 || client == nil {
+		return client
+	}
+	r.requestLimiterOnce.Do(func() {
+		perHour := r.MaxCertificateRequestsPerHour
+		r.requestLimiter = rate.NewLimiter(rate.Limit(float64(perHour)/time.Hour.Seconds()), perHour)
+	})
+	return &rateLimitedACM{ACMAPI: client, limiter: r.requestLimiter, perHour: r.MaxCertificateRequestsPerHour, now: r.clock}
+}
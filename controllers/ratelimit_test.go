@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+)
+
+func TestCertificateRequestRateLimit(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"))
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	r.MaxCertificateRequestsPerHour = 2
+
+	request := func() error {
+		_, err := r.acm(ctx).RequestCertificate(ctx, &acm.RequestCertificateInput{DomainName: aws.String("app.example.com")})
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := request(); err != nil {
+			t.Fatalf("request %d within the limit: %v", i+1, err)
+		}
+	}
+	before := metricValue(t, certificateRequestsDeferred)
+	err := request()
+	var limited *requestRateLimitedError
+	if !errors.As(err, &limited) || !isDeferred(err) {
+		t.Fatalf("expected the third request to be deferred, got %v", err)
+	}
+	if want := now.Add(30 * time.Minute); !limited.retryAt.Equal(want) {
+		t.Errorf("retryAt = %s, want %s", limited.retryAt, want)
+	}
+	if got := metricValue(t, certificateRequestsDeferred) - before; got != 1 {
+		t.Errorf("deferred requests counted %v times, want 1", got)
+	}
+	if len(fakeACM.requests) != 2 {
+		t.Fatalf("a deferred request reached ACM: %d requests", len(fakeACM.requests))
+	}
+
+	// A deferral consumes no token, so the next one is due on time
+	now = now.Add(30 * time.Minute)
+	if err := request(); err != nil {
+		t.Fatalf("request after the refill: %v", err)
+	}
+	if err := request(); !errors.As(err, &limited) {
+		t.Fatalf("expected the bucket to be empty again, got %v", err)
+	}
+}
+
+func TestReconcileRequeuesRateLimitedRequests(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	first := newManagedIngress("first", "first.example.com", nil)
+	second := newManagedIngress("second", "second.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), first, second)
+	r.MaxCertificateRequestsPerHour = 1
+
+	if _, err := r.Reconcile(ctx, requestFor(first)); err != nil {
+		t.Fatalf("Reconcile first: %v", err)
+	}
+	result, err := r.Reconcile(ctx, requestFor(second))
+	if err != nil {
+		t.Fatalf("a deferred request is no failure, got %v", err)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > time.Hour {
+		t.Errorf("RequeueAfter = %s, want the time until the next token", result.RequeueAfter)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(fakeACM.requests))
+	}
+	if got := getIngress(t, r, second); got.Annotations[lastErrorAnnotation] != "" {
+		t.Errorf("deferred request recorded a last error: %q", got.Annotations[lastErrorAnnotation])
+	}
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// recheckInterval is how often an Ingress whose certificate is in place is looked at again
+const recheckInterval = 12 * time.Hour
+
+// Outcomes of a reconcile, the outcome label of the reconcile metrics
+const (
+	reconcileOutcomeSuccess = "success"
+	reconcileOutcomeRequeue = "requeue"
+	reconcileOutcomeError   = "error"
+)
+
+var (
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "acm_manager_reconcile_duration_seconds",
+		Help: "Duration of Ingress reconciles, including any wait for certificate validation, by outcome.",
+		// Reconciles range from a cached no-op to a validation wait of several minutes
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"outcome"})
+	reconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "acm_manager_reconcile_total",
+		Help: "Ingress reconciles, by outcome.",
+	}, []string{"outcome"})
+)
+
+type reconcileFinishedKey struct{}
+
+// withReconcileFinished returns ctx with a flag recheckAfter sets once the reconcile finished
+// its work
+func withReconcileFinished(ctx context.Context) (context.Context, *atomic.Bool) {
+	finished := &atomic.Bool{}
+	return context.WithValue(ctx, reconcileFinishedKey{}, finished), finished
+}
+
+// recheckAfter returns the result of a reconcile that finished its work and only looks at the
+// Ingress again after the given time, as opposed to one waiting for something
+func recheckAfter(ctx context.Context, after time.Duration) ctrl.Result {
+	if finished, ok := ctx.Value(reconcileFinishedKey{}).(*atomic.Bool); ok {
+		finished.Store(true)
+	}
+	return ctrl.Result{RequeueAfter: after}
+}
+
+// reconcileOutcome classifies a reconcile: an error, a requeue to wait for something, such as
+// validation or a backoff, or success, which includes the periodic recheck of a finished one
+func reconcileOutcome(finished bool, result ctrl.Result, err error) string {
+	switch {
+	case err != nil:
+		return reconcileOutcomeError
+	case result.RequeueAfter > 0 && !finished:
+		return reconcileOutcomeRequeue
+	default:
+		return reconcileOutcomeSuccess
+	}
+}
+
+// observeReconcile records a reconcile that took elapsed in the reconcile metrics
+func observeReconcile(elapsed time.Duration, finished bool, result ctrl.Result, err error) {
+	outcome := reconcileOutcome(finished, result, err)
+	reconcileDuration.WithLabelValues(outcome).Observe(elapsed.Seconds())
+	reconcileTotal.WithLabelValues(outcome).Inc()
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileSamples returns how many reconciles with outcome the histogram and the counter saw
+func reconcileSamples(t *testing.T, outcome string) (observed uint64, counted float64) {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(reconcileDuration, reconcileTotal)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if metric.GetLabel()[0].GetValue() != outcome {
+				continue
+			}
+			if metric.GetHistogram() != nil {
+				observed = metric.GetHistogram().GetSampleCount()
+			} else {
+				counted = metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return observed, counted
+}
+
+func TestReconcileDurationObserved(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	beforeObserved, beforeCounted := reconcileSamples(t, reconcileOutcomeSuccess)
+
+	result, err := r.Reconcile(ctx, requestFor(ingress))
+	if err != nil || result.RequeueAfter != recheckInterval {
+		t.Fatalf("Reconcile = %+v, %v; want the periodic recheck", result, err)
+	}
+	observed, counted := reconcileSamples(t, reconcileOutcomeSuccess)
+	if observed != beforeObserved+1 || counted != beforeCounted+1 {
+		t.Fatalf("expected one more successful reconcile, histogram %d -> %d, counter %v -> %v", beforeObserved, observed, beforeCounted, counted)
+	}
+
+	// An Ingress without a hosted zone waits for one
+	beforeObserved, beforeCounted = reconcileSamples(t, reconcileOutcomeRequeue)
+	waiting := newManagedIngress("other", "app.unknown.test", nil)
+	if err := r.Create(ctx, waiting); err != nil {
+		t.Fatalf("create ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(waiting)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if observed, counted := reconcileSamples(t, reconcileOutcomeRequeue); observed != beforeObserved+1 || counted != beforeCounted+1 {
+		t.Fatalf("expected one more requeued reconcile, histogram %d -> %d, counter %v -> %v", beforeObserved, observed, beforeCounted, counted)
+	}
+}
+
+func TestReconcileOutcome(t *testing.T) {
+	cases := []struct {
+		finished bool
+		result   ctrl.Result
+		err      error
+		want     string
+	}{
+		{false, ctrl.Result{}, nil, reconcileOutcomeSuccess},
+		{true, ctrl.Result{RequeueAfter: recheckInterval}, nil, reconcileOutcomeSuccess},
+		{false, ctrl.Result{RequeueAfter: time.Minute}, nil, reconcileOutcomeRequeue},
+		{true, ctrl.Result{RequeueAfter: time.Minute}, errors.New("boom"), reconcileOutcomeError},
+	}
+	for _, c := range cases {
+		if got := reconcileOutcome(c.finished, c.result, c.err); got != c.want {
+			t.Errorf("reconcileOutcome(%v, %+v, %v) = %q, want %q", c.finished, c.result, c.err, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tedens/acm-manager/pkg/certs"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// renewBeforeAnnotation sets how close to NotAfter the controller starts escalating renewal
+const renewBeforeAnnotation = "acm.tedens.dev/renew-before"
+
+// DefaultRenewBefore is the renewal margin used when neither the annotation nor --renew-before
+// sets one (30 days)
+var DefaultRenewBefore = 30 * 24 * time.Hour
+
+// renewalEscalations counts the reconciles that found an attached certificate inside its
+// renewal window, by the ACM renewal status seen
+var renewalEscalations = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "acm_manager_certificate_renewal_escalations_total",
+	Help: "Reconciles that found an attached certificate inside its renew-before window, by ACM renewal status.",
+}, []string{"renewal_status"})
+
+// certificateRenewalEligible and certificateRenewalStatus are the renewal eligibility and
+// status ACM reports for the certificate attached to each managed Ingress
+var (
+	certificateRenewalEligible = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "acm_manager_certificate_renewal_eligible",
+		Help: "Whether ACM can renew the certificate attached to a managed Ingress by itself: 1 when ELIGIBLE, 0 when INELIGIBLE.",
+	}, []string{"namespace", "ingress", "arn"})
+	certificateRenewalStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "acm_manager_certificate_renewal_status",
+		Help: "ACM renewal status of the certificate attached to a managed Ingress, always 1; NONE until ACM starts a renewal.",
+	}, []string{"namespace", "ingress", "arn", "status"})
+)
+
+// RegisterMetrics registers the controller's package-level metrics
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{renewalEscalations, validationExpiredReplacements, certificateRenewalEligible, certificateRenewalStatus, isLeader, leaderTransitions, quarantinedIngresses, pausedIngresses,
+		hostedZoneCacheHits, hostedZoneCacheMisses, hostedZoneCacheMissingDomains, certificateCacheHits, certificateCacheMisses, certificateCacheCertificates, observeOnlySkipped, certificateRequestsDeferred, splitBrainSuspected,
+		reconcileDuration, reconcileTotal, route53Changes, route53ChangeInsync, route53Throttles} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseRenewBefore returns the margin of a renew-before value, reporting false unless it is a
+// positive duration
+func parseRenewBefore(raw string) (time.Duration, bool) {
+	dur, err := time.ParseDuration(strings.TrimSpace(raw))
+	return dur, err == nil && dur > 0
+}
+
+// warnRenewBefore records an InvalidAnnotation Warning, once per value, when the Ingress sets
+// acm.tedens.dev/renew-before to something other than a positive duration
+func (r *IngressReconciler) warnRenewBefore(ingress *networkingv1.Ingress) {
+	raw, set := ingress.Annotations[renewBeforeAnnotation]
+	if _, valid := parseRenewBefore(raw); !set || valid {
+		r.forgetEvent(client.ObjectKeyFromObject(ingress), invalidAnnotationSlot(renewBeforeAnnotation))
+		return
+	}
+	r.warnInvalidAnnotation(ingress, renewBeforeAnnotation, "%s must be a positive duration such as 720h, not %q; the default is used",
+		renewBeforeAnnotation, raw)
+}
+
+// renewBefore returns the renewal margin of cert, falling back to the default with a
+// RenewBeforeTooLong Warning when the configured one is at least the certificate's lifetime
+func (r *IngressReconciler) renewBefore(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, cert *acmtypes.CertificateDetail) time.Duration {
+	fallback := r.settings().RenewBefore
+	if fallback <= 0 {
+		fallback = DefaultRenewBefore
+	}
+	if cfg.RenewBefore <= 0 {
+		r.forgetEvent(client.ObjectKeyFromObject(ingress), ReasonRenewBeforeTooLong)
+		return fallback
+	}
+	if cert.NotBefore != nil && cert.NotAfter != nil {
+		if lifetime := cert.NotAfter.Sub(*cert.NotBefore); cfg.RenewBefore >= lifetime {
+			log.FromContext(ctx).Info("Ignoring renew-before that is not shorter than the certificate lifetime, using the default",
+				"renewBefore", cfg.RenewBefore, "lifetime", lifetime, "default", fallback)
+			r.warnOnce(ingress, ReasonRenewBeforeTooLong, cfg.RenewBefore.String()+" "+lifetime.String(),
+				"%s %s is not shorter than the certificate's lifetime of %s; the default of %s is used",
+				renewBeforeAnnotation, cfg.RenewBefore, lifetime, fallback)
+			return fallback
+		}
+	}
+	r.forgetEvent(client.ObjectKeyFromObject(ingress), ReasonRenewBeforeTooLong)
+	return cfg.RenewBefore
+}
+
+// renewalRequeue returns when to check an issued certificate again: every 12 hours, or at the
+// start of its renewal window when that comes sooner
+func renewalRequeue(notAfter *time.Time, renewBefore time.Duration) time.Duration {
+	requeue := recheckInterval
+	if notAfter == nil {
+		return requeue
+	}
+	if untilWindow := time.Until(notAfter.Add(-renewBefore)); untilWindow > 0 && untilWindow < requeue {
+		return untilWindow
+	}
+	return requeue
+}
+
+// observeRenewal exports the renewal eligibility and status of cert, attached to ingress, and
+// records a Warning event when ACM cannot renew a certificate in use by itself. Imported
+// certificates are never renewed by ACM and are skipped.
+func (r *IngressReconciler) observeRenewal(ingress *networkingv1.Ingress, cert *acmtypes.CertificateDetail) {
+	deleteRenewalMetrics(ingress.Namespace, ingress.Name)
+	if cert.Type == acmtypes.CertificateTypeImported {
+		return
+	}
+	certArn := aws.ToString(cert.CertificateArn)
+	switch cert.RenewalEligibility {
+	case acmtypes.RenewalEligibilityEligible:
+		certificateRenewalEligible.WithLabelValues(ingress.Namespace, ingress.Name, certArn).Set(1)
+	case acmtypes.RenewalEligibilityIneligible:
+		certificateRenewalEligible.WithLabelValues(ingress.Namespace, ingress.Name, certArn).Set(0)
+		// A certificate nothing uses yet, such as one the load balancer has not picked up, is
+		// ineligible without being at risk
+		if len(cert.InUseBy) > 0 && r.Recorder != nil {
+			r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonRenewalIneligible,
+				"Certificate %s is in use but ACM cannot renew it automatically; check that its DNS validation records still exist before it expires at %s",
+				certArn, aws.ToTime(cert.NotAfter).UTC().Format(time.RFC3339))
+		}
+	}
+	renewalStatus := "NONE"
+	if cert.RenewalSummary != nil {
+		renewalStatus = string(cert.RenewalSummary.RenewalStatus)
+	}
+	certificateRenewalStatus.WithLabelValues(ingress.Namespace, ingress.Name, certArn, renewalStatus).Set(1)
+}
+
+// deleteRenewalMetrics drops the renewal series of the Ingress
+func deleteRenewalMetrics(namespace, name string) {
+	labels := prometheus.Labels{"namespace": namespace, "ingress": name}
+	certificateRenewalEligible.DeletePartialMatch(labels)
+	certificateRenewalStatus.DeletePartialMatch(labels)
+}
+
+// forgetCertificateMetrics drops every per-Ingress certificate series of the Ingress
+func (r *IngressReconciler) forgetCertificateMetrics(namespace, name string) {
+	r.CertificateInfo.delete(namespace, name)
+	deleteRenewalMetrics(namespace, name)
+}
+
+// escalateRenewal handles an attached certificate inside its renewal window: unless ACM reports
+// the renewal succeeded it re-creates the DNS validation records ACM needs to renew, then it
+// records an expiring event and bumps the escalation metric
+func (r *IngressReconciler) escalateRenewal(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, domain string, cert *acmtypes.CertificateDetail) error {
+	certArn := aws.ToString(cert.CertificateArn)
+	renewalStatus := "NONE"
+	if cert.RenewalSummary != nil {
+		renewalStatus = string(cert.RenewalSummary.RenewalStatus)
+	}
+	renewalEscalations.WithLabelValues(renewalStatus).Inc()
+	reason := fmt.Sprintf("expires at %s, renewal status %s", cert.NotAfter.UTC().Format(time.RFC3339), renewalStatus)
+	r.notifyCertificateEvent(ingress, NotificationExpiring, domain, certArn, string(cert.Status), reason)
+
+	// Imported certificates are never renewed by ACM
+	if renewalStatus == string(acmtypes.RenewalStatusSuccess) || cert.Type == acmtypes.CertificateTypeImported {
+		return nil
+	}
+	dnsProvider, err := r.dnsProviderFor(ctx, ingress, cfg)
+	if err != nil {
+		return err
+	}
+	records, err := certs.ValidationRecords(ctx, r.acm(ctx), certArn)
+	if err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("Re-creating validation records for renewal", "arn", certArn, "renewalStatus", renewalStatus)
+	if err := dnsProvider.EnsureRecords(ctx, cfg.ZoneID, records); err != nil {
+		return fmt.Errorf("failed to re-create validation records of %s for renewal: %w", certArn, err)
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/tools/record"
+)
+
+// renewalEscalationCount returns the acm_manager_certificate_renewal_escalations_total value for status
+func renewalEscalationCount(t *testing.T, status string) float64 {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(renewalEscalations)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, l := range metric.GetLabel() {
+				if l.GetName() == "renewal_status" && l.GetValue() == status {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestRenewBeforeEscalatesInsideWindow(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeR53 := newFakeRoute53("example.com")
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{renewBeforeAnnotation: "960h"})
+	r := newTestReconciler(t, fakeACM, fakeR53, ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+	for events := r.Recorder.(*record.FakeRecorder).Events; len(events) > 0; {
+		<-events
+	}
+
+	// 35 days left: outside the default 30 day window but inside the annotated 40 days
+	cert := fakeACM.certs[arn]
+	cert.NotBefore = aws.Time(time.Now().Add(-360 * 24 * time.Hour))
+	cert.NotAfter = aws.Time(time.Now().Add(35 * 24 * time.Hour))
+	cert.RenewalSummary = &acmtypes.RenewalSummary{RenewalStatus: acmtypes.RenewalStatusPendingValidation}
+	changes := len(fakeR53.changes)
+	escalations := renewalEscalationCount(t, "PENDING_VALIDATION")
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile inside the renewal window: %v", err)
+	}
+	if len(fakeR53.changes) != changes+1 {
+		t.Fatalf("expected the validation records to be re-created, got %d new changes", len(fakeR53.changes)-changes)
+	}
+	if got := renewalEscalationCount(t, "PENDING_VALIDATION"); got != escalations+1 {
+		t.Fatalf("expected the escalation counter to be bumped, got %v", got-escalations)
+	}
+	events := r.Recorder.(*record.FakeRecorder).Events
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events))
+	}
+	if e := <-events; !strings.HasPrefix(e, "Warning CertificateExpiring") || !strings.Contains(e, "renewal status PENDING_VALIDATION") {
+		t.Fatalf("unexpected event %q", e)
+	}
+
+	// Once ACM reports the renewal succeeded the records are left alone
+	cert.RenewalSummary.RenewalStatus = acmtypes.RenewalStatusSuccess
+	changes = len(fakeR53.changes)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after renewal: %v", err)
+	}
+	if len(fakeR53.changes) != changes {
+		t.Fatal("a successful renewal must not re-create validation records")
+	}
+}
+
+func TestRenewBeforeFallsBackToDefault(t *testing.T) {
+	ctx := context.Background()
+	r := &IngressReconciler{LiveSettings: LiveSettings{RenewBefore: 14 * 24 * time.Hour}}
+	notBefore := time.Now()
+	cert := &acmtypes.CertificateDetail{NotBefore: aws.Time(notBefore), NotAfter: aws.Time(notBefore.Add(90 * 24 * time.Hour))}
+
+	cases := []struct {
+		annotation string
+		want       time.Duration
+	}{
+		{annotation: "", want: 14 * 24 * time.Hour},
+		{annotation: "168h", want: 7 * 24 * time.Hour},
+		{annotation: "two weeks", want: 14 * 24 * time.Hour},
+		{annotation: "-1h", want: 14 * 24 * time.Hour},
+		{annotation: "2160h", want: 14 * 24 * time.Hour},
+	}
+	for _, tc := range cases {
+		annotations := map[string]string{}
+		if tc.annotation != "" {
+			annotations[renewBeforeAnnotation] = tc.annotation
+		}
+		if got := r.renewBefore(ctx, newManagedIngress("web", "app.example.com", annotations), ParseIngressAnnotations(annotations), cert); got != tc.want {
+			t.Errorf("renew-before %q: got %s, want %s", tc.annotation, got, tc.want)
+		}
+	}
+
+	if got := (&IngressReconciler{}).renewBefore(ctx, newManagedIngress("web", "app.example.com", nil), IngressConfig{}, cert); got != DefaultRenewBefore {
+		t.Errorf("unset flag should use DefaultRenewBefore, got %s", got)
+	}
+}
+
+func TestRenewBeforeLongerThanLifetimeWarns(t *testing.T) {
+	ctx := context.Background()
+	recorder := record.NewFakeRecorder(10)
+	r := &IngressReconciler{Recorder: recorder}
+	notBefore := time.Now()
+	cert := &acmtypes.CertificateDetail{NotBefore: aws.Time(notBefore), NotAfter: aws.Time(notBefore.Add(90 * 24 * time.Hour))}
+	renewBefore := func(value string) {
+		t.Helper()
+		annotations := map[string]string{renewBeforeAnnotation: value}
+		r.renewBefore(ctx, newManagedIngress("web", "app.example.com", annotations), ParseIngressAnnotations(annotations), cert)
+	}
+
+	renewBefore("2160h")
+	renewBefore("2160h")
+	events := drainEvents(recorder)
+	if len(events) != 1 || !strings.Contains(events[0], "Warning "+ReasonRenewBeforeTooLong) {
+		t.Fatalf("expected one RenewBeforeTooLong Warning, got %v", events)
+	}
+
+	// A margin that fits lets the Warning be recorded again when the value comes back
+	renewBefore("168h")
+	renewBefore("2160h")
+	if events := drainEvents(recorder); len(events) != 1 {
+		t.Fatalf("expected the Warning again after a valid margin, got %v", events)
+	}
+}
+
+func TestRenewalRequeue(t *testing.T) {
+	notAfter := time.Now().Add(30*24*time.Hour + time.Hour)
+	if got := renewalRequeue(&notAfter, 30*24*time.Hour); got > time.Hour || got < 59*time.Minute {
+		t.Fatalf("expected a requeue at the start of the renewal window, got %s", got)
+	}
+	if got := renewalRequeue(&notAfter, 7*24*time.Hour); got != 12*time.Hour {
+		t.Fatalf("expected the regular requeue far from the window, got %s", got)
+	}
+	if got := renewalRequeue(&notAfter, 60*24*time.Hour); got != 12*time.Hour {
+		t.Fatalf("expected the regular requeue inside the window, got %s", got)
+	}
+}
+
+// renewalSeries returns the value of each series gauge exports for the Ingress, keyed by
+// its arn and, for the status gauge, status labels
+func renewalSeries(t *testing.T, gauge *prometheus.GaugeVec, ingress string) map[string]float64 {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(gauge)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	series := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range metric.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["ingress"] != ingress {
+				continue
+			}
+			key := labels["arn"]
+			if status, ok := labels["status"]; ok {
+				key += " " + status
+			}
+			series[key] = metric.GetGauge().GetValue()
+		}
+	}
+	return series
+}
+
+func TestRenewalMetrics(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("renewal-metrics", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+	drainEvents(r.Recorder.(*record.FakeRecorder))
+
+	cert := fakeACM.certs[arn]
+	cert.RenewalEligibility = acmtypes.RenewalEligibilityEligible
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if got := renewalSeries(t, certificateRenewalEligible, ingress.Name); len(got) != 1 || got[arn] != 1 {
+		t.Fatalf("expected an eligible certificate to export 1, got %v", got)
+	}
+	if got := renewalSeries(t, certificateRenewalStatus, ingress.Name); len(got) != 1 || got[arn+" NONE"] != 1 {
+		t.Fatalf("expected status NONE before any renewal, got %v", got)
+	}
+
+	cert.RenewalEligibility = acmtypes.RenewalEligibilityIneligible
+	cert.RenewalSummary = &acmtypes.RenewalSummary{RenewalStatus: acmtypes.RenewalStatusPendingAutoRenewal}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if got := renewalSeries(t, certificateRenewalEligible, ingress.Name); len(got) != 1 || got[arn] != 0 {
+		t.Fatalf("expected an ineligible certificate to export 0, got %v", got)
+	}
+	if got := renewalSeries(t, certificateRenewalStatus, ingress.Name); len(got) != 1 || got[arn+" PENDING_AUTO_RENEWAL"] != 1 {
+		t.Fatalf("expected the previous status series to be replaced, got %v", got)
+	}
+	for _, e := range drainEvents(r.Recorder.(*record.FakeRecorder)) {
+		if strings.Contains(e, ReasonRenewalIneligible) {
+			t.Fatalf("a certificate in use by nothing must not warn, got %q", e)
+		}
+	}
+
+	cert.InUseBy = []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/1"}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+ReasonRenewalIneligible) || !strings.Contains(events[0], arn) {
+		t.Fatalf("expected one RenewalIneligible event, got %v", events)
+	}
+
+	r.forgetCertificateMetrics(ingress.Namespace, ingress.Name)
+	if got := renewalSeries(t, certificateRenewalStatus, ingress.Name); len(got) != 0 {
+		t.Fatalf("expected the series to be dropped with the Ingress, got %v", got)
+	}
+}
+
+func TestInvalidRenewBeforeWarns(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{renewBeforeAnnotation: "two weeks"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+
+	for range 2 {
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+	}
+	var warnings []string
+	for _, e := range drainEvents(r.Recorder.(*record.FakeRecorder)) {
+		if strings.HasPrefix(e, "Warning "+ReasonInvalidAnnotation) {
+			warnings = append(warnings, e)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], renewBeforeAnnotation) || !strings.Contains(warnings[0], "two weeks") {
+		t.Fatalf("warnings = %v, want one InvalidAnnotation warning naming the value", warnings)
+	}
+	if certificateArnOf(t, r, ingress) == "" {
+		t.Fatal("the default margin should be used and the certificate attached")
+	}
+}
@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// replicateRegionsAnnotation lists extra regions that get a certificate with the same names,
+// such as us-east-1 for CloudFront
+const replicateRegionsAnnotation = "acm.tedens.dev/replicate-regions"
+
+// replicateToUSEast1Annotation adds us-east-1 to the replicate regions
+const replicateToUSEast1Annotation = "acm.tedens.dev/replicate-to-us-east-1"
+
+// replicaArnAnnotationPrefix, followed by a region, is the status annotation publishing the
+// ARN of the replica in that region
+const replicaArnAnnotationPrefix = "acm.tedens.dev/certificate-arn."
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// parseReplicaRegions returns the lower-cased regions of a replicate-regions value, plus
+// us-east-1 when usEast1 is set, without duplicates
+func parseReplicaRegions(value string, usEast1 bool) []string {
+	var regions []string
+	for _, region := range strings.Split(value, ",") {
+		if region = strings.ToLower(strings.TrimSpace(region)); region != "" && !slices.Contains(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+	if usEast1 && !slices.Contains(regions, "us-east-1") {
+		regions = append(regions, "us-east-1")
+	}
+	return regions
+}
+
+// isReplicaArnAnnotation reports whether key publishes the ARN of a replica
+func isReplicaArnAnnotation(key string) bool {
+	return strings.HasPrefix(key, replicaArnAnnotationPrefix)
+}
+
+// replicaArns returns the published replica ARNs of the Ingress by region
+func replicaArns(ingress *networkingv1.Ingress) map[string]string {
+	arns := map[string]string{}
+	for key, value := range ingress.Annotations {
+		if region, ok := strings.CutPrefix(key, replicaArnAnnotationPrefix); ok && value != "" {
+			arns[region] = value
+		}
+	}
+	return arns
+}
+
+// replicaContext returns ctx carrying the clients of the Ingress' role in region
+func (r *IngressReconciler) replicaContext(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, region string) (context.Context, error) {
+	target, err := r.awsTargetFor(ctx, ingress, cfg)
+	if err != nil {
+		return ctx, err
+	}
+	target.Region = region
+	ctx, err = r.withAWSTarget(ctx, target)
+	if err != nil {
+		return ctx, err
+	}
+	return log.IntoContext(ctx, log.FromContext(ctx).WithValues("replicaRegion", region)), nil
+}
+
+// rejectReplicas records a ReplicationUnsupported Warning event when the Ingress asks for
+// replicas of a kind of certificate that is not replicated
+func (r *IngressReconciler) rejectReplicas(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, kind string) {
+	if len(cfg.ReplicaRegions) == 0 {
+		r.forgetEvent(client.ObjectKeyFromObject(ingress), ReasonReplicationUnsupported)
+		return
+	}
+	annotation := replicateRegionsAnnotation
+	if strings.TrimSpace(ingress.Annotations[annotation]) == "" {
+		annotation = replicateToUSEast1Annotation
+	}
+	log.FromContext(ctx).Info("Certificate is not replicated", "kind", kind, "annotation", annotation)
+	r.warnOnce(ingress, ReasonReplicationUnsupported, kind+" "+annotation,
+		"%s certificates are not replicated, so %s is ignored", kind, annotation)
+}
+
+// withReplicas reconciles the replicas of certArn, returning result or the sooner requeue a
+// replica pending validation needs
+func (r *IngressReconciler) withReplicas(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, domain, certArn string, result ctrl.Result) (ctrl.Result, error) {
+	requeue, err := r.reconcileReplicas(ctx, ingress, cfg, domain, certArn)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to replicate certificate", "arn", certArn)
+		if !isDeferred(err) {
+			r.notifyCertificateEvent(ingress, NotificationFailed, domain, certArn, "", err.Error())
+		}
+		return ctrl.Result{}, err
+	}
+	if requeue > 0 && (result.RequeueAfter <= 0 || requeue < result.RequeueAfter) {
+		result.RequeueAfter = requeue
+	}
+	return result, nil
+}
+
+// reconcileReplicas ensures an issued certificate with the Ingress' names in every replicate
+// region other than certArn's, publishes their ARNs and releases the replicas of regions no
+// longer listed. A replica replaced by an issued successor is deleted once nothing uses it. It
+// returns how soon to check again while a replica is pending validation.
+func (r *IngressReconciler) reconcileReplicas(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, domain, certArn string) (time.Duration, error) {
+	published := replicaArns(ingress)
+	superseded := readBookkeeping(ingress).SupersededReplicaArns
+	if len(cfg.ReplicaRegions) == 0 && len(published) == 0 && len(superseded) == 0 {
+		return 0, nil
+	}
+	logger := log.FromContext(ctx)
+	primaryRegion := ""
+	if parsed, err := arn.Parse(certArn); err == nil {
+		primaryRegion = parsed.Region
+	}
+
+	var requeue time.Duration
+	wanted := map[string]string{}
+	for _, region := range cfg.ReplicaRegions {
+		if !regionPattern.MatchString(region) {
+			return 0, fmt.Errorf("invalid %s: %q is not an AWS region", replicateRegionsAnnotation, region)
+		}
+		if region == primaryRegion {
+			logger.Info("Certificate is already in the replicate region, nothing to replicate", "region", region)
+			continue
+		}
+		replicaCtx, err := r.replicaContext(ctx, ingress, cfg, region)
+		if err != nil {
+			return 0, fmt.Errorf("replica in %s: %w", region, err)
+		}
+		replica, pending, err := r.ensureReplica(replicaCtx, ingress, cfg, domain, published[region])
+		if errors.Is(err, certs.ErrAbandoned) {
+			r.abandonCertificate(replicaCtx, replica.CertificateArn)
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("replica in %s: %w", region, err)
+		}
+		if pending {
+			// The replica published before, if any, stays until its successor issues
+			requeue = r.pendingRequeues.next(ingress.UID, replica.CertificateArn+" "+certs.FormatDomainStatuses(replica.Domains))
+			logger.Info("Replica is pending validation, checking again later", "region", region, "arn", replica.CertificateArn, "after", requeue)
+			if previous := published[region]; previous != "" {
+				wanted[region] = previous
+			}
+			continue
+		}
+		wanted[region] = replica.CertificateArn
+		if previous := published[region]; previous != "" && previous != replica.CertificateArn && !cfg.DeletionProtection && !slices.Contains(superseded[region], previous) {
+			superseded = maps.Clone(superseded)
+			if superseded == nil {
+				superseded = map[string][]string{}
+			}
+			superseded[region] = append(slices.Clone(superseded[region]), previous)
+		}
+	}
+	remaining := r.retireReplicas(ctx, ingress, cfg, superseded)
+
+	for _, region := range slices.Sorted(maps.Keys(published)) {
+		if _, ok := wanted[region]; ok {
+			continue
+		}
+		replicaCtx, err := r.replicaContext(ctx, ingress, cfg, region)
+		if err == nil {
+			err = r.releaseCertificateOwners(replicaCtx, ingress, []string{published[region]})
+		}
+		if err != nil {
+			return 0, fmt.Errorf("release replica in %s: %w", region, err)
+		}
+		logger.Info("Region is no longer replicated, unpublishing its replica", "region", region, "arn", published[region])
+	}
+
+	if maps.Equal(wanted, published) && maps.EqualFunc(remaining, readBookkeeping(ingress).SupersededReplicaArns, slices.Equal) {
+		return requeue, nil
+	}
+	if r.ObserveOnly {
+		return 0, observeGate(ctx, "publish replicas", strings.Join(slices.Sorted(maps.Values(wanted)), ","))
+	}
+	patch := client.MergeFrom(ingress.DeepCopy())
+	updateBookkeeping(ingress, func(b *bookkeeping) { b.SupersededReplicaArns = remaining })
+	for region := range published {
+		delete(ingress.Annotations, replicaArnAnnotationPrefix+region)
+	}
+	for region, replicaArn := range wanted {
+		if ingress.Annotations == nil {
+			ingress.Annotations = map[string]string{}
+		}
+		ingress.Annotations[replicaArnAnnotationPrefix+region] = replicaArn
+	}
+	if err := r.patchIngress(ctx, ingress, patch); err != nil {
+		return 0, fmt.Errorf("failed to publish replica ARNs: %w", err)
+	}
+	for _, region := range slices.Sorted(maps.Keys(wanted)) {
+		if replicaArn := wanted[region]; replicaArn != published[region] && r.Recorder != nil {
+			r.Recorder.Eventf(ingress, corev1.EventTypeNormal, ReasonCertificateReplicated,
+				"Certificate for %s replicated to %s as %s", domain, region, replicaArn)
+		}
+	}
+	return requeue, nil
+}
+
+// ensureReplica checks the replica published for the region in ctx, escalating its renewal
+// when it is inside its renewal window, and ensures a new one when it is gone, not issued or
+// no longer covers the Ingress' names. pending reports a replica still validating.
+func (r *IngressReconciler) ensureReplica(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, domain, published string) (result certs.EnsureResult, pending bool, err error) {
+	logger := log.FromContext(ctx)
+	certDomain := certificateDomain(domain, cfg)
+	if published != "" {
+		describe, err := r.acm(ctx).DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+			CertificateArn: aws.String(published),
+		})
+		var notFound *acmtypes.ResourceNotFoundException
+		switch {
+		case errors.As(err, &notFound):
+			logger.Info("Published replica no longer exists, ensuring a new one", "arn", published)
+		case err != nil:
+			return result, false, err
+		case describe.Certificate.Status != acmtypes.CertificateStatusIssued:
+			logger.Info("Published replica is not issued, ensuring a new one", "arn", published, "status", describe.Certificate.Status)
+		case !certs.SameNames(describe.Certificate.SubjectAlternativeNames, certDomain, cfg.SANs):
+			logger.Info("Published replica does not cover the requested names, ensuring a new one", "arn", published)
+		default:
+			cert := describe.Certificate
+			if notAfter := cert.NotAfter; notAfter != nil && time.Until(*notAfter) < r.renewBefore(ctx, ingress, cfg, cert) {
+				if err := r.escalateRenewal(ctx, ingress, cfg, domain, cert); err != nil {
+					return result, false, fmt.Errorf("failed to escalate renewal of %s: %w", published, err)
+				}
+			}
+			return certs.EnsureResult{CertificateArn: published, Status: cert.Status}, false, nil
+		}
+	}
+
+	dnsProvider, err := r.dnsProviderFor(ctx, ingress, cfg)
+	if err != nil {
+		return result, false, err
+	}
+	// The validation records are the same CNAMEs in every region, so writing them again
+	// changes nothing once the certificate in the Ingress' own region validated
+	result, err = r.certManager(ctx).Ensure(ctx, certs.EnsureRequest{
+		Domain:                  certDomain,
+		SubjectAlternativeNames: cfg.SANs,
+		ReuseExisting:           true,
+		DNS:                     dnsProvider,
+		ZoneID:                  cfg.ZoneID,
+		KeyAlgorithm:            cfg.KeyAlgorithm,
+		DisableCTLogging:        cfg.DisableCTLogging,
+		Tags:                    cfg.Tags,
+		OnProgress:              r.validationProgressRecorder(ingress),
+		IdempotencyToken:        requestToken(ingress, certDomain, cfg.SANs),
+		NoWait:                  r.RequeuePendingValidation,
+		Wanted:                  r.ingressWanted(ingress),
+	})
+	if errors.Is(err, certs.ErrValidationPending) {
+		return result, true, nil
+	}
+	if err != nil {
+		return result, false, err
+	}
+	if err := r.tagCertificateOwner(ctx, result.CertificateArn, ingress); err != nil {
+		logOwnerError(ctx, err, result.CertificateArn)
+	}
+	return result, false, nil
+}
+
+// retireReplicas retires the superseded replicas of each region and returns those to retry
+func (r *IngressReconciler) retireReplicas(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, superseded map[string][]string) map[string][]string {
+	var remaining map[string][]string
+	for _, region := range slices.Sorted(maps.Keys(superseded)) {
+		replicaCtx, err := r.replicaContext(ctx, ingress, cfg, region)
+		for _, replicaArn := range superseded[region] {
+			if err != nil {
+				log.FromContext(ctx).Info("Cannot reach the region of a superseded replica, retrying later", "arn", replicaArn, "reason", err.Error())
+			} else if r.retireCertificate(replicaCtx, replicaArn, ingressKey(ingress)) {
+				continue
+			}
+			if remaining == nil {
+				remaining = map[string][]string{}
+			}
+			remaining[region] = append(remaining[region], replicaArn)
+		}
+	}
+	return remaining
+}
+
+// releaseReplicas removes the Ingress from the owners of its published replicas and, with
+// deleteCerts, deletes those this instance owns that nothing else holds
+func (r *IngressReconciler) releaseReplicas(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, deleteCerts bool) error {
+	// Superseded replicas are only retried while the Ingress exists
+	if remaining := r.retireReplicas(ctx, ingress, cfg, readBookkeeping(ingress).SupersededReplicaArns); len(remaining) > 0 {
+		log.FromContext(ctx).Info("Leaving superseded replicas that could not be deleted", "arns", remaining)
+	}
+	published := replicaArns(ingress)
+	var notFound *acmtypes.ResourceNotFoundException
+	for _, region := range slices.Sorted(maps.Keys(published)) {
+		replicaArn := published[region]
+		replicaCtx, err := r.replicaContext(ctx, ingress, cfg, region)
+		if err == nil {
+			err = r.releaseCertificateOwners(replicaCtx, ingress, []string{replicaArn})
+		}
+		if err == nil && deleteCerts {
+			log.FromContext(replicaCtx).Info("Ingress is being deleted. Deleting its replica...", "arn", replicaArn)
+			if _, err = r.deleteOwnedCertificate(replicaCtx, replicaArn, ingressKey(ingress)); errors.As(err, &notFound) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("replica in %s: %w", region, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestParseReplicaRegions(t *testing.T) {
+	if got := parseReplicaRegions(" eu-west-1, EU-West-1,,us-east-1", true); !slices.Equal(got, []string{"eu-west-1", "us-east-1"}) {
+		t.Errorf("parseReplicaRegions = %v, want each region once", got)
+	}
+	if got := parseReplicaRegions("", true); !slices.Equal(got, []string{"us-east-1"}) {
+		t.Errorf("parseReplicaRegions with the shorthand = %v, want us-east-1", got)
+	}
+	if got := parseReplicaRegions("", false); len(got) != 0 {
+		t.Errorf("parseReplicaRegions of nothing = %v, want none", got)
+	}
+}
+
+func TestReplicaFollowsCertificate(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		replicateRegionsAnnotation:                     "eu-west-1",
+		"acm.tedens.dev/delete-cert-on-ingress-delete": "true",
+	})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	built := useTargetClients(r, "example.com")
+	replicaKey := replicaArnAnnotationPrefix + "eu-west-1"
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	replicaACM := built[awsTarget{Region: "eu-west-1"}]
+	if replicaACM == nil || len(replicaACM.requests) != 1 || aws.ToString(replicaACM.requests[0].DomainName) != "app.example.com" {
+		t.Fatalf("expected one replica request in eu-west-1, built %v", built)
+	}
+	replica := getIngress(t, r, ingress).Annotations[replicaKey]
+	if replica == "" {
+		t.Fatalf("expected %s to publish the replica", replicaKey)
+	}
+	if events := drainEvents(r.Recorder.(*record.FakeRecorder)); !slices.ContainsFunc(events, func(e string) bool { return strings.Contains(e, ReasonCertificateReplicated) }) {
+		t.Errorf("events = %v, want %s", events, ReasonCertificateReplicated)
+	}
+
+	// An issued replica covering the names is only described
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(replicaACM.requests) != 1 {
+		t.Fatalf("replica requests = %d after an unchanged reconcile, want 1", len(replicaACM.requests))
+	}
+
+	// New names rotate the replica along with the certificate
+	current := getIngress(t, r, ingress)
+	current.Spec.Rules[0].Host = "www.example.com"
+	if err := r.Update(ctx, current); err != nil {
+		t.Fatalf("update ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(replicaACM.requests) != 2 || aws.ToString(replicaACM.requests[1].DomainName) != "www.example.com" {
+		t.Fatalf("expected a replica for the new names, got %d requests", len(replicaACM.requests))
+	}
+	rotated := getIngress(t, r, ingress).Annotations[replicaKey]
+	if rotated == "" || rotated == replica {
+		t.Fatalf("replica annotation = %q, want the rotated replica", rotated)
+	}
+	if !slices.Equal(replicaACM.deleted, []string{replica}) {
+		t.Fatalf("deleted replicas %v, want the superseded %s", replicaACM.deleted, replica)
+	}
+
+	// Deleting the Ingress deletes the replica too
+	if err := r.Delete(ctx, getIngress(t, r, ingress)); err != nil {
+		t.Fatalf("delete ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after delete: %v", err)
+	}
+	if !slices.Contains(replicaACM.deleted, rotated) {
+		t.Fatalf("deleted replicas %v, want %s", replicaACM.deleted, rotated)
+	}
+}
+
+func TestReplicaUnpublishedWhenRegionRemoved(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{replicateRegionsAnnotation: "eu-west-1,ap-southeast-2"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	useTargetClients(r, "example.com")
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	current := getIngress(t, r, ingress)
+	if len(replicaArns(current)) != 2 {
+		t.Fatalf("published replicas = %v, want two", replicaArns(current))
+	}
+	current.Annotations[replicateRegionsAnnotation] = "ap-southeast-2"
+	if err := r.Update(ctx, current); err != nil {
+		t.Fatalf("update ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	published := replicaArns(getIngress(t, r, ingress))
+	if _, ok := published["eu-west-1"]; ok || len(published) != 1 {
+		t.Fatalf("published replicas = %v, want only ap-southeast-2", published)
+	}
+}
+
+func TestReplicaSkipsTheCertificatesRegion(t *testing.T) {
+	ctx := context.Background()
+	// The fake ACM issues certificates in us-east-1
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{replicateToUSEast1Annotation: "true"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	built := useTargetClients(r, "example.com")
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(built) != 0 || len(replicaArns(getIngress(t, r, ingress))) != 0 {
+		t.Fatalf("expected no replica in the certificate's own region, built %v", built)
+	}
+}
+
+func TestGroupReplicasRejected(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		replicateToUSEast1Annotation: "true",
+		certGroupAnnotation:          "api",
+	})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	built := useTargetClients(r, "example.com")
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(built) != 0 || len(replicaArns(getIngress(t, r, ingress))) != 0 {
+		t.Fatalf("expected no replica of a group certificate, built %v", built)
+	}
+	if events := drainEvents(r.Recorder.(*record.FakeRecorder)); !slices.ContainsFunc(events, func(e string) bool {
+		return strings.Contains(e, ReasonReplicationUnsupported) && strings.Contains(e, replicateToUSEast1Annotation)
+	}) {
+		t.Errorf("events = %v, want %s naming %s", events, ReasonReplicationUnsupported, replicateToUSEast1Annotation)
+	}
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// retryBackoffAnnotation holds the JSON retryBackoff of an Ingress whose reconciles keep
+// failing, so a restarted controller resumes the backoff instead of retrying right away
+const retryBackoffAnnotation = "acm.tedens.dev/retry-backoff"
+
+// Failed reconciles are retried after retryBackoffBase doubled per consecutive failure, up to
+// retryBackoffMax, by the work queue's rate limiter as well. The base is a variable so tests
+// can shorten it.
+var retryBackoffBase = time.Second
+
+const retryBackoffMax = 1000 * time.Second
+
+// retryBackoff is the persisted failure count of an Ingress and when it may be retried.
+// Terminal counts only the latest failures in a row that retrying cannot fix, which is what
+// quarantines the Ingress. Observed fingerprints the Ingress at the time, so an edit retries at
+// once. Quarantined is set while the Ingress is only probed, see quarantinedAnnotation.
+type retryBackoff struct {
+	Failures    int       `json:"failures"`
+	Terminal    int       `json:"terminal,omitempty"`
+	NextRetry   time.Time `json:"nextRetry"`
+	Observed    string    `json:"observed"`
+	Quarantined bool      `json:"quarantined,omitempty"`
+}
+
+// retryBackoffDelay returns the delay after the given number of consecutive failures
+func retryBackoffDelay(failures int) time.Duration {
+	delay := retryBackoffBase
+	for i := 1; i < failures && delay < retryBackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, retryBackoffMax)
+}
+
+// savedRetryBackoff returns the Ingress' persisted retry backoff, or nil
+func savedRetryBackoff(ctx context.Context, ingress *networkingv1.Ingress) *retryBackoff {
+	raw := ingress.Annotations[retryBackoffAnnotation]
+	if raw == "" {
+		return nil
+	}
+	var backoff retryBackoff
+	if err := json.Unmarshal([]byte(raw), &backoff); err != nil {
+		log.FromContext(ctx).Error(err, "ignoring unreadable retry backoff", "annotation", retryBackoffAnnotation)
+		return nil
+	}
+	return &backoff
+}
+
+// observedIngress fingerprints the generation, labels and non-status annotations of the Ingress
+func observedIngress(ingress *networkingv1.Ingress) string {
+	annotations := withoutStatusAnnotations(ingress.Annotations)
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d", ingress.Generation)
+	for _, key := range slices.Sorted(maps.Keys(annotations)) {
+		fmt.Fprintf(h, "\x00%s=%s", key, annotations[key])
+	}
+	// Labels feed the name templates
+	for _, key := range slices.Sorted(maps.Keys(ingress.Labels)) {
+		fmt.Fprintf(h, "\x01%s=%s", key, ingress.Labels[key])
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// retryWait returns how long the Ingress must still wait after its persisted failures. It is
+// zero once the backoff has passed, the Ingress changed since the failure, its quarantine was
+// lifted, or it is deleted.
+func (r *IngressReconciler) retryWait(ctx context.Context, ingress *networkingv1.Ingress) time.Duration {
+	backoff := savedRetryBackoff(ctx, ingress)
+	if backoff == nil || !ingress.DeletionTimestamp.IsZero() || quarantineLifted(backoff, ingress) || backoff.Observed != observedIngress(ingress) {
+		return 0
+	}
+	return max(backoff.NextRetry.Sub(r.clock()), 0)
+}
+
+// quarantineLifted reports whether the Ingress was quarantined and has since been edited or
+// had its quarantined annotation removed
+func quarantineLifted(backoff *retryBackoff, ingress *networkingv1.Ingress) bool {
+	if !backoff.Quarantined {
+		return false
+	}
+	_, ok := ingress.Annotations[quarantinedAnnotation]
+	return !ok || backoff.Observed != observedIngress(ingress)
+}
+
+// nextRetryBackoff sets the retry backoff annotation of the Ingress after another failure with
+// err retried in delay, or the backoff of its failure count when delay is zero. It quarantines
+// the Ingress once err keeps recurring, and then only probes it every quarantineProbeInterval.
+func (r *IngressReconciler) nextRetryBackoff(ctx context.Context, ingress *networkingv1.Ingress, delay time.Duration, err error) {
+	failures, terminal := 1, 0
+	previous := savedRetryBackoff(ctx, ingress)
+	if previous != nil {
+		failures = previous.Failures + 1
+	}
+	// A transient failure, such as throttling, breaks the run of terminal ones
+	if quarantineReason(err) != "" {
+		terminal = 1
+		if previous != nil {
+			terminal = previous.Terminal + 1
+		}
+	}
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+	quarantined := r.quarantine(ingress, terminal, err)
+	switch {
+	case quarantined:
+		delay = r.quarantineProbeInterval()
+	case delay == 0:
+		delay = retryBackoffDelay(failures)
+	}
+	if !quarantined {
+		delete(ingress.Annotations, quarantinedAnnotation)
+	}
+	data, err := json.Marshal(retryBackoff{
+		Failures:    failures,
+		Terminal:    terminal,
+		NextRetry:   r.clock().Add(delay).UTC(),
+		Observed:    observedIngress(ingress),
+		Quarantined: quarantined,
+	})
+	if err != nil {
+		return
+	}
+	ingress.Annotations[retryBackoffAnnotation] = string(data)
+}
+
+// resetTerminalFailures starts the Ingress' run of terminal failures over after a deferred
+// reconcile, which like a transient failure shows the cause was not the same every time
+func (r *IngressReconciler) resetTerminalFailures(ctx context.Context, key client.ObjectKey) {
+	var ingress networkingv1.Ingress
+	if err := r.Get(ctx, key, &ingress); err != nil {
+		return
+	}
+	backoff := savedRetryBackoff(ctx, &ingress)
+	if backoff == nil || backoff.Terminal == 0 || backoff.Quarantined {
+		return
+	}
+	backoff.Terminal = 0
+	data, err := json.Marshal(backoff)
+	if err != nil {
+		return
+	}
+	patch := client.MergeFrom(ingress.DeepCopy())
+	ingress.Annotations[retryBackoffAnnotation] = string(data)
+	if err := r.patchIngress(ctx, &ingress, patch); err != nil {
+		log.FromContext(ctx).Error(err, "failed to reset the terminal failure count")
+	}
+}
+
+// clearRetryBackoff removes the persisted retry backoff and any quarantine, for example so
+// ReconcileAll retries failing Ingresses right away
+func (r *IngressReconciler) clearRetryBackoff(ctx context.Context, ingress *networkingv1.Ingress) error {
+	if _, ok := ingress.Annotations[retryBackoffAnnotation]; !ok {
+		return nil
+	}
+	patch := client.MergeFrom(ingress.DeepCopy())
+	delete(ingress.Annotations, retryBackoffAnnotation)
+	delete(ingress.Annotations, quarantinedAnnotation)
+	if err := r.patchIngress(ctx, ingress, patch); err != nil {
+		return err
+	}
+	r.quarantined.set(client.ObjectKeyFromObject(ingress), false, quarantinedIngresses)
+	return nil
+}
+
+// clock returns the current time, from now when set
+func (r *IngressReconciler) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/aws/smithy-go"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRetryBackoffDelay(t *testing.T) {
+	if retryBackoffBase < time.Second {
+		t.Fatalf("retryBackoffBase = %s, want at least a second so failures do not hammer AWS", retryBackoffBase)
+	}
+	defer func(base time.Duration) { retryBackoffBase = base }(retryBackoffBase)
+	retryBackoffBase = 5 * time.Millisecond
+	cases := map[int]time.Duration{1: 5 * time.Millisecond, 2: 10 * time.Millisecond, 4: 40 * time.Millisecond, 100: retryBackoffMax}
+	for failures, want := range cases {
+		if got := retryBackoffDelay(failures); got != want {
+			t.Errorf("retryBackoffDelay(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestRetryBackoffResumesAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{"app.example.com": acmtypes.DomainStatusFailed}
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	first := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	now := time.Now()
+	first.now = func() time.Time { return now }
+
+	for i := 1; i <= 4; i++ {
+		if _, err := first.Reconcile(ctx, requestFor(ingress)); err == nil {
+			t.Fatal("expected the validation failure")
+		}
+		if i < 4 {
+			now = now.Add(retryBackoffDelay(i))
+		}
+	}
+	var got networkingv1.Ingress
+	if err := first.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	backoff := savedRetryBackoff(ctx, &got)
+	if backoff == nil || backoff.Failures != 4 || !backoff.NextRetry.Equal(now.Add(retryBackoffDelay(4)).UTC()) {
+		t.Fatalf("unexpected retry backoff %+v", backoff)
+	}
+	requests := len(fakeACM.requests)
+
+	// A restarted controller waits out the remaining backoff without calling AWS
+	second := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"))
+	second.Client = first.Client
+	second.now = first.now
+	now = now.Add(retryBackoffDelay(4) / 2)
+	result, err := second.Reconcile(ctx, requestFor(ingress))
+	if err != nil || result.RequeueAfter != retryBackoffDelay(4)/2 {
+		t.Fatalf("expected the backoff to resume, got %+v, %v", result, err)
+	}
+	if len(fakeACM.requests) != requests {
+		t.Fatal("a backing-off Ingress must not be reconciled")
+	}
+
+	// Once it has passed the Ingress is retried and the backoff grows
+	now = now.Add(retryBackoffDelay(4) / 2)
+	if _, err := second.Reconcile(ctx, requestFor(ingress)); err == nil {
+		t.Fatal("expected the validation failure")
+	}
+	if len(fakeACM.requests) != requests+1 {
+		t.Fatalf("expected the Ingress to be retried, got %d new requests", len(fakeACM.requests)-requests)
+	}
+	if err := second.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if backoff := savedRetryBackoff(ctx, &got); backoff == nil || backoff.Failures != 5 {
+		t.Fatalf("expected a fifth failure, got %+v", backoff)
+	}
+}
+
+func TestRetryBackoffSkippedAfterEdit(t *testing.T) {
+	ctx := context.Background()
+	fakeR53 := newFakeRoute53("example.com")
+	fakeR53.err = &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized"}
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), fakeR53, ingress)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err == nil {
+		t.Fatal("expected the Route 53 error")
+	}
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if r.retryWait(ctx, &got) == 0 {
+		t.Fatal("expected the Ingress to back off")
+	}
+	patch := client.MergeFrom(got.DeepCopy())
+	got.Annotations["acm.tedens.dev/zone-id"] = "Z1"
+	if err := r.Patch(ctx, &got, patch); err != nil {
+		t.Fatalf("patch ingress: %v", err)
+	}
+	if wait := r.retryWait(ctx, &got); wait != 0 {
+		t.Fatalf("an edited Ingress must be retried at once, got a wait of %s", wait)
+	}
+}
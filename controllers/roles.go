@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// roleArnAnnotation names an IAM role the controller assumes for the Ingress' certificates
+// and validation records, overriding the namespace role map
+const roleArnAnnotation = "acm.tedens.dev/role-arn"
+
+// awsProfileAnnotation names a shared config profile used for the Ingress' AWS calls, honored
+// with --allow-profile-annotation only
+const awsProfileAnnotation = "acm.tedens.dev/aws-profile"
+
+// parseNamespaceRole parses a namespace role map value of the form "<role-arn>[,<region>]";
+// either part may be empty
+func parseNamespaceRole(value string) (awsTarget, error) {
+	roleARN, region, _ := strings.Cut(value, ",")
+	target := awsTarget{RoleARN: strings.TrimSpace(roleARN), Region: strings.TrimSpace(region)}
+	if err := validateRoleARN(target.RoleARN); err != nil {
+		return awsTarget{}, err
+	}
+	return target, nil
+}
+
+// validateRoleARN checks that value is empty or the ARN of an IAM role
+func validateRoleARN(value string) error {
+	if value == "" {
+		return nil
+	}
+	parsed, err := arn.Parse(value)
+	if err != nil || parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+		return fmt.Errorf("%q is not an IAM role ARN", value)
+	}
+	return nil
+}
+
+// namespaceRole returns the role and region the namespace role map assigns to namespace, or
+// the zero target when no map is configured or it has no entry for the namespace
+func (r *IngressReconciler) namespaceRole(ctx context.Context, namespace string) (awsTarget, error) {
+	if r.NamespaceRoleMap.Name == "" {
+		return awsTarget{}, nil
+	}
+
+	var cm corev1.ConfigMap
+	if err := r.Get(ctx, r.NamespaceRoleMap, &cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.FromContext(ctx).Info("Namespace role map not found, using the controller's credentials", "configmap", r.NamespaceRoleMap)
+			return awsTarget{}, nil
+		}
+		return awsTarget{}, err
+	}
+	value, ok := cm.Data[namespace]
+	if !ok {
+		return awsTarget{}, nil
+	}
+	target, err := parseNamespaceRole(value)
+	if err != nil {
+		return awsTarget{}, fmt.Errorf("namespace role map %s entry %s: %w", r.NamespaceRoleMap, namespace, err)
+	}
+	return target, nil
+}
+
+// awsTargetFor returns the role and region used for the Ingress: its namespace's entry in the
+// namespace role map, with the role and region its IngressClass sets replacing them, and the
+// role replaced by acm.tedens.dev/role-arn when that is set. With AllowProfileAnnotation,
+// acm.tedens.dev/aws-profile selects the shared config profile the role is assumed from.
+func (r *IngressReconciler) awsTargetFor(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig) (awsTarget, error) {
+	target, err := r.namespaceRole(ctx, ingress.Namespace)
+	if err != nil {
+		return awsTarget{}, err
+	}
+	class, err := r.ingressClassTarget(ctx, ingress)
+	if err != nil {
+		return awsTarget{}, err
+	}
+	if class.RoleARN != "" {
+		target.RoleARN = class.RoleARN
+	}
+	if class.Region != "" {
+		target.Region = class.Region
+	}
+	if cfg.RoleARN != "" {
+		if err := validateRoleARN(cfg.RoleARN); err != nil {
+			return awsTarget{}, fmt.Errorf("invalid %s: %w", roleArnAnnotation, err)
+		}
+		target.RoleARN = cfg.RoleARN
+	}
+	if cfg.AWSProfile != "" {
+		if r.AllowProfileAnnotation {
+			target.Profile = cfg.AWSProfile
+		} else {
+			log.FromContext(ctx).Info("Ignoring the AWS profile annotation, --allow-profile-annotation is not set", "annotation", awsProfileAnnotation)
+		}
+	}
+	return target, nil
+}
+
+type awsScopeKey struct{}
+
+// withAWSTarget returns ctx carrying the clients of target, so the ACM and Route 53 calls
+// made with it act with that role and region. The zero target keeps the reconciler's clients.
+func (r *IngressReconciler) withAWSTarget(ctx context.Context, target awsTarget) (context.Context, error) {
+	if target == (awsTarget{}) {
+		return ctx, nil
+	}
+	clients, err := r.awsClients().get(ctx, target)
+	if err != nil {
+		return ctx, err
+	}
+	scoped := *clients
+	if r.Audit != nil {
+		scoped.ACM = r.Audit.ACM(scoped.ACM)
+	}
+	return context.WithValue(ctx, awsScopeKey{}, &scoped), nil
+}
+
+// acm returns the ACM client of the role and region in ctx, or the reconciler's client
+func (r *IngressReconciler) acm(ctx context.Context) ACMAPI {
+	if scoped, ok := ctx.Value(awsScopeKey{}).(*awsClients); ok {
+		return r.observeACMClient(r.maintenanceACMClient(r.requestRateLimitedACMClient(scoped.ACM)))
+	}
+	return r.observeACMClient(r.maintenanceACMClient(r.requestRateLimitedACMClient(r.ACMClient)))
+}
+
+// route53Provider returns the Route 53 provider of the role and region in ctx, or the
+// reconciler's DNS provider
+func (r *IngressReconciler) route53Provider(ctx context.Context) DNSProvider {
+	if scoped, ok := ctx.Value(awsScopeKey{}).(*awsClients); ok && scoped.DNS != nil {
+		return scoped.DNS
+	}
+	return r.DNSProvider
+}
+
+// isNamespaceRoleMap reports whether obj is the configured namespace role map
+func (r *IngressReconciler) isNamespaceRoleMap(obj client.Object) bool {
+	return r.NamespaceRoleMap.Name != "" &&
+		obj.GetNamespace() == r.NamespaceRoleMap.Namespace && obj.GetName() == r.NamespaceRoleMap.Name
+}
+
+// enqueueManagedIngressesForRoleMap requeues every managed Ingress when the namespace role map
+// changes
+func (r *IngressReconciler) enqueueManagedIngressesForRoleMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	if !r.isNamespaceRoleMap(obj) {
+		return nil
+	}
+	return r.managedIngressRequests(ctx)
+}
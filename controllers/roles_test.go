@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// roleCredentials stands in for assumed-role credentials so tests can tell which role clients use
+type roleCredentials string
+
+func (c roleCredentials) Retrieve(context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: string(c)}, nil
+}
+
+// profileCredentials stands in for the credentials of a shared config profile
+type profileCredentials string
+
+func (c profileCredentials) Retrieve(context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: string(c)}, nil
+}
+
+// useTargetClients makes r build a fakeACM and fakeRoute53 per role, region and profile,
+// returned by their target. Profiles load in us-west-2.
+func useTargetClients(r *IngressReconciler, zoneNames ...string) map[awsTarget]*fakeACM {
+	built := map[awsTarget]*fakeACM{}
+	r.clientsOnce.Do(func() {
+		r.clients = &awsClientCache{
+			loadConfig: func(context.Context) (aws.Config, error) { return aws.Config{Region: "us-east-1"}, nil },
+			loadProfile: func(_ context.Context, profile string) (aws.Config, error) {
+				return aws.Config{Region: "us-west-2", Credentials: profileCredentials(profile)}, nil
+			},
+			assumeRole: func(_ aws.Config, roleARN string) aws.CredentialsProvider { return roleCredentials(roleARN) },
+			build: func(cfg aws.Config) awsClients {
+				target := awsTarget{Region: cfg.Region}
+				switch credentials := cfg.Credentials.(type) {
+				case roleCredentials:
+					target.RoleARN = string(credentials)
+				case profileCredentials:
+					target.Profile = string(credentials)
+				}
+				built[target] = newFakeACM()
+				return awsClients{ACM: built[target], Route53: newFakeRoute53(zoneNames...)}
+			},
+		}
+	})
+	return built
+}
+
+func TestParseNamespaceRole(t *testing.T) {
+	cases := []struct {
+		value string
+		want  awsTarget
+	}{
+		{"arn:aws:iam::111122223333:role/acm-manager", awsTarget{RoleARN: "arn:aws:iam::111122223333:role/acm-manager"}},
+		{" arn:aws:iam::111122223333:role/acm-manager , eu-west-1 ", awsTarget{RoleARN: "arn:aws:iam::111122223333:role/acm-manager", Region: "eu-west-1"}},
+		{",eu-west-1", awsTarget{Region: "eu-west-1"}},
+	}
+	for _, tc := range cases {
+		if got, err := parseNamespaceRole(tc.value); err != nil || got != tc.want {
+			t.Errorf("parseNamespaceRole(%q) = %+v, %v, want %+v", tc.value, got, err, tc.want)
+		}
+	}
+	for _, value := range []string{"acm-manager", "arn:aws:iam::111122223333:user/acm-manager", "arn:aws:s3:::bucket"} {
+		if _, err := parseNamespaceRole(value); err == nil {
+			t.Errorf("parseNamespaceRole(%q) should fail", value)
+		}
+	}
+}
+
+func TestNamespaceRoleMapSelectsClients(t *testing.T) {
+	ctx := context.Background()
+	const teamRole = "arn:aws:iam::111122223333:role/team-a"
+	const overrideRole = "arn:aws:iam::444455556666:role/special"
+	roles := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "acm-manager", Name: "roles"},
+		Data:       map[string]string{"team-a": teamRole + ",eu-west-1"},
+	}
+	mapped := newManagedIngress("web", "app.example.com", nil)
+	mapped.Namespace = "team-a"
+	override := newManagedIngress("special", "special.example.com", map[string]string{roleArnAnnotation: overrideRole})
+	override.Namespace = "team-a"
+	unmapped := newManagedIngress("other", "other.example.com", nil)
+
+	defaultACM := newFakeACM()
+	r := newTestReconciler(t, defaultACM, newFakeRoute53("example.com"), mapped, override, unmapped, roles)
+	r.NamespaceRoleMap = types.NamespacedName{Namespace: "acm-manager", Name: "roles"}
+	built := useTargetClients(r, "example.com")
+
+	for _, ingress := range []*networkingv1.Ingress{mapped, override, unmapped} {
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+			t.Fatalf("Reconcile %s: %v", ingress.Name, err)
+		}
+	}
+
+	teamACM := built[awsTarget{RoleARN: teamRole, Region: "eu-west-1"}]
+	if teamACM == nil || len(teamACM.requests) != 1 || aws.ToString(teamACM.requests[0].DomainName) != "app.example.com" {
+		t.Fatalf("the mapped namespace should use its role and region, built %v", built)
+	}
+	overrideACM := built[awsTarget{RoleARN: overrideRole, Region: "eu-west-1"}]
+	if overrideACM == nil || len(overrideACM.requests) != 1 || aws.ToString(overrideACM.requests[0].DomainName) != "special.example.com" {
+		t.Fatalf("the role annotation should replace the mapped role, built %v", built)
+	}
+	if len(defaultACM.requests) != 1 || aws.ToString(defaultACM.requests[0].DomainName) != "other.example.com" {
+		t.Fatalf("an unmapped namespace should use the default clients, got %d requests", len(defaultACM.requests))
+	}
+	if arn := certificateArnOf(t, r, mapped); arn == "" {
+		t.Fatal("expected the mapped Ingress' certificate to be attached")
+	}
+}
+
+func TestAWSProfileAnnotationNeedsFlag(t *testing.T) {
+	ctx := context.Background()
+	ignored := newManagedIngress("web", "app.example.com", map[string]string{awsProfileAnnotation: "dev"})
+	honored := newManagedIngress("api", "api.example.com", map[string]string{awsProfileAnnotation: "dev"})
+	defaultACM := newFakeACM()
+	r := newTestReconciler(t, defaultACM, newFakeRoute53("example.com"), ignored, honored)
+	built := useTargetClients(r, "example.com")
+
+	if _, err := r.Reconcile(ctx, requestFor(ignored)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(defaultACM.requests) != 1 || len(built) != 0 {
+		t.Fatalf("without --allow-profile-annotation the default clients should be used, built %v", built)
+	}
+
+	r.AllowProfileAnnotation = true
+	if _, err := r.Reconcile(ctx, requestFor(honored)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	profileACM := built[awsTarget{Region: "us-west-2", Profile: "dev"}]
+	if profileACM == nil || len(profileACM.requests) != 1 || aws.ToString(profileACM.requests[0].DomainName) != "api.example.com" {
+		t.Fatalf("the profile's clients should request the certificate, built %v", built)
+	}
+}
+
+func TestNamespaceRoleMapChangeEnqueuesManagedIngresses(t *testing.T) {
+	roles := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "acm-manager", Name: "roles"}}
+	managed := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53(), managed)
+	r.NamespaceRoleMap = types.NamespacedName{Namespace: "acm-manager", Name: "roles"}
+
+	if requests := r.enqueueManagedIngressesForRoleMap(context.Background(), roles); len(requests) != 1 || requests[0].Name != "web" {
+		t.Fatalf("expected the managed ingress to be enqueued, got %v", requests)
+	}
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "acm-manager", Name: "other"}}
+	if requests := r.enqueueManagedIngressesForRoleMap(context.Background(), other); len(requests) != 0 {
+		t.Fatalf("other ConfigMaps should not enqueue, got %v", requests)
+	}
+}
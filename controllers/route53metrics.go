@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// route53InsyncPollInterval is how often a submitted change is checked for INSYNC
+	route53InsyncPollInterval = 10 * time.Second
+	// route53InsyncTimeout is how long a change is followed before it is given up on
+	route53InsyncTimeout = 10 * time.Minute
+	// maxFollowedRoute53Changes is how many changes are followed at a time, keeping the polls
+	// well below Route 53's 5 requests per second; more are not timed
+	maxFollowedRoute53Changes = 20
+)
+
+// Statuses of a record change, the status label of acm_manager_route53_changes_total
+const (
+	route53ChangeSuccess   = "success"
+	route53ChangeThrottled = "throttled"
+	route53ChangeError     = "error"
+)
+
+var (
+	route53Changes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "acm_manager_route53_changes_total",
+		Help: "Route 53 ChangeResourceRecordSets calls, by hosted zone, action and status (success, throttled or error), after SDK retries.",
+	}, []string{"zone_id", "action", "status"})
+	route53ChangeInsync = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "acm_manager_route53_change_insync_duration_seconds",
+		Help: "Time from submitting a Route 53 record change until it was INSYNC, by hosted zone.",
+		// Route 53 usually propagates a change within a minute
+		Buckets: []float64{5, 10, 20, 30, 45, 60, 90, 120, 180, 300, 600},
+	}, []string{"zone_id"})
+	route53Throttles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "acm_manager_route53_throttles_total",
+		Help: "Route 53 requests throttled, counting every attempt the SDK retried, by operation.",
+	}, []string{"operation"})
+)
+
+// route53ChangeStatusAPI reads the propagation status of a submitted change
+type route53ChangeStatusAPI interface {
+	GetChange(ctx context.Context, params *route53.GetChangeInput, optFns ...func(*route53.Options)) (*route53.GetChangeOutput, error)
+}
+
+// meteredRoute53 counts record changes by zone, action and status and hands each successful
+// one to follower, which times it until it is INSYNC
+type meteredRoute53 struct {
+	Route53API
+	status   route53ChangeStatusAPI
+	follower *route53ChangeFollower
+}
+
+// newMeteredRoute53 returns client with Route 53 change metrics, reading change status with
+// status; a nil status or follower records no INSYNC durations
+func newMeteredRoute53(client Route53API, status route53ChangeStatusAPI, follower *route53ChangeFollower) *meteredRoute53 {
+	return &meteredRoute53{Route53API: client, status: status, follower: follower}
+}
+
+func (c *meteredRoute53) ChangeResourceRecordSets(ctx context.Context, in *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	submitted := time.Now()
+	out, err := c.Route53API.ChangeResourceRecordSets(ctx, in, optFns...)
+	zoneID := strings.TrimPrefix(aws.ToString(in.HostedZoneId), "/hostedzone/")
+	status := route53ChangeSuccess
+	switch {
+	case isRoute53Throttle(err):
+		status = route53ChangeThrottled
+	case err != nil:
+		status = route53ChangeError
+	}
+	route53Changes.WithLabelValues(zoneID, changeBatchAction(in.ChangeBatch), status).Inc()
+	if err != nil || out == nil || out.ChangeInfo == nil {
+		return out, err
+	}
+	if out.ChangeInfo.Status == route53types.ChangeStatusInsync {
+		route53ChangeInsync.WithLabelValues(zoneID).Observe(time.Since(submitted).Seconds())
+	} else if c.status != nil && c.follower != nil {
+		// The reconcile does not wait for propagation, so the change is followed on its own
+		c.follower.follow(followedChange{status: c.status, id: aws.ToString(out.ChangeInfo.Id), zoneID: zoneID, submitted: submitted})
+	}
+	return out, err
+}
+
+// followedChange is a submitted record change waiting to be INSYNC
+type followedChange struct {
+	status    route53ChangeStatusAPI
+	id        string
+	zoneID    string
+	submitted time.Time
+}
+
+// route53ChangeFollower polls the submitted record changes of every Route 53 client until they
+// are INSYNC, from the one goroutine it runs for the manager's lifetime. At most
+// maxFollowedRoute53Changes are followed at a time; the ones submitted beyond that are not
+// timed.
+type route53ChangeFollower struct {
+	// pollInterval and timeout default to route53InsyncPollInterval and route53InsyncTimeout
+	pollInterval time.Duration
+	timeout      time.Duration
+	submitted    chan followedChange
+}
+
+func newRoute53ChangeFollower() *route53ChangeFollower {
+	return &route53ChangeFollower{
+		pollInterval: route53InsyncPollInterval,
+		timeout:      route53InsyncTimeout,
+		submitted:    make(chan followedChange, maxFollowedRoute53Changes),
+	}
+}
+
+// follow queues the change to be followed, dropping it when too many are followed already
+func (f *route53ChangeFollower) follow(change followedChange) {
+	select {
+	case f.submitted <- change:
+	default:
+		logf.Log.WithName("route53").V(1).Info("Too many record changes followed, not timing this one", "change", change.id)
+	}
+}
+
+// Start polls the followed changes until ctx is done
+func (f *route53ChangeFollower) Start(ctx context.Context) error {
+	ticker := time.NewTicker(f.pollInterval)
+	defer ticker.Stop()
+	var pending []followedChange
+	for {
+		// Changes wait in the channel, and are then dropped, while the pending ones are full
+		submitted := f.submitted
+		if len(pending) >= maxFollowedRoute53Changes {
+			submitted = nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case change := <-submitted:
+			pending = append(pending, change)
+		case <-ticker.C:
+			pending = f.poll(ctx, pending)
+		}
+	}
+}
+
+// poll checks each pending change and returns the ones still pending. A change INSYNC is
+// observed with how long that took since it was submitted. A change still pending after the
+// timeout, or whose status cannot be read, is not observed.
+func (f *route53ChangeFollower) poll(ctx context.Context, pending []followedChange) []followedChange {
+	remaining := pending[:0]
+	for _, change := range pending {
+		if time.Since(change.submitted) > f.timeout {
+			continue
+		}
+		out, err := change.status.GetChange(ctx, &route53.GetChangeInput{Id: aws.String(change.id)})
+		if err != nil {
+			if ctx.Err() == nil {
+				logf.Log.WithName("route53").V(1).Info("Failed to read the status of a record change", "change", change.id, "error", err.Error())
+			}
+			continue
+		}
+		if out.ChangeInfo != nil && out.ChangeInfo.Status == route53types.ChangeStatusInsync {
+			route53ChangeInsync.WithLabelValues(change.zoneID).Observe(time.Since(change.submitted).Seconds())
+			continue
+		}
+		remaining = append(remaining, change)
+	}
+	return remaining
+}
+
+// changeBatchAction returns the action of the batch's changes, which the controller sends one
+// at a time, or "MIXED" when they differ
+func changeBatchAction(batch *route53types.ChangeBatch) string {
+	if batch == nil || len(batch.Changes) == 0 {
+		return ""
+	}
+	action := batch.Changes[0].Action
+	for _, change := range batch.Changes[1:] {
+		if change.Action != action {
+			return "MIXED"
+		}
+	}
+	return string(action)
+}
+
+// isRoute53Throttle reports whether err is Route 53 throttling the request, including the
+// PriorRequestNotComplete it returns for changes made too quickly
+func isRoute53Throttle(err error) bool {
+	return err != nil && retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary
+}
+
+// countRoute53Throttles counts every throttled attempt of the client's requests, including ones
+// the SDK retries successfully
+func countRoute53Throttles(o *route53.Options) {
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		// After Retry, the middleware sees each attempt rather than the final result
+		return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("CountThrottles",
+			func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+				out, metadata, err := next.HandleFinalize(ctx, in)
+				if isRoute53Throttle(err) {
+					route53Throttles.WithLabelValues(middleware.GetOperationName(ctx)).Inc()
+				}
+				return out, metadata, err
+			}), "Retry", middleware.After)
+	})
+}
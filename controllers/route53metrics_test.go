@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pendingRoute53 submits changes as PENDING and reports them INSYNC from GetChange
+type pendingRoute53 struct {
+	*fakeRoute53
+	checks atomic.Int32
+}
+
+func (f *pendingRoute53) ChangeResourceRecordSets(ctx context.Context, in *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	if _, err := f.fakeRoute53.ChangeResourceRecordSets(ctx, in, optFns...); err != nil {
+		return nil, err
+	}
+	return &route53.ChangeResourceRecordSetsOutput{ChangeInfo: &route53types.ChangeInfo{
+		Id:     aws.String("/change/C1"),
+		Status: route53types.ChangeStatusPending,
+	}}, nil
+}
+
+func (f *pendingRoute53) GetChange(_ context.Context, in *route53.GetChangeInput, _ ...func(*route53.Options)) (*route53.GetChangeOutput, error) {
+	status := route53types.ChangeStatusPending
+	if f.checks.Add(1) > 1 {
+		status = route53types.ChangeStatusInsync
+	}
+	return &route53.GetChangeOutput{ChangeInfo: &route53types.ChangeInfo{Id: in.Id, Status: status}}, nil
+}
+
+func upsertIn(zoneID string) *route53.ChangeResourceRecordSetsInput {
+	return &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String("/hostedzone/" + zoneID),
+		ChangeBatch: &route53types.ChangeBatch{Changes: []route53types.Change{{
+			Action:            route53types.ChangeActionUpsert,
+			ResourceRecordSet: &route53types.ResourceRecordSet{Name: aws.String("_x.app.example.com."), Type: route53types.RRTypeCname},
+		}}},
+	}
+}
+
+func TestRoute53ChangesCountedAndFollowedUntilInsync(t *testing.T) {
+	fake := &pendingRoute53{fakeRoute53: newFakeRoute53("example.com")}
+	follower := newRoute53ChangeFollower()
+	follower.pollInterval = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = follower.Start(ctx) }()
+	client := newMeteredRoute53(fake, fake, follower)
+	changed := route53Changes.WithLabelValues("ZMETERED", "UPSERT", route53ChangeSuccess)
+	throttled := route53Changes.WithLabelValues("ZMETERED", "UPSERT", route53ChangeThrottled)
+	insync := route53ChangeInsync.WithLabelValues("ZMETERED").(prometheus.Histogram)
+	changes, throttledChanges, insyncChanges := metricValue(t, changed), metricValue(t, throttled), metricValue(t, insync)
+
+	if _, err := client.ChangeResourceRecordSets(context.Background(), upsertIn("ZMETERED")); err != nil {
+		t.Fatalf("ChangeResourceRecordSets: %v", err)
+	}
+	if got := metricValue(t, changed); got != changes+1 {
+		t.Errorf("changes counted = %v, want %v", got, changes+1)
+	}
+	waitForMetric(t, insync, insyncChanges+1)
+	if checks := fake.checks.Load(); checks != 2 {
+		t.Errorf("expected the change to be checked until INSYNC, checked %d times", checks)
+	}
+
+	// A change that fails after the SDK's retries is counted by why
+	fake.err = &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"}
+	if _, err := client.ChangeResourceRecordSets(context.Background(), upsertIn("ZMETERED")); err == nil {
+		t.Fatal("expected the throttled change to fail")
+	}
+	if got := metricValue(t, throttled); got != throttledChanges+1 {
+		t.Errorf("throttled changes counted = %v, want %v", got, throttledChanges+1)
+	}
+}
+
+func TestRoute53ChangeFollowerIsBounded(t *testing.T) {
+	follower := newRoute53ChangeFollower()
+	for range maxFollowedRoute53Changes + 1 {
+		follower.follow(followedChange{id: "/change/C1", zoneID: "ZMETERED", submitted: time.Now()})
+	}
+	if queued := len(follower.submitted); queued != maxFollowedRoute53Changes {
+		t.Fatalf("queued changes = %d, want at most %d", queued, maxFollowedRoute53Changes)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- follower.Start(ctx) }()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the follower kept running after its context was done")
+	}
+}
+
+func TestRoute53ThrottledAttemptsCounted(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<ListHostedZonesResponse><HostedZones></HostedZones><IsTruncated>false</IsTruncated><MaxItems>1</MaxItems></ListHostedZonesResponse>`))
+	}))
+	defer server.Close()
+
+	client := route53.New(route53.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		}),
+	}, countRoute53Throttles)
+	throttles := metricValue(t, route53Throttles.WithLabelValues("ListHostedZones"))
+
+	if _, err := client.ListHostedZones(context.Background(), &route53.ListHostedZonesInput{}); err != nil {
+		t.Fatalf("ListHostedZones: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Fatalf("expected the throttled request to be retried, got %d requests", requests.Load())
+	}
+	if got := metricValue(t, route53Throttles.WithLabelValues("ListHostedZones")); got != throttles+1 {
+		t.Errorf("throttles counted = %v, want %v", got, throttles+1)
+	}
+}
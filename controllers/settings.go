@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"time"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+// LiveSettings are the reconciler options that can change while the controller runs. They
+// are read at each use, never captured by the clients and runnables SetupWithManager builds.
+type LiveSettings struct {
+	// ManageByDefault treats every Ingress as managed unless it is annotated
+	// acm.tedens.dev/managed: "false"
+	ManageByDefault bool
+
+	// IncludeWWW adds www.<domain> to the certificates of apex domains unless an Ingress sets
+	// acm.tedens.dev/include-www itself
+	IncludeWWW bool
+	// DomainSuffix is appended to hosts without a dot unless an Ingress sets
+	// acm.tedens.dev/domain-suffix
+	DomainSuffix string
+
+	// NamespaceTagAnnotations are Namespace annotations copied as tags, under the same key, onto
+	// the certificates of the Namespace's Ingresses when requested, imported and reconciled
+	NamespaceTagAnnotations []string
+
+	// ReuseCertificateStatuses are the statuses of existing certificates considered for
+	// reuse; empty means ISSUED and PENDING_VALIDATION
+	ReuseCertificateStatuses []acmtypes.CertificateStatus
+
+	// ALBIngressControllers are the IngressClass controllers that read the ALB annotations;
+	// empty means DefaultALBIngressController. Managed Ingresses of other classes get no
+	// certificate unless they are provision-only or set acm.tedens.dev/force-non-alb.
+	ALBIngressControllers []string
+
+	// ValidationLagThreshold is how long after its validation records were written a name may
+	// stay pending while the certificate's other names validated before a ValidationLagging
+	// event names it; zero disables the event
+	ValidationLagThreshold time.Duration
+
+	// MaxNamesPerCertificate is how many names fit on one certificate; zero means ACM's default
+	// of 10. With AutoSplitCertificates an Ingress with more names gets several certificates,
+	// otherwise it fails.
+	MaxNamesPerCertificate int
+	AutoSplitCertificates  bool
+
+	// WildcardSANPolicy decides what happens to SANs a wildcard name of the same certificate
+	// already covers: WildcardSANPolicyKeep (the default when empty), WildcardSANPolicyPrune
+	// or WildcardSANPolicyError
+	WildcardSANPolicy string
+
+	// NoHostRequeueAttempts is how many times a managed Ingress without a host is checked
+	// again, NoHostRequeueInterval apart, before it is skipped with a Warning event; zero skips
+	// it right away
+	NoHostRequeueAttempts int
+	NoHostRequeueInterval time.Duration
+
+	// QuarantineAfterFailures quarantines an Ingress after this many consecutive failures that
+	// retrying is unlikely to fix, probing it only every QuarantineProbeInterval (zero means
+	// DefaultQuarantineProbeInterval); zero never quarantines
+	QuarantineAfterFailures int
+	QuarantineProbeInterval time.Duration
+
+	// RenewBefore is how close to expiry an attached certificate may get before the controller
+	// escalates its renewal, unless acm.tedens.dev/renew-before says otherwise; zero means 30 days
+	RenewBefore time.Duration
+
+	// CertificateArnConflict is how certificate ARN annotations changed outside the controller
+	// are handled: CertificateArnConflictYield (also when empty) or CertificateArnConflictWarn
+	CertificateArnConflict string
+
+	// Notifier, when set, receives the same issued/failed/expiring events recorded on Ingresses
+	Notifier Notifier
+	// PostIssuanceHook, when set, is notified once per certificate newly attached to an Ingress
+	PostIssuanceHook Notifier
+}
+
+// settings returns the live settings in effect: the embedded ones until ApplySettings is
+// called. Callers read it once per decision, since a reload may replace it at any time.
+func (r *IngressReconciler) settings() *LiveSettings {
+	if s := r.live.Load(); s != nil {
+		return s
+	}
+	return &r.LiveSettings
+}
+
+// ApplySettings replaces the live settings for everything the reconciler does from now on.
+// It is safe to call while reconciles run; each read of the settings sees either the old or
+// the new ones as a whole.
+func (r *IngressReconciler) ApplySettings(s LiveSettings) {
+	r.live.Store(&s)
+}
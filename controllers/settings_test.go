@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestApplySettingsTakesEffectOnNextRead(t *testing.T) {
+	ctx := context.Background()
+	ingress := newUnannotatedIngress("web", "app", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+
+	cfg, err := r.ingressConfig(ctx, ingress, nil)
+	if err != nil {
+		t.Fatalf("ingressConfig: %v", err)
+	}
+	if cfg.Managed || cfg.DomainSuffix != "" {
+		t.Fatalf("before the reload got managed=%v suffix=%q, want neither", cfg.Managed, cfg.DomainSuffix)
+	}
+
+	r.ApplySettings(LiveSettings{ManageByDefault: true, DomainSuffix: "example.com"})
+	cfg, err = r.ingressConfig(ctx, ingress, nil)
+	if err != nil {
+		t.Fatalf("ingressConfig: %v", err)
+	}
+	if !cfg.Managed || cfg.DomainSuffix != "example.com" {
+		t.Fatalf("after the reload got managed=%v suffix=%q, want the reloaded settings", cfg.Managed, cfg.DomainSuffix)
+	}
+
+	// The settings the reconciler started with no longer apply
+	r.ManageByDefault = false
+	if !r.settings().ManageByDefault {
+		t.Fatal("the startup settings replaced the reloaded ones")
+	}
+}
+
+func TestApplySettingsWhileReconciling(t *testing.T) {
+	r := &IngressReconciler{}
+	var wg sync.WaitGroup
+	for i := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				r.ApplySettings(LiveSettings{MaxNamesPerCertificate: 20 + i})
+			}
+		}()
+	}
+	for range 100 {
+		if limit := r.maxNames(); limit != maxNamesPerCertificate && limit != 20 && limit != 21 {
+			t.Fatalf("maxNames() = %d, want a value from some settings", limit)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"hash/fnv"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// shardOf returns which of count shards owns the Ingress. Members of a certificate group all
+// hash the group, so one replica reconciles the whole group; other Ingresses hash their UID.
+func shardOf(ingress *networkingv1.Ingress, count int) int {
+	key := certificateGroup(ingress)
+	if key == "" {
+		key = string(ingress.UID)
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(count))
+}
+
+// inShard reports whether this replica's shard owns obj; without sharding it owns everything
+func (r *IngressReconciler) inShard(obj client.Object) bool {
+	ingress, ok := obj.(*networkingv1.Ingress)
+	if r.ShardCount <= 1 || !ok {
+		return true
+	}
+	return shardOf(ingress, r.ShardCount) == r.ShardIndex
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestShardOfCoversEveryIngressOnce(t *testing.T) {
+	for count := 2; count <= 5; count++ {
+		seen := make([]int, count)
+		for i := 0; i < 200; i++ {
+			ingress := newManagedIngress(fmt.Sprintf("web-%d", i), "app.example.com", nil)
+			ingress.UID = types.UID(fmt.Sprintf("uid-%d", i))
+			owners := 0
+			for index := 0; index < count; index++ {
+				r := &IngressReconciler{ShardCount: count, ShardIndex: index}
+				if r.inShard(ingress) {
+					owners++
+					seen[index]++
+				}
+			}
+			if owners != 1 {
+				t.Fatalf("%s has %d owners among %d shards, want exactly 1", ingress.Name, owners, count)
+			}
+		}
+		if slices.Contains(seen, 0) {
+			t.Fatalf("expected every one of %d shards to own some Ingresses, got %v", count, seen)
+		}
+	}
+}
+
+func TestShardKeepsCertificateGroupsTogether(t *testing.T) {
+	group := map[string]string{certGroupAnnotation: "shop"}
+	first := newManagedIngress("shop-a", "a.example.com", group)
+	first.UID = "uid-a"
+	for i := 0; i < 20; i++ {
+		other := newManagedIngress(fmt.Sprintf("shop-%d", i), "b.example.com", group)
+		other.UID = types.UID(fmt.Sprintf("uid-%d", i))
+		if shardOf(other, 4) != shardOf(first, 4) {
+			t.Fatalf("members of a certificate group must share a shard")
+		}
+	}
+}
+
+func TestReconcileSkipsOtherShards(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	ingress.UID = "uid-web"
+	owner := shardOf(ingress, 3)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.ShardCount = 3
+	r.ShardIndex = (owner + 1) % 3
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 0 || slices.Contains(getIngress(t, r, ingress).Finalizers, DefaultFinalizerName) {
+		t.Fatal("another shard's Ingress must be left alone")
+	}
+	if n, err := r.ReconcileAll(ctx); err != nil || n != 0 {
+		t.Fatalf("ReconcileAll must skip other shards, enqueued %d, %v", n, err)
+	}
+
+	r.ShardIndex = owner
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("the owning shard must reconcile the Ingress, got %d requests", len(fakeACM.requests))
+	}
+}
+
+func TestReshardingReleasesFinalizers(t *testing.T) {
+	ctx := context.Background()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	ingress.UID = "uid-web"
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.ShardCount = 2
+	r.ShardIndex = shardOf(ingress, 2)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	if !slices.Contains(got.Finalizers, DefaultFinalizerName) {
+		t.Fatal("expected the finalizer")
+	}
+	if err := r.Delete(ctx, got); err != nil {
+		t.Fatalf("delete ingress: %v", err)
+	}
+
+	// After scaling to 5 shards the Ingress' new owner finishes the deletion
+	r.ShardCount = 5
+	r.ShardIndex = shardOf(ingress, 5)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	var remaining networkingv1.IngressList
+	if err := r.List(ctx, &remaining); err != nil {
+		t.Fatalf("list ingresses: %v", err)
+	}
+	if len(remaining.Items) != 0 {
+		t.Fatalf("the new owner must remove the finalizer, got %v", remaining.Items[0].Finalizers)
+	}
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// primaryAnnotation designates the Ingress that manages the certificate for a shared domain
+	primaryAnnotation = "acm.tedens.dev/primary"
+	// ownerTagKey is the certificate tag naming the namespace/name of the owning Ingress
+	ownerTagKey = "acm.tedens.dev/owner"
+)
+
+// sharedCertificateRequeue is how long a secondary Ingress waits for the primary's certificate
+var sharedCertificateRequeue = time.Minute
+
+// resolveDomain returns the certificate domain for an Ingress, qualified with its domain suffix.
+// Without a domain it is the host of the first rule, or of the rule selected by
+// acm.tedens.dev/primary-rule-index when that is valid. Without rule hosts,
+// acm.tedens.dev/domain-from: status-lb takes it from the load balancer hostname.
+func resolveDomain(ingress *networkingv1.Ingress, cfg IngressConfig) string {
+	domain := cfg.DomainOverride
+	if domain == "" && len(ingress.Spec.Rules) > 0 {
+		index, err := primaryRuleIndex(ingress, cfg)
+		if err != nil {
+			index = 0
+		}
+		domain = ingress.Spec.Rules[index].Host
+	}
+	if domain == "" && cfg.DomainFrom == DomainFromStatusLB && !hasRuleHosts(ingress) {
+		domain = statusLBHostname(ingress)
+	}
+	return qualifyHost(domain, cfg.DomainSuffix)
+}
+
+// acmPrimaryDomain returns the name ACM lists the certificate for domain and sans under, a SAN
+// when domain is too long to be the primary domain
+func acmPrimaryDomain(domain string, sans []string) string {
+	primary, _, err := certs.PrimaryDomain(domain, sans)
+	if err != nil {
+		return domain
+	}
+	return primary
+}
+
+func ingressKey(ingress *networkingv1.Ingress) string {
+	return ingress.Namespace + "/" + ingress.Name
+}
+
+// isDomainPrimary reports whether the Ingress owns the certificate for domain. Among managed
+// Ingresses resolving to the same domain, one annotated acm.tedens.dev/primary wins, then the
+// oldest, then the lowest namespace/name. An Ingress being deleted is never primary while
+// other Ingresses still share its domain, so the certificate outlives it.
+func (r *IngressReconciler) isDomainPrimary(ctx context.Context, ingress *networkingv1.Ingress, domain string, policy map[string]string) (bool, error) {
+	if domain == "" {
+		return true, nil
+	}
+
+	var list networkingv1.IngressList
+	if err := r.List(ctx, &list); err != nil {
+		return false, err
+	}
+
+	candidates := []networkingv1.Ingress{*ingress}
+	for _, other := range list.Items {
+		if ingressKey(&other) == ingressKey(ingress) || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		otherCfg, err := r.ingressConfig(ctx, &other, policy)
+		if err != nil {
+			return false, err
+		}
+		if !otherCfg.Managed || !strings.EqualFold(resolveDomain(&other, otherCfg), domain) {
+			continue
+		}
+		candidates = append(candidates, other)
+	}
+	if len(candidates) == 1 {
+		return true, nil
+	}
+	if !ingress.DeletionTimestamp.IsZero() {
+		return false, nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		pi := candidates[i].Annotations[primaryAnnotation] == "true"
+		pj := candidates[j].Annotations[primaryAnnotation] == "true"
+		if pi != pj {
+			return pi
+		}
+		ti, tj := candidates[i].CreationTimestamp, candidates[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return ingressKey(&candidates[i]) < ingressKey(&candidates[j])
+	})
+	return ingressKey(&candidates[0]) == ingressKey(ingress), nil
+}
+
+// tagCertificateOwner records the owning Ingress on the certificate and among its owners
+func (r *IngressReconciler) tagCertificateOwner(ctx context.Context, certArn string, ingress *networkingv1.Ingress) error {
+	_, err := r.acm(ctx).AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+		CertificateArn: aws.String(certArn),
+		Tags: []acmtypes.Tag{
+			{Key: aws.String(ownerTagKey), Value: aws.String(ingressKey(ingress))},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return r.addCertificateOwner(ctx, certArn, ingress)
+}
+
+// findIssuedCertificate returns an issued certificate owned by this instance for domain, or ""
+func (r *IngressReconciler) findIssuedCertificate(ctx context.Context, domain string) (string, error) {
+	paginator := acm.NewListCertificatesPaginator(r.acm(ctx), &acm.ListCertificatesInput{
+		CertificateStatuses: []acmtypes.CertificateStatus{
+			acmtypes.CertificateStatusIssued,
+		},
+		Includes: certs.AllKeyTypes(),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, cert := range page.CertificateSummaryList {
+			if !strings.EqualFold(aws.ToString(cert.DomainName), domain) {
+				continue
+			}
+			owned, err := r.ownsCertificate(ctx, aws.ToString(cert.CertificateArn))
+			if err != nil {
+				return "", err
+			}
+			if owned {
+				return aws.ToString(cert.CertificateArn), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// attachSharedCertificate attaches the primary Ingress' certificate to a secondary Ingress
+// without requesting, validating or deleting anything
+func (r *IngressReconciler) attachSharedCertificate(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, domain string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	certDomain := certificateDomain(domain, cfg)
+
+	// The tags of the primary Ingress' Namespace are left to it
+	if err := r.syncCertificateTags(ctx, ingress, certificateArnKey(cfg), false); err != nil {
+		return ctrl.Result{}, err
+	}
+	certArn, err := r.findIssuedCertificate(ctx, acmPrimaryDomain(certDomain, cfg.SANs))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if certArn == "" {
+		logger.Info("Domain is managed by another Ingress, waiting for its certificate", "domain", domain)
+		return ctrl.Result{RequeueAfter: sharedCertificateRequeue}, nil
+	}
+
+	// The primary Ingress requested the certificate, so its attach fires the hook
+	if err := r.attachCertificate(ctx, ingress, cfg, domain, []string{certArn}, nil); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.addCertificateOwner(ctx, certArn, ingress); err != nil {
+		logOwnerError(ctx, err, certArn)
+	}
+	return recheckAfter(ctx, recheckInterval), nil
+}
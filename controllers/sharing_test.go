@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// sharedIngresses returns three managed Ingresses on one domain, oldest first
+func sharedIngresses(annotations map[string]map[string]string) []*networkingv1.Ingress {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var out []*networkingv1.Ingress
+	for i, name := range []string{"a", "b", "c"} {
+		ingress := newManagedIngress(name, "app.example.com", annotations[name])
+		ingress.CreationTimestamp = metav1.NewTime(created.Add(time.Duration(i) * time.Hour))
+		out = append(out, ingress)
+	}
+	return out
+}
+
+func certificateArnOf(t *testing.T, r *IngressReconciler, ingress *networkingv1.Ingress) string {
+	t.Helper()
+	var got networkingv1.Ingress
+	if err := r.Get(context.Background(), requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress %s: %v", ingress.Name, err)
+	}
+	return got.Annotations["alb.ingress.kubernetes.io/certificate-arn"]
+}
+
+func TestSharedDomainOnlyPrimaryRequestsCertificate(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingresses := sharedIngresses(nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingresses[0], ingresses[1], ingresses[2])
+
+	// a secondary reconciled before the primary waits without requesting
+	result, err := r.Reconcile(ctx, requestFor(ingresses[1]))
+	if err != nil {
+		t.Fatalf("Reconcile b: %v", err)
+	}
+	if result.RequeueAfter != sharedCertificateRequeue || len(fakeACM.requests) != 0 {
+		t.Fatalf("secondary should wait for the primary, got %+v with %d requests", result, len(fakeACM.requests))
+	}
+
+	for _, ingress := range ingresses {
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+			t.Fatalf("Reconcile %s: %v", ingress.Name, err)
+		}
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected exactly one certificate request, got %d", len(fakeACM.requests))
+	}
+
+	arn := certificateArnOf(t, r, ingresses[0])
+	if arn == "" {
+		t.Fatal("primary should have a certificate attached")
+	}
+	for _, ingress := range ingresses[1:] {
+		if got := certificateArnOf(t, r, ingress); got != arn {
+			t.Fatalf("%s should share %s, got %q", ingress.Name, arn, got)
+		}
+	}
+	if owner := fakeACM.tagValue(arn, ownerTagKey); owner != "default/a" {
+		t.Fatalf("certificate owner tag = %q, want default/a", owner)
+	}
+}
+
+func TestSharedDomainPrimaryAnnotationWins(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingresses := sharedIngresses(map[string]map[string]string{"c": {primaryAnnotation: "true"}})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingresses[0], ingresses[1], ingresses[2])
+
+	if _, err := r.Reconcile(ctx, requestFor(ingresses[0])); err != nil {
+		t.Fatalf("Reconcile a: %v", err)
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatal("the oldest Ingress must not request when another is annotated primary")
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingresses[2])); err != nil {
+		t.Fatalf("Reconcile c: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected the annotated primary to request once, got %d", len(fakeACM.requests))
+	}
+	arn := certificateArnOf(t, r, ingresses[2])
+	if owner := fakeACM.tagValue(arn, ownerTagKey); owner != "default/c" {
+		t.Fatalf("certificate owner tag = %q, want default/c", owner)
+	}
+}
+
+func TestSharedCertificateSurvivesPrimaryDeletion(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	deleteCert := map[string]string{"acm.tedens.dev/delete-cert-on-ingress-delete": "true"}
+	ingresses := sharedIngresses(map[string]map[string]string{"a": deleteCert, "b": deleteCert, "c": deleteCert})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingresses[0], ingresses[1], ingresses[2])
+
+	for _, ingress := range ingresses {
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+			t.Fatalf("Reconcile %s: %v", ingress.Name, err)
+		}
+	}
+
+	var primary networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingresses[0]).NamespacedName, &primary); err != nil {
+		t.Fatalf("get a: %v", err)
+	}
+	if err := r.Delete(ctx, &primary); err != nil {
+		t.Fatalf("delete a: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingresses[0])); err != nil {
+		t.Fatalf("Reconcile a after delete: %v", err)
+	}
+	if len(fakeACM.deleted) != 0 {
+		t.Fatalf("certificate still used by b and c must not be deleted, deleted %v", fakeACM.deleted)
+	}
+}
+
+func TestSharedCertificateDeletionIsReferenceCounted(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	deleteCert := map[string]string{"acm.tedens.dev/delete-cert-on-ingress-delete": "true"}
+	ingresses := sharedIngresses(map[string]map[string]string{"a": deleteCert, "b": deleteCert, "c": deleteCert})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingresses[0], ingresses[1], ingresses[2])
+	for _, ingress := range ingresses {
+		if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+			t.Fatalf("Reconcile %s: %v", ingress.Name, err)
+		}
+	}
+	arn := certificateArnOf(t, r, ingresses[0])
+	if owners := fakeACM.tagValue(arn, ownersTagKey); owners != "default/a default/b default/c" {
+		t.Fatalf("owners tag = %q, want every Ingress attaching the certificate", owners)
+	}
+	// An Ingress the domain check cannot see, such as one in another shard, holds it too
+	if _, err := fakeACM.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+		CertificateArn: aws.String(arn),
+		Tags:           []acmtypes.Tag{{Key: aws.String(ownersTagKey), Value: aws.String("default/a default/b default/c other/web")}},
+	}); err != nil {
+		t.Fatalf("tag certificate: %v", err)
+	}
+
+	deleteIngress := func(ingress *networkingv1.Ingress) error {
+		t.Helper()
+		if err := r.Delete(ctx, getIngress(t, r, ingress)); err != nil {
+			t.Fatalf("delete %s: %v", ingress.Name, err)
+		}
+		_, err := r.Reconcile(ctx, requestFor(ingress))
+		return err
+	}
+	for _, ingress := range ingresses {
+		if err := deleteIngress(ingress); err != nil {
+			t.Fatalf("Reconcile %s after delete: %v", ingress.Name, err)
+		}
+	}
+	if len(fakeACM.deleted) != 0 {
+		t.Fatalf("certificate still held by other/web must not be deleted, deleted %v", fakeACM.deleted)
+	}
+	if owners := fakeACM.tagValue(arn, ownersTagKey); owners != "other/web" {
+		t.Fatalf("owners tag = %q, want only other/web left", owners)
+	}
+
+	// The last owner's deletion waits for the load balancer to let go of the certificate
+	last := newManagedIngress("web", "app.example.com", deleteCert)
+	last.Namespace = "other"
+	last.Annotations["alb.ingress.kubernetes.io/certificate-arn"] = arn
+	last.Finalizers = []string{DefaultFinalizerName}
+	if err := r.Create(ctx, last); err != nil {
+		t.Fatalf("create other/web: %v", err)
+	}
+	fakeACM.certs[arn].InUseBy = []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/1"}
+	if err := deleteIngress(last); err == nil || !strings.Contains(err.Error(), "still in use") {
+		t.Fatalf("expected the deletion to wait while the certificate is in use, got %v", err)
+	}
+	fakeACM.certs[arn].InUseBy = nil
+	if _, err := r.Reconcile(ctx, requestFor(last)); err != nil {
+		t.Fatalf("Reconcile other/web once unused: %v", err)
+	}
+	if !slices.Contains(fakeACM.deleted, arn) {
+		t.Fatalf("expected the certificate deleted with its last owner, deleted %v", fakeACM.deleted)
+	}
+}
+
+func TestOverflowedOwnersKeepCertificate(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+	if _, err := fakeACM.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+		CertificateArn: aws.String(arn),
+		Tags:           []acmtypes.Tag{{Key: aws.String(ownersTagKey), Value: aws.String("default/web " + strings.Repeat("x", 240))}},
+	}); err != nil {
+		t.Fatalf("tag certificate: %v", err)
+	}
+
+	other := newManagedIngress("other", "app.example.com", nil)
+	if err := r.addCertificateOwner(ctx, arn, other); err != nil {
+		t.Fatalf("addCertificateOwner: %v", err)
+	}
+	if fakeACM.tagValue(arn, ownersOverflowTagKey) != "true" {
+		t.Fatalf("expected %s on a certificate whose owners no longer fit", ownersOverflowTagKey)
+	}
+	if held, err := r.certificateStillHeld(ctx, arn, "default/web"); err != nil || !held {
+		t.Fatalf("certificateStillHeld = %v, %v; want an overflowed certificate held", held, err)
+	}
+}
+
+func TestConcurrentOwnersAreAllRecorded(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	arn := certificateArnOf(t, r, ingress)
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.addCertificateOwner(ctx, arn, newManagedIngress(name, "app.example.com", nil)); err != nil {
+				t.Errorf("addCertificateOwner %s: %v", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if owners := fakeACM.tagValue(arn, ownersTagKey); owners != "default/a default/b default/c default/d default/web" {
+		t.Fatalf("owners tag = %q, want every concurrently added owner", owners)
+	}
+}
@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// errTooManyNames reports an Ingress with more names than fit on one certificate
+type errTooManyNames struct {
+	names, limit int
+}
+
+func (e *errTooManyNames) Error() string {
+	return fmt.Sprintf("%d names exceed the limit of %d per certificate; set --auto-split-certificates to split them", e.names, e.limit)
+}
+
+// maxNames returns the number of names that fit on one certificate
+func (r *IngressReconciler) maxNames() int {
+	if limit := r.settings().MaxNamesPerCertificate; limit > 0 {
+		return limit
+	}
+	return maxNamesPerCertificate
+}
+
+// splitNames splits the primary domain and SANs into chunks of at most limit names. The
+// primary domain leads the first chunk. The SANs follow grouped by zone, starting with the
+// primary domain's, so each chunk's validation records land in as few zones as possible.
+func splitNames(domain string, sans []string, limit int, zones map[string]string) [][]string {
+	rank := map[string]int{zones[domain]: 0}
+	rest := slices.Clone(sans)
+	slices.Sort(rest)
+	for _, san := range rest {
+		if _, ok := rank[zones[san]]; !ok {
+			rank[zones[san]] = len(rank)
+		}
+	}
+	slices.SortStableFunc(rest, func(a, b string) int {
+		return rank[zones[a]] - rank[zones[b]]
+	})
+	return slices.Collect(slices.Chunk(append([]string{domain}, rest...), limit))
+}
+
+// stableSplit splits the names like splitNames but keeps each name in its published chunk,
+// so a name change replaces as few certificates as possible. Removed names leave their chunks,
+// and new names fill up the last chunk before starting new ones. Unless the published chunks
+// still start with the primary domain and fit in limit, it falls back to splitNames.
+func stableSplit(domain string, sans []string, limit int, zones map[string]string, published [][]string) [][]string {
+	wanted := map[string]string{strings.ToLower(domain): domain}
+	for _, san := range sans {
+		wanted[strings.ToLower(san)] = san
+	}
+
+	var chunks [][]string
+	placed := map[string]bool{}
+	for _, names := range published {
+		if len(names) > limit {
+			return splitNames(domain, sans, limit, zones)
+		}
+		var chunk []string
+		for _, name := range names {
+			if original, ok := wanted[strings.ToLower(name)]; ok && !placed[strings.ToLower(name)] {
+				placed[strings.ToLower(name)] = true
+				chunk = append(chunk, original)
+			}
+		}
+		if len(chunk) > 0 {
+			chunks = append(chunks, chunk)
+		}
+	}
+	if len(chunks) == 0 || chunks[0][0] != domain {
+		return splitNames(domain, sans, limit, zones)
+	}
+
+	var added []string
+	for _, san := range sans {
+		if !placed[strings.ToLower(san)] {
+			placed[strings.ToLower(san)] = true
+			added = append(added, san)
+		}
+	}
+	// splitNames orders the new names by zone; only the primary domain is already placed
+	if rest := splitNames(domain, added, len(added)+1, zones); len(rest) > 0 {
+		added = rest[0][1:]
+	}
+	for _, name := range added {
+		if last := len(chunks) - 1; len(chunks[last]) < limit {
+			chunks[last] = append(chunks[last], name)
+		} else {
+			chunks = append(chunks, []string{name})
+		}
+	}
+	return chunks
+}
+
+// publishedChunks returns the names of each certificate, primary domain first
+func publishedChunks(certificates []*acmtypes.CertificateDetail) [][]string {
+	var chunks [][]string
+	for _, cert := range certificates {
+		primary := aws.ToString(cert.DomainName)
+		names := []string{primary}
+		for _, san := range cert.SubjectAlternativeNames {
+			if !strings.EqualFold(san, primary) {
+				names = append(names, san)
+			}
+		}
+		chunks = append(chunks, names)
+	}
+	return chunks
+}
+
+// nameZones returns the zone of each of names according to the DNS provider
+func nameZones(ctx context.Context, dnsProvider DNSProvider, zoneID string, names []string) (map[string]string, error) {
+	zones := make(map[string]string, len(names))
+	for _, name := range names {
+		if zoneID != "" {
+			zones[name] = zoneID
+			continue
+		}
+		zone, err := dnsProvider.FindZone(ctx, strings.TrimPrefix(name, "*."))
+		if err != nil {
+			return nil, err
+		}
+		zones[name] = zone
+	}
+	return zones, nil
+}
+
+// reconcileSplit ensures one certificate per chunk of the Ingress' names and attaches all of
+// them, which the ALB serves by SNI. Like group certificates, chunks are always reused so
+// each reconcile finds the certificates it requested before.
+func (r *IngressReconciler) reconcileSplit(ctx context.Context, ingress *networkingv1.Ingress, cfg IngressConfig, domain string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	certDomain := certificateDomain(domain, cfg)
+
+	dnsProvider, err := r.dnsProviderFor(ctx, ingress, cfg)
+	var zones map[string]string
+	if err == nil {
+		zones, err = nameZones(ctx, dnsProvider, cfg.ZoneID, append([]string{certDomain}, cfg.SANs...))
+	}
+	if err != nil {
+		r.notifyCertificateEvent(ingress, NotificationFailed, domain, "", "", err.Error())
+		return ctrl.Result{}, err
+	}
+	key := certificateArnKey(cfg)
+	// Tags removed outside the controller are put back first, or reuse would not find the
+	// split certificates
+	if err := r.syncCertificateTags(ctx, ingress, key, true); err != nil {
+		return ctrl.Result{}, err
+	}
+	previous := attachedCertificateArns(ingress, key)
+	var published [][]string
+	if len(previous) > 0 {
+		certificates, err := r.describeCertificates(ctx, previous)
+		if err != nil {
+			logger.Info("Could not read the attached certificates, splitting the names afresh", "reason", err.Error())
+		} else {
+			published = publishedChunks(certificates)
+		}
+	}
+	chunks := stableSplit(certDomain, cfg.SANs, r.maxNames(), zones, published)
+	logger.Info("Splitting the Ingress' names over several certificates", "domain", domain, "certificates", len(chunks))
+
+	var certArns, requested, pending []string
+	for _, names := range chunks {
+		result, err := r.certManager(ctx).Ensure(ctx, certs.EnsureRequest{
+			Domain:                  names[0],
+			SubjectAlternativeNames: names[1:],
+			ReuseExisting:           true,
+			DNS:                     dnsProvider,
+			ZoneID:                  cfg.ZoneID,
+			KeyAlgorithm:            cfg.KeyAlgorithm,
+			DisableCTLogging:        cfg.DisableCTLogging,
+			Tags:                    cfg.Tags,
+			OnProgress:              r.validationProgressRecorder(ingress),
+			OnLagging:               r.validationLagRecorder(ingress),
+			LagThreshold:            r.settings().ValidationLagThreshold,
+			OnValidationExpired:     r.validationExpiredRecorder(ingress),
+			IdempotencyToken:        requestToken(ingress, names[0], names[1:]),
+			NoWait:                  r.RequeuePendingValidation,
+			Wanted:                  r.ingressWanted(ingress),
+		})
+		if errors.Is(err, certs.ErrAbandoned) {
+			logger.Info("Ingress went away while a split certificate was validating, deleting the certificate", "arn", result.CertificateArn)
+			r.abandonCertificate(ctx, result.CertificateArn)
+			return ctrl.Result{}, nil
+		}
+		if r.validationPending(result, err) {
+			// The other chunks are still requested, so they validate alongside this one. Reused
+			// certificates report no per-name statuses, so only their status keys the backoff.
+			pending = append(pending, result.CertificateArn+" "+string(result.Status))
+			if err != nil {
+				if trackErr := r.trackPendingCertificate(ctx, ingress, result.CertificateArn); trackErr != nil {
+					logger.Error(trackErr, "failed to track pending certificate", "arn", result.CertificateArn)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			logger.Error(err, "failed to ensure split certificate", "names", names)
+			if !isDeferred(err) {
+				r.notifyCertificateEvent(ingress, NotificationFailed, names[0], result.CertificateArn, string(result.Status), err.Error())
+			}
+			if result.CertificateArn != "" {
+				if trackErr := r.trackPendingCertificate(ctx, ingress, result.CertificateArn); trackErr != nil {
+					logger.Error(trackErr, "failed to track pending certificate", "arn", result.CertificateArn)
+				}
+			}
+			return ctrl.Result{}, err
+		}
+		if err := r.tagCertificateOwner(ctx, result.CertificateArn, ingress); err != nil {
+			logOwnerError(ctx, err, result.CertificateArn)
+		}
+		certArns = append(certArns, result.CertificateArn)
+		if !result.Reused {
+			requested = append(requested, result.CertificateArn)
+		}
+	}
+	if len(pending) > 0 {
+		delay := r.pendingRequeues.next(ingress.UID, strings.Join(pending, ", "))
+		logger.Info("Split certificates are pending validation, checking again later", "pending", len(pending), "after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+	r.pendingRequeues.forget(ingress.UID)
+
+	if cfg.FallbackWildcard || ingress.Annotations[key] != strings.Join(certArns, ",") {
+		if err := r.attachCertificate(ctx, ingress, cfg, domain, certArns, requested); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	var replaced []string
+	if !cfg.DeletionProtection {
+		for _, arn := range previous {
+			if !slices.Contains(certArns, arn) {
+				replaced = append(replaced, arn)
+			}
+		}
+	}
+	if err := r.retireCertificates(ctx, ingress, key, replaced); err != nil {
+		return ctrl.Result{}, err
+	}
+	return recheckAfter(ctx, recheckInterval), nil
+}
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func TestSplitNames(t *testing.T) {
+	zones := map[string]string{
+		"app.example.com": "Z1", "a.example.com": "Z1", "b.example.com": "Z1",
+		"a.example.org": "Z2", "b.example.org": "Z2", "x.sub.example.com": "Z3",
+	}
+	chunks := splitNames("app.example.com", []string{"x.sub.example.com", "b.example.org", "a.example.com", "a.example.org", "b.example.com"}, 3, zones)
+	want := [][]string{{"app.example.com", "a.example.com", "b.example.com"}, {"a.example.org", "b.example.org", "x.sub.example.com"}}
+	if !slices.EqualFunc(chunks, want, slices.Equal) {
+		t.Fatalf("splitNames = %v, want %v", chunks, want)
+	}
+}
+
+func TestStableSplit(t *testing.T) {
+	zones := map[string]string{
+		"app.example.com": "Z1", "a.example.com": "Z1", "b.example.com": "Z1", "c.example.com": "Z1",
+		"a.example.org": "Z2", "b.example.org": "Z2", "c.example.org": "Z2",
+	}
+	published := [][]string{{"app.example.com", "a.example.com", "b.example.com"}, {"a.example.org", "b.example.org"}}
+
+	// A new name goes to the last chunk while it has room, then to a new one
+	chunks := stableSplit("app.example.com", []string{"a.example.com", "b.example.com", "a.example.org", "b.example.org", "c.example.com", "c.example.org"}, 3, zones, published)
+	want := [][]string{{"app.example.com", "a.example.com", "b.example.com"}, {"a.example.org", "b.example.org", "c.example.com"}, {"c.example.org"}}
+	if !slices.EqualFunc(chunks, want, slices.Equal) {
+		t.Fatalf("stableSplit with new names = %v, want %v", chunks, want)
+	}
+
+	// A removed name only changes its own chunk
+	chunks = stableSplit("app.example.com", []string{"b.example.com", "a.example.org", "b.example.org"}, 3, zones, published)
+	want = [][]string{{"app.example.com", "b.example.com"}, {"a.example.org", "b.example.org"}}
+	if !slices.EqualFunc(chunks, want, slices.Equal) {
+		t.Fatalf("stableSplit with a removed name = %v, want %v", chunks, want)
+	}
+
+	// A new primary domain splits afresh
+	chunks = stableSplit("c.example.com", []string{"a.example.com", "a.example.org"}, 3, zones, published)
+	want = [][]string{{"c.example.com", "a.example.com", "a.example.org"}}
+	if !slices.EqualFunc(chunks, want, slices.Equal) {
+		t.Fatalf("stableSplit with a new primary domain = %v, want %v", chunks, want)
+	}
+}
+
+// splitIngress returns a deletable Ingress for app.example.com with 24 SANs over two zones
+func splitIngress() *networkingv1.Ingress {
+	var sans []string
+	for i := range 12 {
+		sans = append(sans, fmt.Sprintf("svc%02d.example.com", i), fmt.Sprintf("svc%02d.example.org", i))
+	}
+	return newManagedIngress("web", "app.example.com", map[string]string{
+		"acm.tedens.dev/san":                           strings.Join(sans, ","),
+		"acm.tedens.dev/delete-cert-on-ingress-delete": "true",
+	})
+}
+
+func TestAutoSplitCertificates(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := splitIngress()
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com", "example.org"), ingress)
+	r.AutoSplitCertificates = true
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 3 {
+		t.Fatalf("expected three certificates for 25 names, got %d requests", len(fakeACM.requests))
+	}
+	seen := map[string]bool{}
+	spanning := 0
+	for i, req := range fakeACM.requests {
+		names := append([]string{aws.ToString(req.DomainName)}, req.SubjectAlternativeNames...)
+		if len(names) > maxNamesPerCertificate {
+			t.Fatalf("certificate %d has %d names", i, len(names))
+		}
+		zones := map[string]bool{}
+		for _, name := range names {
+			seen[name] = true
+			zones[name[strings.Index(name, ".")+1:]] = true
+		}
+		if len(zones) > 1 {
+			spanning++
+		}
+	}
+	// Names are grouped by zone, so only the certificate where one zone ends and the next
+	// begins holds both
+	if spanning > 1 {
+		t.Errorf("%d certificates span both zones", spanning)
+	}
+	if len(seen) != 25 || aws.ToString(fakeACM.requests[0].DomainName) != "app.example.com" {
+		t.Fatalf("expected every name once with the domain first, got %d names", len(seen))
+	}
+	arns := attachedCertificateArns(getIngress(t, r, ingress), albCertificateArnAnnotation)
+	if len(arns) != 3 {
+		t.Fatalf("expected three attached certificates, got %v", arns)
+	}
+
+	// Later reconciles reuse the split certificates
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 3 {
+		t.Fatalf("expected the certificates to be reused, got %d requests", len(fakeACM.requests))
+	}
+
+	got := getIngress(t, r, ingress)
+	if err := r.Delete(ctx, got); err != nil {
+		t.Fatalf("delete ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after delete: %v", err)
+	}
+	slices.Sort(arns)
+	slices.Sort(fakeACM.deleted)
+	if !slices.Equal(fakeACM.deleted, arns) {
+		t.Fatalf("expected %v to be deleted, got %v", arns, fakeACM.deleted)
+	}
+}
+
+func TestSplitRequeuesPendingValidation(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	fakeACM.holdPending = true
+	ingress := splitIngress()
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com", "example.org"), ingress)
+	r.AutoSplitCertificates = true
+	r.RequeuePendingValidation = true
+
+	for i, want := range []time.Duration{15 * time.Second, 30 * time.Second} {
+		result, err := r.Reconcile(ctx, requestFor(ingress))
+		if err != nil {
+			t.Fatalf("Reconcile %d: %v", i+1, err)
+		}
+		if result.RequeueAfter != want {
+			t.Fatalf("Reconcile %d: expected requeue after %s, got %s", i+1, want, result.RequeueAfter)
+		}
+	}
+	if len(fakeACM.requests) != 3 {
+		t.Fatalf("expected every chunk requested once and then reused, got %d requests", len(fakeACM.requests))
+	}
+	got := getIngress(t, r, ingress)
+	if arns := attachedCertificateArns(got, albCertificateArnAnnotation); len(arns) != 0 {
+		t.Fatalf("pending certificates must not be attached, got %v", arns)
+	}
+	if pending := pendingCertificateArns(got); len(pending) != 3 {
+		t.Fatalf("expected the pending certificates tracked, got %v", pending)
+	}
+
+	fakeACM.mu.Lock()
+	fakeACM.holdPending = false
+	fakeACM.mu.Unlock()
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after issue: %v", err)
+	}
+	if arns := attachedCertificateArns(getIngress(t, r, ingress), albCertificateArnAnnotation); len(arns) != 3 {
+		t.Fatalf("expected the issued certificates attached, got %v", arns)
+	}
+}
+
+func TestSplitNameChangeReplacesOneCertificate(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := splitIngress()
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com", "example.org"), ingress)
+	r.AutoSplitCertificates = true
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	before := attachedCertificateArns(getIngress(t, r, ingress), albCertificateArnAnnotation)
+
+	// svc00.example.com sorts into the first certificate; the new name goes to the last
+	current := getIngress(t, r, ingress)
+	current.Annotations["acm.tedens.dev/san"] = strings.Replace(current.Annotations["acm.tedens.dev/san"], "svc00.example.com", "new.example.org", 1)
+	if err := r.Update(ctx, current); err != nil {
+		t.Fatalf("update ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after the name change: %v", err)
+	}
+	after := attachedCertificateArns(getIngress(t, r, ingress), albCertificateArnAnnotation)
+	if len(after) != 3 || after[1] != before[1] {
+		t.Fatalf("attached %v, want the middle certificate of %v kept", after, before)
+	}
+	if after[0] == before[0] || after[2] == before[2] {
+		t.Fatalf("attached %v, want the first and last certificates of %v replaced", after, before)
+	}
+	deleted := slices.Sorted(slices.Values(fakeACM.deleted))
+	if want := []string{before[0], before[2]}; !slices.Equal(deleted, want) {
+		t.Fatalf("deleted %v, want the replaced certificates %v", deleted, want)
+	}
+}
+
+func TestTooManyNamesWithoutSplitting(t *testing.T) {
+	fakeACM := newFakeACM()
+	ingress := splitIngress()
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com", "example.org"), ingress)
+
+	_, err := r.Reconcile(context.Background(), requestFor(ingress))
+	var tooMany *errTooManyNames
+	if !errors.As(err, &tooMany) || tooMany.names != 25 {
+		t.Fatalf("expected a too many names error, got %v", err)
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("nothing should be requested, got %d requests", len(fakeACM.requests))
+	}
+}
+
+func getIngress(t *testing.T, r *IngressReconciler, ingress *networkingv1.Ingress) *networkingv1.Ingress {
+	t.Helper()
+	var got networkingv1.Ingress
+	if err := r.Get(context.Background(), requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	return &got
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// managedByPodAnnotation records the pod that last reconciled a managed Ingress, as
+// <pod>@<RFC 3339 time>
+const managedByPodAnnotation = "acm.tedens.dev/managed-by-pod"
+
+const (
+	// podStampRefresh is how old this pod's own stamp may get before it is written again
+	podStampRefresh = time.Minute
+	// splitBrainWindow is how close together two pods must have reconciled the same Ingress
+	// to be suspected of both leading
+	splitBrainWindow = 5 * time.Minute
+)
+
+// splitBrainSuspected counts Ingresses found reconciled by another pod while this one was too
+var splitBrainSuspected = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "acm_manager_split_brain_suspected_total",
+	Help: "Times an Ingress was found recently reconciled by another pod while this pod was reconciling it too, suggesting two leaders.",
+})
+
+// podStamp is the parsed value of managedByPodAnnotation
+type podStamp struct {
+	pod string
+	at  time.Time
+}
+
+func parsePodStamp(value string) (podStamp, bool) {
+	i := strings.LastIndex(value, "@")
+	if i <= 0 {
+		return podStamp{}, false
+	}
+	at, err := time.Parse(time.RFC3339, value[i+1:])
+	if err != nil {
+		return podStamp{}, false
+	}
+	return podStamp{pod: value[:i], at: at}, true
+}
+
+func (s podStamp) String() string {
+	return s.pod + "@" + s.at.UTC().Format(time.RFC3339)
+}
+
+// suspectSplitBrain reports whether stamp shows another pod reconciling the Ingress within
+// splitBrainWindow of now, while this pod, identity, stamped it at own within the window too.
+// A new leader taking over sees its predecessor's stamp without having stamped the Ingress
+// itself, so a plain failover is not suspected.
+func suspectSplitBrain(stamp podStamp, identity string, own time.Time, stamped bool, now time.Time) bool {
+	if !stamped || stamp.pod == "" || stamp.pod == identity {
+		return false
+	}
+	return now.Sub(stamp.at) < splitBrainWindow && now.Sub(own) < splitBrainWindow
+}
+
+// stampPodIdentity records this pod in managedByPodAnnotation of the Ingress, warning with a
+// SplitBrainSuspected event when another pod stamped it while this one was active on it.
+// Failing to stamp does not fail the reconcile.
+func (r *IngressReconciler) stampPodIdentity(ctx context.Context, ingress *networkingv1.Ingress) {
+	if r.PodIdentity == "" {
+		return
+	}
+	logger := log.FromContext(ctx)
+	key := types.NamespacedName{Namespace: ingress.Namespace, Name: ingress.Name}
+	now := r.clock()
+	stamp, _ := parsePodStamp(ingress.Annotations[managedByPodAnnotation])
+	own, stamped := r.podStamps.get(key)
+	if suspectSplitBrain(stamp, r.PodIdentity, own, stamped, now) {
+		logger.Info("Ingress was recently reconciled by another pod, suspecting two leaders", "pod", stamp.pod, "at", stamp.at)
+		splitBrainSuspected.Inc()
+		if r.Recorder != nil {
+			r.Recorder.Eventf(ingress, corev1.EventTypeWarning, ReasonSplitBrainSuspected,
+				"Reconciled by %s at %s while %s also manages it; check that leader election is enabled and both use the same lease",
+				stamp.pod, stamp.at.UTC().Format(time.RFC3339), r.PodIdentity)
+		}
+	}
+	if stamp.pod == r.PodIdentity && now.Sub(stamp.at) < podStampRefresh {
+		return
+	}
+
+	patch := client.MergeFrom(ingress.DeepCopy())
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+	ingress.Annotations[managedByPodAnnotation] = podStamp{pod: r.PodIdentity, at: now}.String()
+	if err := r.patchIngress(ctx, ingress, patch); err != nil {
+		logger.Error(err, "failed to record the reconciling pod")
+		return
+	}
+	r.podStamps.set(key, now)
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSuspectSplitBrain(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	other := podStamp{pod: "acm-manager-b", at: now.Add(-30 * time.Second)}
+	cases := []struct {
+		name    string
+		stamp   podStamp
+		own     time.Time
+		stamped bool
+		want    bool
+	}{
+		{"both pods active", other, now.Add(-time.Minute), true, true},
+		{"new leader taking over", other, time.Time{}, false, false},
+		{"own stamp", podStamp{pod: "acm-manager-a", at: other.at}, now.Add(-time.Minute), true, false},
+		{"other pod long gone", podStamp{pod: "acm-manager-b", at: now.Add(-time.Hour)}, now.Add(-time.Minute), true, false},
+		{"this pod idle on the Ingress", other, now.Add(-time.Hour), true, false},
+		{"no stamp", podStamp{}, now.Add(-time.Minute), true, false},
+	}
+	for _, c := range cases {
+		if got := suspectSplitBrain(c.stamp, "acm-manager-a", c.own, c.stamped, now); got != c.want {
+			t.Errorf("%s: suspectSplitBrain = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	if stamp, ok := parsePodStamp(other.String()); !ok || stamp != other {
+		t.Errorf("parsePodStamp(%q) = %+v, %v", other.String(), stamp, ok)
+	}
+	for _, value := range []string{"", "acm-manager-b", "@2026-01-01T12:00:00Z", "acm-manager-b@yesterday"} {
+		if _, ok := parsePodStamp(value); ok {
+			t.Errorf("parsePodStamp(%q) should fail", value)
+		}
+	}
+}
+
+func TestReconcileWarnsOnSplitBrain(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), ingress)
+	r.PodIdentity = "acm-manager-a"
+	r.now = func() time.Time { return now }
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := getIngress(t, r, ingress)
+	if stamp := got.Annotations[managedByPodAnnotation]; stamp != "acm-manager-a@2026-01-01T12:00:00Z" {
+		t.Fatalf("managed-by-pod = %q", stamp)
+	}
+	drainEvents(r.Recorder.(*record.FakeRecorder))
+
+	// A second leader reconciles the Ingress shortly after
+	now = now.Add(time.Minute)
+	got.Annotations[managedByPodAnnotation] = podStamp{pod: "acm-manager-b", at: now.Add(-10 * time.Second)}.String()
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatalf("update ingress: %v", err)
+	}
+	before := metricValue(t, splitBrainSuspected)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if got := metricValue(t, splitBrainSuspected) - before; got != 1 {
+		t.Errorf("split brain counter grew by %v, want 1", got)
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+ReasonSplitBrainSuspected) || !strings.Contains(events[0], "acm-manager-b") {
+		t.Fatalf("expected a %s warning naming the other pod, got %v", ReasonSplitBrainSuspected, events)
+	}
+	if stamp := getIngress(t, r, ingress).Annotations[managedByPodAnnotation]; !strings.HasPrefix(stamp, "acm-manager-a@") {
+		t.Errorf("expected this pod's stamp back, got %q", stamp)
+	}
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// validationStateAnnotation holds the JSON certs.ValidationState of the certificate being
+// validated for an Ingress, so a new leader resumes it instead of requesting another one
+const validationStateAnnotation = "acm.tedens.dev/validation-state"
+
+// savedValidationState returns the Ingress' persisted validation state, or nil
+func savedValidationState(ctx context.Context, ingress *networkingv1.Ingress) *certs.ValidationState {
+	raw := ingress.Annotations[validationStateAnnotation]
+	if raw == "" {
+		return nil
+	}
+	var state certs.ValidationState
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		log.FromContext(ctx).Error(err, "ignoring unreadable validation state", "annotation", validationStateAnnotation)
+		return nil
+	}
+	return &state
+}
+
+// validationStateSaver returns an OnState callback persisting each phase on the Ingress. The
+// certificate is tracked as pending too, so it is cleaned up if the Ingress goes away first.
+func (r *IngressReconciler) validationStateSaver(ctx context.Context, ingress *networkingv1.Ingress) func(certs.ValidationState) error {
+	return func(state certs.ValidationState) error {
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		patch := client.MergeFrom(ingress.DeepCopy())
+		if ingress.Annotations == nil {
+			ingress.Annotations = map[string]string{}
+		}
+		ingress.Annotations[validationStateAnnotation] = string(data)
+		if arns := pendingCertificateArns(ingress); !slices.Contains(arns, state.CertificateArn) {
+			setPendingCertificateArns(ingress, append(arns, state.CertificateArn))
+		}
+		return r.patchIngress(ctx, ingress, patch)
+	}
+}
+
+// clearValidationState removes the persisted validation state from the Ingress
+func (r *IngressReconciler) clearValidationState(ctx context.Context, ingress *networkingv1.Ingress) error {
+	if _, ok := ingress.Annotations[validationStateAnnotation]; !ok {
+		return nil
+	}
+	patch := client.MergeFrom(ingress.DeepCopy())
+	delete(ingress.Annotations, validationStateAnnotation)
+	if err := r.patchIngress(ctx, ingress, patch); err != nil {
+		return fmt.Errorf("failed to clear validation state: %w", err)
+	}
+	return nil
+}
+
+// validationFinished reports whether err ends the validation of a certificate for good, so
+// its saved state must not be resumed
+func validationFinished(err error) bool {
+	var failed *certs.ValidationFailedError
+	return err == nil || errors.As(err, &failed) || errors.Is(err, certs.ErrValidationTimedOut)
+}
+
+// requestToken returns the ACM idempotency token for the Ingress' next certificate request.
+// It only changes with the names, once a request is tracked as pending or once the attached
+// certificate is forgotten, so a request whose ARN never reached the Ingress returns the same
+// certificate when retried within the hour.
+func requestToken(ingress *networkingv1.Ingress, domain string, sans []string) string {
+	names := []string{strings.ToLower(domain)}
+	for _, san := range sans {
+		names = append(names, strings.ToLower(san))
+	}
+	slices.Sort(names[1:])
+	b := readBookkeeping(ingress)
+	h := sha256.New()
+	for _, part := range append([]string{ingressKey(ingress), string(ingress.UID), strconv.Itoa(len(b.PendingCertificateArns)), strconv.Itoa(b.Generation)}, names...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
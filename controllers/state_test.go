@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func TestValidationResumesAfterRestart(t *testing.T) {
+	interval := validationPollInterval
+	validationPollInterval = time.Millisecond
+	t.Cleanup(func() { validationPollInterval = interval })
+
+	fakeACM := newFakeACM()
+	fakeACM.holdPending = true
+	fakeR53 := newFakeRoute53("example.com")
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/reuse-existing": "false"})
+	first := newTestReconciler(t, fakeACM, fakeR53, ingress)
+
+	// The first leader goes away while waiting for the certificate to be issued
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := first.Reconcile(ctx, requestFor(ingress)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the interrupted wait to fail with the context error, got %v", err)
+	}
+
+	var got networkingv1.Ingress
+	if err := first.Get(context.Background(), requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	state := savedValidationState(context.Background(), &got)
+	if state == nil || state.Phase != "RecordsCreated" || len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one request with saved RecordsCreated state, got %+v", state)
+	}
+	changes := len(fakeR53.changes)
+
+	// A new leader with a fresh reconciler picks the validation up
+	fakeACM.mu.Lock()
+	fakeACM.holdPending = false
+	fakeACM.mu.Unlock()
+	second := newTestReconciler(t, fakeACM, fakeR53)
+	second.Client = first.Client
+	if _, err := second.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after restart: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("resuming must not request a duplicate certificate, got %d requests", len(fakeACM.requests))
+	}
+	if len(fakeR53.changes) != changes {
+		t.Fatalf("resuming must not re-create validation records, got %d new changes", len(fakeR53.changes)-changes)
+	}
+	if got := certificateArnOf(t, second, ingress); got != state.CertificateArn {
+		t.Fatalf("expected resumed certificate %s to be attached, got %q", state.CertificateArn, got)
+	}
+	if err := second.Get(context.Background(), requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if _, ok := got.Annotations[validationStateAnnotation]; ok {
+		t.Fatal("validation state should be cleared once the certificate is attached")
+	}
+	if len(pendingCertificateArns(&got)) != 0 {
+		t.Fatalf("attached certificate must not stay tracked as pending, got %v", pendingCertificateArns(&got))
+	}
+}
+
+func TestRequestRetriedWithSameTokenAfterLostState(t *testing.T) {
+	interval := validationPollInterval
+	validationPollInterval = time.Millisecond
+	t.Cleanup(func() { validationPollInterval = interval })
+
+	fakeACM := newFakeACM()
+	fakeACM.holdPending = true
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/reuse-existing": "false"})
+	first := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	// Dry-run drops the state patch, as if the leader died before persisting the ARN
+	first.IngressDryRun = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := first.Reconcile(ctx, requestFor(ingress)); err == nil {
+		t.Fatal("expected the interrupted wait to fail")
+	}
+
+	fakeACM.mu.Lock()
+	fakeACM.holdPending = false
+	fakeACM.mu.Unlock()
+	second := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"))
+	second.Client = first.Client
+	if _, err := second.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after restart: %v", err)
+	}
+	if len(fakeACM.requests) != 2 || len(fakeACM.certs) != 1 {
+		t.Fatalf("retried request must return the same certificate, got %d requests and %d certificates", len(fakeACM.requests), len(fakeACM.certs))
+	}
+	if token := fakeACM.requests[0].IdempotencyToken; token == nil || *token != *fakeACM.requests[1].IdempotencyToken {
+		t.Fatal("both requests should carry the same idempotency token")
+	}
+}
+
+func TestRequestTokenChangesAfterTrackedRequest(t *testing.T) {
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	token := requestToken(ingress, "app.example.com", []string{"b.example.com", "a.example.com"})
+	if len(token) != 32 {
+		t.Fatalf("token %q should be 32 characters", token)
+	}
+	if got := requestToken(ingress, "app.example.com", []string{"a.example.com", "b.example.com"}); got != token {
+		t.Fatal("token must not depend on SAN order")
+	}
+	setPendingCertificateArns(ingress, []string{"arn:aws:acm:us-east-1:123456789012:certificate/0001"})
+	if requestToken(ingress, "app.example.com", []string{"a.example.com", "b.example.com"}) == token {
+		t.Fatal("token must change once a request is tracked, so a failed certificate is not returned again")
+	}
+}
+
+func TestRequestTokenIgnoresNameCase(t *testing.T) {
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	if requestToken(ingress, "App.Example.com", []string{"B.example.com", "a.example.com"}) != requestToken(ingress, "app.example.com", []string{"a.example.com", "b.example.com"}) {
+		t.Fatal("token must not depend on the case of the names")
+	}
+}
+
+func TestRequestTokenChangesAfterMissingCertificate(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/reuse-existing": "false"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	gone := certificateArnOf(t, r, ingress)
+	fakeACM.mu.Lock()
+	delete(fakeACM.certs, gone)
+	fakeACM.mu.Unlock()
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after the certificate was deleted: %v", err)
+	}
+	if len(fakeACM.requests) != 2 || *fakeACM.requests[0].IdempotencyToken == *fakeACM.requests[1].IdempotencyToken {
+		t.Fatal("a forgotten certificate must not be requested again with the same idempotency token")
+	}
+	if arn := certificateArnOf(t, r, ingress); arn == "" || arn == gone {
+		t.Fatalf("certificate-arn = %q, want a new certificate", arn)
+	}
+}
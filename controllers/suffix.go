@@ -0,0 +1,32 @@
+package controllers
+
+import "strings"
+
+// domainSuffixAnnotation is appended to hosts without a dot, expanding short names to FQDNs
+const domainSuffixAnnotation = "acm.tedens.dev/domain-suffix"
+
+// parseDomainSuffix normalizes a domain suffix value, dropping surrounding dots
+func parseDomainSuffix(value string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(value)), ".")
+}
+
+// qualifyHost appends suffix to a host without a dot; qualified hosts, empty hosts and an
+// empty suffix leave it unchanged
+func qualifyHost(host, suffix string) string {
+	if host == "" || suffix == "" || strings.Contains(host, ".") {
+		return host
+	}
+	return host + "." + suffix
+}
+
+// qualifyHosts applies qualifyHost to each of hosts
+func qualifyHosts(hosts []string, suffix string) []string {
+	if suffix == "" {
+		return hosts
+	}
+	qualified := make([]string, len(hosts))
+	for i, host := range hosts {
+		qualified[i] = qualifyHost(host, suffix)
+	}
+	return qualified
+}
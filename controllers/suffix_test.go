@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func TestQualifyHost(t *testing.T) {
+	cases := []struct{ host, suffix, want string }{
+		{"payments-api", "example.com", "payments-api.example.com"},
+		{"app.example.com", "internal.example.com", "app.example.com"},
+		{"payments-api", "", "payments-api"},
+		{"", "example.com", ""},
+	}
+	for _, tc := range cases {
+		if got := qualifyHost(tc.host, tc.suffix); got != tc.want {
+			t.Errorf("qualifyHost(%q, %q) = %q, want %q", tc.host, tc.suffix, got, tc.want)
+		}
+	}
+	if got := parseDomainSuffix(" .Example.COM. "); got != "example.com" {
+		t.Errorf("parseDomainSuffix = %q", got)
+	}
+}
+
+func TestDomainSuffixExpandsShortHosts(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("payments", "payments-api", map[string]string{
+		"acm.tedens.dev/san":                           "admin, status.example.org",
+		"acm.tedens.dev/delete-cert-on-ingress-delete": "true",
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com", "example.org"), ingress)
+	r.DomainSuffix = "example.com"
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(fakeACM.requests))
+	}
+	req := fakeACM.requests[0]
+	if aws.ToString(req.DomainName) != "payments-api.example.com" || !slices.Equal(req.SubjectAlternativeNames, []string{"admin.example.com", "status.example.org"}) {
+		t.Fatalf("unexpected names %s %v", aws.ToString(req.DomainName), req.SubjectAlternativeNames)
+	}
+	arn := certificateArnOf(t, r, ingress)
+	if arn == "" {
+		t.Fatal("expected the certificate to be attached")
+	}
+
+	// The issued certificate is matched under the expanded names instead of being requested again
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected the certificate to be kept, got %d requests", len(fakeACM.requests))
+	}
+
+	// Deletion looks the certificate up by the expanded domain as well
+	var got networkingv1.Ingress
+	if err := r.Get(ctx, requestFor(ingress).NamespacedName, &got); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if err := r.Delete(ctx, &got); err != nil {
+		t.Fatalf("delete ingress: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile after delete: %v", err)
+	}
+	if !slices.Equal(fakeACM.deleted, []string{arn}) {
+		t.Fatalf("expected %s to be deleted, got %v", arn, fakeACM.deleted)
+	}
+}
+
+func TestDomainSuffixAnnotationOverridesFlag(t *testing.T) {
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("payments", "payments-api", map[string]string{domainSuffixAnnotation: "svc.example.org"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com", "example.org"), ingress)
+	r.DomainSuffix = "example.com"
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 || aws.ToString(fakeACM.requests[0].DomainName) != "payments-api.svc.example.org" {
+		t.Fatalf("expected a request for payments-api.svc.example.org, got %+v", fakeACM.requests)
+	}
+}
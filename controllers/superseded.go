@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// supersededCertificateArns returns the certificates a successor replaced on the Ingress that
+// are still to be deleted
+func supersededCertificateArns(ingress *networkingv1.Ingress) []string {
+	return readBookkeeping(ingress).SupersededCertificateArns
+}
+
+// retireCertificates deletes the certificates in arns, and those superseded before, that this
+// instance owns and nothing holds any more, skipping the ones attached under key. A
+// certificate a load balancer still uses stays in the bookkeeping and is retried on the next
+// call.
+func (r *IngressReconciler) retireCertificates(ctx context.Context, ingress *networkingv1.Ingress, key string, arns []string) error {
+	attached := attachedCertificateArns(ingress, key)
+	previous := supersededCertificateArns(ingress)
+	var candidates []string
+	for _, arn := range append(slices.Clone(previous), arns...) {
+		if arn != "" && !slices.Contains(attached, arn) && !slices.Contains(candidates, arn) {
+			candidates = append(candidates, arn)
+		}
+	}
+	if len(candidates) == 0 && len(previous) == 0 {
+		return nil
+	}
+
+	var remaining []string
+	for _, arn := range candidates {
+		if !r.retireCertificate(ctx, arn, ingressKey(ingress)) {
+			remaining = append(remaining, arn)
+		}
+	}
+	if slices.Equal(remaining, previous) {
+		return nil
+	}
+	if r.ObserveOnly {
+		return observeGate(ctx, "retire", strings.Join(candidates, ","))
+	}
+	patch := client.MergeFrom(ingress.DeepCopy())
+	updateBookkeeping(ingress, func(b *bookkeeping) { b.SupersededCertificateArns = remaining })
+	return r.patchIngress(ctx, ingress, patch)
+}
+
+// retireCertificate takes owner off the certificate's owners and deletes it if this instance
+// owns it and nothing else holds it. It reports false when the certificate must be retried,
+// such as while a load balancer still uses it.
+func (r *IngressReconciler) retireCertificate(ctx context.Context, certArn, owner string) bool {
+	var notFound *acmtypes.ResourceNotFoundException
+	err := r.releaseCertificateOwner(ctx, certArn, owner)
+	if err == nil {
+		_, err = r.deleteOwnedCertificate(ctx, certArn, owner)
+	}
+	switch {
+	case err == nil || errors.As(err, &notFound):
+		log.FromContext(ctx).Info("Released superseded certificate", "arn", certArn)
+		return true
+	case !isDeferred(err):
+		log.FromContext(ctx).Info("Superseded certificate cannot be deleted yet, retrying later", "arn", certArn, "reason", err.Error())
+	}
+	return false
+}
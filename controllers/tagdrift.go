@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/tedens/acm-manager/pkg/certs"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// syncCertificateTags brings the tags of the certificates in the Ingress' certificate ARN
+// annotation key up to date when the controller wrote its value. A ManagedBy tag removed
+// outside the controller is put back whenever the certificate's owners tag still names the
+// Ingress, so reuse, deletion and the pending sweep keep recognizing it; with RequiredTags
+// those are put back too, on any certificate the controller attached. With namespaceTags,
+// the Namespace's NamespaceTagAnnotations are applied when they changed since issuance.
+// Certificates whose ManagedBy tag names another instance are left alone.
+func (r *IngressReconciler) syncCertificateTags(ctx context.Context, ingress *networkingv1.Ingress, key string, namespaceTags bool) error {
+	written, _ := writtenCertificateArn(ingress)
+	if written == "" || written != ingress.Annotations[key] {
+		return nil
+	}
+	var nsTags map[string]string
+	if namespaceTags {
+		var err error
+		if nsTags, err = r.namespaceTags(ctx, ingress.Namespace); err != nil {
+			return err
+		}
+	}
+	for _, certArn := range attachedCertificateArns(ingress, key) {
+		if err := r.syncTags(ctx, ingress, certArn, nsTags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncTags puts back the ownership tags of one attached certificate and applies nsTags
+func (r *IngressReconciler) syncTags(ctx context.Context, ingress *networkingv1.Ingress, certArn string, nsTags map[string]string) error {
+	tags, err := r.certificateTags(ctx, certArn)
+	if err != nil {
+		return fmt.Errorf("failed to list tags of certificate %s: %w", certArn, err)
+	}
+	// Without RequiredTags or the Ingress in its owners tag, a certificate missing the
+	// ManagedBy tag cannot be told apart from one that was never ours, such as a fallback
+	// wildcard
+	owner, tagged := tags[certs.ManagedByTagKey]
+	if tagged && owner != r.managedByValue() {
+		return nil
+	}
+	if !tagged && len(r.RequiredTags) == 0 && !slices.Contains(certificateOwners(tags), ingressKey(ingress)) {
+		return nil
+	}
+
+	want := map[string]string{}
+	for key, value := range nsTags {
+		want[key] = value
+	}
+	ownership := map[string]string{certs.ManagedByTagKey: r.managedByValue()}
+	for key, value := range r.RequiredTags {
+		ownership[key] = value
+	}
+	var restored []string
+	for key, value := range ownership {
+		want[key] = value
+		if got, ok := tags[key]; !ok || got != value {
+			restored = append(restored, key)
+		}
+	}
+	var keys []string
+	for key, value := range want {
+		if got, ok := tags[key]; !ok || got != value {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	sort.Strings(restored)
+	update := make([]acmtypes.Tag, 0, len(keys))
+	for _, key := range keys {
+		update = append(update, acmtypes.Tag{Key: aws.String(key), Value: aws.String(want[key])})
+	}
+	if _, err := r.acm(ctx).AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+		CertificateArn: aws.String(certArn),
+		Tags:           update,
+	}); err != nil {
+		return fmt.Errorf("failed to update tags of certificate %s: %w", certArn, err)
+	}
+	log.FromContext(ctx).Info("Updated certificate tags", "arn", certArn, "tags", keys)
+	if len(restored) > 0 && r.Recorder != nil {
+		r.Recorder.Eventf(ingress, corev1.EventTypeNormal, ReasonCertificateTagsRestored,
+			"Restored tags %v of certificate %s that were removed or changed outside the controller", restored, certArn)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/tedens/acm-manager/pkg/certs"
+)
+
+// DefaultManagedByValue is the ManagedBy tag value used when none is configured
+const DefaultManagedByValue = certs.DefaultManagedByValue
+
+func (r *IngressReconciler) managedByValue() string {
+	if r.ManagedByValue == "" {
+		return DefaultManagedByValue
+	}
+	return r.ManagedByValue
+}
+
+// ownsCertificate reports whether the certificate carries this instance's ManagedBy tag and
+// every tag in RequiredTags, which makes it eligible for reuse and deletion
+func (r *IngressReconciler) ownsCertificate(ctx context.Context, certArn string) (bool, error) {
+	return certs.ManagedByWithTags(ctx, r.acm(ctx), certArn, r.managedByValue(), r.RequiredTags)
+}
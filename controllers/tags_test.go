@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEnsureCertificateOnlyReusesOwnCertificates(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	teamA := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "team-a"})
+	ingress := newManagedIngress("web", "app.example.com", nil)
+
+	a := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	a.ManagedByValue = "team-a"
+	result, err := a.ensureCertificate(ctx, ingress, "app.example.com", IngressConfig{ReuseExisting: true, ZoneID: "Z1"}, a.DNSProvider)
+	if err != nil {
+		t.Fatalf("team-a ensureCertificate: %v", err)
+	}
+	if arn := result.CertificateArn; arn != teamA {
+		t.Fatalf("team-a should reuse its own certificate %s, got %s", teamA, arn)
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("team-a should not request a certificate, got %d requests", len(fakeACM.requests))
+	}
+
+	b := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	b.ManagedByValue = "team-b"
+	result, err = b.ensureCertificate(ctx, ingress, "app.example.com", IngressConfig{ReuseExisting: true, ZoneID: "Z1"}, b.DNSProvider)
+	if err != nil {
+		t.Fatalf("team-b ensureCertificate: %v", err)
+	}
+	if result.CertificateArn == teamA {
+		t.Fatal("team-b must not reuse team-a's certificate")
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("team-b should request exactly one certificate, got %d", len(fakeACM.requests))
+	}
+	tags := fakeACM.requests[0].Tags
+	if len(tags) != 1 || aws.ToString(tags[0].Key) != "ManagedBy" || aws.ToString(tags[0].Value) != "team-b" {
+		t.Fatalf("requested certificate should be tagged ManagedBy=team-b, got %+v", tags)
+	}
+}
+
+func TestDeleteCertificateForDomainSkipsOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	teamA := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "team-a"})
+	untagged := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, nil)
+
+	b := &IngressReconciler{ACMClient: fakeACM, ManagedByValue: "team-b"}
+	if err := b.deleteCertificateForDomain(ctx, "app.example.com", ""); err != nil {
+		t.Fatalf("team-b delete: %v", err)
+	}
+	if len(fakeACM.deleted) != 0 {
+		t.Fatalf("team-b must not delete certificates it does not own, deleted %v", fakeACM.deleted)
+	}
+
+	a := &IngressReconciler{ACMClient: fakeACM, ManagedByValue: "team-a"}
+	if err := a.deleteCertificateForDomain(ctx, "app.example.com", ""); err != nil {
+		t.Fatalf("team-a delete: %v", err)
+	}
+	if len(fakeACM.deleted) != 1 || fakeACM.deleted[0] != teamA {
+		t.Fatalf("team-a should delete only %s, deleted %v", teamA, fakeACM.deleted)
+	}
+	if _, ok := fakeACM.certs[untagged]; !ok {
+		t.Fatal("untagged certificate must be left alone")
+	}
+}
+
+func TestManagedByValueDefault(t *testing.T) {
+	r := &IngressReconciler{}
+	if got := r.managedByValue(); got != DefaultManagedByValue {
+		t.Fatalf("managedByValue() = %q, want %q", got, DefaultManagedByValue)
+	}
+}
+
+func TestEnsureCertificateHonoursReuseStatuses(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	pending := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusPendingValidation, map[string]string{"ManagedBy": DefaultManagedByValue})
+	ingress := newManagedIngress("web", "app.example.com", nil)
+
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.ReuseCertificateStatuses = []acmtypes.CertificateStatus{acmtypes.CertificateStatusIssued}
+	result, err := r.ensureCertificate(ctx, ingress, "app.example.com", IngressConfig{ReuseExisting: true, ZoneID: "Z1"}, r.DNSProvider)
+	if err != nil {
+		t.Fatalf("ensureCertificate: %v", err)
+	}
+	if arn := result.CertificateArn; arn == pending || len(fakeACM.requests) != 1 {
+		t.Fatalf("pending certificate must not be reused when only ISSUED is allowed, got %s", arn)
+	}
+}
+
+func TestRequiredTagsLimitEligibility(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	required := map[string]string{"CostCenter": "42"}
+	untagged := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "acm-manager"})
+	otherValue := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "acm-manager", "CostCenter": "7"})
+	tagged := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "acm-manager", "CostCenter": "42"})
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.RequiredTags = required
+
+	for arn, want := range map[string]bool{untagged: false, otherValue: false, tagged: true} {
+		if owned, err := r.ownsCertificate(ctx, arn); err != nil || owned != want {
+			t.Errorf("ownsCertificate(%s) = %v, %v, want %v", arn, owned, err, want)
+		}
+	}
+
+	result, err := r.ensureCertificate(ctx, ingress, "app.example.com", IngressConfig{ReuseExisting: true, ZoneID: "Z1"}, r.DNSProvider)
+	if err != nil {
+		t.Fatalf("ensureCertificate: %v", err)
+	}
+	if result.CertificateArn != tagged || len(fakeACM.requests) != 0 {
+		t.Fatalf("only %s carries the required tags and may be reused, got %s after %d requests", tagged, result.CertificateArn, len(fakeACM.requests))
+	}
+
+	if err := r.deleteCertificateForDomain(ctx, "app.example.com", ""); err != nil {
+		t.Fatalf("deleteCertificateForDomain: %v", err)
+	}
+	if len(fakeACM.deleted) != 1 || fakeACM.deleted[0] != tagged {
+		t.Fatalf("only %s carries the required tags and may be deleted, deleted %v", tagged, fakeACM.deleted)
+	}
+}
+
+func TestRequiredTagsStampedOnRequests(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.RequiredTags = map[string]string{"CostCenter": "42", "Owner": "platform"}
+
+	cfg := IngressConfig{ZoneID: "Z1", Tags: map[string]string{"CostCenter": "1", "team": "web"}}
+	if _, err := r.ensureCertificate(ctx, ingress, "app.example.com", cfg, r.DNSProvider); err != nil {
+		t.Fatalf("ensureCertificate: %v", err)
+	}
+	tags := map[string]string{}
+	for _, tag := range fakeACM.requests[0].Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	want := map[string]string{"ManagedBy": "acm-manager", "CostCenter": "42", "Owner": "platform", "team": "web"}
+	if len(tags) != len(want) || len(fakeACM.requests[0].Tags) != len(want) {
+		t.Fatalf("tags = %v, want %v", tags, want)
+	}
+	for key, value := range want {
+		if tags[key] != value {
+			t.Fatalf("tags = %v, want %v: required tags win over the Ingress' tags", tags, want)
+		}
+	}
+}
+
+func TestReconcileRestoresStrippedTags(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	arn := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"CostCenter": "7", "team": "web"})
+	other := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": "team-b"})
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		"alb.ingress.kubernetes.io/certificate-arn": arn,
+		writtenCertificateArnAnnotation:             arn,
+	})
+	claimed := newManagedIngress("claimed", "app.example.com", map[string]string{
+		"alb.ingress.kubernetes.io/certificate-arn": other,
+		writtenCertificateArnAnnotation:             other,
+		"acm.tedens.dev/primary":                    "true",
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress, claimed)
+	r.RequiredTags = map[string]string{"CostCenter": "42"}
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if owned, err := r.ownsCertificate(ctx, arn); err != nil || !owned {
+		t.Fatalf("ownsCertificate(%s) = %v, %v after the reconcile, want the tags restored", arn, owned, err)
+	}
+	tags := map[string]string{}
+	for _, tag := range fakeACM.tags[arn] {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	if tags["team"] != "web" {
+		t.Errorf("tags = %v: other tags should be kept", tags)
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if !slices.ContainsFunc(events, func(e string) bool { return strings.Contains(e, ReasonCertificateTagsRestored) }) {
+		t.Errorf("expected a %s event, got %v", ReasonCertificateTagsRestored, events)
+	}
+
+	if _, err := r.Reconcile(ctx, requestFor(claimed)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	for _, tag := range fakeACM.tags[other] {
+		if aws.ToString(tag.Key) == "ManagedBy" && aws.ToString(tag.Value) != "team-b" {
+			t.Fatalf("a certificate claimed by another instance was retagged: %v", fakeACM.tags[other])
+		}
+	}
+	if len(fakeACM.tags[other]) != 1 {
+		t.Fatalf("a certificate claimed by another instance was retagged: %v", fakeACM.tags[other])
+	}
+}
+
+func TestNamespaceTagAnnotationsPropagate(t *testing.T) {
+	ctx := context.Background()
+	fakeACM := newFakeACM()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{
+		"ourco.io/cost-center": "cc-1",
+		"ourco.io/unrelated":   "x",
+	}}}
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{"acm.tedens.dev/tags": "ourco.io/cost-center=mine,team=web"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress, ns)
+	r.NamespaceTagAnnotations = []string{"ourco.io/cost-center"}
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(fakeACM.requests))
+	}
+	tags := map[string]string{}
+	for _, tag := range fakeACM.requests[0].Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	if tags["ourco.io/cost-center"] != "cc-1" || tags["team"] != "web" || tags["ourco.io/unrelated"] != "" {
+		t.Fatalf("requested tags = %v: the Namespace's cost center should win over the Ingress' tags", tags)
+	}
+
+	certArn := certificateArnOf(t, r, ingress)
+	fakeACM.mu.Lock()
+	fakeACM.certs[certArn].Status = acmtypes.CertificateStatusIssued
+	fakeACM.mu.Unlock()
+	ns.Annotations["ourco.io/cost-center"] = "cc-2"
+	if err := r.Update(ctx, ns); err != nil {
+		t.Fatalf("update namespace: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("a tag change should not request a new certificate, got %d requests", len(fakeACM.requests))
+	}
+	tags = map[string]string{}
+	for _, tag := range fakeACM.tags[certArn] {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	if tags["ourco.io/cost-center"] != "cc-2" || tags["ManagedBy"] != "acm-manager" {
+		t.Fatalf("certificate tags = %v, want the changed cost center", tags)
+	}
+}
+
+func TestStrippedManagedByTagRestoredWithoutRequiredTags(t *testing.T) {
+	ctx := context.Background()
+	cases := map[string]map[string]string{
+		"single": nil,
+		"group":  {certGroupAnnotation: "api"},
+	}
+	for name, annotations := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeACM := newFakeACM()
+			ingress := newManagedIngress("web", "app.example.com", annotations)
+			r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+			for range 2 {
+				if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+					t.Fatalf("Reconcile: %v", err)
+				}
+			}
+			certArn := certificateArnOf(t, r, ingress)
+			fakeACM.mu.Lock()
+			fakeACM.tags[certArn] = slices.DeleteFunc(fakeACM.tags[certArn], func(tag acmtypes.Tag) bool {
+				return aws.ToString(tag.Key) == "ManagedBy"
+			})
+			fakeACM.mu.Unlock()
+			drainEvents(r.Recorder.(*record.FakeRecorder))
+
+			if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+			if got := fakeACM.tagValue(certArn, "ManagedBy"); got != "acm-manager" {
+				t.Fatalf("ManagedBy = %q after the reconcile, want it restored", got)
+			}
+			if len(fakeACM.requests) != 1 {
+				t.Fatalf("requests = %d, want the certificate kept", len(fakeACM.requests))
+			}
+			if events := drainEvents(r.Recorder.(*record.FakeRecorder)); !slices.ContainsFunc(events, func(e string) bool { return strings.Contains(e, ReasonCertificateTagsRestored) }) {
+				t.Errorf("events = %v, want %s", events, ReasonCertificateTagsRestored)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// Go templates rendered with the Ingress' metadata into the primary domain and extra SANs
+const (
+	domainTemplateAnnotation = "acm.tedens.dev/domain-template"
+	sanTemplateAnnotation    = "acm.tedens.dev/san-template"
+)
+
+// nameTemplateData is what domain and SAN templates are rendered with
+type nameTemplateData struct {
+	Name      string
+	Namespace string
+	Labels    map[string]string
+}
+
+// renderNameTemplate renders text with the Ingress' name, namespace and labels; a label the
+// Ingress does not carry is an error rather than an empty name
+func renderNameTemplate(annotation, text string, ingress *networkingv1.Ingress) (string, error) {
+	tmpl, err := template.New(annotation).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", annotation, err)
+	}
+	labels := ingress.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, nameTemplateData{Name: ingress.Name, Namespace: ingress.Namespace, Labels: labels}); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", annotation, err)
+	}
+	return out.String(), nil
+}
+
+// applyNameTemplates renders acm.tedens.dev/domain-template into the primary domain, unless
+// acm.tedens.dev/domain is set, and adds the names rendered from acm.tedens.dev/san-template
+// to the SANs. Rendered names are qualified with the domain suffix and must be hostnames.
+func applyNameTemplates(ingress *networkingv1.Ingress, cfg *IngressConfig) error {
+	var domain string
+	if cfg.DomainTemplate != "" && cfg.DomainOverride == "" {
+		rendered, err := renderNameTemplate(domainTemplateAnnotation, cfg.DomainTemplate, ingress)
+		if err != nil {
+			return err
+		}
+		domain = qualifyHost(strings.ToLower(strings.TrimSpace(rendered)), cfg.DomainSuffix)
+		if domain == "" || len(invalidHostnames([]string{domain})) > 0 {
+			return fmt.Errorf("%s rendered an invalid hostname %q", domainTemplateAnnotation, domain)
+		}
+	}
+
+	var sans []string
+	if cfg.SANTemplate != "" {
+		rendered, err := renderNameTemplate(sanTemplateAnnotation, cfg.SANTemplate, ingress)
+		if err != nil {
+			return err
+		}
+		sans = qualifyHosts(parseNameList(rendered), cfg.DomainSuffix)
+		if invalid := invalidHostnames(sans); len(invalid) > 0 {
+			return fmt.Errorf("%s rendered invalid hostnames: %s", sanTemplateAnnotation, strings.Join(invalid, ", "))
+		}
+	}
+
+	if domain != "" {
+		cfg.DomainOverride = domain
+	}
+	for _, san := range sans {
+		if san != cfg.DomainOverride && !containsFold(cfg.SANs, san) {
+			cfg.SANs = append(cfg.SANs, san)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestApplyNameTemplates(t *testing.T) {
+	ingress := newManagedIngress("web", "*.internal.example.com", nil)
+	ingress.Namespace = "pr-42"
+	ingress.Labels = map[string]string{"app": "shop"}
+	cfg := IngressConfig{
+		DomainTemplate: "{{ .Name }}.{{ .Namespace }}.preview.example.com",
+		SANTemplate:    "{{ .Labels.app }}.{{ .Namespace }}.preview.example.com, api-{{ .Name }}",
+		DomainSuffix:   "preview.example.com",
+	}
+	if err := applyNameTemplates(ingress, &cfg); err != nil {
+		t.Fatalf("applyNameTemplates: %v", err)
+	}
+	if cfg.DomainOverride != "web.pr-42.preview.example.com" {
+		t.Fatalf("unexpected domain %q", cfg.DomainOverride)
+	}
+	if !slices.Equal(cfg.SANs, []string{"shop.pr-42.preview.example.com", "api-web.preview.example.com"}) {
+		t.Fatalf("unexpected SANs %v", cfg.SANs)
+	}
+
+	// acm.tedens.dev/domain wins over the template
+	cfg = IngressConfig{DomainOverride: "app.example.com", DomainTemplate: "{{ .Name }}.example.com"}
+	if err := applyNameTemplates(ingress, &cfg); err != nil || cfg.DomainOverride != "app.example.com" {
+		t.Fatalf("explicit domain should be kept, got %q, %v", cfg.DomainOverride, err)
+	}
+
+	for _, tmpl := range []string{"{{ .Name ", "{{ .Labels.team }}.example.com", "{{ .Name }}_{{ .Namespace }}.example.com", `{{ "" }}`} {
+		cfg = IngressConfig{DomainTemplate: tmpl}
+		if err := applyNameTemplates(ingress, &cfg); err == nil {
+			t.Errorf("template %q should fail, got domain %q", cfg.DomainTemplate, cfg.DomainOverride)
+		}
+	}
+}
+
+func TestDomainTemplateRequestsRenderedDomain(t *testing.T) {
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "*.internal.example.com", map[string]string{
+		domainTemplateAnnotation: "{{ .Name }}.{{ .Namespace }}.preview.example.com",
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 || aws.ToString(fakeACM.requests[0].DomainName) != "web.default.preview.example.com" {
+		t.Fatalf("expected a certificate for the rendered domain, got %d requests", len(fakeACM.requests))
+	}
+	if certificateArnOf(t, r, ingress) == "" {
+		t.Fatal("expected the certificate to be attached")
+	}
+}
+
+func TestDomainTemplateErrorRecordsWarning(t *testing.T) {
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		domainTemplateAnnotation: "{{ .Labels.team }}.example.com",
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err == nil {
+		t.Fatal("expected the template error to be returned")
+	}
+	if len(fakeACM.requests) != 0 {
+		t.Fatalf("nothing should be requested, got %d requests", len(fakeACM.requests))
+	}
+	events := drainEvents(r.Recorder.(*record.FakeRecorder))
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+ReasonNameTemplateFailed) || !strings.Contains(events[0], "team") {
+		t.Fatalf("expected a %s warning, got %v", ReasonNameTemplateFailed, events)
+	}
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Policies accepted by --wildcard-san-policy for SANs a wildcard name of the same certificate
+// already covers
+const (
+	WildcardSANPolicyKeep  = "keep"
+	WildcardSANPolicyPrune = "prune"
+	WildcardSANPolicyError = "error"
+)
+
+// IsWildcardSANPolicy reports whether policy is a known wildcard SAN policy
+func IsWildcardSANPolicy(policy string) bool {
+	switch policy {
+	case WildcardSANPolicyKeep, WildcardSANPolicyPrune, WildcardSANPolicyError:
+		return true
+	}
+	return false
+}
+
+// includeWildcardSANAnnotation adds a wildcard SAN next to the primary domain
+const includeWildcardSANAnnotation = "acm.tedens.dev/include-wildcard-san"
+
+// wildcardSAN returns the wildcard SAN for domain: *.<domain> when domain is an apex, so the
+// certificate covers the apex and its subdomains, and *.<parent> otherwise, so it covers the
+// host and its siblings
+func wildcardSAN(domain string, apex bool) string {
+	if apex {
+		return "*." + domain
+	}
+	_, parent, _ := strings.Cut(domain, ".")
+	return "*." + parent
+}
+
+// includeWildcardSAN appends the wildcardSAN of domain to cfg.SANs when cfg asks for it. A
+// wildcard primary domain already is one, so it is left alone.
+func (r *IngressReconciler) includeWildcardSAN(ctx context.Context, dnsProvider DNSProvider, domain string, cfg *IngressConfig) error {
+	if !cfg.IncludeWildcardSAN || cfg.Wildcard || domain == "" || strings.HasPrefix(domain, "*.") {
+		return nil
+	}
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	apex, err := isApexDomain(ctx, dnsProvider, domain)
+	if err != nil {
+		return err
+	}
+	san := wildcardSAN(domain, apex)
+	if containsFold(cfg.SANs, san) {
+		return nil
+	}
+	log.FromContext(ctx).V(1).Info("Adding wildcard SAN", "domain", domain, "san", san)
+	cfg.SANs = append(cfg.SANs, san)
+	return nil
+}
+
+// redundantSANError reports SANs a wildcard name of the same certificate already covers, with
+// the error wildcard SAN policy
+type redundantSANError struct {
+	wildcards []string
+	sans      []string
+}
+
+func (e *redundantSANError) Error() string {
+	return fmt.Sprintf("SANs %s are already covered by %s; remove them or set --wildcard-san-policy=prune",
+		strings.Join(e.sans, ","), strings.Join(e.wildcards, ","))
+}
+
+// redundantSANs returns the wildcard names of the certificate requested for domain and the
+// SANs one of them covers, that is the SANs one label below a wildcard
+func redundantSANs(domain string, cfg IngressConfig) (wildcards, redundant []string) {
+	bases := map[string]bool{}
+	for _, name := range append([]string{certificateDomain(domain, cfg)}, cfg.SANs...) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if base, ok := strings.CutPrefix(name, "*."); ok && !bases[base] {
+			bases[base] = true
+			wildcards = append(wildcards, name)
+		}
+	}
+	for _, san := range cfg.SANs {
+		name := strings.ToLower(strings.TrimSpace(san))
+		if _, parent, ok := strings.Cut(name, "."); ok && !strings.HasPrefix(name, "*.") && bases[parent] {
+			redundant = append(redundant, san)
+		}
+	}
+	return wildcards, redundant
+}
+
+// applyWildcardSANPolicy keeps, prunes or rejects the SANs in cfg that a wildcard name of the
+// same certificate already covers, as WildcardSANPolicy says, and logs what it did
+func (r *IngressReconciler) applyWildcardSANPolicy(ctx context.Context, domain string, cfg *IngressConfig) error {
+	wildcards, redundant := redundantSANs(domain, *cfg)
+	if len(redundant) == 0 {
+		return nil
+	}
+	logger := log.FromContext(ctx).WithValues("wildcards", wildcards, "sans", redundant)
+	switch r.settings().WildcardSANPolicy {
+	case WildcardSANPolicyPrune:
+		logger.Info("Pruning SANs covered by a wildcard")
+		var sans []string
+		for _, san := range cfg.SANs {
+			if !containsFold(redundant, san) {
+				sans = append(sans, san)
+			}
+		}
+		cfg.SANs = sans
+	case WildcardSANPolicyError:
+		err := &redundantSANError{wildcards: wildcards, sans: redundant}
+		logger.Error(err, "rejecting SANs covered by a wildcard")
+		return err
+	default:
+		logger.V(1).Info("Keeping SANs covered by a wildcard")
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestIncludeWildcardSAN(t *testing.T) {
+	cases := []struct {
+		name        string
+		host        string
+		zones       []string
+		annotations map[string]string
+		wantSANs    []string
+	}{
+		{name: "apex", host: "example.com", zones: []string{"example.com"}, wantSANs: []string{"*.example.com"}},
+		{name: "subdomain", host: "app.example.com", zones: []string{"example.com"}, wantSANs: []string{"*.example.com"}},
+		{name: "delegated zone apex", host: "dev.example.com", zones: []string{"example.com", "dev.example.com"}, wantSANs: []string{"*.dev.example.com"}},
+		{name: "already present", host: "app.example.com", zones: []string{"example.com"}, annotations: map[string]string{"acm.tedens.dev/san": "*.EXAMPLE.com"}, wantSANs: []string{"*.EXAMPLE.com"}},
+		{name: "wildcard primary", host: "example.com", zones: []string{"example.com"}, annotations: map[string]string{"acm.tedens.dev/wildcard": "true"}},
+		{name: "covers www", host: "example.com", zones: []string{"example.com"}, annotations: map[string]string{includeWWWAnnotation: "true"}, wantSANs: []string{"*.example.com"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeACM := newFakeACM()
+			annotations := map[string]string{includeWildcardSANAnnotation: "true"}
+			for k, v := range tc.annotations {
+				annotations[k] = v
+			}
+			ingress := newManagedIngress("web", tc.host, annotations)
+			r := newTestReconciler(t, fakeACM, newFakeRoute53(tc.zones...), ingress)
+
+			if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+			if len(fakeACM.requests) != 1 {
+				t.Fatalf("expected one request, got %d", len(fakeACM.requests))
+			}
+			if got := fakeACM.requests[0].SubjectAlternativeNames; !slices.Equal(got, tc.wantSANs) {
+				t.Fatalf("SANs = %v, want %v", got, tc.wantSANs)
+			}
+		})
+	}
+}
+
+func TestWildcardSANPolicy(t *testing.T) {
+	cases := []struct {
+		name     string
+		policy   string
+		wantSANs []string
+		wantErr  bool
+	}{
+		{name: "default keeps", wantSANs: []string{"example.com", "specific.example.com", "deep.app.example.com"}},
+		{name: "keep", policy: WildcardSANPolicyKeep, wantSANs: []string{"example.com", "specific.example.com", "deep.app.example.com"}},
+		{name: "prune", policy: WildcardSANPolicyPrune, wantSANs: []string{"example.com", "deep.app.example.com"}},
+		{name: "error", policy: WildcardSANPolicyError, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeACM := newFakeACM()
+			ingress := newManagedIngress("web", "example.com", map[string]string{
+				"acm.tedens.dev/wildcard": "true",
+				"acm.tedens.dev/san":      "example.com,specific.example.com,deep.app.example.com",
+			})
+			r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+			r.WildcardSANPolicy = tc.policy
+
+			_, err := r.Reconcile(context.Background(), requestFor(ingress))
+			if tc.wantErr {
+				var redundant *redundantSANError
+				if !errors.As(err, &redundant) || !slices.Equal(redundant.sans, []string{"specific.example.com"}) {
+					t.Fatalf("expected a redundant SAN error for specific.example.com, got %v", err)
+				}
+				if quarantineReason(err) != "RedundantSAN" {
+					t.Errorf("quarantine reason = %q", quarantineReason(err))
+				}
+				if len(fakeACM.requests) != 0 {
+					t.Errorf("expected no certificate request, got %d", len(fakeACM.requests))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+			if len(fakeACM.requests) != 1 {
+				t.Fatalf("expected one request, got %d", len(fakeACM.requests))
+			}
+			if got := fakeACM.requests[0].SubjectAlternativeNames; !slices.Equal(got, tc.wantSANs) {
+				t.Fatalf("SANs = %v, want %v", got, tc.wantSANs)
+			}
+		})
+	}
+}
+
+func TestWildcardSANPolicyPrunesUnderWildcardSAN(t *testing.T) {
+	fakeACM := newFakeACM()
+	ingress := newManagedIngress("web", "app.example.com", map[string]string{
+		includeWildcardSANAnnotation: "true",
+		"acm.tedens.dev/san":         "api.example.com",
+	})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+	r.WildcardSANPolicy = WildcardSANPolicyPrune
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(fakeACM.requests))
+	}
+	if got := fakeACM.requests[0].SubjectAlternativeNames; !slices.Equal(got, []string{"*.example.com"}) {
+		t.Fatalf("SANs = %v, want [*.example.com]", got)
+	}
+}
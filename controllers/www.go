@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/tedens/acm-manager/pkg/certs"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// includeWWWAnnotation adds www.<domain> to the SANs of apex domains, and the apex to those of
+// their www host
+const includeWWWAnnotation = "acm.tedens.dev/include-www"
+
+// includeWWW appends www.<domain> to cfg.SANs when cfg asks for it and domain is an apex, unless
+// a *.<domain> SAN already covers it. For www.<apex> it appends the apex instead. A wildcard
+// primary domain already covers www and is left alone.
+func (r *IngressReconciler) includeWWW(ctx context.Context, dnsProvider DNSProvider, domain string, cfg *IngressConfig) error {
+	if !cfg.IncludeWWW || cfg.Wildcard || domain == "" || strings.HasPrefix(domain, "*.") {
+		return nil
+	}
+	if apex, ok := strings.CutPrefix(strings.ToLower(domain), "www."); ok && strings.Contains(apex, ".") {
+		if containsFold(cfg.SANs, apex) {
+			return nil
+		}
+		isApex, err := isApexDomain(ctx, dnsProvider, apex)
+		if err != nil || !isApex {
+			return err
+		}
+		log.FromContext(ctx).V(1).Info("Adding apex SAN for www domain", "domain", domain)
+		cfg.SANs = append(cfg.SANs, apex)
+		return nil
+	}
+	www := "www." + domain
+	if containsFold(cfg.SANs, www) || containsFold(cfg.SANs, "*."+domain) {
+		return nil
+	}
+	apex, err := isApexDomain(ctx, dnsProvider, domain)
+	if err != nil || !apex {
+		return err
+	}
+	log.FromContext(ctx).V(1).Info("Adding www SAN for apex domain", "domain", domain)
+	cfg.SANs = append(cfg.SANs, www)
+	return nil
+}
+
+// isApexDomain reports whether domain has two labels or is the name of its DNS zone, that is
+// its parent resolves to a different zone or none. Providers that cannot look zones up, such
+// as manual, only get the two-label rule.
+func isApexDomain(ctx context.Context, dnsProvider DNSProvider, domain string) (bool, error) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if strings.Count(domain, ".") <= 1 {
+		return true, nil
+	}
+	zone, err := dnsProvider.FindZone(ctx, domain)
+	if errors.Is(err, certs.ErrNoHostedZone) || (err == nil && zone == "") {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	_, parent, _ := strings.Cut(domain, ".")
+	parentZone, err := dnsProvider.FindZone(ctx, parent)
+	if errors.Is(err, certs.ErrNoHostedZone) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return parentZone != zone, nil
+}
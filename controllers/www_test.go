@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+func TestIncludeWWW(t *testing.T) {
+	cases := []struct {
+		name        string
+		host        string
+		zones       []string
+		annotations map[string]string
+		flag        bool
+		wantSANs    []string
+	}{
+		{name: "apex", host: "example.com", zones: []string{"example.com"}, annotations: map[string]string{includeWWWAnnotation: "true"}, wantSANs: []string{"www.example.com"}},
+		{name: "subdomain", host: "app.example.com", zones: []string{"example.com"}, annotations: map[string]string{includeWWWAnnotation: "true"}},
+		{name: "delegated zone apex", host: "dev.example.com", zones: []string{"example.com", "dev.example.com"}, annotations: map[string]string{includeWWWAnnotation: "true"}, wantSANs: []string{"www.dev.example.com"}},
+		{name: "already present", host: "example.com", zones: []string{"example.com"}, annotations: map[string]string{includeWWWAnnotation: "true", "acm.tedens.dev/san": "WWW.example.com"}, wantSANs: []string{"WWW.example.com"}},
+		{name: "flag default", host: "example.com", zones: []string{"example.com"}, flag: true, wantSANs: []string{"www.example.com"}},
+		{name: "www adds apex", host: "www.example.com", zones: []string{"example.com"}, annotations: map[string]string{includeWWWAnnotation: "true"}, wantSANs: []string{"example.com"}},
+		{name: "www of delegated zone apex", host: "www.dev.example.com", zones: []string{"example.com", "dev.example.com"}, annotations: map[string]string{includeWWWAnnotation: "true"}, wantSANs: []string{"dev.example.com"}},
+		{name: "www of subdomain", host: "www.app.example.com", zones: []string{"example.com"}, annotations: map[string]string{includeWWWAnnotation: "true"}},
+		{name: "apex already present", host: "www.example.com", zones: []string{"example.com"}, annotations: map[string]string{includeWWWAnnotation: "true", "acm.tedens.dev/san": "Example.com"}, wantSANs: []string{"Example.com"}},
+		{name: "wildcard primary", host: "example.com", zones: []string{"example.com"}, annotations: map[string]string{includeWWWAnnotation: "true", "acm.tedens.dev/wildcard": "true"}},
+		{name: "wildcard www primary", host: "www.example.com", zones: []string{"example.com"}, annotations: map[string]string{includeWWWAnnotation: "true", "acm.tedens.dev/wildcard": "true"}},
+		{name: "annotation overrides flag", host: "example.com", zones: []string{"example.com"}, annotations: map[string]string{includeWWWAnnotation: "false"}, flag: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeACM := newFakeACM()
+			ingress := newManagedIngress("web", tc.host, tc.annotations)
+			r := newTestReconciler(t, fakeACM, newFakeRoute53(tc.zones...), ingress)
+			r.IncludeWWW = tc.flag
+
+			if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+			if len(fakeACM.requests) != 1 {
+				t.Fatalf("expected one request, got %d", len(fakeACM.requests))
+			}
+			if got := fakeACM.requests[0].SubjectAlternativeNames; !slices.Equal(got, tc.wantSANs) {
+				t.Fatalf("SANs = %v, want %v", got, tc.wantSANs)
+			}
+		})
+	}
+}
+
+func TestIncludeWWWRequiredForReuse(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.addCert("example.com", acmtypes.CertificateStatusIssued, map[string]string{"ManagedBy": DefaultManagedByValue})
+	ingress := newManagedIngress("web", "example.com", map[string]string{includeWWWAnnotation: "true"})
+	r := newTestReconciler(t, fakeACM, newFakeRoute53("example.com"), ingress)
+
+	if _, err := r.Reconcile(context.Background(), requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeACM.requests) != 1 || !slices.Contains(fakeACM.requests[0].SubjectAlternativeNames, "www.example.com") {
+		t.Fatalf("a certificate without the www SAN must not be reused, got %d requests", len(fakeACM.requests))
+	}
+}
@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	acmv1alpha1 "github.com/tedens/acm-manager/api/v1alpha1"
+	"github.com/tedens/acm-manager/pkg/certs"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// zoneMappingEntry is one DomainZoneMapping entry, normalized
+type zoneMappingEntry struct {
+	suffix  string
+	zoneID  string
+	roleARN string
+	// mapping names the DomainZoneMapping the entry is from
+	mapping string
+}
+
+// normalizeZoneDomain lowercases name and drops a wildcard label and surrounding dots
+func normalizeZoneDomain(name string) string {
+	return strings.Trim(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "*."), ".")
+}
+
+// zoneMappingEntries returns the entries of every DomainZoneMapping, longest suffix first and
+// then by mapping name, or nil unless DomainZoneMappings is set. Entries with an invalid role
+// are skipped.
+func (r *IngressReconciler) zoneMappingEntries(ctx context.Context) ([]zoneMappingEntry, error) {
+	if !r.DomainZoneMappings {
+		return nil, nil
+	}
+	var mappings acmv1alpha1.DomainZoneMappingList
+	if err := r.List(ctx, &mappings); err != nil {
+		return nil, fmt.Errorf("failed to list DomainZoneMappings: %w", err)
+	}
+	var entries []zoneMappingEntry
+	for _, mapping := range mappings.Items {
+		for _, entry := range mapping.Spec.Entries {
+			suffix := normalizeZoneDomain(entry.Suffix)
+			zoneID := strings.TrimPrefix(strings.TrimSpace(entry.HostedZoneID), "/hostedzone/")
+			roleARN := strings.TrimSpace(entry.RoleARN)
+			if suffix == "" || zoneID == "" {
+				continue
+			}
+			if err := validateRoleARN(roleARN); err != nil {
+				log.FromContext(ctx).Info("Skipping DomainZoneMapping entry with an invalid role", "mapping", mapping.Name, "suffix", suffix, "error", err.Error())
+				continue
+			}
+			entries = append(entries, zoneMappingEntry{suffix: suffix, zoneID: zoneID, roleARN: roleARN, mapping: mapping.Name})
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b zoneMappingEntry) int {
+		return cmp.Or(cmp.Compare(len(b.suffix), len(a.suffix)), cmp.Compare(a.mapping, b.mapping))
+	})
+	return entries, nil
+}
+
+// matchZoneMapping returns the entry with the longest suffix covering name
+func matchZoneMapping(entries []zoneMappingEntry, name string) (zoneMappingEntry, bool) {
+	name = normalizeZoneDomain(name)
+	for _, entry := range entries {
+		if name == entry.suffix || strings.HasSuffix(name, "."+entry.suffix) {
+			return entry, true
+		}
+	}
+	return zoneMappingEntry{}, false
+}
+
+// zoneMappedDNS routes validation records to the hosted zones of the DomainZoneMapping entries
+// covering their names, writing them with the entry's role when it has one. Names no entry
+// covers, and changes to a zone given explicitly, are left to the wrapped provider.
+type zoneMappedDNS struct {
+	DNSProvider
+	entries []zoneMappingEntry
+	// providerFor returns the Route 53 provider acting with roleARN
+	providerFor func(ctx context.Context, roleARN string) (DNSProvider, error)
+}
+
+func (p *zoneMappedDNS) FindZone(ctx context.Context, domain string) (string, error) {
+	if entry, ok := matchZoneMapping(p.entries, domain); ok {
+		return entry.zoneID, nil
+	}
+	return p.DNSProvider.FindZone(ctx, domain)
+}
+
+func (p *zoneMappedDNS) EnsureRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	return p.change(ctx, zoneID, records, DNSProvider.EnsureRecords)
+}
+
+func (p *zoneMappedDNS) DeleteRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	return p.change(ctx, zoneID, records, DNSProvider.DeleteRecords)
+}
+
+// change applies apply to the records, grouped by the entry covering each of them
+func (p *zoneMappedDNS) change(ctx context.Context, zoneID string, records []ValidationRecord, apply func(DNSProvider, context.Context, string, []ValidationRecord) error) error {
+	if zoneID != "" {
+		return apply(p.DNSProvider, ctx, zoneID, records)
+	}
+	type route struct{ zoneID, roleARN string }
+	var routes []route
+	grouped := map[route][]ValidationRecord{}
+	for _, record := range records {
+		var target route
+		if entry, ok := matchZoneMapping(p.entries, validationRecordZoneDomain(record)); ok {
+			target = route{zoneID: entry.zoneID, roleARN: entry.roleARN}
+		}
+		if _, ok := grouped[target]; !ok {
+			routes = append(routes, target)
+		}
+		grouped[target] = append(grouped[target], record)
+	}
+	for _, target := range routes {
+		provider := p.DNSProvider
+		if target.roleARN != "" {
+			var err error
+			if provider, err = p.providerFor(ctx, target.roleARN); err != nil {
+				return fmt.Errorf("failed to assume %s for hosted zone %s: %w", target.roleARN, target.zoneID, err)
+			}
+		}
+		if err := apply(provider, ctx, target.zoneID, grouped[target]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validationRecordZoneDomain returns the name whose zone holds record, as the Route 53
+// provider picks it: the record's own name, or the name a wildcard covers
+func validationRecordZoneDomain(record ValidationRecord) string {
+	if name := strings.TrimSuffix(record.Name, "."); name != "" {
+		return name
+	}
+	return record.Domain
+}
+
+// zoneMappedProvider returns provider routing records by the DomainZoneMapping entries, or
+// provider itself when there are none
+func (r *IngressReconciler) zoneMappedProvider(ctx context.Context, provider DNSProvider) (DNSProvider, error) {
+	entries, err := r.zoneMappingEntries(ctx)
+	if err != nil || len(entries) == 0 {
+		return provider, err
+	}
+	return &zoneMappedDNS{DNSProvider: provider, entries: entries, providerFor: r.route53ProviderForRole}, nil
+}
+
+// route53ProviderForRole returns the Route 53 provider of roleARN in the default region
+func (r *IngressReconciler) route53ProviderForRole(ctx context.Context, roleARN string) (DNSProvider, error) {
+	clients, err := r.awsClients().get(ctx, awsTarget{RoleARN: roleARN})
+	if err != nil {
+		return nil, err
+	}
+	if clients.DNS == nil {
+		return nil, fmt.Errorf("route53 DNS provider is not configured")
+	}
+	return clients.DNS, nil
+}
+
+// zoneMappingChanges remembers when the DomainZoneMappings last changed and which Ingresses
+// should skip their retry backoff because of it
+type zoneMappingChanges struct {
+	mu      sync.Mutex
+	at      time.Time
+	retries map[types.NamespacedName]bool
+}
+
+func (c *zoneMappingChanges) changed(at time.Time, keys []types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.at = at
+	if c.retries == nil {
+		c.retries = map[types.NamespacedName]bool{}
+	}
+	for _, key := range keys {
+		c.retries[key] = true
+	}
+}
+
+// lastChange returns when the mappings last changed, zero when they did not since the start
+func (c *zoneMappingChanges) lastChange() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.at
+}
+
+// takeRetry reports whether the Ingress should skip its backoff, once per change
+func (c *zoneMappingChanges) takeRetry(key types.NamespacedName) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	retry := c.retries[key]
+	delete(c.retries, key)
+	return retry
+}
+
+// zoneMappingChanged ignores the DomainZoneMappings listed at startup, which every Ingress is
+// reconciled with anyway
+func zoneMappingChanged() predicate.Predicate {
+	return predicate.Funcs{CreateFunc: func(e event.CreateEvent) bool { return !e.IsInInitialList }}
+}
+
+// enqueueIngressesForZoneMapping requeues the managed Ingresses validating a certificate or
+// failing when a DomainZoneMapping changes, so a fixed mapping unblocks them
+func (r *IngressReconciler) enqueueIngressesForZoneMapping(ctx context.Context, obj client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	var keys []types.NamespacedName
+	for _, request := range r.managedIngressRequests(ctx) {
+		var ingress networkingv1.Ingress
+		if err := r.Get(ctx, request.NamespacedName, &ingress); err != nil {
+			continue
+		}
+		_, validating := ingress.Annotations[validationStateAnnotation]
+		_, failing := ingress.Annotations[lastErrorAnnotation]
+		if !validating && !failing {
+			continue
+		}
+		requests = append(requests, request)
+		keys = append(keys, request.NamespacedName)
+	}
+	r.zoneMappingChanges.changed(r.clock(), keys)
+	log.FromContext(ctx).Info("DomainZoneMapping changed, requeueing pending Ingresses", "mapping", obj.GetName(), "count", len(requests))
+	return requests
+}
+
+// resumeAfterZoneMappingChange returns state, set back to rewrite its validation records when
+// the DomainZoneMappings changed since they were written to discovered zones
+func (r *IngressReconciler) resumeAfterZoneMappingChange(ctx context.Context, state *certs.ValidationState) *certs.ValidationState {
+	changed := r.zoneMappingChanges.lastChange()
+	if state == nil || state.Phase != certs.PhaseRecordsCreated || state.ZoneID != "" || changed.IsZero() || !state.RecordsCreatedAt.Before(changed) {
+		return state
+	}
+	log.FromContext(ctx).Info("DomainZoneMapping changed since the validation records were written, writing them again", "arn", state.CertificateArn)
+	rewritten := *state
+	rewritten.Phase = certs.PhaseRequested
+	return &rewritten
+}
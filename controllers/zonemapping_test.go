@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmv1alpha1 "github.com/tedens/acm-manager/api/v1alpha1"
+	"github.com/tedens/acm-manager/pkg/certs"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newZoneMapping(name string, entries ...acmv1alpha1.DomainZoneEntry) *acmv1alpha1.DomainZoneMapping {
+	return &acmv1alpha1.DomainZoneMapping{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       acmv1alpha1.DomainZoneMappingSpec{Entries: entries},
+	}
+}
+
+func TestMatchZoneMappingLongestSuffixWins(t *testing.T) {
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53(),
+		newZoneMapping("dns-team",
+			acmv1alpha1.DomainZoneEntry{Suffix: "example.com", HostedZoneID: "/hostedzone/ZAPEX"},
+			acmv1alpha1.DomainZoneEntry{Suffix: ".dev.example.com.", HostedZoneID: "ZDEV"},
+			acmv1alpha1.DomainZoneEntry{Suffix: "bad.example.com", HostedZoneID: "ZBAD", RoleARN: "not-a-role"},
+		))
+	r.DomainZoneMappings = true
+	entries, err := r.zoneMappingEntries(context.Background())
+	if err != nil {
+		t.Fatalf("zoneMappingEntries: %v", err)
+	}
+	cases := map[string]string{
+		"example.com":         "ZAPEX",
+		"app.example.com":     "ZAPEX",
+		"*.dev.example.com":   "ZDEV",
+		"api.Dev.Example.com": "ZDEV",
+		"bad.example.com":     "ZAPEX",
+		"notexample.com":      "",
+	}
+	for name, want := range cases {
+		entry, _ := matchZoneMapping(entries, name)
+		if entry.zoneID != want {
+			t.Errorf("matchZoneMapping(%q) = %q, want %q", name, entry.zoneID, want)
+		}
+	}
+
+	r.DomainZoneMappings = false
+	if entries, err := r.zoneMappingEntries(context.Background()); err != nil || entries != nil {
+		t.Fatalf("disabled mappings = %v, %v; want none", entries, err)
+	}
+}
+
+func TestZoneMappingRoutesValidationRecords(t *testing.T) {
+	ctx := context.Background()
+	fakeR53 := newFakeRoute53("example.com")
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	pinned := newManagedIngress("pinned", "api.example.com", map[string]string{"acm.tedens.dev/zone-id": "Z1"})
+	r := newTestReconciler(t, newFakeACM(), fakeR53, ingress, pinned,
+		newZoneMapping("dns-team", acmv1alpha1.DomainZoneEntry{Suffix: "example.com", HostedZoneID: "ZMAPPED"}))
+	r.DomainZoneMappings = true
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeR53.changes) == 0 || aws.ToString(fakeR53.changes[0].HostedZoneId) != "ZMAPPED" {
+		t.Fatalf("expected the records in the mapped zone, got %+v", fakeR53.changes)
+	}
+
+	// The zone-id annotation overrides the mapping
+	before := len(fakeR53.changes)
+	if _, err := r.Reconcile(ctx, requestFor(pinned)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeR53.changes) == before || aws.ToString(fakeR53.changes[before].HostedZoneId) != "Z1" {
+		t.Fatalf("expected the records in the annotated zone, got %+v", fakeR53.changes[before:])
+	}
+}
+
+func TestZoneMappingWritesRecordsWithEntryRole(t *testing.T) {
+	const role = "arn:aws:iam::444455556666:role/dns"
+	ctx := context.Background()
+	fakeR53 := newFakeRoute53("example.com")
+	ingress := newManagedIngress("web", "app.example.com", nil)
+	r := newTestReconciler(t, newFakeACM(), fakeR53, ingress,
+		newZoneMapping("dns-team", acmv1alpha1.DomainZoneEntry{Suffix: "example.com", HostedZoneID: "ZSHARED", RoleARN: role}))
+	r.DomainZoneMappings = true
+	roleR53 := newFakeRoute53()
+	r.clientsOnce.Do(func() {
+		r.clients = &awsClientCache{
+			loadConfig: func(context.Context) (aws.Config, error) { return aws.Config{Region: "us-east-1"}, nil },
+			assumeRole: func(_ aws.Config, roleARN string) aws.CredentialsProvider { return roleCredentials(roleARN) },
+			build:      func(aws.Config) awsClients { return awsClients{ACM: newFakeACM(), Route53: roleR53} },
+		}
+	})
+
+	if _, err := r.Reconcile(ctx, requestFor(ingress)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(fakeR53.changes) != 0 {
+		t.Fatalf("the controller's own account changed records: %+v", fakeR53.changes)
+	}
+	if len(roleR53.changes) == 0 || aws.ToString(roleR53.changes[0].HostedZoneId) != "ZSHARED" {
+		t.Fatalf("expected the records written with %s, got %+v", role, roleR53.changes)
+	}
+}
+
+func TestZoneMappingChangeRequeuesPendingIngresses(t *testing.T) {
+	ctx := context.Background()
+	failing := newManagedIngress("failing", "app.unknown.test", map[string]string{lastErrorAnnotation: "no hosted zone"})
+	healthy := newManagedIngress("healthy", "app.example.com", nil)
+	mapping := newZoneMapping("dns-team", acmv1alpha1.DomainZoneEntry{Suffix: "unknown.test", HostedZoneID: "ZFIXED"})
+	r := newTestReconciler(t, newFakeACM(), newFakeRoute53("example.com"), failing, healthy, mapping)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	requests := r.enqueueIngressesForZoneMapping(ctx, mapping)
+	if len(requests) != 1 || requests[0].NamespacedName != requestFor(failing).NamespacedName {
+		t.Fatalf("requeued %v, want only the failing Ingress", requests)
+	}
+	if !r.zoneMappingChanges.takeRetry(requestFor(failing).NamespacedName) {
+		t.Fatal("expected the failing Ingress to skip its backoff")
+	}
+	if r.zoneMappingChanges.takeRetry(requestFor(failing).NamespacedName) {
+		t.Fatal("the backoff is skipped once per change")
+	}
+
+	written := &certs.ValidationState{CertificateArn: "arn", Phase: certs.PhaseRecordsCreated, RecordsCreatedAt: now.Add(-time.Minute)}
+	if got := r.resumeAfterZoneMappingChange(ctx, written); got.Phase != certs.PhaseRequested {
+		t.Errorf("records written before the change resume in %q, want them written again", got.Phase)
+	}
+	pinned := &certs.ValidationState{CertificateArn: "arn", Phase: certs.PhaseRecordsCreated, ZoneID: "Z1", RecordsCreatedAt: now.Add(-time.Minute)}
+	if got := r.resumeAfterZoneMappingChange(ctx, pinned); got.Phase != certs.PhaseRecordsCreated {
+		t.Errorf("records in an annotated zone resume in %q, want them kept", got.Phase)
+	}
+	fresh := &certs.ValidationState{CertificateArn: "arn", Phase: certs.PhaseRecordsCreated, RecordsCreatedAt: now.Add(time.Minute)}
+	if got := r.resumeAfterZoneMappingChange(ctx, fresh); got != fresh {
+		t.Errorf("records written after the change resume in %q, want them kept", got.Phase)
+	}
+}
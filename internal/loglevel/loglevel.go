@@ -0,0 +1,198 @@
+// Package loglevel adjusts the controller's log level at runtime, over HTTP and with signals,
+// reverting to the configured level after a while.
+package loglevel
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Path is where Controller is served on the metrics server
+const Path = "/loglevel"
+
+// The levels that can be selected, from most to least verbose
+var levels = []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel}
+
+// Parse parses debug, info or warn
+func Parse(value string) (zapcore.Level, error) {
+	for _, level := range levels {
+		if strings.EqualFold(strings.TrimSpace(value), level.String()) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, want debug, info or warn", value)
+}
+
+// Controller owns the atomic level of the logger. Levels other than the base level revert to
+// it after RevertAfter; zero keeps them until changed again. It is safe for concurrent use.
+type Controller struct {
+	level       zap.AtomicLevel
+	base        zapcore.Level
+	revertAfter time.Duration
+	gauge       prometheus.Gauge
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	revertAt time.Time
+}
+
+// New returns a Controller starting at base
+func New(base zapcore.Level, revertAfter time.Duration) *Controller {
+	c := &Controller{
+		level:       zap.NewAtomicLevelAt(base),
+		base:        base,
+		revertAfter: revertAfter,
+		gauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "acm_manager_log_level",
+			Help: "Current log level as a zap level: -1 debug, 0 info, 1 warn.",
+		}),
+	}
+	c.gauge.Set(float64(base))
+	return c
+}
+
+// Level is the atomic level to build the logger with
+func (c *Controller) Level() zap.AtomicLevel {
+	return c.level
+}
+
+// RegisterMetrics registers acm_manager_log_level
+func (c *Controller) RegisterMetrics(registerer prometheus.Registerer) error {
+	return registerer.Register(c.gauge)
+}
+
+// Set switches to level and, unless it is the base level, schedules the revert
+func (c *Controller) Set(ctx context.Context, level zapcore.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(ctx, level)
+}
+
+func (c *Controller) setLocked(ctx context.Context, level zapcore.Level) {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.revertAt = time.Time{}
+	c.level.SetLevel(level)
+	c.gauge.Set(float64(level))
+
+	logger := log.FromContext(ctx).WithName("loglevel")
+	if level == c.base || c.revertAfter <= 0 {
+		logger.Info("Log level changed", "level", level.String())
+		return
+	}
+	c.revertAt = time.Now().Add(c.revertAfter)
+	logger.Info("Log level changed", "level", level.String(), "revertAt", c.revertAt.UTC())
+	c.timer = time.AfterFunc(c.revertAfter, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.level.Level() != level {
+			return
+		}
+		c.setLocked(ctx, c.base)
+	})
+}
+
+// Step moves the level by delta places in debug, info, warn, stopping at either end
+func (c *Controller) Step(ctx context.Context, delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current := 0
+	for i, level := range levels {
+		if level == c.level.Level() {
+			current = i
+		}
+	}
+	next := min(max(current+delta, 0), len(levels)-1)
+	c.setLocked(ctx, levels[next])
+}
+
+type status struct {
+	Level    string     `json:"level"`
+	Base     string     `json:"base"`
+	RevertAt *time.Time `json:"revertAt,omitempty"`
+}
+
+func (c *Controller) status() status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := status{Level: c.level.Level().String(), Base: c.base.String()}
+	if !c.revertAt.IsZero() {
+		revertAt := c.revertAt.UTC()
+		s.RevertAt = &revertAt
+	}
+	return s
+}
+
+// Handler serves GET, answering the current level, and PUT with a body of debug, info or warn.
+// When token is set, PUT requests must carry it as a bearer token.
+func (c *Controller) Handler(ctx context.Context, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			if token != "" && subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			body, err := io.ReadAll(io.LimitReader(req.Body, 64))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			level, err := Parse(string(body))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			c.Set(ctx, level)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "use GET or PUT", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c.status())
+	})
+}
+
+// NeedLeaderElection lets every replica handle signals
+func (c *Controller) NeedLeaderElection() bool {
+	return false
+}
+
+// Start makes SIGUSR1 raise the verbosity one level and SIGUSR2 lower it, until ctx is
+// cancelled
+func (c *Controller) Start(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig := <-signals:
+			if sig == syscall.SIGUSR1 {
+				c.Step(ctx, -1)
+			} else {
+				c.Step(ctx, 1)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package loglevel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParse(t *testing.T) {
+	for value, want := range map[string]zapcore.Level{"debug": zapcore.DebugLevel, " INFO\n": zapcore.InfoLevel, "warn": zapcore.WarnLevel} {
+		if got, err := Parse(value); err != nil || got != want {
+			t.Errorf("Parse(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := Parse("error"); err == nil {
+		t.Error("Parse should reject levels outside debug, info and warn")
+	}
+}
+
+func TestSetRevertsToBase(t *testing.T) {
+	c := New(zapcore.InfoLevel, 20*time.Millisecond)
+	c.Set(context.Background(), zapcore.DebugLevel)
+	if c.Level().Level() != zapcore.DebugLevel || c.status().RevertAt == nil {
+		t.Fatalf("expected debug with a revert time, got %+v", c.status())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Level().Level() != zapcore.InfoLevel {
+		if time.Now().After(deadline) {
+			t.Fatal("level was not reverted to info")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if c.status().RevertAt != nil {
+		t.Fatalf("the base level should not carry a revert time, got %+v", c.status())
+	}
+}
+
+func TestStepStopsAtEnds(t *testing.T) {
+	c := New(zapcore.InfoLevel, 0)
+	ctx := context.Background()
+	c.Step(ctx, -1)
+	c.Step(ctx, -1)
+	if got := c.Level().Level(); got != zapcore.DebugLevel {
+		t.Fatalf("stepping past debug should stay at debug, got %v", got)
+	}
+	c.Step(ctx, 5)
+	if got := c.Level().Level(); got != zapcore.WarnLevel {
+		t.Fatalf("stepping past warn should stay at warn, got %v", got)
+	}
+	if c.status().RevertAt != nil {
+		t.Fatal("a zero revert duration should keep the level")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := New(zapcore.InfoLevel, time.Hour)
+	handler := c.Handler(context.Background(), "secret")
+	serve := func(method, body, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, Path, strings.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(http.MethodPut, "debug", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("PUT without the token: got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPut, "verbose", "Bearer secret"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("PUT with an unknown level: got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "debug", "Bearer secret"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST: got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPut, "debug\n", "Bearer secret"); rec.Code != http.StatusOK || c.Level().Level() != zapcore.DebugLevel {
+		t.Fatalf("PUT debug: got %d, level %v", rec.Code, c.Level().Level())
+	}
+
+	rec := serve(http.MethodGet, "", "")
+	var got status
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET: %d, %v", rec.Code, err)
+	}
+	if got.Level != "debug" || got.Base != "info" || got.RevertAt == nil {
+		t.Fatalf("unexpected status %+v", got)
+	}
+}
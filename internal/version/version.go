@@ -0,0 +1,31 @@
+// Package version holds build information injected at link time via -ldflags.
+package version
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Set with -ldflags "-X github.com/tedens/acm-manager/internal/version.Version=..."
+var (
+	Version = "dev"
+	Commit  = "unknown"
+	Date    = "unknown"
+)
+
+// String returns a single-line human readable description of the build
+func String() string {
+	return fmt.Sprintf("acm-manager %s (commit %s, built %s, %s)", Version, Commit, Date, runtime.Version())
+}
+
+// RegisterMetrics registers the acm_manager_build_info gauge, always 1, labelled with the build information
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	buildInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "acm_manager_build_info",
+		Help: "Build information of the running acm-manager binary.",
+	}, []string{"version", "commit", "date", "goversion"})
+	buildInfo.WithLabelValues(Version, Commit, Date, runtime.Version()).Set(1)
+	return registerer.Register(buildInfo)
+}
@@ -0,0 +1,39 @@
+package version
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if err := RegisterMetrics(registry); err != nil {
+		t.Fatalf("RegisterMetrics: %v", err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	if len(families) != 1 || families[0].GetName() != "acm_manager_build_info" {
+		t.Fatalf("unexpected metric families %v", families)
+	}
+	metric := families[0].GetMetric()[0]
+	if metric.GetGauge().GetValue() != 1 {
+		t.Fatalf("build info gauge = %v, want 1", metric.GetGauge().GetValue())
+	}
+	labels := map[string]string{}
+	for _, l := range metric.GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	if labels["version"] != Version || labels["commit"] != Commit {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+}
+
+func TestString(t *testing.T) {
+	if !strings.Contains(String(), Version) {
+		t.Fatalf("String() = %q should contain the version", String())
+	}
+}
@@ -0,0 +1,69 @@
+// Package certs requests ACM certificates, writes their DNS validation records and waits
+// for them to be issued. It holds no Kubernetes state, so any controller can drive it.
+package certs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+)
+
+// ACMAPI is the subset of the ACM client used by acm-manager
+type ACMAPI interface {
+	RequestCertificate(ctx context.Context, params *acm.RequestCertificateInput, optFns ...func(*acm.Options)) (*acm.RequestCertificateOutput, error)
+	DescribeCertificate(ctx context.Context, params *acm.DescribeCertificateInput, optFns ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error)
+	ListCertificates(ctx context.Context, params *acm.ListCertificatesInput, optFns ...func(*acm.Options)) (*acm.ListCertificatesOutput, error)
+	ListTagsForCertificate(ctx context.Context, params *acm.ListTagsForCertificateInput, optFns ...func(*acm.Options)) (*acm.ListTagsForCertificateOutput, error)
+	DeleteCertificate(ctx context.Context, params *acm.DeleteCertificateInput, optFns ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error)
+	ImportCertificate(ctx context.Context, params *acm.ImportCertificateInput, optFns ...func(*acm.Options)) (*acm.ImportCertificateOutput, error)
+	AddTagsToCertificate(ctx context.Context, params *acm.AddTagsToCertificateInput, optFns ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error)
+}
+
+// Route53API is the subset of the Route 53 client used by Route53Provider
+type Route53API interface {
+	ChangeResourceRecordSets(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error)
+	ListHostedZones(ctx context.Context, params *route53.ListHostedZonesInput, optFns ...func(*route53.Options)) (*route53.ListHostedZonesOutput, error)
+	ListTagsForResource(ctx context.Context, params *route53.ListTagsForResourceInput, optFns ...func(*route53.Options)) (*route53.ListTagsForResourceOutput, error)
+}
+
+var (
+	_ ACMAPI     = (*acm.Client)(nil)
+	_ Route53API = (*route53.Client)(nil)
+)
+
+// ManagedByTagKey is the tag identifying which acm-manager instance owns a certificate
+const ManagedByTagKey = "ManagedBy"
+
+// DefaultManagedByValue is the ManagedBy tag value used when none is configured
+const DefaultManagedByValue = "acm-manager"
+
+// ManagedBy reports whether the certificate's ManagedBy tag equals value
+func ManagedBy(ctx context.Context, acmClient ACMAPI, certArn, value string) (bool, error) {
+	return ManagedByWithTags(ctx, acmClient, certArn, value, nil)
+}
+
+// ManagedByWithTags reports whether the certificate's ManagedBy tag equals value and it
+// carries every tag in required with the same value
+func ManagedByWithTags(ctx context.Context, acmClient ACMAPI, certArn, value string, required map[string]string) (bool, error) {
+	out, err := acmClient.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{
+		CertificateArn: aws.String(certArn),
+	})
+	if err != nil {
+		return false, err
+	}
+	tags := make(map[string]string, len(out.Tags))
+	for _, tag := range out.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	if owner, ok := tags[ManagedByTagKey]; !ok || owner != value {
+		return false, nil
+	}
+	for key, want := range required {
+		if got, ok := tags[key]; !ok || got != want {
+			return false, nil
+		}
+	}
+	return true, nil
+}
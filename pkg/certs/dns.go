@@ -0,0 +1,90 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ValidationRecord is a DNS record ACM requires to validate one domain of a certificate
+type ValidationRecord struct {
+	Domain string `json:"domain"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+}
+
+// DNSProvider manages ACM validation records in a DNS service. An empty zoneID asks the
+// provider to locate the zone of each record's domain itself.
+type DNSProvider interface {
+	// FindZone returns the identifier of the zone that should hold records for domain
+	FindZone(ctx context.Context, domain string) (string, error)
+	// EnsureRecords creates or updates records so they match
+	EnsureRecords(ctx context.Context, zoneID string, records []ValidationRecord) error
+	// DeleteRecords removes records, ignoring ones that do not exist
+	DeleteRecords(ctx context.Context, zoneID string, records []ValidationRecord) error
+}
+
+// ValidationRecords returns the distinct validation records of a certificate, failing if
+// ACM has not produced a record for every domain yet
+func ValidationRecords(ctx context.Context, acmClient ACMAPI, certArn string) ([]ValidationRecord, error) {
+	describe, err := acmClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+		CertificateArn: aws.String(certArn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe certificate: %w", err)
+	}
+
+	records, missing := validationRecordsOf(describe.Certificate, nil)
+	if len(missing) > 0 {
+		log.FromContext(ctx).Info("ResourceRecord is nil, skipping and requeuing", "domain", missing[0])
+		return nil, fmt.Errorf("resource record not available yet for domain: %s", missing[0])
+	}
+	return records, nil
+}
+
+// validationRecordsOf returns the distinct validation records of cert, and the names ACM has
+// not published a record for yet: those among names, or among the certificate's validation
+// options when names is empty
+func validationRecordsOf(cert *acmtypes.CertificateDetail, names []string) ([]ValidationRecord, []string) {
+	published := make(map[string]bool)
+	seen := make(map[string]bool)
+	var records []ValidationRecord
+	var missing []string
+	for _, option := range cert.DomainValidationOptions {
+		domain := aws.ToString(option.DomainName)
+		record := option.ResourceRecord
+		if record == nil {
+			if len(names) == 0 {
+				missing = append(missing, domain)
+			}
+			continue
+		}
+		published[strings.ToLower(domain)] = true
+
+		key := fmt.Sprintf("%s|%s|%s", aws.ToString(record.Name), record.Type, aws.ToString(record.Value))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		records = append(records, ValidationRecord{
+			Domain: domain,
+			Name:   aws.ToString(record.Name),
+			Type:   string(record.Type),
+			Value:  aws.ToString(record.Value),
+		})
+	}
+	for _, name := range names {
+		if !published[strings.ToLower(name)] && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	return records, missing
+}
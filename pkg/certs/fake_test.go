@@ -0,0 +1,255 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// fakeACM is an in-memory ACMAPI. Each describe of a requested certificate moves it to
+// requestedStatus; seeded certificates keep the status they were added with. domainStatus
+// sets the validation status of individual names on requested certificates. The first
+// unpublished describes of a requested certificate return it pending with only its primary
+// name's validation option and no ResourceRecord, as ACM does right after a request.
+type fakeACM struct {
+	mu              sync.Mutex
+	domainStatus    map[string]acmtypes.DomainStatus
+	unpublished     int
+	describes       int
+	certs           map[string]*acmtypes.CertificateDetail
+	tags            map[string][]acmtypes.Tag
+	requests        []*acm.RequestCertificateInput
+	requested       map[string]bool
+	requestedStatus acmtypes.CertificateStatus
+	next            int
+}
+
+func newFakeACM() *fakeACM {
+	return &fakeACM{
+		certs:           map[string]*acmtypes.CertificateDetail{},
+		tags:            map[string][]acmtypes.Tag{},
+		requested:       map[string]bool{},
+		requestedStatus: acmtypes.CertificateStatusIssued,
+	}
+}
+
+func (f *fakeACM) addCert(domain string, status acmtypes.CertificateStatus, managedBy string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	arn := f.newArnLocked()
+	f.certs[arn] = &acmtypes.CertificateDetail{
+		CertificateArn:          aws.String(arn),
+		DomainName:              aws.String(domain),
+		SubjectAlternativeNames: []string{domain},
+		Status:                  status,
+		DomainValidationOptions: []acmtypes.DomainValidation{validationFor(domain)},
+	}
+	if managedBy != "" {
+		f.tags[arn] = []acmtypes.Tag{{Key: aws.String(ManagedByTagKey), Value: aws.String(managedBy)}}
+	}
+	return arn
+}
+
+func (f *fakeACM) newArnLocked() string {
+	f.next++
+	return fmt.Sprintf("arn:aws:acm:us-east-1:123456789012:certificate/%04d", f.next)
+}
+
+func validationFor(domain string) acmtypes.DomainValidation {
+	return acmtypes.DomainValidation{
+		DomainName: aws.String(domain),
+		ResourceRecord: &acmtypes.ResourceRecord{
+			Name:  aws.String("_acme." + strings.TrimPrefix(domain, "*.") + "."),
+			Type:  acmtypes.RecordTypeCname,
+			Value: aws.String("_validate.acm-validations.aws."),
+		},
+	}
+}
+
+func (f *fakeACM) RequestCertificate(_ context.Context, in *acm.RequestCertificateInput, _ ...func(*acm.Options)) (*acm.RequestCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, in)
+	arn := f.newArnLocked()
+	detail := &acmtypes.CertificateDetail{
+		CertificateArn:          aws.String(arn),
+		DomainName:              in.DomainName,
+		SubjectAlternativeNames: append([]string{aws.ToString(in.DomainName)}, in.SubjectAlternativeNames...),
+		Status:                  acmtypes.CertificateStatusPendingValidation,
+		KeyAlgorithm:            in.KeyAlgorithm,
+		DomainValidationOptions: []acmtypes.DomainValidation{validationFor(aws.ToString(in.DomainName))},
+	}
+	for _, san := range in.SubjectAlternativeNames {
+		detail.DomainValidationOptions = append(detail.DomainValidationOptions, validationFor(san))
+	}
+	f.certs[arn] = detail
+	f.requested[arn] = true
+	f.tags[arn] = append([]acmtypes.Tag(nil), in.Tags...)
+	return &acm.RequestCertificateOutput{CertificateArn: aws.String(arn)}, nil
+}
+
+func (f *fakeACM) DescribeCertificate(_ context.Context, in *acm.DescribeCertificateInput, _ ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	detail, ok := f.certs[aws.ToString(in.CertificateArn)]
+	if !ok {
+		return nil, &acmtypes.ResourceNotFoundException{Message: aws.String("certificate not found")}
+	}
+	f.describes++
+	if f.requested[aws.ToString(in.CertificateArn)] && f.unpublished > 0 {
+		f.unpublished--
+		copied := *detail
+		copied.Status = acmtypes.CertificateStatusPendingValidation
+		copied.DomainValidationOptions = []acmtypes.DomainValidation{{DomainName: detail.DomainName}}
+		return &acm.DescribeCertificateOutput{Certificate: &copied}, nil
+	}
+	if f.requested[aws.ToString(in.CertificateArn)] {
+		detail.Status = f.requestedStatus
+		if detail.Status == acmtypes.CertificateStatusFailed {
+			detail.FailureReason = acmtypes.FailureReasonCaaError
+		}
+		for i, option := range detail.DomainValidationOptions {
+			if status, ok := f.domainStatus[aws.ToString(option.DomainName)]; ok {
+				detail.DomainValidationOptions[i].ValidationStatus = status
+			}
+		}
+	}
+	copied := *detail
+	return &acm.DescribeCertificateOutput{Certificate: &copied}, nil
+}
+
+func (f *fakeACM) ListCertificates(_ context.Context, in *acm.ListCertificatesInput, _ ...func(*acm.Options)) (*acm.ListCertificatesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := &acm.ListCertificatesOutput{}
+	for arn, detail := range f.certs {
+		if len(in.CertificateStatuses) > 0 && !slices.Contains(in.CertificateStatuses, detail.Status) {
+			continue
+		}
+		// Like ACM, only RSA_2048 certificates are listed without a key type filter
+		alg := detail.KeyAlgorithm
+		if alg == "" {
+			alg = acmtypes.KeyAlgorithmRsa2048
+		}
+		if in.Includes == nil || len(in.Includes.KeyTypes) == 0 {
+			if alg != acmtypes.KeyAlgorithmRsa2048 {
+				continue
+			}
+		} else if !slices.Contains(in.Includes.KeyTypes, alg) {
+			continue
+		}
+		out.CertificateSummaryList = append(out.CertificateSummaryList, acmtypes.CertificateSummary{
+			CertificateArn: aws.String(arn),
+			DomainName:     detail.DomainName,
+			Status:         detail.Status,
+		})
+	}
+	return out, nil
+}
+
+func (f *fakeACM) ListTagsForCertificate(_ context.Context, in *acm.ListTagsForCertificateInput, _ ...func(*acm.Options)) (*acm.ListTagsForCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &acm.ListTagsForCertificateOutput{Tags: f.tags[aws.ToString(in.CertificateArn)]}, nil
+}
+
+func (f *fakeACM) DeleteCertificate(_ context.Context, in *acm.DeleteCertificateInput, _ ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.certs, aws.ToString(in.CertificateArn))
+	return &acm.DeleteCertificateOutput{}, nil
+}
+
+func (f *fakeACM) AddTagsToCertificate(_ context.Context, in *acm.AddTagsToCertificateInput, _ ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	arn := aws.ToString(in.CertificateArn)
+	f.tags[arn] = append(f.tags[arn], in.Tags...)
+	return &acm.AddTagsToCertificateOutput{}, nil
+}
+
+func (f *fakeACM) ImportCertificate(_ context.Context, _ *acm.ImportCertificateInput, _ ...func(*acm.Options)) (*acm.ImportCertificateOutput, error) {
+	return nil, fmt.Errorf("ImportCertificate is not supported by the fake")
+}
+
+// fakeDNS records every record passed to EnsureRecords
+type fakeDNS struct {
+	mu      sync.Mutex
+	ensured []ValidationRecord
+}
+
+func (f *fakeDNS) FindZone(_ context.Context, _ string) (string, error) {
+	return "Z1", nil
+}
+
+func (f *fakeDNS) EnsureRecords(_ context.Context, _ string, records []ValidationRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ensured = append(f.ensured, records...)
+	return nil
+}
+
+func (f *fakeDNS) DeleteRecords(_ context.Context, _ string, _ []ValidationRecord) error {
+	return nil
+}
+
+// fakeRoute53 is an in-memory Route53API recording every change batch
+type fakeRoute53 struct {
+	mu        sync.Mutex
+	zones     []route53types.HostedZone
+	zoneTags  map[string][]route53types.Tag
+	tagErrs   map[string]error
+	tagCalls  int
+	listCalls int
+	changes   []*route53.ChangeResourceRecordSetsInput
+	err       error
+}
+
+func newFakeRoute53(zoneNames ...string) *fakeRoute53 {
+	f := &fakeRoute53{}
+	for i, name := range zoneNames {
+		f.zones = append(f.zones, route53types.HostedZone{
+			Id:   aws.String(fmt.Sprintf("/hostedzone/Z%d", i+1)),
+			Name: aws.String(name + "."),
+		})
+	}
+	return f
+}
+
+func (f *fakeRoute53) ChangeResourceRecordSets(_ context.Context, in *route53.ChangeResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.changes = append(f.changes, in)
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+func (f *fakeRoute53) ListHostedZones(_ context.Context, _ *route53.ListHostedZonesInput, _ ...func(*route53.Options)) (*route53.ListHostedZonesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listCalls++
+	return &route53.ListHostedZonesOutput{HostedZones: f.zones}, nil
+}
+
+func (f *fakeRoute53) ListTagsForResource(_ context.Context, in *route53.ListTagsForResourceInput, _ ...func(*route53.Options)) (*route53.ListTagsForResourceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tagCalls++
+	if err := f.tagErrs[aws.ToString(in.ResourceId)]; err != nil {
+		return nil, err
+	}
+	return &route53.ListTagsForResourceOutput{ResourceTagSet: &route53types.ResourceTagSet{
+		ResourceId: in.ResourceId,
+		Tags:       f.zoneTags[aws.ToString(in.ResourceId)],
+	}}, nil
+}
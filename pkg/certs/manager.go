@@ -0,0 +1,817 @@
+package certs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Defaults for how long and how often Ensure polls a requested certificate until it is issued
+const (
+	DefaultValidationTimeout = 10 * time.Minute
+	DefaultPollInterval      = 15 * time.Second
+)
+
+// DefaultReuseStatuses are the statuses of existing certificates Ensure reuses by default
+var DefaultReuseStatuses = []acmtypes.CertificateStatus{
+	acmtypes.CertificateStatusIssued,
+	acmtypes.CertificateStatusPendingValidation,
+}
+
+// AllKeyTypes makes ListCertificates include certificates of every key algorithm the
+// controller can request; without a key type filter ACM only lists RSA_2048 ones
+func AllKeyTypes() *acmtypes.Filters {
+	return &acmtypes.Filters{KeyTypes: acmtypes.KeyAlgorithm("").Values()}
+}
+
+// ParseCertificateStatuses parses a comma-separated list of ACM certificate statuses such as
+// "ISSUED,PENDING_VALIDATION", case-insensitively, rejecting unknown values
+func ParseCertificateStatuses(value string) ([]acmtypes.CertificateStatus, error) {
+	var statuses []acmtypes.CertificateStatus
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToUpper(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		status := acmtypes.CertificateStatus(part)
+		if !slices.Contains(status.Values(), status) {
+			return nil, fmt.Errorf("unknown certificate status %q, valid statuses are %v", part, status.Values())
+		}
+		if !slices.Contains(statuses, status) {
+			statuses = append(statuses, status)
+		}
+	}
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("no certificate statuses given")
+	}
+	return statuses, nil
+}
+
+// How many times Ensure describes a requested certificate waiting for ACM to publish its
+// validation records, backing off from recordWaitInterval up to recordWaitMaxInterval
+var (
+	recordWaitInterval    = 2 * time.Second
+	recordWaitMaxInterval = 30 * time.Second
+	recordWaitAttempts    = 10
+)
+
+// Manager ensures ACM certificates exist and are validated
+type Manager struct {
+	ACM ACMAPI
+
+	// ManagedByValue is stamped on requested certificates as the ManagedBy tag and required
+	// on existing ones before they are reused
+	ManagedByValue string
+
+	// RequiredTags are stamped on requested certificates, winning over the request's tags,
+	// and an existing certificate must carry all of them to be reused
+	RequiredTags map[string]string
+
+	// ReuseStatuses are the certificate statuses considered for reuse; empty uses
+	// DefaultReuseStatuses
+	ReuseStatuses []acmtypes.CertificateStatus
+
+	// ValidationTimeout and PollInterval bound the wait for a requested certificate to be
+	// issued; zero uses the defaults
+	ValidationTimeout time.Duration
+	PollInterval      time.Duration
+
+	// UseWaiter waits for validation with the SDK's CertificateValidatedWaiter, configured by
+	// Waiter, instead of describing the certificate every PollInterval. NoWait requests still
+	// check the certificate once.
+	UseWaiter bool
+	Waiter    WaiterOptions
+
+	// Index, when set, is consulted for reusable certificates before ReuseExisting lists the
+	// account; every candidate is confirmed with a describe and a miss falls back to the listing
+	Index CertificateIndex
+}
+
+// CertificateIndex answers which certificates carry a name without listing the account
+type CertificateIndex interface {
+	// Lookup returns the ARNs of indexed certificates carrying name as their domain or a SAN,
+	// or nil when the name is not indexed or the index is not warm yet
+	Lookup(name string) []string
+	// Forget drops a certificate a describe showed to be gone
+	Forget(certArn string)
+}
+
+// NewManager returns a Manager using the default validation timings
+func NewManager(acmClient ACMAPI, managedByValue string) *Manager {
+	return &Manager{ACM: acmClient, ManagedByValue: managedByValue}
+}
+
+// EnsureRequest describes the certificate Ensure should produce
+type EnsureRequest struct {
+	// Domain is the certificate's primary domain name, for example "*.example.com"
+	Domain                  string
+	SubjectAlternativeNames []string
+
+	// ReuseExisting reuses an issued or pending certificate for Domain owned by this Manager.
+	// When SubjectAlternativeNames is set the certificate must cover exactly those names too.
+	ReuseExisting bool
+
+	// DNS writes the validation records; ZoneID pins the zone, empty lets the provider find it
+	DNS    DNSProvider
+	ZoneID string
+
+	KeyAlgorithm     acmtypes.KeyAlgorithm
+	DisableCTLogging bool
+	// Tags are added to requested certificates; a ManagedBy entry is ignored
+	Tags map[string]string
+	// IdempotencyToken, when set, makes ACM return the same certificate for repeated requests
+	// with the same token within an hour
+	IdempotencyToken string
+
+	// OnProgress, when set, is called whenever the per-name validation status changes while
+	// Ensure waits for the certificate to be issued
+	OnProgress func(certArn string, domains []DomainStatus)
+	// OnLagging, when set, is called with the names still pending validation once some names
+	// validated and the validation records exist for longer than LagThreshold, and again
+	// whenever those names change; an empty list means none lag any more
+	OnLagging    func(certArn string, lagging []DomainStatus)
+	LagThreshold time.Duration
+	// OnValidationExpired, when set, is called for each certificate Ensure found past ACM's
+	// validation window and replaced, with whether it owned and deleted it
+	OnValidationExpired func(certArn string, status acmtypes.CertificateStatus, deleted bool)
+
+	// Resume continues the validation of a certificate requested by an earlier Ensure call
+	// instead of requesting a new one, when that certificate still matches the request
+	Resume *ValidationState
+	// OnState, when set, is called after each validation phase so the caller can persist the
+	// state and pass it back as Resume; an error aborts Ensure
+	OnState func(ValidationState) error
+	// NoWait checks the certificate once after its validation records exist and returns
+	// ErrValidationPending while it is still pending, instead of waiting for it to be issued
+	NoWait bool
+	// Wanted, when set, is called before each check while Ensure waits for the certificate to
+	// be issued; once it reports false Ensure stops waiting and returns ErrAbandoned
+	Wanted func(ctx context.Context) bool
+}
+
+// Validation phases of a requested certificate
+const (
+	PhaseRequested      = "Requested"
+	PhaseRecordsCreated = "RecordsCreated"
+)
+
+// ValidationState is the in-flight state of a requested certificate
+type ValidationState struct {
+	CertificateArn string             `json:"certificateArn"`
+	Phase          string             `json:"phase"`
+	ZoneID         string             `json:"zoneId,omitempty"`
+	Records        []ValidationRecord `json:"records,omitempty"`
+	// Deadline is when the wait for the certificate to be issued gives up
+	Deadline time.Time `json:"deadline"`
+	// RecordsCreatedAt is when the validation records were written
+	RecordsCreatedAt time.Time `json:"recordsCreatedAt,omitempty"`
+	// Domains is the last observed validation status of each name
+	Domains map[string]acmtypes.DomainStatus `json:"domains,omitempty"`
+	// Lagging lists the names last reported to OnLagging
+	Lagging []string `json:"lagging,omitempty"`
+}
+
+// ErrValidationTimedOut is returned when a certificate is not issued before its deadline
+var ErrValidationTimedOut = errors.New("certificate validation timed out")
+
+// ErrAbandoned is returned when the wait for a certificate stops because Wanted reported false
+var ErrAbandoned = errors.New("certificate no longer wanted")
+
+// ErrValidationPending is returned by a NoWait Ensure whose certificate is not issued yet
+var ErrValidationPending = errors.New("certificate validation pending")
+
+// DomainStatus is the validation status of one name on a certificate
+type DomainStatus struct {
+	Domain string
+	Status acmtypes.DomainStatus
+	// Record is the DNS record ACM validates the name with, nil until ACM provides it
+	Record *ValidationRecord
+}
+
+// ValidationFailedError reports a certificate that failed validation and the names that failed
+type ValidationFailedError struct {
+	CertificateArn string
+	Reason         acmtypes.FailureReason
+	FailedDomains  []string
+}
+
+func (e *ValidationFailedError) Error() string {
+	if len(e.FailedDomains) == 0 {
+		return fmt.Sprintf("certificate validation failed: %s", e.Reason)
+	}
+	return fmt.Sprintf("certificate validation failed: %s (failed domains: %s)", e.Reason, strings.Join(e.FailedDomains, ", "))
+}
+
+// EnsureResult reports the certificate Ensure settled on
+type EnsureResult struct {
+	// CertificateArn is set as soon as a certificate was found or requested, even on error
+	CertificateArn string
+	Status         acmtypes.CertificateStatus
+	// Reused is true when an existing certificate was returned instead of a new request
+	Reused bool
+	// Domains is the last observed validation status of each name
+	Domains []DomainStatus
+}
+
+func (m *Manager) managedByValue() string {
+	if m.ManagedByValue == "" {
+		return DefaultManagedByValue
+	}
+	return m.ManagedByValue
+}
+
+// Ensure returns a certificate matching req, requesting one, writing its validation records
+// and waiting for it to be issued when no reusable certificate exists
+func (m *Manager) Ensure(ctx context.Context, req EnsureRequest) (EnsureResult, error) {
+	domain, sans, err := PrimaryDomain(req.Domain, req.SubjectAlternativeNames)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+	if domain != req.Domain {
+		log.FromContext(ctx).Info("Domain is too long to be the certificate's primary name, using a SAN instead",
+			"domain", req.Domain, "primary", domain, "limit", MaxDomainNameLength)
+		req.Domain, req.SubjectAlternativeNames = domain, sans
+	}
+
+	if req.Resume != nil && req.Resume.CertificateArn != "" {
+		result, ok, err := m.resume(ctx, req)
+		if err != nil || ok {
+			return result, err
+		}
+	}
+
+	if req.ReuseExisting {
+		result, ok, err := m.reuse(ctx, req)
+		if err != nil || ok {
+			return result, err
+		}
+	}
+
+	input := &acm.RequestCertificateInput{
+		DomainName:       aws.String(req.Domain),
+		ValidationMethod: acmtypes.ValidationMethodDns,
+		Tags: []acmtypes.Tag{
+			{Key: aws.String(ManagedByTagKey), Value: aws.String(m.managedByValue())},
+		},
+	}
+
+	if req.ZoneID == "" {
+		_, err := req.DNS.FindZone(ctx, strings.TrimPrefix(req.Domain, "*."))
+		if err != nil {
+			return EnsureResult{}, fmt.Errorf("failed to find matching DNS zone for domain %s: %w", req.Domain, err)
+		}
+	}
+
+	if len(req.SubjectAlternativeNames) > 0 {
+		input.SubjectAlternativeNames = req.SubjectAlternativeNames
+	}
+
+	if req.KeyAlgorithm != "" {
+		input.KeyAlgorithm = req.KeyAlgorithm
+	}
+
+	if req.IdempotencyToken != "" {
+		input.IdempotencyToken = aws.String(req.IdempotencyToken)
+	}
+
+	if req.DisableCTLogging {
+		input.Options = &acmtypes.CertificateOptions{
+			CertificateTransparencyLoggingPreference: acmtypes.CertificateTransparencyLoggingPreferenceDisabled,
+		}
+	}
+
+	for key, value := range req.Tags {
+		if _, required := m.RequiredTags[key]; key == ManagedByTagKey || required {
+			continue
+		}
+		input.Tags = append(input.Tags, acmtypes.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	for key, value := range m.RequiredTags {
+		if key != ManagedByTagKey {
+			input.Tags = append(input.Tags, acmtypes.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+	}
+
+	resp, err := m.ACM.RequestCertificate(ctx, input)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+
+	result := EnsureResult{
+		CertificateArn: aws.ToString(resp.CertificateArn),
+		Status:         acmtypes.CertificateStatusPendingValidation,
+	}
+
+	state := ValidationState{
+		CertificateArn: result.CertificateArn,
+		Phase:          PhaseRequested,
+		Deadline:       time.Now().Add(m.validationTimeout()).UTC().Truncate(time.Second),
+	}
+	if err := m.saveState(req, state); err != nil {
+		return result, err
+	}
+	return m.validate(ctx, req, state, result)
+}
+
+// validate creates the validation records unless state says they exist, then waits for the
+// certificate to be issued until the state's deadline, or checks it once for NoWait requests
+func (m *Manager) validate(ctx context.Context, req EnsureRequest, state ValidationState, result EnsureResult) (EnsureResult, error) {
+	if state.Phase != PhaseRecordsCreated {
+		records, err := m.waitForRecords(ctx, result.CertificateArn, append([]string{req.Domain}, req.SubjectAlternativeNames...))
+		if err != nil {
+			return result, err
+		}
+
+		if len(records) > 0 {
+			err = req.DNS.EnsureRecords(ctx, req.ZoneID, records)
+		}
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to create DNS validation records")
+			return result, err
+		}
+
+		state.Phase = PhaseRecordsCreated
+		state.ZoneID = req.ZoneID
+		state.Records = records
+		state.RecordsCreatedAt = time.Now().UTC().Truncate(time.Second)
+		if err := m.saveState(req, state); err != nil {
+			return result, err
+		}
+	}
+
+	progress := &validationProgress{
+		certArn:      result.CertificateArn,
+		onProgress:   req.OnProgress,
+		onLagging:    req.OnLagging,
+		lagThreshold: req.LagThreshold,
+		wanted:       req.Wanted,
+		state:        &state,
+		save:         func(state ValidationState) error { return m.saveState(req, state) },
+	}
+	var status acmtypes.CertificateStatus
+	var domains []DomainStatus
+	var err error
+	if req.NoWait {
+		var done bool
+		status, domains, done, err = m.checkIssued(ctx, result.CertificateArn)
+		if len(domains) > 0 {
+			progress.observe(ctx, domains)
+		}
+		if err == nil && !done {
+			pending := ErrValidationPending
+			if time.Now().After(state.Deadline) {
+				pending = ErrValidationTimedOut
+			}
+			err = fmt.Errorf("%w: %s (%s)", pending, result.CertificateArn, FormatDomainStatuses(domains))
+		}
+	} else if m.UseWaiter {
+		status, domains, err = m.waitForValidated(ctx, result.CertificateArn, state.Deadline, progress)
+	} else {
+		status, domains, err = m.waitForIssued(ctx, result.CertificateArn, state.Deadline, progress)
+	}
+	result.Status = status
+	result.Domains = domains
+	return result, err
+}
+
+// resume continues req.Resume, reporting false when its certificate is gone, no longer
+// pending or no longer matches the request, so a new certificate must be requested
+func (m *Manager) resume(ctx context.Context, req EnsureRequest) (EnsureResult, bool, error) {
+	logger := log.FromContext(ctx)
+	state := *req.Resume
+
+	describe, err := m.ACM.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+		CertificateArn: aws.String(state.CertificateArn),
+	})
+	var notFound *acmtypes.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		logger.Info("Certificate of the saved validation state is gone, requesting a new one", "arn", state.CertificateArn)
+		return EnsureResult{}, false, nil
+	}
+	if err != nil {
+		return EnsureResult{CertificateArn: state.CertificateArn}, false, err
+	}
+
+	cert := describe.Certificate
+	if !strings.EqualFold(aws.ToString(cert.DomainName), req.Domain) || !SameNames(cert.SubjectAlternativeNames, req.Domain, req.SubjectAlternativeNames) {
+		logger.Info("Saved validation state is for other names, requesting a new certificate", "arn", state.CertificateArn)
+		return EnsureResult{}, false, nil
+	}
+
+	if validationExpired(cert) {
+		if err := m.replaceExpired(ctx, req, cert); err != nil {
+			return EnsureResult{CertificateArn: state.CertificateArn}, false, err
+		}
+		return EnsureResult{}, false, nil
+	}
+
+	result := EnsureResult{CertificateArn: state.CertificateArn, Status: cert.Status, Domains: domainStatuses(cert)}
+	switch cert.Status {
+	case acmtypes.CertificateStatusIssued:
+		return result, true, nil
+	case acmtypes.CertificateStatusPendingValidation:
+		logger.Info("Resuming certificate validation", "arn", state.CertificateArn, "phase", state.Phase, "deadline", state.Deadline)
+		result, err := m.validate(ctx, req, state, result)
+		return result, true, err
+	default:
+		logger.Info("Certificate of the saved validation state is not pending, requesting a new one", "arn", state.CertificateArn, "status", cert.Status)
+		return EnsureResult{}, false, nil
+	}
+}
+
+func (m *Manager) saveState(req EnsureRequest, state ValidationState) error {
+	if req.OnState == nil {
+		return nil
+	}
+	if err := req.OnState(state); err != nil {
+		return fmt.Errorf("failed to save validation state of %s: %w", state.CertificateArn, err)
+	}
+	return nil
+}
+
+func (m *Manager) validationTimeout() time.Duration {
+	if m.ValidationTimeout <= 0 {
+		return DefaultValidationTimeout
+	}
+	return m.ValidationTimeout
+}
+
+// reuse returns an owned certificate for the request, reporting false when none can be reused as is
+func (m *Manager) reuse(ctx context.Context, req EnsureRequest) (EnsureResult, bool, error) {
+	domain := req.Domain
+	statuses := m.ReuseStatuses
+	if len(statuses) == 0 {
+		statuses = DefaultReuseStatuses
+	}
+	if m.Index != nil {
+		if result, reused, stop, err := m.reuseIndexed(ctx, req, statuses); err != nil || reused || stop {
+			return result, reused, err
+		}
+	}
+	out, err := m.ACM.ListCertificates(ctx, &acm.ListCertificatesInput{
+		CertificateStatuses: statuses,
+		Includes:            AllKeyTypes(),
+	})
+	if err != nil {
+		return EnsureResult{}, false, err
+	}
+
+	var candidates []*acmtypes.CertificateDetail
+	for _, cert := range out.CertificateSummaryList {
+		if !strings.EqualFold(aws.ToString(cert.DomainName), domain) {
+			continue
+		}
+		certArn := aws.ToString(cert.CertificateArn)
+		owned, err := ManagedByWithTags(ctx, m.ACM, certArn, m.managedByValue(), m.RequiredTags)
+		if err != nil {
+			return EnsureResult{}, false, err
+		}
+		if !owned {
+			continue
+		}
+		// Confirm ResourceRecord exists before proceeding
+		describe, err := m.ACM.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+			CertificateArn: aws.String(certArn),
+		})
+		if err != nil {
+			return EnsureResult{CertificateArn: certArn}, false, err
+		}
+		candidates = append(candidates, describe.Certificate)
+	}
+	result, reused, _, err := m.reuseBest(ctx, req, candidates)
+	return result, reused, err
+}
+
+// reuseIndexed looks for a reusable certificate among Index's candidates for the request's
+// domain, confirming each with a describe and forgetting those that are gone
+func (m *Manager) reuseIndexed(ctx context.Context, req EnsureRequest, statuses []acmtypes.CertificateStatus) (result EnsureResult, reused, stop bool, err error) {
+	var candidates []*acmtypes.CertificateDetail
+	for _, certArn := range m.Index.Lookup(req.Domain) {
+		describe, err := m.ACM.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+			CertificateArn: aws.String(certArn),
+		})
+		var notFound *acmtypes.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			m.Index.Forget(certArn)
+			continue
+		}
+		if err != nil {
+			return EnsureResult{CertificateArn: certArn}, false, false, err
+		}
+		cert := describe.Certificate
+		if !strings.EqualFold(aws.ToString(cert.DomainName), req.Domain) || !slices.Contains(statuses, cert.Status) {
+			continue
+		}
+		owned, err := ManagedByWithTags(ctx, m.ACM, certArn, m.managedByValue(), m.RequiredTags)
+		if err != nil {
+			return EnsureResult{}, false, false, err
+		}
+		if !owned {
+			continue
+		}
+		candidates = append(candidates, cert)
+	}
+	return m.reuseBest(ctx, req, candidates)
+}
+
+// reuseBest tries the owned certificates for the request's domain, newest first, so that
+// after manual experiments the certificate with the most lifetime left is reused rather
+// than whichever ACM listed first
+func (m *Manager) reuseBest(ctx context.Context, req EnsureRequest, candidates []*acmtypes.CertificateDetail) (result EnsureResult, reused, stop bool, err error) {
+	preferNewest(candidates)
+	for _, cert := range candidates {
+		if result, reused, stop, err := m.reuseDescribed(ctx, req, cert); err != nil || reused || stop {
+			return result, reused, stop, err
+		}
+	}
+	return EnsureResult{}, false, false, nil
+}
+
+// preferNewest orders certificates by the latest NotAfter, then the latest NotBefore.
+// Certificates not issued yet have neither and come last, the most recently created first.
+func preferNewest(certs []*acmtypes.CertificateDetail) {
+	slices.SortStableFunc(certs, func(a, b *acmtypes.CertificateDetail) int {
+		if c := laterFirst(a.NotAfter, b.NotAfter); c != 0 {
+			return c
+		}
+		if c := laterFirst(a.NotBefore, b.NotBefore); c != 0 {
+			return c
+		}
+		return laterFirst(a.CreatedAt, b.CreatedAt)
+	})
+}
+
+// laterFirst compares a and b so that the later time sorts first and an unset one last
+func laterFirst(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return b.Compare(*a)
+}
+
+// reuseDescribed decides on an owned certificate for the request's domain: reused reports it
+// can be reused as is, and stop that the search ends so a certificate is requested instead
+func (m *Manager) reuseDescribed(ctx context.Context, req EnsureRequest, cert *acmtypes.CertificateDetail) (result EnsureResult, reused, stop bool, err error) {
+	certArn := aws.ToString(cert.CertificateArn)
+	if len(req.SubjectAlternativeNames) > 0 && !SameNames(cert.SubjectAlternativeNames, req.Domain, req.SubjectAlternativeNames) {
+		return EnsureResult{}, false, false, nil
+	}
+	// Timed-out certificates are only listed when ReuseStatuses asks to pick them back up
+	if cert.Status == acmtypes.CertificateStatusPendingValidation && validationExpired(cert) {
+		if err := m.replaceExpired(ctx, req, cert); err != nil {
+			return EnsureResult{CertificateArn: certArn}, false, false, err
+		}
+		return EnsureResult{}, false, false, nil
+	}
+	log.FromContext(ctx).Info("Reusing existing ACM certificate", "domain", req.Domain, "arn", certArn)
+
+	options := cert.DomainValidationOptions
+	if len(options) > 0 && options[0].ResourceRecord != nil {
+		// ResourceRecord already exists; skip DNS setup and validation wait
+		return EnsureResult{CertificateArn: certArn, Status: cert.Status, Reused: true}, true, false, nil
+	}
+	return EnsureResult{}, false, true, nil // proceed to request a certificate with DNS record creation
+}
+
+// MaxDomainNameLength is the longest primary domain ACM accepts; longer names can only be SANs
+const MaxDomainNameLength = 64
+
+// DomainTooLongError reports a certificate whose names are all too long to be its primary domain
+type DomainTooLongError struct {
+	Names []string
+}
+
+func (e *DomainTooLongError) Error() string {
+	return fmt.Sprintf("ACM requires a certificate's primary domain to be at most %d characters, and every name is longer: %s",
+		MaxDomainNameLength, strings.Join(e.Names, ", "))
+}
+
+// PrimaryDomain returns domain and sans unchanged when domain fits ACM's limit on the primary
+// domain. Otherwise the first SAN that fits becomes the primary domain and domain takes its
+// place among the SANs, so the certificate still covers the same names.
+func PrimaryDomain(domain string, sans []string) (string, []string, error) {
+	if len(domain) <= MaxDomainNameLength {
+		return domain, sans, nil
+	}
+	for i, san := range sans {
+		if len(san) > MaxDomainNameLength {
+			continue
+		}
+		demoted := append([]string{domain}, sans[:i]...)
+		return san, append(demoted, sans[i+1:]...), nil
+	}
+	return "", nil, &DomainTooLongError{Names: append([]string{domain}, sans...)}
+}
+
+// SameNames reports whether names holds exactly domain and sans, ignoring order and case
+func SameNames(names []string, domain string, sans []string) bool {
+	want := map[string]bool{strings.ToLower(domain): true}
+	for _, san := range sans {
+		want[strings.ToLower(san)] = true
+	}
+	got := map[string]bool{}
+	for _, name := range names {
+		got[strings.ToLower(name)] = true
+	}
+	if len(got) != len(want) {
+		return false
+	}
+	for name := range want {
+		if !got[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// waitForRecords describes the certificate until ACM published a validation record for every
+// name, backing off between attempts: right after a request the describe can return options
+// without a ResourceRecord, or none at all. A certificate that left PENDING_VALIDATION needs
+// no records, so whatever it has is returned.
+func (m *Manager) waitForRecords(ctx context.Context, certArn string, names []string) ([]ValidationRecord, error) {
+	interval := recordWaitInterval
+	var missing []string
+	for i := 0; i < recordWaitAttempts; i++ {
+		describe, err := m.ACM.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+			CertificateArn: aws.String(certArn),
+		})
+		if err != nil {
+			return nil, err
+		}
+		var records []ValidationRecord
+		records, missing = validationRecordsOf(describe.Certificate, names)
+		if len(missing) == 0 || describe.Certificate.Status != acmtypes.CertificateStatusPendingValidation {
+			return records, nil
+		}
+		if i == recordWaitAttempts-1 {
+			break
+		}
+
+		log.FromContext(ctx).Info("Waiting for ACM to publish validation records", "certArn", certArn, "missing", missing, "attempt", i+1)
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		interval = min(2*interval, recordWaitMaxInterval)
+	}
+	return nil, fmt.Errorf("resource record not available yet for domain: %s", strings.Join(missing, ", "))
+}
+
+// WaitForIssued polls the certificate until it is issued, fails or the validation timeout passes,
+// logging and reporting to onProgress (which may be nil) each change in per-name status
+func (m *Manager) WaitForIssued(ctx context.Context, certArn string, onProgress func(string, []DomainStatus)) (acmtypes.CertificateStatus, []DomainStatus, error) {
+	return m.waitForIssued(ctx, certArn, time.Now().Add(m.validationTimeout()), &validationProgress{certArn: certArn, onProgress: onProgress})
+}
+
+func (m *Manager) waitForIssued(ctx context.Context, certArn string, deadline time.Time, progress *validationProgress) (acmtypes.CertificateStatus, []DomainStatus, error) {
+	logger := log.FromContext(ctx)
+
+	interval := m.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	status := acmtypes.CertificateStatusPendingValidation
+	var domains []DomainStatus
+	attempts := 0
+
+	for {
+		if time.Now().After(deadline) {
+			return status, domains, fmt.Errorf("%w: %s (%s)", ErrValidationTimedOut, certArn, FormatDomainStatuses(domains))
+		}
+
+		if err := progress.abandoned(ctx); err != nil {
+			return status, domains, err
+		}
+
+		var done bool
+		var err error
+		status, domains, done, err = m.checkIssued(ctx, certArn)
+		if domains == nil {
+			// DescribeCertificate failed
+			return status, domains, err
+		}
+
+		progress.observe(ctx, domains)
+		if err != nil || done {
+			return status, domains, err
+		}
+
+		attempts++
+		if attempts%4 == 0 {
+			logger.Info("Waiting for ACM certificate validation", "attempt", attempts, "certArn", certArn)
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, domains, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkIssued describes the certificate once, reporting done once it is issued or failed
+func (m *Manager) checkIssued(ctx context.Context, certArn string) (acmtypes.CertificateStatus, []DomainStatus, bool, error) {
+	status := acmtypes.CertificateStatusPendingValidation
+	describe, err := m.ACM.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+		CertificateArn: aws.String(certArn),
+	})
+	if err != nil {
+		return status, nil, false, err
+	}
+
+	status = describe.Certificate.Status
+	domains := domainStatuses(describe.Certificate)
+
+	switch status {
+	case acmtypes.CertificateStatusIssued:
+		return status, domains, true, nil
+	case acmtypes.CertificateStatusFailed:
+		verr := &ValidationFailedError{CertificateArn: certArn, Reason: describe.Certificate.FailureReason}
+		for _, domain := range domains {
+			if domain.Status == acmtypes.DomainStatusFailed {
+				verr.FailedDomains = append(verr.FailedDomains, domain.Domain)
+			}
+		}
+		return status, domains, true, verr
+	}
+	return status, domains, false, nil
+}
+
+// domainStatuses returns the validation status of each name on the certificate
+func domainStatuses(cert *acmtypes.CertificateDetail) []DomainStatus {
+	domains := make([]DomainStatus, 0, len(cert.DomainValidationOptions))
+	for _, option := range cert.DomainValidationOptions {
+		domain := DomainStatus{Domain: aws.ToString(option.DomainName), Status: option.ValidationStatus}
+		if record := option.ResourceRecord; record != nil {
+			domain.Record = &ValidationRecord{Domain: domain.Domain, Name: aws.ToString(record.Name), Type: string(record.Type), Value: aws.ToString(record.Value)}
+		}
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
+// FormatDomainStatuses renders statuses as "name=STATUS, name=STATUS"
+func FormatDomainStatuses(domains []DomainStatus) string {
+	parts := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		parts = append(parts, fmt.Sprintf("%s=%s", domain.Domain, domain.Status))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ValidationWindow is how long ACM waits for a requested certificate to be validated
+// before giving up on it
+const ValidationWindow = 72 * time.Hour
+
+// validationExpired reports whether ACM gave up validating cert, or will never validate it
+// because it is still pending past the validation window
+func validationExpired(cert *acmtypes.CertificateDetail) bool {
+	switch cert.Status {
+	case acmtypes.CertificateStatusValidationTimedOut:
+		return true
+	case acmtypes.CertificateStatusPendingValidation:
+		return cert.CreatedAt != nil && time.Since(*cert.CreatedAt) > ValidationWindow
+	}
+	return false
+}
+
+// replaceExpired deletes cert, a request past the validation window, when it is owned, so
+// Ensure can request a fresh certificate in its place
+func (m *Manager) replaceExpired(ctx context.Context, req EnsureRequest, cert *acmtypes.CertificateDetail) error {
+	logger := log.FromContext(ctx)
+	certArn := aws.ToString(cert.CertificateArn)
+	owned, err := ManagedByWithTags(ctx, m.ACM, certArn, m.managedByValue(), m.RequiredTags)
+	if err != nil {
+		return err
+	}
+	if owned {
+		_, err := m.ACM.DeleteCertificate(ctx, &acm.DeleteCertificateInput{CertificateArn: aws.String(certArn)})
+		var notFound *acmtypes.ResourceNotFoundException
+		if err != nil && !errors.As(err, &notFound) {
+			return fmt.Errorf("failed to delete certificate %s past the validation window: %w", certArn, err)
+		}
+	}
+	logger.Info("Certificate is past the validation window, requesting a new one",
+		"arn", certArn, "status", cert.Status, "createdAt", cert.CreatedAt, "deleted", owned)
+	if req.OnValidationExpired != nil {
+		req.OnValidationExpired(certArn, cert.Status, owned)
+	}
+	return nil
+}
@@ -0,0 +1,565 @@
+package certs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+func TestEnsureRequestsAndValidatesCertificate(t *testing.T) {
+	fakeACM := newFakeACM()
+	dns := &fakeDNS{}
+	m := NewManager(fakeACM, "team-a")
+
+	result, err := m.Ensure(context.Background(), EnsureRequest{
+		Domain:                  "*.example.com",
+		SubjectAlternativeNames: []string{"example.com"},
+		DNS:                     dns,
+		KeyAlgorithm:            acmtypes.KeyAlgorithmEcPrime256v1,
+		Tags:                    map[string]string{"team": "a", ManagedByTagKey: "spoofed"},
+	})
+	if err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if result.CertificateArn == "" || result.Status != acmtypes.CertificateStatusIssued || result.Reused {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(fakeACM.requests))
+	}
+	req := fakeACM.requests[0]
+	if aws.ToString(req.DomainName) != "*.example.com" || req.KeyAlgorithm != acmtypes.KeyAlgorithmEcPrime256v1 {
+		t.Fatalf("unexpected request %+v", req)
+	}
+	tags := map[string]string{}
+	for _, tag := range req.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	if tags[ManagedByTagKey] != "team-a" || tags["team"] != "a" || len(tags) != 2 {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+
+	// *.example.com and example.com share one validation record
+	if len(dns.ensured) != 1 || dns.ensured[0].Name != "_acme.example.com." {
+		t.Fatalf("expected one deduplicated validation record, got %+v", dns.ensured)
+	}
+}
+
+func TestEnsureReusesOnlyOwnedCertificates(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, "team-b")
+	owned := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, "team-a")
+	m := NewManager(fakeACM, "team-a")
+
+	result, err := m.Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", ReuseExisting: true, DNS: &fakeDNS{}})
+	if err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if result.CertificateArn != owned || !result.Reused || len(fakeACM.requests) != 0 {
+		t.Fatalf("expected to reuse %s without requesting, got %+v and %d requests", owned, result, len(fakeACM.requests))
+	}
+}
+
+func TestEnsureReusesECCertificates(t *testing.T) {
+	fakeACM := newFakeACM()
+	owned := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, "team-a")
+	fakeACM.certs[owned].KeyAlgorithm = acmtypes.KeyAlgorithmEcPrime256v1
+	m := NewManager(fakeACM, "team-a")
+
+	result, err := m.Ensure(context.Background(), EnsureRequest{
+		Domain:        "app.example.com",
+		ReuseExisting: true,
+		KeyAlgorithm:  acmtypes.KeyAlgorithmEcPrime256v1,
+		DNS:           &fakeDNS{},
+	})
+	if err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if result.CertificateArn != owned || len(fakeACM.requests) != 0 {
+		t.Fatalf("expected to reuse the EC certificate %s, got %+v and %d requests", owned, result, len(fakeACM.requests))
+	}
+}
+
+func TestEnsureReusesTheNewestCertificate(t *testing.T) {
+	fakeACM := newFakeACM()
+	older := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, "team-a")
+	newer := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, "team-a")
+	pending := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusPendingValidation, "team-a")
+	now := time.Now()
+	fakeACM.certs[older].NotBefore, fakeACM.certs[older].NotAfter = aws.Time(now.AddDate(0, -11, 0)), aws.Time(now.AddDate(0, 1, 0))
+	fakeACM.certs[newer].NotBefore, fakeACM.certs[newer].NotAfter = aws.Time(now.AddDate(0, -1, 0)), aws.Time(now.AddDate(0, 11, 0))
+	fakeACM.certs[pending].CreatedAt = aws.Time(now)
+	m := NewManager(fakeACM, "team-a")
+	m.ReuseStatuses = []acmtypes.CertificateStatus{acmtypes.CertificateStatusIssued, acmtypes.CertificateStatusPendingValidation}
+
+	// The listing is unordered, so a few attempts would catch a lucky first pick
+	for range 5 {
+		result, err := m.Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", ReuseExisting: true, DNS: &fakeDNS{}})
+		if err != nil {
+			t.Fatalf("Ensure: %v", err)
+		}
+		if result.CertificateArn != newer {
+			t.Fatalf("expected the newest certificate %s, got %s", newer, result.CertificateArn)
+		}
+	}
+
+	// The index is tried in the same order
+	m.Index = mapIndex{"app.example.com": {pending, older, newer}}
+	result, err := m.Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", ReuseExisting: true, DNS: &fakeDNS{}})
+	if err != nil || result.CertificateArn != newer {
+		t.Fatalf("expected the index to reuse %s, got %+v, %v", newer, result, err)
+	}
+}
+
+// mapIndex is a CertificateIndex over a fixed map of names to ARNs
+type mapIndex map[string][]string
+
+func (x mapIndex) Lookup(name string) []string { return x[name] }
+
+func (x mapIndex) Forget(certArn string) {
+	for name, arns := range x {
+		x[name] = slices.DeleteFunc(arns, func(arn string) bool { return arn == certArn })
+	}
+}
+
+func TestEnsureConfirmsIndexedCertificates(t *testing.T) {
+	fakeACM := newFakeACM()
+	foreign := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, "team-b")
+	owned := fakeACM.addCert("app.example.com", acmtypes.CertificateStatusIssued, "team-a")
+	index := mapIndex{"app.example.com": {"arn:aws:acm:us-east-1:123456789012:certificate/gone", foreign, owned}}
+	m := NewManager(fakeACM, "team-a")
+	m.Index = index
+
+	result, err := m.Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", ReuseExisting: true, DNS: &fakeDNS{}})
+	if err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if result.CertificateArn != owned || !result.Reused {
+		t.Fatalf("expected to reuse the owned indexed certificate, got %+v", result)
+	}
+	if !slices.Equal(index["app.example.com"], []string{foreign, owned}) {
+		t.Fatalf("expected the missing certificate to be forgotten, index holds %v", index["app.example.com"])
+	}
+
+	// A miss falls back to listing the account
+	delete(index, "app.example.com")
+	if result, err := m.Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", ReuseExisting: true, DNS: &fakeDNS{}}); err != nil || result.CertificateArn != owned {
+		t.Fatalf("expected the listing to find %s, got %+v, %v", owned, result, err)
+	}
+}
+
+// fastRecordWait shortens the wait for validation records for the test
+func fastRecordWait(t *testing.T) {
+	interval, maxInterval := recordWaitInterval, recordWaitMaxInterval
+	recordWaitInterval, recordWaitMaxInterval = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { recordWaitInterval, recordWaitMaxInterval = interval, maxInterval })
+}
+
+func TestEnsureWaitsForPublishedValidationRecords(t *testing.T) {
+	fastRecordWait(t)
+	fakeACM := newFakeACM()
+	fakeACM.unpublished = 3
+	dns := &fakeDNS{}
+	m := NewManager(fakeACM, "team-a")
+
+	result, err := m.Ensure(context.Background(), EnsureRequest{
+		Domain:                  "app.example.com",
+		SubjectAlternativeNames: []string{"api.example.com"},
+		DNS:                     dns,
+	})
+	if err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if result.Status != acmtypes.CertificateStatusIssued {
+		t.Fatalf("expected the certificate to be issued, got %+v", result)
+	}
+	names := []string{}
+	for _, record := range dns.ensured {
+		names = append(names, record.Name)
+	}
+	if !slices.Equal(names, []string{"_acme.app.example.com.", "_acme.api.example.com."}) {
+		t.Fatalf("expected records for both names once ACM published them, got %v", names)
+	}
+}
+
+func TestEnsureGivesUpWaitingForValidationRecords(t *testing.T) {
+	fastRecordWait(t)
+	fakeACM := newFakeACM()
+	fakeACM.unpublished = 100
+	dns := &fakeDNS{}
+	m := NewManager(fakeACM, "team-a")
+
+	_, err := m.Ensure(context.Background(), EnsureRequest{
+		Domain:                  "app.example.com",
+		SubjectAlternativeNames: []string{"api.example.com"},
+		DNS:                     dns,
+	})
+	if err == nil || !strings.Contains(err.Error(), "app.example.com, api.example.com") {
+		t.Fatalf("expected an error naming the names without records, got %v", err)
+	}
+	if len(dns.ensured) != 0 || fakeACM.describes != recordWaitAttempts {
+		t.Fatalf("expected %d describes and no records, got %d describes and %+v", recordWaitAttempts, fakeACM.describes, dns.ensured)
+	}
+}
+
+func TestEnsureReportsValidationFailure(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.requestedStatus = acmtypes.CertificateStatusFailed
+	m := NewManager(fakeACM, "")
+
+	result, err := m.Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", DNS: &fakeDNS{}})
+	if err == nil || !strings.Contains(err.Error(), "validation failed") {
+		t.Fatalf("expected validation failure, got %v", err)
+	}
+	if result.CertificateArn == "" || result.Status != acmtypes.CertificateStatusFailed {
+		t.Fatalf("failed result should carry the ARN and status, got %+v", result)
+	}
+	if got := fakeACM.tags[result.CertificateArn][0]; aws.ToString(got.Value) != DefaultManagedByValue {
+		t.Fatalf("empty ManagedByValue should tag %q, got %q", DefaultManagedByValue, aws.ToString(got.Value))
+	}
+}
+
+func TestEnsureTimesOutWaitingForIssue(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.requestedStatus = acmtypes.CertificateStatusPendingValidation
+	m := &Manager{ACM: fakeACM, ValidationTimeout: 20 * time.Millisecond, PollInterval: time.Millisecond}
+
+	result, err := m.Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", DNS: &fakeDNS{}})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if result.Status != acmtypes.CertificateStatusPendingValidation {
+		t.Fatalf("timed out certificate should still be pending, got %s", result.Status)
+	}
+}
+
+func TestEnsureReuseRequiresMatchingNames(t *testing.T) {
+	fakeACM := newFakeACM()
+	m := NewManager(fakeACM, "")
+	req := EnsureRequest{Domain: "a.example.com", SubjectAlternativeNames: []string{"b.example.com"}, ReuseExisting: true, DNS: &fakeDNS{}}
+
+	first, err := m.Ensure(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	again, err := m.Ensure(context.Background(), req)
+	if err != nil || again.CertificateArn != first.CertificateArn || !again.Reused {
+		t.Fatalf("identical names should reuse %s, got %+v, %v", first.CertificateArn, again, err)
+	}
+
+	req.SubjectAlternativeNames = append(req.SubjectAlternativeNames, "c.example.com")
+	grown, err := m.Ensure(context.Background(), req)
+	if err != nil || grown.Reused || grown.CertificateArn == first.CertificateArn {
+		t.Fatalf("a new name should request a new certificate, got %+v, %v", grown, err)
+	}
+}
+
+func TestEnsureNamesFailedDomains(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.requestedStatus = acmtypes.CertificateStatusFailed
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{
+		"a.example.com": acmtypes.DomainStatusSuccess,
+		"b.example.com": acmtypes.DomainStatusFailed,
+	}
+	m := NewManager(fakeACM, "")
+
+	var progress []string
+	result, err := m.Ensure(context.Background(), EnsureRequest{
+		Domain:                  "a.example.com",
+		SubjectAlternativeNames: []string{"b.example.com"},
+		DNS:                     &fakeDNS{},
+		OnProgress: func(_ string, domains []DomainStatus) {
+			progress = append(progress, FormatDomainStatuses(domains))
+		},
+	})
+
+	var verr *ValidationFailedError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationFailedError, got %v", err)
+	}
+	if len(verr.FailedDomains) != 1 || verr.FailedDomains[0] != "b.example.com" {
+		t.Fatalf("only b.example.com failed, got %v", verr.FailedDomains)
+	}
+	if !strings.Contains(err.Error(), "b.example.com") || strings.Contains(err.Error(), "a.example.com") {
+		t.Fatalf("error should name exactly the failed SAN, got %q", err)
+	}
+	if len(result.Domains) != 2 {
+		t.Fatalf("result should carry both domain statuses, got %+v", result.Domains)
+	}
+	if len(progress) != 1 || progress[0] != "a.example.com=SUCCESS, b.example.com=FAILED" {
+		t.Fatalf("unexpected progress reports %v", progress)
+	}
+}
+
+func TestEnsureReportsLaggingDomains(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.requestedStatus = acmtypes.CertificateStatusPendingValidation
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{
+		"a.example.com": acmtypes.DomainStatusSuccess,
+		"b.example.com": acmtypes.DomainStatusPendingValidation,
+	}
+	// The deadline is truncated to the second, so this waits between 100ms and 1.1s
+	m := &Manager{ACM: fakeACM, ValidationTimeout: 1100 * time.Millisecond, PollInterval: 10 * time.Millisecond}
+
+	var lagging [][]DomainStatus
+	var saved ValidationState
+	_, err := m.Ensure(context.Background(), EnsureRequest{
+		Domain:                  "a.example.com",
+		SubjectAlternativeNames: []string{"b.example.com"},
+		DNS:                     &fakeDNS{},
+		LagThreshold:            time.Nanosecond,
+		OnLagging: func(_ string, domains []DomainStatus) {
+			lagging = append(lagging, domains)
+		},
+		OnState: func(state ValidationState) error {
+			saved = state
+			return nil
+		},
+	})
+	if !errors.Is(err, ErrValidationTimedOut) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if len(lagging) != 1 || len(lagging[0]) != 1 || lagging[0][0].Domain != "b.example.com" || lagging[0][0].Record == nil {
+		t.Fatalf("expected b.example.com and its record to be reported once, got %+v", lagging)
+	}
+	if saved.Domains["b.example.com"] != acmtypes.DomainStatusPendingValidation || len(saved.Lagging) != 1 {
+		t.Fatalf("expected the per-name statuses and lagging names to be saved, got %+v", saved)
+	}
+}
+
+func TestLaggingDomains(t *testing.T) {
+	pending := []DomainStatus{{Domain: "a.example.com", Status: acmtypes.DomainStatusPendingValidation}, {Domain: "b.example.com", Status: acmtypes.DomainStatusPendingValidation}}
+	if got := laggingDomains(pending); len(got) != 0 {
+		t.Fatalf("no name lags while none validated, got %v", got)
+	}
+	pending[0].Status = acmtypes.DomainStatusSuccess
+	if got := laggingDomains(pending); len(got) != 1 || got[0].Domain != "b.example.com" {
+		t.Fatalf("expected b.example.com to lag, got %v", got)
+	}
+}
+
+func TestParseCertificateStatuses(t *testing.T) {
+	statuses, err := ParseCertificateStatuses("issued, VALIDATION_TIMED_OUT,issued")
+	if err != nil {
+		t.Fatalf("ParseCertificateStatuses: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0] != acmtypes.CertificateStatusIssued || statuses[1] != acmtypes.CertificateStatusValidationTimedOut {
+		t.Fatalf("unexpected statuses %v", statuses)
+	}
+	for _, invalid := range []string{"", " , ", "ISSUED,BOGUS"} {
+		if _, err := ParseCertificateStatuses(invalid); err == nil {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestEnsureReuseStatuses(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		statuses []acmtypes.CertificateStatus
+		seeded   acmtypes.CertificateStatus
+		reuse    bool
+	}{
+		{name: "default reuses pending", seeded: acmtypes.CertificateStatusPendingValidation, reuse: true},
+		{name: "default skips timed out", seeded: acmtypes.CertificateStatusValidationTimedOut, reuse: false},
+		{name: "issued only skips pending", statuses: []acmtypes.CertificateStatus{acmtypes.CertificateStatusIssued}, seeded: acmtypes.CertificateStatusPendingValidation, reuse: false},
+		{
+			name:     "timed out included",
+			statuses: []acmtypes.CertificateStatus{acmtypes.CertificateStatusIssued, acmtypes.CertificateStatusValidationTimedOut},
+			seeded:   acmtypes.CertificateStatusValidationTimedOut,
+			reuse:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeACM := newFakeACM()
+			seeded := fakeACM.addCert("app.example.com", tc.seeded, DefaultManagedByValue)
+			m := NewManager(fakeACM, "")
+			m.ReuseStatuses = tc.statuses
+
+			result, err := m.Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", ReuseExisting: true, DNS: &fakeDNS{}})
+			if err != nil {
+				t.Fatalf("Ensure: %v", err)
+			}
+			if reused := result.CertificateArn == seeded; reused != tc.reuse {
+				t.Fatalf("reused seeded certificate = %v, want %v (result %+v)", reused, tc.reuse, result)
+			}
+		})
+	}
+}
+
+func TestEnsureResumesSavedState(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.requestedStatus = acmtypes.CertificateStatusPendingValidation
+	dns := &fakeDNS{}
+	m := &Manager{ACM: fakeACM, ValidationTimeout: 20 * time.Millisecond, PollInterval: time.Millisecond}
+
+	var states []ValidationState
+	req := EnsureRequest{
+		Domain: "app.example.com",
+		DNS:    dns,
+		OnState: func(state ValidationState) error {
+			states = append(states, state)
+			return nil
+		},
+	}
+	result, err := m.Ensure(context.Background(), req)
+	if !errors.Is(err, ErrValidationTimedOut) {
+		t.Fatalf("expected ErrValidationTimedOut, got %v", err)
+	}
+	if len(states) != 2 || states[0].Phase != PhaseRequested || states[1].Phase != PhaseRecordsCreated {
+		t.Fatalf("expected Requested then RecordsCreated states, got %+v", states)
+	}
+	if states[1].CertificateArn != result.CertificateArn || len(states[1].Records) != 1 {
+		t.Fatalf("unexpected saved state %+v", states[1])
+	}
+
+	// A restarted caller resumes the saved certificate instead of requesting another
+	fakeACM.requestedStatus = acmtypes.CertificateStatusIssued
+	resume := states[1]
+	resume.Deadline = time.Now().Add(time.Minute)
+	req.Resume = &resume
+	resumed, err := m.Ensure(context.Background(), req)
+	if err != nil {
+		t.Fatalf("resumed Ensure: %v", err)
+	}
+	if resumed.CertificateArn != result.CertificateArn || resumed.Status != acmtypes.CertificateStatusIssued {
+		t.Fatalf("expected %s to be issued, got %+v", result.CertificateArn, resumed)
+	}
+	if len(fakeACM.requests) != 1 || len(dns.ensured) != 1 {
+		t.Fatalf("resume must not request or write records again, got %d requests and %d records", len(fakeACM.requests), len(dns.ensured))
+	}
+}
+
+func TestEnsureReplacesExpiredValidation(t *testing.T) {
+	type expired struct {
+		arn     string
+		status  acmtypes.CertificateStatus
+		deleted bool
+	}
+	for _, tc := range []struct {
+		name      string
+		status    acmtypes.CertificateStatus
+		age       time.Duration
+		managedBy string
+		resume    bool
+		deleted   bool
+	}{
+		{name: "pending past the window is not reused", status: acmtypes.CertificateStatusPendingValidation, age: 73 * time.Hour, managedBy: DefaultManagedByValue, deleted: true},
+		{name: "timed out saved state", status: acmtypes.CertificateStatusValidationTimedOut, age: 80 * time.Hour, managedBy: DefaultManagedByValue, resume: true, deleted: true},
+		{name: "unowned saved state is kept", status: acmtypes.CertificateStatusPendingValidation, age: 73 * time.Hour, resume: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeACM := newFakeACM()
+			seeded := fakeACM.addCert("app.example.com", tc.status, tc.managedBy)
+			fakeACM.certs[seeded].CreatedAt = aws.Time(time.Now().Add(-tc.age))
+			m := NewManager(fakeACM, "")
+
+			var replaced []expired
+			req := EnsureRequest{
+				Domain:        "app.example.com",
+				ReuseExisting: !tc.resume,
+				DNS:           &fakeDNS{},
+				OnValidationExpired: func(arn string, status acmtypes.CertificateStatus, deleted bool) {
+					replaced = append(replaced, expired{arn, status, deleted})
+				},
+			}
+			if tc.resume {
+				req.Resume = &ValidationState{CertificateArn: seeded, Phase: PhaseRecordsCreated, Deadline: time.Now().Add(time.Minute)}
+			}
+			result, err := m.Ensure(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Ensure: %v", err)
+			}
+			if result.CertificateArn == seeded || len(fakeACM.requests) != 1 {
+				t.Fatalf("expected a fresh certificate in place of %s, got %+v after %d requests", seeded, result, len(fakeACM.requests))
+			}
+			if len(replaced) != 1 || replaced[0] != (expired{seeded, tc.status, tc.deleted}) {
+				t.Fatalf("expected %s to be reported once, got %+v", seeded, replaced)
+			}
+			if _, kept := fakeACM.certs[seeded]; kept == tc.deleted {
+				t.Fatalf("expected the expired certificate to be deleted only when owned, kept = %v", kept)
+			}
+		})
+	}
+}
+
+func TestEnsureNoWaitReturnsPending(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.requestedStatus = acmtypes.CertificateStatusPendingValidation
+	dns := &fakeDNS{}
+	m := &Manager{ACM: fakeACM, ValidationTimeout: time.Hour, PollInterval: time.Hour}
+
+	var saved ValidationState
+	req := EnsureRequest{
+		Domain: "app.example.com",
+		DNS:    dns,
+		NoWait: true,
+		OnState: func(state ValidationState) error {
+			saved = state
+			return nil
+		},
+	}
+	result, err := m.Ensure(context.Background(), req)
+	if !errors.Is(err, ErrValidationPending) {
+		t.Fatalf("expected ErrValidationPending, got %v", err)
+	}
+	if saved.Phase != PhaseRecordsCreated || saved.CertificateArn != result.CertificateArn || len(dns.ensured) != 1 {
+		t.Fatalf("records should be written and saved before returning, got %+v", saved)
+	}
+
+	fakeACM.requestedStatus = acmtypes.CertificateStatusIssued
+	req.Resume = &saved
+	resumed, err := m.Ensure(context.Background(), req)
+	if err != nil || resumed.Status != acmtypes.CertificateStatusIssued {
+		t.Fatalf("expected the pending certificate to be issued on the next call, got %+v, %v", resumed, err)
+	}
+}
+
+func TestPrimaryDomain(t *testing.T) {
+	long := strings.Repeat("a", 50) + ".preview.example.com"
+	domain, sans, err := PrimaryDomain(long, []string{long + ".x", "app.example.com", "api.example.com"})
+	if err != nil {
+		t.Fatalf("PrimaryDomain: %v", err)
+	}
+	if domain != "app.example.com" || strings.Join(sans, ",") != long+","+long+".x,api.example.com" {
+		t.Fatalf("expected the first short SAN to become primary, got %q with %v", domain, sans)
+	}
+	if domain, _, _ := PrimaryDomain("app.example.com", []string{"a.example.com"}); domain != "app.example.com" {
+		t.Fatalf("a domain within the limit must stay primary, got %q", domain)
+	}
+	var tooLong *DomainTooLongError
+	if _, _, err := PrimaryDomain(long, []string{long + ".x"}); !errors.As(err, &tooLong) || len(tooLong.Names) != 2 {
+		t.Fatalf("expected a DomainTooLongError naming both names, got %v", err)
+	}
+}
+
+func TestEnsureDemotesLongDomain(t *testing.T) {
+	fakeACM := newFakeACM()
+	m := NewManager(fakeACM, "")
+	long := strings.Repeat("a", 50) + ".preview.example.com"
+	req := EnsureRequest{Domain: long, SubjectAlternativeNames: []string{"preview.example.com"}, ReuseExisting: true, DNS: &fakeDNS{}}
+
+	first, err := m.Ensure(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if len(fakeACM.requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(fakeACM.requests))
+	}
+	in := fakeACM.requests[0]
+	if aws.ToString(in.DomainName) != "preview.example.com" || len(in.SubjectAlternativeNames) != 1 || in.SubjectAlternativeNames[0] != long {
+		t.Fatalf("expected the long name as a SAN, got %q with %v", aws.ToString(in.DomainName), in.SubjectAlternativeNames)
+	}
+
+	again, err := m.Ensure(context.Background(), req)
+	if err != nil || !again.Reused || again.CertificateArn != first.CertificateArn {
+		t.Fatalf("the same names should reuse %s, got %+v, %v", first.CertificateArn, again, err)
+	}
+}
@@ -0,0 +1,109 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// validationProgress follows the per-name validation status of a certificate being waited for
+type validationProgress struct {
+	certArn    string
+	onProgress func(string, []DomainStatus)
+
+	onLagging    func(string, []DomainStatus)
+	lagThreshold time.Duration
+
+	// wanted, when set, reports whether the certificate is still needed
+	wanted func(context.Context) bool
+
+	// state, when set, records the observed statuses and lagging names and is persisted with
+	// save whenever they change
+	state *ValidationState
+	save  func(ValidationState) error
+
+	reported string
+}
+
+// abandoned returns ErrAbandoned once wanted reports the certificate is no longer needed
+func (p *validationProgress) abandoned(ctx context.Context) error {
+	if p.wanted == nil || p.wanted(ctx) {
+		return nil
+	}
+	log.FromContext(ctx).Info("Certificate is no longer wanted, stopping the validation wait", "certArn", p.certArn)
+	return fmt.Errorf("%w: %s", ErrAbandoned, p.certArn)
+}
+
+// observe logs and reports domains when they differ from the last observation, then checks
+// for names lagging behind the others
+func (p *validationProgress) observe(ctx context.Context, domains []DomainStatus) {
+	logger := log.FromContext(ctx)
+	if summary := FormatDomainStatuses(domains); summary != p.reported {
+		p.reported = summary
+		for _, domain := range domains {
+			logger.Info("Domain validation status", "certArn", p.certArn, "domain", domain.Domain, "status", domain.Status)
+		}
+		if p.onProgress != nil {
+			p.onProgress(p.certArn, domains)
+		}
+	}
+	if p.state == nil {
+		return
+	}
+
+	changed := false
+	statuses := make(map[string]acmtypes.DomainStatus, len(domains))
+	for _, domain := range domains {
+		statuses[domain.Domain] = domain.Status
+	}
+	if !maps.Equal(statuses, p.state.Domains) {
+		p.state.Domains = statuses
+		changed = true
+	}
+
+	if p.onLagging != nil && p.lagThreshold > 0 {
+		lagging := laggingDomains(domains)
+		if since := p.state.RecordsCreatedAt; since.IsZero() || time.Since(since) < p.lagThreshold {
+			lagging = nil
+		}
+		names := make([]string, 0, len(lagging))
+		for _, domain := range lagging {
+			names = append(names, domain.Domain)
+		}
+		if !slices.Equal(names, p.state.Lagging) && (len(names) > 0 || len(p.state.Lagging) > 0) {
+			p.state.Lagging = names
+			changed = true
+			p.onLagging(p.certArn, lagging)
+		}
+	}
+
+	if changed && p.save != nil {
+		if err := p.save(*p.state); err != nil {
+			logger.Error(err, "failed to save validation progress", "certArn", p.certArn)
+		}
+	}
+}
+
+// laggingDomains returns the names still pending validation when at least one other name
+// validated, which points at a problem with those names' records rather than with ACM
+func laggingDomains(domains []DomainStatus) []DomainStatus {
+	var lagging []DomainStatus
+	validated := false
+	for _, domain := range domains {
+		switch domain.Status {
+		case acmtypes.DomainStatusSuccess:
+			validated = true
+		case acmtypes.DomainStatusPendingValidation:
+			lagging = append(lagging, domain)
+		}
+	}
+	if !validated {
+		return nil
+	}
+	return lagging
+}
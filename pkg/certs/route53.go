@@ -0,0 +1,358 @@
+package certs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultNegativeZoneCacheTTL is how long a domain without a hosted zone is remembered
+const DefaultNegativeZoneCacheTTL = 10 * time.Minute
+
+// ErrNoHostedZone is returned by FindZone when no eligible hosted zone matches the domain
+var ErrNoHostedZone = errors.New("no hosted zone")
+
+// Route53Provider manages validation records in public Route 53 hosted zones
+type Route53Provider struct {
+	Client Route53API
+	// ZoneTags restricts zone discovery to hosted zones carrying all of these tags
+	ZoneTags map[string]string
+	// NegativeCacheTTL is how long FindZone returns a cached ErrNoHostedZone for a domain
+	// without listing zones again; zero uses DefaultNegativeZoneCacheTTL
+	NegativeCacheTTL time.Duration
+
+	// OnCacheLookup, when set, is called for every FindZone with whether the cache of domains
+	// without a hosted zone answered it, and OnCacheResize with each change in the number of
+	// domains cached
+	OnCacheLookup func(hit bool)
+	OnCacheResize func(delta int)
+
+	mu          sync.Mutex
+	zoneTags    map[string]map[string]string
+	missingZone map[string]missingZone
+	now         func() time.Time
+}
+
+// noHostedZoneError is matched by errors.Is(err, ErrNoHostedZone)
+type noHostedZoneError struct{ msg string }
+
+func (e *noHostedZoneError) Error() string { return e.msg }
+
+func (e *noHostedZoneError) Is(target error) bool { return target == ErrNoHostedZone }
+
+type missingZone struct {
+	err     error
+	expires time.Time
+}
+
+// NewRoute53Provider returns the default Route 53 DNS provider
+func NewRoute53Provider(client Route53API) *Route53Provider {
+	return &Route53Provider{Client: client}
+}
+
+func (p *Route53Provider) EnsureRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	return p.changeRecords(ctx, route53types.ChangeActionUpsert, zoneID, records)
+}
+
+func (p *Route53Provider) DeleteRecords(ctx context.Context, zoneID string, records []ValidationRecord) error {
+	return p.changeRecords(ctx, route53types.ChangeActionDelete, zoneID, records)
+}
+
+func (p *Route53Provider) changeRecords(ctx context.Context, action route53types.ChangeAction, zoneID string, records []ValidationRecord) error {
+	logger := log.FromContext(ctx)
+
+	// Without every record the certificate cannot validate, so none is written
+	if action == route53types.ChangeActionUpsert {
+		for _, record := range records {
+			if _, _, ok := route53RecordData(record); !ok {
+				return &UnsupportedRecordTypeError{Name: record.Name, Type: record.Type}
+			}
+		}
+	}
+
+	for _, record := range records {
+		rrType, value, ok := route53RecordData(record)
+		if !ok {
+			// Never written, see above
+			continue
+		}
+
+		hostedZoneID := zoneID
+		if hostedZoneID == "" {
+			guessedZoneID, err := p.FindZone(ctx, recordZoneDomain(record))
+			if err != nil {
+				return fmt.Errorf("failed to infer zone: %w", err)
+			}
+			hostedZoneID = guessedZoneID
+		}
+
+		logger.Info("Changing Route 53 validation record", "action", action, "zone", hostedZoneID, "name", record.Name, "type", record.Type, "value", record.Value)
+
+		change := &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(hostedZoneID),
+			ChangeBatch: &route53types.ChangeBatch{
+				Changes: []route53types.Change{
+					{
+						Action: action,
+						ResourceRecordSet: &route53types.ResourceRecordSet{
+							Name: aws.String(record.Name),
+							Type: rrType,
+							TTL:  aws.Int64(300),
+							ResourceRecords: []route53types.ResourceRecord{
+								{Value: aws.String(value)},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		_, err := p.Client.ChangeResourceRecordSets(ctx, change)
+		if err != nil {
+			if action == route53types.ChangeActionDelete && isRoute53RecordNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to change DNS validation record: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// recordZoneDomain returns the name whose zone holds record: the validation record's own
+// name, which may sit below a delegated subzone, or the name a wildcard covers when the
+// record has no name
+func recordZoneDomain(record ValidationRecord) string {
+	if name := strings.TrimSuffix(record.Name, "."); name != "" {
+		return name
+	}
+	return strings.TrimPrefix(record.Domain, "*.")
+}
+
+// UnsupportedRecordTypeError reports a validation record whose type Route 53 does not support
+type UnsupportedRecordTypeError struct {
+	Name string
+	Type string
+}
+
+func (e *UnsupportedRecordTypeError) Error() string {
+	return fmt.Sprintf("validation record %s has type %q, which Route 53 does not support", e.Name, e.Type)
+}
+
+// route53RecordData returns the Route 53 type and value of record, reporting false when
+// Route 53 has no such record type. TXT values are quoted as Route 53 requires.
+func route53RecordData(record ValidationRecord) (route53types.RRType, string, bool) {
+	rrType := route53types.RRType(strings.ToUpper(strings.TrimSpace(record.Type)))
+	if !slices.Contains(rrType.Values(), rrType) {
+		return "", "", false
+	}
+	value := record.Value
+	if rrType == route53types.RRTypeTxt && !strings.HasPrefix(value, `"`) {
+		value = strconv.Quote(value)
+	}
+	return rrType, value, true
+}
+
+// isRoute53RecordNotFound reports whether a DELETE change failed because the record is already gone
+func isRoute53RecordNotFound(err error) bool {
+	var invalid *route53types.InvalidChangeBatch
+	return errors.As(err, &invalid) && strings.Contains(invalid.ErrorMessage(), "not found")
+}
+
+// FindZone returns the ID of the most specific public hosted zone containing domain that
+// carries ZoneTags and the controller can access. A domain without one fails with
+// ErrNoHostedZone, which is cached for NegativeCacheTTL.
+func (p *Route53Provider) FindZone(ctx context.Context, domain string) (string, error) {
+	if err := p.cachedMissingZone(domain); err != nil {
+		p.cacheLookup(true)
+		return "", err
+	}
+	p.cacheLookup(false)
+	zoneID, err := p.findZone(ctx, domain)
+	if errors.Is(err, ErrNoHostedZone) {
+		p.rememberMissingZone(domain, err)
+	}
+	return zoneID, err
+}
+
+// ForgetMissingZones drops the cached ErrNoHostedZone results so the next lookups list zones
+func (p *Route53Provider) ForgetMissingZones() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cacheResize(-len(p.missingZone))
+	p.missingZone = nil
+}
+
+func (p *Route53Provider) cacheLookup(hit bool) {
+	if p.OnCacheLookup != nil {
+		p.OnCacheLookup(hit)
+	}
+}
+
+func (p *Route53Provider) cacheResize(delta int) {
+	if p.OnCacheResize != nil && delta != 0 {
+		p.OnCacheResize(delta)
+	}
+}
+
+func (p *Route53Provider) cachedMissingZone(domain string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	missing, ok := p.missingZone[domain]
+	if !ok {
+		return nil
+	}
+	if !p.clock().Before(missing.expires) {
+		delete(p.missingZone, domain)
+		p.cacheResize(-1)
+		return nil
+	}
+	return missing.err
+}
+
+func (p *Route53Provider) rememberMissingZone(domain string, err error) {
+	ttl := p.NegativeCacheTTL
+	if ttl <= 0 {
+		ttl = DefaultNegativeZoneCacheTTL
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.missingZone == nil {
+		p.missingZone = map[string]missingZone{}
+	}
+	if _, ok := p.missingZone[domain]; !ok {
+		p.cacheResize(1)
+	}
+	p.missingZone[domain] = missingZone{err: err, expires: p.clock().Add(ttl)}
+}
+
+func (p *Route53Provider) clock() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
+}
+
+func (p *Route53Provider) findZone(ctx context.Context, domain string) (string, error) {
+	list, err := p.Client.ListHostedZones(ctx, &route53.ListHostedZonesInput{})
+	if err != nil {
+		return "", err
+	}
+
+	zones := map[string][]string{}
+	for _, zone := range list.HostedZones {
+		// Skip private zones
+		if zone.Config != nil && zone.Config.PrivateZone {
+			continue
+		}
+		zoneName := strings.ToLower(strings.TrimSuffix(aws.ToString(zone.Name), "."))
+		zones[zoneName] = append(zones[zoneName], aws.ToString(zone.Id))
+	}
+
+	// Walk from the most specific zone name to the least, so a delegated subzone wins over
+	// its parent, and skip zones that are filtered out or not accessible
+	var filtered, inaccessible []string
+	for zoneName := strings.ToLower(strings.TrimSuffix(domain, ".")); zoneName != ""; zoneName = parentZoneName(zoneName) {
+		for _, zoneID := range zones[zoneName] {
+			eligible, err := p.zoneEligible(ctx, zoneID)
+			if isRoute53AccessDenied(err) {
+				log.FromContext(ctx).Info("Skipping hosted zone the controller cannot access", "zone", zoneID, "name", zoneName, "error", err.Error())
+				inaccessible = append(inaccessible, zoneName)
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			if !eligible {
+				filtered = append(filtered, zoneName)
+				continue
+			}
+			return strings.TrimPrefix(zoneID, "/hostedzone/"), nil
+		}
+	}
+
+	switch {
+	case len(filtered) > 0:
+		return "", &noHostedZoneError{fmt.Sprintf("no eligible hosted zone found for domain %s: matching zones %s lack the tags %s",
+			domain, strings.Join(filtered, ", "), formatTags(p.ZoneTags))}
+	case len(inaccessible) > 0:
+		return "", &noHostedZoneError{fmt.Sprintf("no accessible hosted zone found for domain %s: access to matching zones %s is denied",
+			domain, strings.Join(inaccessible, ", "))}
+	}
+	return "", &noHostedZoneError{"no matching public hosted zone found for domain: " + domain}
+}
+
+// parentZoneName returns name without its first label, or "" for a single label
+func parentZoneName(name string) string {
+	_, parent, _ := strings.Cut(name, ".")
+	return parent
+}
+
+// isRoute53AccessDenied reports whether err is Route 53 refusing access to a hosted zone
+func isRoute53AccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && (apiErr.ErrorCode() == "AccessDenied" || apiErr.ErrorCode() == "AccessDeniedException")
+}
+
+// zoneEligible reports whether the zone carries every ZoneTags tag. Zone tags are fetched
+// once per provider and cached.
+func (p *Route53Provider) zoneEligible(ctx context.Context, zoneID string) (bool, error) {
+	if len(p.ZoneTags) == 0 {
+		return true, nil
+	}
+	zoneID = strings.TrimPrefix(zoneID, "/hostedzone/")
+
+	p.mu.Lock()
+	tags, ok := p.zoneTags[zoneID]
+	p.mu.Unlock()
+	if !ok {
+		out, err := p.Client.ListTagsForResource(ctx, &route53.ListTagsForResourceInput{
+			ResourceId:   aws.String(zoneID),
+			ResourceType: route53types.TagResourceTypeHostedzone,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to list tags of hosted zone %s: %w", zoneID, err)
+		}
+		tags = map[string]string{}
+		if out.ResourceTagSet != nil {
+			for _, tag := range out.ResourceTagSet.Tags {
+				tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+		}
+		p.mu.Lock()
+		if p.zoneTags == nil {
+			p.zoneTags = map[string]map[string]string{}
+		}
+		p.zoneTags[zoneID] = tags
+		p.mu.Unlock()
+	}
+
+	for key, value := range p.ZoneTags {
+		if got, ok := tags[key]; !ok || got != value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// formatTags renders tags as sorted key=value pairs
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
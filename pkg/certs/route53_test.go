@@ -0,0 +1,174 @@
+package certs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+)
+
+func TestRoute53ProviderWritesUpsert(t *testing.T) {
+	fakeR53 := newFakeRoute53("example.com", "dev.example.com")
+	provider := NewRoute53Provider(fakeR53)
+
+	zone, err := provider.FindZone(context.Background(), "api.dev.example.com")
+	if err != nil || zone != "Z2" {
+		t.Fatalf("FindZone = %q, %v; want the longest matching zone Z2", zone, err)
+	}
+
+	err = provider.EnsureRecords(context.Background(), "", []ValidationRecord{
+		{Domain: "app.example.com", Name: "_x.app.example.com.", Type: "CNAME", Value: "_y.acm-validations.aws."},
+		{Domain: "*.dev.example.com", Name: "_x.dev.example.com.", Type: "CNAME", Value: "_y.acm-validations.aws."},
+	})
+	if err != nil {
+		t.Fatalf("EnsureRecords: %v", err)
+	}
+	if len(fakeR53.changes) != 2 || aws.ToString(fakeR53.changes[0].HostedZoneId) != "Z1" || aws.ToString(fakeR53.changes[1].HostedZoneId) != "Z2" {
+		t.Fatalf("expected changes in Z1 and, for the wildcard, Z2, got %+v", fakeR53.changes)
+	}
+}
+
+func TestRoute53ProviderValidationRecordInDelegatedSubzone(t *testing.T) {
+	ctx := context.Background()
+	fakeR53 := newFakeRoute53("example.com", "dev.example.com", "ample.com")
+	provider := NewRoute53Provider(fakeR53)
+
+	err := provider.EnsureRecords(ctx, "", []ValidationRecord{
+		{Domain: "app.dev.example.com", Name: "_x.app.dev.example.com.", Type: "CNAME", Value: "_y.acm-validations.aws."},
+		{Domain: "www.example.com", Name: "_x.www.example.com.", Type: "CNAME", Value: "_y.acm-validations.aws."},
+	})
+	if err != nil {
+		t.Fatalf("EnsureRecords: %v", err)
+	}
+	if len(fakeR53.changes) != 2 || aws.ToString(fakeR53.changes[0].HostedZoneId) != "Z2" || aws.ToString(fakeR53.changes[1].HostedZoneId) != "Z1" {
+		t.Fatalf("expected the delegated subzone Z2 and the parent zone Z1, got %+v", fakeR53.changes)
+	}
+	if zone, err := provider.FindZone(ctx, "app.badexample.com"); !errors.Is(err, ErrNoHostedZone) {
+		t.Fatalf("zones must match whole labels, got %q, %v", zone, err)
+	}
+
+	// A subzone the controller cannot access falls back to the next zone up
+	denied := NewRoute53Provider(fakeR53)
+	denied.ZoneTags = map[string]string{"team": "platform"}
+	fakeR53.zoneTags = map[string][]route53types.Tag{"Z1": {{Key: aws.String("team"), Value: aws.String("platform")}}}
+	accessDenied := &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized"}
+	fakeR53.tagErrs = map[string]error{"Z2": accessDenied, "Z3": accessDenied}
+	if zone, err := denied.FindZone(ctx, "_x.app.dev.example.com"); err != nil || zone != "Z1" {
+		t.Fatalf("FindZone = %q, %v; want the accessible parent zone Z1", zone, err)
+	}
+	if _, err := denied.FindZone(ctx, "_x.dev.ample.com"); !errors.Is(err, ErrNoHostedZone) || !strings.Contains(err.Error(), "no accessible hosted zone") {
+		t.Fatalf("expected a no accessible zone error, got %v", err)
+	}
+}
+
+func TestRoute53ProviderDeleteIgnoresMissingRecord(t *testing.T) {
+	fakeR53 := newFakeRoute53("example.com")
+	fakeR53.err = &route53types.InvalidChangeBatch{Message: aws.String("Tried to delete resource record set but it was not found")}
+	provider := NewRoute53Provider(fakeR53)
+
+	records := []ValidationRecord{{Domain: "app.example.com", Name: "_x.app.example.com.", Type: "CNAME", Value: "_y.acm-validations.aws."}}
+	if err := provider.DeleteRecords(context.Background(), "Z1", records); err != nil {
+		t.Fatalf("DeleteRecords should ignore missing records, got %v", err)
+	}
+	if err := provider.EnsureRecords(context.Background(), "Z1", records); err == nil {
+		t.Fatal("EnsureRecords must surface the change error")
+	}
+}
+
+func TestRoute53ProviderZoneTagFilter(t *testing.T) {
+	fakeR53 := newFakeRoute53("example.com", "dev.example.com", "other.org")
+	fakeR53.zoneTags = map[string][]route53types.Tag{
+		"Z1": {{Key: aws.String("team"), Value: aws.String("platform")}},
+		"Z2": {{Key: aws.String("team"), Value: aws.String("elsewhere")}},
+	}
+	provider := NewRoute53Provider(fakeR53)
+	provider.ZoneTags = map[string]string{"team": "platform"}
+
+	zone, err := provider.FindZone(context.Background(), "api.dev.example.com")
+	if err != nil || zone != "Z1" {
+		t.Fatalf("FindZone = %q, %v; want the tagged parent zone Z1", zone, err)
+	}
+	if _, err := provider.FindZone(context.Background(), "app.other.org"); err == nil || !strings.Contains(err.Error(), "no eligible hosted zone") {
+		t.Fatalf("expected a no eligible zone error, got %v", err)
+	}
+
+	calls := fakeR53.tagCalls
+	if _, err := provider.FindZone(context.Background(), "api.dev.example.com"); err != nil {
+		t.Fatalf("FindZone: %v", err)
+	}
+	if fakeR53.tagCalls != calls {
+		t.Fatalf("zone tags should be cached, got %d more ListTagsForResource calls", fakeR53.tagCalls-calls)
+	}
+}
+
+func TestRoute53ProviderCachesMissingZones(t *testing.T) {
+	ctx := context.Background()
+	fakeR53 := newFakeRoute53("example.com")
+	provider := NewRoute53Provider(fakeR53)
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	if _, err := provider.FindZone(ctx, "app.other.org"); !errors.Is(err, ErrNoHostedZone) {
+		t.Fatalf("expected ErrNoHostedZone, got %v", err)
+	}
+	if _, err := provider.FindZone(ctx, "app.other.org"); !errors.Is(err, ErrNoHostedZone) || fakeR53.listCalls != 1 {
+		t.Fatalf("expected a cached ErrNoHostedZone without listing zones, got %v after %d calls", err, fakeR53.listCalls)
+	}
+	if _, err := provider.FindZone(ctx, "app.example.com"); err != nil || fakeR53.listCalls != 2 {
+		t.Fatalf("other domains must still be looked up, got %v", err)
+	}
+
+	// The zone is created: it is found once the negative entry expires
+	fakeR53.mu.Lock()
+	fakeR53.zones = append(fakeR53.zones, route53types.HostedZone{Id: aws.String("/hostedzone/Z2"), Name: aws.String("other.org.")})
+	fakeR53.mu.Unlock()
+	now = now.Add(DefaultNegativeZoneCacheTTL - time.Second)
+	if _, err := provider.FindZone(ctx, "app.other.org"); !errors.Is(err, ErrNoHostedZone) {
+		t.Fatalf("expected the negative entry to hold until its TTL, got %v", err)
+	}
+	now = now.Add(time.Second)
+	if zone, err := provider.FindZone(ctx, "app.other.org"); err != nil || zone != "Z2" {
+		t.Fatalf("FindZone after the TTL = %q, %v; want Z2", zone, err)
+	}
+
+	// ForgetMissingZones drops negative entries right away
+	if _, err := provider.FindZone(ctx, "app.missing.net"); !errors.Is(err, ErrNoHostedZone) {
+		t.Fatalf("expected ErrNoHostedZone, got %v", err)
+	}
+	calls := fakeR53.listCalls
+	provider.ForgetMissingZones()
+	if _, err := provider.FindZone(ctx, "app.missing.net"); !errors.Is(err, ErrNoHostedZone) || fakeR53.listCalls != calls+1 {
+		t.Fatalf("expected a fresh lookup after ForgetMissingZones, got %d calls", fakeR53.listCalls-calls)
+	}
+}
+
+func TestRoute53ProviderHonoursRecordType(t *testing.T) {
+	fakeR53 := newFakeRoute53("example.com")
+	provider := NewRoute53Provider(fakeR53)
+
+	txt := ValidationRecord{Domain: "app.example.com", Name: "_x.app.example.com.", Type: "TXT", Value: "token"}
+	bogus := ValidationRecord{Domain: "api.example.com", Name: "_x.api.example.com.", Type: "BOGUS", Value: "token"}
+	var unsupported *UnsupportedRecordTypeError
+	if err := provider.EnsureRecords(context.Background(), "Z1", []ValidationRecord{txt, bogus}); !errors.As(err, &unsupported) || unsupported.Type != "BOGUS" {
+		t.Fatalf("EnsureRecords = %v, want an UnsupportedRecordTypeError for BOGUS", err)
+	}
+	if len(fakeR53.changes) != 0 {
+		t.Fatalf("no record may be written when one is unsupported, got %d changes", len(fakeR53.changes))
+	}
+
+	if err := provider.EnsureRecords(context.Background(), "Z1", []ValidationRecord{txt}); err != nil {
+		t.Fatalf("EnsureRecords: %v", err)
+	}
+	if len(fakeR53.changes) != 1 {
+		t.Fatalf("expected the TXT record to be written, got %d changes", len(fakeR53.changes))
+	}
+	set := fakeR53.changes[0].ChangeBatch.Changes[0].ResourceRecordSet
+	if set.Type != route53types.RRTypeTxt || aws.ToString(set.ResourceRecords[0].Value) != `"token"` {
+		t.Fatalf("expected a quoted TXT record, got %s %s", set.Type, aws.ToString(set.ResourceRecords[0].Value))
+	}
+}
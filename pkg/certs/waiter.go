@@ -0,0 +1,75 @@
+package certs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+// WaiterOptions configure waiting for validation with the SDK's CertificateValidatedWaiter.
+// Zero delays keep the SDK defaults of 60s and 120s; a zero MaxWait waits until the
+// validation deadline.
+type WaiterOptions struct {
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	MaxWait  time.Duration
+}
+
+// waitForValidated waits for the certificate with acm.CertificateValidatedWaiter, reporting
+// progress from each of its describes, and then confirms the certificate status
+func (m *Manager) waitForValidated(ctx context.Context, certArn string, deadline time.Time, progress *validationProgress) (acmtypes.CertificateStatus, []DomainStatus, error) {
+	maxWait := time.Until(deadline)
+	if m.Waiter.MaxWait > 0 && m.Waiter.MaxWait < maxWait {
+		maxWait = m.Waiter.MaxWait
+	}
+	if maxWait <= 0 {
+		return acmtypes.CertificateStatusPendingValidation, nil, fmt.Errorf("%w: %s", ErrValidationTimedOut, certArn)
+	}
+
+	waiter := acm.NewCertificateValidatedWaiter(m.ACM, func(o *acm.CertificateValidatedWaiterOptions) {
+		if m.Waiter.MinDelay > 0 {
+			o.MinDelay = m.Waiter.MinDelay
+		}
+		if m.Waiter.MaxDelay > 0 {
+			o.MaxDelay = m.Waiter.MaxDelay
+		}
+		o.MaxDelay = max(o.MaxDelay, o.MinDelay)
+
+		retryable := o.Retryable
+		o.Retryable = func(ctx context.Context, in *acm.DescribeCertificateInput, out *acm.DescribeCertificateOutput, err error) (bool, error) {
+			if err == nil && out != nil && out.Certificate != nil {
+				progress.observe(ctx, domainStatuses(out.Certificate))
+			}
+			if abandonErr := progress.abandoned(ctx); abandonErr != nil {
+				return false, abandonErr
+			}
+			return retryable(ctx, in, out, err)
+		}
+	})
+	waitErr := waiter.Wait(ctx, &acm.DescribeCertificateInput{CertificateArn: aws.String(certArn)}, maxWait)
+	if ctx.Err() != nil {
+		return acmtypes.CertificateStatusPendingValidation, nil, ctx.Err()
+	}
+	if errors.Is(waitErr, ErrAbandoned) {
+		return acmtypes.CertificateStatusPendingValidation, nil, waitErr
+	}
+
+	// The waiter only looks at the names' validation status; the certificate decides the outcome
+	status, domains, done, err := m.checkIssued(ctx, certArn)
+	if domains == nil || err != nil || done {
+		return status, domains, err
+	}
+	if waitErr == nil {
+		// Every name is validated but ACM has not marked the certificate issued yet
+		return m.waitForIssued(ctx, certArn, deadline, progress)
+	}
+	if time.Now().After(deadline) {
+		return status, domains, fmt.Errorf("%w: %s (%s)", ErrValidationTimedOut, certArn, FormatDomainStatuses(domains))
+	}
+	return status, domains, fmt.Errorf("certificate %s is still pending (%s): %w", certArn, FormatDomainStatuses(domains), waitErr)
+}
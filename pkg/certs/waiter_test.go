@@ -0,0 +1,82 @@
+package certs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+func newWaiterManager(fakeACM *fakeACM) *Manager {
+	return &Manager{
+		ACM:               fakeACM,
+		ValidationTimeout: time.Minute,
+		PollInterval:      time.Hour,
+		UseWaiter:         true,
+		Waiter:            WaiterOptions{MinDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond},
+	}
+}
+
+func TestEnsureWaiterIssues(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{"app.example.com": acmtypes.DomainStatusSuccess}
+
+	var progress []string
+	result, err := newWaiterManager(fakeACM).Ensure(context.Background(), EnsureRequest{
+		Domain: "app.example.com",
+		DNS:    &fakeDNS{},
+		OnProgress: func(_ string, domains []DomainStatus) {
+			progress = append(progress, FormatDomainStatuses(domains))
+		},
+	})
+	if err != nil || result.Status != acmtypes.CertificateStatusIssued {
+		t.Fatalf("expected an issued certificate, got %+v, %v", result, err)
+	}
+	if len(progress) != 1 || progress[0] != "app.example.com=SUCCESS" {
+		t.Fatalf("unexpected progress reports %v", progress)
+	}
+}
+
+func TestEnsureWaiterReportsValidationFailure(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.requestedStatus = acmtypes.CertificateStatusFailed
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{"app.example.com": acmtypes.DomainStatusFailed}
+
+	_, err := newWaiterManager(fakeACM).Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", DNS: &fakeDNS{}})
+	var verr *ValidationFailedError
+	if !errors.As(err, &verr) || len(verr.FailedDomains) != 1 {
+		t.Fatalf("expected a ValidationFailedError naming the domain, got %v", err)
+	}
+}
+
+func TestEnsureWaiterTimesOut(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.requestedStatus = acmtypes.CertificateStatusPendingValidation
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{"app.example.com": acmtypes.DomainStatusPendingValidation}
+	m := newWaiterManager(fakeACM)
+	m.ValidationTimeout = 20 * time.Millisecond
+
+	result, err := m.Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", DNS: &fakeDNS{}})
+	if !errors.Is(err, ErrValidationTimedOut) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if result.Status != acmtypes.CertificateStatusPendingValidation {
+		t.Fatalf("timed out certificate should still be pending, got %s", result.Status)
+	}
+}
+
+func TestEnsureWaiterMaxWaitKeepsPending(t *testing.T) {
+	fakeACM := newFakeACM()
+	fakeACM.requestedStatus = acmtypes.CertificateStatusPendingValidation
+	fakeACM.domainStatus = map[string]acmtypes.DomainStatus{"app.example.com": acmtypes.DomainStatusPendingValidation}
+	m := newWaiterManager(fakeACM)
+	m.Waiter.MaxWait = 10 * time.Millisecond
+
+	_, err := m.Ensure(context.Background(), EnsureRequest{Domain: "app.example.com", DNS: &fakeDNS{}})
+	if err == nil || errors.Is(err, ErrValidationTimedOut) || !strings.Contains(err.Error(), "still pending") {
+		t.Fatalf("a wait shorter than the deadline should leave the certificate pending, got %v", err)
+	}
+}
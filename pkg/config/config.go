@@ -0,0 +1,388 @@
+// Package config holds the versioned configuration file of the controller, which sets the same
+// options as its flags.
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// APIVersion is the apiVersion a configuration file must declare
+	APIVersion = "config.acm.tedens.dev/v1alpha1"
+	// Kind is the kind a configuration file must declare
+	Kind = "ControllerConfiguration"
+	// Redacted replaces the value of secret options in the effective configuration
+	Redacted = "REDACTED"
+)
+
+// Configuration is a configuration file. Every option names the flag it sets with its flag tag;
+// options left out keep the flag's value. Options marked secret are redacted by Effective, and
+// only options marked live are changed by a Watcher while the controller runs.
+type Configuration struct {
+	APIVersion string `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string `yaml:"kind" json:"kind"`
+
+	Metrics        Metrics        `yaml:"metrics" json:"metrics"`
+	Health         Health         `yaml:"health" json:"health"`
+	Admin          Admin          `yaml:"admin" json:"admin"`
+	LeaderElection LeaderElection `yaml:"leaderElection" json:"leaderElection"`
+	Sharding       Sharding       `yaml:"sharding" json:"sharding"`
+	Logging        Logging        `yaml:"logging" json:"logging"`
+	AWS            AWS            `yaml:"aws" json:"aws"`
+	DNS            DNS            `yaml:"dns" json:"dns"`
+	Certificates   Certificates   `yaml:"certificates" json:"certificates"`
+	Ingresses      Ingresses      `yaml:"ingresses" json:"ingresses"`
+	Validation     Validation     `yaml:"validation" json:"validation"`
+	Maintenance    Maintenance    `yaml:"maintenance" json:"maintenance"`
+	Notifications  Notifications  `yaml:"notifications" json:"notifications"`
+	Audit          Audit          `yaml:"audit" json:"audit"`
+	Inventory      Inventory      `yaml:"inventory" json:"inventory"`
+
+	// path is the file the configuration was loaded from, digest the hash of its content and
+	// lines the line of each option set in it, by flag
+	path   string
+	digest [sha256.Size]byte
+	lines  map[string]location
+	// explicit are the flags Apply found given on the command line
+	explicit map[string]bool
+}
+
+// location is where an option is set in the file
+type location struct {
+	line int
+	key  string
+}
+
+// Metrics configures the metrics endpoint
+type Metrics struct {
+	BindAddress            *string `yaml:"bindAddress" json:"bindAddress,omitempty" flag:"metrics-bind-address"`
+	IngressCertificateInfo *bool   `yaml:"ingressCertificateInfo" json:"ingressCertificateInfo,omitempty" flag:"ingress-certificate-info-metric"`
+}
+
+// Health configures the health probes and the startup permission check
+type Health struct {
+	ProbeBindAddress *string `yaml:"probeBindAddress" json:"probeBindAddress,omitempty" flag:"health-probe-bind-address"`
+	Preflight        *bool   `yaml:"preflight" json:"preflight,omitempty" flag:"preflight"`
+}
+
+// Admin configures the admin endpoints, served on a listener of their own
+type Admin struct {
+	Enabled     *bool   `yaml:"enabled" json:"enabled,omitempty" flag:"enable-admin-endpoint"`
+	TokenFile   *string `yaml:"tokenFile" json:"tokenFile,omitempty" flag:"admin-token-file"`
+	BindAddress *string `yaml:"bindAddress" json:"bindAddress,omitempty" flag:"admin-bind-address"`
+}
+
+// LeaderElection configures leader election and split-brain detection
+type LeaderElection struct {
+	Enabled     *bool   `yaml:"enabled" json:"enabled,omitempty" flag:"leader-elect"`
+	PodIdentity *string `yaml:"podIdentity" json:"podIdentity,omitempty" flag:"pod-identity"`
+}
+
+// Sharding splits the managed Ingresses over replicas
+type Sharding struct {
+	Count *int `yaml:"count" json:"count,omitempty" flag:"shard-count"`
+	Index *int `yaml:"index" json:"index,omitempty" flag:"shard-index"`
+}
+
+// Logging configures the log level
+type Logging struct {
+	Level       *string   `yaml:"level" json:"level,omitempty" flag:"log-level"`
+	RevertAfter *Duration `yaml:"revertAfter" json:"revertAfter,omitempty" flag:"log-level-revert-after"`
+}
+
+// AWS configures the AWS clients
+type AWS struct {
+	MaxAttempts      *int      `yaml:"maxAttempts" json:"maxAttempts,omitempty" flag:"aws-max-attempts"`
+	MaxBackoff       *Duration `yaml:"maxBackoff" json:"maxBackoff,omitempty" flag:"aws-max-backoff"`
+	UseFIPSEndpoints *bool     `yaml:"useFIPSEndpoints" json:"useFIPSEndpoints,omitempty" flag:"use-fips-endpoints"`
+	NamespaceRoleMap *string   `yaml:"namespaceRoleMap" json:"namespaceRoleMap,omitempty" flag:"namespace-role-map"`
+	// AllowProfileAnnotation is meant for local development only
+	AllowProfileAnnotation *bool `yaml:"allowProfileAnnotation" json:"allowProfileAnnotation,omitempty" flag:"allow-profile-annotation"`
+}
+
+// DNS configures the validation record providers
+type DNS struct {
+	Provider                 *string   `yaml:"provider" json:"provider,omitempty" flag:"dns-provider"`
+	WebhookURL               *string   `yaml:"webhookURL" json:"webhookURL,omitempty" flag:"dns-webhook-url" secret:"true"`
+	CloudflareAPITokenSecret *string   `yaml:"cloudflareAPITokenSecret" json:"cloudflareAPITokenSecret,omitempty" flag:"cloudflare-api-token-secret"`
+	NoRoute53                *bool     `yaml:"noRoute53" json:"noRoute53,omitempty" flag:"no-route53"`
+	ZoneFilterTags           *string   `yaml:"zoneFilterTags" json:"zoneFilterTags,omitempty" flag:"zone-filter-tags"`
+	NegativeZoneCacheTTL     *Duration `yaml:"negativeZoneCacheTTL" json:"negativeZoneCacheTTL,omitempty" flag:"negative-zone-cache-ttl"`
+	DomainZoneMappings       *bool     `yaml:"domainZoneMappings" json:"domainZoneMappings,omitempty" flag:"enable-domain-zone-mappings"`
+}
+
+// Certificates configures how certificates are requested, tagged, reused and cleaned up
+type Certificates struct {
+	ManagedByValue          *string   `yaml:"managedByValue" json:"managedByValue,omitempty" flag:"managed-by-value"`
+	RequiredTags            *string   `yaml:"requiredTags" json:"requiredTags,omitempty" flag:"required-tags"`
+	NamespaceTagAnnotations *string   `yaml:"namespaceTagAnnotations" json:"namespaceTagAnnotations,omitempty" flag:"namespace-tag-annotations" live:"true"`
+	ReuseStatuses           *string   `yaml:"reuseStatuses" json:"reuseStatuses,omitempty" flag:"reuse-certificate-statuses" live:"true"`
+	RenewBefore             *Duration `yaml:"renewBefore" json:"renewBefore,omitempty" flag:"renew-before" live:"true"`
+	MaxNamesPerCertificate  *int      `yaml:"maxNamesPerCertificate" json:"maxNamesPerCertificate,omitempty" flag:"max-names-per-certificate" live:"true"`
+	AutoSplit               *bool     `yaml:"autoSplit" json:"autoSplit,omitempty" flag:"auto-split-certificates" live:"true"`
+	WildcardSANPolicy       *string   `yaml:"wildcardSANPolicy" json:"wildcardSANPolicy,omitempty" flag:"wildcard-san-policy" live:"true"`
+	IncludeWWW              *bool     `yaml:"includeWWW" json:"includeWWW,omitempty" flag:"include-www" live:"true"`
+	DomainSuffix            *string   `yaml:"domainSuffix" json:"domainSuffix,omitempty" flag:"domain-suffix" live:"true"`
+	MaxRequestsPerHour      *int      `yaml:"maxRequestsPerHour" json:"maxRequestsPerHour,omitempty" flag:"max-certificate-requests-per-hour"`
+	ArnConflict             *string   `yaml:"arnConflict" json:"arnConflict,omitempty" flag:"certificate-arn-conflict" live:"true"`
+	IndexInterval           *Duration `yaml:"indexInterval" json:"indexInterval,omitempty" flag:"certificate-index-interval"`
+	PendingMaxAge           *Duration `yaml:"pendingMaxAge" json:"pendingMaxAge,omitempty" flag:"pending-certificate-max-age"`
+	GCInterval              *Duration `yaml:"gcInterval" json:"gcInterval,omitempty" flag:"gc-interval"`
+}
+
+// Ingresses configures which Ingresses are managed and how they are reconciled
+type Ingresses struct {
+	ManageByDefault         *bool     `yaml:"manageByDefault" json:"manageByDefault,omitempty" flag:"manage-by-default" live:"true"`
+	ALBIngressControllers   *string   `yaml:"albIngressControllers" json:"albIngressControllers,omitempty" flag:"alb-ingress-controllers" live:"true"`
+	FinalizerName           *string   `yaml:"finalizerName" json:"finalizerName,omitempty" flag:"finalizer-name"`
+	PolicyConfigMap         *string   `yaml:"policyConfigMap" json:"policyConfigMap,omitempty" flag:"policy-configmap"`
+	DryRun                  *bool     `yaml:"dryRun" json:"dryRun,omitempty" flag:"ingress-dry-run"`
+	ObserveOnly             *bool     `yaml:"observeOnly" json:"observeOnly,omitempty" flag:"observe-only"`
+	PrioritizeExpiring      *bool     `yaml:"prioritizeExpiring" json:"prioritizeExpiring,omitempty" flag:"prioritize-expiring-certificates"`
+	NoHostRequeueAttempts   *int      `yaml:"noHostRequeueAttempts" json:"noHostRequeueAttempts,omitempty" flag:"no-host-requeue-attempts" live:"true"`
+	NoHostRequeueInterval   *Duration `yaml:"noHostRequeueInterval" json:"noHostRequeueInterval,omitempty" flag:"no-host-requeue-interval" live:"true"`
+	QuarantineAfterFailures *int      `yaml:"quarantineAfterFailures" json:"quarantineAfterFailures,omitempty" flag:"quarantine-after-failures" live:"true"`
+	QuarantineProbeInterval *Duration `yaml:"quarantineProbeInterval" json:"quarantineProbeInterval,omitempty" flag:"quarantine-probe-interval" live:"true"`
+}
+
+// Validation configures the wait for DNS validation
+type Validation struct {
+	RequeuePending    *bool     `yaml:"requeuePending" json:"requeuePending,omitempty" flag:"requeue-pending-validation"`
+	LagThreshold      *Duration `yaml:"lagThreshold" json:"lagThreshold,omitempty" flag:"validation-lag-threshold" live:"true"`
+	UseACMWaiter      *bool     `yaml:"useACMWaiter" json:"useACMWaiter,omitempty" flag:"use-acm-waiter"`
+	ACMWaiterMinDelay *Duration `yaml:"acmWaiterMinDelay" json:"acmWaiterMinDelay,omitempty" flag:"acm-waiter-min-delay"`
+	ACMWaiterMaxDelay *Duration `yaml:"acmWaiterMaxDelay" json:"acmWaiterMaxDelay,omitempty" flag:"acm-waiter-max-delay"`
+	ACMWaiterMaxWait  *Duration `yaml:"acmWaiterMaxWait" json:"acmWaiterMaxWait,omitempty" flag:"acm-waiter-max-wait"`
+}
+
+// Maintenance restricts changes to a weekly window
+type Maintenance struct {
+	Window   *string `yaml:"window" json:"window,omitempty" flag:"maintenance-window"`
+	Timezone *string `yaml:"timezone" json:"timezone,omitempty" flag:"maintenance-window-timezone"`
+}
+
+// Notifications configures the webhooks and events about certificates
+type Notifications struct {
+	WebhookURL       *string   `yaml:"webhookURL" json:"webhookURL,omitempty" flag:"notification-webhook-url" live:"true" secret:"true"`
+	PostIssuanceHook *string   `yaml:"postIssuanceHook" json:"postIssuanceHook,omitempty" flag:"post-issuance-hook" live:"true" secret:"true"`
+	EventDedupWindow *Duration `yaml:"eventDedupWindow" json:"eventDedupWindow,omitempty" flag:"event-dedup-window"`
+}
+
+// Audit configures the audit log
+type Audit struct {
+	Enabled *bool   `yaml:"enabled" json:"enabled,omitempty" flag:"enable-audit-log"`
+	Path    *string `yaml:"path" json:"path,omitempty" flag:"audit-log-path"`
+}
+
+// Inventory configures the inventory ConfigMap
+type Inventory struct {
+	ConfigMap *string   `yaml:"configMap" json:"configMap,omitempty" flag:"inventory-configmap"`
+	Interval  *Duration `yaml:"interval" json:"interval,omitempty" flag:"inventory-interval"`
+}
+
+// Duration is a time.Duration written as a Go duration string, such as 15m
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := time.ParseDuration(node.Value)
+	if node.Kind != yaml.ScalarNode || err != nil {
+		return fmt.Errorf("line %d: %q is not a duration such as 90s or 15m", node.Line, node.Value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Load reads the configuration file at path. Unknown options, values of the wrong type and a
+// missing or different apiVersion or kind are errors naming their line.
+func Load(path string) (*Configuration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.path = path
+	cfg.digest = sha256.Sum256(data)
+	return cfg, nil
+}
+
+// Parse parses a configuration file
+func Parse(data []byte) (*Configuration, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, errors.New("the configuration is empty")
+	}
+	doc := root.Content[0]
+
+	cfg := &Configuration{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.APIVersion != APIVersion {
+		return nil, fmt.Errorf("line %d: apiVersion must be %s, got %q", keyLine(doc, "apiVersion"), APIVersion, cfg.APIVersion)
+	}
+	if cfg.Kind != Kind {
+		return nil, fmt.Errorf("line %d: kind must be %s, got %q", keyLine(doc, "kind"), Kind, cfg.Kind)
+	}
+	cfg.lines = map[string]location{}
+	recordLocations(doc, reflect.TypeOf(cfg).Elem(), "", cfg.lines)
+	return cfg, nil
+}
+
+// keyLine returns the line of key in the mapping node, or of the node when key is missing
+func keyLine(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i].Line
+		}
+	}
+	return node.Line
+}
+
+// recordLocations records the line of every option set in node, which decodes into t
+func recordLocations(node *yaml.Node, t reflect.Type, prefix string, lines map[string]location) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		for j := range t.NumField() {
+			field := t.Field(j)
+			if field.Tag.Get("yaml") != key.Value {
+				continue
+			}
+			if name := field.Tag.Get("flag"); name != "" {
+				lines[name] = location{line: key.Line, key: prefix + key.Value}
+			} else if field.Type.Kind() == reflect.Struct {
+				recordLocations(value, field.Type, prefix+key.Value+".", lines)
+			}
+		}
+	}
+}
+
+// option is a configuration field bound to a flag
+type option struct {
+	flag   string
+	secret bool
+	live   bool
+	value  reflect.Value
+}
+
+// options returns the options of cfg in field order
+func options(cfg *Configuration) []option {
+	var out []option
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if name := field.Tag.Get("flag"); name != "" {
+				out = append(out, option{flag: name, secret: field.Tag.Get("secret") == "true", live: field.Tag.Get("live") == "true", value: v.Field(i)})
+			} else if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem())
+	return out
+}
+
+// Apply sets the flags of fs to the options of the configuration, except for flags given on
+// the command line, which win. fs must already be parsed.
+func (c *Configuration) Apply(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	c.explicit = explicit
+
+	for _, opt := range options(c) {
+		if fs.Lookup(opt.flag) == nil {
+			return fmt.Errorf("configuration option for --%s has no flag", opt.flag)
+		}
+		if opt.value.IsNil() || explicit[opt.flag] {
+			continue
+		}
+		if err := fs.Set(opt.flag, fmt.Sprint(opt.value.Elem().Interface())); err != nil {
+			return fmt.Errorf("%s: %w", c.position(opt.flag), err)
+		}
+	}
+	return nil
+}
+
+// position names the file, line and key an option is set at
+func (c *Configuration) position(flagName string) string {
+	where := c.lines[flagName]
+	if c.path == "" {
+		return fmt.Sprintf("line %d: %s", where.line, where.key)
+	}
+	return fmt.Sprintf("%s:%d: %s", c.path, where.line, where.key)
+}
+
+// Effective returns the configuration the flags of fs amount to, with every option set and the
+// values of secret options replaced by Redacted
+func Effective(fs *flag.FlagSet) (*Configuration, error) {
+	cfg := &Configuration{APIVersion: APIVersion, Kind: Kind}
+	for _, opt := range options(cfg) {
+		f := fs.Lookup(opt.flag)
+		if f == nil {
+			return nil, fmt.Errorf("configuration option for --%s has no flag", opt.flag)
+		}
+		raw := f.Value.String()
+		if opt.secret && raw != "" {
+			raw = Redacted
+		}
+		value := reflect.New(opt.value.Type().Elem())
+		switch target := value.Interface().(type) {
+		case *string:
+			*target = raw
+		case *bool:
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, fmt.Errorf("--%s: %w", opt.flag, err)
+			}
+			*target = parsed
+		case *int:
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, fmt.Errorf("--%s: %w", opt.flag, err)
+			}
+			*target = parsed
+		case *Duration:
+			parsed, err := time.ParseDuration(raw)
+			if err != nil {
+				return nil, fmt.Errorf("--%s: %w", opt.flag, err)
+			}
+			*target = Duration(parsed)
+		default:
+			return nil, fmt.Errorf("--%s: unsupported option type %s", opt.flag, opt.value.Type())
+		}
+		opt.value.Set(value)
+	}
+	return cfg, nil
+}
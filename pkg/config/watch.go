@@ -0,0 +1,204 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultReloadInterval is how often a Watcher reads the file unless told otherwise
+const DefaultReloadInterval = 30 * time.Second
+
+// Reload results, the result label of acm_manager_config_reloads_total
+const (
+	ReloadSuccess = "success"
+	ReloadError   = "error"
+)
+
+// Watcher reloads the configuration file when its content changes. Live options that changed,
+// and that no flag on the command line overrides, are set on the flags before OnReload applies
+// them. Changes to other options need a restart: they are logged and the running value is kept.
+type Watcher struct {
+	flags    *flag.FlagSet
+	interval time.Duration
+	onReload func(ctx context.Context) error
+
+	path     string
+	digest   [sha256.Size]byte
+	explicit map[string]bool
+	// running is the value each option was last applied with, by flag
+	running map[string]string
+
+	reloads        *prometheus.CounterVec
+	lastSuccess    prometheus.Gauge
+	restartPending prometheus.Gauge
+}
+
+// NewWatcher returns a watcher of the file cfg was loaded from, after cfg was applied to fs. It
+// reads the file every interval and calls onReload once changed live options are set on fs;
+// when onReload fails, the flags are set back.
+func NewWatcher(cfg *Configuration, fs *flag.FlagSet, interval time.Duration, onReload func(ctx context.Context) error) *Watcher {
+	w := &Watcher{
+		flags:    fs,
+		interval: interval,
+		onReload: onReload,
+		path:     cfg.path,
+		digest:   cfg.digest,
+		explicit: cfg.explicit,
+		running:  map[string]string{},
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "acm_manager_config_reloads_total",
+			Help: "Changes to the configuration file, by result (success or error).",
+		}, []string{"result"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "acm_manager_config_last_reload_success_timestamp_seconds",
+			Help: "Unix time the configuration file was last reloaded, zero when it was not since the start.",
+		}),
+		restartPending: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "acm_manager_config_restart_pending_options",
+			Help: "Options of the configuration file that changed but only take effect after a restart.",
+		}),
+	}
+	if w.interval <= 0 {
+		w.interval = DefaultReloadInterval
+	}
+	for _, opt := range options(cfg) {
+		if f := fs.Lookup(opt.flag); f != nil {
+			w.running[opt.flag] = optionValue(opt, f)
+		}
+	}
+	return w
+}
+
+// optionValue returns the value opt sets its flag to, the flag default when it is left out
+func optionValue(opt option, f *flag.Flag) string {
+	if opt.value.IsNil() {
+		return f.DefValue
+	}
+	return fmt.Sprint(opt.value.Elem().Interface())
+}
+
+// RegisterMetrics registers the reload metrics
+func (w *Watcher) RegisterMetrics(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{w.reloads, w.lastSuccess, w.restartPending} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NeedLeaderElection lets every replica follow the file, since each runs with its options
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start reloads the file whenever it changed until ctx is cancelled
+func (w *Watcher) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("config")
+	ctx = log.IntoContext(ctx, logger)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := w.reload(ctx); err != nil {
+			logger.Error(err, "Failed to reload the configuration file, keeping the running configuration", "path", w.path)
+		}
+	}
+}
+
+// change is a live option to set to value
+type change struct {
+	flag   string
+	value  string
+	secret bool
+}
+
+// reload applies the file when its content changed since it was last read
+func (w *Watcher) reload(ctx context.Context) error {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return w.failed(fmt.Errorf("failed to read configuration: %w", err))
+	}
+	digest := sha256.Sum256(data)
+	if digest == w.digest {
+		return nil
+	}
+	// A broken file is reported once rather than at every read
+	w.digest = digest
+	cfg, err := Parse(data)
+	if err != nil {
+		return w.failed(fmt.Errorf("%s: %w", w.path, err))
+	}
+	cfg.path = w.path
+
+	logger := log.FromContext(ctx)
+	var changes []change
+	pending := 0
+	for _, opt := range options(cfg) {
+		f := w.flags.Lookup(opt.flag)
+		if f == nil {
+			return w.failed(fmt.Errorf("configuration option for --%s has no flag", opt.flag))
+		}
+		value := optionValue(opt, f)
+		if w.explicit[opt.flag] || value == w.running[opt.flag] {
+			continue
+		}
+		if !opt.live {
+			pending++
+			logger.Info("Configuration option changed but only takes effect after a restart, keeping the running value", "flag", opt.flag)
+			continue
+		}
+		changes = append(changes, change{flag: opt.flag, value: value, secret: opt.secret})
+	}
+	w.restartPending.Set(float64(pending))
+
+	previous := map[string]string{}
+	restore := func() {
+		for name, value := range previous {
+			_ = w.flags.Set(name, value)
+		}
+	}
+	for _, c := range changes {
+		previous[c.flag] = w.flags.Lookup(c.flag).Value.String()
+		if err := w.flags.Set(c.flag, c.value); err != nil {
+			restore()
+			return w.failed(fmt.Errorf("%s: %w", cfg.position(c.flag), err))
+		}
+	}
+	if len(changes) > 0 && w.onReload != nil {
+		if err := w.onReload(ctx); err != nil {
+			restore()
+			return w.failed(err)
+		}
+	}
+
+	for _, c := range changes {
+		w.running[c.flag] = c.value
+		value := c.value
+		if c.secret && value != "" {
+			value = Redacted
+		}
+		logger.Info("Configuration option reloaded", "flag", c.flag, "value", value)
+	}
+	w.reloads.WithLabelValues(ReloadSuccess).Inc()
+	w.lastSuccess.SetToCurrentTime()
+	logger.Info("Reloaded configuration file", "path", w.path, "changed", len(changes), "restartPending", pending)
+	return nil
+}
+
+// failed counts a failed reload and returns err
+func (w *Watcher) failed(err error) error {
+	w.reloads.WithLabelValues(ReloadError).Inc()
+	return err
+}
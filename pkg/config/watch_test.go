@@ -0,0 +1,144 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// startWatcher loads content from a file, applies it to flags parsed from args and returns a
+// watcher of the file and the number of reloads applied
+func startWatcher(t *testing.T, content string, args []string, onReload func() error) (*Watcher, string, *int) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fs := testFlags(t)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("flag parse: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := cfg.Apply(fs); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	applied := 0
+	w := NewWatcher(cfg, fs, 0, func(context.Context) error {
+		applied++
+		if onReload != nil {
+			return onReload()
+		}
+		return nil
+	})
+	return w, path, &applied
+}
+
+func rewrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// metricValue returns the value of the unlabelled gauge or counter collector
+func metricValue(t *testing.T, collector prometheus.Collector) float64 {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil || len(families) != 1 || len(families[0].GetMetric()) != 1 {
+		t.Fatalf("Gather: %v, %v", families, err)
+	}
+	metric := families[0].GetMetric()[0]
+	if metric.GetGauge() != nil {
+		return metric.GetGauge().GetValue()
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func flagValue(w *Watcher, name string) string {
+	return w.flags.Lookup(name).Value.String()
+}
+
+func TestWatcherAppliesLiveChangesOnly(t *testing.T) {
+	ctx := context.Background()
+	w, path, applied := startWatcher(t, testConfig, []string{"--manage-by-default=false"}, nil)
+
+	if err := w.reload(ctx); err != nil || *applied != 0 {
+		t.Fatalf("unchanged file: err=%v reloads=%d, want nothing applied", err, *applied)
+	}
+
+	changed := strings.Replace(testConfig, "720h", "360h", 1)
+	changed = strings.Replace(changed, "provider: webhook", "provider: route53", 1)
+	rewrite(t, path, changed)
+	if err := w.reload(ctx); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if *applied != 1 {
+		t.Fatalf("reloads applied = %d, want 1", *applied)
+	}
+	if got := flagValue(w, "renew-before"); got != "360h0m0s" {
+		t.Errorf("--renew-before = %q, want the reloaded 360h", got)
+	}
+	if got := flagValue(w, "dns-provider"); got != "webhook" {
+		t.Errorf("--dns-provider = %q, want the running webhook until a restart", got)
+	}
+	if got := flagValue(w, "manage-by-default"); got != "false" {
+		t.Errorf("--manage-by-default = %q, want the command line to win", got)
+	}
+	if got := metricValue(t, w.restartPending); got != 1 {
+		t.Errorf("restart pending options = %v, want 1", got)
+	}
+	if got := metricValue(t, w.reloads.WithLabelValues(ReloadSuccess)); got != 1 {
+		t.Errorf("successful reloads = %v, want 1", got)
+	}
+
+	// Removing an option puts its flag back to the default
+	rewrite(t, path, strings.Replace(changed, "  renewBefore: 360h\n", "", 1))
+	if err := w.reload(ctx); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := flagValue(w, "renew-before"); got != "0s" {
+		t.Errorf("--renew-before = %q, want the flag default", got)
+	}
+}
+
+func TestWatcherKeepsRunningConfigurationOnError(t *testing.T) {
+	ctx := context.Background()
+	reject := false
+	w, path, _ := startWatcher(t, testConfig, nil, func() error {
+		if reject {
+			return errors.New("invalid settings")
+		}
+		return nil
+	})
+
+	rewrite(t, path, testConfig+"  unknownOption: 1\n")
+	if err := w.reload(ctx); err == nil || !strings.Contains(err.Error(), "line 11") {
+		t.Fatalf("reload error = %v, want the line of the unknown option", err)
+	}
+	// The broken file is reported once
+	if err := w.reload(ctx); err != nil {
+		t.Fatalf("unchanged broken file: %v", err)
+	}
+
+	reject = true
+	rewrite(t, path, strings.Replace(testConfig, "maxNamesPerCertificate: 20", "maxNamesPerCertificate: 0", 1))
+	if err := w.reload(ctx); err == nil {
+		t.Fatal("expected the rejected settings to fail the reload")
+	}
+	if got := flagValue(w, "max-names-per-certificate"); got != "20" {
+		t.Errorf("--max-names-per-certificate = %q, want it set back to 20", got)
+	}
+	if got := metricValue(t, w.reloads.WithLabelValues(ReloadError)); got != 2 {
+		t.Errorf("failed reloads = %v, want 2", got)
+	}
+}